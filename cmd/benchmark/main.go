@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/benchmark"
+)
+
+func main() {
+	var cfg benchmark.Config
+	var mode, format string
+	flag.StringVar(&cfg.Host, "host", "127.0.0.1", "host of the echoing endpoint")
+	flag.IntVar(&cfg.Port, "port", 8080, "port of the echoing endpoint")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 1, "number of parallel connections")
+	flag.IntVar(&cfg.Rate, "rate", 0, "requests per second across all connections in reqresp mode (0 is unlimited)")
+	flag.DurationVar(&cfg.Duration, "duration", 10*time.Second, "how long to measure")
+	flag.DurationVar(&cfg.Warmup, "warmup", 0, "how long to send requests before measuring")
+	flag.StringVar(&mode, "mode", string(benchmark.ModeEcho), "workload: echo, throughput or reqresp")
+	flag.IntVar(&cfg.PayloadSize, "size", 0, "bytes per message (0 uses the mode's default)")
+	flag.StringVar(&format, "format", "text", "output format: text or json")
+	flag.Parse()
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", format)
+		os.Exit(2)
+	}
+	cfg.Mode = benchmark.Mode(mode)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := benchmark.Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Benchmark failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode result: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Print(result)
+}
@@ -1,28 +1,46 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/integrity"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/security/access"
+	"github.com/o3willard-AI/SSSonector/internal/security/caps"
+	"github.com/o3willard-AI/SSSonector/internal/security/cert"
 	"github.com/o3willard-AI/SSSonector/internal/service"
 	"github.com/o3willard-AI/SSSonector/internal/service/control"
+	"github.com/o3willard-AI/SSSonector/internal/service/platform"
+	"github.com/o3willard-AI/SSSonector/internal/startup"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 var (
-	// Version is set during build
-	Version = "dev"
+	// Version, BuildTime (RFC 3339) and CommitHash are set during build
+	Version    = "dev"
+	BuildTime  = ""
+	CommitHash = ""
 
 	// Command line flags
-	configFile = flag.String("config", "/etc/sssonector/config.yaml", "Path to config file")
-	socketPath = flag.String("socket", "/var/run/sssonector.sock", "Path to control socket")
-	logLevel   = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	configFile     = flag.String("config", "/etc/sssonector/config.yaml", "Path to config file")
+	socketPath     = flag.String("socket", "/var/run/sssonector.sock", "Path to control socket, TCP address or pipe name")
+	network        = flag.String("network", control.DefaultNetwork, "Control transport (unix, tcp, npipe)")
+	logLevel       = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	stateDir       = flag.String("state-dir", "/var/lib/sssonector", "Directory for persisted state")
+	strict         = flag.Bool("strict-integrity", false, "Refuse to run unless the binary's signature verifies")
+	tokenFile      = flag.String("token-file", "", "File holding the token control clients must send; required for the tcp transport")
+	startupTimeout = flag.Duration("startup-timeout", 2*time.Minute, "How long listening or connecting may take before startup is aborted")
+	migrateConfig  = flag.Bool("migrate-config", false, "Upgrade a config file with an older schema version and write it back when it is loaded")
 )
 
 func getLogLevel(level string) zapcore.Level {
@@ -43,10 +61,13 @@ func getLogLevel(level string) zapcore.Level {
 func main() {
 	// Parse command line flags
 	flag.Parse()
+	control.ApplyPipeDefault(flag.CommandLine, *network, socketPath)
 
 	// Initialize logger
+	// The level is atomic so that the control protocol can change it at runtime
+	level := zap.NewAtomicLevelAt(getLogLevel(*logLevel))
 	logConfig := zap.NewProductionConfig()
-	logConfig.Level = zap.NewAtomicLevelAt(getLogLevel(*logLevel))
+	logConfig.Level = level
 	logger, err := logConfig.Build()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
@@ -54,64 +75,196 @@ func main() {
 	}
 	defer logger.Sync()
 
-	// Initialize configuration manager
-	configManager := config.CreateManager(*configFile)
+	// Startup progress is reported once the service listens, or when it fails
+	progress := startup.NewStartupLogger(logger)
+	fail := func(component, operation string, start time.Time, err error) {
+		progress.LogOperation(component, operation, time.Since(start), err)
+		logger.Info("Startup report", zap.Reflect("report", progress.Report()))
+		os.Exit(1)
+	}
+	setPhase := func(phase startup.Phase) {
+		if err := progress.SetPhase(phase); err != nil {
+			fail("startup", "enter "+phase.String(), time.Now(), err)
+		}
+	}
+
+	// A hung listen or connect aborts startup instead of leaving the daemon
+	// half started
+	progress.SetAbortHandler(func(phase startup.Phase, err error) {
+		fail("startup", phase.String(), time.Now().Add(-*startupTimeout), err)
+	})
+
+	// Check the binary against its release signature
+	start := time.Now()
+	if err := integrity.CheckExecutable(*strict, func(err error) {
+		progress.Warn("integrity", "Binary signature verification failed", zap.Error(err))
+	}); err != nil {
+		fail("integrity", "verify binary", start, err)
+	}
+
+	// Fail before the tunnel needs the TUN device or raw sockets
+	start = time.Now()
+	if err := caps.Check(caps.Required...); err != nil {
+		fail("security", "check capabilities", start, err)
+	}
+	progress.LogOperation("security", "check capabilities", time.Since(start), nil)
+
+	setPhase(startup.PhaseConfiguration)
+
+	// Upgrade an older schema on disk before the manager reads the file
+	if *migrateConfig {
+		start = time.Now()
+		if _, err := config.LoadConfig(*configFile, configLoadOptions(false)); err != nil {
+			fail("config", "migrate", start, err)
+		}
+		progress.LogOperation("config", "migrate", time.Since(start), nil)
+	}
+
+	// Initialize configuration manager for the file SIGHUP reloads
+	configManager := config.CreateManagerForFile(*configFile)
 
 	// Get configuration
+	start = time.Now()
 	cfg, err := configManager.Get()
 	if err != nil {
-		logger.Error("Failed to get config", zap.Error(err))
-		os.Exit(1)
+		fail("config", "load", start, err)
+	}
+	progress.LogOperation("config", "load", time.Since(start), nil)
+
+	// Persistence degrades to disabled on a read-only state directory
+	state := platform.ProbeStateDir(*stateDir)
+	if !state.Writable {
+		progress.Warn("state", "State directory is not writable, persistence disabled",
+			zap.String("state_dir", *stateDir),
+			zap.Strings("disabled", state.Disabled()),
+			zap.NamedError("reason", state.Reason))
 	}
 
 	// Create service
+	start = time.Now()
 	svc, err := service.NewBaseService(cfg, service.ServiceOptions{
 		Name:      "sssonector",
 		ConfigDir: "/etc/sssonector",
-		DataDir:   "/var/lib/sssonector",
+		DataDir:   *stateDir,
 		LogDir:    "/var/log/sssonector",
 	})
 	if err != nil {
-		logger.Error("Failed to create service", zap.Error(err))
-		os.Exit(1)
+		fail("service", "create", start, err)
+	}
+	svc.SetLogger(logger, level)
+	svc.SetVersion(service.NewVersionInfo("sssonector", Version, BuildTime, CommitHash))
+	svc.SetStateDir(state)
+
+	// Rotated certificates are served to new handshakes without a restart
+	rotator, err := newCertificateRotator(cfg, svc, logger)
+	if err != nil {
+		fail("cert", "start rotation", start, err)
+	}
+	if rotator != nil {
+		svc.SetCertificateRotator(rotator)
+		defer rotator.Stop()
+	}
+
+	// The RBAC model is re-read by the rbac reload command
+	if model := cfg.Config.Security.RBAC.Model; model != "" {
+		rbac := access.NewRBACManager(logger)
+		if err := rbac.ReloadModel(model); err != nil {
+			fail("rbac", "load model", start, err)
+		}
+		svc.SetRBACModel(rbac, model)
+	}
+
+	// The monitor reports on the service once it runs
+	var mon *monitor.Monitor
+	if monitor.Enabled(cfg) {
+		monitorCfg, err := monitor.ConfigFrom(cfg)
+		if err != nil {
+			fail("monitor", "create", start, err)
+		}
+		mon, err = monitor.New(monitorCfg)
+		if err != nil {
+			fail("monitor", "create", start, err)
+		}
+		mon.SetMemoryPressureSource(svc.MemoryPressure)
+		svc.SetMonitor(mon)
 	}
 
 	// Create control server
 	controlServer, err := control.NewControlServer(svc)
 	if err != nil {
-		logger.Error("Failed to create control server", zap.Error(err))
-		os.Exit(1)
+		fail("control", "create", start, err)
+	}
+	progress.LogOperation("service", "create", time.Since(start), nil)
+
+	// A server's startup ends with listening for clients, a client's with
+	// connecting to its server
+	tunnelPhase := startup.PhaseListen
+	if cfg.Config.Mode == types.ModeClient {
+		tunnelPhase = startup.PhaseConnection
+	}
+	if err := progress.SetPhaseWithDeadline(tunnelPhase, *startupTimeout); err != nil {
+		fail("startup", "enter "+tunnelPhase.String(), time.Now(), err)
 	}
 
 	// Set socket path and start control server
+	start = time.Now()
+	controlServer.SetNetwork(*network)
 	controlServer.SetSocketPath(*socketPath)
+	if *tokenFile != "" {
+		token, err := control.ReadTokenFile(*tokenFile)
+		if err != nil {
+			fail("control", "start", start, err)
+		}
+		controlServer.SetToken(token)
+	}
 	if err := controlServer.Start(); err != nil {
-		logger.Error("Failed to start control server", zap.Error(err))
-		os.Exit(1)
+		fail("control", "start", start, err)
 	}
 	defer controlServer.Stop()
+	progress.LogOperation("control", "start", time.Since(start), nil)
 
 	// Start service
+	start = time.Now()
 	if err := svc.Start(); err != nil {
-		logger.Error("Failed to start service", zap.Error(err))
-		os.Exit(1)
+		fail("tunnel", "start", start, err)
 	}
 	defer svc.Stop()
+	progress.LogOperation("tunnel", "start", time.Since(start), nil)
+
+	if mon != nil {
+		start = time.Now()
+		if err := mon.Start(); err != nil {
+			fail("monitor", "start", start, err)
+		}
+		defer mon.Stop()
+		progress.LogOperation("monitor", "start", time.Since(start), nil)
+	}
+	logger.Info("Startup report", zap.Reflect("report", progress.Report()))
+	setPhase(startup.PhaseRunning)
 
 	// Log startup
 	logger.Info("Service started",
 		zap.String("version", Version),
+		zap.String("build_time", BuildTime),
+		zap.String("commit", CommitHash),
 		zap.String("config", *configFile),
+		zap.String("network", *network),
 		zap.String("socket", *socketPath),
 		zap.String("log_level", *logLevel),
+		zap.String("state_dir", *stateDir),
+		zap.Bool("state_writable", state.Writable),
 	)
 
 	// Wait for signals
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	// Handle signals
+	// Handle signals, reloading the configuration on SIGHUP until asked to stop
 	sig := <-sigChan
+	for ; sig == syscall.SIGHUP; sig = <-sigChan {
+		logger.Info("Received signal", zap.String("signal", sig.String()))
+		reloadConfig(svc, logger)
+	}
 	logger.Info("Received signal", zap.String("signal", sig.String()))
 
 	// Stop service
@@ -122,3 +275,55 @@ func main() {
 
 	logger.Info("Service stopped")
 }
+
+// newCertificateRotator starts rotating the server's certificate when
+// auth.cert_rotation is enabled, with the session ticket key rotating along
+func newCertificateRotator(cfg *types.AppConfig, svc *service.BaseService, logger *zap.Logger) (*cert.CertificateRotator, error) {
+	auth := cfg.Config.Auth
+	if !auth.CertRotation.Enabled || cfg.Config.Mode != types.ModeServer {
+		return nil, nil
+	}
+	if auth.CertFile == "" || auth.KeyFile == "" {
+		return nil, fmt.Errorf("certificate rotation requires auth.cert_file and auth.key_file")
+	}
+
+	rotationConfig := cert.DefaultRotationConfig()
+	if auth.CertRotation.Interval > 0 {
+		rotationConfig.RotationInterval = auth.CertRotation.Interval
+	}
+	rotationConfig.OnRotation = svc.CertificateRotated
+
+	store := cert.NewFileStoreWithCA(auth.CertFile, auth.KeyFile, auth.CAFile)
+	rotator := cert.NewCertificateRotator(rotationConfig, cert.NewManager(store, logger), logger)
+	if err := rotator.Start(context.Background()); err != nil {
+		return nil, err
+	}
+	return rotator, nil
+}
+
+// configLoadOptions returns the options for reading the config file, migrating
+// it in place when -migrate-config is set
+func configLoadOptions(validate bool) config.LoadOptions {
+	return config.LoadOptions{
+		AutoMigrate: *migrateConfig,
+		WriteBack:   *migrateConfig,
+		Validate:    validate,
+	}
+}
+
+// reloadConfig re-reads and validates the configuration file and applies it to
+// the running service. On failure the service keeps its current configuration.
+func reloadConfig(svc *service.BaseService, logger *zap.Logger) {
+	cfg, err := config.LoadConfig(*configFile, configLoadOptions(true))
+	if err == nil {
+		err = svc.ReloadConfig(cfg)
+	}
+	if err != nil {
+		logger.Error("Failed to reload configuration, keeping the running configuration",
+			zap.String("path", *configFile),
+			zap.Error(err),
+		)
+		return
+	}
+	logger.Info("Reloaded configuration", zap.String("path", *configFile))
+}
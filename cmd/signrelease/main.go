@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/o3willard-AI/SSSonector/internal/integrity"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [arguments]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  keygen <private-key-file>          Create a signing key and print its public key\n")
+	fmt.Fprintf(os.Stderr, "  sign <private-key-file> <file>...  Write a detached signature next to each file\n")
+	fmt.Fprintf(os.Stderr, "  verify <public-key> <file>...      Check each file against its signature\n")
+	os.Exit(1)
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen(os.Args[2])
+	case "sign":
+		err = sign(os.Args[2], os.Args[3:])
+	case "verify":
+		err = verify(os.Args[2], os.Args[3:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// keygen writes a new private key seed to path and prints the public key to
+// embed in builds
+func keygen(path string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	seed := hex.EncodeToString(private.Seed())
+	if err := os.WriteFile(path, []byte(seed+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	fmt.Println(hex.EncodeToString(public))
+	return nil
+}
+
+// sign writes a detached signature for each file
+func sign(keyPath string, files []string) error {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+	key, err := integrity.ParsePrivateKey(string(data))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := integrity.SignFile(file, key); err != nil {
+			return err
+		}
+		fmt.Printf("Signed %s\n", file)
+	}
+	return nil
+}
+
+// verify checks each file against its detached signature
+func verify(publicKey string, files []string) error {
+	key, err := integrity.ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := integrity.VerifyFileSignature(file, key); err != nil {
+			return err
+		}
+		fmt.Printf("Verified %s\n", file)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+)
+
+// printConnections renders client connections as a table
+func printConnections(out io.Writer, conns []tunnel.ConnectionInfo) {
+	if len(conns) == 0 {
+		fmt.Fprintln(out, "No active connections")
+		return
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPEER\tCLIENT CN\tTUNNEL IP\tTLS\tESTABLISHED\tBYTES IN\tBYTES OUT\tIDLE")
+	for _, c := range conns {
+		tunnelIP := c.TunnelIP
+		if tunnelIP == "" {
+			tunnelIP = "-"
+		}
+		clientCN := c.ClientCN
+		if clientCN == "" {
+			clientCN = "-"
+		}
+		tlsVersion := c.TLSVersion
+		if tlsVersion == "" {
+			tlsVersion = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			c.ID,
+			c.RemoteAddr,
+			clientCN,
+			tunnelIP,
+			tlsVersion,
+			c.Established.Local().Format(time.RFC3339),
+			c.BytesIn,
+			c.BytesOut,
+			now.Sub(c.LastActivity).Truncate(time.Second))
+	}
+	w.Flush()
+}
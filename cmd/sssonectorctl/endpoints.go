@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+)
+
+// endpointsArgs builds the command arguments for endpoints [list|add|remove]
+func endpointsArgs(args []string) (map[string]interface{}, error) {
+	if len(args) == 0 || args[0] == "list" {
+		return map[string]interface{}{"action": "list"}, nil
+	}
+
+	switch action := args[0]; action {
+	case "add":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("endpoints add takes an address and an optional weight")
+		}
+		weight := 1
+		if len(args) == 3 {
+			w, err := strconv.Atoi(args[2])
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("invalid weight: %s", args[2])
+			}
+			weight = w
+		}
+		return map[string]interface{}{"action": action, "address": args[1], "weight": weight}, nil
+	case "remove":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("endpoints remove takes an address")
+		}
+		return map[string]interface{}{"action": action, "address": args[1]}, nil
+	default:
+		return nil, fmt.Errorf("unknown endpoints action: %s", action)
+	}
+}
+
+// printEndpoints renders the server endpoints as a table
+func printEndpoints(out io.Writer, endpoints []tunnel.EndpointStatus) {
+	if len(endpoints) == 0 {
+		fmt.Fprintln(out, "No endpoints configured, connecting to server_address")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tWEIGHT\tHEALTHY\tCHECK\tBREAKER\tSELECTED\tFAILURES\tTRANSITIONS")
+	for _, e := range endpoints {
+		healthy := "yes"
+		if !e.Healthy {
+			healthy = "no"
+		}
+		check := "up"
+		if e.Down {
+			check = "down"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%d\t%d\t%d\n",
+			e.Address, e.Weight, healthy, check, e.State, e.Selected, e.Failures, e.Transitions)
+	}
+	w.Flush()
+
+	for _, e := range endpoints {
+		if e.LastProbe != "" {
+			fmt.Fprintf(out, "\nLast failed health check of %s: %s\n", e.Address, e.LastProbe)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/o3willard-AI/SSSonector/internal/security/cert"
+	"go.uber.org/zap"
+)
+
+// p12PasswordEnv is read when no password file is given
+const p12PasswordEnv = "SSSONECTOR_P12_PASSWORD"
+
+// runExportP12 writes a client certificate bundle to disk. It works on local
+// certificate files and does not use the control socket.
+func runExportP12(args []string, logger *zap.Logger) error {
+	fs := flag.NewFlagSet("export-p12", flag.ExitOnError)
+	certFile := fs.String("cert", "/etc/sssonector/certs/client.crt", "Client certificate file")
+	keyFile := fs.String("key", "/etc/sssonector/certs/client.key", "Client private key file")
+	caFile := fs.String("ca", "/etc/sssonector/certs/ca.crt", "CA certificate chain file")
+	serial := fs.String("serial", "", "Serial number of the client certificate (default: the certificate in -cert)")
+	passwordFile := fs.String("password-file", "", "File containing the bundle password (default: $"+p12PasswordEnv+")")
+	outFile := fs.String("out", "client.p12", "Output file")
+	fs.Parse(args)
+
+	password := os.Getenv(p12PasswordEnv)
+	if *passwordFile != "" {
+		data, err := os.ReadFile(*passwordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read password file: %w", err)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	}
+	if password == "" {
+		return fmt.Errorf("a bundle password is required via -password-file or $%s", p12PasswordEnv)
+	}
+
+	store := cert.NewFileStoreWithCA(*certFile, *keyFile, *caFile)
+	if *serial == "" {
+		current, err := store.LoadCurrent()
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		*serial = current.Cert.SerialNumber.String()
+	}
+
+	manager := cert.NewManager(store, logger)
+	data, err := manager.ExportPKCS12(*serial, password)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(*outFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(*outFile, 0600); err != nil {
+		return fmt.Errorf("failed to set bundle permissions: %w", err)
+	}
+
+	fmt.Printf("Wrote %s\n", *outFile)
+	return nil
+}
+
+// runWriteChain writes a certificate followed by the intermediates that issued it
+// to a PEM file that can be used as cert_file. It works on local certificate
+// files and does not use the control socket.
+func runWriteChain(args []string) error {
+	fs := flag.NewFlagSet("write-chain", flag.ExitOnError)
+	certFile := fs.String("cert", "/etc/sssonector/certs/server.crt", "Certificate file")
+	keyFile := fs.String("key", "/etc/sssonector/certs/server.key", "Private key file")
+	caFile := fs.String("ca", "/etc/sssonector/certs/ca.crt", "CA certificate chain file")
+	outFile := fs.String("out", "chain.pem", "Output file")
+	fs.Parse(args)
+
+	store := cert.NewFileStoreWithCA(*certFile, *keyFile, *caFile)
+	current, err := store.LoadCurrent()
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	if err := store.WriteChainFile(current.ToCertificate(), *outFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s\n", *outFile)
+	return nil
+}
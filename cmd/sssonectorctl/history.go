@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+)
+
+// sparkBars are the levels of a sparkline, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// printHistory renders the trend of each metric as a sparkline followed by a
+// table of the snapshots. Throughput and errors are per second between
+// consecutive snapshots.
+func printHistory(out io.Writer, history []monitor.MetricsSnapshot) {
+	if len(history) == 0 {
+		fmt.Fprintln(out, "No metrics history in this window")
+		return
+	}
+
+	n := len(history)
+	in, outRate, errors := make([]float64, n), make([]float64, n), make([]float64, n)
+	conns, cpu, mem := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i, s := range history {
+		if i > 0 {
+			prev := history[i-1]
+			if elapsed := s.Time.Sub(prev.Time).Seconds(); elapsed > 0 {
+				in[i] = float64(s.BytesIn-prev.BytesIn) / elapsed
+				outRate[i] = float64(s.BytesOut-prev.BytesOut) / elapsed
+				errors[i] = float64(s.Errors-prev.Errors) / elapsed
+			}
+		}
+		conns[i] = float64(s.Connections)
+		cpu[i] = s.CPUUsage
+		mem[i] = float64(s.MemoryUsage)
+	}
+
+	first, last := history[0].Time.Local(), history[n-1].Time.Local()
+	fmt.Fprintf(out, "%d snapshots from %s to %s\n\n", n, first.Format(time.RFC3339), last.Format(time.RFC3339))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Connections:\t%s\t%.0f\n", sparkline(conns), conns[n-1])
+	fmt.Fprintf(w, "In (B/s):\t%s\t%.0f\n", sparkline(in), in[n-1])
+	fmt.Fprintf(w, "Out (B/s):\t%s\t%.0f\n", sparkline(outRate), outRate[n-1])
+	fmt.Fprintf(w, "Errors (/s):\t%s\t%.2f\n", sparkline(errors), errors[n-1])
+	fmt.Fprintf(w, "CPU (%%):\t%s\t%.1f\n", sparkline(cpu), cpu[n-1])
+	fmt.Fprintf(w, "Memory (B):\t%s\t%.0f\n", sparkline(mem), mem[n-1])
+	w.Flush()
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tCONNECTIONS\tIN B/S\tOUT B/S\tERRORS/S\tCPU %\tMEMORY")
+	for i, s := range history {
+		fmt.Fprintf(w, "%s\t%d\t%.0f\t%.0f\t%.2f\t%.1f\t%d\n",
+			s.Time.Local().Format("15:04:05"), s.Connections, in[i], outRate[i], errors[i], s.CPUUsage, s.MemoryUsage)
+	}
+	w.Flush()
+}
+
+// sparkline draws values scaled between their minimum and maximum
+func sparkline(values []float64) string {
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparkBars)-1))
+		}
+		line[i] = sparkBars[level]
+	}
+	return string(line)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/config/validator"
+)
+
+// runConfigLint prints warnings for insecure settings in a configuration file. The
+// warnings do not make the command fail.
+func runConfigLint(args []string) error {
+	fs := flag.NewFlagSet("config lint", flag.ExitOnError)
+	configFile := fs.String("config", "/etc/sssonector/config.yaml", "Configuration file")
+	fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configFile, config.LoadOptions{})
+	if err != nil {
+		return err
+	}
+
+	warnings := validator.NewValidator().Lint(cfg)
+	for _, w := range warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+	fmt.Printf("%s: %d warning(s)\n", *configFile, len(warnings))
+	return nil
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/pool"
 	"github.com/o3willard-AI/SSSonector/internal/service"
 	"github.com/o3willard-AI/SSSonector/internal/service/control"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
 	"go.uber.org/zap"
 )
 
@@ -16,13 +23,29 @@ var (
 	Version = "dev"
 
 	// Command line flags
-	socketPath = flag.String("socket", "/var/run/sssonector.sock", "Path to control socket")
+	socketPath = flag.String("socket", "/var/run/sssonector.sock", "Path to control socket, TCP address or pipe name")
+	network    = flag.String("network", control.DefaultNetwork, "Control transport (unix, tcp, npipe)")
 	jsonOutput = flag.Bool("json", false, "Output in JSON format")
+	format     = flag.String("format", "text", "Output format (text, json)")
+	timeout    = flag.Duration("timeout", 30*time.Second, "Give up on a command after this long")
+	compress   = flag.Bool("compress", false, "Ask the service to compress large responses")
+	command    = flag.String("command", "", "Command to run, instead of the first argument")
+	connID     = flag.String("id", "", "Connection ID or peer address for disconnect")
+	tokenFile  = flag.String("token-file", "", "File holding the token the service requires")
 )
 
 func main() {
 	// Parse command line flags
 	flag.Parse()
+	control.ApplyPipeDefault(flag.CommandLine, *network, socketPath)
+	switch *format {
+	case "text":
+	case "json":
+		*jsonOutput = true
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", *format)
+		os.Exit(1)
+	}
 
 	// Initialize logger
 	logger, err := zap.NewProduction()
@@ -32,6 +55,36 @@ func main() {
 	}
 	defer logger.Sync()
 
+	// Local commands that do not use the control socket
+	if args := flag.Args(); len(args) > 0 && args[0] == "export-p12" {
+		if err := runExportP12(args[1:], logger); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "write-chain" {
+		if err := runWriteChain(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 1 && args[0] == "config" && args[1] == "lint" {
+		if err := runConfigLint(args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "mib" {
+		if err := runMIBDump(os.Stdout, *jsonOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create control client
 	client, err := control.NewClient(nil, logger)
 	if err != nil {
@@ -40,10 +93,23 @@ func main() {
 	}
 
 	// Set socket path
+	client.SetNetwork(*network)
 	client.SetSocketPath(*socketPath)
+	client.SetCompression(*compress)
+	if *tokenFile != "" {
+		token, err := control.ReadTokenFile(*tokenFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		client.SetToken(token)
+	}
 
 	// Get command
 	args := flag.Args()
+	if *command != "" {
+		args = append([]string{*command}, args...)
+	}
 	if len(args) < 1 {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] <command>\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nCommands:\n")
@@ -53,6 +119,24 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  start     Start service\n")
 		fmt.Fprintf(os.Stderr, "  stop      Stop service\n")
 		fmt.Fprintf(os.Stderr, "  reload    Reload configuration\n")
+		fmt.Fprintf(os.Stderr, "  connections   List active client connections\n")
+		fmt.Fprintf(os.Stderr, "  disconnect <id>  Close a client connection by ID or peer address (or -id)\n")
+		fmt.Fprintf(os.Stderr, "  debug [on|off]  Switch debug logging on (default) or off without restart\n")
+		fmt.Fprintf(os.Stderr, "  log-level [level]  Show or set the log level (debug, info, warn, error)\n")
+		fmt.Fprintf(os.Stderr, "  endpoints     List, add or remove the client's server endpoints\n")
+		fmt.Fprintf(os.Stderr, "  pool          Show the client's connection pool statistics\n")
+		fmt.Fprintf(os.Stderr, "  rate-limits   Show rate limits and which clients are being throttled\n")
+		fmt.Fprintf(os.Stderr, "  version       Show the version, build time and commit of the service\n")
+		fmt.Fprintf(os.Stderr, "  history [window]  Show recent metrics trends, optionally only the last window (e.g. 10m)\n")
+		fmt.Fprintf(os.Stderr, "  rotate-certs  Rotate TLS certificates without restart\n")
+		fmt.Fprintf(os.Stderr, "  export-p12    Export a client certificate bundle (see export-p12 -h)\n")
+		fmt.Fprintf(os.Stderr, "  write-chain   Write a certificate with its intermediates (see write-chain -h)\n")
+		fmt.Fprintf(os.Stderr, "  config lint   Warn about insecure configuration settings\n")
+		fmt.Fprintf(os.Stderr, "  mib           List the SNMP OIDs with their types and descriptions\n")
+		fmt.Fprintf(os.Stderr, "  rbac reload   Reload RBAC roles and policies without restart\n")
+		fmt.Fprintf(os.Stderr, "  acl reload    Reload the connection allow/deny rules without restart\n")
+		fmt.Fprintf(os.Stderr, "\nExit status:\n")
+		fmt.Fprintf(os.Stderr, "  0 success, 1 internal error, 3 not found, 4 permission denied, 5 timeout\n")
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		flag.PrintDefaults()
 		os.Exit(1)
@@ -60,6 +144,7 @@ func main() {
 
 	// Map command to ServiceCommand
 	var cmd service.ServiceCommand
+	var cmdArgs map[string]interface{}
 	switch args[0] {
 	case "status":
 		cmd = service.CmdStatus
@@ -73,17 +158,102 @@ func main() {
 		cmd = service.CmdStop
 	case "reload":
 		cmd = service.CmdReload
+	case "rotate-certs":
+		cmd = service.CmdRotateCerts
+	case "connections":
+		cmd = service.CmdConnections
+	case "debug":
+		level := "debug"
+		if len(args) > 1 {
+			switch args[1] {
+			case "on":
+			case "off":
+				level = "info"
+			default:
+				fmt.Fprintf(os.Stderr, "Usage: %s debug [on|off]\n", os.Args[0])
+				os.Exit(1)
+			}
+		}
+		cmd = service.CmdLogLevel
+		cmdArgs = map[string]interface{}{"level": level}
+	case "log-level":
+		cmd = service.CmdLogLevel
+		if len(args) > 1 {
+			cmdArgs = map[string]interface{}{"level": args[1]}
+		}
+	case "endpoints":
+		cmd = service.CmdEndpoints
+		cmdArgs, err = endpointsArgs(args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Usage: %s endpoints [list|add <host:port> [weight]|remove <host:port>]\n", os.Args[0])
+			os.Exit(1)
+		}
+	case "pool":
+		cmd = service.CmdPool
+	case "rate-limits":
+		cmd = service.CmdRateLimits
+	case "version":
+		cmd = service.CmdVersion
+	case "history":
+		cmd = service.CmdHistory
+		if len(args) > 1 {
+			if _, err := time.ParseDuration(args[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Usage: %s history [window, e.g. 10m]\n", os.Args[0])
+				os.Exit(1)
+			}
+			cmdArgs = map[string]interface{}{"window": args[1]}
+		}
+	case "disconnect":
+		if len(args) > 1 && *connID == "" {
+			*connID = args[1]
+		}
+		if *connID == "" {
+			fmt.Fprintf(os.Stderr, "Usage: %s disconnect <id>\n", os.Args[0])
+			os.Exit(1)
+		}
+		cmd = service.CmdDisconnect
+		cmdArgs = map[string]interface{}{"id": *connID}
+	case "rbac":
+		if len(args) < 2 || args[1] != "reload" {
+			fmt.Fprintf(os.Stderr, "Usage: %s rbac reload\n", os.Args[0])
+			os.Exit(1)
+		}
+		cmd = service.CmdReloadRBAC
+	case "acl":
+		if len(args) < 2 || args[1] != "reload" {
+			fmt.Fprintf(os.Stderr, "Usage: %s acl reload\n", os.Args[0])
+			os.Exit(1)
+		}
+		cmd = service.CmdReloadACL
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
 		os.Exit(1)
 	}
 
 	// Execute command
-	resp, err := client.ExecuteCommand(cmd, nil)
-	if err != nil {
-		logger.Error("Command failed", zap.Error(err))
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := client.ConnectContext(ctx); err != nil {
+		logger.Error("Failed to connect to service", zap.Error(err))
 		os.Exit(1)
 	}
+	defer client.Close()
+
+	resp, err := client.ExecuteCommandContext(ctx, cmd, cmdArgs)
+	if err != nil {
+		code := control.ErrorCodeOf(err)
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(map[string]interface{}{
+				"error": control.NewError(code, "%s", commandErrorMessage(err)),
+			})
+		} else {
+			logger.Error("Command failed", zap.String("code", string(code)), zap.Error(err))
+		}
+		os.Exit(exitStatus(code))
+	}
 
 	// Output response
 	if *jsonOutput {
@@ -100,7 +270,19 @@ func main() {
 			if resp.Message != "" {
 				fmt.Println(resp.Message)
 			}
-			if resp.Data != nil {
+			if conns, ok := resp.Data.([]tunnel.ConnectionInfo); ok {
+				printConnections(os.Stdout, conns)
+			} else if endpoints, ok := resp.Data.([]tunnel.EndpointStatus); ok {
+				printEndpoints(os.Stdout, endpoints)
+			} else if stats, ok := resp.Data.(*pool.Stats); ok {
+				printPoolStats(os.Stdout, stats)
+			} else if status, ok := resp.Data.(*throttle.RateLimitStatus); ok {
+				printRateLimits(os.Stdout, status)
+			} else if info, ok := resp.Data.(*service.VersionInfo); ok {
+				printVersion(os.Stdout, info)
+			} else if history, ok := resp.Data.([]monitor.MetricsSnapshot); ok {
+				printHistory(os.Stdout, history)
+			} else if resp.Data != nil && cmd != service.CmdLogLevel { // The message names the level
 				data, err := json.MarshalIndent(resp.Data, "", "  ")
 				if err != nil {
 					logger.Error("Failed to marshal data", zap.Error(err))
@@ -114,3 +296,26 @@ func main() {
 		}
 	}
 }
+
+// exitStatus maps a control error code to the process exit status
+func exitStatus(code control.ErrorCode) int {
+	switch code {
+	case control.CodeNotFound:
+		return 3
+	case control.CodePermissionDenied:
+		return 4
+	case control.CodeTimeout:
+		return 5
+	default:
+		return 1
+	}
+}
+
+// commandErrorMessage returns the message of a control error, or the error text
+func commandErrorMessage(err error) string {
+	var ctlErr *control.Error
+	if errors.As(err, &ctlErr) {
+		return ctlErr.Message
+	}
+	return err.Error()
+}
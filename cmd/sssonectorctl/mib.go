@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+)
+
+// runMIBDump prints the OIDs the SNMP agent serves. The MIB is the same for
+// every service, so it is built locally rather than asked of the service.
+func runMIBDump(out io.Writer, asJSON bool) error {
+	entries := monitor.NewMIBTree(monitor.NewMetrics()).Dump()
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	printMIB(out, entries)
+	return nil
+}
+
+// printMIB renders the MIB entries as a table
+func printMIB(out io.Writer, entries []monitor.MIBEntryInfo) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "OID\tNAME\tTYPE\tACCESS\tDESCRIPTION\n")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.OID, e.Name, e.Type, e.Access, e.Description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/pool"
+)
+
+// printPoolStats renders the connection pool statistics
+func printPoolStats(out io.Writer, stats *pool.Stats) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Active:\t%d\n", stats.ActiveCount)
+	fmt.Fprintf(w, "Idle:\t%d\n", stats.IdleCount)
+	fmt.Fprintf(w, "Created:\t%d\n", stats.CreatedCount)
+	fmt.Fprintf(w, "Evicted:\t%d\n", stats.EvictedCount)
+	fmt.Fprintf(w, "Waits:\t%d (%s total)\n", stats.WaitCount, stats.WaitTime.Truncate(time.Millisecond))
+	w.Flush()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
+)
+
+// printRateLimits renders the rate limit scopes as a table followed by the
+// clients currently being throttled and the per-source-IP connection limit
+func printRateLimits(out io.Writer, status *throttle.RateLimitStatus) {
+	if !status.Enabled {
+		fmt.Fprintln(out, "Rate limiting is disabled")
+	} else {
+		printScopes(out, status)
+	}
+	if status.Connections != nil {
+		printConnectionLimit(out, status.Connections)
+	}
+}
+
+// printScopes renders the byte rate limit scopes and the throttled clients
+func printScopes(out io.Writer, status *throttle.RateLimitStatus) {
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCOPE\tRATE\tBURST\tIN TOKENS\tOUT TOKENS\tTHROTTLES\tLAST THROTTLED\tTHROTTLED")
+	var throttled []string
+	for _, s := range status.Scopes {
+		last := "-"
+		if !s.LastThrottled.IsZero() {
+			last = s.LastThrottled.Local().Format(time.RFC3339)
+		}
+		state := "no"
+		if s.Throttled {
+			state = "yes"
+			if s.Scope != throttle.GlobalScope {
+				throttled = append(throttled, s.Scope)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%.0f\t%.0f\t%.0f\t%.0f\t%d\t%s\t%s\n",
+			s.Scope, s.Rate, s.Burst, s.InTokens, s.OutTokens, s.ThrottleCount, last, state)
+	}
+	w.Flush()
+
+	if !status.PerClient {
+		return
+	}
+	if len(throttled) == 0 {
+		fmt.Fprintln(out, "\nNo clients are currently throttled")
+		return
+	}
+	fmt.Fprintln(out, "\nCurrently throttled clients:")
+	for _, client := range throttled {
+		fmt.Fprintf(out, "  %s\n", client)
+	}
+}
+
+// printConnectionLimit renders the connection limit and the source IPs that
+// had connections rejected
+func printConnectionLimit(out io.Writer, status *throttle.ConnectionLimitStatus) {
+	fmt.Fprintf(out, "\nConnection limit: %g/s per source IP, burst %.0f\n", status.Rate, status.Burst)
+	fmt.Fprintf(out, "Tracked sources: %d, rejected connections: %d\n", status.Tracked, status.Rejected)
+	if len(status.Sources) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tTOKENS\tREJECTED\tLAST REJECTED")
+	for _, s := range status.Sources {
+		fmt.Fprintf(w, "%s\t%.1f\t%d\t%s\n",
+			s.Source, s.Tokens, s.Rejected, s.LastRejected.Local().Format(time.RFC3339))
+	}
+	w.Flush()
+}
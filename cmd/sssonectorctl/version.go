@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/service"
+)
+
+// printVersion renders the build of the running service next to the build of
+// this control tool
+func printVersion(out io.Writer, info *service.VersionInfo) {
+	buildDate, commit := "unknown", "unknown"
+	if !info.BuildDate.IsZero() {
+		buildDate = info.BuildDate.UTC().Format(time.RFC3339)
+	}
+	if info.CommitHash != "" {
+		commit = info.CommitHash
+	}
+	fmt.Fprintf(out, "Service version: %s\n", info.Version)
+	fmt.Fprintf(out, "Build time:      %s\n", buildDate)
+	fmt.Fprintf(out, "Commit:          %s\n", commit)
+	if !info.StartedAt.IsZero() {
+		fmt.Fprintf(out, "Started:         %s\n", info.StartedAt.Local().Format(time.RFC3339))
+	}
+	fmt.Fprintf(out, "Client version:  %s\n", Version)
+}
@@ -17,7 +17,7 @@ type Client struct {
 	config  *config.AppConfig
 	manager config.ConfigManager
 	logger  *zap.Logger
-	tunnel  tunnel.Tunnel
+	tunnel  reloadableTunnel
 }
 
 // NewClient creates a new tunnel client
@@ -39,10 +39,17 @@ func (c *Client) Start() error {
 
 	// Handle signals
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
 
-	// Wait for signal
+	// Reload the configuration on SIGHUP until asked to stop
 	sig := <-sigChan
+	for ; sig == syscall.SIGHUP; sig = <-sigChan {
+		c.logger.Info("Received signal", zap.String("signal", sig.String()))
+		if cfg := handleReload(c.tunnel, c.logger); cfg != nil {
+			c.config = cfg
+		}
+	}
 	c.logger.Info("Received signal", zap.String("signal", sig.String()))
 
 	// Stop tunnel
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/o3willard-AI/SSSonector/internal/integrity"
+	"go.uber.org/zap"
+)
+
+// How to handle a configuration file that changed since its info was recorded
+const (
+	integrityWarn   = "warn"   // Log a warning and keep the recorded info
+	integrityRefuse = "refuse" // Refuse to start
+	integrityUpdate = "update" // Accept the change and record the new info
+)
+
+// checkConfigIntegrity compares the configuration file with its .info sidecar,
+// recording the sidecar when there is none yet
+func checkConfigIntegrity(path, mode string) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil // Nothing to check
+	}
+
+	result, err := integrity.Verify(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, err := integrity.WriteInfo(path, integrity.DefaultAlgorithm); err != nil {
+			return fmt.Errorf("failed to record configuration file info: %w", err)
+		}
+		logger.Info("Recorded configuration file info", zap.String("path", path+integrity.InfoSuffix))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify configuration file: %w", err)
+	}
+	if result.Match {
+		return nil
+	}
+
+	fields := []zap.Field{
+		zap.String("path", path),
+		zap.String("algorithm", result.Recorded.Algorithm),
+		zap.String("recorded_hash", result.Recorded.Hash),
+		zap.String("current_hash", result.Current.Hash),
+		zap.Time("recorded_mod_time", result.Recorded.ModTime),
+		zap.Time("current_mod_time", result.Current.ModTime),
+	}
+	switch mode {
+	case integrityRefuse:
+		logger.Error("Configuration file changed since its info was recorded", fields...)
+		return fmt.Errorf("configuration file %s does not match %s", path, path+integrity.InfoSuffix)
+	case integrityUpdate:
+		if _, err := integrity.WriteInfo(path, result.Recorded.Algorithm); err != nil {
+			return fmt.Errorf("failed to record configuration file info: %w", err)
+		}
+		logger.Warn("Accepted configuration file change", fields...)
+	default:
+		logger.Warn("Configuration file changed since its info was recorded; run with -config-integrity=update to accept the change", fields...)
+	}
+	return nil
+}
+
+// checkBinarySignature verifies the running binary against its detached
+// signature. Failures are only returned in strict mode and logged otherwise.
+func checkBinarySignature(strict bool) error {
+	return integrity.CheckExecutable(strict, func(err error) {
+		logger.Warn("Binary signature verification failed", zap.Error(err))
+	})
+}
@@ -6,20 +6,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/config/validator"
 	"github.com/o3willard-AI/SSSonector/internal/tunnel"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 var (
-	configPath string
-	logger     *zap.Logger
+	configPath      string
+	configIntegrity string
+	strictIntegrity bool
+	logger          *zap.Logger
 )
 
 func init() {
 	// Parse command line flags
 	flag.StringVar(&configPath, "config", "", "path to configuration file")
+	flag.StringVar(&configIntegrity, "config-integrity", integrityWarn,
+		"when the configuration file no longer matches its .info: warn, refuse or update")
+	flag.BoolVar(&strictIntegrity, "strict-integrity", false, "refuse to run unless the binary's signature verifies")
 	flag.Parse()
 
 	// Initialize logger
@@ -31,6 +39,21 @@ func init() {
 	}
 }
 
+// newLogger creates a production logger writing to output: stdout, stderr or a
+// file path. It logs at level, or at info when level is empty.
+func newLogger(output, level string) (*zap.Logger, error) {
+	logConfig := zap.NewProductionConfig()
+	logConfig.OutputPaths = []string{output}
+	if level != "" {
+		parsed, err := zapcore.ParseLevel(strings.ToLower(level))
+		if err != nil {
+			return nil, err
+		}
+		logConfig.Level = zap.NewAtomicLevelAt(parsed)
+	}
+	return logConfig.Build()
+}
+
 func main() {
 	// Create context
 	ctx := context.Background()
@@ -40,14 +63,30 @@ func main() {
 		configPath = "/etc/sssonector/config.yaml"
 	}
 
-	// Create configuration manager
-	manager := config.CreateManager(filepath.Dir(configPath))
+	// Create configuration manager for the file SIGHUP reloads
+	manager := config.CreateManagerForFile(configPath)
 
-	// Copy config file to manager's directory if it's not already there
+	// Make sure the configuration directory exists
 	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		logger.Fatal("Failed to create config directory", zap.Error(err))
 	}
 
+	// Check the binary against its release signature
+	if err := checkBinarySignature(strictIntegrity); err != nil {
+		logger.Fatal("Binary signature verification failed", zap.Error(err))
+	}
+
+	// Detect changes since its info was recorded to the configuration file,
+	// which is the file the manager loads
+	switch configIntegrity {
+	case integrityWarn, integrityRefuse, integrityUpdate:
+	default:
+		logger.Fatal("Invalid -config-integrity value", zap.String("value", configIntegrity))
+	}
+	if err := checkConfigIntegrity(configPath, configIntegrity); err != nil {
+		logger.Fatal("Configuration integrity check failed", zap.Error(err))
+	}
+
 	// Load configuration
 	appCfg, err := manager.Get()
 	if err != nil {
@@ -57,11 +96,40 @@ func main() {
 		)
 	}
 
-	// Set server type if not already set
+	// Log where the configuration says, now that it is loaded
+	if appCfg.Config != nil {
+		output, warning := validator.LogOutputPath(appCfg.Config.Logging)
+		configured, err := newLogger(output, appCfg.Config.Logging.Level)
+		if err != nil {
+			logger.Fatal("Failed to configure logging",
+				zap.String("output", output),
+				zap.String("level", appCfg.Config.Logging.Level),
+				zap.Error(err),
+			)
+		}
+		logger = configured
+		if warning != "" {
+			logger.Warn("Inconsistent logging configuration", zap.String("warning", warning))
+		}
+	}
+
+	// Default to a server; the file is left as written
 	if appCfg.Type == "" {
 		appCfg.Type = config.TypeServer
-		if err := manager.Set(appCfg); err != nil {
-			logger.Fatal("Failed to set configuration type", zap.Error(err))
+	}
+
+	// Preflight: warn about insecure but valid settings
+	for _, w := range validator.NewValidator().Lint(appCfg) {
+		logger.Warn("Insecure configuration setting",
+			zap.String("setting", w.Setting),
+			zap.String("warning", w.Message),
+		)
+	}
+
+	// Preflight: fail fast if the server listen port is taken
+	if appCfg.Config != nil && appCfg.Config.Mode == string(config.TypeServer) {
+		if err := tunnel.ProbeListenPort(appCfg); err != nil {
+			logger.Fatal("Listen port unavailable", zap.Error(err))
 		}
 	}
 
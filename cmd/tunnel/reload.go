@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+	"go.uber.org/zap"
+)
+
+// reloadableTunnel is a tunnel that applies a new configuration without
+// restarting
+type reloadableTunnel interface {
+	tunnel.Tunnel
+	Reload(cfg *config.AppConfig) error
+}
+
+// reloadConfig re-reads and validates the configuration file and applies it to
+// t. When any step fails, t keeps running with its current configuration.
+func reloadConfig(path string, t reloadableTunnel) (*config.AppConfig, error) {
+	cfg, err := config.LoadConfig(path, config.LoadOptions{Validate: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := tunnel.UpdateCertificatePaths(cfg, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to update certificate paths: %w", err)
+	}
+	if err := t.Reload(cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// handleReload reloads the configuration on SIGHUP, logging the outcome. It
+// returns the new configuration, or nil when the reload failed.
+func handleReload(t reloadableTunnel, logger *zap.Logger) *config.AppConfig {
+	cfg, err := reloadConfig(configPath, t)
+	if err != nil {
+		logger.Error("Failed to reload configuration, keeping the running configuration",
+			zap.String("path", configPath),
+			zap.Error(err),
+		)
+		return nil
+	}
+	logger.Info("Reloaded configuration", zap.String("path", configPath))
+	return cfg
+}
@@ -17,7 +17,7 @@ type Server struct {
 	config  *config.AppConfig
 	manager config.ConfigManager
 	logger  *zap.Logger
-	tunnel  tunnel.Tunnel
+	tunnel  reloadableTunnel
 }
 
 // NewServer creates a new tunnel server
@@ -39,10 +39,17 @@ func (s *Server) Start() error {
 
 	// Handle signals
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
 
-	// Wait for signal
+	// Reload the configuration on SIGHUP until asked to stop
 	sig := <-sigChan
+	for ; sig == syscall.SIGHUP; sig = <-sigChan {
+		s.logger.Info("Received signal", zap.String("signal", sig.String()))
+		if cfg := handleReload(s.tunnel, s.logger); cfg != nil {
+			s.config = cfg
+		}
+	}
 	s.logger.Info("Received signal", zap.String("signal", sig.String()))
 
 	// Stop tunnel
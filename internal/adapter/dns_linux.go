@@ -0,0 +1,287 @@
+//go:build linux
+// +build linux
+
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// resolvConfPath is the system resolver configuration
+const resolvConfPath = "/etc/resolv.conf"
+
+// dnsBackupFile is the file in the state directory holding the resolv.conf
+// that Apply replaced, until Restore writes it back
+const dnsBackupFile = "resolv.conf.backup"
+
+// dnsBackend sets the system resolvers for an interface and puts back what
+// was there before
+type dnsBackend interface {
+	Set(iface string, servers []string) error
+	Restore(iface string) error
+}
+
+// DNSManager points the system resolver at the tunnel's DNS servers and
+// restores the previous resolver configuration
+type DNSManager struct {
+	iface   string
+	backend dnsBackend
+
+	mu      sync.Mutex
+	applied bool
+}
+
+// NewDNSManager creates a DNS manager for the named interface. It uses
+// systemd-resolved when resolv.conf is managed by it, and otherwise rewrites
+// resolv.conf. With a state directory, the replaced resolv.conf is also saved
+// there, so that RestoreDNSBackup can put it back after a crash.
+func NewDNSManager(iface, stateDir string) *DNSManager {
+	var backend dnsBackend
+	if usesResolved(resolvConfPath) {
+		backend = &resolvedBackend{run: runCommand}
+	} else {
+		backend = &resolvConfBackend{path: resolvConfPath, backupPath: dnsBackupPath(stateDir)}
+	}
+	return &DNSManager{iface: iface, backend: backend}
+}
+
+// RestoreDNSBackup writes back the resolv.conf saved in stateDir by a client
+// that did not restore it, such as one that crashed while connected. It
+// reports whether there was a backup to restore.
+func RestoreDNSBackup(stateDir string) (bool, error) {
+	b := &resolvConfBackend{path: resolvConfPath, backupPath: dnsBackupPath(stateDir)}
+	return b.restoreBackup()
+}
+
+// dnsBackupPath returns the backup file in stateDir, or "" without one
+func dnsBackupPath(stateDir string) string {
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, dnsBackupFile)
+}
+
+// Apply makes servers the resolvers for the interface. It does nothing when
+// no servers are given.
+func (m *DNSManager) Apply(servers []string) error {
+	if len(servers) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.backend.Set(m.iface, servers); err != nil {
+		return err
+	}
+	m.applied = true
+	return nil
+}
+
+// Restore puts back the resolver configuration Apply replaced. It does
+// nothing if Apply did not change it.
+func (m *DNSManager) Restore() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.applied {
+		return nil
+	}
+	if err := m.backend.Restore(m.iface); err != nil {
+		return err
+	}
+	m.applied = false
+	return nil
+}
+
+// usesResolved reports whether path is systemd-resolved's stub resolv.conf
+// and resolvectl is available to configure it
+func usesResolved(path string) bool {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil || !strings.Contains(target, "/systemd/resolve/") {
+		return false
+	}
+	_, err = exec.LookPath("resolvectl")
+	return err == nil
+}
+
+// runCommand runs a command, including its output in any error
+func runCommand(name string, args ...string) error {
+	if out, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w (output: %s)", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// resolvedBackend sets per-link DNS servers through systemd-resolved. The
+// tunnel interface has no DNS settings of its own before, so reverting the
+// link restores them.
+type resolvedBackend struct {
+	run func(name string, args ...string) error
+}
+
+func (b *resolvedBackend) Set(iface string, servers []string) error {
+	if err := b.run("resolvectl", append([]string{"dns", iface}, servers...)...); err != nil {
+		return fmt.Errorf("failed to set DNS servers on %s: %w", iface, err)
+	}
+	return nil
+}
+
+func (b *resolvedBackend) Restore(iface string) error {
+	if err := b.run("resolvectl", "revert", iface); err != nil {
+		return fmt.Errorf("failed to revert DNS servers on %s: %w", iface, err)
+	}
+	return nil
+}
+
+// resolvConfBackend rewrites resolv.conf, saving its contents, or the symlink
+// it was, to write back on Restore. With a backup path the saved state is
+// also written there before resolv.conf is changed.
+type resolvConfBackend struct {
+	path       string
+	backupPath string
+
+	saved    bool
+	contents []byte
+	link     string // Target if path was a symlink
+	mode     os.FileMode
+}
+
+// resolvConfBackup is the saved state of resolv.conf in the backup file
+type resolvConfBackup struct {
+	Contents []byte      `json:"contents"`
+	Link     string      `json:"link,omitempty"`
+	Mode     os.FileMode `json:"mode"`
+}
+
+func (b *resolvConfBackend) Set(iface string, servers []string) error {
+	if !b.saved {
+		info, err := os.Lstat(b.path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", b.path, err)
+		}
+		b.link, b.mode = "", info.Mode().Perm()
+		if info.Mode()&os.ModeSymlink != 0 {
+			if b.link, err = os.Readlink(b.path); err != nil {
+				return fmt.Errorf("failed to read %s: %w", b.path, err)
+			}
+		}
+		if b.contents, err = os.ReadFile(b.path); err != nil {
+			return fmt.Errorf("failed to read %s: %w", b.path, err)
+		}
+		if b.link != "" {
+			b.mode = 0644
+		}
+		if err := b.writeBackup(); err != nil {
+			return err
+		}
+		b.saved = true
+	}
+
+	// The tunnel's servers come first; search domains and options are kept
+	var conf bytes.Buffer
+	fmt.Fprintf(&conf, "# Generated by sssonector for %s; restored on disconnect\n", iface)
+	for _, server := range servers {
+		fmt.Fprintf(&conf, "nameserver %s\n", server)
+	}
+	for _, line := range strings.Split(string(b.contents), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] != "nameserver" && !strings.HasPrefix(fields[0], "#") {
+			conf.WriteString(line + "\n")
+		}
+	}
+
+	// Replace rather than write through, so a symlink target is left untouched
+	if err := replaceFile(b.path, conf.Bytes(), b.mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", b.path, err)
+	}
+	return nil
+}
+
+func (b *resolvConfBackend) Restore(iface string) error {
+	if !b.saved {
+		return nil
+	}
+
+	var err error
+	if b.link != "" {
+		tmp := b.path + ".sssonector"
+		os.Remove(tmp)
+		if err = os.Symlink(b.link, tmp); err == nil {
+			err = os.Rename(tmp, b.path)
+		}
+	} else {
+		err = replaceFile(b.path, b.contents, b.mode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", b.path, err)
+	}
+	b.saved = false
+
+	if b.backupPath != "" {
+		if err := os.Remove(b.backupPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove DNS backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeBackup saves the state Restore writes back to the backup file, if any
+func (b *resolvConfBackend) writeBackup() error {
+	if b.backupPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(resolvConfBackup{Contents: b.contents, Link: b.link, Mode: b.mode})
+	if err != nil {
+		return fmt.Errorf("failed to encode DNS backup: %w", err)
+	}
+	if err := replaceFile(b.backupPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write DNS backup: %w", err)
+	}
+	return nil
+}
+
+// restoreBackup loads the state saved in the backup file and restores it
+func (b *resolvConfBackend) restoreBackup() (bool, error) {
+	if b.backupPath == "" {
+		return false, nil
+	}
+	data, err := os.ReadFile(b.backupPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read DNS backup: %w", err)
+	}
+
+	var backup resolvConfBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return false, fmt.Errorf("failed to decode DNS backup %s: %w", b.backupPath, err)
+	}
+	b.contents, b.link, b.mode, b.saved = backup.Contents, backup.Link, backup.Mode, true
+	return true, b.Restore("")
+}
+
+// replaceFile atomically replaces path with a file holding data
+func replaceFile(path string, data []byte, mode os.FileMode) error {
+	tmp := path + ".sssonector"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+//go:build linux
+// +build linux
+
+package adapter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeDNSBackend records the servers set on each interface
+type fakeDNSBackend struct {
+	servers map[string][]string
+}
+
+func (b *fakeDNSBackend) Set(iface string, servers []string) error {
+	b.servers[iface] = servers
+	return nil
+}
+
+func (b *fakeDNSBackend) Restore(iface string) error {
+	delete(b.servers, iface)
+	return nil
+}
+
+func TestDNSManagerApplyRestore(t *testing.T) {
+	backend := &fakeDNSBackend{servers: map[string][]string{}}
+	m := &DNSManager{iface: "tun0", backend: backend}
+
+	if err := m.Apply(nil); err != nil {
+		t.Fatalf("Failed to apply no servers: %v", err)
+	}
+	if len(backend.servers) != 0 {
+		t.Errorf("Expected no servers to be set, got %v", backend.servers)
+	}
+
+	servers := []string{"10.0.0.53", "10.0.0.54"}
+	if err := m.Apply(servers); err != nil {
+		t.Fatalf("Failed to apply servers: %v", err)
+	}
+	if !reflect.DeepEqual(backend.servers["tun0"], servers) {
+		t.Errorf("Expected %v on tun0, got %v", servers, backend.servers["tun0"])
+	}
+
+	if err := m.Restore(); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if _, ok := backend.servers["tun0"]; ok {
+		t.Error("Expected tun0 servers to be restored")
+	}
+}
+
+func TestResolvedBackend(t *testing.T) {
+	var commands []string
+	b := &resolvedBackend{run: func(name string, args ...string) error {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return nil
+	}}
+
+	if err := b.Set("tun0", []string{"10.0.0.53"}); err != nil {
+		t.Fatalf("Failed to set servers: %v", err)
+	}
+	if err := b.Restore("tun0"); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+
+	expected := []string{"resolvectl dns tun0 10.0.0.53", "resolvectl revert tun0"}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected %v, got %v", expected, commands)
+	}
+}
+
+func TestResolvConfBackend(t *testing.T) {
+	dir := t.TempDir()
+	original := "# local resolver\nnameserver 192.168.1.1\nsearch example.com\noptions edns0\n"
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(dir, "resolv.conf")
+		if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+			t.Fatalf("Failed to write resolv.conf: %v", err)
+		}
+		b := &resolvConfBackend{path: path}
+
+		if err := b.Set("tun0", []string{"10.0.0.53"}); err != nil {
+			t.Fatalf("Failed to set servers: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		conf := string(data)
+		if !strings.Contains(conf, "nameserver 10.0.0.53\n") || strings.Contains(conf, "192.168.1.1") {
+			t.Errorf("Expected only the tunnel's nameserver, got %q", conf)
+		}
+		if !strings.Contains(conf, "search example.com\n") || !strings.Contains(conf, "options edns0\n") {
+			t.Errorf("Expected search and options to be kept, got %q", conf)
+		}
+
+		if err := b.Restore("tun0"); err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != original {
+			t.Errorf("Expected the original resolv.conf, got %q", data)
+		}
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		target := filepath.Join(dir, "stub-resolv.conf")
+		if err := os.WriteFile(target, []byte(original), 0644); err != nil {
+			t.Fatalf("Failed to write resolv.conf: %v", err)
+		}
+		path := filepath.Join(dir, "linked-resolv.conf")
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatalf("Failed to link resolv.conf: %v", err)
+		}
+		b := &resolvConfBackend{path: path}
+
+		if err := b.Set("tun0", []string{"10.0.0.53"}); err != nil {
+			t.Fatalf("Failed to set servers: %v", err)
+		}
+		if data, _ := os.ReadFile(target); string(data) != original {
+			t.Errorf("Expected the symlink target to be untouched, got %q", data)
+		}
+
+		if err := b.Restore("tun0"); err != nil {
+			t.Fatalf("Failed to restore: %v", err)
+		}
+		if link, err := os.Readlink(path); err != nil || link != target {
+			t.Errorf("Expected the symlink to %s to be restored, got %q (%v)", target, link, err)
+		}
+	})
+}
+
+func TestResolvConfBackendBackup(t *testing.T) {
+	dir := t.TempDir()
+	original := "nameserver 192.168.1.1\n"
+	path := filepath.Join(dir, "resolv.conf")
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write resolv.conf: %v", err)
+	}
+	backupPath := filepath.Join(dir, "state", dnsBackupFile)
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		t.Fatalf("Failed to create state directory: %v", err)
+	}
+
+	b := &resolvConfBackend{path: path, backupPath: backupPath}
+	if err := b.Set("tun0", []string{"10.0.0.53"}); err != nil {
+		t.Fatalf("Failed to set servers: %v", err)
+	}
+	if _, err := os.Stat(backupPath); err != nil {
+		t.Fatalf("Expected a backup while the servers are set: %v", err)
+	}
+
+	// A new process, as after a crash, restores the backup
+	restarted := &resolvConfBackend{path: path, backupPath: backupPath}
+	found, err := restarted.restoreBackup()
+	if err != nil || !found {
+		t.Fatalf("Expected the backup to be restored, got %v (%v)", found, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("Expected the original resolv.conf, got %q", data)
+	}
+	if _, err := os.Stat(backupPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the backup to be removed, got %v", err)
+	}
+
+	// Nothing to restore without a backup
+	if found, err := restarted.restoreBackup(); err != nil || found {
+		t.Errorf("Expected no backup, got %v (%v)", found, err)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !linux
+// +build !linux
+
+package adapter
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// DNSManager points the system resolver at the tunnel's DNS servers. It is
+// only supported on Linux.
+type DNSManager struct {
+	iface string
+}
+
+// NewDNSManager creates a DNS manager for the named interface
+func NewDNSManager(iface, stateDir string) *DNSManager {
+	return &DNSManager{iface: iface}
+}
+
+// RestoreDNSBackup does nothing, as no resolver configuration is ever saved
+func RestoreDNSBackup(stateDir string) (bool, error) {
+	return false, nil
+}
+
+// Apply fails if any servers are given
+func (m *DNSManager) Apply(servers []string) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	return fmt.Errorf("applying DNS servers is not supported on %s", runtime.GOOS)
+}
+
+// Restore does nothing, as Apply never changes the resolver configuration
+func (m *DNSManager) Restore() error {
+	return nil
+}
@@ -272,8 +272,15 @@ func (i *linuxInterface) IsUp() bool {
 	return i.isUp
 }
 
+// Cleanup removes the interface. It is idempotent and also removes an interface left
+// in the error state by a failed configuration.
 func (i *linuxInterface) Cleanup() error {
-	if !i.transitionState(StateReady, StateStopping) {
+	switch i.getState() {
+	case StateUninitialized, StateStopped:
+		return nil
+	}
+
+	if !i.transitionState(StateReady, StateStopping) && !i.transitionState(StateError, StateStopping) {
 		return ErrInvalidStateTransition
 	}
 
@@ -316,12 +323,14 @@ func (i *linuxInterface) performCleanup() error {
 			time.Sleep(time.Duration(i.opts.RetryDelay) * time.Millisecond)
 		}
 
-		// Delete the TUN interface
+		i.isUp = false
+	}
+
+	// Delete the TUN interface, even if configuration never brought it up
+	if _, err := os.Stat(fmt.Sprintf("/sys/class/net/%s", i.name)); err == nil {
 		if out, err := exec.Command("sudo", "ip", "tuntap", "del", "dev", i.name, "mode", "tun").CombinedOutput(); err != nil {
 			return fmt.Errorf("failed to delete TUN interface: %w (output: %s)", err, string(out))
 		}
-
-		i.isUp = false
 	}
 
 	return i.Close()
@@ -0,0 +1,27 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RouteConflict is a configured route whose destination is already routed
+// through another interface
+type RouteConflict struct {
+	Route string
+	Via   string
+}
+
+// RouteConflictError reports the configured routes that were not installed
+// because another route already covers their destination
+type RouteConflictError struct {
+	Conflicts []RouteConflict
+}
+
+func (e *RouteConflictError) Error() string {
+	conflicts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		conflicts[i] = fmt.Sprintf("%s (via %s)", c.Route, c.Via)
+	}
+	return "routes already exist: " + strings.Join(conflicts, ", ")
+}
@@ -0,0 +1,158 @@
+//go:build linux
+// +build linux
+
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+// routeHandle is the part of a netlink handle the route manager uses
+type routeHandle interface {
+	LinkByName(name string) (netlink.Link, error)
+	LinkByIndex(index int) (netlink.Link, error)
+	RouteList(link netlink.Link, family int) ([]netlink.Route, error)
+	RouteAdd(route *netlink.Route) error
+	RouteDel(route *netlink.Route) error
+}
+
+// RouteManager installs the configured routes through the tunnel interface
+// and removes them again. It only ever removes routes it added itself.
+type RouteManager struct {
+	iface  string
+	handle routeHandle
+
+	mu    sync.Mutex
+	added []netlink.Route
+}
+
+// NewRouteManager creates a route manager for the named interface
+func NewRouteManager(iface string) *RouteManager {
+	return &RouteManager{iface: iface, handle: &netlink.Handle{}}
+}
+
+// Apply routes each CIDR in routes through the interface, which must be up.
+// A destination that already has a route through another interface is left
+// alone and reported in a *RouteConflictError once the other routes are
+// installed; a destination already routed through the interface is skipped.
+func (m *RouteManager) Apply(routes []string) error {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link, err := m.handle.LinkByName(m.iface)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", m.iface, err)
+	}
+	existing, err := m.handle.RouteList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	var conflicts []RouteConflict
+	for _, cidr := range routes {
+		_, dst, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid route %s: %w", cidr, err)
+		}
+
+		if current, ok := findRoute(existing, dst); ok {
+			if current.LinkIndex != link.Attrs().Index {
+				conflicts = append(conflicts, RouteConflict{Route: cidr, Via: m.linkName(current.LinkIndex)})
+			}
+			continue
+		}
+
+		route := netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Dst:       dst,
+			Scope:     netlink.SCOPE_LINK,
+		}
+		if err := m.handle.RouteAdd(&route); err != nil {
+			return fmt.Errorf("failed to add route %s via %s: %w", cidr, m.iface, err)
+		}
+		m.added = append(m.added, route)
+	}
+
+	if len(conflicts) > 0 {
+		return &RouteConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// Remove removes the routes Apply added. Routes that are already gone, e.g.
+// with the interface, are not an error.
+func (m *RouteManager) Remove() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for i := range m.added {
+		route := m.added[i]
+		if err := m.handle.RouteDel(&route); err != nil && !isNoSuchRoute(err) {
+			errs = append(errs, fmt.Errorf("failed to remove route %s: %w", route.Dst, err))
+		}
+	}
+	m.added = nil
+	return errors.Join(errs...)
+}
+
+// Routes returns the destinations of the routes Apply added
+func (m *RouteManager) Routes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := make([]string, len(m.added))
+	for i, route := range m.added {
+		routes[i] = route.Dst.String()
+	}
+	return routes
+}
+
+// linkName names the interface with the given index for a conflict report
+func (m *RouteManager) linkName(index int) string {
+	if index == 0 {
+		return "a gateway"
+	}
+	link, err := m.handle.LinkByIndex(index)
+	if err != nil {
+		return fmt.Sprintf("interface %d", index)
+	}
+	return link.Attrs().Name
+}
+
+// findRoute returns the route in routes for exactly dst
+func findRoute(routes []netlink.Route, dst *net.IPNet) (netlink.Route, bool) {
+	for _, route := range routes {
+		if routeDst(route).String() == dst.String() {
+			return route, true
+		}
+	}
+	return netlink.Route{}, false
+}
+
+// routeDst returns the destination of route; netlink leaves it nil for a
+// default route
+func routeDst(route netlink.Route) *net.IPNet {
+	if route.Dst != nil {
+		return route.Dst
+	}
+	if route.Family == netlink.FAMILY_V6 {
+		return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 8*net.IPv6len)}
+	}
+	return &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 8*net.IPv4len)}
+}
+
+// isNoSuchRoute reports whether err means the route to delete does not exist
+func isNoSuchRoute(err error) bool {
+	return errors.Is(err, syscall.ESRCH)
+}
@@ -0,0 +1,147 @@
+//go:build linux
+// +build linux
+
+package adapter
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+// fakeRouteHandle is an in-memory routing table
+type fakeRouteHandle struct {
+	links  []netlink.Link
+	routes []netlink.Route
+}
+
+func (h *fakeRouteHandle) LinkByName(name string) (netlink.Link, error) {
+	for _, link := range h.links {
+		if link.Attrs().Name == name {
+			return link, nil
+		}
+	}
+	return nil, netlink.LinkNotFoundError{}
+}
+
+func (h *fakeRouteHandle) LinkByIndex(index int) (netlink.Link, error) {
+	for _, link := range h.links {
+		if link.Attrs().Index == index {
+			return link, nil
+		}
+	}
+	return nil, netlink.LinkNotFoundError{}
+}
+
+func (h *fakeRouteHandle) RouteList(link netlink.Link, family int) ([]netlink.Route, error) {
+	return append([]netlink.Route(nil), h.routes...), nil
+}
+
+func (h *fakeRouteHandle) RouteAdd(route *netlink.Route) error {
+	for _, r := range h.routes {
+		if r.Dst.String() == route.Dst.String() {
+			return syscall.EEXIST
+		}
+	}
+	h.routes = append(h.routes, *route)
+	return nil
+}
+
+func (h *fakeRouteHandle) RouteDel(route *netlink.Route) error {
+	for i, r := range h.routes {
+		if r.Dst.String() == route.Dst.String() && r.LinkIndex == route.LinkIndex {
+			h.routes = append(h.routes[:i], h.routes[i+1:]...)
+			return nil
+		}
+	}
+	return syscall.ESRCH
+}
+
+func (h *fakeRouteHandle) has(cidr string, index int) bool {
+	for _, r := range h.routes {
+		if r.Dst.String() == cidr && r.LinkIndex == index {
+			return true
+		}
+	}
+	return false
+}
+
+func mustCIDR(t *testing.T, cidr string) *net.IPNet {
+	_, dst, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", cidr, err)
+	}
+	return dst
+}
+
+func TestRouteManagerApplyRemove(t *testing.T) {
+	handle := &fakeRouteHandle{
+		links: []netlink.Link{
+			&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth0", Index: 2}},
+			&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "tun0", Index: 7}},
+		},
+	}
+	handle.routes = []netlink.Route{
+		{LinkIndex: 2, Dst: mustCIDR(t, "192.168.1.0/24")},
+		{LinkIndex: 7, Dst: mustCIDR(t, "10.0.0.0/24")},
+	}
+	m := &RouteManager{iface: "tun0", handle: handle}
+
+	err := m.Apply([]string{"10.1.0.0/16", "192.168.1.0/24", "10.0.0.0/24"})
+	var conflict *RouteConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a route conflict, got %v", err)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Route != "192.168.1.0/24" || conflict.Conflicts[0].Via != "eth0" {
+		t.Errorf("Expected 192.168.1.0/24 to conflict via eth0, got %+v", conflict.Conflicts)
+	}
+
+	if !handle.has("10.1.0.0/16", 7) {
+		t.Error("Expected 10.1.0.0/16 to be routed through tun0")
+	}
+	if !handle.has("192.168.1.0/24", 2) {
+		t.Error("Expected the conflicting route through eth0 to be left alone")
+	}
+	if routes := m.Routes(); len(routes) != 1 || routes[0] != "10.1.0.0/16" {
+		t.Errorf("Expected only 10.1.0.0/16 to be tracked, got %v", routes)
+	}
+
+	if err := m.Remove(); err != nil {
+		t.Fatalf("Failed to remove routes: %v", err)
+	}
+	if handle.has("10.1.0.0/16", 7) {
+		t.Error("Expected 10.1.0.0/16 to be removed")
+	}
+	if !handle.has("10.0.0.0/24", 7) || !handle.has("192.168.1.0/24", 2) {
+		t.Error("Expected routes the manager did not add to remain")
+	}
+
+	// A route removed along with the interface is not an error
+	if err := m.Apply([]string{"10.2.0.0/16"}); err != nil {
+		t.Fatalf("Failed to apply route: %v", err)
+	}
+	handle.routes = nil
+	if err := m.Remove(); err != nil {
+		t.Errorf("Expected removing a vanished route to succeed, got %v", err)
+	}
+}
+
+func TestRouteManagerDefaultRoute(t *testing.T) {
+	handle := &fakeRouteHandle{
+		links: []netlink.Link{&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "tun0", Index: 7}}},
+		// netlink reports the default route without a destination
+		routes: []netlink.Route{{LinkIndex: 0, Family: netlink.FAMILY_V4}},
+	}
+	m := &RouteManager{iface: "tun0", handle: handle}
+
+	var conflict *RouteConflictError
+	if err := m.Apply([]string{"0.0.0.0/0"}); !errors.As(err, &conflict) {
+		t.Fatalf("Expected the default route to conflict, got %v", err)
+	}
+	if len(m.Routes()) != 0 {
+		t.Errorf("Expected no routes to be added, got %v", m.Routes())
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !linux
+// +build !linux
+
+package adapter
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// RouteManager installs the configured routes through the tunnel interface.
+// Routes are only supported on Linux.
+type RouteManager struct {
+	iface string
+}
+
+// NewRouteManager creates a route manager for the named interface
+func NewRouteManager(iface string) *RouteManager {
+	return &RouteManager{iface: iface}
+}
+
+// Apply fails if any routes are configured
+func (m *RouteManager) Apply(routes []string) error {
+	if len(routes) == 0 {
+		return nil
+	}
+	return fmt.Errorf("routes are not supported on %s", runtime.GOOS)
+}
+
+// Remove does nothing, as Apply never adds routes
+func (m *RouteManager) Remove() error {
+	return nil
+}
+
+// Routes returns no routes, as Apply never adds any
+func (m *RouteManager) Routes() []string {
+	return nil
+}
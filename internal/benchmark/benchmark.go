@@ -0,0 +1,308 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode selects the workload a benchmark drives
+type Mode string
+
+const (
+	// ModeEcho sends small payloads one at a time and measures round-trip latency
+	ModeEcho Mode = "echo"
+	// ModeThroughput streams large payloads and measures MB/s
+	ModeThroughput Mode = "throughput"
+	// ModeReqResp sends requests at the configured rate and measures latency
+	// percentiles under load
+	ModeReqResp Mode = "reqresp"
+)
+
+// Default payload sizes per mode
+const (
+	DefaultEchoPayload       = 64
+	DefaultThroughputPayload = 64 * 1024
+	DefaultReqRespPayload    = 1024
+)
+
+// DefaultDialTimeout bounds connecting to the target when none is configured
+const DefaultDialTimeout = 5 * time.Second
+
+// Config describes a benchmark run against an echoing host and port
+type Config struct {
+	Host        string
+	Port        int
+	Mode        Mode          // Empty selects echo
+	Concurrency int           // Connections driven in parallel; 0 means 1
+	Rate        int           // Requests per second across all connections in reqresp mode; 0 is unlimited
+	Duration    time.Duration // How long to measure the target
+	Warmup      time.Duration // How long to drive the target before measuring
+	PayloadSize int           // Bytes per message; 0 uses the mode's default
+	DialTimeout time.Duration
+}
+
+// Result holds the metrics of a benchmark run. Latency is recorded in echo and
+// reqresp modes, throughput in all modes. Requests started during the warmup
+// are left out of every metric except Errors.
+type Result struct {
+	Mode     Mode          `json:"mode"`
+	Duration time.Duration `json:"duration_ns"` // Measured window, excluding the warmup
+	Warmup   time.Duration `json:"warmup_ns"`
+	Requests int64         `json:"requests"` // Completed round trips; chunks echoed in throughput mode
+	Errors   int64         `json:"errors"`
+	Bytes    int64         `json:"bytes"` // Bytes echoed back by the target
+
+	WarmupRequests int64 `json:"warmup_requests"` // Requests excluded as part of the warmup
+
+	Latency LatencyStats `json:"latency"`
+
+	ThroughputMBps float64 `json:"throughput_mbps"` // Echoed megabytes (10^6 bytes) per second
+}
+
+// String formats the result for display
+func (r *Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Mode:       %s\n", r.Mode)
+	fmt.Fprintf(&b, "Duration:   %s\n", r.Duration.Round(time.Millisecond))
+	if r.Warmup > 0 {
+		fmt.Fprintf(&b, "Warmup:     %s (%d requests excluded)\n", r.Warmup, r.WarmupRequests)
+	}
+	fmt.Fprintf(&b, "Requests:   %d\n", r.Requests)
+	fmt.Fprintf(&b, "Errors:     %d\n", r.Errors)
+	fmt.Fprintf(&b, "Throughput: %.2f MB/s\n", r.ThroughputMBps)
+	if r.Latency.Count > 0 {
+		l := r.Latency
+		fmt.Fprintf(&b, "Latency:    mean %s  p50 %s  p90 %s  p95 %s  p99 %s  p99.9 %s  max %s\n",
+			l.Mean, l.P50, l.P90, l.P95, l.P99, l.P999, l.Max)
+	}
+	return b.String()
+}
+
+// worker drives one connection and records what it measured
+type worker struct {
+	bytes     int64
+	requests  int64
+	errors    int64
+	latencies *LatencyRecorder // Shared by all workers
+
+	measureFrom time.Time // End of the warmup; earlier requests are not measured
+	warmup      int64     // Requests excluded as part of the warmup
+}
+
+// Run drives the target for the configured duration
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeEcho
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = DefaultDialTimeout
+	}
+	if cfg.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if cfg.Warmup < 0 {
+		return nil, fmt.Errorf("warmup must not be negative")
+	}
+
+	var run func(ctx context.Context, conn net.Conn, w *worker, payload []byte, tokens <-chan struct{})
+	switch cfg.Mode {
+	case ModeEcho:
+		run = runRoundTrips
+		cfg.PayloadSize = orDefault(cfg.PayloadSize, DefaultEchoPayload)
+	case ModeReqResp:
+		run = runRoundTrips
+		cfg.PayloadSize = orDefault(cfg.PayloadSize, DefaultReqRespPayload)
+	case ModeThroughput:
+		run = runStream
+		cfg.PayloadSize = orDefault(cfg.PayloadSize, DefaultThroughputPayload)
+	default:
+		return nil, fmt.Errorf("unknown benchmark mode: %s", cfg.Mode)
+	}
+
+	address := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	conns := make([]net.Conn, 0, cfg.Concurrency)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	dialer := net.Dialer{Timeout: cfg.DialTimeout}
+	for i := 0; i < cfg.Concurrency; i++ {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+		}
+		conns = append(conns, conn)
+	}
+
+	// Time the run from when every connection is up, so that slow dials eat
+	// into neither the warmup nor the measurement
+	measureFrom := time.Now().Add(cfg.Warmup)
+	ctx, cancel := context.WithDeadline(ctx, measureFrom.Add(cfg.Duration))
+	defer cancel()
+
+	var tokens <-chan struct{}
+	if cfg.Mode == ModeReqResp && cfg.Rate > 0 {
+		tokens = pace(ctx, cfg.Rate)
+	}
+
+	payload := make([]byte, cfg.PayloadSize)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	latencies := NewLatencyRecorder()
+	workers := make([]*worker, len(conns))
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		workers[i] = &worker{latencies: latencies, measureFrom: measureFrom}
+		wg.Add(1)
+		go func(conn net.Conn, w *worker) {
+			defer wg.Done()
+			run(ctx, conn, w, payload, tokens)
+		}(conn, workers[i])
+	}
+	wg.Wait()
+
+	elapsed := time.Since(measureFrom)
+	if elapsed < 0 { // Interrupted during the warmup
+		elapsed = 0
+	}
+	result := summarize(cfg.Mode, elapsed, workers, latencies)
+	result.Warmup = cfg.Warmup
+	return result, nil
+}
+
+// runRoundTrips sends the payload and waits for it to be echoed, one request at
+// a time, until ctx is done. With tokens, each request waits for a token.
+func runRoundTrips(ctx context.Context, conn net.Conn, w *worker, payload []byte, tokens <-chan struct{}) {
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	reply := make([]byte, len(payload))
+
+	for ctx.Err() == nil {
+		if tokens != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tokens:
+			}
+		}
+
+		start := time.Now()
+		if _, err := conn.Write(payload); err != nil {
+			w.fail(ctx, err)
+			return
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			w.fail(ctx, err)
+			return
+		}
+		if start.Before(w.measureFrom) {
+			w.warmup++
+			continue
+		}
+		w.latencies.Record(time.Since(start))
+		w.requests++
+		w.bytes += int64(len(reply))
+	}
+}
+
+// runStream writes the payload back to back until ctx is done while counting
+// the echoed bytes. Bytes echoed during the warmup are counted separately.
+func runStream(ctx context.Context, conn net.Conn, w *worker, payload []byte, _ <-chan struct{}) {
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	var received, warmup int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, len(payload))
+		for {
+			n, err := conn.Read(buf)
+			if time.Now().Before(w.measureFrom) {
+				warmup += int64(n)
+			} else {
+				received += int64(n)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for ctx.Err() == nil {
+		if _, err := conn.Write(payload); err != nil {
+			w.fail(ctx, err)
+			break
+		}
+	}
+	<-done
+
+	w.bytes = received
+	w.requests = w.bytes / int64(len(payload))
+	w.warmup = warmup / int64(len(payload))
+}
+
+// fail counts err unless it was caused by the run ending
+func (w *worker) fail(ctx context.Context, err error) {
+	if ctx.Err() == nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		w.errors++
+	}
+}
+
+// pace returns a channel that yields rate tokens per second until ctx is done
+func pace(ctx context.Context, rate int) <-chan struct{} {
+	tokens := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				select {
+				case tokens <- struct{}{}:
+				default: // Every connection is busy; the request is dropped
+				}
+			}
+		}
+	}()
+	return tokens
+}
+
+// summarize merges the workers' measurements
+func summarize(mode Mode, elapsed time.Duration, workers []*worker, latencies *LatencyRecorder) *Result {
+	result := &Result{Mode: mode, Duration: elapsed, Latency: latencies.Stats()}
+	for _, w := range workers {
+		result.Requests += w.requests
+		result.Errors += w.errors
+		result.Bytes += w.bytes
+		result.WarmupRequests += w.warmup
+	}
+	if elapsed > 0 {
+		result.ThroughputMBps = float64(result.Bytes) / 1e6 / elapsed.Seconds()
+	}
+	return result
+}
+
+// orDefault returns v, or def when v is not positive
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
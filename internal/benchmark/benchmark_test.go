@@ -0,0 +1,190 @@
+package benchmark
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startEchoServer starts a loopback server echoing everything it reads
+func startEchoServer(t *testing.T) (string, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func checkLatencies(t *testing.T, result *Result) {
+	t.Helper()
+	l := result.Latency
+	if l.Count != uint64(result.Requests) {
+		t.Errorf("Expected a latency for each of %d requests, got %d", result.Requests, l.Count)
+	}
+	if l.P50 <= 0 {
+		t.Errorf("Expected a positive p50 latency, got %s", l.P50)
+	}
+	if l.P50 > l.P90 || l.P90 > l.P99 || l.P99 > l.P999 || l.P999 > l.Max {
+		t.Errorf("Expected ordered percentiles, got %+v", l)
+	}
+	if !strings.Contains(result.String(), "p99.9") {
+		t.Errorf("Expected the latency percentiles in the summary, got %q", result.String())
+	}
+}
+
+func TestRunEcho(t *testing.T) {
+	host, port := startEchoServer(t)
+
+	result, err := Run(context.Background(), Config{
+		Host:        host,
+		Port:        port,
+		Mode:        ModeEcho,
+		Concurrency: 2,
+		Duration:    200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run benchmark: %v", err)
+	}
+
+	if result.Requests == 0 || result.Errors != 0 {
+		t.Fatalf("Expected requests without errors, got %d requests and %d errors", result.Requests, result.Errors)
+	}
+	if result.Bytes != result.Requests*DefaultEchoPayload {
+		t.Errorf("Expected %d echoed bytes, got %d", result.Requests*DefaultEchoPayload, result.Bytes)
+	}
+	checkLatencies(t, result)
+}
+
+func TestRunThroughput(t *testing.T) {
+	host, port := startEchoServer(t)
+
+	result, err := Run(context.Background(), Config{
+		Host:        host,
+		Port:        port,
+		Mode:        ModeThroughput,
+		Duration:    200 * time.Millisecond,
+		PayloadSize: 32 * 1024,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run benchmark: %v", err)
+	}
+
+	if result.Bytes == 0 || result.Errors != 0 {
+		t.Fatalf("Expected echoed bytes without errors, got %d bytes and %d errors", result.Bytes, result.Errors)
+	}
+	if result.Requests != result.Bytes/(32*1024) {
+		t.Errorf("Expected %d echoed chunks, got %d", result.Bytes/(32*1024), result.Requests)
+	}
+	want := float64(result.Bytes) / 1e6 / result.Duration.Seconds()
+	if result.ThroughputMBps <= 0 || result.ThroughputMBps != want {
+		t.Errorf("Expected %.2f MB/s, got %.2f", want, result.ThroughputMBps)
+	}
+}
+
+func TestRunReqRespRate(t *testing.T) {
+	host, port := startEchoServer(t)
+
+	result, err := Run(context.Background(), Config{
+		Host:        host,
+		Port:        port,
+		Mode:        ModeReqResp,
+		Concurrency: 4,
+		Rate:        100,
+		Duration:    500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run benchmark: %v", err)
+	}
+
+	// 100 requests per second for half a second, less scheduling slack
+	if result.Requests < 25 || result.Requests > 50 {
+		t.Errorf("Expected about 50 paced requests, got %d", result.Requests)
+	}
+	if result.Errors != 0 {
+		t.Errorf("Expected no errors, got %d", result.Errors)
+	}
+	if result.Bytes != result.Requests*DefaultReqRespPayload {
+		t.Errorf("Expected %d echoed bytes, got %d", result.Requests*DefaultReqRespPayload, result.Bytes)
+	}
+	checkLatencies(t, result)
+}
+
+func TestRunWarmupExcluded(t *testing.T) {
+	host, port := startEchoServer(t)
+
+	result, err := Run(context.Background(), Config{
+		Host:        host,
+		Port:        port,
+		Mode:        ModeReqResp,
+		Concurrency: 2,
+		Rate:        100,
+		Warmup:      300 * time.Millisecond,
+		Duration:    300 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run benchmark: %v", err)
+	}
+
+	// The rate applies to the warmup too: about 30 requests in each phase
+	if result.WarmupRequests < 15 || result.WarmupRequests > 31 {
+		t.Errorf("Expected about 30 paced warmup requests, got %d", result.WarmupRequests)
+	}
+	if result.Requests < 15 || result.Requests > 31 {
+		t.Errorf("Expected about 30 measured requests, got %d", result.Requests)
+	}
+	if result.Bytes != result.Requests*DefaultReqRespPayload {
+		t.Errorf("Expected only measured requests in the bytes, got %d for %d requests", result.Bytes, result.Requests)
+	}
+	if result.Warmup != 300*time.Millisecond || result.Duration < 300*time.Millisecond || result.Duration > 400*time.Millisecond {
+		t.Errorf("Expected a 300ms warmup and measured window, got %s and %s", result.Warmup, result.Duration)
+	}
+	checkLatencies(t, result)
+
+	throughput, err := Run(context.Background(), Config{
+		Host:     host,
+		Port:     port,
+		Mode:     ModeThroughput,
+		Warmup:   100 * time.Millisecond,
+		Duration: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run benchmark: %v", err)
+	}
+	if throughput.WarmupRequests == 0 || throughput.Requests == 0 {
+		t.Errorf("Expected chunks in both phases, got %d warmup and %d measured", throughput.WarmupRequests, throughput.Requests)
+	}
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	host, port := startEchoServer(t)
+
+	if _, err := Run(context.Background(), Config{Host: host, Port: port, Mode: "bogus", Duration: time.Second}); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+	if _, err := Run(context.Background(), Config{Host: host, Port: port}); err == nil {
+		t.Error("Expected an error for a missing duration")
+	}
+	if _, err := Run(context.Background(), Config{Host: host, Port: port, Duration: time.Second, Warmup: -time.Second}); err == nil {
+		t.Error("Expected an error for a negative warmup")
+	}
+}
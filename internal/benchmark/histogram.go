@@ -0,0 +1,127 @@
+package benchmark
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Histogram layout: values below subBuckets nanoseconds are counted exactly;
+// larger values keep their top subBits+1 significant bits, so each bucket spans
+// at most 1/subBuckets (about 1.6%) of its value
+const (
+	subBits    = 6
+	subBuckets = 1 << subBits
+	numBuckets = subBuckets + (64-subBits-1)*subBuckets
+)
+
+// LatencyRecorder is an HDR-style latency histogram. Record is lock-free, so
+// all workers of a run can share one recorder.
+type LatencyRecorder struct {
+	counts [numBuckets]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Uint64 // Nanoseconds
+	max    atomic.Int64  // Nanoseconds
+}
+
+// LatencyStats summarizes recorded latencies. Percentiles are accurate to
+// within about 1.6%; Max is exact.
+type LatencyStats struct {
+	Count uint64        `json:"count"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P95   time.Duration `json:"p95_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	P999  time.Duration `json:"p99_9_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// NewLatencyRecorder creates an empty recorder
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{}
+}
+
+// Record adds one latency; negative latencies count as zero
+func (r *LatencyRecorder) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	r.counts[bucketIndex(uint64(d))].Add(1)
+	r.count.Add(1)
+	r.sum.Add(uint64(d))
+	for {
+		max := r.max.Load()
+		if int64(d) <= max || r.max.CompareAndSwap(max, int64(d)) {
+			return
+		}
+	}
+}
+
+// Count returns the number of recorded latencies
+func (r *LatencyRecorder) Count() uint64 {
+	return r.count.Load()
+}
+
+// Percentile returns the latency below which the fraction q (0 to 1) of the
+// recorded latencies fall, or 0 if none were recorded
+func (r *LatencyRecorder) Percentile(q float64) time.Duration {
+	total := r.count.Load()
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	max := time.Duration(r.max.Load())
+	var seen uint64
+	for i := range r.counts {
+		seen += r.counts[i].Load()
+		if seen >= rank {
+			if d := bucketValue(i); d < max {
+				return d
+			}
+			return max
+		}
+	}
+	return max
+}
+
+// Stats summarizes the recorded latencies
+func (r *LatencyRecorder) Stats() LatencyStats {
+	stats := LatencyStats{
+		Count: r.count.Load(),
+		P50:   r.Percentile(0.50),
+		P90:   r.Percentile(0.90),
+		P95:   r.Percentile(0.95),
+		P99:   r.Percentile(0.99),
+		P999:  r.Percentile(0.999),
+		Max:   time.Duration(r.max.Load()),
+	}
+	if stats.Count > 0 {
+		stats.Mean = time.Duration(r.sum.Load() / stats.Count)
+	}
+	return stats
+}
+
+// bucketIndex returns the bucket counting the value v
+func bucketIndex(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBits - 1
+	return subBuckets + shift*subBuckets + int(v>>shift) - subBuckets
+}
+
+// bucketValue returns the midpoint of the values counted by bucket i
+func bucketValue(i int) time.Duration {
+	if i < subBuckets {
+		return time.Duration(i)
+	}
+	shift := (i - subBuckets) / subBuckets
+	lower := uint64(subBuckets+(i-subBuckets)%subBuckets) << shift
+	return time.Duration(lower + (uint64(1)<<shift)/2)
+}
@@ -0,0 +1,113 @@
+package benchmark
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// within reports whether got is within the histogram's relative error of want
+func within(got, want time.Duration) bool {
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= want/50
+}
+
+func TestLatencyRecorderPercentiles(t *testing.T) {
+	r := NewLatencyRecorder()
+
+	// 1ms to 10s in 1ms steps, recorded concurrently in shuffled order
+	const n = 10000
+	values := rand.New(rand.NewSource(1)).Perm(n)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < n; i += 8 {
+				r.Record(time.Duration(values[i]+1) * time.Millisecond)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	stats := r.Stats()
+	if stats.Count != n {
+		t.Fatalf("Expected %d latencies, got %d", n, stats.Count)
+	}
+	if stats.Max != n*time.Millisecond {
+		t.Errorf("Expected an exact max of %s, got %s", n*time.Millisecond, stats.Max)
+	}
+	if want := (n + 1) * time.Millisecond / 2; stats.Mean != want {
+		t.Errorf("Expected an exact mean of %s, got %s", want, stats.Mean)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"p50", stats.P50, 5000 * time.Millisecond},
+		{"p90", stats.P90, 9000 * time.Millisecond},
+		{"p95", stats.P95, 9500 * time.Millisecond},
+		{"p99", stats.P99, 9900 * time.Millisecond},
+		{"p99.9", stats.P999, 9990 * time.Millisecond},
+	} {
+		if !within(tc.got, tc.want) {
+			t.Errorf("Expected %s near %s, got %s", tc.name, tc.want, tc.got)
+		}
+	}
+}
+
+func TestLatencyRecorderTail(t *testing.T) {
+	r := NewLatencyRecorder()
+
+	// 990 fast requests and a slow tail of 10
+	for i := 0; i < 990; i++ {
+		r.Record(200 * time.Microsecond)
+	}
+	for i := 0; i < 10; i++ {
+		r.Record(time.Duration(50+i) * time.Millisecond)
+	}
+
+	stats := r.Stats()
+	if !within(stats.P50, 200*time.Microsecond) || !within(stats.P99, 200*time.Microsecond) {
+		t.Errorf("Expected p50 and p99 near 200µs, got %s and %s", stats.P50, stats.P99)
+	}
+	if !within(stats.P999, 59*time.Millisecond) {
+		t.Errorf("Expected p99.9 in the slow tail near 59ms, got %s", stats.P999)
+	}
+	if stats.Max != 59*time.Millisecond {
+		t.Errorf("Expected a max of 59ms, got %s", stats.Max)
+	}
+}
+
+func TestLatencyRecorderEmptyAndSmall(t *testing.T) {
+	r := NewLatencyRecorder()
+	if stats := r.Stats(); stats != (LatencyStats{}) {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+
+	// Values below the sub-bucket count are exact
+	r.Record(-time.Nanosecond)
+	r.Record(7 * time.Nanosecond)
+	if got := r.Percentile(1); got != 7*time.Nanosecond {
+		t.Errorf("Expected an exact 7ns, got %s", got)
+	}
+	if got := r.Percentile(0.5); got != 0 {
+		t.Errorf("Expected a negative latency to count as 0, got %s", got)
+	}
+}
+
+func TestBucketBounds(t *testing.T) {
+	for _, v := range []uint64{0, 63, 64, 127, 128, 1000, 123456789, 1<<63 - 1} {
+		i := bucketIndex(v)
+		if i < 0 || i >= numBuckets {
+			t.Fatalf("Value %d maps outside the histogram: bucket %d", v, i)
+		}
+		if d := time.Duration(v); !within(bucketValue(i), d) && v >= subBuckets {
+			t.Errorf("Expected bucket %d of %d to be near it, got %d", i, v, bucketValue(i))
+		}
+	}
+}
@@ -1,34 +1,44 @@
 package cert
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
 	"math/big"
+	"net"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/cert/keys"
 )
 
 // CertificateGenerator handles the generation of SSL certificates
 type CertificateGenerator struct {
 	outputDir string
+	keyType   KeyType
 }
 
 // NewCertificateGenerator creates a new certificate generator
 func NewCertificateGenerator(outputDir string) *CertificateGenerator {
 	return &CertificateGenerator{
 		outputDir: outputDir,
+		keyType:   KeyTypeRSA,
 	}
 }
 
+// SetKeyType sets the private key algorithm for generated certificates
+func (g *CertificateGenerator) SetKeyType(keyType KeyType) {
+	g.keyType = keyType
+}
+
 // GenerateCA generates a new CA certificate and private key
 func (g *CertificateGenerator) GenerateCA() error {
 	// Generate CA private key
-	caKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	caKey, err := keys.Generate(g.keyType, rsaKeySize)
 	if err != nil {
 		return fmt.Errorf("failed to generate CA private key: %v", err)
 	}
@@ -47,7 +57,7 @@ func (g *CertificateGenerator) GenerateCA() error {
 	}
 
 	// Create CA certificate
-	caCertBytes, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	caCertBytes, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
 	if err != nil {
 		return fmt.Errorf("failed to create CA certificate: %v", err)
 	}
@@ -72,7 +82,11 @@ func (g *CertificateGenerator) GenerateCA() error {
 	}
 	defer keyOut.Close()
 
-	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caKey)}); err != nil {
+	caKeyBlock, err := keys.Encode(caKey)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(keyOut, caKeyBlock); err != nil {
 		return fmt.Errorf("failed to write CA private key: %v", err)
 	}
 
@@ -80,7 +94,7 @@ func (g *CertificateGenerator) GenerateCA() error {
 }
 
 // generateCertificate generates a new certificate signed by the CA
-func (g *CertificateGenerator) generateCertificate(name, certFile, keyFile string, isServer bool) error {
+func (g *CertificateGenerator) generateCertificate(name, certFile, keyFile string, isServer bool, dnsNames []string, ipAddresses []net.IP) error {
 	// Load CA certificate and private key
 	caCertBytes, err := os.ReadFile(filepath.Join(g.outputDir, "ca.crt"))
 	if err != nil {
@@ -107,13 +121,13 @@ func (g *CertificateGenerator) generateCertificate(name, certFile, keyFile strin
 		return fmt.Errorf("failed to decode CA private key")
 	}
 
-	caKey, err := x509.ParsePKCS1PrivateKey(caKeyBlock.Bytes)
+	caKey, err := keys.Parse(caKeyBlock)
 	if err != nil {
 		return fmt.Errorf("failed to parse CA private key: %v", err)
 	}
 
 	// Generate certificate private key
-	certKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	certKey, err := keys.Generate(g.keyType, rsaKeySize)
 	if err != nil {
 		return fmt.Errorf("failed to generate certificate private key: %v", err)
 	}
@@ -126,9 +140,11 @@ func (g *CertificateGenerator) generateCertificate(name, certFile, keyFile strin
 		},
 		NotBefore:             time.Now(),
 		NotAfter:              time.Now().AddDate(1, 0, 0), // 1 year validity
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		KeyUsage:              keys.LeafUsage(certKey),
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
 	}
 
 	if isServer {
@@ -136,7 +152,7 @@ func (g *CertificateGenerator) generateCertificate(name, certFile, keyFile strin
 	}
 
 	// Create certificate
-	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, caCert, &certKey.PublicKey, caKey)
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, caCert, certKey.Public(), caKey)
 	if err != nil {
 		return fmt.Errorf("failed to create certificate: %v", err)
 	}
@@ -159,27 +175,47 @@ func (g *CertificateGenerator) generateCertificate(name, certFile, keyFile strin
 	}
 	defer keyOut.Close()
 
-	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(certKey)}); err != nil {
+	keyBlock, err := keys.Encode(certKey)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(keyOut, keyBlock); err != nil {
 		return fmt.Errorf("failed to write private key: %v", err)
 	}
 
 	return nil
 }
 
-// GenerateServerCert generates a new server certificate signed by the CA
+// GenerateServerCert generates a new server certificate signed by the CA, valid for localhost
 func (g *CertificateGenerator) GenerateServerCert() error {
-	return g.generateCertificate("sssonector-server", "server.crt", "server.key", true)
+	return g.GenerateServerCertWithSANs("sssonector-server", nil, nil)
+}
+
+// GenerateServerCertWithSANs generates a new server certificate signed by the CA with the
+// given subject alternative names. Without any names the certificate covers localhost and 127.0.0.1.
+func (g *CertificateGenerator) GenerateServerCertWithSANs(cn string, dns []string, ips []net.IP) error {
+	if len(dns) == 0 && len(ips) == 0 {
+		dns = []string{"localhost"}
+		ips = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+	return g.generateCertificate(cn, "server.crt", "server.key", true, dns, ips)
 }
 
 // GenerateClientCert generates a new client certificate signed by the CA
 func (g *CertificateGenerator) GenerateClientCert() error {
-	return g.generateCertificate("sssonector-client", "client.crt", "client.key", false)
+	return g.GenerateClientCertWithSANs("sssonector-client", nil, nil)
+}
+
+// GenerateClientCertWithSANs generates a new client certificate signed by the CA with the
+// given subject alternative names
+func (g *CertificateGenerator) GenerateClientCertWithSANs(cn string, dns []string, ips []net.IP) error {
+	return g.generateCertificate(cn, "client.crt", "client.key", false, dns, ips)
 }
 
 // GenerateTestCerts generates temporary certificates for testing
 func (g *CertificateGenerator) GenerateTestCerts() error {
 	// Generate test CA private key
-	caKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	caKey, err := keys.Generate(g.keyType, rsaKeySize)
 	if err != nil {
 		return fmt.Errorf("failed to generate test CA private key: %v", err)
 	}
@@ -198,7 +234,7 @@ func (g *CertificateGenerator) GenerateTestCerts() error {
 	}
 
 	// Create test CA certificate
-	caCertBytes, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	caCertBytes, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
 	if err != nil {
 		return fmt.Errorf("failed to create test CA certificate: %v", err)
 	}
@@ -223,7 +259,11 @@ func (g *CertificateGenerator) GenerateTestCerts() error {
 	}
 	defer keyOut.Close()
 
-	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caKey)}); err != nil {
+	caKeyBlock, err := keys.Encode(caKey)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(keyOut, caKeyBlock); err != nil {
 		return fmt.Errorf("failed to write test CA private key: %v", err)
 	}
 
@@ -240,8 +280,8 @@ func (g *CertificateGenerator) GenerateTestCerts() error {
 }
 
 // generateTestCertificate generates a test certificate with 15-second validity
-func (g *CertificateGenerator) generateTestCertificate(name, certFile, keyFile string, isServer bool, caTemplate *x509.Certificate, caKey *rsa.PrivateKey) error {
-	certKey, err := rsa.GenerateKey(rand.Reader, 4096)
+func (g *CertificateGenerator) generateTestCertificate(name, certFile, keyFile string, isServer bool, caTemplate *x509.Certificate, caKey crypto.Signer) error {
+	certKey, err := keys.Generate(g.keyType, rsaKeySize)
 	if err != nil {
 		return fmt.Errorf("failed to generate test certificate private key: %v", err)
 	}
@@ -253,7 +293,7 @@ func (g *CertificateGenerator) generateTestCertificate(name, certFile, keyFile s
 		},
 		NotBefore:             time.Now(),
 		NotAfter:              time.Now().Add(15 * time.Second),
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		KeyUsage:              keys.LeafUsage(certKey),
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
 	}
@@ -262,7 +302,7 @@ func (g *CertificateGenerator) generateTestCertificate(name, certFile, keyFile s
 		certTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
 	}
 
-	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, caTemplate, &certKey.PublicKey, caKey)
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, caTemplate, certKey.Public(), caKey)
 	if err != nil {
 		return fmt.Errorf("failed to create test certificate: %v", err)
 	}
@@ -283,7 +323,11 @@ func (g *CertificateGenerator) generateTestCertificate(name, certFile, keyFile s
 	}
 	defer keyOut.Close()
 
-	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(certKey)}); err != nil {
+	keyBlock, err := keys.Encode(certKey)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(keyOut, keyBlock); err != nil {
 		return fmt.Errorf("failed to write test private key: %v", err)
 	}
 
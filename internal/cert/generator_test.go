@@ -0,0 +1,110 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readGeneratedCert parses a PEM certificate written by the generator
+func readGeneratedCert(t *testing.T, path string) *x509.Certificate {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read certificate: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("Failed to decode certificate %s", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestGenerateServerCertSANs(t *testing.T) {
+	tempDir := t.TempDir()
+	g := NewCertificateGenerator(tempDir)
+
+	if err := g.GenerateCA(); err != nil {
+		t.Fatalf("Failed to generate CA: %v", err)
+	}
+
+	// Default server certificate covers localhost
+	if err := g.GenerateServerCert(); err != nil {
+		t.Fatalf("Failed to generate server certificate: %v", err)
+	}
+	cert := readGeneratedCert(t, filepath.Join(tempDir, "server.crt"))
+	if err := cert.VerifyHostname("localhost"); err != nil {
+		t.Errorf("Expected certificate valid for localhost: %v", err)
+	}
+	if err := cert.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("Expected certificate valid for 127.0.0.1: %v", err)
+	}
+
+	// Explicit SANs replace the defaults
+	ip := net.ParseIP("192.168.50.210")
+	if err := g.GenerateServerCertWithSANs("vpn.example.com", []string{"vpn.example.com"}, []net.IP{ip}); err != nil {
+		t.Fatalf("Failed to generate server certificate: %v", err)
+	}
+	cert = readGeneratedCert(t, filepath.Join(tempDir, "server.crt"))
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "vpn.example.com" {
+		t.Errorf("Expected DNS names [vpn.example.com], got %v", cert.DNSNames)
+	}
+	if len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(ip) {
+		t.Errorf("Expected IP addresses [%s], got %v", ip, cert.IPAddresses)
+	}
+	if err := cert.VerifyHostname("localhost"); err == nil {
+		t.Error("Expected certificate not to be valid for localhost")
+	}
+}
+
+func TestGenerateKeyTypes(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeRSA, KeyTypeECDSAP256, KeyTypeEd25519} {
+		t.Run(string(keyType), func(t *testing.T) {
+			tempDir := t.TempDir()
+			g := NewCertificateGenerator(tempDir)
+			g.SetKeyType(keyType)
+
+			if err := g.GenerateCA(); err != nil {
+				t.Fatalf("Failed to generate CA: %v", err)
+			}
+			if err := g.GenerateServerCert(); err != nil {
+				t.Fatalf("Failed to generate server certificate: %v", err)
+			}
+			if err := g.GenerateClientCert(); err != nil {
+				t.Fatalf("Failed to generate client certificate: %v", err)
+			}
+
+			caPath := filepath.Join(tempDir, "ca.crt")
+			serverCert := filepath.Join(tempDir, "server.crt")
+			serverKey := filepath.Join(tempDir, "server.key")
+
+			// Validates the key pair and the signature chain against the CA
+			if err := NewCertificateLocator(tempDir).ValidateCertificates(serverCert, serverKey, caPath); err != nil {
+				t.Errorf("Failed to validate server certificate: %v", err)
+			}
+			if _, err := tls.LoadX509KeyPair(serverCert, serverKey); err != nil {
+				t.Errorf("Failed to load server key pair: %v", err)
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(readGeneratedCert(t, caPath))
+			client := readGeneratedCert(t, filepath.Join(tempDir, "client.crt"))
+			if _, err := client.Verify(x509.VerifyOptions{
+				Roots:     roots,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}); err != nil {
+				t.Errorf("Failed to verify client certificate: %v", err)
+			}
+			if _, err := tls.LoadX509KeyPair(filepath.Join(tempDir, "client.crt"), filepath.Join(tempDir, "client.key")); err != nil {
+				t.Errorf("Failed to load client key pair: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package keys generates, encodes and parses the private keys of certificates
+// for every supported key algorithm
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// Type represents the private key algorithm of a certificate
+type Type string
+
+const (
+	TypeRSA       Type = "RSA"
+	TypeECDSAP256 Type = "ECDSA-P256"
+	TypeEd25519   Type = "Ed25519"
+)
+
+// DefaultRSAKeySize is used for RSA keys when no key size is requested
+const DefaultRSAKeySize = 2048
+
+// Generate generates a private key of the given type. An empty type generates
+// an RSA key of rsaBits, or DefaultRSAKeySize when rsaBits is 0.
+func Generate(keyType Type, rsaBits int) (crypto.Signer, error) {
+	switch keyType {
+	case TypeRSA, "":
+		if rsaBits == 0 {
+			rsaBits = DefaultRSAKeySize
+		}
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	case TypeECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case TypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
+
+// LeafUsage returns the key usage for an end-entity certificate. Key
+// encipherment only applies to RSA keys.
+func LeafUsage(key crypto.Signer) x509.KeyUsage {
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		return x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	}
+	return x509.KeyUsageDigitalSignature
+}
+
+// Encode returns the PEM block for a private key. RSA keys keep the PKCS#1
+// encoding for compatibility; other key types use PKCS#8.
+func Encode(key crypto.Signer) (*pem.Block, error) {
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %v", err)
+	}
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+}
+
+// Parse parses a PKCS#1, SEC 1 or PKCS#8 private key PEM block
+func Parse(block *pem.Block) (crypto.Signer, error) {
+	var key interface{}
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// Match reports whether a private key belongs to a certificate
+func Match(cert *x509.Certificate, key crypto.Signer) bool {
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(cert.PublicKey)
+}
@@ -0,0 +1,63 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestEncodeParseRoundTrip(t *testing.T) {
+	for _, keyType := range []Type{TypeRSA, TypeECDSAP256, TypeEd25519} {
+		t.Run(string(keyType), func(t *testing.T) {
+			key, err := Generate(keyType, 0)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %v", err)
+			}
+
+			block, err := Encode(key)
+			if err != nil {
+				t.Fatalf("Failed to encode key: %v", err)
+			}
+			parsed, err := Parse(block)
+			if err != nil {
+				t.Fatalf("Failed to parse key: %v", err)
+			}
+
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: "test"},
+				NotBefore:    time.Now(),
+				NotAfter:     time.Now().Add(time.Hour),
+				KeyUsage:     LeafUsage(key),
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+			if err != nil {
+				t.Fatalf("Failed to create certificate: %v", err)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				t.Fatalf("Failed to parse certificate: %v", err)
+			}
+			if !Match(cert, parsed) {
+				t.Error("Expected the parsed key to match its certificate")
+			}
+
+			other, err := Generate(keyType, 0)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %v", err)
+			}
+			if Match(cert, other) {
+				t.Error("Expected another key not to match the certificate")
+			}
+		})
+	}
+}
+
+func TestGenerateUnsupportedType(t *testing.T) {
+	if _, err := Generate("DSA", 0); err == nil {
+		t.Error("Expected an unsupported key type to be rejected")
+	}
+}
@@ -1,13 +1,15 @@
 package cert
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/cert/keys"
 )
 
 // CertificateLocator handles finding and validating certificates
@@ -69,7 +71,7 @@ func (l *CertificateLocator) ValidateCertificates(certPath, keyPath, caPath stri
 		return fmt.Errorf("failed to decode private key PEM")
 	}
 
-	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	key, err := keys.Parse(keyBlock)
 	if err != nil {
 		return fmt.Errorf("failed to parse private key: %v", err)
 	}
@@ -95,22 +97,11 @@ func (l *CertificateLocator) ValidateCertificates(certPath, keyPath, caPath stri
 }
 
 // verifyKeyPair checks if the private key matches the certificate
-func verifyKeyPair(cert *x509.Certificate, key *rsa.PrivateKey) error {
-	// Get the public key from the certificate
-	certPubKey, ok := cert.PublicKey.(*rsa.PublicKey)
-	if !ok {
-		return fmt.Errorf("certificate public key is not RSA")
-	}
-
-	// Compare the public key components
-	if certPubKey.N.Cmp(key.N) != 0 {
+func verifyKeyPair(cert *x509.Certificate, key crypto.Signer) error {
+	if !keys.Match(cert, key) {
 		return fmt.Errorf("public key mismatch")
 	}
 
-	if certPubKey.E != key.E {
-		return fmt.Errorf("public key exponent mismatch")
-	}
-
 	return nil
 }
 
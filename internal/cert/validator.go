@@ -1,12 +1,14 @@
 package cert
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"os"
 	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/cert/keys"
 )
 
 // CertificateValidator handles certificate validation and testing
@@ -57,7 +59,7 @@ func (v *CertificateValidator) ValidateCertificateFiles(certPath, keyPath, caPat
 }
 
 // loadCertificateAndKey loads and parses a certificate and private key from files
-func (v *CertificateValidator) loadCertificateAndKey(certPath, keyPath string) (*x509.Certificate, *rsa.PrivateKey, error) {
+func (v *CertificateValidator) loadCertificateAndKey(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
 	// Read certificate
 	certPEM, err := os.ReadFile(certPath)
 	if err != nil {
@@ -85,7 +87,7 @@ func (v *CertificateValidator) loadCertificateAndKey(certPath, keyPath string) (
 		return nil, nil, fmt.Errorf("failed to decode private key PEM")
 	}
 
-	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	key, err := keys.Parse(keyBlock)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to parse private key: %v", err)
 	}
@@ -128,18 +130,9 @@ func (v *CertificateValidator) verifyCertificateChain(cert *x509.Certificate, ca
 }
 
 // verifyKeyPair verifies that a certificate and private key match
-func (v *CertificateValidator) verifyKeyPair(cert *x509.Certificate, key *rsa.PrivateKey) error {
-	certPubKey, ok := cert.PublicKey.(*rsa.PublicKey)
-	if !ok {
-		return fmt.Errorf("certificate public key is not RSA")
-	}
-
-	if certPubKey.N.Cmp(key.N) != 0 {
-		return fmt.Errorf("public key modulus mismatch")
-	}
-
-	if certPubKey.E != key.E {
-		return fmt.Errorf("public key exponent mismatch")
+func (v *CertificateValidator) verifyKeyPair(cert *x509.Certificate, key crypto.Signer) error {
+	if !keys.Match(cert, key) {
+		return fmt.Errorf("public key mismatch")
 	}
 
 	return nil
@@ -26,6 +26,7 @@ type (
 	MonitorConfig   = types.MonitorConfig
 	MetricsConfig   = types.MetricsConfig
 	ThrottleConfig  = types.ThrottleConfig
+	RecoveryConfig  = types.RecoveryConfig
 	ConfigStore     = interfaces.ConfigStore
 	ConfigValidator = interfaces.ConfigValidator
 	ConfigManager   = interfaces.ConfigManager
@@ -47,7 +48,15 @@ func CreateManager(configDir string) ConfigManager {
 	}
 	configDir = filepath.Clean(configDir)
 
-	s := store.NewFileStore(configDir)
+	s := &envStore{ConfigStore: store.NewFileStore(configDir)}
+	v := validator.NewValidator()
+	return manager.NewManager(s, v)
+}
+
+// CreateManagerForFile creates a configuration manager that loads and stores the
+// configuration file at path
+func CreateManagerForFile(path string) ConfigManager {
+	s := &envStore{ConfigStore: store.NewFileStoreForFile(filepath.Clean(path))}
 	v := validator.NewValidator()
 	return manager.NewManager(s, v)
 }
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// EnvPrefix is the prefix shared by all configuration override environment variables
+const EnvPrefix = "SSSONECTOR_"
+
+// envOverride maps a single environment variable onto a configuration field
+type envOverride struct {
+	name  string
+	apply func(cfg *types.AppConfig, value string) error
+	// keep copies the field from src to dst, undoing the override
+	keep func(dst, src *types.AppConfig)
+}
+
+// envOverrides lists the supported environment variable overrides:
+//
+//	SSSONECTOR_LISTEN_ADDRESS    config.tunnel.listen_address
+//	SSSONECTOR_LISTEN_PORT       config.tunnel.listen_port (int)
+//	SSSONECTOR_SERVER_ADDRESS    config.tunnel.server_address
+//	SSSONECTOR_SERVER_PORT       config.tunnel.server_port (int)
+//	SSSONECTOR_LOG_LEVEL         config.logging.level
+//	SSSONECTOR_LOG_FILE          config.logging.file
+//	SSSONECTOR_LOG_OUTPUT        config.logging.output
+//	SSSONECTOR_SNMP_ENABLED      config.snmp.enabled (bool)
+//	SSSONECTOR_SNMP_PORT         config.snmp.port (int)
+//	SSSONECTOR_SNMP_COMMUNITY    config.snmp.community
+//	SSSONECTOR_MONITOR_INTERVAL  config.monitor.interval (duration)
+//	SSSONECTOR_THROTTLE_ENABLED  throttle.enabled (bool)
+var envOverrides = []envOverride{
+	{"LISTEN_ADDRESS", func(cfg *types.AppConfig, v string) error {
+		cfg.Config.Tunnel.ListenAddress = v
+		return nil
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Tunnel.ListenAddress = src.Config.Tunnel.ListenAddress
+	}},
+	{"LISTEN_PORT", func(cfg *types.AppConfig, v string) error {
+		return parseEnvInt(v, &cfg.Config.Tunnel.ListenPort)
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Tunnel.ListenPort = src.Config.Tunnel.ListenPort
+	}},
+	{"SERVER_ADDRESS", func(cfg *types.AppConfig, v string) error {
+		cfg.Config.Tunnel.ServerAddress = v
+		return nil
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Tunnel.ServerAddress = src.Config.Tunnel.ServerAddress
+	}},
+	{"SERVER_PORT", func(cfg *types.AppConfig, v string) error {
+		return parseEnvInt(v, &cfg.Config.Tunnel.ServerPort)
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Tunnel.ServerPort = src.Config.Tunnel.ServerPort
+	}},
+	{"LOG_LEVEL", func(cfg *types.AppConfig, v string) error {
+		cfg.Config.Logging.Level = strings.ToLower(v)
+		return nil
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Logging.Level = src.Config.Logging.Level
+	}},
+	{"LOG_FILE", func(cfg *types.AppConfig, v string) error {
+		cfg.Config.Logging.File = v
+		return nil
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Logging.File = src.Config.Logging.File
+	}},
+	{"LOG_OUTPUT", func(cfg *types.AppConfig, v string) error {
+		cfg.Config.Logging.Output = strings.ToLower(v)
+		return nil
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Logging.Output = src.Config.Logging.Output
+	}},
+	{"SNMP_ENABLED", func(cfg *types.AppConfig, v string) error {
+		return parseEnvBool(v, &cfg.Config.SNMP.Enabled)
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.SNMP.Enabled = src.Config.SNMP.Enabled
+	}},
+	{"SNMP_PORT", func(cfg *types.AppConfig, v string) error {
+		return parseEnvInt(v, &cfg.Config.SNMP.Port)
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.SNMP.Port = src.Config.SNMP.Port
+	}},
+	{"SNMP_COMMUNITY", func(cfg *types.AppConfig, v string) error {
+		cfg.Config.SNMP.Community = v
+		return nil
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.SNMP.Community = src.Config.SNMP.Community
+	}},
+	{"MONITOR_INTERVAL", func(cfg *types.AppConfig, v string) error {
+		return parseEnvDuration(v, &cfg.Config.Monitor.Interval)
+	}, func(dst, src *types.AppConfig) {
+		dst.Config.Monitor.Interval = src.Config.Monitor.Interval
+	}},
+	{"THROTTLE_ENABLED", func(cfg *types.AppConfig, v string) error {
+		return parseEnvBool(v, &cfg.Throttle.Enabled)
+	}, func(dst, src *types.AppConfig) {
+		dst.Throttle.Enabled = src.Throttle.Enabled
+	}},
+}
+
+// EnvOverrideNames returns the names of all supported override environment variables
+func EnvOverrideNames() []string {
+	names := make([]string, 0, len(envOverrides))
+	for _, o := range envOverrides {
+		names = append(names, EnvPrefix+o.name)
+	}
+	return names
+}
+
+// ApplyEnvOverrides overlays SSSONECTOR_* environment variables onto the configuration.
+// Variables that are unset leave the file value in place; malformed values return an error
+// naming the offending variable.
+func ApplyEnvOverrides(cfg *types.AppConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("config cannot be nil")
+	}
+
+	for _, o := range envOverrides {
+		value, ok := os.LookupEnv(EnvPrefix + o.name)
+		if !ok {
+			continue
+		}
+
+		if cfg.Config == nil {
+			cfg.Config = &types.Config{Mode: string(cfg.Type)}
+		}
+
+		if err := o.apply(cfg, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("invalid value for %s%s: %v", EnvPrefix, o.name, err)
+		}
+	}
+
+	return nil
+}
+
+// parseEnvInt parses an integer override value
+func parseEnvInt(value string, target *int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("expected integer, got %q", value)
+	}
+	*target = n
+	return nil
+}
+
+// parseEnvBool parses a boolean override value
+func parseEnvBool(value string, target *bool) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("expected boolean, got %q", value)
+	}
+	*target = b
+	return nil
+}
+
+// parseEnvDuration parses a duration override value
+func parseEnvDuration(value string, target *time.Duration) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("expected duration, got %q", value)
+	}
+	*target = d
+	return nil
+}
+
+// envStore applies the SSSONECTOR_* environment overrides to each configuration
+// it loads, so that a manager sees them as LoadConfig does, and keeps them out
+// of each configuration it stores.
+type envStore struct {
+	ConfigStore
+}
+
+// Load loads the configuration and applies the environment overrides
+func (s *envStore) Load() (*types.AppConfig, error) {
+	cfg, err := s.ConfigStore.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := ApplyEnvOverrides(cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %v", err)
+	}
+	return cfg, nil
+}
+
+// Store stores the configuration with the stored values of the overridden
+// fields, which are left unset when there is no stored configuration yet
+func (s *envStore) Store(cfg *types.AppConfig) error {
+	if cfg == nil || cfg.Config == nil {
+		return s.ConfigStore.Store(cfg)
+	}
+
+	stored, err := s.ConfigStore.Load()
+	if err != nil || stored.Config == nil {
+		stored = &types.AppConfig{Config: &types.Config{}}
+	}
+
+	out := *cfg
+	config := *cfg.Config
+	out.Config = &config
+	for _, o := range envOverrides {
+		if _, ok := os.LookupEnv(EnvPrefix + o.name); ok {
+			o.keep(&out, stored)
+		}
+	}
+	return s.ConfigStore.Store(&out)
+}
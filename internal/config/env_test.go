@@ -0,0 +1,205 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/store"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+const envTestConfig = `type: server
+metadata:
+  schema_version: "2.0.0"
+config:
+  mode: server
+  logging:
+    level: info
+  tunnel:
+    listen_port: 8443
+    server_address: file.example.com
+  snmp:
+    enabled: false
+    community: public
+  monitor:
+    interval: 30s
+  security:
+    tls:
+      min_version: "1.2"
+`
+
+func writeEnvTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(envTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	path := writeEnvTestConfig(t)
+
+	t.Setenv("SSSONECTOR_LISTEN_PORT", "9443")
+	t.Setenv("SSSONECTOR_SERVER_ADDRESS", "env.example.com")
+	t.Setenv("SSSONECTOR_LOG_LEVEL", "DEBUG")
+	t.Setenv("SSSONECTOR_SNMP_ENABLED", "true")
+	t.Setenv("SSSONECTOR_SNMP_COMMUNITY", "secret")
+	t.Setenv("SSSONECTOR_MONITOR_INTERVAL", "5s")
+
+	cfg, err := LoadConfig(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Config.Tunnel.ListenPort != 9443 {
+		t.Errorf("Expected listen port 9443, got %d", cfg.Config.Tunnel.ListenPort)
+	}
+	if cfg.Config.Tunnel.ServerAddress != "env.example.com" {
+		t.Errorf("Expected server address env.example.com, got %s", cfg.Config.Tunnel.ServerAddress)
+	}
+	if cfg.Config.Logging.Level != "debug" {
+		t.Errorf("Expected log level debug, got %s", cfg.Config.Logging.Level)
+	}
+	if !cfg.Config.SNMP.Enabled {
+		t.Error("Expected SNMP to be enabled")
+	}
+	if cfg.Config.SNMP.Community != "secret" {
+		t.Errorf("Expected community secret, got %s", cfg.Config.SNMP.Community)
+	}
+	if cfg.Config.Monitor.Interval != 5*time.Second {
+		t.Errorf("Expected monitor interval 5s, got %v", cfg.Config.Monitor.Interval)
+	}
+}
+
+func TestLoadConfigWithoutEnvKeepsFileValues(t *testing.T) {
+	path := writeEnvTestConfig(t)
+
+	cfg, err := LoadConfig(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Config.Tunnel.ListenPort != 8443 {
+		t.Errorf("Expected listen port 8443, got %d", cfg.Config.Tunnel.ListenPort)
+	}
+	if cfg.Config.Tunnel.ServerAddress != "file.example.com" {
+		t.Errorf("Expected server address file.example.com, got %s", cfg.Config.Tunnel.ServerAddress)
+	}
+	if cfg.Config.Monitor.Interval != 30*time.Second {
+		t.Errorf("Expected monitor interval 30s, got %v", cfg.Config.Monitor.Interval)
+	}
+}
+
+func TestApplyEnvOverridesMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"SSSONECTOR_LISTEN_PORT", "not-a-port"},
+		{"SSSONECTOR_SNMP_ENABLED", "maybe"},
+		{"SSSONECTOR_MONITOR_INTERVAL", "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+
+			err := ApplyEnvOverrides(types.NewAppConfig(types.TypeServer))
+			if err == nil {
+				t.Fatalf("Expected error for %s=%s", tt.name, tt.value)
+			}
+			if !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("Expected error to name %s, got: %v", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvOverridesNotWrittenBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := strings.Replace(envTestConfig, `schema_version: "2.0.0"`, `schema_version: "1.1.0"`, 1)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	t.Setenv("SSSONECTOR_LISTEN_PORT", "9443")
+
+	cfg, err := LoadConfig(path, LoadOptions{AutoMigrate: true, WriteBack: true})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Config.Tunnel.ListenPort != 9443 {
+		t.Errorf("Expected listen port 9443, got %d", cfg.Config.Tunnel.ListenPort)
+	}
+
+	os.Unsetenv("SSSONECTOR_LISTEN_PORT")
+	reloaded, err := LoadConfig(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if reloaded.Config.Tunnel.ListenPort != 8443 {
+		t.Errorf("Expected written listen port 8443, got %d", reloaded.Config.Tunnel.ListenPort)
+	}
+}
+
+func TestManagerEnvOverrides(t *testing.T) {
+	path := writeEnvTestConfig(t)
+	t.Setenv("SSSONECTOR_LISTEN_PORT", "9443")
+	t.Setenv("SSSONECTOR_LOG_LEVEL", "debug")
+
+	cfg, err := CreateManager(filepath.Dir(path)).Get()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.Config.Tunnel.ListenPort != 9443 {
+		t.Errorf("Expected listen port 9443, got %d", cfg.Config.Tunnel.ListenPort)
+	}
+	if cfg.Config.Logging.Level != "debug" {
+		t.Errorf("Expected log level debug, got %s", cfg.Config.Logging.Level)
+	}
+	if cfg.Config.Tunnel.ServerAddress != "file.example.com" {
+		t.Errorf("Expected server address from file, got %s", cfg.Config.Tunnel.ServerAddress)
+	}
+}
+
+func TestManagerKeepsEnvOverridesOutOfStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tunnel.yaml")
+	if err := os.WriteFile(path, []byte(envTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("SSSONECTOR_LISTEN_PORT", "9443")
+
+	// The manager reads the named file, not config.yaml beside it
+	cfg, err := CreateManagerForFile(path).Get()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.Config.Tunnel.ListenPort != 9443 {
+		t.Fatalf("Expected listen port 9443, got %d", cfg.Config.Tunnel.ListenPort)
+	}
+
+	s := &envStore{ConfigStore: store.NewFileStoreForFile(path)}
+	cfg.Config.Tunnel.ServerAddress = "stored.example.com"
+	if err := s.Store(cfg); err != nil {
+		t.Fatalf("Failed to store config: %v", err)
+	}
+	if cfg.Config.Tunnel.ListenPort != 9443 {
+		t.Errorf("Expected Store to leave the caller's config alone, got listen port %d", cfg.Config.Tunnel.ListenPort)
+	}
+
+	os.Unsetenv("SSSONECTOR_LISTEN_PORT")
+	stored, err := LoadConfig(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if stored.Config.Tunnel.ListenPort != 8443 {
+		t.Errorf("Expected the file's listen port 8443 to be stored, got %d", stored.Config.Tunnel.ListenPort)
+	}
+	if stored.Config.Tunnel.ServerAddress != "stored.example.com" {
+		t.Errorf("Expected the changed server address to be stored, got %s", stored.Config.Tunnel.ServerAddress)
+	}
+}
@@ -10,7 +10,10 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/o3willard-AI/SSSonector/internal/config/store"
 	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/config/validator"
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
 )
 
 // ConfigLoader handles loading and upgrading configuration files
@@ -23,8 +26,15 @@ func NewConfigLoader() *ConfigLoader {
 
 // LoadData loads configuration data from raw bytes with automatic version detection and upgrade
 func (l *ConfigLoader) LoadData(data []byte, format string) (*types.AppConfig, error) {
+	config, _, err := l.loadData(data, format)
+	return config, err
+}
+
+// loadData loads configuration data like LoadData and also returns the schema
+// version the data was written for
+func (l *ConfigLoader) loadData(data []byte, format string) (*types.AppConfig, string, error) {
 	if len(data) == 0 {
-		return nil, fmt.Errorf("config data is empty")
+		return nil, "", apperrors.Config(apperrors.ErrConfigSyntax, nil, "config data is empty")
 	}
 
 	// Detect format if not specified
@@ -35,31 +45,31 @@ func (l *ConfigLoader) LoadData(data []byte, format string) (*types.AppConfig, e
 	// Parse the data into a raw map for version detection
 	var raw map[string]interface{}
 	if err := l.parseData(data, format, &raw); err != nil {
-		return nil, fmt.Errorf("failed to parse config data: %v", err)
+		return nil, "", apperrors.Config(apperrors.ErrConfigSyntax, err, "failed to parse config data")
 	}
 
 	// Detect version
 	version, err := l.detectVersion(raw)
 	if err != nil {
-		return nil, fmt.Errorf("failed to detect config version: %v", err)
+		return nil, "", apperrors.Config(apperrors.ErrConfigVersion, err, "failed to detect config version")
 	}
 
 	// If version is current, parse directly
-	if version == "2.0.0" {
+	if version == CurrentSchemaVersion {
 		var config types.AppConfig
 		if err := l.parseData(data, format, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse current version config: %v", err)
+			return nil, "", apperrors.Config(apperrors.ErrConfigSyntax, err, "failed to parse current version config")
 		}
-		return &config, nil
+		return &config, version, nil
 	}
 
 	// Upgrade the configuration
-	upgradedConfig, err := l.upgradeConfig(raw, version)
+	upgradedConfig, err := l.upgradeConfig(raw, format, version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upgrade config from version %s: %v", version, err)
+		return nil, "", apperrors.Config(apperrors.ErrConfigVersion, err, "failed to upgrade config from version %s", version)
 	}
 
-	return upgradedConfig, nil
+	return upgradedConfig, version, nil
 }
 
 // detectFormat tries to detect the config file format (JSON/YAML)
@@ -127,166 +137,172 @@ func (l *ConfigLoader) detectVersion(raw map[string]interface{}) (string, error)
 	return "0.0.0", nil
 }
 
-// upgradeConfig upgrades configuration from an older version to current version 2.0.0
-func (l *ConfigLoader) upgradeConfig(raw map[string]interface{}, fromVersion string) (*types.AppConfig, error) {
-	var config types.AppConfig
-
-	switch fromVersion {
-	case "0.0.0":
-		config = l.upgradeFromLegacy(raw)
-	case "1.0.0":
-		config = l.upgradeFrom10(raw)
-	case "1.1.0":
-		config = l.upgradeFrom11(raw)
-	default:
-		return nil, fmt.Errorf("unsupported upgrade from version %s", fromVersion)
+// upgradeConfig upgrades configuration from an older version to the current
+// schema version with the registered Migrator steps
+func (l *ConfigLoader) upgradeConfig(raw map[string]interface{}, format, fromVersion string) (*types.AppConfig, error) {
+	// 1.x wrote the monitor interval as a number of seconds, which does not
+	// decode into a duration
+	var interval time.Duration
+	if rawConfig, ok := raw["config"].(map[string]interface{}); ok {
+		if monitor, ok := rawConfig["monitor"].(map[string]interface{}); ok {
+			if seconds, ok := toFloat(monitor["interval"]); ok {
+				interval = time.Duration(seconds * float64(time.Second))
+				delete(monitor, "interval")
+			}
+		}
+	}
+
+	data, err := l.marshalData(raw, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields missing from older layouts keep their defaults
+	config := types.NewAppConfig(types.TypeServer)
+	config.Metadata.MigrationHistory = nil
+	if err := l.parseData(data, format, config); err != nil {
+		return nil, fmt.Errorf("failed to parse version %s config: %v", fromVersion, err)
+	}
+	if interval > 0 {
+		config.Config.Monitor.Interval = interval
 	}
 
-	// Record the migration
-	l.recordMigration(&config, fromVersion, "2.0.0", "Automatic upgrade via loader")
+	// Legacy configs kept the mode at the top level
+	if fromVersion == LegacySchemaVersion {
+		if mode, ok := raw["mode"].(string); ok {
+			config.Config.Mode = mode
+		}
+	}
+	config.Metadata.SchemaVersion = fromVersion
 
-	return &config, nil
+	return NewMigrator().Migrate(config)
 }
 
-// upgradeFromLegacy upgrades from unversioned/legacy configuration
-func (l *ConfigLoader) upgradeFromLegacy(raw map[string]interface{}) types.AppConfig {
-	config := types.NewAppConfig(types.TypeServer)
+// toFloat returns a numeric YAML or JSON value as a float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
 
-	// Copy basic fields if they exist
-	if mode, ok := raw["mode"].(string); ok {
-		config.Config.Mode = mode
+// LoadFile loads configuration from a file with automatic format detection and version upgrading
+func (l *ConfigLoader) LoadFile(filename string) (*types.AppConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, apperrors.Config(apperrors.ErrConfigRead, err, "failed to read config file %s", filename)
 	}
 
-	// Default values for legacy upgrades
-	config.Metadata.SchemaVersion = "2.0.0"
-	config.Metadata.Environment = "production" // Legacy configs assumed production
+	format := l.detectFormat(data)
+	return l.LoadData(data, format)
+}
 
-	return *config
+// LoadFromString loads configuration from a string with specified format
+func (l *ConfigLoader) LoadFromString(content, format string) (*types.AppConfig, error) {
+	return l.LoadData([]byte(content), format)
 }
 
-// upgradeFrom10 upgrades from version 1.0.0 to 2.0.0
-func (l *ConfigLoader) upgradeFrom10(raw map[string]interface{}) types.AppConfig {
-	config := types.NewAppConfig(types.TypeServer)
+// LoadOptions controls optional behaviour of LoadConfig
+type LoadOptions struct {
+	// AutoMigrate upgrades configurations with an older schema version to CurrentSchemaVersion
+	AutoMigrate bool
+	// WriteBack persists a migrated configuration to the file it was loaded from
+	WriteBack bool
+	// Validate validates the configuration after environment overrides are applied
+	Validate bool
+}
 
-	// Copy config section
-	if rawConfig, ok := raw["config"].(map[string]interface{}); ok {
-		// Copy mode
-		if mode, ok := rawConfig["mode"].(string); ok {
-			config.Config.Mode = mode
-		}
+// LoadConfig loads a typed configuration file, optionally migrating it to the
+// current schema version and writing the upgraded file back. SSSONECTOR_*
+// environment overrides are applied after parsing and before validation; they are
+// never written back to the file.
+func LoadConfig(filename string, opts LoadOptions) (*types.AppConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, apperrors.Config(apperrors.ErrConfigRead, err, "failed to read config file %s", filename)
+	}
 
-		// Copy network settings
-		if network, ok := rawConfig["network"].(map[string]interface{}); ok {
-			if iface, ok := network["interface"].(string); ok {
-				config.Config.Network.Interface = iface
-			}
-			if mtu, ok := network["mtu"].(int); ok {
-				config.Config.Network.MTU = mtu
-			}
-			if addr, ok := network["address"].(string); ok {
-				config.Config.Network.Address = addr
-			}
-			if dnsServers, ok := network["dns_servers"].([]interface{}); ok {
-				for _, dns := range dnsServers {
-					if dnsStr, ok := dns.(string); ok {
-						config.Config.Network.DNSServers = append(config.Config.Network.DNSServers, dnsStr)
-					}
-				}
-			}
-		}
+	l := NewConfigLoader()
+	format := l.detectFormat(data)
 
-		// Copy logging settings
-		if logging, ok := rawConfig["logging"].(map[string]interface{}); ok {
-			if level, ok := logging["level"].(string); ok {
-				config.Config.Logging.Level = level
-			}
-			if file, ok := logging["file"].(string); ok {
-				config.Config.Logging.File = file
-			}
+	var cfg *types.AppConfig
+	if opts.AutoMigrate {
+		cfg, err = l.migrateFile(filename, format, data, opts.WriteBack)
+	} else {
+		cfg = &types.AppConfig{}
+		if err = l.parseData(data, format, cfg); err != nil {
+			err = apperrors.Config(apperrors.ErrConfigSyntax, err, "failed to parse config file %s", filename)
 		}
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		// Copy tunnel settings
-		if tunnel, ok := rawConfig["tunnel"].(map[string]interface{}); ok {
-			if portRaw, ok := tunnel["port"].(int); ok {
-				config.Config.Tunnel.Port = portRaw
-			}
-			if protocol, ok := tunnel["protocol"].(string); ok {
-				config.Config.Tunnel.Protocol = protocol
-			}
-		}
+	if err := ApplyEnvOverrides(cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %v", err)
 	}
 
-	// Set TLS defaults for version 2.0.0 compatibility
-	config.Config.Security.TLS.MinVersion = "1.2"
-	config.Config.Security.TLS.MaxVersion = "1.3"
-	config.Metadata.SchemaVersion = "2.0.0"
+	if opts.Validate {
+		if err := validator.NewValidator().Validate(cfg); err != nil {
+			return nil, apperrors.Config(apperrors.ErrConfigInvalid, err, "invalid config %s", filename)
+		}
+	}
 
-	return *config
+	return cfg, nil
 }
 
-// upgradeFrom11 upgrades from version 1.1.0 to 2.0.0
-func (l *ConfigLoader) upgradeFrom11(raw map[string]interface{}) types.AppConfig {
-	// Start from 1.0.0 upgrade as base
-	config := l.upgradeFrom10(raw)
-
-	// Add version 1.1.0 specific changes that need handling for 2.0.0
-	if rawConfig, ok := raw["config"].(map[string]interface{}); ok {
-		if monitor, ok := rawConfig["monitor"].(map[string]interface{}); ok {
-			if mtype, ok := monitor["type"].(string); ok {
-				config.Config.Monitor.Type = mtype
-			}
-			if enabledRaw, ok := monitor["enabled"].(bool); ok {
-				config.Config.Monitor.Enabled = enabledRaw
-			}
-			if intervalRaw, ok := monitor["interval"].(float64); ok {
-				config.Config.Monitor.Interval = time.Duration(intervalRaw * 1000000000) // Convert seconds to nanoseconds
-			}
-		}
+// migrateFile loads a configuration, upgrading it to the current schema version,
+// and optionally writes an upgraded result back to its source file
+func (l *ConfigLoader) migrateFile(filename, format string, data []byte, writeBack bool) (*types.AppConfig, error) {
+	cfg, version, err := l.loadData(data, format)
+	if err != nil {
+		return nil, err
+	}
 
-		// Copy metrics settings if present
-		if metrics, ok := rawConfig["metrics"].(map[string]interface{}); ok {
-			if enabledRaw, ok := metrics["enabled"].(bool); ok {
-				config.Config.Metrics.Enabled = enabledRaw
-			}
-			if addr, ok := metrics["address"].(string); ok {
-				config.Config.Metrics.Address = addr
-			}
+	if writeBack && version != CurrentSchemaVersion {
+		if err := l.writeFile(filename, format, cfg); err != nil {
+			return nil, fmt.Errorf("failed to write migrated config: %v", err)
 		}
 	}
 
-	// Ensure TLS settings for 2.0.0
-	config.Config.Security.TLS.MinVersion = "1.2"
-	config.Config.Security.TLS.MaxVersion = "1.3"
-	config.Metadata.SchemaVersion = "2.0.0"
-
-	return config
+	return cfg, nil
 }
 
-// recordMigration adds a migration record to the configuration
-func (l *ConfigLoader) recordMigration(config *types.AppConfig, fromVersion, toVersion, notes string) {
-	record := types.MigrationRecord{
-		FromVersion: fromVersion,
-		ToVersion:   toVersion,
-		Timestamp:   time.Now(),
-		Status:      "completed",
-		Notes:       notes,
+// writeFile serializes a configuration in the given format and replaces the file
+// with it, see writeFileAtomic
+func (l *ConfigLoader) writeFile(filename, format string, cfg *types.AppConfig) error {
+	data, err := l.marshalData(cfg, format)
+	if err != nil {
+		return err
 	}
 
-	config.Metadata.MigrationHistory = append(config.Metadata.MigrationHistory, record)
+	return store.WriteFileAtomic(filename, data)
 }
 
-// LoadFile loads configuration from a file with automatic format detection and version upgrading
-func (l *ConfigLoader) LoadFile(filename string) (*types.AppConfig, error) {
-	data, err := os.ReadFile(filename)
+// marshalData serializes configuration data in the given format
+func (l *ConfigLoader) marshalData(v interface{}, format string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch strings.ToLower(format) {
+	case "json":
+		data, err = json.MarshalIndent(v, "", "  ")
+	case "yaml", "yml":
+		data, err = yaml.Marshal(v)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %v", filename, err)
+		return nil, fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	format := l.detectFormat(data)
-	return l.LoadData(data, format)
-}
-
-// LoadFromString loads configuration from a string with specified format
-func (l *ConfigLoader) LoadFromString(content, format string) (*types.AppConfig, error) {
-	return l.LoadData([]byte(content), format)
+	return data, nil
 }
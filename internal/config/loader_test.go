@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+)
+
+func TestLoadConfigErrorCodes(t *testing.T) {
+	dir := t.TempDir()
+	malformed := filepath.Join(dir, "malformed.yaml")
+	if err := os.WriteFile(malformed, []byte("config: [unclosed\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code apperrors.Code
+	}{
+		{"missing file", filepath.Join(dir, "missing.yaml"), apperrors.ErrConfigRead},
+		{"malformed file", malformed, apperrors.ErrConfigSyntax},
+	}
+
+	for _, tt := range tests {
+		_, err := LoadConfig(tt.path, LoadOptions{})
+		if !errors.Is(err, tt.code) {
+			t.Errorf("%s: expected error code %s, got %v", tt.name, tt.code, err)
+		}
+		if apperrors.CategoryOf(err) != apperrors.CategoryConfiguration {
+			t.Errorf("%s: expected a configuration error, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestWriteFileKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	original := "type: server\nconfig:\n  mode: server\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	l := NewConfigLoader()
+	cfg, err := l.LoadFromString(original, "yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	cfg.Config.Network.MTU = 1400
+
+	// A config that cannot be serialized leaves the file alone
+	if err := l.writeFile(path, "toml", cfg); err == nil {
+		t.Fatal("Expected an unsupported format to fail")
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("Expected the config to be untouched, got %q", data)
+	}
+
+	if err := l.writeFile(path, "yaml", cfg); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if string(backup) != original {
+		t.Errorf("Expected the backup to hold the original config, got %q", backup)
+	}
+
+	written, err := LoadConfig(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to load written config: %v", err)
+	}
+	if written.Config.Network.MTU != 1400 {
+		t.Errorf("Expected MTU 1400 in the written config, got %d", written.Config.Network.MTU)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat config: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600 to be preserved, got %v", info.Mode().Perm())
+	}
+
+	// No temporary file is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the config and its backup, got %d files", len(entries))
+	}
+}
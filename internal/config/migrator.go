@@ -0,0 +1,281 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// CurrentSchemaVersion is the configuration schema version understood by this binary
+const CurrentSchemaVersion = types.CurrentSchemaVersion
+
+// InitialSchemaVersion is the schema version assumed for configurations written
+// before schema_version was recorded
+const InitialSchemaVersion = "1.0.0"
+
+// LegacySchemaVersion is the schema version of configurations that predate the
+// versioned layout, as detected by ConfigLoader
+const LegacySchemaVersion = "0.0.0"
+
+// MigrationFunc transforms a configuration in place from one schema version to the next
+type MigrationFunc func(cfg *types.AppConfig) error
+
+// migrationStep is a single registered schema upgrade
+type migrationStep struct {
+	from  string
+	to    string
+	fn    MigrationFunc
+	notes string
+}
+
+// Migrator upgrades configurations to the current schema version by applying
+// registered migration steps in order
+type Migrator struct {
+	steps  map[string]migrationStep
+	target string
+}
+
+// NewMigrator creates a Migrator with the built-in migration steps registered
+func NewMigrator() *Migrator {
+	m := &Migrator{
+		steps:  make(map[string]migrationStep),
+		target: CurrentSchemaVersion,
+	}
+
+	m.mustRegister(LegacySchemaVersion, "1.0.0", "Mark legacy config as production", migrateLegacyTo10)
+	m.mustRegister("1.0.0", "1.1.0", "Add monitor type for metrics", migrate10To11)
+	m.mustRegister("1.1.0", "2.0.0", "Set default TLS versions", migrate11To20)
+
+	return m
+}
+
+// NewMigratorWithTarget creates an empty Migrator that upgrades to the given schema version
+func NewMigratorWithTarget(target string) (*Migrator, error) {
+	if _, err := parseSchemaVersion(target); err != nil {
+		return nil, fmt.Errorf("invalid target version: %v", err)
+	}
+
+	return &Migrator{
+		steps:  make(map[string]migrationStep),
+		target: target,
+	}, nil
+}
+
+// Target returns the schema version the migrator upgrades to
+func (m *Migrator) Target() string {
+	return m.target
+}
+
+// Register registers a migration step from one schema version to another
+func (m *Migrator) Register(from, to, notes string, fn MigrationFunc) error {
+	if fn == nil {
+		return fmt.Errorf("migration function cannot be nil")
+	}
+
+	cmp, err := compareSchemaVersions(from, to)
+	if err != nil {
+		return err
+	}
+	if cmp >= 0 {
+		return fmt.Errorf("migration must upgrade the schema version: %s -> %s", from, to)
+	}
+
+	if _, exists := m.steps[from]; exists {
+		return fmt.Errorf("migration from version %s already registered", from)
+	}
+
+	m.steps[from] = migrationStep{
+		from:  from,
+		to:    to,
+		fn:    fn,
+		notes: notes,
+	}
+	return nil
+}
+
+// mustRegister registers a built-in migration step and panics on failure
+func (m *Migrator) mustRegister(from, to, notes string, fn MigrationFunc) {
+	if err := m.Register(from, to, notes, fn); err != nil {
+		panic(fmt.Sprintf("failed to register migration %s -> %s: %v", from, to, err))
+	}
+}
+
+// NeedsMigration reports whether the configuration is older than the target schema version
+func (m *Migrator) NeedsMigration(cfg *types.AppConfig) (bool, error) {
+	if cfg == nil {
+		return false, fmt.Errorf("config cannot be nil")
+	}
+
+	version := schemaVersionOf(cfg)
+	cmp, err := compareSchemaVersions(version, m.target)
+	if err != nil {
+		return false, err
+	}
+	if cmp > 0 {
+		return false, fmt.Errorf("config schema version %s is newer than supported version %s",
+			version, m.target)
+	}
+
+	return cmp < 0, nil
+}
+
+// Migrate applies registered migration steps in order until the configuration reaches
+// the target schema version. The input configuration is not modified; a migrated copy
+// is returned with a MigrationRecord appended for every applied step.
+func (m *Migrator) Migrate(cfg *types.AppConfig) (*types.AppConfig, error) {
+	needed, err := m.NeedsMigration(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated, err := cloneAppConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !needed {
+		return migrated, nil
+	}
+
+	migrated.Metadata.SchemaVersion = schemaVersionOf(cfg)
+	if migrated.Config == nil {
+		migrated.Config = &types.Config{Mode: string(migrated.Type)}
+	}
+
+	for migrated.Metadata.SchemaVersion != m.target {
+		current := migrated.Metadata.SchemaVersion
+
+		step, ok := m.steps[current]
+		if !ok {
+			return nil, fmt.Errorf("no migration registered from version %s", current)
+		}
+
+		if cmp, err := compareSchemaVersions(step.to, m.target); err != nil || cmp > 0 {
+			return nil, fmt.Errorf("migration %s -> %s overshoots target version %s", step.from, step.to, m.target)
+		}
+
+		if err := step.fn(migrated); err != nil {
+			appendMigrationRecord(migrated, step, "failed", err.Error())
+			return nil, fmt.Errorf("migration %s -> %s failed: %v", step.from, step.to, err)
+		}
+
+		migrated.Metadata.SchemaVersion = step.to
+		appendMigrationRecord(migrated, step, "completed", step.notes)
+	}
+
+	migrated.Metadata.Modified = time.Now()
+	migrated.Metadata.UpdatedAt = migrated.Metadata.Modified
+
+	return migrated, nil
+}
+
+// appendMigrationRecord records the outcome of a migration step
+func appendMigrationRecord(cfg *types.AppConfig, step migrationStep, status, notes string) {
+	cfg.Metadata.MigrationHistory = append(cfg.Metadata.MigrationHistory, types.MigrationRecord{
+		FromVersion: step.from,
+		ToVersion:   step.to,
+		Timestamp:   time.Now(),
+		Status:      status,
+		Notes:       notes,
+	})
+}
+
+// schemaVersionOf returns the schema version of the configuration, treating an
+// unset version as InitialSchemaVersion
+func schemaVersionOf(cfg *types.AppConfig) string {
+	if cfg.Metadata.SchemaVersion == "" {
+		return InitialSchemaVersion
+	}
+	return cfg.Metadata.SchemaVersion
+}
+
+// cloneAppConfig returns a deep copy of the configuration that can be modified
+// independently. The copy goes through the same JSON encoding used to write
+// configuration files, so every slice, map and pointer field is duplicated.
+func cloneAppConfig(cfg *types.AppConfig) (*types.AppConfig, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy config: %v", err)
+	}
+
+	clone := &types.AppConfig{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, fmt.Errorf("failed to copy config: %v", err)
+	}
+	return clone, nil
+}
+
+// migrateLegacyTo10 upgrades an unversioned legacy configuration to 1.0.0
+func migrateLegacyTo10(cfg *types.AppConfig) error {
+	// Legacy configs carried no environment and were only used in production
+	cfg.Metadata.Environment = "production"
+	return nil
+}
+
+// migrate10To11 upgrades a 1.0.0 configuration to 1.1.0
+func migrate10To11(cfg *types.AppConfig) error {
+	// 1.1.0 requires a monitor type whenever metrics are enabled; 1.0.0 only
+	// exported Prometheus metrics
+	if cfg.Config.Metrics.Enabled && cfg.Config.Monitor.Type == "" {
+		cfg.Config.Monitor.Type = "prometheus"
+	}
+	return nil
+}
+
+// migrate11To20 upgrades a 1.1.0 configuration to 2.0.0
+func migrate11To20(cfg *types.AppConfig) error {
+	// 2.0.0 requires explicit TLS versions; keep any configured value
+	tls := &cfg.Config.Security.TLS
+	if tls.MinVersion == "" {
+		tls.MinVersion = "1.2"
+	}
+	if tls.MaxVersion == "" {
+		tls.MaxVersion = "1.3"
+	}
+	return nil
+}
+
+// parseSchemaVersion parses a MAJOR.MINOR.PATCH schema version
+func parseSchemaVersion(version string) ([3]int, error) {
+	var parsed [3]int
+
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return parsed, fmt.Errorf("version must be in MAJOR.MINOR.PATCH format: %q", version)
+	}
+
+	for i, part := range parts {
+		num, err := strconv.Atoi(part)
+		if err != nil || num < 0 {
+			return parsed, fmt.Errorf("version part %d must be a non-negative integer: %q", i+1, version)
+		}
+		parsed[i] = num
+	}
+
+	return parsed, nil
+}
+
+// compareSchemaVersions returns -1, 0 or 1 depending on whether a is older, equal or newer than b
+func compareSchemaVersions(a, b string) (int, error) {
+	va, err := parseSchemaVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseSchemaVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range va {
+		switch {
+		case va[i] < vb[i]:
+			return -1, nil
+		case va[i] > vb[i]:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestMigratorTwoStepChain(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Metadata.SchemaVersion = "1.0.0"
+	cfg.Config.Metrics.Enabled = true
+	cfg.Config.Monitor.Type = "snmp"
+	cfg.Config.Security.TLS.MinVersion = "1.3"
+	cfg.Config.Network.DNSServers = []string{"10.0.0.53"}
+
+	migrated, err := NewMigrator().Migrate(cfg)
+	if err != nil {
+		t.Fatalf("Failed to migrate config: %v", err)
+	}
+
+	if migrated.Metadata.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Expected schema version %s, got %s", CurrentSchemaVersion, migrated.Metadata.SchemaVersion)
+	}
+
+	// Original history plus one record per applied step
+	history := migrated.Metadata.MigrationHistory
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 migration records, got %d", len(history))
+	}
+
+	expected := []struct{ from, to string }{
+		{"1.0.0", "1.1.0"},
+		{"1.1.0", "2.0.0"},
+	}
+	for i, want := range expected {
+		record := history[i+1]
+		if record.FromVersion != want.from || record.ToVersion != want.to {
+			t.Errorf("Record %d: expected %s -> %s, got %s -> %s",
+				i+1, want.from, want.to, record.FromVersion, record.ToVersion)
+		}
+		if record.Status != "completed" {
+			t.Errorf("Record %d: expected status completed, got %s", i+1, record.Status)
+		}
+		if record.Timestamp.IsZero() {
+			t.Errorf("Record %d: expected timestamp to be set", i+1)
+		}
+	}
+
+	// Migration must not rewrite configured values
+	if migrated.Config.Monitor.Type != "snmp" {
+		t.Errorf("Expected monitor type snmp to be kept, got %q", migrated.Config.Monitor.Type)
+	}
+	if migrated.Config.Security.TLS.MinVersion != "1.3" {
+		t.Errorf("Expected TLS minimum version 1.3 to be kept, got %q", migrated.Config.Security.TLS.MinVersion)
+	}
+
+	// Input must not share state with the migrated copy
+	migrated.Config.Network.DNSServers[0] = "10.0.0.1"
+	if cfg.Config.Network.DNSServers[0] != "10.0.0.53" {
+		t.Errorf("Expected input DNS servers to be unchanged, got %v", cfg.Config.Network.DNSServers)
+	}
+
+	// Input must not be modified
+	if cfg.Metadata.SchemaVersion != "1.0.0" {
+		t.Errorf("Expected input schema version to remain 1.0.0, got %s", cfg.Metadata.SchemaVersion)
+	}
+	if len(cfg.Metadata.MigrationHistory) != 1 {
+		t.Errorf("Expected input migration history to be unchanged, got %d records", len(cfg.Metadata.MigrationHistory))
+	}
+}
+
+func TestMigratorFillsDefaults(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Metadata.SchemaVersion = "1.0.0"
+	cfg.Config.Metrics.Enabled = true
+	cfg.Config.Monitor.Type = ""
+	cfg.Config.Security.TLS.MinVersion = ""
+	cfg.Config.Security.TLS.MaxVersion = ""
+
+	migrated, err := NewMigrator().Migrate(cfg)
+	if err != nil {
+		t.Fatalf("Failed to migrate config: %v", err)
+	}
+
+	if migrated.Config.Monitor.Type != "prometheus" {
+		t.Errorf("Expected monitor type prometheus, got %q", migrated.Config.Monitor.Type)
+	}
+	if migrated.Config.Security.TLS.MinVersion != "1.2" || migrated.Config.Security.TLS.MaxVersion != "1.3" {
+		t.Errorf("Expected TLS versions 1.2-1.3, got %q-%q",
+			migrated.Config.Security.TLS.MinVersion, migrated.Config.Security.TLS.MaxVersion)
+	}
+}
+
+func TestLoaderUpgradesThroughMigrator(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		from        string
+		environment string
+		interval    time.Duration
+	}{
+		{"legacy", "mode: client\n", LegacySchemaVersion, "production", 0},
+		{"1.0.0", "throttle: {}\nconfig:\n  mode: client\n  network:\n    mtu: 1400\n", "1.0.0", "development", 0},
+		{"1.1.0", "throttle: {}\nconfig:\n  mode: client\n  monitor:\n    type: snmp\n    interval: 2.5\n", "1.1.0", "development", 2500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewConfigLoader().LoadFromString(tt.content, "yaml")
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+
+			if cfg.Metadata.SchemaVersion != CurrentSchemaVersion {
+				t.Errorf("Expected schema version %s, got %s", CurrentSchemaVersion, cfg.Metadata.SchemaVersion)
+			}
+			if cfg.Config.Mode != "client" {
+				t.Errorf("Expected mode client, got %q", cfg.Config.Mode)
+			}
+			if cfg.Metadata.Environment != tt.environment {
+				t.Errorf("Expected environment %s, got %s", tt.environment, cfg.Metadata.Environment)
+			}
+			if cfg.Config.Security.TLS.MinVersion != "1.2" {
+				t.Errorf("Expected TLS minimum version 1.2, got %q", cfg.Config.Security.TLS.MinVersion)
+			}
+			if cfg.Config.Monitor.Interval != tt.interval {
+				t.Errorf("Expected monitor interval %v, got %v", tt.interval, cfg.Config.Monitor.Interval)
+			}
+
+			history := cfg.Metadata.MigrationHistory
+			if len(history) == 0 || history[0].FromVersion != tt.from {
+				t.Fatalf("Expected migration to start from %s, got %+v", tt.from, history)
+			}
+			if last := history[len(history)-1]; last.ToVersion != CurrentSchemaVersion {
+				t.Errorf("Expected migration to end at %s, got %s", CurrentSchemaVersion, last.ToVersion)
+			}
+		})
+	}
+}
+
+func TestMigratorUnversionedConfig(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Metadata.SchemaVersion = ""
+	cfg.Metadata.MigrationHistory = nil
+	cfg.Config.Security.TLS.MinVersion = "1.2"
+
+	m := NewMigrator()
+	needed, err := m.NeedsMigration(cfg)
+	if err != nil {
+		t.Fatalf("Failed to check unversioned config: %v", err)
+	}
+	if !needed {
+		t.Fatal("Expected unversioned config to need migration")
+	}
+
+	migrated, err := m.Migrate(cfg)
+	if err != nil {
+		t.Fatalf("Failed to migrate unversioned config: %v", err)
+	}
+	if migrated.Metadata.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Expected schema version %s, got %s", CurrentSchemaVersion, migrated.Metadata.SchemaVersion)
+	}
+	if history := migrated.Metadata.MigrationHistory; len(history) == 0 || history[0].FromVersion != InitialSchemaVersion {
+		t.Errorf("Expected migration to start from %s, got %+v", InitialSchemaVersion, history)
+	}
+}
+
+func TestMigratorRejectsNewerSchema(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Metadata.SchemaVersion = "3.0.0"
+
+	if _, err := NewMigrator().Migrate(cfg); err == nil {
+		t.Fatal("Expected error migrating config newer than supported schema")
+	}
+}
+
+func TestMigratorFailedStep(t *testing.T) {
+	m, err := NewMigratorWithTarget("1.2.0")
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	if err := m.Register("1.0.0", "1.1.0", "first", func(*types.AppConfig) error { return nil }); err != nil {
+		t.Fatalf("Failed to register step: %v", err)
+	}
+
+	cfg := types.NewAppConfig(types.TypeServer)
+	if _, err := m.Migrate(cfg); err == nil {
+		t.Fatal("Expected error for missing migration step")
+	}
+
+	if err := m.Register("1.1.0", "1.0.0", "downgrade", func(*types.AppConfig) error { return nil }); err == nil {
+		t.Error("Expected error registering a downgrade step")
+	}
+}
+
+func TestLoadConfigAutoMigrateWriteBack(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "config.json")
+
+	content := `{
+  "type": "server",
+  "config": {"mode": "server", "security": {"tls": {"min_version": "1.2"}}},
+  "metadata": {"schema_version": "1.1.0", "environment": "development"}
+}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path, LoadOptions{AutoMigrate: true, WriteBack: true})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Metadata.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Expected schema version %s, got %s", CurrentSchemaVersion, cfg.Metadata.SchemaVersion)
+	}
+
+	reloaded, err := LoadConfig(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if reloaded.Metadata.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Expected written schema version %s, got %s", CurrentSchemaVersion, reloaded.Metadata.SchemaVersion)
+	}
+	if len(reloaded.Metadata.MigrationHistory) != 1 {
+		t.Errorf("Expected 1 migration record, got %d", len(reloaded.Metadata.MigrationHistory))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat config: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600 to be preserved, got %v", info.Mode().Perm())
+	}
+}
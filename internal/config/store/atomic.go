@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic replaces filename with data so that a crash leaves either the
+// old or the new file, never a truncated one. The data is written and synced to
+// a temporary file in the same directory, which is renamed over filename. An
+// existing file is first copied to filename.bak, and its mode and ownership
+// are kept.
+func WriteFileAtomic(filename string, data []byte) error {
+	mode := os.FileMode(0644)
+	info, err := os.Stat(filename)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+		if err := copyFile(filename, filename+".bak", mode); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", filename, err)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if info != nil {
+		if err := chownLike(tmp.Name(), info); err != nil {
+			return fmt.Errorf("failed to keep the owner of %s: %v", filename, err)
+		}
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// copyFile copies src to dst, synced, creating or truncating dst with mode
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -17,16 +17,22 @@ import (
 // FileStore implements ConfigStore interface for file-based storage
 type FileStore struct {
 	configDir string
+	filename  string
 }
 
-// NewFileStore creates a new FileStore instance
+// NewFileStore creates a new FileStore instance for config.yaml in configDir
 func NewFileStore(configDir string) *FileStore {
-	return &FileStore{configDir: configDir}
+	return &FileStore{configDir: configDir, filename: "config.yaml"}
+}
+
+// NewFileStoreForFile creates a FileStore that loads and stores the file at path
+func NewFileStoreForFile(path string) *FileStore {
+	return &FileStore{configDir: filepath.Dir(path), filename: filepath.Base(path)}
 }
 
 // Load loads configuration from file
 func (s *FileStore) Load() (*types.AppConfig, error) {
-	data, err := ioutil.ReadFile(filepath.Join(s.configDir, "config.yaml"))
+	data, err := ioutil.ReadFile(filepath.Join(s.configDir, s.filename))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
@@ -50,10 +56,9 @@ func (s *FileStore) Store(config *types.AppConfig) error {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	filename := "config.yaml"
-	path := filepath.Join(s.configDir, filename)
+	path := filepath.Join(s.configDir, s.filename)
 
-	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+	if err := WriteFileAtomic(path, data); err != nil {
 		return fmt.Errorf("failed to write config file: %v", err)
 	}
 
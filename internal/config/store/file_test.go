@@ -0,0 +1,42 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestFileStoreKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("type: server\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	s := NewFileStore(dir)
+	if err := s.Store(types.NewAppConfig(types.TypeClient)); err != nil {
+		t.Fatalf("Failed to store config: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat config: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the config to keep mode 0600, got %v", info.Mode().Perm())
+	}
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil || string(backup) != "type: server\n" {
+		t.Errorf("Expected the previous config to be backed up, got %q, %v", backup, err)
+	}
+
+	cfg, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Type != types.TypeClient {
+		t.Errorf("Expected the stored config to be loaded, got type %s", cfg.Type)
+	}
+}
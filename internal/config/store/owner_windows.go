@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package store
+
+import "os"
+
+// chownLike does nothing, as files on Windows have no Unix owner to keep
+func chownLike(path string, info os.FileInfo) error {
+	return nil
+}
@@ -20,6 +20,39 @@ const (
 	ModeClient = "client"
 )
 
+// Packet size limits
+const (
+	// DefaultMTU is the standard Ethernet MTU
+	DefaultMTU = 1500
+	// MaxJumboMTU is the largest jumbo frame MTU supported by the tunnel
+	MaxJumboMTU = 9000
+	// PacketOverhead is the allowance for link and tunnel headers on top of the MTU
+	PacketOverhead = 128
+	// MaxPacketSizeLimit is the upper bound for network.max_packet_size
+	MaxPacketSizeLimit = MaxJumboMTU + PacketOverhead
+)
+
+// Write coalescing limits
+const (
+	// MaxCoalesceWindow is the upper bound for tunnel.coalesce_window
+	MaxCoalesceWindow = 10 * time.Millisecond
+	// DefaultCoalesceBytes is the default for tunnel.coalesce_bytes
+	DefaultCoalesceBytes = 16 * 1024
+	// DefaultCoalescePackets is the default for tunnel.coalesce_packets
+	DefaultCoalescePackets = 32
+)
+
+// DefaultKeepaliveMisses is the default for tunnel.keepalive_misses
+const DefaultKeepaliveMisses = 3
+
+// Network list limits
+const (
+	// DefaultMaxRoutes is the default limit for network.routes
+	DefaultMaxRoutes = 256
+	// DefaultMaxDNSServers is the default limit for network.dns_servers
+	DefaultMaxDNSServers = 8
+)
+
 // String returns the string representation of Type
 func (t Type) String() string {
 	return string(t)
@@ -66,15 +99,15 @@ type AppConfig struct {
 
 // ConfigMetadata represents configuration metadata
 type ConfigMetadata struct {
-	Version     string    `yaml:"version" json:"version"`
-	Created     time.Time `yaml:"created" json:"created"`
-	Modified    time.Time `yaml:"modified" json:"modified"`
-	CreatedBy   string    `yaml:"created_by" json:"created_by"`
-	CreatedAt   time.Time `yaml:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `yaml:"updated_at" json:"updated_at"`
-	Environment string    `yaml:"environment" json:"environment"`
-	Region      string    `yaml:"region" json:"region"`
-	SchemaVersion string  `yaml:"schema_version" json:"schema_version"`
+	Version          string            `yaml:"version" json:"version"`
+	Created          time.Time         `yaml:"created" json:"created"`
+	Modified         time.Time         `yaml:"modified" json:"modified"`
+	CreatedBy        string            `yaml:"created_by" json:"created_by"`
+	CreatedAt        time.Time         `yaml:"created_at" json:"created_at"`
+	UpdatedAt        time.Time         `yaml:"updated_at" json:"updated_at"`
+	Environment      string            `yaml:"environment" json:"environment"`
+	Region           string            `yaml:"region" json:"region"`
+	SchemaVersion    string            `yaml:"schema_version" json:"schema_version"`
 	MigrationHistory []MigrationRecord `yaml:"migration_history" json:"migration_history"`
 }
 
@@ -98,6 +131,7 @@ type Config struct {
 	Monitor  MonitorConfig  `yaml:"monitor" json:"monitor"`
 	Metrics  MetricsConfig  `yaml:"metrics" json:"metrics"`
 	SNMP     SNMPConfig     `yaml:"snmp" json:"snmp"`
+	Recovery RecoveryConfig `yaml:"recovery" json:"recovery"`
 }
 
 // LoggingConfig represents logging configuration
@@ -105,6 +139,9 @@ type LoggingConfig struct {
 	Level  string `yaml:"level" json:"level"`
 	File   string `yaml:"file" json:"file"`
 	Format string `yaml:"format" json:"format"`
+	// Output is where logs are written: stdout, stderr or file. It takes
+	// precedence over File, which is only used when Output is file or empty.
+	Output string `yaml:"output" json:"output"`
 }
 
 // AuthConfig represents authentication configuration
@@ -128,6 +165,18 @@ type NetworkConfig struct {
 	Address    string     `yaml:"address" json:"address"`
 	DNSServers []string   `yaml:"dns_servers" json:"dns_servers"`
 	IPv6       IPv6Config `yaml:"ipv6" json:"ipv6"`
+	// Routes lists the CIDR networks routed through the tunnel
+	Routes []string `yaml:"routes" json:"routes"`
+	// AllowDefaultRoute permits a default route (0.0.0.0/0 or ::/0) in client mode
+	AllowDefaultRoute bool `yaml:"allow_default_route" json:"allow_default_route"`
+	// MaxPacketSize is the largest packet forwarded in a single read; defaults to MTU plus PacketOverhead
+	MaxPacketSize int `yaml:"max_packet_size" json:"max_packet_size"`
+	// MaxRoutes limits the number of routes; defaults to DefaultMaxRoutes
+	MaxRoutes int `yaml:"max_routes" json:"max_routes"`
+	// MaxDNSServers limits the number of DNS servers; defaults to DefaultMaxDNSServers
+	MaxDNSServers int `yaml:"max_dns_servers" json:"max_dns_servers"`
+	// ApplyDNS makes a client point the system resolver at DNSServers while connected
+	ApplyDNS bool `yaml:"apply_dns" json:"apply_dns"`
 }
 
 // IPv6Config represents IPv6 experimental configuration
@@ -142,14 +191,119 @@ type IPv6Config struct {
 
 // TunnelConfig represents tunnel configuration
 type TunnelConfig struct {
-	ListenAddress string `yaml:"listen_address" json:"listen_address"`
-	ListenPort    int    `yaml:"listen_port" json:"listen_port"`
-	ServerAddress string `yaml:"server_address" json:"server_address"`
-	ServerPort    int    `yaml:"server_port" json:"server_port"`
-	Port          int    `yaml:"port" json:"port"`
-	Protocol      string `yaml:"protocol" json:"protocol"`
-	Compression   bool   `yaml:"compression" json:"compression"`
-	Keepalive     string `yaml:"keepalive" json:"keepalive"`
+	ListenAddress string         `yaml:"listen_address" json:"listen_address"`
+	ListenPort    int            `yaml:"listen_port" json:"listen_port"`
+	ServerAddress string         `yaml:"server_address" json:"server_address"`
+	ServerPort    int            `yaml:"server_port" json:"server_port"`
+	Port          int            `yaml:"port" json:"port"`
+	Protocol      string         `yaml:"protocol" json:"protocol"`
+	Compression   bool           `yaml:"compression" json:"compression"`
+	Keepalive     string         `yaml:"keepalive" json:"keepalive"`
+	DNSCache      DNSCacheConfig `yaml:"dns_cache" json:"dns_cache"`
+	// KeepaliveMisses is the number of keepalive intervals without a frame from
+	// the peer before its connection is closed; defaults to DefaultKeepaliveMisses
+	KeepaliveMisses int `yaml:"keepalive_misses" json:"keepalive_misses"`
+	// Heartbeat sends a heartbeat frame every keepalive interval. It frames the
+	// stream, so the client and server must agree on it.
+	Heartbeat bool `yaml:"heartbeat" json:"heartbeat"`
+	// ListenAddresses are host:port addresses the server listens on, in place of
+	// listen_address and listen_port; they may mix IPv4 and IPv6
+	ListenAddresses []string `yaml:"listen_addresses" json:"listen_addresses"`
+	// ProxyProtocol reads a PROXY protocol v1 or v2 header from each accepted
+	// connection to recover the client address behind a load balancer
+	ProxyProtocol bool `yaml:"proxy_protocol" json:"proxy_protocol"`
+	// TrustedProxies are the CIDRs of the load balancers allowed to send a
+	// PROXY protocol header; connections from other peers are refused
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
+	// ServerName is the hostname the certificate of server_address is verified
+	// against, for dialing it by IP; defaults to server_address
+	ServerName string `yaml:"server_name" json:"server_name"`
+	// MaxTransientErrors is the number of consecutive transient read/write errors
+	// tolerated before a connection is closed
+	MaxTransientErrors int `yaml:"max_transient_errors" json:"max_transient_errors"`
+	// TransientErrorDelay is the pause before retrying after a transient error
+	TransientErrorDelay time.Duration `yaml:"transient_error_delay" json:"transient_error_delay"`
+	// CoalesceWindow is how long packets sent to the peer may wait to be written
+	// together in one call; 0 disables coalescing. Coalesced packets are length
+	// prefixed, so both ends of a tunnel must enable it.
+	CoalesceWindow time.Duration `yaml:"coalesce_window" json:"coalesce_window"`
+	// CoalesceBytes writes the waiting packets once they hold this many bytes
+	CoalesceBytes int `yaml:"coalesce_bytes" json:"coalesce_bytes"`
+	// CoalescePackets writes the waiting packets once there are this many
+	CoalescePackets int `yaml:"coalesce_packets" json:"coalesce_packets"`
+	// HandshakeTimeout bounds the TLS handshake of an accepted connection, which
+	// is closed if the client has not completed it in time; defaults to 10s
+	HandshakeTimeout time.Duration `yaml:"handshake_timeout" json:"handshake_timeout"`
+	// DrainTimeout is how long the server waits for client connections to finish
+	// when it stops, before closing them; 0 closes them at once. Unset, it
+	// defaults to 30s.
+	DrainTimeout *time.Duration `yaml:"drain_timeout" json:"drain_timeout"`
+	// MaxMemory is the memory in bytes the server may use; memory pressure is
+	// measured against it. 0 uses GOMEMLIMIT, if set, and otherwise only GC
+	// load counts as pressure.
+	MaxMemory uint64 `yaml:"max_memory" json:"max_memory"`
+	// Multiplex carries the client's connections as streams of one connection
+	// to the server. The client offers it when connecting; a server with it
+	// enabled accepts, and still serves clients that do not offer it.
+	Multiplex bool `yaml:"multiplex" json:"multiplex"`
+	// Pool sizes the client's pool of connections to the server
+	Pool PoolConfig `yaml:"pool" json:"pool"`
+	// Endpoints are server addresses the client balances new connections over,
+	// in place of server_address and server_port
+	Endpoints []EndpointConfig `yaml:"endpoints" json:"endpoints"`
+	// EndpointHealth actively checks that the endpoints accept connections
+	EndpointHealth EndpointHealthConfig `yaml:"endpoint_health" json:"endpoint_health"`
+}
+
+// EndpointConfig represents one server endpoint of a client
+type EndpointConfig struct {
+	// Address is the endpoint in host:port form
+	Address string `yaml:"address" json:"address"`
+	// Weight is the endpoint's relative share of new connections; 0 means 1
+	Weight int `yaml:"weight" json:"weight"`
+	// ServerName is the hostname the endpoint's certificate is verified
+	// against, for dialing it by IP; defaults to the address's host
+	ServerName string `yaml:"server_name" json:"server_name"`
+}
+
+// EndpointHealthConfig represents active health checks of the client's server
+// endpoints. Zero values use the built-in defaults.
+type EndpointHealthConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Interval is the time between checks of each endpoint
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// Timeout bounds a single check
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// HealthyThreshold is the number of consecutive successful checks that mark
+	// a down endpoint up
+	HealthyThreshold int `yaml:"healthy_threshold" json:"healthy_threshold"`
+	// UnhealthyThreshold is the number of consecutive failed checks that mark
+	// an endpoint down
+	UnhealthyThreshold int `yaml:"unhealthy_threshold" json:"unhealthy_threshold"`
+}
+
+// PoolConfig represents the client's pool of connections to the server. Zero
+// values use the built-in defaults.
+type PoolConfig struct {
+	// MinIdle is the number of idle connections kept open in advance
+	MinIdle int `yaml:"min_idle" json:"min_idle"`
+	// MaxIdle is the most idle connections kept for reuse
+	MaxIdle int `yaml:"max_idle" json:"max_idle"`
+	// MaxActive is the most connections in use at once
+	MaxActive int `yaml:"max_active" json:"max_active"`
+	// IdleTimeout is how long an idle connection is kept before it is evicted
+	IdleTimeout time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
+	// WaitTimeout is how long dialing waits for a free connection when
+	// max_active are in use
+	WaitTimeout time.Duration `yaml:"wait_timeout" json:"wait_timeout"`
+}
+
+// DNSCacheConfig represents client-side caching of the server address resolution
+type DNSCacheConfig struct {
+	// Enabled turns on caching of resolved server addresses
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// TTL is how long a resolved address is reused before resolving again
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
 }
 
 // SecurityConfig represents security configuration
@@ -161,6 +315,54 @@ type SecurityConfig struct {
 	TLS               TLSConfigOptions        `yaml:"tls" json:"tls"`
 	AuthMethod        string                  `yaml:"auth_method" json:"auth_method"`
 	CertRotation      CertRotation            `yaml:"cert_rotation" json:"cert_rotation"`
+	CRL               CRLConfig               `yaml:"crl" json:"crl"`
+	ACL               ACLConfig               `yaml:"acl" json:"acl"`
+	RBAC              RBACConfig              `yaml:"rbac" json:"rbac"`
+	// PSK is the pre-shared key clients present when auth_method is psk
+	PSK string `yaml:"psk" json:"psk"`
+	// Tokens are the bearer tokens the server accepts when auth_method is token
+	Tokens []string `yaml:"tokens" json:"tokens"`
+	// Token is the bearer token the client presents when auth_method is token
+	Token string `yaml:"token" json:"token"`
+}
+
+// RBACConfig locates the role and policy model
+type RBACConfig struct {
+	// Model is the YAML file of roles and policies; it is re-read by the rbac reload command
+	Model string `yaml:"model" json:"model"`
+}
+
+// ACLConfig represents the connection allowlist/denylist
+type ACLConfig struct {
+	// File holds the allow and deny rules; it is re-read by the acl reload command
+	File string `yaml:"file" json:"file"`
+	// ReevaluateOnReload closes established connections that reloaded rules deny
+	ReevaluateOnReload bool `yaml:"reevaluate_on_reload" json:"reevaluate_on_reload"`
+	// Rules are checked in order before those of File, the first match winning
+	Rules []ACLRule `yaml:"rules" json:"rules"`
+	// DefaultAction applies to addresses no rule matches: allow (the default) or deny
+	DefaultAction string `yaml:"default_action" json:"default_action"`
+}
+
+// ACLRule allows or denies connections from a network
+type ACLRule struct {
+	// Network is a CIDR such as 192.0.2.0/24 or 2001:db8::/32
+	Network string `yaml:"network" json:"network"`
+	// Action is allow or deny
+	Action      string `yaml:"action" json:"action"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// CRLConfig represents client certificate revocation checking settings
+type CRLConfig struct {
+	// Enabled turns on CRL checking of client certificates during the TLS handshake
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Source is a CRL URL or local file; when empty the certificate's CRL distribution points are used
+	Source string `yaml:"source" json:"source"`
+	// RefreshInterval is how often the CRL is reloaded
+	RefreshInterval time.Duration `yaml:"refresh_interval" json:"refresh_interval"`
+	// FailOpen accepts client certificates when no current CRL is available
+	FailOpen bool `yaml:"fail_open" json:"fail_open"`
 }
 
 // MemoryProtectionsConfig represents memory protection settings
@@ -181,6 +383,12 @@ type CapabilitiesConfig struct {
 // SeccompConfig represents seccomp settings
 type SeccompConfig struct {
 	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Profile is a Docker/OCI-style JSON seccomp profile; the built-in allowlist
+	// is used when it is empty
+	Profile string `yaml:"profile" json:"profile"`
+	// Violations is deny (default) to fail denied system calls silently, or log
+	// to log each by name before failing it
+	Violations string `yaml:"violations" json:"violations"`
 }
 
 // TLSConfigOptions represents TLS security settings
@@ -188,6 +396,9 @@ type TLSConfigOptions struct {
 	MinVersion string   `yaml:"min_version" json:"min_version"`
 	MaxVersion string   `yaml:"max_version" json:"max_version"`
 	Ciphers    []string `yaml:"ciphers" json:"ciphers"`
+	// DisableSessionResumption makes reconnecting clients run a full handshake
+	// instead of resuming their session from a ticket
+	DisableSessionResumption bool `yaml:"disable_session_resumption" json:"disable_session_resumption"`
 }
 
 // CertRotation represents certificate rotation settings
@@ -202,6 +413,73 @@ type MonitorConfig struct {
 	Type       string           `yaml:"type" json:"type"`
 	Interval   time.Duration    `yaml:"interval" json:"interval"`
 	Prometheus PrometheusConfig `yaml:"prometheus" json:"prometheus"`
+	Summary    SummaryConfig    `yaml:"summary" json:"summary"`
+	Health     HealthConfig     `yaml:"health" json:"health"`
+	// HistorySize is the number of metrics snapshots, taken every Interval, kept
+	// for trend queries; 0 keeps none
+	HistorySize int `yaml:"history_size" json:"history_size"`
+	// Alerts notify a webhook when metrics cross thresholds
+	Alerts AlertsConfig `yaml:"alerts" json:"alerts"`
+}
+
+// AlertsConfig represents threshold alerts on the monitor metrics
+type AlertsConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Interval is the time between evaluations of the rules
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// WebhookURL receives a JSON POST when an alert fires or resolves
+	WebhookURL string `yaml:"webhook_url" json:"webhook_url"`
+	// Timeout bounds a single webhook call
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// Rules are the thresholds checked at each evaluation
+	Rules []AlertRuleConfig `yaml:"rules" json:"rules"`
+}
+
+// AlertRuleConfig represents a threshold on one metric
+type AlertRuleConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Metric is connections, error_rate, cpu_usage, memory_usage, packet_loss
+	// or memory_pressure
+	Metric string `yaml:"metric" json:"metric"`
+	// Threshold is the value the metric must exceed to fire
+	Threshold float64 `yaml:"threshold" json:"threshold"`
+	// Level is the memory pressure (low, medium, high or critical) that fires a
+	// memory_pressure rule, in place of Threshold
+	Level string `yaml:"level" json:"level"`
+	// For is how long the threshold must stay crossed before the alert fires
+	For time.Duration `yaml:"for" json:"for"`
+	// Cooldown is the least time between two firings of the rule
+	Cooldown time.Duration `yaml:"cooldown" json:"cooldown"`
+}
+
+// HealthConfig represents the thresholds of the composite health check. Zero
+// values select the defaults.
+type HealthConfig struct {
+	// DegradedOpenBreakers is the number of open circuit breakers that makes the
+	// service degraded
+	DegradedOpenBreakers int `yaml:"degraded_open_breakers" json:"degraded_open_breakers"`
+	// UnhealthyOpenBreakers is the number of open circuit breakers that makes the
+	// service unhealthy
+	UnhealthyOpenBreakers int `yaml:"unhealthy_open_breakers" json:"unhealthy_open_breakers"`
+	// DegradedMemoryPressure is the memory pressure level that makes the service
+	// degraded (none, low, medium, high or critical)
+	DegradedMemoryPressure string `yaml:"degraded_memory_pressure" json:"degraded_memory_pressure"`
+	// UnhealthyMemoryPressure is the memory pressure level that makes the service
+	// unhealthy
+	UnhealthyMemoryPressure string `yaml:"unhealthy_memory_pressure" json:"unhealthy_memory_pressure"`
+	// DegradedConnectionRatio is the share of the connection limit in use that
+	// makes the service degraded
+	DegradedConnectionRatio float64 `yaml:"degraded_connection_ratio" json:"degraded_connection_ratio"`
+	// UnhealthyConnectionRatio is the share of the connection limit in use that
+	// makes the service unhealthy
+	UnhealthyConnectionRatio float64 `yaml:"unhealthy_connection_ratio" json:"unhealthy_connection_ratio"`
+}
+
+// SummaryConfig represents the periodic connection summary log
+type SummaryConfig struct {
+	Enabled    bool          `yaml:"enabled" json:"enabled"`
+	Interval   time.Duration `yaml:"interval" json:"interval"`
+	TopTalkers int           `yaml:"top_talkers" json:"top_talkers"`
 }
 
 // PrometheusConfig represents Prometheus monitoring settings
@@ -210,6 +488,22 @@ type PrometheusConfig struct {
 	Port       int    `yaml:"port" json:"port"`
 	Path       string `yaml:"path" json:"path"`
 	BufferSize int    `yaml:"buffer_size" json:"buffer_size"`
+	// RemoteWrite pushes the metrics for environments that cannot scrape
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write" json:"remote_write"`
+}
+
+// RemoteWriteConfig represents pushing metrics to a Prometheus remote-write endpoint
+type RemoteWriteConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// URL is the remote-write endpoint, e.g. https://prometheus:9090/api/v1/write
+	URL string `yaml:"url" json:"url"`
+	// Interval is the time between pushes
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// Timeout bounds a single push
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// Username and Password enable basic auth when Username is set
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
 }
 
 // MetricsConfig represents metrics configuration
@@ -225,6 +519,42 @@ type SNMPConfig struct {
 	Enabled   bool   `yaml:"enabled" json:"enabled"`
 	Port      int    `yaml:"port" json:"port"`
 	Community string `yaml:"community" json:"community"`
+	// Address is the address the agent listens on; empty listens on all
+	Address string `yaml:"address" json:"address"`
+	// ReportInterval is how often the agent logs its request statistics
+	ReportInterval time.Duration `yaml:"report_interval" json:"report_interval"`
+	// ReportJitter is the fraction the report interval varies by; negative disables
+	ReportJitter float64 `yaml:"report_jitter" json:"report_jitter"`
+	// AllowedNetworks are the CIDRs allowed to query the agent; empty allows all
+	AllowedNetworks []string `yaml:"allowed_networks" json:"allowed_networks"`
+	// RateLimit is the requests per second answered per source IP; 0 disables
+	RateLimit float64 `yaml:"rate_limit" json:"rate_limit"`
+	// RateBurst is the requests a source IP may send at once; 0 uses the rate
+	RateBurst int `yaml:"rate_burst" json:"rate_burst"`
+}
+
+// RecoveryConfig represents error recovery configuration
+type RecoveryConfig struct {
+	MaxRetries int                               `yaml:"max_retries" json:"max_retries"`
+	Timeout    time.Duration                     `yaml:"timeout" json:"timeout"`
+	Strategies map[string]RecoveryStrategyConfig `yaml:"strategies" json:"strategies"`
+	// MaxConcurrent is the most recoveries run at once; 0 is unlimited
+	MaxConcurrent int `yaml:"max_concurrent" json:"max_concurrent"`
+	// ShedExcess returns the original error for recoveries past MaxConcurrent
+	// instead of queuing them
+	ShedExcess bool `yaml:"shed_excess" json:"shed_excess"`
+}
+
+// RecoveryStrategyConfig represents the settings of a built-in recovery strategy.
+// Zero values leave the strategy's own default in place.
+type RecoveryStrategyConfig struct {
+	Enabled    bool          `yaml:"enabled" json:"enabled"`
+	BaseDelay  time.Duration `yaml:"base_delay" json:"base_delay"`
+	MaxDelay   time.Duration `yaml:"max_delay" json:"max_delay"`
+	RetryDelay time.Duration `yaml:"retry_delay" json:"retry_delay"`
+	ResetDelay time.Duration `yaml:"reset_delay" json:"reset_delay"`
+	MaxRetries int           `yaml:"max_retries" json:"max_retries"`
+	MaxResets  int           `yaml:"max_resets" json:"max_resets"`
 }
 
 // ThrottleConfig represents rate limiting configuration
@@ -232,8 +562,26 @@ type ThrottleConfig struct {
 	Enabled bool    `yaml:"enabled" json:"enabled"`
 	Rate    float64 `yaml:"rate" json:"rate"`
 	Burst   int     `yaml:"burst" json:"burst"`
+	// PerClient gives each client its own rate and burst in addition to the global limit
+	PerClient bool `yaml:"per_client" json:"per_client"`
+	// Connections limits how fast each source IP may open connections to the server
+	Connections ConnectionThrottleConfig `yaml:"connections" json:"connections"`
 }
 
+// ConnectionThrottleConfig represents the per-source-IP connection rate limit
+type ConnectionThrottleConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Rate is the sustained number of connections per second from one IP
+	Rate float64 `yaml:"rate" json:"rate"`
+	// Burst is the number of connections one IP may open at once
+	Burst int `yaml:"burst" json:"burst"`
+	// IdleTimeout is how long an IP is tracked after its last connection
+	IdleTimeout time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
+}
+
+// CurrentSchemaVersion is the configuration schema version understood by this binary
+const CurrentSchemaVersion = "2.0.0"
+
 // DefaultConfig returns a default configuration
 func DefaultConfig() *AppConfig {
 	return NewAppConfig(TypeServer)
@@ -246,19 +594,19 @@ func NewAppConfig(configType Type) *AppConfig {
 		Config:  &Config{Mode: string(configType)},
 		Version: "1.0.0",
 		Metadata: ConfigMetadata{
-			Version:     "1.0.0",
-			Created:     time.Now(),
-			Modified:    time.Now(),
-			CreatedBy:   "system",
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Environment: "development",
-			Region:      "local",
-			SchemaVersion: "1.0.0",
+			Version:       "1.0.0",
+			Created:       time.Now(),
+			Modified:      time.Now(),
+			CreatedBy:     "system",
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			Environment:   "development",
+			Region:        "local",
+			SchemaVersion: CurrentSchemaVersion,
 			MigrationHistory: []MigrationRecord{
 				{
 					FromVersion: "0.0.0",
-					ToVersion:   "1.0.0",
+					ToVersion:   CurrentSchemaVersion,
 					Timestamp:   time.Now(),
 					Status:      "completed",
 					Notes:       "Initial configuration schema",
@@ -0,0 +1,42 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// ValidateDuplicateSettings checks settings that can be given in more than one place.
+// Either location may be used on its own, but when both are set they must agree.
+func (v *Validator) ValidateDuplicateSettings(config *types.Config) error {
+	auth := config.Auth
+	security := config.Security
+
+	paths := []struct {
+		alias, aliasValue, canonical, canonicalValue string
+	}{
+		{"auth.certificate", auth.Certificate, "auth.cert_file", auth.CertFile},
+		{"auth.key", auth.Key, "auth.key_file", auth.KeyFile},
+		{"auth.ca_certificate", auth.CACertificate, "auth.ca_file", auth.CAFile},
+		{"auth.auth_method", auth.AuthMethod, "security.auth_method", security.AuthMethod},
+	}
+	for _, p := range paths {
+		if p.aliasValue != "" && p.canonicalValue != "" && p.aliasValue != p.canonicalValue {
+			return fmt.Errorf("%s (%q) conflicts with %s (%q)", p.alias, p.aliasValue, p.canonical, p.canonicalValue)
+		}
+	}
+
+	if isCertRotationSet(auth.CertRotation) && isCertRotationSet(security.CertRotation) &&
+		auth.CertRotation != security.CertRotation {
+		return fmt.Errorf("auth.cert_rotation (enabled=%t, interval=%v) conflicts with security.cert_rotation (enabled=%t, interval=%v)",
+			auth.CertRotation.Enabled, auth.CertRotation.Interval,
+			security.CertRotation.Enabled, security.CertRotation.Interval)
+	}
+
+	return nil
+}
+
+// isCertRotationSet reports whether any certificate rotation setting was given
+func isCertRotationSet(rotation types.CertRotation) bool {
+	return rotation.Enabled || rotation.Interval != 0
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestValidateDuplicateSettings(t *testing.T) {
+	rotation := types.CertRotation{Enabled: true, Interval: 720 * time.Hour}
+
+	tests := []struct {
+		name    string
+		config  types.Config
+		wantErr string
+	}{
+		{
+			name: "consistent duplicates",
+			config: types.Config{
+				Auth: types.AuthConfig{
+					Certificate:  "/etc/sssonector/certs/server.crt",
+					CertFile:     "/etc/sssonector/certs/server.crt",
+					AuthMethod:   "certificate",
+					CertRotation: rotation,
+				},
+				Security: types.SecurityConfig{
+					AuthMethod:   "certificate",
+					CertRotation: rotation,
+				},
+			},
+		},
+		{
+			name: "settings in one place only",
+			config: types.Config{
+				Auth:     types.AuthConfig{KeyFile: "/etc/sssonector/certs/server.key"},
+				Security: types.SecurityConfig{CertRotation: rotation},
+			},
+		},
+		{
+			name: "conflicting certificate paths",
+			config: types.Config{
+				Auth: types.AuthConfig{
+					Certificate: "/etc/sssonector/certs/old.crt",
+					CertFile:    "/etc/sssonector/certs/server.crt",
+				},
+			},
+			wantErr: "auth.certificate",
+		},
+		{
+			name: "conflicting auth method",
+			config: types.Config{
+				Auth:     types.AuthConfig{AuthMethod: "certificate"},
+				Security: types.SecurityConfig{AuthMethod: "psk"},
+			},
+			wantErr: "security.auth_method",
+		},
+		{
+			name: "conflicting rotation",
+			config: types.Config{
+				Auth:     types.AuthConfig{CertRotation: rotation},
+				Security: types.SecurityConfig{CertRotation: types.CertRotation{Enabled: true, Interval: 24 * time.Hour}},
+			},
+			wantErr: "cert_rotation",
+		},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateDuplicateSettings(&tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package validator
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// LintWarning describes a setting that is valid but weakens security
+type LintWarning struct {
+	Setting string
+	Message string
+}
+
+// String returns the warning as "setting: message"
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Setting, w.Message)
+}
+
+// insecureCiphers holds the cipher suites crypto/tls considers insecure
+var insecureCiphers = func() map[string]bool {
+	ciphers := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		ciphers[suite.Name] = true
+	}
+	return ciphers
+}()
+
+// Lint reports insecure but valid settings. Unlike Validate it never fails; each
+// finding is returned as a warning.
+func (v *Validator) Lint(config *types.AppConfig) []LintWarning {
+	if config == nil || config.Config == nil {
+		return nil
+	}
+	cfg := config.Config
+	var warnings []LintWarning
+
+	for _, tv := range []struct{ setting, version string }{
+		{"security.tls.min_version", cfg.Security.TLS.MinVersion},
+		{"security.tls.max_version", cfg.Security.TLS.MaxVersion},
+	} {
+		switch strings.TrimPrefix(strings.ToLower(tv.version), "tls") {
+		case "1.0", "1.1":
+			warnings = append(warnings, LintWarning{tv.setting,
+				fmt.Sprintf("TLS %s is deprecated; use 1.2 or later", tv.version)})
+		}
+	}
+
+	for _, cipher := range cfg.Security.TLS.Ciphers {
+		if insecureCiphers[cipher] || strings.Contains(cipher, "_CBC_") {
+			warnings = append(warnings, LintWarning{"security.tls.ciphers",
+				fmt.Sprintf("cipher suite %s is weak; prefer AEAD suites with ECDHE", cipher)})
+		}
+	}
+
+	if cfg.SNMP.Enabled && (cfg.SNMP.Community == "" || cfg.SNMP.Community == "public") {
+		warnings = append(warnings, LintWarning{"snmp.community",
+			`SNMP uses the default community "public"; set a private community string`})
+	}
+
+	// Only a server with a certificate of its own rotates it
+	if cfg.Mode == string(types.ModeServer) && cfg.Auth.CertFile != "" &&
+		!cfg.Auth.CertRotation.Enabled && !cfg.Security.CertRotation.Enabled {
+		warnings = append(warnings, LintWarning{"security.cert_rotation",
+			"certificate rotation is disabled; certificates must be renewed by hand"})
+	}
+
+	return warnings
+}
@@ -0,0 +1,63 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestLint(t *testing.T) {
+	config := &types.AppConfig{Config: &types.Config{}}
+	config.Config.SNMP = types.SNMPConfig{Enabled: true, Port: 161, Community: "public"}
+	config.Config.Security.TLS = types.TLSConfigOptions{
+		MinVersion: "1.0",
+		MaxVersion: "1.3",
+		Ciphers:    []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA"},
+	}
+	config.Config.Security.CertRotation = types.CertRotation{Enabled: true, Interval: 720 * time.Hour}
+
+	warnings := NewValidator().Lint(config)
+
+	got := make(map[string]int)
+	for _, w := range warnings {
+		got[w.Setting]++
+	}
+	want := map[string]int{
+		"snmp.community":           1,
+		"security.tls.min_version": 1,
+		"security.tls.ciphers":     1,
+	}
+	for setting, count := range want {
+		if got[setting] != count {
+			t.Errorf("Expected %d warning(s) for %s, got %d: %v", count, setting, got[setting], warnings)
+		}
+	}
+	if len(warnings) != 3 {
+		t.Errorf("Expected 3 warnings, got %d: %v", len(warnings), warnings)
+	}
+
+	// A hardened configuration produces no warnings
+	config.Config.SNMP.Community = "s3cret"
+	config.Config.Security.TLS.MinVersion = "1.2"
+	config.Config.Security.TLS.Ciphers = []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}
+	if warnings := NewValidator().Lint(config); len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got: %v", warnings)
+	}
+
+	// Disabled certificate rotation is only reported for a server with a certificate
+	config.Config.Security.CertRotation.Enabled = false
+	if warnings := NewValidator().Lint(config); len(warnings) != 0 {
+		t.Errorf("Expected no cert rotation warning without a server certificate, got: %v", warnings)
+	}
+	config.Config.Mode = string(types.ModeServer)
+	config.Config.Auth.CertFile = "server.crt"
+	warnings = NewValidator().Lint(config)
+	if len(warnings) != 1 || warnings[0].Setting != "security.cert_rotation" {
+		t.Errorf("Expected a cert rotation warning, got: %v", warnings)
+	}
+	config.Config.Mode = string(types.ModeClient)
+	if warnings := NewValidator().Lint(config); len(warnings) != 0 {
+		t.Errorf("Expected no cert rotation warning for a client, got: %v", warnings)
+	}
+}
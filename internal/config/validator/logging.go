@@ -0,0 +1,37 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// Log outputs accepted in logging.output
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+	LogOutputFile   = "file"
+)
+
+// LogOutputPath returns where logs are written: "stdout", "stderr" or the log
+// file path. logging.output wins over logging.file; the file is only used when
+// output is "file", or when output is empty and a file is set. Without either,
+// logs go to stderr. When a file is set but output sends logs elsewhere, the
+// returned warning says that the file is ignored.
+func LogOutputPath(config types.LoggingConfig) (path string, warning string) {
+	switch config.Output {
+	case LogOutputFile:
+		return config.File, ""
+	case LogOutputStdout, LogOutputStderr:
+		if config.File != "" {
+			warning = fmt.Sprintf("logging.file %q is ignored because logging.output is %s; set output to file to use it",
+				config.File, config.Output)
+		}
+		return config.Output, warning
+	default:
+		if config.File != "" {
+			return config.File, ""
+		}
+		return LogOutputStderr, ""
+	}
+}
@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestLogOutputPath(t *testing.T) {
+	tests := []struct {
+		config      types.LoggingConfig
+		wantPath    string
+		wantWarning bool
+	}{
+		{types.LoggingConfig{}, "stderr", false},
+		{types.LoggingConfig{File: "/var/log/sssonector.log"}, "/var/log/sssonector.log", false},
+		{types.LoggingConfig{Output: "file", File: "/var/log/sssonector.log"}, "/var/log/sssonector.log", false},
+		{types.LoggingConfig{Output: "stdout"}, "stdout", false},
+		{types.LoggingConfig{Output: "stdout", File: "/var/log/sssonector.log"}, "stdout", true},
+		{types.LoggingConfig{Output: "stderr", File: "/var/log/sssonector.log"}, "stderr", true},
+	}
+
+	for _, tt := range tests {
+		path, warning := LogOutputPath(tt.config)
+		if path != tt.wantPath {
+			t.Errorf("Expected %+v to log to %s, got %s", tt.config, tt.wantPath, path)
+		}
+		if tt.wantWarning != (warning != "") {
+			t.Errorf("Expected warning %v for %+v, got %q", tt.wantWarning, tt.config, warning)
+		}
+		if warning != "" && !strings.Contains(warning, "logging.file") {
+			t.Errorf("Expected the warning to name logging.file, got %q", warning)
+		}
+	}
+}
+
+func TestValidateLoggingOutput(t *testing.T) {
+	tests := []struct {
+		config  types.LoggingConfig
+		wantErr bool
+	}{
+		{types.LoggingConfig{Level: "info"}, false},
+		{types.LoggingConfig{Level: "info", Output: "stdout", File: "/var/log/sssonector.log"}, false},
+		{types.LoggingConfig{Level: "info", Output: "file", File: "/var/log/sssonector.log"}, false},
+		{types.LoggingConfig{Level: "info", Output: "file"}, true},
+		{types.LoggingConfig{Level: "info", Output: "syslog"}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		err := v.validateLogging(tt.config)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected %+v to be rejected", tt.config)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected %+v to be accepted, got %v", tt.config, err)
+		}
+	}
+}
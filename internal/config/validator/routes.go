@@ -0,0 +1,147 @@
+package validator
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// ValidateInterfaceAddress validates the tunnel interface address. The address may be
+// a plain IP or an IP with prefix length (e.g. 10.0.0.1/24); in the latter case it
+// must be a usable host within its implied network. The returned network is nil for
+// plain IP addresses.
+func (v *Validator) ValidateInterfaceAddress(address string) (net.IP, *net.IPNet, error) {
+	if !strings.Contains(address, "/") {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return nil, nil, fmt.Errorf("invalid IP address: %s", address)
+		}
+		if ip.IsUnspecified() || ip.IsMulticast() || ip.IsLoopback() {
+			return nil, nil, fmt.Errorf("invalid interface address type: %s", address)
+		}
+		return ip, nil, nil
+	}
+
+	ip, network, err := net.ParseCIDR(address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid interface address: %s", address)
+	}
+
+	if ip.IsUnspecified() || ip.IsMulticast() || ip.IsLoopback() {
+		return nil, nil, fmt.Errorf("invalid interface address type: %s", address)
+	}
+
+	ones, bits := network.Mask.Size()
+	if bits-ones >= 2 {
+		if ip.Equal(network.IP) {
+			return nil, nil, fmt.Errorf("interface address %s is the network address of %s", address, network)
+		}
+		if ip4 := ip.To4(); ip4 != nil && ip4.Equal(broadcastAddress(network)) {
+			return nil, nil, fmt.Errorf("interface address %s is the broadcast address of %s", address, network)
+		}
+	}
+
+	return ip, network, nil
+}
+
+// ValidateRoutes validates the configured tunnel routes. The number of routes is
+// limited by MaxRoutes, every route must be a canonical CIDR, routes must not
+// overlap each other or the tunnel's own network, and a default route is rejected
+// in client mode unless AllowDefaultRoute is set.
+func (v *Validator) ValidateRoutes(network types.NetworkConfig, mode string) error {
+	maxRoutes, err := listLimit("max_routes", network.MaxRoutes, types.DefaultMaxRoutes)
+	if err != nil {
+		return err
+	}
+	if len(network.Routes) > maxRoutes {
+		return fmt.Errorf("%d routes exceed the limit of %d (set max_routes to raise it)", len(network.Routes), maxRoutes)
+	}
+
+	var (
+		tunnelIP  net.IP
+		tunnelNet *net.IPNet
+	)
+	if network.Address != "" {
+		tunnelIP, tunnelNet, err = v.ValidateInterfaceAddress(network.Address)
+		if err != nil {
+			return err
+		}
+	}
+
+	routes := make([]*net.IPNet, 0, len(network.Routes))
+	for _, route := range network.Routes {
+		ip, routeNet, err := net.ParseCIDR(route)
+		if err != nil {
+			return fmt.Errorf("invalid route CIDR: %s", route)
+		}
+		if !ip.Equal(routeNet.IP) {
+			return fmt.Errorf("route %s has host bits set (did you mean %s?)", route, routeNet)
+		}
+
+		isDefault := isDefaultRoute(routeNet)
+		if isDefault && mode == types.ModeClient && !network.AllowDefaultRoute {
+			return fmt.Errorf("default route %s not allowed in client mode (set allow_default_route to permit)", route)
+		}
+
+		for i, existing := range routes {
+			if networksOverlap(existing, routeNet) {
+				return fmt.Errorf("route %s overlaps route %s", route, network.Routes[i])
+			}
+		}
+
+		if !isDefault {
+			if tunnelNet != nil && networksOverlap(tunnelNet, routeNet) {
+				return fmt.Errorf("route %s conflicts with tunnel network %s", route, tunnelNet)
+			}
+			if tunnelNet == nil && tunnelIP != nil && routeNet.Contains(tunnelIP) {
+				return fmt.Errorf("route %s contains tunnel address %s", route, tunnelIP)
+			}
+		}
+
+		routes = append(routes, routeNet)
+	}
+
+	return nil
+}
+
+// listLimit returns a configured list limit, or def when it is unset
+func listLimit(name string, limit, def int) (int, error) {
+	if limit < 0 {
+		return 0, fmt.Errorf("%s cannot be negative: %d", name, limit)
+	}
+	if limit == 0 {
+		return def, nil
+	}
+	return limit, nil
+}
+
+// networksOverlap reports whether two networks share any address
+func networksOverlap(a, b *net.IPNet) bool {
+	if (a.IP.To4() == nil) != (b.IP.To4() == nil) {
+		return false
+	}
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// isDefaultRoute reports whether the network is 0.0.0.0/0 or ::/0
+func isDefaultRoute(n *net.IPNet) bool {
+	ones, _ := n.Mask.Size()
+	return ones == 0
+}
+
+// broadcastAddress returns the IPv4 broadcast address of a network
+func broadcastAddress(n *net.IPNet) net.IP {
+	ip := n.IP.To4()
+	mask := net.IP(n.Mask).To4()
+	if ip == nil || mask == nil {
+		return nil
+	}
+
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range ip {
+		broadcast[i] = ip[i] | ^mask[i]
+	}
+	return broadcast
+}
@@ -0,0 +1,250 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestValidateRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		network types.NetworkConfig
+		wantErr []string
+	}{
+		{
+			name: "valid routes",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address: "10.0.0.1/24",
+				Routes:  []string{"192.168.1.0/24", "192.168.2.0/24", "fd00::/64"},
+			},
+		},
+		{
+			name:    "plain address without routes",
+			mode:    types.ModeServer,
+			network: types.NetworkConfig{Address: "10.0.0.1"},
+		},
+		{
+			name: "point-to-point address",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address: "10.0.0.0/31",
+			},
+		},
+		{
+			name: "invalid route",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Routes: []string{"192.168.1.0/33"},
+			},
+			wantErr: []string{"192.168.1.0/33"},
+		},
+		{
+			name: "route without prefix length",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				Routes: []string{"192.168.1.0"},
+			},
+			wantErr: []string{"invalid route CIDR", "192.168.1.0"},
+		},
+		{
+			name: "too many routes",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				Routes: testRoutes(types.DefaultMaxRoutes + 1),
+			},
+			wantErr: []string{"257 routes exceed the limit of 256"},
+		},
+		{
+			name: "routes within raised limit",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				Routes:    testRoutes(300),
+				MaxRoutes: 300,
+			},
+		},
+		{
+			name: "routes over configured limit",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				Routes:    testRoutes(3),
+				MaxRoutes: 2,
+			},
+			wantErr: []string{"3 routes exceed the limit of 2"},
+		},
+		{
+			name: "negative route limit",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				MaxRoutes: -1,
+			},
+			wantErr: []string{"max_routes"},
+		},
+		{
+			name: "route with host bits",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Routes: []string{"192.168.1.7/24"},
+			},
+			wantErr: []string{"192.168.1.7/24", "192.168.1.0/24"},
+		},
+		{
+			name: "overlapping routes",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Routes: []string{"192.168.0.0/16", "172.16.0.0/12", "192.168.4.0/24"},
+			},
+			wantErr: []string{"192.168.4.0/24", "192.168.0.0/16"},
+		},
+		{
+			name: "duplicate routes",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				Routes: []string{"fd00::/64", "fd00::/64"},
+			},
+			wantErr: []string{"fd00::/64 overlaps route fd00::/64"},
+		},
+		{
+			name: "route overlaps tunnel network",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address: "10.0.0.1/24",
+				Routes:  []string{"10.0.0.128/25"},
+			},
+			wantErr: []string{"10.0.0.128/25", "10.0.0.0/24"},
+		},
+		{
+			name: "route contains tunnel address",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address: "10.0.0.1",
+				Routes:  []string{"10.0.0.0/8"},
+			},
+			wantErr: []string{"10.0.0.0/8", "10.0.0.1"},
+		},
+		{
+			name: "network address as interface address",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address: "10.0.0.0/24",
+			},
+			wantErr: []string{"network address"},
+		},
+		{
+			name: "broadcast address as interface address",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address: "10.0.0.255/24",
+			},
+			wantErr: []string{"broadcast address"},
+		},
+		{
+			name: "default route in client mode",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address: "10.0.0.2/24",
+				Routes:  []string{"0.0.0.0/0"},
+			},
+			wantErr: []string{"0.0.0.0/0", "allow_default_route"},
+		},
+		{
+			name: "IPv6 default route in client mode",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Routes: []string{"::/0"},
+			},
+			wantErr: []string{"::/0"},
+		},
+		{
+			name: "default route allowed in client mode",
+			mode: types.ModeClient,
+			network: types.NetworkConfig{
+				Address:           "10.0.0.2/24",
+				Routes:            []string{"0.0.0.0/0"},
+				AllowDefaultRoute: true,
+			},
+		},
+		{
+			name: "default route in server mode",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				Address: "10.0.0.1/24",
+				Routes:  []string{"0.0.0.0/0"},
+			},
+		},
+		{
+			name: "default route overlaps specific route",
+			mode: types.ModeServer,
+			network: types.NetworkConfig{
+				Routes: []string{"0.0.0.0/0", "192.168.1.0/24"},
+			},
+			wantErr: []string{"192.168.1.0/24 overlaps route 0.0.0.0/0"},
+		},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateRoutes(tt.network, tt.mode)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected error containing %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateNetworkDNSServers(t *testing.T) {
+	v := NewValidator()
+	network := types.NetworkConfig{
+		Interface:  "tun0",
+		MTU:        types.DefaultMTU,
+		DNSServers: []string{"10.0.0.53", "fd00::53"},
+	}
+	if err := v.validateNetwork(network); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	network.DNSServers = make([]string, types.DefaultMaxDNSServers+1)
+	for i := range network.DNSServers {
+		network.DNSServers[i] = fmt.Sprintf("10.0.0.%d", i+1)
+	}
+	err := v.validateNetwork(network)
+	if err == nil || !strings.Contains(err.Error(), "max_dns_servers") {
+		t.Errorf("Expected DNS server limit error, got: %v", err)
+	}
+
+	network.MaxDNSServers = len(network.DNSServers)
+	if err := v.validateNetwork(network); err != nil {
+		t.Errorf("Expected no error with raised limit, got: %v", err)
+	}
+
+	network.DNSServers = []string{"10.0.0.300"}
+	if err := v.validateNetwork(network); err == nil {
+		t.Error("Expected error for invalid DNS server IP")
+	}
+}
+
+// testRoutes returns n distinct /24 routes
+func testRoutes(n int) []string {
+	routes := make([]string, n)
+	for i := range routes {
+		routes[i] = fmt.Sprintf("10.%d.%d.0/24", 100+i/256, i%256)
+	}
+	return routes
+}
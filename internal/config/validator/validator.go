@@ -2,8 +2,11 @@
 package validator
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -47,6 +50,11 @@ func (v *Validator) Validate(config *types.AppConfig) error {
 		return fmt.Errorf("invalid security config: %v", err)
 	}
 
+	// Validate settings that appear in more than one section
+	if err := v.ValidateDuplicateSettings(config.Config); err != nil {
+		return fmt.Errorf("conflicting settings: %v", err)
+	}
+
 	// Validate certificate file paths and extensions
 	if err := v.validateCertificateFilesExist(config); err != nil {
 		return fmt.Errorf("invalid certificate files: %v", err)
@@ -74,6 +82,10 @@ func (v *Validator) Validate(config *types.AppConfig) error {
 		return fmt.Errorf("invalid network config: %v", err)
 	}
 
+	if err := v.ValidateRoutes(config.Config.Network, config.Config.Mode); err != nil {
+		return fmt.Errorf("invalid network routes: %v", err)
+	}
+
 	if err := v.validateTunnel(config.Config.Tunnel); err != nil {
 		return fmt.Errorf("invalid tunnel config: %v", err)
 	}
@@ -90,6 +102,10 @@ func (v *Validator) Validate(config *types.AppConfig) error {
 		return fmt.Errorf("invalid metrics config: %v", err)
 	}
 
+	if err := v.validateSNMP(config.Config.SNMP); err != nil {
+		return fmt.Errorf("invalid snmp config: %v", err)
+	}
+
 	if err := v.validateThrottle(config.Throttle); err != nil {
 		return fmt.Errorf("invalid throttle config: %v", err)
 	}
@@ -108,6 +124,9 @@ func (v *Validator) validateMigration(config *types.AppConfig) error {
 
 	// Define valid migration paths (from -> to)
 	validMigrations := map[string]map[string]bool{
+		"0.0.0": {
+			"1.0.0": true,
+		},
 		"1.0.0": {
 			"1.1.0": true,
 			"2.0.0": true,
@@ -254,6 +273,16 @@ func (v *Validator) validateLogging(config types.LoggingConfig) error {
 		return fmt.Errorf("invalid log level: %s", config.Level)
 	}
 
+	switch config.Output {
+	case "", LogOutputStdout, LogOutputStderr:
+	case LogOutputFile:
+		if config.File == "" {
+			return fmt.Errorf("log output is file but no log file is set")
+		}
+	default:
+		return fmt.Errorf("invalid log output: %s", config.Output)
+	}
+
 	return nil
 }
 
@@ -266,12 +295,29 @@ func (v *Validator) validateNetwork(config types.NetworkConfig) error {
 		return fmt.Errorf("invalid MTU: %d", config.MTU)
 	}
 
+	if config.MaxPacketSize != 0 {
+		if config.MaxPacketSize < config.MTU {
+			return fmt.Errorf("max packet size %d is smaller than MTU %d", config.MaxPacketSize, config.MTU)
+		}
+		if config.MaxPacketSize > types.MaxPacketSizeLimit {
+			return fmt.Errorf("max packet size %d exceeds limit %d", config.MaxPacketSize, types.MaxPacketSizeLimit)
+		}
+	}
+
 	if config.Address != "" {
-		if ip := net.ParseIP(config.Address); ip == nil {
-			return fmt.Errorf("invalid IP address: %s", config.Address)
+		if _, _, err := v.ValidateInterfaceAddress(config.Address); err != nil {
+			return err
 		}
 	}
 
+	maxDNSServers, err := listLimit("max_dns_servers", config.MaxDNSServers, types.DefaultMaxDNSServers)
+	if err != nil {
+		return err
+	}
+	if len(config.DNSServers) > maxDNSServers {
+		return fmt.Errorf("%d DNS servers exceed the limit of %d (set max_dns_servers to raise it)", len(config.DNSServers), maxDNSServers)
+	}
+
 	for _, dns := range config.DNSServers {
 		if ip := net.ParseIP(dns); ip == nil {
 			return fmt.Errorf("invalid DNS server IP: %s", dns)
@@ -304,6 +350,33 @@ func (v *Validator) ValidateCIDR(cidrStr string) error {
 	return nil
 }
 
+// ValidateHostname checks that name is a DNS hostname usable for TLS server name
+// verification. IP addresses are rejected.
+func (v *Validator) ValidateHostname(name string) error {
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("%s is an IP address, not a hostname", name)
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("hostname too long: %d characters", len(name))
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return fmt.Errorf("invalid hostname label %q in %s", label, name)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("hostname label %q in %s starts or ends with a hyphen", label, name)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("invalid character %q in hostname %s", r, name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (v *Validator) validateEnvironmentConfig(config *types.AppConfig) error {
 	// Validate environment-specific settings
 	validEnvironments := map[string]bool{
@@ -351,6 +424,129 @@ func (v *Validator) validateTunnel(config types.TunnelConfig) error {
 		return fmt.Errorf("invalid protocol: %s", config.Protocol)
 	}
 
+	if config.ServerName != "" {
+		if err := v.ValidateHostname(config.ServerName); err != nil {
+			return fmt.Errorf("invalid server name: %v", err)
+		}
+	}
+
+	if config.DNSCache.Enabled && config.DNSCache.TTL < 0 {
+		return fmt.Errorf("invalid DNS cache TTL: %v", config.DNSCache.TTL)
+	}
+
+	if config.MaxTransientErrors < 0 {
+		return fmt.Errorf("invalid max transient errors: %d", config.MaxTransientErrors)
+	}
+
+	if config.TransientErrorDelay < 0 {
+		return fmt.Errorf("invalid transient error delay: %v", config.TransientErrorDelay)
+	}
+
+	if config.HandshakeTimeout < 0 {
+		return fmt.Errorf("invalid handshake timeout: %v", config.HandshakeTimeout)
+	}
+
+	if config.DrainTimeout != nil && *config.DrainTimeout < 0 {
+		return fmt.Errorf("invalid drain timeout: %v", *config.DrainTimeout)
+	}
+
+	if config.Keepalive != "" {
+		if keepalive, err := time.ParseDuration(config.Keepalive); err != nil || keepalive <= 0 {
+			return fmt.Errorf("invalid keepalive: %q (must be a positive duration such as 30s)", config.Keepalive)
+		}
+	}
+
+	if config.KeepaliveMisses < 0 {
+		return fmt.Errorf("invalid keepalive misses: %d", config.KeepaliveMisses)
+	}
+
+	if config.Heartbeat && config.Keepalive == "" {
+		return fmt.Errorf("heartbeat requires a keepalive interval")
+	}
+
+	if config.CoalesceWindow < 0 || config.CoalesceWindow > types.MaxCoalesceWindow {
+		return fmt.Errorf("invalid coalesce window: %v (must be between 0 and %v)", config.CoalesceWindow, types.MaxCoalesceWindow)
+	}
+
+	if config.CoalesceBytes < 0 {
+		return fmt.Errorf("invalid coalesce bytes: %d", config.CoalesceBytes)
+	}
+
+	if config.CoalescePackets < 0 {
+		return fmt.Errorf("invalid coalesce packets: %d", config.CoalescePackets)
+	}
+
+	if err := v.validatePool(config.Pool); err != nil {
+		return fmt.Errorf("invalid pool configuration: %v", err)
+	}
+
+	listening := make(map[string]bool, len(config.ListenAddresses))
+	for _, address := range config.ListenAddresses {
+		_, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("invalid listen address %q: %v", address, err)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid listen address %q: invalid port %s", address, port)
+		}
+		if listening[address] {
+			return fmt.Errorf("duplicate listen address: %s", address)
+		}
+		listening[address] = true
+	}
+
+	if config.ProxyProtocol && len(config.TrustedProxies) == 0 {
+		return fmt.Errorf("proxy_protocol requires trusted_proxies")
+	}
+	for _, network := range config.TrustedProxies {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %v", network, err)
+		}
+	}
+
+	seen := make(map[string]bool, len(config.Endpoints))
+	for _, endpoint := range config.Endpoints {
+		if _, _, err := net.SplitHostPort(endpoint.Address); err != nil {
+			return fmt.Errorf("invalid endpoint address %q: %v", endpoint.Address, err)
+		}
+		if endpoint.Weight < 0 {
+			return fmt.Errorf("invalid endpoint weight for %s: %d", endpoint.Address, endpoint.Weight)
+		}
+		if endpoint.ServerName != "" {
+			if err := v.ValidateHostname(endpoint.ServerName); err != nil {
+				return fmt.Errorf("invalid server name for endpoint %s: %v", endpoint.Address, err)
+			}
+		}
+		if seen[endpoint.Address] {
+			return fmt.Errorf("duplicate endpoint: %s", endpoint.Address)
+		}
+		seen[endpoint.Address] = true
+	}
+
+	health := config.EndpointHealth
+	if health.Interval < 0 || health.Timeout < 0 {
+		return fmt.Errorf("endpoint health check interval and timeout must not be negative")
+	}
+	if health.HealthyThreshold < 0 || health.UnhealthyThreshold < 0 {
+		return fmt.Errorf("endpoint health check thresholds must not be negative")
+	}
+
+	return nil
+}
+
+func (v *Validator) validatePool(config types.PoolConfig) error {
+	if config.MinIdle < 0 || config.MaxIdle < 0 || config.MaxActive < 0 {
+		return fmt.Errorf("connection counts cannot be negative")
+	}
+	if config.MaxIdle > 0 && config.MinIdle > config.MaxIdle {
+		return fmt.Errorf("min_idle %d exceeds max_idle %d", config.MinIdle, config.MaxIdle)
+	}
+	if config.MaxActive > 0 && config.MaxIdle > config.MaxActive {
+		return fmt.Errorf("max_idle %d exceeds max_active %d", config.MaxIdle, config.MaxActive)
+	}
+	if config.IdleTimeout < 0 || config.WaitTimeout < 0 {
+		return fmt.Errorf("timeouts cannot be negative")
+	}
 	return nil
 }
 
@@ -376,10 +572,98 @@ func (v *Validator) validateSecurity(config types.SecurityConfig) error {
 		return fmt.Errorf("invalid TLS max version: %s", config.TLS.MaxVersion)
 	}
 
+	for i, rule := range config.ACL.Rules {
+		if _, _, err := net.ParseCIDR(rule.Network); err != nil {
+			return fmt.Errorf("invalid ACL rule %d network: %v", i+1, err)
+		}
+		if rule.Action != "allow" && rule.Action != "deny" {
+			return fmt.Errorf("invalid ACL rule %d action: %s", i+1, rule.Action)
+		}
+	}
+	switch config.ACL.DefaultAction {
+	case "", "allow", "deny":
+	default:
+		return fmt.Errorf("invalid ACL default action: %s", config.ACL.DefaultAction)
+	}
+
+	switch config.Seccomp.Violations {
+	case "", "deny", "log":
+	default:
+		return fmt.Errorf("invalid seccomp violations mode: %s", config.Seccomp.Violations)
+	}
+	if config.Seccomp.Enabled && config.Seccomp.Profile != "" {
+		if err := validateSeccompProfile(config.Seccomp.Profile); err != nil {
+			return err
+		}
+	}
+
+	if config.CRL.Enabled && config.CRL.RefreshInterval < 0 {
+		return fmt.Errorf("invalid CRL refresh interval: %v", config.CRL.RefreshInterval)
+	}
+
+	switch config.AuthMethod {
+	case "", "certificate":
+	case "psk":
+		if config.PSK == "" {
+			return fmt.Errorf("auth method psk requires a psk")
+		}
+	case "token":
+		if len(config.Tokens) == 0 && config.Token == "" {
+			return fmt.Errorf("auth method token requires tokens or a token")
+		}
+	default:
+		return fmt.Errorf("invalid auth method: %s", config.AuthMethod)
+	}
+
+	return nil
+}
+
+// validateSeccompProfile checks that the seccomp profile is a readable JSON
+// file; its rules are checked when the filter is built
+func validateSeccompProfile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("invalid seccomp profile: %v", err)
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("invalid seccomp profile %s: not valid JSON", path)
+	}
 	return nil
 }
 
 func (v *Validator) validateMonitor(config types.MonitorConfig) error {
+	if config.Summary.Enabled {
+		if config.Summary.Interval.Seconds() < 1 {
+			return fmt.Errorf("invalid summary interval: %v", config.Summary.Interval)
+		}
+		if config.Summary.TopTalkers < 0 {
+			return fmt.Errorf("invalid summary top_talkers: %d", config.Summary.TopTalkers)
+		}
+	}
+
+	if err := validateHealth(config.Health); err != nil {
+		return err
+	}
+
+	if err := validatePrometheus(config.Prometheus); err != nil {
+		return err
+	}
+
+	if err := validateRemoteWrite(config.Prometheus.RemoteWrite); err != nil {
+		return err
+	}
+
+	if config.HistorySize < 0 {
+		return fmt.Errorf("invalid history size: %d", config.HistorySize)
+	}
+	if config.HistorySize > 0 && config.Interval <= 0 {
+		return fmt.Errorf("history_size requires a monitor interval to snapshot at")
+	}
+
+	if err := validateAlerts(config.Alerts); err != nil {
+		return err
+	}
+
 	if !config.Enabled {
 		return nil
 	}
@@ -404,6 +688,104 @@ func (v *Validator) validateMonitor(config types.MonitorConfig) error {
 	return nil
 }
 
+// validateAlerts checks the alert webhook, timing and rules
+func validateAlerts(config types.AlertsConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	u, err := url.Parse(config.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid alert webhook URL: %q", config.WebhookURL)
+	}
+	if config.Interval.Seconds() < 1 {
+		return fmt.Errorf("invalid alert interval: %v", config.Interval)
+	}
+	if config.Timeout < 0 {
+		return fmt.Errorf("invalid alert timeout: %v", config.Timeout)
+	}
+	if len(config.Rules) == 0 {
+		return fmt.Errorf("alerts require at least one rule")
+	}
+
+	validMetrics := map[string]bool{
+		"connections":     true,
+		"error_rate":      true,
+		"cpu_usage":       true,
+		"memory_usage":    true,
+		"packet_loss":     true,
+		"memory_pressure": true,
+	}
+	validLevels := map[string]bool{
+		"low":      true,
+		"medium":   true,
+		"high":     true,
+		"critical": true,
+	}
+	names := make(map[string]bool)
+	for _, rule := range config.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("alert rule name cannot be empty")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate alert rule: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if !validMetrics[rule.Metric] {
+			return fmt.Errorf("invalid metric for alert rule %s: %s", rule.Name, rule.Metric)
+		}
+		if rule.Metric == "memory_pressure" {
+			if !validLevels[rule.Level] {
+				return fmt.Errorf("invalid memory pressure level for alert rule %s: %s", rule.Name, rule.Level)
+			}
+		} else if rule.Threshold < 0 {
+			return fmt.Errorf("invalid threshold for alert rule %s: %v", rule.Name, rule.Threshold)
+		}
+		if rule.For < 0 || rule.Cooldown < 0 {
+			return fmt.Errorf("invalid timing for alert rule %s: for %v, cooldown %v", rule.Name, rule.For, rule.Cooldown)
+		}
+	}
+	return nil
+}
+
+// validatePrometheus checks the address and path the metrics are scraped from
+func validatePrometheus(config types.PrometheusConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.Port < 1 || config.Port > 65535 {
+		return fmt.Errorf("invalid prometheus port: %d", config.Port)
+	}
+	if config.Path != "" && !strings.HasPrefix(config.Path, "/") {
+		return fmt.Errorf("invalid prometheus path: %q", config.Path)
+	}
+	return nil
+}
+
+// validateRemoteWrite checks the Prometheus remote-write endpoint and timing
+func validateRemoteWrite(config types.RemoteWriteConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid remote write URL: %q", config.URL)
+	}
+	if config.Interval.Seconds() < 1 {
+		return fmt.Errorf("invalid remote write interval: %v", config.Interval)
+	}
+	if config.Timeout < 0 {
+		return fmt.Errorf("invalid remote write timeout: %v", config.Timeout)
+	}
+	if config.Password != "" && config.Username == "" {
+		return fmt.Errorf("remote write password requires a username")
+	}
+	return nil
+}
+
 func (v *Validator) validateMetrics(config types.MetricsConfig) error {
 	if !config.Enabled {
 		return nil
@@ -424,7 +806,58 @@ func (v *Validator) validateMetrics(config types.MetricsConfig) error {
 	return nil
 }
 
+func (v *Validator) validateSNMP(config types.SNMPConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.Port < 0 || config.Port > 65535 {
+		return fmt.Errorf("invalid SNMP port: %d", config.Port)
+	}
+	if config.Address != "" && net.ParseIP(config.Address) == nil {
+		return fmt.Errorf("invalid SNMP address: %s", config.Address)
+	}
+	if config.ReportInterval < 0 {
+		return fmt.Errorf("invalid SNMP report interval: %v", config.ReportInterval)
+	}
+	if config.ReportJitter > 1 {
+		return fmt.Errorf("invalid SNMP report jitter: %v", config.ReportJitter)
+	}
+	for _, network := range config.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return fmt.Errorf("invalid SNMP allowed network %s: %v", network, err)
+		}
+	}
+	if config.RateLimit < 0 {
+		return fmt.Errorf("invalid SNMP rate limit: %v", config.RateLimit)
+	}
+	if config.RateBurst < 0 {
+		return fmt.Errorf("invalid SNMP rate burst: %d", config.RateBurst)
+	}
+	return nil
+}
+
 func (v *Validator) validateThrottle(config types.ThrottleConfig) error {
+	if err := v.validateConnectionThrottle(config.Connections); err != nil {
+		return fmt.Errorf("invalid connections: %v", err)
+	}
+
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.Rate <= 0 {
+		return fmt.Errorf("invalid rate: %f", config.Rate)
+	}
+
+	if config.Burst <= 0 {
+		return fmt.Errorf("invalid burst: %d", config.Burst)
+	}
+
+	return nil
+}
+
+func (v *Validator) validateConnectionThrottle(config types.ConnectionThrottleConfig) error {
 	if !config.Enabled {
 		return nil
 	}
@@ -437,6 +870,10 @@ func (v *Validator) validateThrottle(config types.ThrottleConfig) error {
 		return fmt.Errorf("invalid burst: %d", config.Burst)
 	}
 
+	if config.IdleTimeout < 0 {
+		return fmt.Errorf("invalid idle timeout: %v", config.IdleTimeout)
+	}
+
 	return nil
 }
 
@@ -593,6 +1030,16 @@ func (v *Validator) validateSecurityConfig(config *types.AppConfig) error {
 		}
 	}
 
+	// A credential is only sent over TLS, which needs a certificate and key
+	authMethod := config.Config.Security.AuthMethod
+	if authMethod == "" {
+		authMethod = config.Config.Auth.AuthMethod
+	}
+	if (authMethod == "psk" || authMethod == "token") &&
+		(config.Config.Auth.CertFile == "" || config.Config.Auth.KeyFile == "") {
+		return fmt.Errorf("auth method %s requires TLS: set auth.cert_file and auth.key_file", authMethod)
+	}
+
 	// Validate certificate rotation settings
 	if config.Config.Auth.CertRotation.Enabled {
 		if config.Config.Auth.CertRotation.Interval < time.Hour {
@@ -703,3 +1150,58 @@ func (v *Validator) validateCertificateFilesExist(config *types.AppConfig) error
 
 	return nil
 }
+
+func validateHealth(config types.HealthConfig) error {
+	if config.DegradedOpenBreakers < 0 || config.UnhealthyOpenBreakers < 0 {
+		return fmt.Errorf("invalid health breaker thresholds: %d/%d", config.DegradedOpenBreakers, config.UnhealthyOpenBreakers)
+	}
+
+	if config.DegradedOpenBreakers > 0 && config.UnhealthyOpenBreakers > 0 &&
+		config.DegradedOpenBreakers > config.UnhealthyOpenBreakers {
+		return fmt.Errorf("health degraded_open_breakers %d exceeds unhealthy_open_breakers %d",
+			config.DegradedOpenBreakers, config.UnhealthyOpenBreakers)
+	}
+
+	// "none" is left out: every level is at least none, so it would always apply
+	pressureRank := map[string]int{
+		"low":      1,
+		"medium":   2,
+		"high":     3,
+		"critical": 4,
+	}
+	for _, level := range []string{config.DegradedMemoryPressure, config.UnhealthyMemoryPressure} {
+		if _, ok := pressureRank[level]; level != "" && !ok {
+			return fmt.Errorf("invalid health memory pressure: %s", level)
+		}
+	}
+	degradedPressure, unhealthyPressure := config.DegradedMemoryPressure, config.UnhealthyMemoryPressure
+	if degradedPressure == "" {
+		degradedPressure = "high"
+	}
+	if unhealthyPressure == "" {
+		unhealthyPressure = "critical"
+	}
+	if pressureRank[degradedPressure] > pressureRank[unhealthyPressure] {
+		return fmt.Errorf("health degraded_memory_pressure %s exceeds unhealthy_memory_pressure %s",
+			degradedPressure, unhealthyPressure)
+	}
+
+	for _, ratio := range []float64{config.DegradedConnectionRatio, config.UnhealthyConnectionRatio} {
+		if ratio < 0 || ratio > 1 {
+			return fmt.Errorf("invalid health connection ratio: %v", ratio)
+		}
+	}
+	degradedRatio, unhealthyRatio := config.DegradedConnectionRatio, config.UnhealthyConnectionRatio
+	if degradedRatio == 0 {
+		degradedRatio = 0.8
+	}
+	if unhealthyRatio == 0 {
+		unhealthyRatio = 1.0
+	}
+	if degradedRatio > unhealthyRatio {
+		return fmt.Errorf("health degraded_connection_ratio %v exceeds unhealthy_connection_ratio %v",
+			degradedRatio, unhealthyRatio)
+	}
+
+	return nil
+}
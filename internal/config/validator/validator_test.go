@@ -0,0 +1,487 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestValidateTunnelServerName(t *testing.T) {
+	tests := []struct {
+		serverName string
+		wantErr    bool
+	}{
+		{"", false},
+		{"tunnel.example.com", false},
+		{"vpn-1", false},
+		{"192.168.50.210", true},
+		{"::1", true},
+		{"bad_name.example.com", true},
+		{"-leading.example.com", true},
+		{"empty..label", true},
+		{strings.Repeat("a", 64) + ".example.com", true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", ServerName: tt.serverName}
+		err := v.validateTunnel(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected server name %q to be rejected", tt.serverName)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected server name %q to be accepted, got %v", tt.serverName, err)
+		}
+	}
+}
+
+func TestValidateTunnelPool(t *testing.T) {
+	tests := []struct {
+		pool    types.PoolConfig
+		wantErr bool
+	}{
+		{types.PoolConfig{}, false},
+		{types.PoolConfig{MinIdle: 2, MaxIdle: 4, MaxActive: 8, WaitTimeout: time.Second}, false},
+		{types.PoolConfig{MinIdle: 2}, false},
+		{types.PoolConfig{MinIdle: 5, MaxIdle: 4}, true},
+		{types.PoolConfig{MaxIdle: 10, MaxActive: 4}, true},
+		{types.PoolConfig{MaxActive: -1}, true},
+		{types.PoolConfig{WaitTimeout: -time.Second}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", Pool: tt.pool}
+		err := v.validateTunnel(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected pool %+v to be rejected", tt.pool)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected pool %+v to be accepted, got %v", tt.pool, err)
+		}
+	}
+}
+
+func TestValidateTunnelEndpoints(t *testing.T) {
+	tests := []struct {
+		endpoints []types.EndpointConfig
+		wantErr   bool
+	}{
+		{nil, false},
+		{[]types.EndpointConfig{{Address: "10.0.0.1:8443", Weight: 2}, {Address: "vpn.example.com:8443"}}, false},
+		{[]types.EndpointConfig{{Address: "10.0.0.1"}}, true},
+		{[]types.EndpointConfig{{Address: "10.0.0.1:8443", Weight: -1}}, true},
+		{[]types.EndpointConfig{{Address: "10.0.0.1:8443"}, {Address: "10.0.0.1:8443"}}, true},
+		{[]types.EndpointConfig{{Address: "10.0.0.1:8443", ServerName: "vpn.example.com"}}, false},
+		{[]types.EndpointConfig{{Address: "10.0.0.1:8443", ServerName: "10.0.0.1"}}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", Endpoints: tt.endpoints}
+		err := v.validateTunnel(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected endpoints %+v to be rejected", tt.endpoints)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected endpoints %+v to be accepted, got %v", tt.endpoints, err)
+		}
+	}
+
+	for _, health := range []types.EndpointHealthConfig{
+		{Enabled: true, Interval: -time.Second},
+		{Enabled: true, UnhealthyThreshold: -1},
+	} {
+		cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", EndpointHealth: health}
+		if err := v.validateTunnel(cfg); err == nil {
+			t.Errorf("Expected endpoint health %+v to be rejected", health)
+		}
+	}
+}
+
+func TestValidateTunnelListenAddresses(t *testing.T) {
+	tests := []struct {
+		addresses []string
+		wantErr   bool
+	}{
+		{nil, false},
+		{[]string{"0.0.0.0:8443", "[::]:8443", "10.1.0.1:9443"}, false},
+		{[]string{"0.0.0.0"}, true},
+		{[]string{"0.0.0.0:0"}, true},
+		{[]string{"0.0.0.0:https"}, true},
+		{[]string{"0.0.0.0:8443", "0.0.0.0:8443"}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", ListenAddresses: tt.addresses}
+		err := v.validateTunnel(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected listen addresses %v to be rejected", tt.addresses)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected listen addresses %v to be accepted, got %v", tt.addresses, err)
+		}
+	}
+}
+
+func TestValidateTunnelTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   bool
+		trusted []string
+		wantErr bool
+	}{
+		{"disabled", false, nil, false},
+		{"proxy without trusted proxies", true, nil, true},
+		{"proxy with trusted proxies", true, []string{"10.0.0.0/24", "2001:db8::/32"}, false},
+		{"invalid network", true, []string{"10.0.0.1"}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", ProxyProtocol: tt.proxy, TrustedProxies: tt.trusted}
+		err := v.validateTunnel(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidatePrometheus(t *testing.T) {
+	tests := []struct {
+		prometheus types.PrometheusConfig
+		wantErr    bool
+	}{
+		{types.PrometheusConfig{}, false},
+		{types.PrometheusConfig{Enabled: true, Port: 9100}, false},
+		{types.PrometheusConfig{Enabled: true, Port: 9100, Path: "/metrics"}, false},
+		{types.PrometheusConfig{Enabled: true}, true},
+		{types.PrometheusConfig{Enabled: true, Port: 70000}, true},
+		{types.PrometheusConfig{Enabled: true, Port: 9100, Path: "metrics"}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		err := v.validateMonitor(types.MonitorConfig{Prometheus: tt.prometheus})
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected prometheus %+v to be rejected", tt.prometheus)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected prometheus %+v to be accepted, got %v", tt.prometheus, err)
+		}
+	}
+}
+
+func TestValidateHealth(t *testing.T) {
+	tests := []struct {
+		health  types.HealthConfig
+		wantErr bool
+	}{
+		{types.HealthConfig{}, false},
+		{types.HealthConfig{DegradedOpenBreakers: 1, UnhealthyOpenBreakers: 3}, false},
+		{types.HealthConfig{DegradedMemoryPressure: "medium", UnhealthyMemoryPressure: "high"}, false},
+		{types.HealthConfig{DegradedConnectionRatio: 0.5}, false},
+		{types.HealthConfig{DegradedOpenBreakers: 3, UnhealthyOpenBreakers: 1}, true},
+		{types.HealthConfig{DegradedMemoryPressure: "none"}, true},
+		{types.HealthConfig{DegradedMemoryPressure: "critical", UnhealthyMemoryPressure: "high"}, true},
+		{types.HealthConfig{UnhealthyMemoryPressure: "medium"}, true},
+		{types.HealthConfig{UnhealthyConnectionRatio: 0.5}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateHealth(tt.health)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected health %+v to be rejected", tt.health)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected health %+v to be accepted, got %v", tt.health, err)
+		}
+	}
+}
+
+func TestValidateRemoteWrite(t *testing.T) {
+	tests := []struct {
+		remote  types.RemoteWriteConfig
+		wantErr bool
+	}{
+		{types.RemoteWriteConfig{}, false},
+		{types.RemoteWriteConfig{URL: "not a url"}, false}, // Ignored while disabled
+		{types.RemoteWriteConfig{Enabled: true, URL: "https://prometheus:9090/api/v1/write", Interval: 15 * time.Second}, false},
+		{types.RemoteWriteConfig{Enabled: true, URL: "prometheus:9090", Interval: 15 * time.Second}, true},
+		{types.RemoteWriteConfig{Enabled: true, URL: "http://prometheus/api/v1/write"}, true},
+		{types.RemoteWriteConfig{Enabled: true, URL: "http://prometheus/api/v1/write", Interval: time.Minute, Password: "secret"}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.MonitorConfig{Prometheus: types.PrometheusConfig{RemoteWrite: tt.remote}}
+		err := v.validateMonitor(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected remote write %+v to be rejected", tt.remote)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected remote write %+v to be accepted, got %v", tt.remote, err)
+		}
+	}
+}
+
+func TestValidateAlerts(t *testing.T) {
+	connections := types.AlertRuleConfig{Name: "connections", Metric: "connections", Threshold: 100}
+	pressure := types.AlertRuleConfig{Name: "memory", Metric: "memory_pressure", Level: "high"}
+	alerts := func(rules ...types.AlertRuleConfig) types.AlertsConfig {
+		return types.AlertsConfig{Enabled: true, Interval: 10 * time.Second, WebhookURL: "https://alerts.example.com/hook", Rules: rules}
+	}
+
+	tests := []struct {
+		alerts  types.AlertsConfig
+		wantErr bool
+	}{
+		{types.AlertsConfig{}, false},
+		{alerts(connections, pressure), false},
+		{alerts(), true},
+		{alerts(connections, connections), true},
+		{alerts(types.AlertRuleConfig{Name: "disk", Metric: "disk_usage", Threshold: 1}), true},
+		{alerts(types.AlertRuleConfig{Name: "memory", Metric: "memory_pressure"}), true},
+		{alerts(types.AlertRuleConfig{Name: "errors", Metric: "error_rate", Threshold: 1, Cooldown: -time.Second}), true},
+		{types.AlertsConfig{Enabled: true, Interval: 10 * time.Second, WebhookURL: "alerts.example.com", Rules: []types.AlertRuleConfig{connections}}, true},
+		{types.AlertsConfig{Enabled: true, WebhookURL: "https://alerts.example.com/hook", Rules: []types.AlertRuleConfig{connections}}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		err := v.validateMonitor(types.MonitorConfig{Alerts: tt.alerts})
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected alerts %+v to be rejected", tt.alerts)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected alerts %+v to be accepted, got %v", tt.alerts, err)
+		}
+	}
+}
+
+func TestValidateSNMP(t *testing.T) {
+	tests := []struct {
+		snmp    types.SNMPConfig
+		wantErr bool
+	}{
+		{types.SNMPConfig{}, false},
+		{types.SNMPConfig{Port: -1}, false},
+		{types.SNMPConfig{Enabled: true, Port: 161, Address: "127.0.0.1", ReportInterval: time.Minute, ReportJitter: 0.1}, false},
+		{types.SNMPConfig{Enabled: true, Port: 161, ReportJitter: -1}, false},
+		{types.SNMPConfig{Enabled: true, Port: 70000}, true},
+		{types.SNMPConfig{Enabled: true, Port: 161, Address: "localhost"}, true},
+		{types.SNMPConfig{Enabled: true, Port: 161, ReportInterval: -time.Second}, true},
+		{types.SNMPConfig{Enabled: true, Port: 161, ReportJitter: 1.5}, true},
+		{types.SNMPConfig{Enabled: true, Port: 161, AllowedNetworks: []string{"10.20.0.0/16", "fd00::/8"}, RateLimit: 10, RateBurst: 20}, false},
+		{types.SNMPConfig{Enabled: true, Port: 161, AllowedNetworks: []string{"10.20.0.1"}}, true},
+		{types.SNMPConfig{Enabled: true, Port: 161, RateLimit: -1}, true},
+		{types.SNMPConfig{Enabled: true, Port: 161, RateBurst: -1}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		err := v.validateSNMP(tt.snmp)
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected %+v to be rejected", tt.snmp)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected %+v to be accepted, got %v", tt.snmp, err)
+		}
+	}
+}
+
+func TestValidateConnectionThrottle(t *testing.T) {
+	tests := []struct {
+		config  types.ConnectionThrottleConfig
+		wantErr bool
+	}{
+		{types.ConnectionThrottleConfig{}, false},
+		{types.ConnectionThrottleConfig{Enabled: true, Rate: 5, Burst: 10}, false},
+		{types.ConnectionThrottleConfig{Enabled: true, Rate: 0.5, Burst: 1, IdleTimeout: time.Minute}, false},
+		{types.ConnectionThrottleConfig{Enabled: true, Burst: 10}, true},
+		{types.ConnectionThrottleConfig{Enabled: true, Rate: 5}, true},
+		{types.ConnectionThrottleConfig{Enabled: true, Rate: 5, Burst: 10, IdleTimeout: -time.Second}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		// The connection limit is checked even when byte rate limiting is off
+		err := v.validateThrottle(types.ThrottleConfig{Connections: tt.config})
+		if tt.wantErr && err == nil {
+			t.Errorf("Expected connection throttle %+v to be rejected", tt.config)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Expected connection throttle %+v to be accepted, got %v", tt.config, err)
+		}
+	}
+}
+
+func TestValidateTunnelHandshakeTimeout(t *testing.T) {
+	v := NewValidator()
+	for _, timeout := range []time.Duration{0, 3 * time.Second} {
+		cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", HandshakeTimeout: timeout}
+		if err := v.validateTunnel(cfg); err != nil {
+			t.Errorf("Unexpected error for handshake timeout %v: %v", timeout, err)
+		}
+	}
+
+	cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", HandshakeTimeout: -time.Second}
+	if err := v.validateTunnel(cfg); err == nil {
+		t.Error("Expected an error for a negative handshake timeout")
+	}
+}
+
+func TestValidateTunnelHeartbeat(t *testing.T) {
+	v := NewValidator()
+	cfg := types.TunnelConfig{Port: 8443, Protocol: "tcp", Heartbeat: true}
+	if err := v.validateTunnel(cfg); err == nil {
+		t.Error("Expected a heartbeat without a keepalive to be rejected")
+	}
+
+	cfg.Keepalive = "10s"
+	if err := v.validateTunnel(cfg); err != nil {
+		t.Errorf("Expected a heartbeat with a keepalive to be accepted, got %v", err)
+	}
+}
+
+func TestValidateTunnelCoalescing(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  time.Duration
+		bytes   int
+		packets int
+		wantErr bool
+	}{
+		{"disabled", 0, 0, 0, false},
+		{"sub-millisecond window", 500 * time.Microsecond, 8192, 16, false},
+		{"maximum window", types.MaxCoalesceWindow, 0, 0, false},
+		{"window too long", types.MaxCoalesceWindow + time.Millisecond, 0, 0, true},
+		{"negative window", -time.Millisecond, 0, 0, true},
+		{"negative bytes", time.Millisecond, -1, 0, true},
+		{"negative packets", time.Millisecond, 0, -1, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.TunnelConfig{
+			Port:            8443,
+			Protocol:        "tcp",
+			CoalesceWindow:  tt.window,
+			CoalesceBytes:   tt.bytes,
+			CoalescePackets: tt.packets,
+		}
+		err := v.validateTunnel(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateSecurityAuthMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		security types.SecurityConfig
+		wantErr  bool
+	}{
+		{"default", types.SecurityConfig{}, false},
+		{"certificate", types.SecurityConfig{AuthMethod: "certificate"}, false},
+		{"psk", types.SecurityConfig{AuthMethod: "psk", PSK: "secret"}, false},
+		{"psk without key", types.SecurityConfig{AuthMethod: "psk"}, true},
+		{"server tokens", types.SecurityConfig{AuthMethod: "token", Tokens: []string{"a"}}, false},
+		{"client token", types.SecurityConfig{AuthMethod: "token", Token: "a"}, false},
+		{"token without tokens", types.SecurityConfig{AuthMethod: "token"}, true},
+		{"unknown", types.SecurityConfig{AuthMethod: "kerberos"}, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		tt.security.TLS = types.TLSConfigOptions{MinVersion: "1.2", MaxVersion: "1.3"}
+		err := v.validateSecurity(tt.security)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateSeccompProfile(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "profile.json")
+	if err := os.WriteFile(profile, []byte(`{"defaultAction": "SCMP_ACT_ALLOW"}`), 0644); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+	malformed := filepath.Join(dir, "malformed.json")
+	if err := os.WriteFile(malformed, []byte("defaultAction: allow"), 0644); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		seccomp types.SeccompConfig
+		wantErr bool
+	}{
+		{"profile", types.SeccompConfig{Enabled: true, Profile: profile}, false},
+		{"missing profile", types.SeccompConfig{Enabled: true, Profile: filepath.Join(dir, "missing.json")}, true},
+		{"directory", types.SeccompConfig{Enabled: true, Profile: dir}, true},
+		{"malformed profile", types.SeccompConfig{Enabled: true, Profile: malformed}, true},
+		{"disabled", types.SeccompConfig{Profile: filepath.Join(dir, "missing.json")}, false},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		security := types.SecurityConfig{
+			TLS:     types.TLSConfigOptions{MinVersion: "1.2", MaxVersion: "1.3"},
+			Seccomp: tt.seccomp,
+		}
+		err := v.validateSecurity(security)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateCredentialAuthRequiresTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		auth    types.AuthConfig
+		wantErr bool
+	}{
+		{"certificate without TLS", "certificate", types.AuthConfig{}, false},
+		{"psk without TLS", "psk", types.AuthConfig{}, true},
+		{"token without TLS", "token", types.AuthConfig{}, true},
+		{"token without key", "token", types.AuthConfig{CertFile: "server.crt"}, true},
+		{"psk alias without TLS", "", types.AuthConfig{AuthMethod: "psk"}, true},
+		{"psk with TLS", "psk", types.AuthConfig{CertFile: "server.crt", KeyFile: "server.key"}, false},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		cfg := types.NewAppConfig(types.TypeServer)
+		cfg.Config.Auth = tt.auth
+		cfg.Config.Security.AuthMethod = tt.method
+		err := v.validateSecurityConfig(cfg)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
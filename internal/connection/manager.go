@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
 	"go.uber.org/zap"
 )
 
@@ -200,6 +201,22 @@ func (m *Manager) GetConnections() []ConnectionInfo {
 	return infos
 }
 
+// TalkerStats returns the traffic of each connection for the connection summary
+func (m *Manager) TalkerStats() []monitor.TalkerStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make([]monitor.TalkerStats, 0, len(m.conns))
+	for _, info := range m.conns {
+		stats = append(stats, monitor.TalkerStats{
+			RemoteAddr:    info.RemoteAddr,
+			BytesSent:     info.BytesSent,
+			BytesReceived: info.BytesReceived,
+		})
+	}
+	return stats
+}
+
 // GetState returns the current connection state
 func (m *Manager) GetState() ConnectionState {
 	m.mu.RLock()
@@ -0,0 +1,125 @@
+// Package errors provides coded errors, which carry a stable code and category
+// so that callers can handle them without matching on error messages
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Category groups error codes by the part of the system that failed
+type Category string
+
+const (
+	CategoryConfiguration Category = "configuration"
+	CategoryNetwork       Category = "network"
+	CategoryTunnel        Category = "tunnel"
+)
+
+// Code identifies a kind of failure and is stable across releases. Codes are
+// errors themselves, so errors.Is(err, ErrConfigSyntax) matches any CodedError
+// with that code in the chain of err.
+type Code string
+
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Configuration error codes
+const (
+	ErrConfigRead    Code = "CONFIG_READ"    // The configuration file could not be read
+	ErrConfigSyntax  Code = "CONFIG_SYNTAX"  // The configuration could not be parsed
+	ErrConfigInvalid Code = "CONFIG_INVALID" // The configuration failed validation
+	ErrConfigVersion Code = "CONFIG_VERSION" // The configuration could not be migrated to the current schema
+)
+
+// Network error codes
+const (
+	ErrNetworkPort    Code = "NETWORK_PORT"    // A listen address could not be bound
+	ErrNetworkDial    Code = "NETWORK_DIAL"    // The server could not be connected to
+	ErrNetworkResolve Code = "NETWORK_RESOLVE" // A host name could not be resolved
+	ErrNetworkLimited Code = "NETWORK_LIMITED" // A rate limit refused the connection or request
+)
+
+// Tunnel error codes
+const (
+	ErrTunnelInterface Code = "TUNNEL_INTERFACE" // The network interface could not be created or configured
+	ErrTunnelHandshake Code = "TUNNEL_HANDSHAKE" // The TLS handshake with the peer failed
+)
+
+// CodedError is an error with a stable code and category, wrapping the error
+// that caused it, if any
+type CodedError struct {
+	Code     Code
+	Category Category
+	Message  string
+	Cause    error
+}
+
+// Error returns the message followed by the cause, as fmt.Errorf("%s: %w")
+// would
+func (e *CodedError) Error() string {
+	switch {
+	case e.Cause == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Cause.Error()
+	default:
+		return e.Message + ": " + e.Cause.Error()
+	}
+}
+
+// Unwrap returns the cause of the error
+func (e *CodedError) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is the code of the error
+func (e *CodedError) Is(target error) bool {
+	code, ok := target.(Code)
+	return ok && code == e.Code
+}
+
+func newCodedError(category Category, code Code, cause error, format string, args []interface{}) *CodedError {
+	return &CodedError{
+		Code:     code,
+		Category: category,
+		Message:  fmt.Sprintf(format, args...),
+		Cause:    cause,
+	}
+}
+
+// Config returns a configuration error with code, caused by cause
+func Config(code Code, cause error, format string, args ...interface{}) *CodedError {
+	return newCodedError(CategoryConfiguration, code, cause, format, args)
+}
+
+// Network returns a network error with code, caused by cause
+func Network(code Code, cause error, format string, args ...interface{}) *CodedError {
+	return newCodedError(CategoryNetwork, code, cause, format, args)
+}
+
+// Tunnel returns a tunnel error with code, caused by cause
+func Tunnel(code Code, cause error, format string, args ...interface{}) *CodedError {
+	return newCodedError(CategoryTunnel, code, cause, format, args)
+}
+
+// CodeOf returns the code of the first CodedError in the chain of err, or ""
+// when there is none
+func CodeOf(err error) Code {
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
+
+// CategoryOf returns the category of the first CodedError in the chain of err,
+// or "" when there is none
+func CategoryOf(err error) Category {
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Category
+	}
+	return ""
+}
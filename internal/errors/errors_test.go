@@ -0,0 +1,55 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestCodedErrorMessage(t *testing.T) {
+	tests := []struct {
+		err      *CodedError
+		expected string
+	}{
+		{Config(ErrConfigRead, fs.ErrNotExist, "failed to read config file %s", "a.yaml"), "failed to read config file a.yaml: file does not exist"},
+		{Config(ErrConfigInvalid, nil, "invalid tunnel configuration"), "invalid tunnel configuration"},
+		{Network(ErrNetworkPort, fs.ErrPermission, ""), "permission denied"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestCodedErrorMatchesCode(t *testing.T) {
+	err := fmt.Errorf("failed to start: %w", Network(ErrNetworkPort, fs.ErrPermission, "failed to bind %s", ":443"))
+
+	if !errors.Is(err, ErrNetworkPort) {
+		t.Error("Expected the wrapped error to match its code")
+	}
+	if errors.Is(err, ErrNetworkDial) {
+		t.Error("Expected the wrapped error not to match another code")
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Error("Expected the wrapped error to match its cause")
+	}
+	if code := CodeOf(err); code != ErrNetworkPort {
+		t.Errorf("Expected code %s, got %s", ErrNetworkPort, code)
+	}
+	if category := CategoryOf(err); category != CategoryNetwork {
+		t.Errorf("Expected category %s, got %s", CategoryNetwork, category)
+	}
+}
+
+func TestCodeOfUncodedError(t *testing.T) {
+	err := errors.New("connection refused")
+	if code := CodeOf(err); code != "" {
+		t.Errorf("Expected no code, got %s", code)
+	}
+	if category := CategoryOf(err); category != "" {
+		t.Errorf("Expected no category, got %s", category)
+	}
+}
@@ -0,0 +1,144 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+// Supported hash algorithms
+const (
+	AlgorithmSHA256 = "sha256"
+	AlgorithmSHA512 = "sha512"
+)
+
+// DefaultAlgorithm is used when no algorithm is configured
+const DefaultAlgorithm = AlgorithmSHA256
+
+// InfoSuffix is appended to a file's path to name its info file
+const InfoSuffix = ".info"
+
+// FileInfo records the size, modification time and hash of a file, and the
+// algorithm used to hash it
+type FileInfo struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Algorithm string    `json:"algorithm"`
+	Hash      string    `json:"hash"` // Hex encoded
+}
+
+// Result compares a file with the info recorded for it
+type Result struct {
+	Match    bool      // Size and hash are unchanged; the modification time is not compared
+	Recorded *FileInfo // Read from the info file
+	Current  *FileInfo // Of the file as it is now, hashed with the recorded algorithm
+}
+
+// newHash returns a hash for algorithm; an empty algorithm selects the default
+func newHash(algorithm string) (hash.Hash, string, error) {
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+	switch algorithm {
+	case AlgorithmSHA256:
+		return sha256.New(), algorithm, nil
+	case AlgorithmSHA512:
+		return sha512.New(), algorithm, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+	}
+}
+
+// GetFileInfo hashes the file at path with algorithm, or the default if empty
+func GetFileInfo(path, algorithm string) (*FileInfo, error) {
+	h, algorithm, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	return &FileInfo{
+		Path:      path,
+		Size:      size,
+		ModTime:   stat.ModTime(),
+		Algorithm: algorithm,
+		Hash:      hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// WriteInfo hashes the file at path and writes its info to path+InfoSuffix
+func WriteInfo(path, algorithm string) (*FileInfo, error) {
+	info, err := GetFileInfo(path, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode info: %w", err)
+	}
+	if err := os.WriteFile(path+InfoSuffix, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write info: %w", err)
+	}
+	return info, nil
+}
+
+// ReadInfo reads the info written for the file at path. Info without an
+// algorithm was written before the algorithm was recorded and uses the default.
+func ReadInfo(path string) (*FileInfo, error) {
+	data, err := os.ReadFile(path + InfoSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read info: %w", err)
+	}
+
+	var info FileInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode info: %w", err)
+	}
+	if info.Algorithm == "" {
+		info.Algorithm = DefaultAlgorithm
+	}
+	return &info, nil
+}
+
+// Verify compares the file at path with its info, hashing it with the
+// algorithm recorded there. A missing info file is an error wrapping
+// fs.ErrNotExist; a changed file is reported by the result, not as an error.
+func Verify(path string) (Result, error) {
+	recorded, err := ReadInfo(path)
+	if err != nil {
+		return Result{}, err
+	}
+	current, err := GetFileInfo(path, recorded.Algorithm)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Match:    current.Size == recorded.Size && current.Hash == recorded.Hash,
+		Recorded: recorded,
+		Current:  current,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package integrity
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySHA512(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sssonector")
+	if err := os.WriteFile(path, []byte("binary contents"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	info, err := WriteInfo(path, AlgorithmSHA512)
+	if err != nil {
+		t.Fatalf("Failed to write info: %v", err)
+	}
+	if info.Algorithm != AlgorithmSHA512 || len(info.Hash) != 128 {
+		t.Errorf("Expected a sha512 hash, got %s %q", info.Algorithm, info.Hash)
+	}
+
+	recorded, err := ReadInfo(path)
+	if err != nil {
+		t.Fatalf("Failed to read info: %v", err)
+	}
+	if recorded.Algorithm != AlgorithmSHA512 {
+		t.Errorf("Expected the algorithm to be recorded, got %q", recorded.Algorithm)
+	}
+	if result, err := Verify(path); err != nil || !result.Match {
+		t.Errorf("Expected the untouched file to verify, got %+v, %v", result, err)
+	}
+
+	if err := os.WriteFile(path, []byte("binary c0ntents"), 0755); err != nil {
+		t.Fatalf("Failed to tamper with file: %v", err)
+	}
+	result, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if result.Match {
+		t.Error("Expected tampering to be detected")
+	}
+	if result.Current.Algorithm != AlgorithmSHA512 || result.Current.Hash == result.Recorded.Hash {
+		t.Errorf("Expected a differing sha512 hash, got %+v and %+v", result.Recorded, result.Current)
+	}
+}
+
+func TestVerifyReportsMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("mode: server\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+	stored, err := WriteInfo(path, "")
+	if err != nil {
+		t.Fatalf("Failed to write info: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("mode: client\n"), 0644); err != nil {
+		t.Fatalf("Failed to alter file: %v", err)
+	}
+	result, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+
+	if result.Match {
+		t.Fatal("Expected the altered file not to match its info")
+	}
+	if result.Recorded.Hash != stored.Hash || result.Current.Hash == stored.Hash {
+		t.Errorf("Expected the old hash %s and a new one, got %s and %s", stored.Hash, result.Recorded.Hash, result.Current.Hash)
+	}
+	if !result.Recorded.ModTime.Equal(old) || !result.Current.ModTime.After(old) {
+		t.Errorf("Expected the old modification time %v and a later one, got %v and %v", old, result.Recorded.ModTime, result.Current.ModTime)
+	}
+}
+
+func TestVerifyWithoutInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("mode: server\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := Verify(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing info error, got %v", err)
+	}
+}
+
+func TestReadInfoDefaultsAlgorithm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("mode: server\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	info, err := GetFileInfo(path, "")
+	if err != nil {
+		t.Fatalf("Failed to hash file: %v", err)
+	}
+	if info.Algorithm != DefaultAlgorithm {
+		t.Errorf("Expected the default algorithm, got %s", info.Algorithm)
+	}
+
+	// Info written without an algorithm is checked with the default
+	legacy := `{"path": "` + path + `", "size": 13, "hash": "` + info.Hash + `"}`
+	if err := os.WriteFile(path+InfoSuffix, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write info: %v", err)
+	}
+	if result, err := Verify(path); err != nil || !result.Match {
+		t.Errorf("Expected info without an algorithm to verify, got %+v, %v", result, err)
+	}
+}
+
+func TestGetFileInfoUnsupportedAlgorithm(t *testing.T) {
+	_, err := GetFileInfo("unused", "md5")
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Expected an unsupported algorithm error, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PublicKey is the hex encoded Ed25519 key that release binaries are signed
+// with. It is embedded at build time:
+//
+//	go build -ldflags "-X github.com/o3willard-AI/SSSonector/internal/integrity.PublicKey=<hex>"
+var PublicKey string
+
+// SignatureSuffix is appended to a file's path to name its detached signature
+const SignatureSuffix = ".sig"
+
+var (
+	// ErrNoPublicKey is returned by VerifyExecutable for a build without a key
+	ErrNoPublicKey = errors.New("no signing public key embedded in this build")
+	// ErrInvalidSignature is returned when a signature does not match the file
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// ParsePublicKey decodes a hex encoded Ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ParsePrivateKey decodes a hex encoded Ed25519 private key seed
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid private key: expected %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// signedDigest returns the message signed for the file at path: its SHA-512
+// digest, so that large binaries need not be held in memory
+func signedDigest(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return h.Sum(nil), nil
+}
+
+// SignFile signs the file at path and writes the base64 encoded signature to
+// path+SignatureSuffix
+func SignFile(path string, key ed25519.PrivateKey) error {
+	digest, err := signedDigest(path)
+	if err != nil {
+		return err
+	}
+
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest))
+	if err := os.WriteFile(path+SignatureSuffix, []byte(sig+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// VerifyFileSignature checks the file at path against its detached signature
+func VerifyFileSignature(path string, key ed25519.PublicKey) error {
+	data, err := os.ReadFile(path + SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature file: %v", ErrInvalidSignature, err)
+	}
+
+	digest, err := signedDigest(path)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, digest, sig) {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, path)
+	}
+	return nil
+}
+
+// VerifyExecutable checks the running binary against its detached signature
+// with the embedded public key
+func VerifyExecutable() error {
+	if PublicKey == "" {
+		return ErrNoPublicKey
+	}
+	key, err := ParsePublicKey(PublicKey)
+	if err != nil {
+		return err
+	}
+
+	path, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %w", err)
+	}
+	return VerifyFileSignature(path, key)
+}
+
+// CheckExecutable verifies the running binary at startup. In strict mode any
+// failure is returned. Otherwise the binary runs regardless: a build without
+// a public key is skipped, and other failures are passed to warn.
+func CheckExecutable(strict bool, warn func(err error)) error {
+	err := VerifyExecutable()
+	switch {
+	case err == nil:
+		return nil
+	case strict:
+		return err
+	case !errors.Is(err, ErrNoPublicKey):
+		warn(err)
+	}
+	return nil
+}
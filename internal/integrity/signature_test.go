@@ -0,0 +1,113 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test keypair derived from a fixed seed
+const testSeed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+
+func TestSignAndVerifyFile(t *testing.T) {
+	private, err := ParsePrivateKey(testSeed)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	public, err := ParsePublicKey(hex.EncodeToString(private.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "sssonector")
+	if err := os.WriteFile(path, []byte("release binary"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := SignFile(path, private); err != nil {
+		t.Fatalf("Failed to sign file: %v", err)
+	}
+
+	if err := VerifyFileSignature(path, public); err != nil {
+		t.Errorf("Expected the signed file to verify, got %v", err)
+	}
+
+	// A different key rejects the signature
+	_, other, _ := ed25519.GenerateKey(nil)
+	if err := VerifyFileSignature(path, other.Public().(ed25519.PublicKey)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a signature by another key to be rejected, got %v", err)
+	}
+
+	// A swapped binary fails even though its hash could be recomputed
+	if err := os.WriteFile(path, []byte("release b1nary"), 0755); err != nil {
+		t.Fatalf("Failed to tamper with file: %v", err)
+	}
+	if err := VerifyFileSignature(path, public); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected the tampered file to be rejected, got %v", err)
+	}
+
+	if err := os.Remove(path + SignatureSuffix); err != nil {
+		t.Fatalf("Failed to remove signature: %v", err)
+	}
+	if err := VerifyFileSignature(path, public); err == nil {
+		t.Error("Expected an error for a missing signature")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	if _, err := ParsePublicKey("abcd"); err == nil {
+		t.Error("Expected a short public key to be rejected")
+	}
+	if _, err := ParsePublicKey("not hex"); err == nil {
+		t.Error("Expected a malformed public key to be rejected")
+	}
+	if _, err := ParsePrivateKey(testSeed[:10]); err == nil {
+		t.Error("Expected a short private key to be rejected")
+	}
+}
+
+func TestVerifyExecutableWithoutKey(t *testing.T) {
+	saved := PublicKey
+	defer func() { PublicKey = saved }()
+
+	PublicKey = ""
+	if err := VerifyExecutable(); !errors.Is(err, ErrNoPublicKey) {
+		t.Errorf("Expected no public key, got %v", err)
+	}
+
+	// The test binary has no signature
+	private, _ := ParsePrivateKey(testSeed)
+	PublicKey = hex.EncodeToString(private.Public().(ed25519.PublicKey))
+	if err := VerifyExecutable(); err == nil {
+		t.Error("Expected an unsigned executable to fail verification")
+	}
+}
+
+func TestCheckExecutable(t *testing.T) {
+	saved := PublicKey
+	defer func() { PublicKey = saved }()
+
+	var warnings []error
+	warn := func(err error) { warnings = append(warnings, err) }
+
+	// A build without a key is skipped unless strict
+	PublicKey = ""
+	if err := CheckExecutable(false, warn); err != nil || len(warnings) != 0 {
+		t.Errorf("Expected a build without a key to be skipped, got %v and warnings %v", err, warnings)
+	}
+	if err := CheckExecutable(true, warn); !errors.Is(err, ErrNoPublicKey) {
+		t.Errorf("Expected strict mode to require a key, got %v", err)
+	}
+
+	// The test binary has no signature
+	private, _ := ParsePrivateKey(testSeed)
+	PublicKey = hex.EncodeToString(private.Public().(ed25519.PublicKey))
+	if err := CheckExecutable(false, warn); err != nil || len(warnings) != 1 {
+		t.Errorf("Expected a warning for an unsigned executable, got %v and warnings %v", err, warnings)
+	}
+	if err := CheckExecutable(true, warn); err == nil {
+		t.Error("Expected strict mode to refuse an unsigned executable")
+	}
+}
@@ -3,6 +3,8 @@ package memory
 import (
 	"context"
 	"fmt"
+	"math"
+	"os"
 	"runtime"
 	"runtime/debug"
 	"sync"
@@ -26,12 +28,24 @@ type MemoryManager struct {
 	allocationCount uint64
 
 	// Pools
-	bufferPool interface{} // We'll integrate with our existing buffer pool
+	bufferPool *SizedPool
 
 	// Callbacks
 	onMemoryLimit    func(current, limit uint64) error
 	onGCTrigger      func()
 	onMemoryPressure func(level float64) error
+	pressureHandlers []func(MemPressure)
+
+	// readStats reads runtime memory statistics
+	readStats func(*runtime.MemStats)
+	pressure  int32 // MemPressure at the last check
+
+	// Runtime soft memory limit, applied while the manager is running
+	limitLock     sync.Mutex
+	running       bool
+	previousLimit int64 // Runtime limit before Start, restored by Stop
+	envLimit      bool  // GOMEMLIMIT is set, so the runtime limit is left to it
+	softLimit     atomic.Bool
 
 	// Metrics
 	lock        sync.RWMutex
@@ -54,6 +68,11 @@ type MemoryStats struct {
 	GCFraction     float64
 	ObjectCount    uint64
 	PauseTimeNs    []uint64
+	PoolHits       uint64 // Buffer pool requests served from the pool
+	PoolMisses     uint64 // Buffer pool requests that allocated
+	// SoftLimitActive reports whether the max memory is applied as the runtime
+	// soft memory limit (GOMEMLIMIT)
+	SoftLimitActive bool
 }
 
 // MemPressure describes memory pressure levels
@@ -67,6 +86,34 @@ const (
 	MemPressureCritical
 )
 
+// String returns the name of the pressure level
+func (p MemPressure) String() string {
+	switch p {
+	case MemPressureNone:
+		return "none"
+	case MemPressureLow:
+		return "low"
+	case MemPressureMedium:
+		return "medium"
+	case MemPressureHigh:
+		return "high"
+	case MemPressureCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+// ParsePressure returns the pressure level named by s
+func ParsePressure(s string) (MemPressure, error) {
+	for p := MemPressureNone; p <= MemPressureCritical; p++ {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return MemPressureNone, fmt.Errorf("unknown memory pressure level: %s", s)
+}
+
 // MemManagerConfig represents memory manager configuration
 type MemManagerConfig struct {
 	MaxMemoryBytes   uint64
@@ -75,7 +122,9 @@ type MemManagerConfig struct {
 	OnMemoryLimit    func(uint64, uint64) error
 	OnGCTrigger      func()
 	OnMemoryPressure func(float64) error
-	Logger           *zap.Logger
+	// StatSource reads memory statistics (default: runtime.ReadMemStats)
+	StatSource func(*runtime.MemStats)
+	Logger     *zap.Logger
 }
 
 // NewMemoryManager creates a new memory manager
@@ -91,9 +140,15 @@ func NewMemoryManager(cfg *MemManagerConfig) *MemoryManager {
 		onMemoryLimit:    cfg.OnMemoryLimit,
 		onGCTrigger:      cfg.OnGCTrigger,
 		onMemoryPressure: cfg.OnMemoryPressure,
+		readStats:        cfg.StatSource,
 		logger:           cfg.Logger,
 		stopCh:           make(chan struct{}),
 		memoryStats:      MemoryStats{},
+		bufferPool:       NewSizedPool(),
+	}
+
+	if mgr.readStats == nil {
+		mgr.readStats = runtime.ReadMemStats
 	}
 
 	// Initialize stats
@@ -106,23 +161,64 @@ func NewMemoryManager(cfg *MemManagerConfig) *MemoryManager {
 	return mgr
 }
 
-// Start begins memory monitoring
+// Start begins memory monitoring. A nonzero max memory also becomes the runtime
+// soft memory limit, so the GC works to stay below it before the reactive limit
+// callbacks fire. When GOMEMLIMIT is set the runtime limit is left as the
+// operator set it, and is used as the max memory if none is configured.
 func (m *MemoryManager) Start(ctx context.Context) error {
+	m.limitLock.Lock()
+	m.previousLimit = debug.SetMemoryLimit(-1)
+	m.envLimit = os.Getenv("GOMEMLIMIT") != ""
+	if m.envLimit && m.previousLimit < math.MaxInt64 && atomic.LoadUint64(&m.maxMemoryBytes) == 0 {
+		atomic.StoreUint64(&m.maxMemoryBytes, uint64(m.previousLimit))
+	}
+	m.running = true
+	m.applySoftLimit(atomic.LoadUint64(&m.maxMemoryBytes))
+	m.limitLock.Unlock()
+
 	go m.monitorLoop(ctx)
 
 	m.logger.Info("Memory manager started",
-		zap.Uint64("max_memory", m.maxMemoryBytes),
+		zap.Uint64("max_memory", atomic.LoadUint64(&m.maxMemoryBytes)),
+		zap.Bool("soft_limit", m.softLimit.Load()),
 		zap.Float64("gc_threshold", m.gcThreshold),
 		zap.Duration("monitor_interval", m.monitorInterval))
 
 	return nil
 }
 
-// Stop stops memory monitoring
+// Stop stops memory monitoring and restores the runtime memory limit
 func (m *MemoryManager) Stop() {
+	m.limitLock.Lock()
+	if m.running {
+		m.applySoftLimit(0)
+		m.running = false
+	}
+	m.limitLock.Unlock()
+
 	close(m.stopCh)
 }
 
+// applySoftLimit sets the runtime soft memory limit, or restores the limit in
+// place before Start when limit is zero. It does nothing when GOMEMLIMIT is
+// set. Callers hold limitLock.
+func (m *MemoryManager) applySoftLimit(limit uint64) {
+	if m.envLimit {
+		return
+	}
+	if limit == 0 {
+		debug.SetMemoryLimit(m.previousLimit)
+		m.softLimit.Store(false)
+		return
+	}
+
+	if limit > math.MaxInt64 {
+		limit = math.MaxInt64
+	}
+	debug.SetMemoryLimit(int64(limit))
+	m.softLimit.Store(true)
+}
+
 // monitorLoop continuously monitors memory usage
 func (m *MemoryManager) monitorLoop(ctx context.Context) {
 	ticker := time.NewTicker(m.monitorInterval)
@@ -144,7 +240,7 @@ func (m *MemoryManager) monitorLoop(ctx context.Context) {
 // checkMemoryUsage monitors current memory usage and takes action if needed
 func (m *MemoryManager) checkMemoryUsage() {
 	stats := runtime.MemStats{}
-	runtime.ReadMemStats(&stats)
+	m.readStats(&stats)
 
 	currentMemory := stats.Alloc
 
@@ -153,8 +249,9 @@ func (m *MemoryManager) checkMemoryUsage() {
 	atomic.StoreUint64(&m.currentMemory, currentMemory)
 
 	// Check memory limit
-	if m.maxMemoryBytes > 0 && currentMemory > m.maxMemoryBytes {
-		m.handleMemoryLimit(currentMemory, m.maxMemoryBytes)
+	if limit := atomic.LoadUint64(&m.maxMemoryBytes); limit > 0 && currentMemory > limit {
+		m.setPressure(MemPressureCritical)
+		m.handleMemoryLimit(currentMemory, limit)
 		return
 	}
 
@@ -165,6 +262,7 @@ func (m *MemoryManager) checkMemoryUsage() {
 
 	// Check memory pressure
 	pressure := m.calculatePressure()
+	m.setPressure(pressure)
 	if pressure >= MemPressureMedium {
 		m.handleMemoryPressure(float64(pressure) / 4.0) // Normalize to 0-1
 	}
@@ -226,16 +324,37 @@ func (m *MemoryManager) handleMemoryPressure(level float64) {
 	}
 }
 
+// setPressure records the pressure level and notifies handlers when it changes
+func (m *MemoryManager) setPressure(level MemPressure) {
+	old := MemPressure(atomic.SwapInt32(&m.pressure, int32(level)))
+	if old == level {
+		return
+	}
+
+	m.logger.Info("Memory pressure changed",
+		zap.Stringer("from", old),
+		zap.Stringer("to", level))
+
+	m.lock.RLock()
+	handlers := m.pressureHandlers
+	m.lock.RUnlock()
+	for _, handler := range handlers {
+		handler(level)
+	}
+}
+
 // calculatePressure calculates current memory pressure level
 func (m *MemoryManager) calculatePressure() MemPressure {
 	stats := runtime.MemStats{}
-	runtime.ReadMemStats(&stats)
-
-	// Calculate pressure based on multiple factors
-	memoryPressure := float64(stats.Alloc) / float64(stats.Sys) // Allocation vs system memory
-	gcPressure := stats.GCCPUFraction                           // GC CPU fraction
-
-	combinedPressure := (memoryPressure + gcPressure) / 2
+	m.readStats(&stats)
+
+	// The heap's share of the max memory or the CPU share spent in GC,
+	// whichever is higher. Without a max memory the heap may grow freely and
+	// only the GC load counts.
+	combinedPressure := stats.GCCPUFraction
+	if limit := atomic.LoadUint64(&m.maxMemoryBytes); limit > 0 {
+		combinedPressure = math.Max(combinedPressure, float64(stats.Alloc)/float64(limit))
+	}
 
 	switch {
 	case combinedPressure > 0.8:
@@ -271,7 +390,7 @@ func (m *MemoryManager) updateStats() {
 	defer m.lock.Unlock()
 
 	stats := runtime.MemStats{}
-	runtime.ReadMemStats(&stats)
+	m.readStats(&stats)
 
 	m.memoryStats = MemoryStats{
 		CurrentMemory:  stats.Alloc,
@@ -293,8 +412,17 @@ func (m *MemoryManager) updateStats() {
 // GetMemoryStats returns current memory statistics
 func (m *MemoryManager) GetMemoryStats() MemoryStats {
 	m.lock.RLock()
-	defer m.lock.RUnlock()
-	return m.memoryStats
+	stats := m.memoryStats
+	m.lock.RUnlock()
+
+	stats.PoolHits, stats.PoolMisses = m.bufferPool.Stats()
+	stats.SoftLimitActive = m.softLimit.Load()
+	return stats
+}
+
+// BufferPool returns the manager's buffer pool
+func (m *MemoryManager) BufferPool() *SizedPool {
+	return m.bufferPool
 }
 
 // GetCurrentMemory returns current memory usage
@@ -314,6 +442,19 @@ func (m *MemoryManager) GetPressureLevel() MemPressure {
 	return m.calculatePressure()
 }
 
+// LastPressureLevel returns the pressure level seen by the most recent check
+func (m *MemoryManager) LastPressureLevel() MemPressure {
+	return MemPressure(atomic.LoadInt32(&m.pressure))
+}
+
+// OnPressureChange registers a handler called with the new level whenever the
+// memory pressure level changes, including when it falls
+func (m *MemoryManager) OnPressureChange(handler func(MemPressure)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pressureHandlers = append(m.pressureHandlers, handler)
+}
+
 // Alloc allocates memory and tracks allocation
 func (m *MemoryManager) Alloc(size uint64) []byte {
 	atomic.AddUint64(&m.allocationCount, 1)
@@ -321,9 +462,16 @@ func (m *MemoryManager) Alloc(size uint64) []byte {
 	return buf
 }
 
-// SetMaxMemory sets the maximum memory limit
+// SetMaxMemory sets the maximum memory limit. While the manager is running the
+// runtime soft memory limit follows it; zero removes the soft limit.
 func (m *MemoryManager) SetMaxMemory(limit uint64) {
+	m.limitLock.Lock()
+	defer m.limitLock.Unlock()
+
 	atomic.StoreUint64(&m.maxMemoryBytes, limit)
+	if m.running {
+		m.applySoftLimit(limit)
+	}
 }
 
 // SetGCThreshold sets the GC trigger threshold
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryManagerSoftLimit(t *testing.T) {
+	original := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(original)
+
+	mgr := NewMemoryManager(&MemManagerConfig{
+		MaxMemoryBytes:  512 << 20,
+		GCThreshold:     0.7,
+		MonitorInterval: time.Second,
+	})
+	if mgr.GetMemoryStats().SoftLimitActive {
+		t.Error("Expected soft limit to be inactive before Start")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Failed to start memory manager: %v", err)
+	}
+
+	if limit := debug.SetMemoryLimit(-1); limit != 512<<20 {
+		t.Errorf("Expected runtime limit %d, got %d", 512<<20, limit)
+	}
+	if !mgr.GetMemoryStats().SoftLimitActive {
+		t.Error("Expected soft limit to be active")
+	}
+
+	mgr.SetMaxMemory(256 << 20)
+	if limit := debug.SetMemoryLimit(-1); limit != 256<<20 {
+		t.Errorf("Expected runtime limit %d after SetMaxMemory, got %d", 256<<20, limit)
+	}
+
+	// Zero removes the soft limit but keeps the manager running
+	mgr.SetMaxMemory(0)
+	if limit := debug.SetMemoryLimit(-1); limit != original {
+		t.Errorf("Expected runtime limit restored to %d, got %d", original, limit)
+	}
+	if mgr.GetMemoryStats().SoftLimitActive {
+		t.Error("Expected soft limit to be inactive without a max memory")
+	}
+
+	mgr.SetMaxMemory(128 << 20)
+	mgr.Stop()
+	if limit := debug.SetMemoryLimit(-1); limit != original {
+		t.Errorf("Expected runtime limit restored to %d after Stop, got %d", original, limit)
+	}
+}
+
+func TestMemoryManagerRespectsGOMEMLIMIT(t *testing.T) {
+	original := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(original)
+
+	// As if the process had started with GOMEMLIMIT=768MiB
+	t.Setenv("GOMEMLIMIT", "768MiB")
+	debug.SetMemoryLimit(768 << 20)
+
+	mgr := NewMemoryManager(&MemManagerConfig{GCThreshold: 0.7, MonitorInterval: time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Failed to start memory manager: %v", err)
+	}
+
+	if limit := atomic.LoadUint64(&mgr.maxMemoryBytes); limit != 768<<20 {
+		t.Errorf("Expected GOMEMLIMIT to be used as max memory, got %d", limit)
+	}
+
+	mgr.SetMaxMemory(256 << 20)
+	if limit := debug.SetMemoryLimit(-1); limit != 768<<20 {
+		t.Errorf("Expected runtime limit to stay at GOMEMLIMIT, got %d", limit)
+	}
+	if mgr.GetMemoryStats().SoftLimitActive {
+		t.Error("Expected soft limit to be inactive with GOMEMLIMIT set")
+	}
+
+	mgr.Stop()
+	if limit := debug.SetMemoryLimit(-1); limit != 768<<20 {
+		t.Errorf("Expected runtime limit to stay at GOMEMLIMIT after Stop, got %d", limit)
+	}
+}
+
+func TestMemoryManagerPressureLevels(t *testing.T) {
+	var stats runtime.MemStats
+	mgr := NewMemoryManager(&MemManagerConfig{
+		MaxMemoryBytes: 100,
+		StatSource:     func(s *runtime.MemStats) { *s = stats },
+	})
+
+	tests := []struct {
+		alloc    uint64
+		gc       float64
+		expected MemPressure
+	}{
+		{10, 0, MemPressureNone},
+		{30, 0, MemPressureLow},
+		{50, 0, MemPressureMedium},
+		{70, 0, MemPressureHigh},
+		{90, 0, MemPressureCritical},
+		{10, 0.7, MemPressureHigh}, // GC load alone
+	}
+	for _, tt := range tests {
+		stats = runtime.MemStats{Alloc: tt.alloc, GCCPUFraction: tt.gc}
+		if got := mgr.calculatePressure(); got != tt.expected {
+			t.Errorf("alloc %d, gc %.1f: expected pressure %s, got %s", tt.alloc, tt.gc, tt.expected, got)
+		}
+	}
+}
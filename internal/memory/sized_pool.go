@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	// minPooledSize is the smallest size class of a SizedPool
+	minPooledSize = 512
+	// maxPooledSize is the largest size class; larger buffers are not pooled
+	maxPooledSize = 64 * 1024
+	// maxPooledPerClass is how many idle buffers each size class keeps
+	maxPooledPerClass = 64
+)
+
+// SizedPool is a bounded pool of byte buffers in power-of-two size classes from
+// 512 bytes to 64KB, keeping at most 64 idle buffers per class. Buffers are
+// zeroed when they are returned, so a buffer from Get never holds data from a
+// previous user.
+type SizedPool struct {
+	// classes[i] holds idle buffers with a capacity of minPooledSize<<i
+	classes []chan []byte
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+// NewSizedPool creates an empty sized buffer pool
+func NewSizedPool() *SizedPool {
+	classes := make([]chan []byte, sizeClass(maxPooledSize)+1)
+	for i := range classes {
+		classes[i] = make(chan []byte, maxPooledPerClass)
+	}
+	return &SizedPool{classes: classes}
+}
+
+// sizeClass returns the index of the smallest class holding size bytes
+func sizeClass(size int) int {
+	if size <= minPooledSize {
+		return 0
+	}
+	return bits.Len(uint(size-1)) - bits.Len(uint(minPooledSize-1))
+}
+
+// Get returns a zeroed buffer of length size. Sizes above 64KB are allocated
+// directly and count as a miss.
+func (p *SizedPool) Get(size int) []byte {
+	if size > maxPooledSize {
+		p.misses.Add(1)
+		return make([]byte, size)
+	}
+
+	class := sizeClass(size)
+	select {
+	case buf := <-p.classes[class]:
+		p.hits.Add(1)
+		return buf[:size]
+	default:
+		p.misses.Add(1)
+		return make([]byte, size, minPooledSize<<class)
+	}
+}
+
+// Put zeroes a buffer and returns it to the pool. Buffers that did not come from
+// Get are dropped unless their capacity is exactly a size class, as are buffers
+// returned to a full class.
+func (p *SizedPool) Put(buf []byte) {
+	c := cap(buf)
+	if c < minPooledSize || c > maxPooledSize || c&(c-1) != 0 {
+		return
+	}
+
+	buf = buf[:c]
+	clear(buf)
+	select {
+	case p.classes[sizeClass(c)] <- buf:
+	default:
+	}
+}
+
+// Stats returns the number of Get calls served from the pool and those that
+// allocated a new buffer
+func (p *SizedPool) Stats() (hits, misses uint64) {
+	return p.hits.Load(), p.misses.Load()
+}
@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// readSize is a full MTU-sized packet plus overhead, as used by the transfer path
+const readSize = 1500 + 128
+
+func TestSizedPoolGet(t *testing.T) {
+	pool := NewSizedPool()
+
+	tests := []struct {
+		size    int
+		wantCap int
+	}{
+		{1, 512},
+		{512, 512},
+		{513, 1024},
+		{readSize, 2048},
+		{9128, 16384},
+		{64 * 1024, 64 * 1024},
+		{64*1024 + 1, 64*1024 + 1},
+	}
+
+	for _, tt := range tests {
+		buf := pool.Get(tt.size)
+		if len(buf) != tt.size || cap(buf) != tt.wantCap {
+			t.Errorf("Get(%d): expected len %d cap %d, got len %d cap %d", tt.size, tt.size, tt.wantCap, len(buf), cap(buf))
+		}
+		pool.Put(buf)
+	}
+}
+
+func TestSizedPoolReuseIsZeroed(t *testing.T) {
+	pool := NewSizedPool()
+	zero := make([]byte, 2048)
+
+	// Dirty every buffer up to its capacity before returning it
+	for i := 0; i < 100; i++ {
+		buf := pool.Get(readSize - i)
+		full := buf[:cap(buf)]
+		if !bytes.Equal(full, zero[:len(full)]) {
+			t.Fatalf("Expected buffer %d to be zeroed", i)
+		}
+		for j := range full {
+			full[j] = 0xAA
+		}
+		pool.Put(buf)
+	}
+
+	if hits, _ := pool.Stats(); hits == 0 {
+		t.Error("Expected buffers to be reused")
+	}
+}
+
+func TestSizedPoolBounded(t *testing.T) {
+	pool := NewSizedPool()
+
+	bufs := make([][]byte, maxPooledPerClass+10)
+	for i := range bufs {
+		bufs[i] = pool.Get(readSize)
+	}
+	for _, buf := range bufs {
+		pool.Put(buf)
+	}
+	if idle := len(pool.classes[sizeClass(readSize)]); idle != maxPooledPerClass {
+		t.Errorf("Expected %d idle buffers in the class, got %d", maxPooledPerClass, idle)
+	}
+}
+
+func TestSizedPoolStats(t *testing.T) {
+	pool := NewSizedPool()
+
+	buf := pool.Get(readSize)
+	pool.Put(buf)
+	for i := 0; i < 10; i++ {
+		pool.Put(pool.Get(readSize))
+	}
+	pool.Get(128 * 1024)
+
+	hits, misses := pool.Stats()
+	if hits+misses != 12 {
+		t.Errorf("Expected 12 requests, got %d hits and %d misses", hits, misses)
+	}
+	if misses < 2 {
+		t.Errorf("Expected at least 2 misses, got %d", misses)
+	}
+
+	// Foreign buffers of other capacities are not pooled
+	pool.Put(make([]byte, 1000))
+	pool.Put(make([]byte, 100))
+}
+
+func TestMemoryManagerBufferPoolStats(t *testing.T) {
+	mgr := NewMemoryManager(nil)
+
+	mgr.BufferPool().Put(mgr.BufferPool().Get(readSize))
+	mgr.BufferPool().Get(readSize)
+
+	stats := mgr.GetMemoryStats()
+	if stats.PoolHits+stats.PoolMisses != 2 {
+		t.Errorf("Expected 2 pool requests, got %d hits and %d misses", stats.PoolHits, stats.PoolMisses)
+	}
+}
+
+// packetReader returns a fixed packet on every read
+type packetReader struct {
+	packet []byte
+}
+
+func (r *packetReader) Read(b []byte) (int, error) {
+	return copy(b, r.packet), nil
+}
+
+// benchSource is read through the interface, so read buffers escape as they do
+// with a net.Conn
+var benchSource io.Reader = &packetReader{packet: bytes.Repeat([]byte{1}, 1400)}
+
+// BenchmarkReadBufferAlloc allocates a fresh buffer for every read
+func BenchmarkReadBufferAlloc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := make([]byte, readSize)
+		if _, err := benchSource.Read(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSizedPool draws every read buffer from a SizedPool
+func BenchmarkSizedPool(b *testing.B) {
+	pool := NewSizedPool()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := pool.Get(readSize)
+		if _, err := benchSource.Read(buf); err != nil {
+			b.Fatal(err)
+		}
+		pool.Put(buf)
+	}
+}
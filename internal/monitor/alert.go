@@ -0,0 +1,298 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"go.uber.org/zap"
+)
+
+// DefaultAlertTimeout bounds a webhook call when no timeout is configured
+const DefaultAlertTimeout = 10 * time.Second
+
+// AlertRule is a threshold on one metric
+type AlertRule struct {
+	Name      string
+	Metric    string        // See alertValue for the metrics known
+	Threshold float64       // Fires above it; memory_pressure fires at this level or above
+	For       time.Duration // How long the threshold must stay crossed before firing
+	Cooldown  time.Duration // Least time between two firings
+}
+
+// AlertConfig controls threshold alerts
+type AlertConfig struct {
+	Interval   time.Duration // Between evaluations; zero disables alerts
+	WebhookURL string
+	Timeout    time.Duration
+	Rules      []AlertRule
+}
+
+// AlertConfigFrom converts the configured alert settings. Disabled alerts have
+// a zero interval.
+func AlertConfigFrom(cfg types.AlertsConfig) (AlertConfig, error) {
+	if !cfg.Enabled {
+		return AlertConfig{}, nil
+	}
+
+	config := AlertConfig{
+		Interval:   cfg.Interval,
+		WebhookURL: cfg.WebhookURL,
+		Timeout:    cfg.Timeout,
+	}
+	for _, rule := range cfg.Rules {
+		threshold := rule.Threshold
+		if rule.Metric == "memory_pressure" {
+			level, err := memory.ParsePressure(rule.Level)
+			if err != nil {
+				return AlertConfig{}, fmt.Errorf("alert rule %s: %w", rule.Name, err)
+			}
+			threshold = float64(level)
+		}
+		config.Rules = append(config.Rules, AlertRule{
+			Name:      rule.Name,
+			Metric:    rule.Metric,
+			Threshold: threshold,
+			For:       rule.For,
+			Cooldown:  rule.Cooldown,
+		})
+	}
+	return config, nil
+}
+
+// AlertStatus is whether an alert is firing or has resolved
+type AlertStatus string
+
+const (
+	AlertFiring   AlertStatus = "firing"
+	AlertResolved AlertStatus = "resolved"
+)
+
+// Alert is sent to a notifier when a rule fires or resolves
+type Alert struct {
+	Rule      string      `json:"rule"`
+	Metric    string      `json:"metric"`
+	Status    AlertStatus `json:"status"`
+	Value     float64     `json:"value"`
+	Threshold float64     `json:"threshold"`
+	Time      time.Time   `json:"time"`
+}
+
+// Notifier delivers alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// WebhookNotifier POSTs each alert as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier for url whose calls are bounded by timeout
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	if timeout <= 0 {
+		timeout = DefaultAlertTimeout
+	}
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify implements Notifier
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sssonector")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// alertState tracks one rule between evaluations
+type alertState struct {
+	crossedAt time.Time // When the threshold was crossed; zero while it is not
+	firing    bool
+	firedAt   time.Time // When the rule last fired
+}
+
+// AlertManager periodically checks the metrics against the alert rules,
+// notifying when a rule fires and again when its metric recovers
+type AlertManager struct {
+	config   AlertConfig
+	snapshot func() *Metrics
+	pressure func() memory.MemPressure
+	notifier Notifier
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	states []alertState // By rule index
+
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewAlertManager creates a manager that checks the metrics read from snapshot.
+// pressure reports the memory pressure for memory_pressure rules and may be nil.
+func NewAlertManager(cfg AlertConfig, snapshot func() *Metrics, pressure func() memory.MemPressure, notifier Notifier, logger *zap.Logger) *AlertManager {
+	return &AlertManager{
+		config:   cfg,
+		snapshot: snapshot,
+		pressure: pressure,
+		notifier: notifier,
+		logger:   logger,
+		states:   make([]alertState, len(cfg.Rules)),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins evaluating the rules at the configured interval
+func (a *AlertManager) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(a.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-a.stopCh:
+				return
+			case now := <-ticker.C:
+				a.evaluate(context.Background(), a.snapshot(), now)
+			}
+		}
+	}()
+}
+
+// Stop stops evaluating; alerts still firing are not resolved
+func (a *AlertManager) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.stopCh)
+	})
+	a.wg.Wait()
+}
+
+// Evaluate checks metrics against every rule now, notifying of the alerts that
+// fire or resolve
+func (a *AlertManager) Evaluate(ctx context.Context, metrics *Metrics) {
+	a.evaluate(ctx, metrics, time.Now())
+}
+
+func (a *AlertManager) evaluate(ctx context.Context, metrics *Metrics, now time.Time) {
+	var alerts []Alert
+
+	a.mu.Lock()
+	for i, rule := range a.config.Rules {
+		value, ok := a.alertValue(rule.Metric, metrics)
+		if !ok {
+			continue
+		}
+		crossed := value > rule.Threshold
+		if rule.Metric == "memory_pressure" {
+			crossed = value >= rule.Threshold
+		}
+		if status, changed := a.states[i].update(rule, crossed, now); changed {
+			alerts = append(alerts, Alert{
+				Rule:      rule.Name,
+				Metric:    rule.Metric,
+				Status:    status,
+				Value:     value,
+				Threshold: rule.Threshold,
+				Time:      now,
+			})
+		}
+	}
+	a.mu.Unlock()
+
+	for _, alert := range alerts {
+		a.logger.Warn("Alert "+string(alert.Status),
+			zap.String("rule", alert.Rule),
+			zap.String("metric", alert.Metric),
+			zap.Float64("value", alert.Value),
+			zap.Float64("threshold", alert.Threshold))
+		if err := a.notifier.Notify(ctx, alert); err != nil {
+			a.logger.Warn("Failed to send alert", zap.String("rule", alert.Rule), zap.Error(err))
+		}
+	}
+}
+
+// update moves the state on by one evaluation, reporting whether the alert fired
+// or resolved. A rule fires once its threshold has been crossed for rule.For,
+// unless it fired within rule.Cooldown; one held back by its cooldown fires
+// when the cooldown ends if the threshold is still crossed.
+func (state *alertState) update(rule AlertRule, crossed bool, now time.Time) (AlertStatus, bool) {
+	if !crossed {
+		state.crossedAt = time.Time{}
+		if state.firing {
+			state.firing = false
+			return AlertResolved, true
+		}
+		return "", false
+	}
+
+	if state.crossedAt.IsZero() {
+		state.crossedAt = now
+	}
+	if state.firing || now.Sub(state.crossedAt) < rule.For {
+		return "", false
+	}
+	if !state.firedAt.IsZero() && now.Sub(state.firedAt) < rule.Cooldown {
+		return "", false
+	}
+	state.firing = true
+	state.firedAt = now
+	return AlertFiring, true
+}
+
+// alertValue reads metric from metrics, reporting false for an unknown metric
+// or memory pressure with no source for it
+func (a *AlertManager) alertValue(metric string, metrics *Metrics) (float64, bool) {
+	switch metric {
+	case "connections":
+		return float64(metrics.Connections), true
+	case "error_rate":
+		return metrics.ErrorRate, true
+	case "cpu_usage":
+		return metrics.CPUUsage, true
+	case "memory_usage":
+		return float64(metrics.MemoryUsage), true
+	case "packet_loss":
+		return metrics.PacketLoss, true
+	case "memory_pressure":
+		if a.pressure == nil {
+			return 0, false
+		}
+		return float64(a.pressure()), true
+	}
+	return 0, false
+}
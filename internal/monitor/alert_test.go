@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"go.uber.org/zap"
+)
+
+// alertRecorder is a webhook that keeps the alerts posted to it
+type alertRecorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *alertRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var alert Alert
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected a JSON POST", http.StatusBadRequest)
+		return
+	}
+	if err := json.NewDecoder(req.Body).Decode(&alert); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	r.alerts = append(r.alerts, alert)
+	r.mu.Unlock()
+}
+
+func (r *alertRecorder) received() []Alert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Alert(nil), r.alerts...)
+}
+
+func TestAlertManagerWebhook(t *testing.T) {
+	recorder := &alertRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	cfg := AlertConfig{
+		Rules: []AlertRule{{Name: "too-many-connections", Metric: "connections", Threshold: 10, For: 2 * time.Second, Cooldown: time.Minute}},
+	}
+	manager := NewAlertManager(cfg, nil, nil, NewWebhookNotifier(server.URL, time.Second), zap.NewNop())
+
+	ctx := context.Background()
+	start := time.Now()
+	at := func(seconds int, connections int32) {
+		manager.evaluate(ctx, &Metrics{Connections: connections}, start.Add(time.Duration(seconds)*time.Second))
+	}
+
+	// Crossing the threshold fires once it has held for 2s, and only once
+	at(0, 5)
+	at(1, 20)
+	at(2, 20)
+	if alerts := recorder.received(); len(alerts) != 0 {
+		t.Fatalf("Expected no alert within the debounce, got %+v", alerts)
+	}
+	at(3, 20)
+	at(4, 25)
+	at(5, 30)
+	alerts := recorder.received()
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 webhook call, got %d", len(alerts))
+	}
+	if alerts[0].Rule != "too-many-connections" || alerts[0].Status != AlertFiring || alerts[0].Value != 20 || alerts[0].Threshold != 10 {
+		t.Errorf("Expected the rule firing at 20 connections, got %+v", alerts[0])
+	}
+
+	// Recovering resolves it
+	at(6, 3)
+	alerts = recorder.received()
+	if len(alerts) != 2 || alerts[1].Status != AlertResolved || alerts[1].Value != 3 {
+		t.Fatalf("Expected the alert to resolve, got %+v", alerts)
+	}
+
+	// Crossing again within the cooldown does not fire
+	at(10, 20)
+	at(20, 20)
+	if alerts := recorder.received(); len(alerts) != 2 {
+		t.Fatalf("Expected no alert within the cooldown, got %+v", alerts[2:])
+	}
+
+	// Still crossed once the cooldown ends, it fires again
+	at(65, 20)
+	if alerts := recorder.received(); len(alerts) != 3 || alerts[2].Status != AlertFiring {
+		t.Fatalf("Expected the alert to fire after the cooldown, got %+v", alerts)
+	}
+}
+
+func TestAlertManagerMemoryPressure(t *testing.T) {
+	recorder := &alertRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	cfg, err := AlertConfigFrom(types.AlertsConfig{
+		Enabled:    true,
+		Interval:   time.Second,
+		WebhookURL: server.URL,
+		Rules:      []types.AlertRuleConfig{{Name: "memory", Metric: "memory_pressure", Level: "high"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert alert config: %v", err)
+	}
+
+	pressure := memory.MemPressureMedium
+	manager := NewAlertManager(cfg, nil, func() memory.MemPressure { return pressure }, NewWebhookNotifier(server.URL, 0), zap.NewNop())
+
+	manager.Evaluate(context.Background(), &Metrics{})
+	pressure = memory.MemPressureHigh
+	manager.Evaluate(context.Background(), &Metrics{})
+
+	alerts := recorder.received()
+	if len(alerts) != 1 || alerts[0].Status != AlertFiring || alerts[0].Value != float64(memory.MemPressureHigh) {
+		t.Fatalf("Expected the rule to fire at high pressure, got %+v", alerts)
+	}
+}
+
+func TestAlertConfigFrom(t *testing.T) {
+	if cfg, err := AlertConfigFrom(types.AlertsConfig{Interval: time.Second}); err != nil || cfg.Interval != 0 {
+		t.Errorf("Expected disabled alerts to have a zero interval, got %+v, %v", cfg, err)
+	}
+
+	_, err := AlertConfigFrom(types.AlertsConfig{
+		Enabled: true,
+		Rules:   []types.AlertRuleConfig{{Name: "memory", Metric: "memory_pressure", Level: "extreme"}},
+	})
+	if err == nil {
+		t.Error("Expected an unknown pressure level to be rejected")
+	}
+}
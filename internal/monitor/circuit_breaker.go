@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+)
+
+// BreakerStats holds the state and counters of one circuit breaker
+type BreakerStats struct {
+	Name        string
+	State       resilience.CircuitBreakerState // 0 closed, 1 half-open, 2 open
+	Transitions uint64
+	FailureRate float64
+	Requests    uint64
+	Failures    uint64
+}
+
+// CollectBreakerStats reads the current state of every breaker in states,
+// sorted by name
+func CollectBreakerStats(states *resilience.CircuitBreakerStates) []BreakerStats {
+	breakers := states.GetAllBreakers()
+	stats := make([]BreakerStats, 0, len(breakers))
+	for _, breaker := range breakers {
+		s := breaker.GetStats()
+		stats = append(stats, BreakerStats{
+			Name:        breaker.Name(),
+			State:       s.State,
+			Transitions: s.Transitions,
+			FailureRate: breaker.GetFailureRate(),
+			Requests:    s.TotalRequests,
+			Failures:    s.TotalFailures,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// SetCircuitBreakers sets the circuit breakers whose state is read into every
+// metrics snapshot
+func (m *Monitor) SetCircuitBreakers(states *resilience.CircuitBreakerStates) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.breakers = states
+}
+
+// writeBreakersPrometheus writes circuit breaker state and counters in the
+// Prometheus text exposition format, labelled by breaker name
+func writeBreakersPrometheus(w io.Writer, breakers []BreakerStats) error {
+	if len(breakers) == 0 {
+		return nil
+	}
+
+	metrics := []struct {
+		name, help, kind string
+		value            func(BreakerStats) string
+	}{
+		{"sssonector_circuit_breaker_state", "Circuit breaker state: 0 closed, 1 half-open, 2 open", "gauge",
+			func(b BreakerStats) string { return fmt.Sprintf("%d", b.State) }},
+		{"sssonector_circuit_breaker_transitions_total", "Circuit breaker state transitions", "counter",
+			func(b BreakerStats) string { return fmt.Sprintf("%d", b.Transitions) }},
+		{"sssonector_circuit_breaker_failure_rate", "Fraction of circuit breaker requests that failed", "gauge",
+			func(b BreakerStats) string { return fmt.Sprintf("%g", b.FailureRate) }},
+		{"sssonector_circuit_breaker_requests_total", "Requests through the circuit breaker", "counter",
+			func(b BreakerStats) string { return fmt.Sprintf("%d", b.Requests) }},
+		{"sssonector_circuit_breaker_failures_total", "Failed requests through the circuit breaker", "counter",
+			func(b BreakerStats) string { return fmt.Sprintf("%d", b.Failures) }},
+	}
+
+	var lines []string
+	for _, metric := range metrics {
+		lines = append(lines,
+			fmt.Sprintf("# HELP %s %s", metric.name, metric.help),
+			fmt.Sprintf("# TYPE %s %s", metric.name, metric.kind),
+		)
+		for _, breaker := range breakers {
+			lines = append(lines, fmt.Sprintf("%s{breaker=%q} %s", metric.name, breaker.Name, metric.value(breaker)))
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
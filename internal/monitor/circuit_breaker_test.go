@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+)
+
+func TestCircuitBreakerPrometheus(t *testing.T) {
+	states := resilience.NewCircuitBreakerStates(nil)
+	for _, name := range []string{"tunnel", "cert"} {
+		breaker := resilience.NewCircuitBreaker(&resilience.CircuitBreakerConfig{Name: name}, nil)
+		if err := states.AddBreaker(name, breaker); err != nil {
+			t.Fatalf("Failed to add breaker: %v", err)
+		}
+	}
+	tunnel, _ := states.GetBreaker("tunnel")
+	tunnel.ForceOpen()
+
+	m := &Monitor{metrics: NewMetrics()}
+	m.SetCircuitBreakers(states)
+
+	var out bytes.Buffer
+	if err := m.GetMetrics().WritePrometheus(&out); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	scraped := out.String()
+
+	for _, expected := range []string{
+		"# TYPE sssonector_circuit_breaker_state gauge",
+		`sssonector_circuit_breaker_state{breaker="tunnel"} 2`,
+		`sssonector_circuit_breaker_state{breaker="cert"} 0`,
+		`sssonector_circuit_breaker_transitions_total{breaker="tunnel"} 1`,
+		`sssonector_circuit_breaker_transitions_total{breaker="cert"} 0`,
+		`sssonector_circuit_breaker_failure_rate{breaker="tunnel"} 0`,
+		`sssonector_circuit_breaker_requests_total{breaker="tunnel"} 0`,
+		`sssonector_circuit_breaker_failures_total{breaker="tunnel"} 0`,
+	} {
+		if !strings.Contains(scraped, expected+"\n") {
+			t.Errorf("Expected scrape to contain %q", expected)
+		}
+	}
+
+	// Breakers are listed by name
+	if strings.Index(scraped, `{breaker="cert"}`) > strings.Index(scraped, `{breaker="tunnel"}`) {
+		t.Error("Expected breakers sorted by name")
+	}
+}
+
+func TestPrometheusScrapeEndpoint(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	states := resilience.NewCircuitBreakerStates(nil)
+	breaker := resilience.NewCircuitBreaker(&resilience.CircuitBreakerConfig{Name: "endpoint 10.0.0.1:8443"}, nil)
+	if err := states.AddBreaker(breaker.Name(), breaker); err != nil {
+		t.Fatalf("Failed to add breaker: %v", err)
+	}
+
+	m, err := New(&Config{LogFile: "stderr", PrometheusAddress: address})
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+	m.SetCircuitBreakers(states)
+	if err := m.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+	defer m.Stop()
+
+	resp, err := http.Get("http://" + address + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to scrape: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read scrape: %v", err)
+	}
+	if want := `sssonector_circuit_breaker_state{breaker="endpoint 10.0.0.1:8443"} 0`; !strings.Contains(string(body), want) {
+		t.Errorf("Expected %q in the scrape, got:\n%s", want, body)
+	}
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// handshakeSampleSize is the number of recent handshakes used for the average and p95
+const handshakeSampleSize = 1024
+
+// HandshakeStats summarizes TLS handshake durations
+type HandshakeStats struct {
+	Count     int64         // Completed handshakes
+	Failures  int64         // Failed handshakes
+	Timeouts  int64         // Failed handshakes the peer did not complete in time
+	SlowCount int64         // Handshakes slower than the slow threshold
+	Average   time.Duration // Average over recent handshakes
+	P95       time.Duration // 95th percentile over recent handshakes
+	Max       time.Duration // Slowest handshake seen
+}
+
+// HandshakeRecorder records TLS handshake durations per connection
+type HandshakeRecorder struct {
+	mu            sync.Mutex
+	slowThreshold time.Duration
+	samples       []time.Duration // ring buffer of recent durations
+	next          int
+	stats         HandshakeStats
+}
+
+// NewHandshakeRecorder creates a recorder that counts handshakes above slowThreshold as slow
+func NewHandshakeRecorder(slowThreshold time.Duration) *HandshakeRecorder {
+	return &HandshakeRecorder{
+		slowThreshold: slowThreshold,
+		samples:       make([]time.Duration, 0, handshakeSampleSize),
+	}
+}
+
+// SlowThreshold returns the duration above which a handshake is considered slow
+func (r *HandshakeRecorder) SlowThreshold() time.Duration {
+	return r.slowThreshold
+}
+
+// Record records the duration of a handshake and reports whether it was slow
+func (r *HandshakeRecorder) Record(duration time.Duration, err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.stats.Failures++
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+			r.stats.Timeouts++
+		}
+		return false
+	}
+
+	r.stats.Count++
+	if duration > r.stats.Max {
+		r.stats.Max = duration
+	}
+
+	if len(r.samples) < handshakeSampleSize {
+		r.samples = append(r.samples, duration)
+	} else {
+		r.samples[r.next] = duration
+		r.next = (r.next + 1) % handshakeSampleSize
+	}
+
+	slow := r.slowThreshold > 0 && duration > r.slowThreshold
+	if slow {
+		r.stats.SlowCount++
+	}
+	return slow
+}
+
+// Stats returns the handshake counters and aggregates over recent handshakes
+func (r *HandshakeRecorder) Stats() HandshakeStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	if len(r.samples) == 0 {
+		return stats
+	}
+
+	sorted := append([]time.Duration(nil), r.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	stats.Average = total / time.Duration(len(sorted))
+	stats.P95 = sorted[(len(sorted)*95+99)/100-1]
+
+	return stats
+}
+
+// UpdateHandshakeMetrics records a snapshot of TLS handshake statistics
+func (m *Monitor) UpdateHandshakeMetrics(stats HandshakeStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.Handshake = stats
+}
+
+// WritePrometheus writes handshake statistics in the Prometheus text exposition format
+func (s HandshakeStats) WritePrometheus(w io.Writer) error {
+	lines := []string{
+		"# HELP sssonector_tls_handshakes_total Completed TLS handshakes",
+		"# TYPE sssonector_tls_handshakes_total counter",
+		fmt.Sprintf("sssonector_tls_handshakes_total %d", s.Count),
+		"# HELP sssonector_tls_handshake_failures_total Failed TLS handshakes",
+		"# TYPE sssonector_tls_handshake_failures_total counter",
+		fmt.Sprintf("sssonector_tls_handshake_failures_total %d", s.Failures),
+		"# HELP sssonector_tls_handshake_timeouts_total TLS handshakes abandoned at the handshake timeout",
+		"# TYPE sssonector_tls_handshake_timeouts_total counter",
+		fmt.Sprintf("sssonector_tls_handshake_timeouts_total %d", s.Timeouts),
+		"# HELP sssonector_tls_handshakes_slow_total TLS handshakes slower than the slow threshold",
+		"# TYPE sssonector_tls_handshakes_slow_total counter",
+		fmt.Sprintf("sssonector_tls_handshakes_slow_total %d", s.SlowCount),
+		"# HELP sssonector_tls_handshake_duration_seconds TLS handshake duration over recent handshakes",
+		"# TYPE sssonector_tls_handshake_duration_seconds gauge",
+		fmt.Sprintf("sssonector_tls_handshake_duration_seconds{stat=\"avg\"} %g", s.Average.Seconds()),
+		fmt.Sprintf("sssonector_tls_handshake_duration_seconds{stat=\"p95\"} %g", s.P95.Seconds()),
+		fmt.Sprintf("sssonector_tls_handshake_duration_seconds{stat=\"max\"} %g", s.Max.Seconds()),
+	}
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandshakeRecorderStats(t *testing.T) {
+	recorder := NewHandshakeRecorder(90 * time.Millisecond)
+
+	for i := 1; i <= 100; i++ {
+		recorder.Record(time.Duration(i)*time.Millisecond, nil)
+	}
+	if recorder.Record(time.Second, errors.New("handshake failed")) {
+		t.Error("Expected failed handshake not to count as slow")
+	}
+
+	stats := recorder.Stats()
+	if stats.Count != 100 {
+		t.Errorf("Expected 100 handshakes, got %d", stats.Count)
+	}
+	if stats.Failures != 1 {
+		t.Errorf("Expected 1 failure, got %d", stats.Failures)
+	}
+	if stats.SlowCount != 10 {
+		t.Errorf("Expected 10 slow handshakes, got %d", stats.SlowCount)
+	}
+	if stats.Average != 50500*time.Microsecond {
+		t.Errorf("Expected average 50.5ms, got %v", stats.Average)
+	}
+	if stats.P95 != 95*time.Millisecond {
+		t.Errorf("Expected p95 95ms, got %v", stats.P95)
+	}
+	if stats.Max != 100*time.Millisecond {
+		t.Errorf("Expected max 100ms, got %v", stats.Max)
+	}
+
+	var sb strings.Builder
+	if err := stats.WritePrometheus(&sb); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	if !strings.Contains(sb.String(), `sssonector_tls_handshake_duration_seconds{stat="p95"} 0.095`) {
+		t.Errorf("Expected p95 series in output, got:\n%s", sb.String())
+	}
+}
+
+func TestHandshakeRecorderTimeouts(t *testing.T) {
+	recorder := NewHandshakeRecorder(time.Second)
+	recorder.Record(time.Second, fmt.Errorf("handshake: %w", os.ErrDeadlineExceeded))
+	recorder.Record(time.Second, context.DeadlineExceeded)
+	recorder.Record(time.Millisecond, errors.New("bad certificate"))
+
+	stats := recorder.Stats()
+	if stats.Failures != 3 {
+		t.Errorf("Expected 3 failures, got %d", stats.Failures)
+	}
+	if stats.Timeouts != 2 {
+		t.Errorf("Expected 2 timeouts, got %d", stats.Timeouts)
+	}
+
+	var sb strings.Builder
+	if err := stats.WritePrometheus(&sb); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	if !strings.Contains(sb.String(), "sssonector_tls_handshake_timeouts_total 2") {
+		t.Errorf("Expected timeouts series in output, got:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// HistoryConfig controls the in-memory history of metrics snapshots
+type HistoryConfig struct {
+	Interval time.Duration // Between snapshots; zero disables the history
+	Size     int           // Snapshots kept; the oldest is dropped for a new one
+}
+
+// HistoryConfigFrom converts the configured history settings, snapshotting
+// every monitor interval. A disabled history has a zero interval.
+func HistoryConfigFrom(cfg types.MonitorConfig) HistoryConfig {
+	if cfg.HistorySize <= 0 || cfg.Interval <= 0 {
+		return HistoryConfig{}
+	}
+	return HistoryConfig{
+		Interval: cfg.Interval,
+		Size:     cfg.HistorySize,
+	}
+}
+
+// MetricsSnapshot holds the metrics of one point in a history. Counters are
+// totals since start; rates are those at the time of the snapshot.
+type MetricsSnapshot struct {
+	Time        time.Time `json:"time"`
+	BytesIn     int64     `json:"bytes_in"`
+	BytesOut    int64     `json:"bytes_out"`
+	PacketsIn   int64     `json:"packets_in"`
+	PacketsOut  int64     `json:"packets_out"`
+	Errors      int64     `json:"errors"`
+	Connections int32     `json:"connections"`
+	RTT         int64     `json:"rtt_us"`
+	PacketLoss  float64   `json:"packet_loss"`
+	CPUUsage    float64   `json:"cpu_usage_percent"`
+	MemoryUsage int64     `json:"memory_usage_bytes"`
+}
+
+// SnapshotOf takes the history fields of m at time at
+func SnapshotOf(m *Metrics, at time.Time) MetricsSnapshot {
+	return MetricsSnapshot{
+		Time:        at,
+		BytesIn:     m.BytesIn,
+		BytesOut:    m.BytesOut,
+		PacketsIn:   m.PacketsIn,
+		PacketsOut:  m.PacketsOut,
+		Errors:      m.Errors,
+		Connections: m.Connections,
+		RTT:         m.RTT,
+		PacketLoss:  m.PacketLoss,
+		CPUUsage:    m.CPUUsage,
+		MemoryUsage: m.MemoryUsage,
+	}
+}
+
+// MetricsHistory keeps the most recent metrics snapshots in a fixed-size ring,
+// so its memory does not grow with uptime
+type MetricsHistory struct {
+	mu      sync.RWMutex
+	samples []MetricsSnapshot
+	next    int // Index the next snapshot is stored at
+	count   int // Snapshots stored, up to len(samples)
+
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewMetricsHistory creates a history of up to size snapshots
+func NewMetricsHistory(size int) *MetricsHistory {
+	if size < 1 {
+		size = 1
+	}
+	return &MetricsHistory{
+		samples: make([]MetricsSnapshot, size),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Add stores a snapshot, dropping the oldest when the history is full
+func (h *MetricsHistory) Add(snapshot MetricsSnapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples[h.next] = snapshot
+	h.next = (h.next + 1) % len(h.samples)
+	if h.count < len(h.samples) {
+		h.count++
+	}
+}
+
+// History returns the snapshots taken within window of now, oldest first. A
+// window of zero or less returns every snapshot kept.
+func (h *MetricsHistory) History(window time.Duration) []MetricsSnapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var since time.Time
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+
+	history := make([]MetricsSnapshot, 0, h.count)
+	oldest := (h.next - h.count + len(h.samples)) % len(h.samples)
+	for i := 0; i < h.count; i++ {
+		snapshot := h.samples[(oldest+i)%len(h.samples)]
+		if !snapshot.Time.Before(since) {
+			history = append(history, snapshot)
+		}
+	}
+	return history
+}
+
+// Start snapshots the metrics read from snapshot every interval
+func (h *MetricsHistory) Start(interval time.Duration, snapshot func() *Metrics) {
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-h.stopCh:
+				return
+			case now := <-ticker.C:
+				h.Add(SnapshotOf(snapshot(), now))
+			}
+		}
+	}()
+}
+
+// Stop stops taking snapshots; those taken are kept
+func (h *MetricsHistory) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+	h.wg.Wait()
+}
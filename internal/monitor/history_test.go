@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestMetricsHistoryEvictsOldest(t *testing.T) {
+	history := NewMetricsHistory(5)
+	now := time.Now()
+
+	// Eight snapshots a minute apart, the newest now
+	for i := 0; i < 8; i++ {
+		history.Add(MetricsSnapshot{Time: now.Add(time.Duration(i-7) * time.Minute), BytesIn: int64(i)})
+	}
+
+	all := history.History(0)
+	if len(all) != 5 {
+		t.Fatalf("Expected the 5 newest snapshots, got %d", len(all))
+	}
+	for i, snapshot := range all {
+		if snapshot.BytesIn != int64(i+3) {
+			t.Errorf("Snapshot %d: expected sample %d, got %d", i, i+3, snapshot.BytesIn)
+		}
+	}
+
+	// Only the snapshots within the window, oldest first
+	recent := history.History(150 * time.Second)
+	if len(recent) != 3 {
+		t.Fatalf("Expected 3 snapshots in the last 150s, got %d", len(recent))
+	}
+	if recent[0].BytesIn != 5 || recent[2].BytesIn != 7 {
+		t.Errorf("Expected samples 5 to 7, got %d to %d", recent[0].BytesIn, recent[2].BytesIn)
+	}
+
+	if len(history.samples) != 5 {
+		t.Errorf("Expected the ring to stay at 5 entries, got %d", len(history.samples))
+	}
+}
+
+func TestMetricsHistoryRecords(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.BytesIn = 42
+
+	history := NewMetricsHistory(3)
+	history.Start(5*time.Millisecond, func() *Metrics { return metrics.Clone() })
+	time.Sleep(50 * time.Millisecond)
+	history.Stop()
+
+	snapshots := history.History(0)
+	if len(snapshots) != 3 {
+		t.Fatalf("Expected the history to fill up to 3 snapshots, got %d", len(snapshots))
+	}
+	if snapshots[2].BytesIn != 42 {
+		t.Errorf("Expected 42 bytes in, got %d", snapshots[2].BytesIn)
+	}
+	if !snapshots[0].Time.Before(snapshots[2].Time) {
+		t.Error("Expected snapshots oldest first")
+	}
+}
+
+func TestHistoryConfigFrom(t *testing.T) {
+	if cfg := HistoryConfigFrom(types.MonitorConfig{Interval: time.Second}); cfg.Interval != 0 {
+		t.Errorf("Expected no history without a size, got %+v", cfg)
+	}
+	cfg := HistoryConfigFrom(types.MonitorConfig{Interval: 10 * time.Second, HistorySize: 360})
+	if cfg.Interval != 10*time.Second || cfg.Size != 360 {
+		t.Errorf("Expected 360 snapshots every 10s, got %+v", cfg)
+	}
+}
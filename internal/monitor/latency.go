@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// rttGain is the EWMA weight of a new RTT sample (1/8, as for TCP SRTT)
+	rttGain = 8
+	// jitterGain is the weight of a new jitter sample (1/16, as in RFC 3550)
+	jitterGain = 16
+)
+
+// PerformanceStats holds transport performance measured by a LatencyTracker
+type PerformanceStats struct {
+	RTT     time.Duration // Smoothed round-trip time
+	Jitter  time.Duration // Mean deviation between consecutive RTT samples
+	Samples uint64        // RTT samples observed
+}
+
+// LatencyTracker derives smoothed RTT and jitter from RTT samples. The tunnel
+// framing carries no sequence numbers, so packet loss and reordering are not
+// measured.
+type LatencyTracker struct {
+	mu         sync.Mutex
+	srtt       time.Duration
+	jitter     time.Duration
+	lastSample time.Duration
+	samples    uint64
+}
+
+// NewLatencyTracker creates an empty latency tracker
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{}
+}
+
+// AddRTTSample records one measured round trip
+func (t *LatencyTracker) AddRTTSample(rtt time.Duration) {
+	if rtt < 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.samples == 0 {
+		t.srtt = rtt
+	} else {
+		t.srtt += (rtt - t.srtt) / rttGain
+
+		d := rtt - t.lastSample
+		if d < 0 {
+			d = -d
+		}
+		t.jitter += (d - t.jitter) / jitterGain
+	}
+	t.lastSample = rtt
+	t.samples++
+}
+
+// Stats returns the current performance figures
+func (t *LatencyTracker) Stats() PerformanceStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return PerformanceStats{
+		RTT:     t.srtt,
+		Jitter:  t.jitter,
+		Samples: t.samples,
+	}
+}
+
+// Apply stores the tracked RTT and jitter in m, keeping its latency
+func (t *LatencyTracker) Apply(m *Metrics) {
+	stats := t.Stats()
+	atomic.StoreInt64(&m.Jitter, stats.Jitter.Microseconds())
+	atomic.StoreInt64(&m.RTT, stats.RTT.Microseconds())
+}
@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLatencyTrackerRTTAndJitter(t *testing.T) {
+	tracker := NewLatencyTracker()
+
+	// The first sample seeds the smoothed RTT with no jitter
+	tracker.AddRTTSample(100 * time.Millisecond)
+	stats := tracker.Stats()
+	if stats.RTT != 100*time.Millisecond || stats.Jitter != 0 {
+		t.Errorf("Expected RTT 100ms and no jitter, got %v and %v", stats.RTT, stats.Jitter)
+	}
+
+	// srtt += (180-100)/8 = 110ms; jitter += (80-0)/16 = 5ms
+	tracker.AddRTTSample(180 * time.Millisecond)
+	stats = tracker.Stats()
+	if stats.RTT != 110*time.Millisecond || stats.Jitter != 5*time.Millisecond {
+		t.Errorf("Expected RTT 110ms and jitter 5ms, got %v and %v", stats.RTT, stats.Jitter)
+	}
+
+	// A steady stream converges on its RTT and the jitter decays
+	for i := 0; i < 200; i++ {
+		tracker.AddRTTSample(50 * time.Millisecond)
+	}
+	stats = tracker.Stats()
+	if diff := stats.RTT - 50*time.Millisecond; diff < 0 || diff > time.Millisecond {
+		t.Errorf("Expected RTT to converge on 50ms, got %v", stats.RTT)
+	}
+	if stats.Jitter > 100*time.Microsecond {
+		t.Errorf("Expected jitter to decay for a steady stream, got %v", stats.Jitter)
+	}
+	if stats.Samples != 202 {
+		t.Errorf("Expected 202 samples, got %d", stats.Samples)
+	}
+
+	// Alternating samples settle at a jitter of their difference
+	for i := 0; i < 500; i++ {
+		tracker.AddRTTSample(time.Duration(40+20*(i%2)) * time.Millisecond)
+	}
+	if jitter := tracker.Stats().Jitter; math.Abs(float64(jitter-20*time.Millisecond)) > float64(time.Millisecond) {
+		t.Errorf("Expected jitter near 20ms, got %v", jitter)
+	}
+}
+
+func TestLatencyTrackerApply(t *testing.T) {
+	tracker := NewLatencyTracker()
+	tracker.AddRTTSample(2 * time.Millisecond)
+
+	metrics := NewMetrics()
+	metrics.UpdatePerformanceMetrics(300, 0, 0, 0, 0)
+	tracker.Apply(metrics)
+
+	if metrics.RTT != 2000 || metrics.Latency != 300 {
+		t.Errorf("Expected RTT 2000us and latency kept at 300us, got %d and %d", metrics.RTT, metrics.Latency)
+	}
+}
@@ -58,6 +58,21 @@ type Metrics struct {
 	SystemLoad float64
 	DiskIO     int64
 	NetworkIO  int64
+
+	// Error recovery metrics
+	Recovery RecoveryStats
+
+	// TLS handshake metrics
+	Handshake HandshakeStats
+
+	// Negotiated TLS parameter metrics
+	TLS TLSStats
+
+	// Rate limiting metrics
+	Throttle ThrottleStats
+
+	// Circuit breaker state, read when the metrics are snapshotted
+	CircuitBreakers []BreakerStats
 }
 
 // NewMetrics creates a new metrics instance
@@ -92,43 +107,53 @@ func (m *Metrics) Reset() {
 	atomic.StoreInt64(&m.Uptime, 0)
 	atomic.StoreInt64(&m.DiskIO, 0)
 	atomic.StoreInt64(&m.NetworkIO, 0)
+	m.Recovery = RecoveryStats{}
+	m.Handshake = HandshakeStats{}
+	m.TLS = TLSStats{}
+	m.Throttle = ThrottleStats{}
+	m.CircuitBreakers = nil
 	m.LastUpdate = time.Now()
 }
 
 // Clone creates a copy of the metrics
 func (m *Metrics) Clone() *Metrics {
 	return &Metrics{
-		BytesIn:        atomic.LoadInt64(&m.BytesIn),
-		BytesOut:       atomic.LoadInt64(&m.BytesOut),
-		PacketsIn:      atomic.LoadInt64(&m.PacketsIn),
-		PacketsOut:     atomic.LoadInt64(&m.PacketsOut),
-		ByteRate:       m.ByteRate,
-		PacketRate:     m.PacketRate,
-		Errors:         atomic.LoadInt64(&m.Errors),
-		LastError:      m.LastError,
-		ErrorRate:      m.ErrorRate,
-		RetryCount:     atomic.LoadInt64(&m.RetryCount),
-		DropCount:      atomic.LoadInt64(&m.DropCount),
-		Latency:        atomic.LoadInt64(&m.Latency),
-		Jitter:         atomic.LoadInt64(&m.Jitter),
-		RTT:            atomic.LoadInt64(&m.RTT),
-		PacketLoss:     m.PacketLoss,
-		ReorderingRate: m.ReorderingRate,
-		CPUUsage:       m.CPUUsage,
-		MemoryUsage:    atomic.LoadInt64(&m.MemoryUsage),
-		BufferSize:     atomic.LoadInt64(&m.BufferSize),
-		QueueLength:    atomic.LoadInt64(&m.QueueLength),
-		GoroutineNum:   atomic.LoadInt64(&m.GoroutineNum),
-		Connections:    atomic.LoadInt32(&m.Connections),
-		MaxConnections: atomic.LoadInt32(&m.MaxConnections),
-		ConnectTime:    atomic.LoadInt64(&m.ConnectTime),
-		DisconnectTime: atomic.LoadInt64(&m.DisconnectTime),
-		Uptime:         atomic.LoadInt64(&m.Uptime),
-		LastUpdate:     m.LastUpdate,
-		StartTime:      m.StartTime,
-		SystemLoad:     m.SystemLoad,
-		DiskIO:         atomic.LoadInt64(&m.DiskIO),
-		NetworkIO:      atomic.LoadInt64(&m.NetworkIO),
+		BytesIn:         atomic.LoadInt64(&m.BytesIn),
+		BytesOut:        atomic.LoadInt64(&m.BytesOut),
+		PacketsIn:       atomic.LoadInt64(&m.PacketsIn),
+		PacketsOut:      atomic.LoadInt64(&m.PacketsOut),
+		ByteRate:        m.ByteRate,
+		PacketRate:      m.PacketRate,
+		Errors:          atomic.LoadInt64(&m.Errors),
+		LastError:       m.LastError,
+		ErrorRate:       m.ErrorRate,
+		RetryCount:      atomic.LoadInt64(&m.RetryCount),
+		DropCount:       atomic.LoadInt64(&m.DropCount),
+		Latency:         atomic.LoadInt64(&m.Latency),
+		Jitter:          atomic.LoadInt64(&m.Jitter),
+		RTT:             atomic.LoadInt64(&m.RTT),
+		PacketLoss:      m.PacketLoss,
+		ReorderingRate:  m.ReorderingRate,
+		CPUUsage:        m.CPUUsage,
+		MemoryUsage:     atomic.LoadInt64(&m.MemoryUsage),
+		BufferSize:      atomic.LoadInt64(&m.BufferSize),
+		QueueLength:     atomic.LoadInt64(&m.QueueLength),
+		GoroutineNum:    atomic.LoadInt64(&m.GoroutineNum),
+		Connections:     atomic.LoadInt32(&m.Connections),
+		MaxConnections:  atomic.LoadInt32(&m.MaxConnections),
+		ConnectTime:     atomic.LoadInt64(&m.ConnectTime),
+		DisconnectTime:  atomic.LoadInt64(&m.DisconnectTime),
+		Uptime:          atomic.LoadInt64(&m.Uptime),
+		LastUpdate:      m.LastUpdate,
+		StartTime:       m.StartTime,
+		SystemLoad:      m.SystemLoad,
+		DiskIO:          atomic.LoadInt64(&m.DiskIO),
+		NetworkIO:       atomic.LoadInt64(&m.NetworkIO),
+		Recovery:        m.Recovery.Clone(),
+		Handshake:       m.Handshake,
+		TLS:             m.TLS.Clone(),
+		Throttle:        m.Throttle,
+		CircuitBreakers: append([]BreakerStats(nil), m.CircuitBreakers...),
 	}
 }
 
@@ -2,7 +2,9 @@ package monitor
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -27,8 +29,44 @@ const (
 	maxConnsOID = baseOID + ".3.1" // INTEGER: Maximum allowed connections
 	rateUpOID   = baseOID + ".3.2" // Gauge32: Upload rate limit (kbps)
 	rateDownOID = baseOID + ".3.3" // Gauge32: Download rate limit (kbps)
+
+	// Error Recovery (4.x)
+	recoveryTotalOID    = baseOID + ".4.1" // Counter64: Total recovery attempts
+	recoverySuccessOID  = baseOID + ".4.2" // Counter64: Successful recoveries
+	recoveryFailedOID   = baseOID + ".4.3" // Counter64: Failed recoveries
+	recoveryStrategyOID = baseOID + ".4.4" // Counter64 table: Recoveries per strategy
+	recoveryCategoryOID = baseOID + ".4.5" // Counter64 table: Recoveries per error category
+
+	// Throttling (5.x)
+	throttleEnabledOID = baseOID + ".5.1" // INTEGER: 0=disabled, 1=enabled
+	throttleRateOID    = baseOID + ".5.2" // Gauge32: Configured rate limit (bytes/sec)
+	throttleBurstOID   = baseOID + ".5.3" // Gauge32: Configured burst (bytes)
+	throttleDropsOID   = baseOID + ".5.4" // Counter64: Connections dropped by the rate limit
 )
 
+// recoveryStrategies lists the built-in recovery strategies in OID index order
+var recoveryStrategies = []string{
+	"backoff_retry",
+	"circuit_reset",
+	"resource_retry",
+	"network_retry",
+}
+
+// recoveryCategories lists the error categories in OID index order
+var recoveryCategories = []string{
+	"unknown",
+	"retryable",
+	"non_retryable",
+	"rate_limit",
+	"resource_exhaustion",
+	"network",
+	"timeout",
+	"circuit_open",
+	"validation",
+	"configuration",
+	"recoverable",
+}
+
 // SNMP community strings
 const (
 	defaultCommunity = "public"
@@ -51,6 +89,7 @@ var (
 	ErrInvalidCommunity = &MIBError{Code: 1, Message: "Invalid community string"}
 	ErrNoAccess         = &MIBError{Code: 2, Message: "No access to this OID"}
 	ErrWrongType        = &MIBError{Code: 3, Message: "Wrong value type"}
+	ErrEndOfMibView     = &MIBError{Code: 7, Message: "No next OID found"}
 )
 
 // MIBEntry represents a single entry in our MIB
@@ -65,6 +104,15 @@ type MIBEntry struct {
 	Validate     func() error // Custom validation function
 }
 
+// MIBEntryInfo describes a registered MIB entry, without its value
+type MIBEntryInfo struct {
+	OID         string `json:"oid"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Access      string `json:"access"`
+	Description string `json:"description"`
+}
+
 // MIBTree represents our complete MIB structure
 type MIBTree struct {
 	entries map[string]MIBEntry
@@ -79,9 +127,9 @@ func NewMIBTree(metrics *Metrics) *MIBTree {
 	// Performance Metrics
 	tree.addCounter64(bytesInOID, "bytesIn", "Total bytes received", metrics.BytesIn, "read-only")
 	tree.addCounter64(bytesOutOID, "bytesOut", "Total bytes sent", metrics.BytesOut, "read-only")
-	tree.addGauge32(activeConnsOID, "activeConnections", "Current active connections", metrics.Connections, "read-only")
-	tree.addGauge32(cpuUsageOID, "cpuUsage", "CPU usage percentage", int32(metrics.CPUUsage), "read-only")
-	tree.addGauge32(memoryUsageOID, "memoryUsage", "Memory usage in MB", int32(metrics.MemoryUsage/1024/1024), "read-only")
+	tree.addGauge32(activeConnsOID, "activeConnections", "Current active connections", clampGauge32(int64(metrics.Connections)), "read-only")
+	tree.addGauge32(cpuUsageOID, "cpuUsage", "CPU usage percentage", clampGauge32(int64(metrics.CPUUsage)), "read-only")
+	tree.addGauge32(memoryUsageOID, "memoryUsage", "Memory usage in MB", clampGauge32(metrics.MemoryUsage/1024/1024), "read-only")
 
 	// Status Metrics
 	tree.addInteger(tunnelStatusOID, "tunnelStatus", "Tunnel operational status", 1, "read-only")
@@ -93,9 +141,92 @@ func NewMIBTree(metrics *Metrics) *MIBTree {
 	tree.addGauge32(rateUpOID, "uploadRateLimit", "Upload rate limit in kbps", 10240, "read-write")
 	tree.addGauge32(rateDownOID, "downloadRateLimit", "Download rate limit in kbps", 10240, "read-write")
 
+	// Error Recovery
+	tree.addCounter64(recoveryTotalOID, "recoveriesTotal", "Total error recovery attempts", metrics.Recovery.TotalRecoveries, "read-only")
+	tree.addCounter64(recoverySuccessOID, "recoveriesSuccessful", "Successful error recoveries", metrics.Recovery.SuccessfulRecoveries, "read-only")
+	tree.addCounter64(recoveryFailedOID, "recoveriesFailed", "Failed error recoveries", metrics.Recovery.FailedRecoveries, "read-only")
+	for i, name := range recoveryStrategies {
+		tree.addCounter64(recoveryStrategyEntryOID(i), "recoveryStrategy."+name,
+			fmt.Sprintf("Recovery attempts using the %s strategy", name), metrics.Recovery.StrategyUsage[name], "read-only")
+	}
+	for i, name := range recoveryCategories {
+		tree.addCounter64(recoveryCategoryEntryOID(i), "recoveryCategory."+name,
+			fmt.Sprintf("Recovery attempts for %s errors", name), metrics.Recovery.CategoryUsage[name], "read-only")
+	}
+
+	// Throttling
+	tree.addInteger(throttleEnabledOID, "throttleEnabled", "Rate limiting enabled", throttleEnabledValue(metrics.Throttle), "read-only")
+	tree.addGauge32(throttleRateOID, "throttleRate", "Configured rate limit in bytes/sec", clampGauge32(int64(metrics.Throttle.Rate)), "read-only")
+	tree.addGauge32(throttleBurstOID, "throttleBurst", "Configured burst in bytes", clampGauge32(metrics.Throttle.Burst), "read-only")
+	tree.addCounter64(throttleDropsOID, "throttleDrops", "Connections dropped by the rate limit", metrics.Throttle.Drops, "read-only")
+
 	return tree
 }
 
+// recoveryStrategyEntryOID returns the OID of the per-strategy recovery counter at index i
+func recoveryStrategyEntryOID(i int) string {
+	return fmt.Sprintf("%s.%d", recoveryStrategyOID, i+1)
+}
+
+// recoveryCategoryEntryOID returns the OID of the per-category recovery counter at index i
+func recoveryCategoryEntryOID(i int) string {
+	return fmt.Sprintf("%s.%d", recoveryCategoryOID, i+1)
+}
+
+// updateRecoveryEntries refreshes the error recovery entries from the given metrics
+func (t *MIBTree) updateRecoveryEntries(entries map[string]MIBEntry, metrics *Metrics) {
+	set := func(oid string, value int64) {
+		if entry, ok := entries[oid]; ok {
+			entry.Value = value
+			entries[oid] = entry
+		}
+	}
+
+	set(recoveryTotalOID, metrics.Recovery.TotalRecoveries)
+	set(recoverySuccessOID, metrics.Recovery.SuccessfulRecoveries)
+	set(recoveryFailedOID, metrics.Recovery.FailedRecoveries)
+	for i, name := range recoveryStrategies {
+		set(recoveryStrategyEntryOID(i), metrics.Recovery.StrategyUsage[name])
+	}
+	for i, name := range recoveryCategories {
+		set(recoveryCategoryEntryOID(i), metrics.Recovery.CategoryUsage[name])
+	}
+}
+
+// updateThrottleEntries refreshes the throttling entries from the given metrics
+func (t *MIBTree) updateThrottleEntries(entries map[string]MIBEntry, metrics *Metrics) {
+	set := func(oid string, value interface{}) {
+		if entry, ok := entries[oid]; ok {
+			entry.Value = value
+			entries[oid] = entry
+		}
+	}
+
+	set(throttleEnabledOID, throttleEnabledValue(metrics.Throttle))
+	set(throttleRateOID, clampGauge32(int64(metrics.Throttle.Rate)))
+	set(throttleBurstOID, clampGauge32(metrics.Throttle.Burst))
+	set(throttleDropsOID, metrics.Throttle.Drops)
+}
+
+// throttleEnabledValue returns the throttleEnabled INTEGER for the given stats
+func throttleEnabledValue(stats ThrottleStats) int {
+	if stats.Enabled {
+		return 1
+	}
+	return 0
+}
+
+// clampGauge32 limits v to the unsigned 32-bit range of a Gauge32 entry
+func clampGauge32(v int64) uint32 {
+	switch {
+	case v < 0:
+		return 0
+	case v > math.MaxUint32:
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
+
 // Helper methods for adding metrics
 func (t *MIBTree) addCounter64(oid, name, desc string, value int64, access string) {
 	t.entries[oid] = MIBEntry{
@@ -120,7 +251,7 @@ func (t *MIBTree) addCounter64(oid, name, desc string, value int64, access strin
 	}
 }
 
-func (t *MIBTree) addGauge32(oid, name, desc string, value int32, access string) {
+func (t *MIBTree) addGauge32(oid, name, desc string, value uint32, access string) {
 	t.entries[oid] = MIBEntry{
 		OID:         oid,
 		Name:        name,
@@ -129,15 +260,12 @@ func (t *MIBTree) addGauge32(oid, name, desc string, value int32, access string)
 		Value:       value,
 		Access:      access,
 		ValueToInt64: func(v interface{}) int64 {
-			if val, ok := v.(int32); ok {
+			if val, ok := v.(uint32); ok {
 				return int64(val)
 			}
 			return 0
 		},
 		Validate: func() error {
-			if value < 0 {
-				return &MIBError{Code: 5, Message: "Gauge32 cannot be negative"}
-			}
 			return nil
 		},
 	}
@@ -192,11 +320,11 @@ func (t *MIBTree) UpdateMetrics(metrics *Metrics) {
 		case bytesOutOID:
 			newEntry.Value = metrics.BytesOut
 		case activeConnsOID:
-			newEntry.Value = metrics.Connections
+			newEntry.Value = clampGauge32(int64(metrics.Connections))
 		case cpuUsageOID:
-			newEntry.Value = int32(metrics.CPUUsage)
+			newEntry.Value = clampGauge32(int64(metrics.CPUUsage))
 		case memoryUsageOID:
-			newEntry.Value = int32(metrics.MemoryUsage / 1024 / 1024) // Convert to MB
+			newEntry.Value = clampGauge32(metrics.MemoryUsage / 1024 / 1024) // Convert to MB
 		case tunnelStatusOID:
 			// Connected if last connect time is after last disconnect time
 			if metrics.ConnectTime > metrics.DisconnectTime {
@@ -211,6 +339,8 @@ func (t *MIBTree) UpdateMetrics(metrics *Metrics) {
 		}
 		newEntries[oid] = newEntry
 	}
+	t.updateRecoveryEntries(newEntries, metrics)
+	t.updateThrottleEntries(newEntries, metrics)
 	t.entries = newEntries
 }
 
@@ -250,15 +380,66 @@ func (t *MIBTree) GetNextEntry(oid string, community string) (MIBEntry, error) {
 		}
 		oids = append(oids, k)
 	}
-	sort.Strings(oids)
+	sort.Slice(oids, func(i, j int) bool {
+		return compareOIDs(oids[i], oids[j]) < 0
+	})
 
 	// Find the next OID
-	for i, currentOID := range oids {
-		if currentOID > oid && i < len(oids) {
-			return t.entries[oids[i]], nil
+	for _, currentOID := range oids {
+		if compareOIDs(currentOID, oid) > 0 {
+			return t.entries[currentOID], nil
 		}
 	}
-	return MIBEntry{}, &MIBError{Code: 7, Message: "No next OID found"}
+	return MIBEntry{}, ErrEndOfMibView
+}
+
+// Dump describes every registered entry, in OID order
+func (t *MIBTree) Dump() []MIBEntryInfo {
+	entries := make([]MIBEntryInfo, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, MIBEntryInfo{
+			OID:         entry.OID,
+			Name:        entry.Name,
+			Type:        entry.Type,
+			Access:      entry.Access,
+			Description: entry.Description,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return compareOIDs(entries[i].OID, entries[j].OID) < 0
+	})
+	return entries
+}
+
+// compareOIDs compares two dotted OIDs numerically arc by arc, returning -1, 0 or 1
+func compareOIDs(a, b string) int {
+	arcsA := strings.Split(strings.Trim(a, "."), ".")
+	arcsB := strings.Split(strings.Trim(b, "."), ".")
+
+	for i := 0; i < len(arcsA) && i < len(arcsB); i++ {
+		numA, errA := strconv.ParseUint(arcsA[i], 10, 64)
+		numB, errB := strconv.ParseUint(arcsB[i], 10, 64)
+		if errA != nil || errB != nil {
+			if c := strings.Compare(arcsA[i], arcsB[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		switch {
+		case numA < numB:
+			return -1
+		case numA > numB:
+			return 1
+		}
+	}
+
+	switch {
+	case len(arcsA) < len(arcsB):
+		return -1
+	case len(arcsA) > len(arcsB):
+		return 1
+	}
+	return 0
 }
 
 // String returns a string representation of the MIB tree
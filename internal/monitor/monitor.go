@@ -2,22 +2,90 @@ package monitor
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"sync"
 	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 // Config holds monitoring configuration
 type Config struct {
-	LogFile       string
-	SNMPEnabled   bool
-	SNMPPort      int
-	SNMPCommunity string
-	SNMPAddress   string
+	LogFile            string
+	SNMPEnabled        bool
+	SNMPPort           int
+	SNMPCommunity      string
+	SNMPAddress        string
+	SNMPReportInterval time.Duration // How often SNMP agent statistics are logged
+	SNMPReportJitter   float64       // Fraction the report interval varies by; negative disables
+	SNMPRateLimit      float64       // Requests per second answered per source IP; zero disables
+	SNMPRateBurst      int           // Requests a source IP may send at once; defaults to the rate
+	SNMPAllowedCIDRs   []string      // Networks allowed to query the SNMP agent; empty allows all
+	SNMPWriteTimeout   time.Duration // Bounds sending an SNMP response; defaults to 2s
+	SNMPMetricsBuffer  int           // Request metrics updates queued for the writer; defaults to 256
+	PrometheusAddress  string        // Address the metrics are served on for scraping; empty serves none
+	PrometheusPath     string        // Path the metrics are served at; defaults to /metrics
+	Summary            SummaryConfig
+	RemoteWrite        RemoteWriteConfig
+	History            HistoryConfig
+	Alerts             AlertConfig
+}
+
+// ConfigFrom builds the monitor configuration from the application
+// configuration. The monitor logs where the application does, or to stderr.
+func ConfigFrom(cfg *types.AppConfig) (*Config, error) {
+	logFile := "stderr"
+	switch logging := cfg.Config.Logging; logging.Output {
+	case "stdout", "stderr":
+		logFile = logging.Output
+	case "", "file":
+		if logging.File != "" {
+			logFile = logging.File
+		}
+	}
+
+	alerts, err := AlertConfigFrom(cfg.Config.Monitor.Alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	var prometheusAddress string
+	if prometheus := cfg.Config.Monitor.Prometheus; cfg.Config.Monitor.Enabled && prometheus.Enabled {
+		prometheusAddress = fmt.Sprintf(":%d", prometheus.Port)
+	}
+
+	snmp := cfg.Config.SNMP
+	return &Config{
+		LogFile:            logFile,
+		SNMPEnabled:        snmp.Enabled,
+		SNMPPort:           snmp.Port,
+		SNMPCommunity:      snmp.Community,
+		SNMPAddress:        snmp.Address,
+		SNMPReportInterval: snmp.ReportInterval,
+		SNMPReportJitter:   snmp.ReportJitter,
+		SNMPAllowedCIDRs:   snmp.AllowedNetworks,
+		SNMPRateLimit:      snmp.RateLimit,
+		SNMPRateBurst:      snmp.RateBurst,
+		SNMPMetricsBuffer:  cfg.Config.Metrics.BufferSize,
+		PrometheusAddress:  prometheusAddress,
+		PrometheusPath:     cfg.Config.Monitor.Prometheus.Path,
+		Summary:            SummaryConfigFrom(cfg.Config.Monitor.Summary),
+		RemoteWrite:        RemoteWriteConfigFrom(cfg.Config.Monitor.Prometheus.RemoteWrite),
+		History:            HistoryConfigFrom(cfg.Config.Monitor),
+		Alerts:             alerts,
+	}, nil
+}
+
+// Enabled reports whether cfg asks for the monitor, for monitor.enabled or
+// the SNMP agent
+func Enabled(cfg *types.AppConfig) bool {
+	return cfg.Config.Monitor.Enabled || cfg.Config.SNMP.Enabled
 }
 
 // Monitor handles system monitoring and logging
@@ -27,6 +95,15 @@ type Monitor struct {
 	metrics    *Metrics
 	snmpAgent  *SNMPAgent
 	sysMetrics *SystemMetricsCollector
+	summary    *SummaryEmitter
+	remote     *RemoteWriter
+	scrape     *http.Server
+	history    *MetricsHistory
+	alerts     *AlertManager
+	talkers    func() []TalkerStats
+	pressure   func() memory.MemPressure
+	latency    *LatencyTracker
+	breakers   *resilience.CircuitBreakerStates
 	startTime  time.Time
 	mu         sync.RWMutex
 	shutdownCh chan struct{}
@@ -73,6 +150,26 @@ func (m *Monitor) Logger() *zap.Logger {
 	return m.logger
 }
 
+// SetConnectionSource sets the function that reports per-connection traffic for
+// the connection summary. It must be called before Start.
+func (m *Monitor) SetConnectionSource(source func() []TalkerStats) {
+	m.talkers = source
+}
+
+// SetLatencyTracker sets the tracker whose RTT and jitter are reported in the
+// performance metrics
+func (m *Monitor) SetLatencyTracker(tracker *LatencyTracker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency = tracker
+}
+
+// SetMemoryPressureSource sets the function that reports the memory pressure
+// checked by memory_pressure alert rules. It must be called before Start.
+func (m *Monitor) SetMemoryPressureSource(source func() memory.MemPressure) {
+	m.pressure = source
+}
+
 // Start initializes monitoring
 func (m *Monitor) Start() error {
 	if m.config.SNMPEnabled && m.snmpAgent != nil {
@@ -84,6 +181,12 @@ func (m *Monitor) Start() error {
 			zap.Int("port", m.config.SNMPPort))
 	}
 
+	if m.config.PrometheusAddress != "" {
+		if err := m.servePrometheus(); err != nil {
+			return fmt.Errorf("failed to serve Prometheus metrics: %w", err)
+		}
+	}
+
 	// Start certificate expiration monitor in test mode
 	if m.isTestMode {
 		m.shutdownWg.Add(1)
@@ -94,9 +197,49 @@ func (m *Monitor) Start() error {
 	m.shutdownWg.Add(1)
 	go m.collectSystemMetrics()
 
+	// Start periodic connection summary if configured
+	if m.config.Summary.Interval > 0 {
+		m.summary = NewSummaryEmitter(m.config.Summary, m.GetMetrics, m.talkers, m.logger)
+		m.summary.Start()
+	}
+
+	// Start pushing metrics to a remote-write endpoint if configured
+	if m.config.RemoteWrite.URL != "" && m.config.RemoteWrite.Interval > 0 {
+		m.remote = NewRemoteWriter(m.config.RemoteWrite, m.GetMetrics, m.logger)
+		m.remote.Start()
+		m.logger.Info("Prometheus remote write started",
+			zap.String("url", m.config.RemoteWrite.URL),
+			zap.Duration("interval", m.config.RemoteWrite.Interval))
+	}
+
+	// Keep a history of recent metrics if configured
+	if m.config.History.Interval > 0 {
+		m.history = NewMetricsHistory(m.config.History.Size)
+		m.history.Start(m.config.History.Interval, m.GetMetrics)
+	}
+
+	// Check alert thresholds if configured
+	if m.config.Alerts.Interval > 0 && len(m.config.Alerts.Rules) > 0 {
+		notifier := NewWebhookNotifier(m.config.Alerts.WebhookURL, m.config.Alerts.Timeout)
+		m.alerts = NewAlertManager(m.config.Alerts, m.GetMetrics, m.pressure, notifier, m.logger)
+		m.alerts.Start()
+		m.logger.Info("Alerting started",
+			zap.String("webhook", m.config.Alerts.WebhookURL),
+			zap.Int("rules", len(m.config.Alerts.Rules)))
+	}
+
 	return nil
 }
 
+// History returns the metrics snapshots taken within window, oldest first, or
+// nil if no history is kept. A window of zero or less returns all of them.
+func (m *Monitor) History(window time.Duration) []MetricsSnapshot {
+	if m.history == nil {
+		return nil
+	}
+	return m.history.History(window)
+}
+
 // Stop shuts down monitoring
 func (m *Monitor) Stop() {
 	select {
@@ -112,6 +255,26 @@ func (m *Monitor) Stop() {
 		m.logger.Info("SNMP monitoring stopped")
 	}
 
+	if m.scrape != nil {
+		m.stopPrometheus()
+	}
+
+	if m.summary != nil {
+		m.summary.Stop()
+	}
+
+	if m.remote != nil {
+		m.remote.Stop()
+	}
+
+	if m.history != nil {
+		m.history.Stop()
+	}
+
+	if m.alerts != nil {
+		m.alerts.Stop()
+	}
+
 	m.shutdownWg.Wait()
 
 	// Close and sync logger
@@ -148,8 +311,14 @@ func (m *Monitor) UpdateMetrics(bytesIn, bytesOut, packetsIn, packetsOut, errors
 // GetMetrics returns current metrics
 func (m *Monitor) GetMetrics() *Metrics {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.metrics.Clone()
+	metrics := m.metrics.Clone()
+	breakers := m.breakers
+	m.mu.RUnlock()
+
+	if breakers != nil {
+		metrics.CircuitBreakers = CollectBreakerStats(breakers)
+	}
+	return metrics
 }
 
 // monitorCertExpiration monitors certificate expiration in test mode
@@ -202,6 +371,11 @@ func (m *Monitor) collectSystemMetrics() {
 			if err := m.sysMetrics.CollectMetrics(m.metrics); err != nil {
 				m.logger.Error("Failed to collect system metrics", zap.Error(err))
 			}
+
+			// Update transport performance metrics
+			if m.latency != nil {
+				m.latency.Apply(m.metrics)
+			}
 			m.mu.Unlock()
 		}
 	}
@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+)
+
+func TestConfigFrom(t *testing.T) {
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Logging.File = "/var/log/sssonector/sssonector.log"
+	cfg.Config.SNMP = types.SNMPConfig{
+		Enabled:         true,
+		Port:            1161,
+		Community:       "monitor",
+		Address:         "127.0.0.1",
+		ReportInterval:  5 * time.Minute,
+		ReportJitter:    -1,
+		AllowedNetworks: []string{"10.20.0.0/16"},
+		RateLimit:       5,
+		RateBurst:       10,
+	}
+	cfg.Config.Metrics.BufferSize = 1024
+	cfg.Config.Monitor = types.MonitorConfig{
+		Interval:    10 * time.Second,
+		HistorySize: 360,
+		Summary:     types.SummaryConfig{Enabled: true, Interval: 5 * time.Minute, TopTalkers: 3},
+		Prometheus: types.PrometheusConfig{
+			RemoteWrite: types.RemoteWriteConfig{
+				Enabled:  true,
+				URL:      "https://prometheus.example.com/api/v1/write",
+				Interval: 15 * time.Second,
+			},
+		},
+		Alerts: types.AlertsConfig{
+			Enabled:    true,
+			Interval:   30 * time.Second,
+			WebhookURL: "https://alerts.example.com/hook",
+			Rules:      []types.AlertRuleConfig{{Name: "pressure", Metric: "memory_pressure", Level: "high"}},
+		},
+	}
+
+	monitorCfg, err := ConfigFrom(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build the monitor configuration: %v", err)
+	}
+	if monitorCfg.LogFile != cfg.Config.Logging.File {
+		t.Errorf("Expected log file %s, got %s", cfg.Config.Logging.File, monitorCfg.LogFile)
+	}
+	if !monitorCfg.SNMPEnabled || monitorCfg.SNMPPort != 1161 || monitorCfg.SNMPCommunity != "monitor" || monitorCfg.SNMPAddress != "127.0.0.1" {
+		t.Errorf("Expected the SNMP agent settings to be copied, got %+v", monitorCfg)
+	}
+	if monitorCfg.SNMPReportInterval != 5*time.Minute || monitorCfg.SNMPReportJitter != -1 {
+		t.Errorf("Expected report interval 5m and jitter -1, got %v and %v", monitorCfg.SNMPReportInterval, monitorCfg.SNMPReportJitter)
+	}
+
+	if len(monitorCfg.SNMPAllowedCIDRs) != 1 || monitorCfg.SNMPAllowedCIDRs[0] != "10.20.0.0/16" {
+		t.Errorf("Expected allowed networks [10.20.0.0/16], got %v", monitorCfg.SNMPAllowedCIDRs)
+	}
+	if monitorCfg.SNMPRateLimit != 5 || monitorCfg.SNMPRateBurst != 10 {
+		t.Errorf("Expected rate limit 5 and burst 10, got %v and %d", monitorCfg.SNMPRateLimit, monitorCfg.SNMPRateBurst)
+	}
+
+	if monitorCfg.SNMPMetricsBuffer != 1024 {
+		t.Errorf("Expected a metrics buffer of 1024, got %d", monitorCfg.SNMPMetricsBuffer)
+	}
+
+	if monitorCfg.History.Interval != 10*time.Second || monitorCfg.History.Size != 360 {
+		t.Errorf("Expected a history of 360 snapshots every 10s, got %+v", monitorCfg.History)
+	}
+
+	if monitorCfg.Summary.Interval != 5*time.Minute || monitorCfg.Summary.TopTalkers != 3 {
+		t.Errorf("Expected a summary of the top 3 talkers every 5m, got %+v", monitorCfg.Summary)
+	}
+	if monitorCfg.RemoteWrite.URL != "https://prometheus.example.com/api/v1/write" || monitorCfg.RemoteWrite.Interval != 15*time.Second {
+		t.Errorf("Expected the remote write settings to be copied, got %+v", monitorCfg.RemoteWrite)
+	}
+	if monitorCfg.Alerts.Interval != 30*time.Second || len(monitorCfg.Alerts.Rules) != 1 || monitorCfg.Alerts.Rules[0].Threshold != float64(memory.MemPressureHigh) {
+		t.Errorf("Expected the alert rules to be copied, got %+v", monitorCfg.Alerts)
+	}
+
+	cfg.Config.Logging.Output = "stdout"
+	if monitorCfg, _ := ConfigFrom(cfg); monitorCfg.LogFile != "stdout" {
+		t.Errorf("Expected the monitor to log to stdout, got %s", monitorCfg.LogFile)
+	}
+}
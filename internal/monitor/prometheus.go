@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// prometheusShutdownTimeout bounds waiting for in-flight scrapes at Stop
+const prometheusShutdownTimeout = 5 * time.Second
+
+// WritePrometheus writes the traffic, connection, recovery, TLS and circuit
+// breaker metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	lines := []string{
+		"# HELP sssonector_bytes_in_total Bytes received",
+		"# TYPE sssonector_bytes_in_total counter",
+		fmt.Sprintf("sssonector_bytes_in_total %d", m.BytesIn),
+		"# HELP sssonector_bytes_out_total Bytes sent",
+		"# TYPE sssonector_bytes_out_total counter",
+		fmt.Sprintf("sssonector_bytes_out_total %d", m.BytesOut),
+		"# HELP sssonector_packets_in_total Packets received",
+		"# TYPE sssonector_packets_in_total counter",
+		fmt.Sprintf("sssonector_packets_in_total %d", m.PacketsIn),
+		"# HELP sssonector_packets_out_total Packets sent",
+		"# TYPE sssonector_packets_out_total counter",
+		fmt.Sprintf("sssonector_packets_out_total %d", m.PacketsOut),
+		"# HELP sssonector_errors_total Errors",
+		"# TYPE sssonector_errors_total counter",
+		fmt.Sprintf("sssonector_errors_total %d", m.Errors),
+		"# HELP sssonector_connections Active connections",
+		"# TYPE sssonector_connections gauge",
+		fmt.Sprintf("sssonector_connections %d", m.Connections),
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Recovery.WritePrometheus(w); err != nil {
+		return err
+	}
+	if err := m.Handshake.WritePrometheus(w); err != nil {
+		return err
+	}
+	if err := m.TLS.WritePrometheus(w); err != nil {
+		return err
+	}
+	return writeBreakersPrometheus(w, m.CircuitBreakers)
+}
+
+// servePrometheus serves the metrics for scraping on the configured address
+// and path until stopPrometheus
+func (m *Monitor) servePrometheus() error {
+	path := m.config.PrometheusPath
+	if path == "" {
+		path = "/metrics"
+	}
+
+	listener, err := net.Listen("tcp", m.config.PrometheusAddress)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, m.handlePrometheus)
+	m.scrape = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := m.scrape.Serve(listener); err != nil && err != http.ErrServerClosed {
+			m.logger.Error("Prometheus metrics server failed", zap.Error(err))
+		}
+	}()
+	m.logger.Info("Serving Prometheus metrics",
+		zap.String("address", listener.Addr().String()),
+		zap.String("path", path))
+	return nil
+}
+
+// stopPrometheus stops serving the metrics, waiting briefly for scrapes in flight
+func (m *Monitor) stopPrometheus() {
+	ctx, cancel := context.WithTimeout(context.Background(), prometheusShutdownTimeout)
+	defer cancel()
+
+	if err := m.scrape.Shutdown(ctx); err != nil {
+		m.logger.Warn("Failed to stop Prometheus metrics server", zap.Error(err))
+	}
+}
+
+// handlePrometheus answers a scrape with the current metrics
+func (m *Monitor) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	var body bytes.Buffer
+	if err := m.GetMetrics().WritePrometheus(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// RecoveryStats holds error recovery counters reported by the resilience layer
+type RecoveryStats struct {
+	TotalRecoveries      int64
+	SuccessfulRecoveries int64
+	FailedRecoveries     int64
+	AverageRecoveryTime  time.Duration
+	StrategyUsage        map[string]int64 // recoveries per strategy name
+	CategoryUsage        map[string]int64 // recoveries per error category name
+}
+
+// Clone creates a deep copy of the recovery stats
+func (s RecoveryStats) Clone() RecoveryStats {
+	clone := s
+	clone.StrategyUsage = copyCounts(s.StrategyUsage)
+	clone.CategoryUsage = copyCounts(s.CategoryUsage)
+	return clone
+}
+
+// copyCounts copies a map of named counters
+func copyCounts(src map[string]int64) map[string]int64 {
+	dst := make(map[string]int64, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// UpdateRecoveryMetrics records a snapshot of error recovery statistics
+func (m *Monitor) UpdateRecoveryMetrics(stats RecoveryStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.Recovery = stats.Clone()
+}
+
+// WritePrometheus writes recovery statistics in the Prometheus text exposition format
+func (s RecoveryStats) WritePrometheus(w io.Writer) error {
+	lines := []string{
+		"# HELP sssonector_recoveries_total Total error recovery attempts",
+		"# TYPE sssonector_recoveries_total counter",
+		fmt.Sprintf("sssonector_recoveries_total %d", s.TotalRecoveries),
+		"# HELP sssonector_recoveries_successful_total Successful error recoveries",
+		"# TYPE sssonector_recoveries_successful_total counter",
+		fmt.Sprintf("sssonector_recoveries_successful_total %d", s.SuccessfulRecoveries),
+		"# HELP sssonector_recoveries_failed_total Failed error recoveries",
+		"# TYPE sssonector_recoveries_failed_total counter",
+		fmt.Sprintf("sssonector_recoveries_failed_total %d", s.FailedRecoveries),
+		"# HELP sssonector_recovery_duration_seconds_avg Average recovery duration",
+		"# TYPE sssonector_recovery_duration_seconds_avg gauge",
+		fmt.Sprintf("sssonector_recovery_duration_seconds_avg %g", s.AverageRecoveryTime.Seconds()),
+		"# HELP sssonector_recovery_strategy_total Recovery attempts per strategy",
+		"# TYPE sssonector_recovery_strategy_total counter",
+	}
+	for _, name := range sortedKeys(s.StrategyUsage) {
+		lines = append(lines, fmt.Sprintf("sssonector_recovery_strategy_total{strategy=%q} %d", name, s.StrategyUsage[name]))
+	}
+
+	lines = append(lines,
+		"# HELP sssonector_recovery_category_total Recovery attempts per error category",
+		"# TYPE sssonector_recovery_category_total counter",
+	)
+	for _, name := range sortedKeys(s.CategoryUsage) {
+		lines = append(lines, fmt.Sprintf("sssonector_recovery_category_total{category=%q} %d", name, s.CategoryUsage[name]))
+	}
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a counter map in sorted order
+func sortedKeys(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
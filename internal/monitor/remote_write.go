@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+// DefaultRemoteWriteTimeout bounds a push when no timeout is configured
+const DefaultRemoteWriteTimeout = 10 * time.Second
+
+// RemoteWriteConfig controls pushing metrics to a Prometheus remote-write endpoint
+type RemoteWriteConfig struct {
+	URL      string // Empty disables remote write
+	Interval time.Duration
+	Timeout  time.Duration
+	Username string
+	Password string
+}
+
+// RemoteWriteConfigFrom converts the configured remote-write settings. A disabled
+// remote write has an empty URL.
+func RemoteWriteConfigFrom(cfg types.RemoteWriteConfig) RemoteWriteConfig {
+	if !cfg.Enabled {
+		return RemoteWriteConfig{}
+	}
+	return RemoteWriteConfig{
+		URL:      cfg.URL,
+		Interval: cfg.Interval,
+		Timeout:  cfg.Timeout,
+		Username: cfg.Username,
+		Password: cfg.Password,
+	}
+}
+
+// RemoteWriter periodically pushes the metrics exposed for scraping to a
+// Prometheus remote-write endpoint
+type RemoteWriter struct {
+	config   RemoteWriteConfig
+	snapshot func() *Metrics
+	client   *http.Client
+	logger   *zap.Logger
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewRemoteWriter creates a writer that pushes the metrics read from snapshot
+func NewRemoteWriter(cfg RemoteWriteConfig, snapshot func() *Metrics, logger *zap.Logger) *RemoteWriter {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRemoteWriteTimeout
+	}
+	return &RemoteWriter{
+		config:   cfg,
+		snapshot: snapshot,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		logger:   logger,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins pushing at the configured interval
+func (w *RemoteWriter) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+				if err := w.Push(context.Background()); err != nil {
+					w.logger.Warn("Failed to push metrics", zap.String("url", w.config.URL), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops pushing
+func (w *RemoteWriter) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+	w.wg.Wait()
+}
+
+// Push sends a single snapshot of the metrics
+func (w *RemoteWriter) Push(ctx context.Context) error {
+	var text bytes.Buffer
+	if err := w.snapshot().WritePrometheus(&text); err != nil {
+		return fmt.Errorf("failed to render metrics: %w", err)
+	}
+	series, err := parseExposition(&text, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	body := snappyEncode(encodeWriteRequest(series))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "sssonector")
+	if w.config.Username != "" {
+		req.SetBasicAuth(w.config.Username, w.config.Password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// label is a metric label
+type label struct {
+	name, value string
+}
+
+// timeSeries is one sample of a metric with its labels
+type timeSeries struct {
+	labels    []label // Sorted by name, including __name__
+	value     float64
+	timestamp int64 // Milliseconds since the epoch
+}
+
+// parseExposition reads samples from the text exposition format, stamping each
+// with now. Comment lines are skipped.
+func parseExposition(r io.Reader, now time.Time) ([]timeSeries, error) {
+	var series []timeSeries
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ts, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %q", err, line)
+		}
+		ts.timestamp = now.UnixMilli()
+		series = append(series, ts)
+	}
+	return series, scanner.Err()
+}
+
+// parseSample parses a line of the form name{label="value",...} value
+func parseSample(line string) (timeSeries, error) {
+	var ts timeSeries
+
+	end := strings.IndexAny(line, "{ ")
+	if end <= 0 {
+		return ts, fmt.Errorf("missing metric name")
+	}
+	ts.labels = append(ts.labels, label{name: "__name__", value: line[:end]})
+	rest := line[end:]
+
+	if strings.HasPrefix(rest, "{") {
+		rest = rest[1:]
+		for !strings.HasPrefix(rest, "}") {
+			eq := strings.IndexByte(rest, '=')
+			if eq <= 0 {
+				return ts, fmt.Errorf("malformed label")
+			}
+			name := strings.TrimSpace(rest[:eq])
+			quoted, err := strconv.QuotedPrefix(rest[eq+1:])
+			if err != nil {
+				return ts, fmt.Errorf("malformed label value: %w", err)
+			}
+			value, _ := strconv.Unquote(quoted)
+			ts.labels = append(ts.labels, label{name: name, value: value})
+
+			rest = strings.TrimPrefix(rest[eq+1+len(quoted):], ",")
+			if rest == "" {
+				return ts, fmt.Errorf("unterminated labels")
+			}
+		}
+		rest = rest[1:]
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+	if err != nil {
+		return ts, fmt.Errorf("malformed value: %w", err)
+	}
+	ts.value = value
+
+	sort.Slice(ts.labels, func(i, j int) bool { return ts.labels[i].name < ts.labels[j].name })
+	return ts, nil
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Protobuf wire types used by the remote-write messages
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// maxSnappyLiteral is the longest literal emitted in one snappy element
+const maxSnappyLiteral = 1 << 16
+
+// encodeWriteRequest encodes series as a prometheus.WriteRequest message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []timeSeries) []byte {
+	var req []byte
+	for _, ts := range series {
+		var msg []byte
+		for _, l := range ts.labels {
+			var lb []byte
+			lb = appendBytesField(lb, 1, []byte(l.name))
+			lb = appendBytesField(lb, 2, []byte(l.value))
+			msg = appendBytesField(msg, 1, lb)
+		}
+
+		var sample []byte
+		sample = appendTag(sample, 1, wireFixed64)
+		sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(ts.value))
+		sample = appendTag(sample, 2, wireVarint)
+		sample = binary.AppendUvarint(sample, uint64(ts.timestamp))
+		msg = appendBytesField(msg, 2, sample)
+
+		req = appendBytesField(req, 1, msg)
+	}
+	return req
+}
+
+// appendTag appends a protobuf field key
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+// appendBytesField appends a length-delimited protobuf field
+func appendBytesField(b []byte, field int, data []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// snappyEncode encodes src in the snappy block format using literal elements
+// only. The result is larger than a compressed block but is decoded by any
+// snappy implementation, and metric pushes are small.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > maxSnappyLiteral {
+			n = maxSnappyLiteral
+		}
+
+		switch l := n - 1; {
+		case l < 60:
+			dst = append(dst, byte(l)<<2)
+		case l < 1<<8:
+			dst = append(dst, 60<<2, byte(l))
+		default:
+			dst = append(dst, 61<<2, byte(l), byte(l>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
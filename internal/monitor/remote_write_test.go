@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// decodeSnappyLiterals decodes a snappy block made only of literal elements
+func decodeSnappyLiterals(t *testing.T, src []byte) []byte {
+	t.Helper()
+	n, k := binary.Uvarint(src)
+	if k <= 0 {
+		t.Fatal("Malformed snappy length")
+	}
+	src = src[k:]
+
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		src = src[1:]
+		if tag&3 != 0 {
+			t.Fatalf("Expected a literal element, got tag %#x", tag)
+		}
+		l := int(tag >> 2)
+		switch l {
+		case 60:
+			l, src = int(src[0]), src[1:]
+		case 61:
+			l, src = int(binary.LittleEndian.Uint16(src)), src[2:]
+		}
+		dst = append(dst, src[:l+1]...)
+		src = src[l+1:]
+	}
+	if uint64(len(dst)) != n {
+		t.Fatalf("Expected %d decoded bytes, got %d", n, len(dst))
+	}
+	return dst
+}
+
+// protoFields splits a protobuf message into its length-delimited, varint and
+// fixed64 fields keyed by field number
+func protoFields(t *testing.T, b []byte) map[int][][]byte {
+	t.Helper()
+	fields := make(map[int][][]byte)
+	for len(b) > 0 {
+		key, k := binary.Uvarint(b)
+		if k <= 0 {
+			t.Fatal("Malformed field key")
+		}
+		b = b[k:]
+		field := int(key >> 3)
+		switch key & 7 {
+		case wireBytes:
+			l, k := binary.Uvarint(b)
+			if k <= 0 || int(l) > len(b[k:]) {
+				t.Fatal("Malformed field length")
+			}
+			fields[field] = append(fields[field], b[k:k+int(l)])
+			b = b[k+int(l):]
+		case wireVarint:
+			_, k := binary.Uvarint(b)
+			fields[field] = append(fields[field], b[:k])
+			b = b[k:]
+		case wireFixed64:
+			fields[field] = append(fields[field], b[:8])
+			b = b[8:]
+		default:
+			t.Fatalf("Unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func TestRemoteWriterPushes(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "prom" || pass != "secret" {
+			t.Errorf("Expected basic auth prom/secret, got %q/%q", user, pass)
+		}
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("Unexpected headers: %v", r.Header)
+		}
+		if r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+			t.Errorf("Expected remote write version 0.1.0, got %q", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics()
+	metrics.Handshake = HandshakeStats{Count: 7}
+	metrics.Recovery = RecoveryStats{StrategyUsage: map[string]int64{"network_retry": 3}}
+
+	writer := NewRemoteWriter(RemoteWriteConfig{
+		URL:      server.URL,
+		Interval: 20 * time.Millisecond,
+		Username: "prom",
+		Password: "secret",
+	}, func() *Metrics { return metrics.Clone() }, zap.NewNop())
+	writer.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(bodies)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	writer.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) < 2 {
+		t.Fatalf("Expected a push on each interval, got %d pushes", len(bodies))
+	}
+
+	// Index the samples of the first push by metric name and labels
+	samples := make(map[string]float64)
+	for _, series := range protoFields(t, decodeSnappyLiterals(t, bodies[0]))[1] {
+		fields := protoFields(t, series)
+		key := ""
+		for _, l := range fields[1] {
+			lf := protoFields(t, l)
+			name, value := string(lf[1][0]), string(lf[2][0])
+			if name == "__name__" {
+				key = value + key
+			} else {
+				key += "{" + name + "=" + value + "}"
+			}
+		}
+		if len(fields[2]) != 1 {
+			t.Fatalf("Expected one sample for %s, got %d", key, len(fields[2]))
+		}
+		sample := protoFields(t, fields[2][0])
+		if ts, _ := binary.Uvarint(sample[2][0]); ts == 0 {
+			t.Errorf("Expected a timestamp on %s", key)
+		}
+		samples[key] = math.Float64frombits(binary.LittleEndian.Uint64(sample[1][0]))
+	}
+
+	if v, ok := samples["sssonector_tls_handshakes_total"]; !ok || v != 7 {
+		t.Errorf("Expected 7 handshakes, got %v (present %v)", v, ok)
+	}
+	if v := samples["sssonector_recovery_strategy_total{strategy=network_retry}"]; v != 3 {
+		t.Errorf("Expected 3 network_retry recoveries, got %v", v)
+	}
+	if v, ok := samples[`sssonector_tls_handshake_duration_seconds{stat=p95}`]; !ok || v != 0 {
+		t.Errorf("Expected a p95 handshake duration sample, got %v (present %v)", v, ok)
+	}
+}
+
+func TestRemoteWriterPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	writer := NewRemoteWriter(RemoteWriteConfig{URL: server.URL, Interval: time.Minute},
+		func() *Metrics { return NewMetrics() }, zap.NewNop())
+	if err := writer.Push(context.Background()); err == nil {
+		t.Error("Expected an error for a rejected push")
+	}
+}
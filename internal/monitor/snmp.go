@@ -1,40 +1,119 @@
 package monitor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"net"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
 	"github.com/gosnmp/gosnmp"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
 	"go.uber.org/zap"
 )
 
+// Defaults for the SNMP statistics report when none is configured
+const (
+	DefaultSNMPReportInterval = 60 * time.Second
+	DefaultSNMPReportJitter   = 0.1
+)
+
+// DefaultSNMPRequestTimeout bounds processing a single SNMP request
+const DefaultSNMPRequestTimeout = 5 * time.Second
+
+// DefaultSNMPWriteTimeout bounds sending a single SNMP response
+const DefaultSNMPWriteTimeout = 2 * time.Second
+
+// DefaultSNMPMetricsBuffer is the number of metrics updates from processed
+// requests queued for the metrics writer when no buffer size is configured
+const DefaultSNMPMetricsBuffer = 256
+
+const (
+	// snmpRequestHandlers is the number of goroutines reading requests
+	snmpRequestHandlers = 4
+
+	// MaxSNMPInFlightRequests bounds the requests processed at once; requests
+	// received while the limit is reached are dropped unanswered
+	MaxSNMPInFlightRequests = 64
+)
+
+// snmpConn is the agent's UDP socket
+type snmpConn interface {
+	ReadFromUDP(b []byte) (int, *net.UDPAddr, error)
+	WriteToUDP(b []byte, addr *net.UDPAddr) (int, error)
+	SetWriteDeadline(t time.Time) error
+	LocalAddr() net.Addr
+	Close() error
+}
+
 // SNMPAgent handles SNMP monitoring
 type SNMPAgent struct {
 	config      *Config
 	metrics     *Metrics
 	mibTree     *MIBTree
-	conn        *net.UDPConn
+	conn        snmpConn
 	startTime   time.Time
 	mu          sync.RWMutex
 	logger      *zap.Logger
 	requestPool sync.Pool
 	stats       *SNMPStats
+	rand        *rand.Rand // Jitters the report interval; used by reportMetrics only
+
+	// Requests from outside allowed, or over the rate limit of their source,
+	// are dropped unanswered so the agent cannot be used as a reflector
+	allowed []*net.IPNet
+	limiter *throttle.ConnectionLimiter
+
+	// Request processing is bounded by requestTimeout and cancelled on Stop
+	requestTimeout time.Duration
+	process        func(ctx context.Context, request *SNMPMessage, remoteAddr *net.UDPAddr)
+	listen         func(addr *net.UDPAddr) (snmpConn, error)
+	writeTimeout   time.Duration
+	writeMu        sync.Mutex // Pairs each response with its own write deadline
+	ctx            context.Context
+	cancel         context.CancelFunc
+
+	// Goroutines started by Start, including in-flight requests, which Stop
+	// waits for
+	lifecycle sync.Mutex // Serializes Start and Stop
+	running   bool
+	wg        sync.WaitGroup
+	inflight  chan struct{} // Holds a slot per request being processed
+
+	// Processed requests queue their metrics for a single writer rather than
+	// taking mu on the request path
+	updates chan requestSample
+}
+
+// requestSample is the metrics update of one processed request
+type requestSample struct {
+	latency   time.Duration
+	variables int64
 }
 
 // SNMPStats tracks SNMP agent statistics
 type SNMPStats struct {
-	totalRequests      uint64
-	invalidRequests    uint64
-	authErrors         uint64
-	successfulRequests uint64
-	lastError          string
-	lastErrorTime      time.Time
-	mu                 sync.RWMutex
+	totalRequests       uint64
+	invalidRequests     uint64
+	authErrors          uint64
+	successfulRequests  uint64
+	timedOutRequests    uint64 // Requests abandoned after the request timeout
+	filteredRequests    uint64 // Requests dropped from sources outside the allowed networks
+	droppedRequests     uint64 // Requests dropped while MaxSNMPInFlightRequests were processed
+	rateLimitedRequests uint64 // Requests dropped over the per-source rate limit
+	droppedUpdates      uint64 // Metrics updates dropped while the update queue was full
+	lastError           string
+	lastErrorTime       time.Time
+	mu                  sync.RWMutex
 }
 
 // NewSNMPAgent creates a new SNMP agent
@@ -45,65 +124,179 @@ func NewSNMPAgent(cfg *Config, metrics *Metrics, logger *zap.Logger) (*SNMPAgent
 		startTime: time.Now(),
 		logger:    logger,
 		stats:     &SNMPStats{},
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
 		requestPool: sync.Pool{
 			New: func() interface{} {
 				return make([]byte, 4096) // Increased buffer size for large packets
 			},
 		},
 	}
+	for _, cidr := range cfg.SNMPAllowedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNMP allowed network: %w", err)
+		}
+		agent.allowed = append(agent.allowed, network)
+	}
+	if cfg.SNMPRateLimit > 0 {
+		burst := cfg.SNMPRateBurst
+		if burst <= 0 {
+			burst = int(math.Ceil(cfg.SNMPRateLimit))
+		}
+		agent.limiter = throttle.NewConnectionLimiter(types.ConnectionThrottleConfig{
+			Enabled: true,
+			Rate:    cfg.SNMPRateLimit,
+			Burst:   burst,
+		})
+	}
 	agent.mibTree = NewMIBTree(metrics)
+	agent.requestTimeout = DefaultSNMPRequestTimeout
+	agent.process = agent.processRequest
+	agent.listen = agent.listenUDP
+	agent.writeTimeout = cfg.SNMPWriteTimeout
+	if agent.writeTimeout <= 0 {
+		agent.writeTimeout = DefaultSNMPWriteTimeout
+	}
+	agent.ctx, agent.cancel = context.WithCancel(context.Background())
+	agent.inflight = make(chan struct{}, MaxSNMPInFlightRequests)
+	bufferSize := cfg.SNMPMetricsBuffer
+	if bufferSize <= 0 {
+		bufferSize = DefaultSNMPMetricsBuffer
+	}
+	agent.updates = make(chan requestSample, bufferSize)
 	return agent, nil
 }
 
-// Start initializes the SNMP agent
+// Start initializes the SNMP agent. A stopped agent may be started again.
 func (a *SNMPAgent) Start() error {
+	a.lifecycle.Lock()
+	defer a.lifecycle.Unlock()
+
+	if a.running {
+		return fmt.Errorf("SNMP agent already started")
+	}
+
 	addr := &net.UDPAddr{
 		IP:   net.ParseIP(a.config.SNMPAddress),
 		Port: a.config.SNMPPort,
 	}
 
 	var err error
-	a.conn, err = net.ListenUDP("udp", addr)
+	a.conn, err = a.listen(addr)
 	if err != nil {
 		return fmt.Errorf("failed to start SNMP listener: %w", err)
 	}
 
-	// Set socket buffer sizes
-	if err := a.conn.SetReadBuffer(262144); err != nil { // 256KB read buffer
-		a.logger.Error("Failed to set UDP read buffer size", zap.Error(err))
-	}
-	if err := a.conn.SetWriteBuffer(262144); err != nil { // 256KB write buffer
-		a.logger.Error("Failed to set UDP write buffer size", zap.Error(err))
-	}
-
 	a.logger.Info("SNMP agent started",
 		zap.String("address", a.config.SNMPAddress),
 		zap.Int("port", a.config.SNMPPort),
 		zap.String("community", a.config.SNMPCommunity))
 
+	// A previous Stop cancelled the context
+	if a.ctx.Err() != nil {
+		a.ctx, a.cancel = context.WithCancel(context.Background())
+	}
+	a.running = true
+
 	// Start request handlers
-	for i := 0; i < 4; i++ { // Multiple handlers for concurrent processing
-		go a.handleRequests()
+	a.wg.Add(snmpRequestHandlers + 2)
+	for i := 0; i < snmpRequestHandlers; i++ { // Multiple handlers for concurrent processing
+		go func() {
+			defer a.wg.Done()
+			a.handleRequests()
+		}()
 	}
 
 	// Start metrics reporting
-	go a.reportMetrics()
+	go func() {
+		defer a.wg.Done()
+		a.reportMetrics()
+	}()
+
+	// Start the writer of request metrics
+	go func() {
+		defer a.wg.Done()
+		a.writeMetrics()
+	}()
 
 	return nil
 }
 
+// listenUDP opens the agent's socket with enlarged buffers
+func (a *SNMPAgent) listenUDP(addr *net.UDPAddr) (snmpConn, error) {
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set socket buffer sizes
+	if err := conn.SetReadBuffer(262144); err != nil { // 256KB read buffer
+		a.logger.Error("Failed to set UDP read buffer size", zap.Error(err))
+	}
+	if err := conn.SetWriteBuffer(262144); err != nil { // 256KB write buffer
+		a.logger.Error("Failed to set UDP write buffer size", zap.Error(err))
+	}
+	return conn, nil
+}
+
+// writeResponse sends a response, giving up after the write timeout so that a
+// congested interface cannot hold the sender indefinitely. The deadline is set
+// on the socket shared by all handlers, so responses are written one at a time
+// to keep another handler from moving it during a write.
+func (a *SNMPAgent) writeResponse(response []byte, remoteAddr *net.UDPAddr) error {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+
+	if err := a.conn.SetWriteDeadline(time.Now().Add(a.writeTimeout)); err != nil {
+		return err
+	}
+	_, err := a.conn.WriteToUDP(response, remoteAddr)
+	return err
+}
+
+// reportInterval returns the wait before the next statistics report. The
+// interval is jittered so that agents started together do not report in step.
+func (a *SNMPAgent) reportInterval() time.Duration {
+	interval := a.config.SNMPReportInterval
+	if interval <= 0 {
+		interval = DefaultSNMPReportInterval
+	}
+	jitter := a.config.SNMPReportJitter
+	if jitter == 0 {
+		jitter = DefaultSNMPReportJitter
+	}
+	if jitter < 0 {
+		return interval
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return resilience.Jitter(interval, jitter, a.rand)
+}
+
 // reportMetrics periodically logs SNMP agent statistics
 func (a *SNMPAgent) reportMetrics() {
-	ticker := time.NewTicker(60 * time.Second)
-	defer ticker.Stop()
+	timer := time.NewTimer(a.reportInterval())
+	defer timer.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		timer.Reset(a.reportInterval())
 		a.stats.mu.RLock()
 		a.logger.Info("SNMP Stats",
 			zap.Uint64("total_requests", a.stats.totalRequests),
 			zap.Uint64("successful_requests", a.stats.successfulRequests),
 			zap.Uint64("invalid_requests", a.stats.invalidRequests),
-			zap.Uint64("auth_errors", a.stats.authErrors))
+			zap.Uint64("auth_errors", a.stats.authErrors),
+			zap.Uint64("timed_out_requests", a.stats.timedOutRequests),
+			zap.Uint64("filtered_requests", a.stats.filteredRequests),
+			zap.Uint64("dropped_requests", a.stats.droppedRequests),
+			zap.Uint64("rate_limited_requests", a.stats.rateLimitedRequests),
+			zap.Uint64("dropped_metrics_updates", a.stats.droppedUpdates))
 		if a.stats.lastError != "" {
 			a.logger.Info("Last Error",
 				zap.Time("time", a.stats.lastErrorTime),
@@ -113,11 +306,103 @@ func (a *SNMPAgent) reportMetrics() {
 	}
 }
 
-// Stop shuts down the SNMP agent
+// Stop shuts down the SNMP agent, abandons requests being processed and waits
+// for its goroutines to exit. It is safe to call more than once and from
+// several goroutines.
 func (a *SNMPAgent) Stop() {
-	if a.conn != nil {
-		a.conn.Close()
+	a.lifecycle.Lock()
+	defer a.lifecycle.Unlock()
+
+	a.cancel()
+	if !a.running {
+		return
 	}
+	a.running = false
+	a.conn.Close()
+	a.wg.Wait()
+}
+
+// recordRequest queues the metrics of a processed request for writeMetrics
+// without blocking. Updates are at most once: when the queue is full the
+// sample is dropped and counted, not retried. As each update overwrites the
+// latency and resource gauges, a dropped one is made up for by the next.
+func (a *SNMPAgent) recordRequest(sample requestSample) {
+	select {
+	case a.updates <- sample:
+	default:
+		a.stats.mu.Lock()
+		a.stats.droppedUpdates++
+		a.stats.mu.Unlock()
+	}
+}
+
+// writeMetrics applies queued request metrics until the agent stops. Samples
+// queued while the previous one was applied are collapsed into the latest.
+func (a *SNMPAgent) writeMetrics() {
+	for {
+		var sample requestSample
+		select {
+		case <-a.ctx.Done():
+			return
+		case sample = <-a.updates:
+		}
+		for drained := false; !drained; {
+			select {
+			case sample = <-a.updates:
+			default:
+				drained = true
+			}
+		}
+		a.applyRequestSample(sample)
+	}
+}
+
+// applyRequestSample updates the resource and latency metrics from a request.
+// The transport metrics belong to the latency tracker and are left alone.
+func (a *SNMPAgent) applyRequestSample(sample requestSample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.metrics.UpdateResourceMetrics(
+		a.metrics.CPUUsage,
+		a.metrics.MemoryUsage,
+		4096,                          // Fixed buffer size from pool
+		sample.variables,              // Queue length is number of variables
+		int64(runtime.NumGoroutine()), // Current goroutines
+	)
+	atomic.StoreInt64(&a.metrics.Latency, sample.latency.Microseconds())
+}
+
+// admitSource reports whether a request from addr should be processed,
+// counting it as filtered or rate limited when it is dropped
+func (a *SNMPAgent) admitSource(addr *net.UDPAddr) bool {
+	if len(a.allowed) > 0 {
+		allowed := false
+		for _, network := range a.allowed {
+			if network.Contains(addr.IP) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			a.stats.mu.Lock()
+			a.stats.filteredRequests++
+			a.stats.mu.Unlock()
+			a.logger.Debug("Dropped SNMP request from a source outside the allowed networks",
+				zap.String("remote_addr", addr.String()))
+			return false
+		}
+	}
+
+	if a.limiter != nil && !a.limiter.Allow(addr.IP.String()) {
+		a.stats.mu.Lock()
+		a.stats.rateLimitedRequests++
+		a.stats.mu.Unlock()
+		a.logger.Debug("Dropped SNMP request over the rate limit",
+			zap.String("remote_addr", addr.String()))
+		return false
+	}
+	return true
 }
 
 // validateCommunity checks if the provided community string matches the configured one
@@ -167,7 +452,7 @@ func (a *SNMPAgent) handleRequests() {
 		n, remoteAddr, err := a.conn.ReadFromUDP(buffer)
 
 		if err != nil {
-			if opErr, ok := err.(*net.OpError); ok && opErr.Err.Error() == "use of closed network connection" {
+			if errors.Is(err, net.ErrClosed) {
 				a.requestPool.Put(buffer)
 				return
 			}
@@ -181,6 +466,13 @@ func (a *SNMPAgent) handleRequests() {
 		a.stats.totalRequests++
 		a.stats.mu.Unlock()
 
+		// Drop unwanted sources before any parsing, so that neither responses
+		// nor timing depend on the community string
+		if !a.admitSource(remoteAddr) {
+			a.requestPool.Put(buffer)
+			continue
+		}
+
 		// Debug log raw packet
 		a.logger.Debug("Received SNMP packet",
 			zap.Int("bytes", n),
@@ -237,7 +529,7 @@ func (a *SNMPAgent) handleRequests() {
 				a.logger.Debug("Sending auth failure response",
 					zap.String("remote_addr", remoteAddr.String()),
 					zap.Binary("data", responseBytes))
-				if _, err := a.conn.WriteToUDP(responseBytes, remoteAddr); err != nil {
+				if err := a.writeResponse(responseBytes, remoteAddr); err != nil {
 					a.logger.Error("Error sending auth failure response", zap.Error(err))
 				}
 			} else {
@@ -246,30 +538,66 @@ func (a *SNMPAgent) handleRequests() {
 			continue
 		}
 
-		// Handle request with timeout
+		// Drop the request rather than queue it when every slot is taken
+		select {
+		case a.inflight <- struct{}{}:
+		default:
+			a.stats.mu.Lock()
+			a.stats.droppedRequests++
+			a.stats.mu.Unlock()
+			a.logger.Warn("Dropped SNMP request, too many requests in flight",
+				zap.String("remote_addr", remoteAddr.String()),
+				zap.Int("in_flight", cap(a.inflight)))
+			continue
+		}
+		a.wg.Add(1)
 		go func() {
-			done := make(chan struct{})
-			go func() {
-				a.processRequest(request, remoteAddr)
-				close(done)
+			defer func() {
+				<-a.inflight
+				a.wg.Done()
 			}()
-
-			select {
-			case <-done:
-				// Request completed normally
-			case <-time.After(5 * time.Second):
-				a.logger.Error("Request timeout",
-					zap.String("remote_addr", remoteAddr.String()))
-				a.stats.mu.Lock()
-				a.stats.lastError = "Request timeout"
-				a.stats.lastErrorTime = time.Now()
-				a.stats.mu.Unlock()
-			}
+			a.serveRequest(request, remoteAddr)
 		}()
 	}
 }
 
-func (a *SNMPAgent) processRequest(request *SNMPMessage, remoteAddr *net.UDPAddr) {
+// serveRequest processes a request within the request timeout. Processing
+// stops once the timeout expires, so a slow request is abandoned without a
+// response instead of being left running. A request blocked where it cannot
+// see the timeout, such as waiting for the MIB lock, is reported when the
+// timeout expires, and keeps its slot until it gives up.
+func (a *SNMPAgent) serveRequest(request *SNMPMessage, remoteAddr *net.UDPAddr) {
+	ctx, cancel := context.WithTimeout(a.ctx, a.requestTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.process(ctx, request, remoteAddr)
+	}()
+	defer func() { <-done }()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return
+	}
+
+	a.logger.Error("Request timeout",
+		zap.String("remote_addr", remoteAddr.String()),
+		zap.Duration("timeout", a.requestTimeout))
+	a.stats.mu.Lock()
+	a.stats.timedOutRequests++
+	a.stats.lastError = "Request timeout"
+	a.stats.lastErrorTime = time.Now()
+	a.stats.mu.Unlock()
+}
+
+// processRequest answers a request. It gives up without responding once ctx is
+// done, checking before each variable and before sending.
+func (a *SNMPAgent) processRequest(ctx context.Context, request *SNMPMessage, remoteAddr *net.UDPAddr) {
 	start := time.Now()
 	defer func() {
 		if r := recover(); r != nil {
@@ -285,25 +613,7 @@ func (a *SNMPAgent) processRequest(request *SNMPMessage, remoteAddr *net.UDPAddr
 		a.logger.Debug("Request processing completed",
 			zap.Duration("duration", duration))
 
-		// Update metrics
-		a.mu.Lock()
-		a.metrics.UpdateResourceMetrics(
-			a.metrics.CPUUsage,
-			a.metrics.MemoryUsage,
-			4096,                          // Fixed buffer size from pool
-			int64(len(request.Variables)), // Queue length is number of variables
-			int64(runtime.NumGoroutine()), // Current goroutines
-		)
-		a.mu.Unlock()
-
-		// Update performance metrics
-		a.metrics.UpdatePerformanceMetrics(
-			duration.Microseconds(), // Latency
-			0,                       // Jitter (not tracked yet)
-			0,                       // RTT (not tracked yet)
-			0,                       // Packet loss (not tracked yet)
-			0,                       // Reordering rate (not tracked yet)
-		)
+		a.recordRequest(requestSample{latency: duration, variables: int64(len(request.Variables))})
 	}()
 
 	a.logger.Debug("Processing SNMP request",
@@ -323,19 +633,25 @@ func (a *SNMPAgent) processRequest(request *SNMPMessage, remoteAddr *net.UDPAddr
 
 	// Track successful requests
 	defer func() {
-		if response.Error == gosnmp.NoError {
+		if response.Error == gosnmp.NoError && ctx.Err() == nil {
 			a.stats.mu.Lock()
 			a.stats.successfulRequests++
 			a.stats.mu.Unlock()
 		}
 	}()
 
-	// Lock metrics while processing request
-	a.mu.RLock()
+	// Refresh the MIB, which concurrent requests read, then lock metrics while
+	// processing request
+	a.mu.Lock()
 	a.mibTree.UpdateMetrics(a.metrics)
+	a.mu.Unlock()
+	a.mu.RLock()
 
 	// Process each variable in the request
 	for i, varBind := range request.Variables {
+		if ctx.Err() != nil {
+			break
+		}
 		oid := varBind.Name
 		var result gosnmp.SnmpPDU
 
@@ -401,6 +717,16 @@ func (a *SNMPAgent) processRequest(request *SNMPMessage, remoteAddr *net.UDPAddr
 
 		case gosnmp.GetNextRequest:
 			entry, err := a.mibTree.GetNextEntry(oid, request.Community)
+			if err == ErrEndOfMibView && request.Version != gosnmp.Version1 {
+				// SNMPv2c reports the end of the tree in the varbind, not the error status
+				result = gosnmp.SnmpPDU{
+					Name: oid,
+					Type: gosnmp.EndOfMibView,
+				}
+				a.logger.Debug("End of MIB view reached",
+					zap.String("after", oid))
+				break
+			}
 			if err != nil {
 				if mibErr, ok := err.(*MIBError); ok {
 					switch mibErr.Code {
@@ -470,8 +796,17 @@ func (a *SNMPAgent) processRequest(request *SNMPMessage, remoteAddr *net.UDPAddr
 
 	a.mu.RUnlock()
 
-	// Encode and send response
-	responseBytes, err := EncodeMessage(response)
+	if ctx.Err() != nil {
+		a.logger.Debug("Abandoned SNMP request",
+			zap.String("remote_addr", remoteAddr.String()),
+			zap.Error(ctx.Err()))
+		return
+	}
+
+	// Encode and send response using a pooled buffer
+	buffer := a.requestPool.Get().([]byte)
+	defer a.requestPool.Put(buffer)
+	responseBytes, err := AppendMessage(buffer[:0], response)
 	if err != nil {
 		a.logger.Error("Error encoding SNMP response", zap.Error(err))
 		return
@@ -481,7 +816,7 @@ func (a *SNMPAgent) processRequest(request *SNMPMessage, remoteAddr *net.UDPAddr
 		zap.String("remote_addr", remoteAddr.String()),
 		zap.Binary("data", responseBytes))
 
-	if _, err := a.conn.WriteToUDP(responseBytes, remoteAddr); err != nil {
+	if err := a.writeResponse(responseBytes, remoteAddr); err != nil {
 		a.logger.Error("Failed to send SNMP response", zap.Error(err))
 		return
 	}
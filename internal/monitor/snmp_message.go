@@ -135,9 +135,8 @@ func DecodeMessage(data []byte) (*SNMPMessage, error) {
 		return nil, fmt.Errorf("variable bindings length exceeds message size")
 	}
 
-	// Pre-allocate variables slice with reasonable capacity
-	msg.Variables = make([]gosnmp.SnmpPDU, 0, 16)
 	endOffset := offset + varbindLen
+	msg.Variables = make([]gosnmp.SnmpPDU, 0, countVarBinds(data[offset:endOffset]))
 
 	for offset < endOffset {
 		// Decode varbind sequence
@@ -215,8 +214,29 @@ func DecodeMessage(data []byte) (*SNMPMessage, error) {
 	return msg, nil
 }
 
+// countVarBinds returns the number of varbind sequences in an encoded variable
+// bindings list, so the decoded slice is allocated once
+func countVarBinds(data []byte) int {
+	count := 0
+	for offset := 0; offset+1 < len(data); offset += 2 + int(data[offset+1]) {
+		count++
+	}
+	return count
+}
+
 // EncodeMessage encodes an SNMP message to BER format
 func EncodeMessage(msg *SNMPMessage) ([]byte, error) {
+	// Headers take under 32 bytes; each varbind adds its OID plus a short value
+	size := 32 + len(msg.Community)
+	for _, v := range msg.Variables {
+		size += 24 + len(v.Name)
+	}
+	return AppendMessage(make([]byte, 0, size), msg)
+}
+
+// AppendMessage appends the BER encoding of an SNMP message to dst, so callers can
+// encode into a reused buffer
+func AppendMessage(dst []byte, msg *SNMPMessage) ([]byte, error) {
 	// Validate message fields
 	if err := validateCommunity(msg.Community); err != nil {
 		return nil, fmt.Errorf("invalid community string: %w", err)
@@ -231,161 +251,82 @@ func EncodeMessage(msg *SNMPMessage) ([]byte, error) {
 			return nil, fmt.Errorf("invalid OID in variable binding: %w", err)
 		}
 	}
-	// Pre-calculate buffer size
-	bufSize := 0
-
-	// Version (tag + len + value)
-	bufSize += 3
 
-	// Community string (tag + len + value)
-	bufSize += 2 + len(msg.Community)
-
-	// PDU header (tag + len)
-	bufSize += 2
-
-	// Request ID (tag + len + value)
-	bufSize += 6
-
-	// Error status (tag + len + value)
-	bufSize += 3
-
-	// Error index (tag + len + value)
-	bufSize += 3
-
-	// Variable bindings sequence header (tag + len)
-	bufSize += 2
-
-	// Variable bindings
-	for _, v := range msg.Variables {
-		// Varbind sequence (tag + len)
-		bufSize += 2
-
-		// OID (tag + len + value)
-		bufSize += 2 + len(v.Name)
-
-		// Value (tag + len + value)
-		var valueStr string
-		switch v.Type {
-		case gosnmp.Counter64, gosnmp.Gauge32, gosnmp.Integer:
-			valueStr = strconv.FormatInt(v.Value.(int64), 10)
-		default:
-			valueStr = fmt.Sprintf("%v", v.Value)
-		}
-		bufSize += 2 + len(valueStr)
-	}
-
-	// Allocate buffer with pre-calculated size
-	buf := make([]byte, bufSize+2) // +2 for outer sequence header
-	offset := 0
-
-	// Sequence header
-	buf[offset] = TagSequence
-	offset++
-	buf[offset] = byte(bufSize) // Total length
-	offset++
+	// Sequence header, length is updated at the end
+	start := len(dst)
+	buf := append(dst, TagSequence, 0)
 
 	// Version
-	buf[offset] = TagInteger
-	offset++
-	buf[offset] = 1 // Length
-	offset++
-	buf[offset] = byte(msg.Version)
-	offset++
+	buf = append(buf, TagInteger, 1, byte(msg.Version))
 
 	// Community string
-	buf[offset] = TagOctetString
-	offset++
-	buf[offset] = byte(len(msg.Community))
-	offset++
-	copy(buf[offset:], []byte(msg.Community))
-	offset += len(msg.Community)
+	buf = append(buf, TagOctetString, byte(len(msg.Community)))
+	buf = append(buf, msg.Community...)
 
 	// PDU header
-	buf[offset] = byte(msg.PDUType)
-	offset++
-	pduStartPos := offset // Remember position to calculate PDU length
-	buf[offset] = 0       // Temporary length
-	offset++
+	pduStartPos := len(buf) + 1 // Remember position to calculate PDU length
+	buf = append(buf, byte(msg.PDUType), 0)
 
 	// Request ID
-	buf[offset] = TagInteger
-	offset++
-	buf[offset] = 4 // Length
-	offset++
-	buf[offset] = byte(msg.RequestID >> 24)
-	buf[offset+1] = byte(msg.RequestID >> 16)
-	buf[offset+2] = byte(msg.RequestID >> 8)
-	buf[offset+3] = byte(msg.RequestID)
-	offset += 4
-
-	// Error status
-	buf[offset] = TagInteger
-	offset++
-	buf[offset] = 1 // Length
-	offset++
-	buf[offset] = byte(msg.Error)
-	offset++
+	buf = append(buf, TagInteger, 4,
+		byte(msg.RequestID>>24), byte(msg.RequestID>>16),
+		byte(msg.RequestID>>8), byte(msg.RequestID))
 
-	// Error index
-	buf[offset] = TagInteger
-	offset++
-	buf[offset] = 1 // Length
-	offset++
-	buf[offset] = byte(msg.Index)
-	offset++
+	// Error status and index
+	buf = append(buf, TagInteger, 1, byte(msg.Error))
+	buf = append(buf, TagInteger, 1, byte(msg.Index))
 
 	// Variable bindings sequence
-	varbindStartPos := offset
-	buf[offset] = TagSequence
-	offset++
-	buf[offset] = 0 // Temporary length
-	offset++
+	varbindStartPos := len(buf) + 1
+	buf = append(buf, TagSequence, 0)
 
 	for _, v := range msg.Variables {
 		// Start varbind sequence
-		buf[offset] = TagSequence
-		offset++
-		varbindSeqPos := offset
-		buf[offset] = 0 // Temporary length
-		offset++
+		varbindSeqPos := len(buf) + 1
+		buf = append(buf, TagSequence, 0)
 
 		// OID
-		buf[offset] = TagObjectID
-		offset++
-		oidLen := len(v.Name)
-		buf[offset] = byte(oidLen)
-		offset++
-		copy(buf[offset:], []byte(v.Name))
-		offset += oidLen
+		buf = append(buf, TagObjectID, byte(len(v.Name)))
+		buf = append(buf, v.Name...)
 
 		// Value
-		buf[offset] = byte(v.Type)
-		offset++
-		var valueStr string
-		switch v.Type {
-		case gosnmp.Counter64, gosnmp.Gauge32, gosnmp.Integer:
-			valueStr = strconv.FormatInt(v.Value.(int64), 10)
-		default:
-			valueStr = fmt.Sprintf("%v", v.Value)
-		}
-		valueLen := len(valueStr)
-		buf[offset] = byte(valueLen)
-		offset++
-		copy(buf[offset:], []byte(valueStr))
-		offset += valueLen
+		buf = append(buf, byte(v.Type), 0)
+		valuePos := len(buf)
+		buf = appendValue(buf, v)
+		buf[valuePos-1] = byte(len(buf) - valuePos)
 
 		// Update varbind sequence length
-		varbindLen := offset - varbindSeqPos - 1
-		buf[varbindSeqPos] = byte(varbindLen)
+		buf[varbindSeqPos] = byte(len(buf) - varbindSeqPos - 1)
 	}
 
 	// Update varbindings sequence length
-	varbindLen := offset - varbindStartPos - 2
-	buf[varbindStartPos+1] = byte(varbindLen)
+	buf[varbindStartPos] = byte(len(buf) - varbindStartPos - 1)
 
 	// Update PDU length
-	pduLen := offset - pduStartPos - 1
-	buf[pduStartPos] = byte(pduLen)
+	buf[pduStartPos] = byte(len(buf) - pduStartPos - 1)
+
+	// Update total length
+	buf[start+1] = byte(len(buf) - start - 2)
+
+	return buf, nil
+}
 
-	return buf[:offset], nil
+// appendValue appends the text form of a variable binding value
+func appendValue(buf []byte, v gosnmp.SnmpPDU) []byte {
+	switch v.Type {
+	case gosnmp.Counter64, gosnmp.Gauge32, gosnmp.Integer:
+		return strconv.AppendInt(buf, v.Value.(int64), 10)
+	case gosnmp.Null, gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+		// Exception values carry no content
+		return buf
+	}
+
+	switch value := v.Value.(type) {
+	case string:
+		return append(buf, value...)
+	case []byte:
+		return append(buf, value...)
+	default:
+		return fmt.Appendf(buf, "%v", v.Value)
+	}
 }
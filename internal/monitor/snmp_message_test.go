@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// benchmarkMessages are typical poller requests and agent responses
+var benchmarkMessages = []struct {
+	name string
+	msg  *SNMPMessage
+}{
+	{"Get", &SNMPMessage{
+		Version:   gosnmp.Version2c,
+		Community: readCommunity,
+		PDUType:   gosnmp.GetRequest,
+		RequestID: 1234567,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.4.1.54321.1.1.0", Type: gosnmp.Null}},
+	}},
+	{"GetNext", &SNMPMessage{
+		Version:   gosnmp.Version2c,
+		Community: readCommunity,
+		PDUType:   gosnmp.GetNextRequest,
+		RequestID: 1234568,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.4.1.54321.1.1", Type: gosnmp.Null},
+			{Name: ".1.3.6.1.4.1.54321.1.2", Type: gosnmp.Null},
+			{Name: ".1.3.6.1.4.1.54321.1.3", Type: gosnmp.Null},
+		},
+	}},
+	{"Response", &SNMPMessage{
+		Version:   gosnmp.Version2c,
+		Community: readCommunity,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: 1234569,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.4.1.54321.1.1.0", Type: gosnmp.Counter64, Value: int64(123456789)},
+			{Name: ".1.3.6.1.4.1.54321.1.2.0", Type: gosnmp.Integer, Value: int64(42)},
+			{Name: ".1.3.6.1.4.1.54321.1.3.0", Type: gosnmp.OctetString, Value: "tunnel0"},
+		},
+	}},
+}
+
+func TestEncodeDecodeMessage(t *testing.T) {
+	for _, bm := range benchmarkMessages {
+		t.Run(bm.name, func(t *testing.T) {
+			data, err := EncodeMessage(bm.msg)
+			if err != nil {
+				t.Fatalf("Failed to encode message: %v", err)
+			}
+			decoded, err := DecodeMessage(data)
+			if err != nil {
+				t.Fatalf("Failed to decode message: %v", err)
+			}
+
+			if decoded.Version != bm.msg.Version || decoded.Community != bm.msg.Community ||
+				decoded.PDUType != bm.msg.PDUType || decoded.RequestID != bm.msg.RequestID {
+				t.Errorf("Expected header %+v, got %+v", bm.msg, decoded)
+			}
+			if len(decoded.Variables) != len(bm.msg.Variables) {
+				t.Fatalf("Expected %d variables, got %d", len(bm.msg.Variables), len(decoded.Variables))
+			}
+			for i, v := range bm.msg.Variables {
+				if decoded.Variables[i].Name != v.Name || decoded.Variables[i].Type != v.Type {
+					t.Errorf("Expected variable %s (%v), got %s (%v)", v.Name, v.Type,
+						decoded.Variables[i].Name, decoded.Variables[i].Type)
+				}
+			}
+
+			// Encoding into a reused buffer produces the same bytes
+			buf, err := AppendMessage(make([]byte, 0, MaxSNMPPacketSize), bm.msg)
+			if err != nil {
+				t.Fatalf("Failed to append message: %v", err)
+			}
+			if !reflect.DeepEqual(buf, data) {
+				t.Errorf("Expected appended message %x, got %x", data, buf)
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeMessage(b *testing.B) {
+	for _, bm := range benchmarkMessages {
+		data, err := EncodeMessage(bm.msg)
+		if err != nil {
+			b.Fatalf("Failed to encode message: %v", err)
+		}
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodeMessage(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeMessage(b *testing.B) {
+	for _, bm := range benchmarkMessages {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := EncodeMessage(bm.msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppendMessage(b *testing.B) {
+	for _, bm := range benchmarkMessages {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			buf := make([]byte, 0, MaxSNMPPacketSize)
+			for i := 0; i < b.N; i++ {
+				if _, err := AppendMessage(buf[:0], bm.msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,695 @@
+package monitor
+
+import (
+	"context"
+	"math"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+)
+
+func TestMIBWalkReachesEndOfMibView(t *testing.T) {
+	tree := NewMIBTree(NewMetrics())
+
+	oid := baseOID
+	walked := 0
+	for {
+		entry, err := tree.GetNextEntry(oid, readCommunity)
+		if err == ErrEndOfMibView {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to get next OID after %s: %v", oid, err)
+		}
+		if compareOIDs(entry.OID, oid) <= 0 {
+			t.Fatalf("Expected OID after %s, got %s", oid, entry.OID)
+		}
+		oid = entry.OID
+		walked++
+		if walked > len(tree.entries) {
+			t.Fatalf("Walk did not terminate after %d entries", walked)
+		}
+	}
+
+	if walked != len(tree.entries) {
+		t.Errorf("Expected to walk %d entries, got %d", len(tree.entries), walked)
+	}
+}
+
+func TestMIBDump(t *testing.T) {
+	tree := NewMIBTree(NewMetrics())
+	entries := tree.Dump()
+	if len(entries) != len(tree.entries) {
+		t.Fatalf("Expected %d entries, got %d", len(tree.entries), len(entries))
+	}
+
+	var bytesIn *MIBEntryInfo
+	for i, entry := range entries {
+		if i > 0 && compareOIDs(entries[i-1].OID, entry.OID) >= 0 {
+			t.Errorf("Expected entries in OID order, got %s before %s", entries[i-1].OID, entry.OID)
+		}
+		if entry.OID == bytesInOID {
+			bytesIn = &entries[i]
+		}
+	}
+
+	expected := MIBEntryInfo{
+		OID:         bytesInOID,
+		Name:        "bytesIn",
+		Type:        "Counter64",
+		Access:      "read-only",
+		Description: "Total bytes received",
+	}
+	if bytesIn == nil || *bytesIn != expected {
+		t.Errorf("Expected the bytes received counter %+v, got %+v", expected, bytesIn)
+	}
+}
+
+func TestCompareOIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{".1.3.6.1.2", ".1.3.6.1.10", -1},
+		{".1.3.6.1.10", ".1.3.6.1.2", 1},
+		{".1.3.6.1", ".1.3.6.1.1", -1},
+		{".1.3.6.1", "1.3.6.1", 0},
+	}
+
+	for _, tt := range tests {
+		if got := compareOIDs(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareOIDs(%s, %s): expected %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestGetNextAtEndOfMib(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   gosnmp.SnmpVersion
+		wantError gosnmp.SNMPError
+		wantType  gosnmp.Asn1BER
+	}{
+		{"v2c returns endOfMibView", gosnmp.Version2c, gosnmp.NoError, gosnmp.EndOfMibView},
+		{"v1 returns noSuchName", gosnmp.Version1, gosnmp.NoSuchName, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := sendTestRequest(t, &SNMPMessage{
+				Version:   tt.version,
+				Community: readCommunity,
+				PDUType:   gosnmp.GetNextRequest,
+				RequestID: 42,
+				Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.4.1.54322", Type: gosnmp.Null}},
+			})
+
+			if response.Error != tt.wantError {
+				t.Fatalf("Expected error status %v, got %v", tt.wantError, response.Error)
+			}
+			if tt.wantError != gosnmp.NoError {
+				return
+			}
+			if len(response.Variables) != 1 {
+				t.Fatalf("Expected 1 variable, got %d", len(response.Variables))
+			}
+			if response.Variables[0].Type != tt.wantType {
+				t.Errorf("Expected type %v, got %v", tt.wantType, response.Variables[0].Type)
+			}
+			if response.Variables[0].Name != ".1.3.6.1.4.1.54322" {
+				t.Errorf("Expected requested OID in varbind, got %s", response.Variables[0].Name)
+			}
+		})
+	}
+}
+
+// sendTestRequest runs a request through a local agent and decodes the response
+func sendTestRequest(t *testing.T, request *SNMPMessage) *SNMPMessage {
+	t.Helper()
+
+	agent, err := NewSNMPAgent(&Config{}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	agent.conn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer agent.conn.Close()
+
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer client.Close()
+
+	agent.processRequest(context.Background(), request, client.LocalAddr().(*net.UDPAddr))
+
+	buf := make([]byte, MaxSNMPPacketSize)
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := client.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	response, err := DecodeMessage(buf[:n])
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestThrottleOIDs(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.Throttle = ThrottleStats{Enabled: true, Rate: 1048576, Burst: 65536, Drops: 7}
+	tree := NewMIBTree(metrics)
+
+	tests := []struct {
+		oid      string
+		wantType string
+		want     int64
+	}{
+		{throttleEnabledOID, "INTEGER", 1},
+		{throttleRateOID, "Gauge32", 1048576},
+		{throttleBurstOID, "Gauge32", 65536},
+		{throttleDropsOID, "Counter64", 7},
+	}
+	for _, tt := range tests {
+		entry, err := tree.GetEntry(tt.oid, readCommunity)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", tt.oid, err)
+		}
+		if entry.Type != tt.wantType {
+			t.Errorf("%s: expected type %s, got %s", entry.Name, tt.wantType, entry.Type)
+		}
+		if got := entry.ValueToInt64(entry.Value); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", entry.Name, tt.want, got)
+		}
+	}
+
+	// Live values are picked up on update
+	metrics.Throttle.Enabled = false
+	metrics.Throttle.Drops = 9
+	tree.UpdateMetrics(metrics)
+	for oid, want := range map[string]int64{throttleEnabledOID: 0, throttleDropsOID: 9} {
+		entry, err := tree.GetEntry(oid, readCommunity)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", oid, err)
+		}
+		if got := entry.ValueToInt64(entry.Value); got != want {
+			t.Errorf("%s: expected %d after update, got %d", entry.Name, want, got)
+		}
+	}
+
+	// Gauge32 is unsigned, so rates above 2^31 are reported up to 2^32-1
+	metrics.Throttle.Rate = 3e9
+	metrics.Throttle.Burst = 1 << 40
+	tree.UpdateMetrics(metrics)
+	for oid, want := range map[string]int64{throttleRateOID: 3e9, throttleBurstOID: math.MaxUint32} {
+		entry, err := tree.GetEntry(oid, readCommunity)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", oid, err)
+		}
+		if got := entry.ValueToInt64(entry.Value); got != want {
+			t.Errorf("%s: expected %d, got %d", entry.Name, want, got)
+		}
+	}
+}
+
+func TestSNMPReportIntervalJitter(t *testing.T) {
+	const interval = 10 * time.Second
+	const jitter = 0.2
+
+	agent, err := NewSNMPAgent(&Config{SNMPReportInterval: interval, SNMPReportJitter: jitter}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	bound := time.Duration(float64(interval) * jitter)
+	const n = 2000
+	var total time.Duration
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < n; i++ {
+		d := agent.reportInterval()
+		if d < interval-bound || d > interval+bound {
+			t.Fatalf("Expected an interval within %v of %v, got %v", bound, interval, d)
+		}
+		distinct[d] = true
+		total += d
+	}
+
+	if len(distinct) < n/2 {
+		t.Errorf("Expected successive intervals to vary, got %d distinct values in %d", len(distinct), n)
+	}
+	if avg := total / n; avg < interval-interval/50 || avg > interval+interval/50 {
+		t.Errorf("Expected the average interval to be near %v, got %v", interval, avg)
+	}
+}
+
+func TestSNMPReportIntervalDefaults(t *testing.T) {
+	agent, err := NewSNMPAgent(&Config{SNMPReportJitter: -1}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	if d := agent.reportInterval(); d != DefaultSNMPReportInterval {
+		t.Errorf("Expected %v without jitter, got %v", DefaultSNMPReportInterval, d)
+	}
+}
+
+func TestSNMPRequestTimeoutAbandonsRequest(t *testing.T) {
+	agent, err := NewSNMPAgent(&Config{}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	agent.requestTimeout = 50 * time.Millisecond
+
+	// A request that only finishes when cancelled or released
+	release := make(chan struct{})
+	defer close(release)
+	agent.process = func(ctx context.Context, request *SNMPMessage, remoteAddr *net.UDPAddr) {
+		select {
+		case <-ctx.Done():
+		case <-release:
+		}
+	}
+
+	before := runtime.NumGoroutine()
+	done := make(chan struct{})
+	go func() {
+		agent.serveRequest(&SNMPMessage{}, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 161})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the slow request to be abandoned after the timeout")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Expected no lingering goroutines after the timeout, got %d (was %d)", n, before)
+	}
+
+	agent.stats.mu.RLock()
+	timedOut, lastError := agent.stats.timedOutRequests, agent.stats.lastError
+	agent.stats.mu.RUnlock()
+	if timedOut != 1 || lastError != "Request timeout" {
+		t.Errorf("Expected one timed out request, got %d (last error %q)", timedOut, lastError)
+	}
+}
+
+func TestSNMPRequestTimeoutReportsBlockedRequest(t *testing.T) {
+	agent, err := NewSNMPAgent(&Config{}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	agent.requestTimeout = 50 * time.Millisecond
+
+	// A request blocked where it cannot see the timeout
+	release := make(chan struct{})
+	agent.process = func(ctx context.Context, request *SNMPMessage, remoteAddr *net.UDPAddr) {
+		<-release
+	}
+
+	done := make(chan struct{})
+	go func() {
+		agent.serveRequest(&SNMPMessage{}, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 161})
+		close(done)
+	}()
+
+	timedOut := func() uint64 {
+		agent.stats.mu.RLock()
+		defer agent.stats.mu.RUnlock()
+		return agent.stats.timedOutRequests
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for timedOut() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if timedOut() != 1 {
+		t.Fatal("Expected the blocked request to be reported at the timeout")
+	}
+
+	// The request keeps its slot until it gives up
+	select {
+	case <-done:
+		t.Fatal("Expected serveRequest to wait for the blocked request")
+	default:
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected serveRequest to return once the request gave up")
+	}
+}
+
+func TestSNMPProcessRequestHonoursCancellation(t *testing.T) {
+	agent, err := NewSNMPAgent(&Config{}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The agent has no connection, so sending a response would panic
+	agent.processRequest(ctx, &SNMPMessage{
+		Version:   gosnmp.Version2c,
+		Community: readCommunity,
+		PDUType:   gosnmp.GetRequest,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.4.1.54321.1.1.0", Type: gosnmp.Null}},
+	}, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 161})
+
+	agent.stats.mu.RLock()
+	defer agent.stats.mu.RUnlock()
+	if agent.stats.successfulRequests != 0 {
+		t.Errorf("Expected a cancelled request not to count as successful, got %d", agent.stats.successfulRequests)
+	}
+}
+
+func TestSNMPAgentStopLeavesNoGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	agent, err := NewSNMPAgent(&Config{SNMPAddress: "127.0.0.1", SNMPCommunity: "public"}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	// Requests are held in flight until the agent stops
+	started := make(chan struct{}, 1)
+	agent.process = func(ctx context.Context, request *SNMPMessage, remoteAddr *net.UDPAddr) {
+		started <- struct{}{}
+		<-ctx.Done()
+	}
+	request, err := EncodeMessage(&SNMPMessage{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.GetRequest,
+		RequestID: 1,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.Null}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+
+	// A stopped agent starts again
+	for i := 0; i < 2; i++ {
+		if err := agent.Start(); err != nil {
+			t.Fatalf("Failed to start agent: %v", err)
+		}
+
+		conn, err := net.DialUDP("udp", nil, agent.conn.LocalAddr().(*net.UDPAddr))
+		if err != nil {
+			t.Fatalf("Failed to dial agent: %v", err)
+		}
+		if _, err := conn.Write(request); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		select {
+		case <-started:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the request to be processed")
+		}
+		conn.Close()
+
+		// Concurrent and repeated calls all return once the agent has stopped
+		var wg sync.WaitGroup
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				agent.Stop()
+			}()
+		}
+		wg.Wait()
+		agent.Stop()
+	}
+}
+
+// sendSNMPRequests sends count GetRequests with the community public to agent
+// from one socket
+func sendSNMPRequests(t *testing.T, agent *SNMPAgent, count int) {
+	t.Helper()
+	request, err := EncodeMessage(&SNMPMessage{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.GetRequest,
+		RequestID: 1,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.Null}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, agent.conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("Failed to dial agent: %v", err)
+	}
+	defer conn.Close()
+	for i := 0; i < count; i++ {
+		if _, err := conn.Write(request); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+	}
+}
+
+// waitForSNMPRequests waits until the agent has received count requests
+func waitForSNMPRequests(t *testing.T, agent *SNMPAgent, count uint64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		agent.stats.mu.RLock()
+		total := agent.stats.totalRequests
+		agent.stats.mu.RUnlock()
+		if total >= count {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected the agent to receive %d requests", count)
+}
+
+func TestSNMPAgentRateLimitsSources(t *testing.T) {
+	agent, err := NewSNMPAgent(&Config{
+		SNMPAddress:   "127.0.0.1",
+		SNMPCommunity: "public",
+		SNMPRateLimit: 0.01, // No refill while the test runs
+		SNMPRateBurst: 3,
+	}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	var processed atomic.Int32
+	agent.process = func(ctx context.Context, request *SNMPMessage, remoteAddr *net.UDPAddr) {
+		processed.Add(1)
+	}
+	if err := agent.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer agent.Stop()
+
+	sendSNMPRequests(t, agent, 8)
+	waitForSNMPRequests(t, agent, 8)
+	agent.Stop()
+
+	if n := processed.Load(); n != 3 {
+		t.Errorf("Expected only the burst of 3 requests to be answered, got %d", n)
+	}
+	if n := agent.stats.rateLimitedRequests; n != 5 {
+		t.Errorf("Expected 5 rate limited requests, got %d", n)
+	}
+}
+
+func TestSNMPAgentDropsSourcesOutsideAllowedNetworks(t *testing.T) {
+	if _, err := NewSNMPAgent(&Config{SNMPAllowedCIDRs: []string{"10.0.0.0"}}, NewMetrics(), zap.NewNop()); err == nil {
+		t.Error("Expected an invalid allowed network to be rejected")
+	}
+
+	agent, err := NewSNMPAgent(&Config{
+		SNMPAddress:      "127.0.0.1",
+		SNMPCommunity:    "public",
+		SNMPAllowedCIDRs: []string{"10.0.0.0/8"},
+	}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	var processed atomic.Int32
+	agent.process = func(ctx context.Context, request *SNMPMessage, remoteAddr *net.UDPAddr) {
+		processed.Add(1)
+	}
+	if err := agent.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer agent.Stop()
+
+	sendSNMPRequests(t, agent, 2)
+	waitForSNMPRequests(t, agent, 2)
+	agent.Stop()
+
+	if n := processed.Load(); n != 0 {
+		t.Errorf("Expected requests from 127.0.0.1 to be dropped, %d were answered", n)
+	}
+	if n := agent.stats.filteredRequests; n != 2 {
+		t.Errorf("Expected 2 filtered requests, got %d", n)
+	}
+	if n := agent.stats.authErrors; n != 0 {
+		t.Errorf("Expected filtered requests to skip community validation, got %d auth errors", n)
+	}
+}
+
+// congestedConn is a UDP socket whose writes block until the write deadline,
+// as on a congested interface
+type congestedConn struct {
+	*net.UDPConn
+	mu       sync.Mutex
+	deadline time.Time
+	writing  int
+	peak     int // Most writes blocked at once
+	writes   int
+}
+
+func (c *congestedConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *congestedConn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.writing++
+	c.writes++
+	c.peak = max(c.peak, c.writing)
+	c.mu.Unlock()
+
+	time.Sleep(time.Until(deadline))
+
+	c.mu.Lock()
+	c.writing--
+	c.mu.Unlock()
+	return 0, os.ErrDeadlineExceeded
+}
+
+func TestSNMPAgentBoundsBlockedResponses(t *testing.T) {
+	agent, err := NewSNMPAgent(&Config{
+		SNMPAddress:      "127.0.0.1",
+		SNMPCommunity:    "public",
+		SNMPWriteTimeout: 200 * time.Millisecond,
+	}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	agent.inflight = make(chan struct{}, 2)
+	var conn *congestedConn
+	agent.listen = func(addr *net.UDPAddr) (snmpConn, error) {
+		udp, err := net.ListenUDP("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		conn = &congestedConn{UDPConn: udp}
+		return conn, nil
+	}
+	if err := agent.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer agent.Stop()
+
+	sendSNMPRequests(t, agent, 10)
+	waitForSNMPRequests(t, agent, 10)
+	agent.Stop()
+
+	// Blocked writes give up at their own deadline, one at a time
+	conn.mu.Lock()
+	peak, writes := conn.peak, conn.writes
+	conn.mu.Unlock()
+	if peak != 1 {
+		t.Errorf("Expected responses to be written one at a time, got %d at once", peak)
+	}
+	if dropped := agent.stats.droppedRequests; dropped == 0 || writes+int(dropped) != 10 {
+		t.Errorf("Expected the requests without a slot to be dropped, got %d written and %d dropped", writes, dropped)
+	}
+}
+
+func TestSNMPAgentQueuesRequestMetrics(t *testing.T) {
+	agent, err := NewSNMPAgent(&Config{SNMPMetricsBuffer: 2}, NewMetrics(), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	// With no writer running, updates past the buffer are dropped, not waited on
+	for i := 1; i <= 3; i++ {
+		agent.recordRequest(requestSample{latency: time.Duration(i) * time.Millisecond, variables: int64(i)})
+	}
+	if dropped := agent.stats.droppedUpdates; dropped != 1 {
+		t.Errorf("Expected 1 dropped update, got %d", dropped)
+	}
+
+	// The writer applies the latest of the queued updates
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.writeMetrics()
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt64(&agent.metrics.QueueLength) != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	agent.cancel()
+	<-done
+
+	if queue, latency := agent.metrics.QueueLength, agent.metrics.Latency; queue != 2 || latency != 2000 {
+		t.Errorf("Expected queue length 2 and latency 2000us, got %d and %d", queue, latency)
+	}
+}
+
+// BenchmarkSNMPRequestMetrics compares updating the metrics of concurrent
+// requests under the agent lock with queueing them for the writer
+func BenchmarkSNMPRequestMetrics(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		record func(agent *SNMPAgent, sample requestSample)
+	}{
+		{"locked", (*SNMPAgent).applyRequestSample},
+		{"buffered", (*SNMPAgent).recordRequest},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			agent, err := NewSNMPAgent(&Config{}, NewMetrics(), zap.NewNop())
+			if err != nil {
+				b.Fatalf("Failed to create agent: %v", err)
+			}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				agent.writeMetrics()
+			}()
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				sample := requestSample{latency: time.Millisecond, variables: 1}
+				for pb.Next() {
+					bm.record(agent, sample)
+				}
+			})
+			b.StopTimer()
+			agent.cancel()
+			<-done
+		})
+	}
+}
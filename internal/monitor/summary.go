@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultTopTalkers is the number of connections listed in a summary when none is configured
+const DefaultTopTalkers = 5
+
+// SummaryConfig controls the periodic connection summary log
+type SummaryConfig struct {
+	Interval   time.Duration // Zero disables the summary
+	TopTalkers int
+}
+
+// SummaryConfigFrom converts the configured summary settings. A disabled summary
+// has a zero interval.
+func SummaryConfigFrom(cfg types.SummaryConfig) SummaryConfig {
+	if !cfg.Enabled {
+		return SummaryConfig{}
+	}
+	return SummaryConfig{
+		Interval:   cfg.Interval,
+		TopTalkers: cfg.TopTalkers,
+	}
+}
+
+// TalkerStats holds the traffic of a single connection
+type TalkerStats struct {
+	RemoteAddr    string
+	ClientCN      string // Common name of the verified client certificate, if any
+	BytesSent     int64
+	BytesReceived int64
+}
+
+// Total returns the bytes sent and received on the connection
+func (t TalkerStats) Total() int64 {
+	return t.BytesSent + t.BytesReceived
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (t TalkerStats) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("remote_addr", t.RemoteAddr)
+	if t.ClientCN != "" {
+		enc.AddString("client_cn", t.ClientCN)
+	}
+	enc.AddInt64("bytes_sent", t.BytesSent)
+	enc.AddInt64("bytes_received", t.BytesReceived)
+	return nil
+}
+
+// SummaryEmitter periodically logs active connections, aggregate throughput and
+// the connections with the most traffic
+type SummaryEmitter struct {
+	config      SummaryConfig
+	snapshot    func() *Metrics
+	connections func() []TalkerStats
+	logger      *zap.Logger
+	last        *Metrics
+	lastTime    time.Time
+	stopCh      chan struct{}
+	wg          sync.WaitGroup
+	stopOnce    sync.Once
+}
+
+// NewSummaryEmitter creates an emitter that reads aggregate traffic from snapshot
+// and per-connection traffic from connections. connections may be nil.
+func NewSummaryEmitter(cfg SummaryConfig, snapshot func() *Metrics, connections func() []TalkerStats, logger *zap.Logger) *SummaryEmitter {
+	if cfg.TopTalkers <= 0 {
+		cfg.TopTalkers = DefaultTopTalkers
+	}
+	return &SummaryEmitter{
+		config:      cfg,
+		snapshot:    snapshot,
+		connections: connections,
+		logger:      logger,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Start begins emitting summaries at the configured interval
+func (e *SummaryEmitter) Start() {
+	e.last = e.snapshot()
+	e.lastTime = time.Now()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(e.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.stopCh:
+				return
+			case <-ticker.C:
+				e.Emit()
+			}
+		}
+	}()
+}
+
+// Stop stops emitting summaries
+func (e *SummaryEmitter) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+	e.wg.Wait()
+}
+
+// Emit logs a single summary. Throughput covers the time since the previous summary.
+func (e *SummaryEmitter) Emit() {
+	now := time.Now()
+	current := e.snapshot()
+
+	var inRate, outRate float64
+	if e.last != nil {
+		if elapsed := now.Sub(e.lastTime).Seconds(); elapsed > 0 {
+			inRate = float64(current.BytesIn-e.last.BytesIn) / elapsed
+			outRate = float64(current.BytesOut-e.last.BytesOut) / elapsed
+		}
+	}
+	e.last = current
+	e.lastTime = now
+
+	active := int(current.Connections)
+	var talkers []TalkerStats
+	if e.connections != nil {
+		talkers = e.connections()
+		active = len(talkers)
+	}
+
+	e.logger.Info("Connection summary",
+		zap.Int("active_connections", active),
+		zap.Int64("bytes_in", current.BytesIn),
+		zap.Int64("bytes_out", current.BytesOut),
+		zap.Float64("throughput_in_bps", inRate),
+		zap.Float64("throughput_out_bps", outRate),
+		zap.Objects("top_talkers", topTalkers(talkers, e.config.TopTalkers)),
+	)
+}
+
+// topTalkers returns up to n connections ordered by total traffic, largest first
+func topTalkers(talkers []TalkerStats, n int) []TalkerStats {
+	sorted := make([]TalkerStats, len(talkers))
+	copy(sorted, talkers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Total() > sorted[j].Total()
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
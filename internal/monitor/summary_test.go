@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSummaryEmitter(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	// Traffic grows by 500 bytes each way after the first snapshot
+	metrics := NewMetrics()
+	metrics.UpdateNetworkMetrics(1000, 2000, 10, 20)
+	var mu sync.Mutex
+	snapshots := 0
+	snapshot := func() *Metrics {
+		mu.Lock()
+		defer mu.Unlock()
+		snapshots++
+		if snapshots == 2 {
+			metrics.UpdateNetworkMetrics(500, 500, 5, 5)
+		}
+		return metrics.Clone()
+	}
+	connections := func() []TalkerStats {
+		return []TalkerStats{
+			{RemoteAddr: "10.0.0.2:4000", BytesSent: 100, BytesReceived: 100},
+			{RemoteAddr: "10.0.0.3:4000", BytesSent: 5000, BytesReceived: 0},
+			{RemoteAddr: "10.0.0.4:4000", BytesSent: 300, BytesReceived: 700},
+		}
+	}
+
+	emitter := NewSummaryEmitter(SummaryConfig{Interval: 20 * time.Millisecond, TopTalkers: 2},
+		snapshot, connections, zap.New(core))
+	emitter.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for logs.FilterMessage("Connection summary").Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	emitter.Stop()
+
+	entries := logs.FilterMessage("Connection summary").All()
+	if len(entries) == 0 {
+		t.Fatal("Expected a connection summary to be emitted")
+	}
+	fields := entries[0].ContextMap()
+
+	if fields["active_connections"] != int64(3) {
+		t.Errorf("Expected 3 active connections, got %v", fields["active_connections"])
+	}
+	if fields["bytes_in"] != int64(1500) || fields["bytes_out"] != int64(2500) {
+		t.Errorf("Expected 1500/2500 bytes, got %v/%v", fields["bytes_in"], fields["bytes_out"])
+	}
+	if rate, ok := fields["throughput_in_bps"].(float64); !ok || rate <= 0 {
+		t.Errorf("Expected positive inbound throughput, got %v", fields["throughput_in_bps"])
+	}
+	if _, ok := fields["throughput_out_bps"].(float64); !ok {
+		t.Errorf("Expected outbound throughput, got %v", fields["throughput_out_bps"])
+	}
+
+	talkers, ok := fields["top_talkers"].([]interface{})
+	if !ok || len(talkers) != 2 {
+		t.Fatalf("Expected 2 top talkers, got %v", fields["top_talkers"])
+	}
+	first := talkers[0].(map[string]interface{})
+	second := talkers[1].(map[string]interface{})
+	if first["remote_addr"] != "10.0.0.3:4000" || second["remote_addr"] != "10.0.0.4:4000" {
+		t.Errorf("Expected top talkers 10.0.0.3 and 10.0.0.4, got %v and %v", first["remote_addr"], second["remote_addr"])
+	}
+}
+
+func TestSummaryConfigFromDisabled(t *testing.T) {
+	cfg := SummaryConfigFrom(types.SummaryConfig{Enabled: false, Interval: time.Minute})
+	if cfg.Interval != 0 {
+		t.Errorf("Expected disabled summary to have no interval, got %v", cfg.Interval)
+	}
+}
@@ -0,0 +1,17 @@
+package monitor
+
+// ThrottleStats holds the configured rate limit and how often it has dropped traffic
+type ThrottleStats struct {
+	Enabled bool
+	Rate    float64 // Configured sustained rate in bytes/sec
+	Burst   int64   // Configured burst in bytes
+	Drops   int64   // Connections rejected by the connection rate limit
+}
+
+// UpdateThrottleMetrics records a snapshot of rate limiting state
+func (m *Monitor) UpdateThrottleMetrics(stats ThrottleStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.Throttle = stats
+}
@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+)
+
+// TLSStats counts connections by the TLS parameters they negotiated
+type TLSStats struct {
+	Versions     map[string]int64 // connections per TLS version, e.g. "TLS 1.3"
+	CipherSuites map[string]int64 // connections per cipher suite name
+}
+
+// Record counts a connection that negotiated the given version and cipher suite
+func (s *TLSStats) Record(version, cipherSuite string) {
+	if s.Versions == nil {
+		s.Versions = make(map[string]int64)
+	}
+	if s.CipherSuites == nil {
+		s.CipherSuites = make(map[string]int64)
+	}
+	s.Versions[version]++
+	s.CipherSuites[cipherSuite]++
+}
+
+// Clone creates a deep copy of the TLS stats
+func (s TLSStats) Clone() TLSStats {
+	return TLSStats{
+		Versions:     copyCounts(s.Versions),
+		CipherSuites: copyCounts(s.CipherSuites),
+	}
+}
+
+// UpdateTLSMetrics records a snapshot of negotiated TLS parameter counts
+func (m *Monitor) UpdateTLSMetrics(stats TLSStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.TLS = stats.Clone()
+}
+
+// WritePrometheus writes negotiated TLS parameter counts in the Prometheus text exposition format
+func (s TLSStats) WritePrometheus(w io.Writer) error {
+	lines := []string{
+		"# HELP sssonector_tls_connections_by_version_total Connections per negotiated TLS version",
+		"# TYPE sssonector_tls_connections_by_version_total counter",
+	}
+	for _, name := range sortedKeys(s.Versions) {
+		lines = append(lines, fmt.Sprintf("sssonector_tls_connections_by_version_total{version=%q} %d", name, s.Versions[name]))
+	}
+
+	lines = append(lines,
+		"# HELP sssonector_tls_connections_by_cipher_total Connections per negotiated cipher suite",
+		"# TYPE sssonector_tls_connections_by_cipher_total counter",
+	)
+	for _, name := range sortedKeys(s.CipherSuites) {
+		lines = append(lines, fmt.Sprintf("sssonector_tls_connections_by_cipher_total{cipher=%q} %d", name, s.CipherSuites[name]))
+	}
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
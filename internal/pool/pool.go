@@ -7,15 +7,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
 	"go.uber.org/zap"
 )
 
 const (
-	defaultIdleTimeout   = 5 * time.Minute
-	defaultMaxIdle       = 100
-	defaultMaxActive     = 500
-	defaultRetryInterval = 5 * time.Second
-	defaultMaxRetries    = 3
+	defaultIdleTimeout         = 5 * time.Minute
+	defaultMaxIdle             = 100
+	defaultMaxActive           = 500
+	defaultRetryInterval       = 5 * time.Second
+	defaultMaxRetries          = 3
+	defaultHealthCheckInterval = 30 * time.Second
 )
 
 // Config holds pool configuration
@@ -25,6 +27,16 @@ type Config struct {
 	MaxActive     int
 	RetryInterval time.Duration
 	MaxRetries    int
+
+	// MinIdle is the number of idle connections the health checker keeps open
+	// once the pool is warmed
+	MinIdle int
+	// WaitTimeout is how long Get blocks for a connection to be returned when
+	// MaxActive connections are in use; zero fails with ErrPoolExhausted at once
+	WaitTimeout time.Duration
+	// HealthCheckInterval is how often idle and active connections are checked
+	// and idle connections past IdleTimeout are evicted
+	HealthCheckInterval time.Duration
 }
 
 // DefaultConfig returns default pool configuration
@@ -35,6 +47,8 @@ func DefaultConfig() *Config {
 		MaxActive:     defaultMaxActive,
 		RetryInterval: defaultRetryInterval,
 		MaxRetries:    defaultMaxRetries,
+
+		HealthCheckInterval: defaultHealthCheckInterval,
 	}
 }
 
@@ -49,10 +63,21 @@ type Pool struct {
 
 	mu          sync.Mutex
 	closed      bool
+	warm        bool
 	active      int
 	idle        []*poolConn
 	activeConns map[*poolConn]struct{}
 
+	// released is closed and replaced whenever an active slot frees up, waking
+	// callers blocked in Get
+	released chan struct{}
+
+	// Statistics, guarded by mu
+	created   int64
+	evicted   int64
+	waitCount int64
+	waitTime  time.Duration
+
 	// Health check
 	healthCheck chan struct{}
 }
@@ -66,17 +91,32 @@ type poolConn struct {
 	failCount int
 }
 
-// NewPool creates a new connection pool
+// NewPool creates a new connection pool. Zero sizes, timeouts and intervals in
+// cfg take their defaults.
 func NewPool(factory Factory, cfg *Config, logger *zap.Logger) *Pool {
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
+	config := *cfg
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultIdleTimeout
+	}
+	if config.MaxIdle <= 0 {
+		config.MaxIdle = defaultMaxIdle
+	}
+	if config.MaxActive <= 0 {
+		config.MaxActive = defaultMaxActive
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultRetryInterval
+	}
 
 	p := &Pool{
 		factory:     factory,
-		config:      cfg,
+		config:      &config,
 		logger:      logger,
 		activeConns: make(map[*poolConn]struct{}),
+		released:    make(chan struct{}),
 		healthCheck: make(chan struct{}, 1),
 	}
 
@@ -86,59 +126,88 @@ func NewPool(factory Factory, cfg *Config, logger *zap.Logger) *Pool {
 	return p
 }
 
-// Get gets a connection from the pool
+// Get gets a connection from the pool, reusing a healthy idle connection when
+// there is one. When MaxActive connections are in use it waits up to
+// WaitTimeout for one to be returned before failing with ErrPoolExhausted.
 func (p *Pool) Get(ctx context.Context) (net.Conn, error) {
+	var deadline <-chan time.Time
+	var waitStart time.Time
+
 	p.mu.Lock()
-	if p.closed {
-		p.mu.Unlock()
-		return nil, ErrPoolClosed
-	}
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		// Check for idle connections
+		for i := len(p.idle) - 1; i >= 0; i-- {
+			conn := p.idle[i]
+			p.idle = p.idle[:i]
 
-	// Check for idle connections
-	for i := len(p.idle) - 1; i >= 0; i-- {
-		conn := p.idle[i]
-		p.idle = p.idle[:i]
+			// Remove stale connection
+			if time.Since(conn.timeUsed) > p.config.IdleTimeout {
+				p.evict(conn)
+				continue
+			}
 
-		// Remove stale connection
-		if time.Since(conn.timeUsed) > p.config.IdleTimeout {
-			conn.Conn.Close()
-			continue
+			// Verify connection is still alive
+			if err := p.testConnection(conn.Conn); err != nil {
+				p.evict(conn)
+				continue
+			}
+
+			p.active++
+			p.activeConns[conn] = struct{}{}
+			p.recordWait(waitStart)
+			p.mu.Unlock()
+			return conn, nil
 		}
 
-		// Verify connection is still alive
-		if err := p.testConnection(conn.Conn); err != nil {
-			conn.Conn.Close()
-			continue
+		if p.active < p.config.MaxActive {
+			break
 		}
 
-		p.active++
-		p.activeConns[conn] = struct{}{}
+		// Wait for a connection to be returned
+		if p.config.WaitTimeout <= 0 {
+			p.mu.Unlock()
+			return nil, ErrPoolExhausted
+		}
+		if deadline == nil {
+			waitStart = time.Now()
+			timer := time.NewTimer(p.config.WaitTimeout)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		released := p.released
 		p.mu.Unlock()
-		return conn, nil
-	}
 
-	// Check if we can create new connection
-	if p.active >= p.config.MaxActive {
-		p.mu.Unlock()
-		return nil, ErrPoolExhausted
+		select {
+		case <-released:
+		case <-deadline:
+			p.mu.Lock()
+			p.recordWait(waitStart)
+			p.mu.Unlock()
+			return nil, ErrPoolExhausted
+		case <-ctx.Done():
+			p.mu.Lock()
+			p.recordWait(waitStart)
+			p.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		p.mu.Lock()
 	}
 
 	// Create new connection
 	p.active++
+	p.recordWait(waitStart)
 	p.mu.Unlock()
 
-	var conn net.Conn
-	var err error
-	for i := 0; i < p.config.MaxRetries; i++ {
-		conn, err = p.factory(ctx)
-		if err == nil {
-			break
-		}
-		time.Sleep(p.config.RetryInterval)
-	}
+	conn, err := p.dial(ctx)
 	if err != nil {
 		p.mu.Lock()
 		p.active--
+		p.release()
 		p.mu.Unlock()
 		return nil, err
 	}
@@ -151,12 +220,60 @@ func (p *Pool) Get(ctx context.Context) (net.Conn, error) {
 	}
 
 	p.mu.Lock()
+	p.created++
 	p.activeConns[pc] = struct{}{}
 	p.mu.Unlock()
 
 	return pc, nil
 }
 
+// dial creates a connection with the factory, making at least one attempt and
+// up to MaxRetries. An error marked as not retryable is returned at once.
+func (p *Pool) dial(ctx context.Context) (net.Conn, error) {
+	attempts := p.config.MaxRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var conn net.Conn
+	var err error
+	for i := 0; i < attempts; i++ {
+		conn, err = p.factory(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		if retryable, ok := resilience.IsRetryable(err); ok && !retryable {
+			return nil, err
+		}
+		if i < attempts-1 {
+			time.Sleep(p.config.RetryInterval)
+		}
+	}
+	return nil, err
+}
+
+// evict closes a connection dropped by the pool; the caller holds mu
+func (p *Pool) evict(conn *poolConn) {
+	conn.Conn.Close()
+	p.evicted++
+}
+
+// release wakes callers waiting for an active slot; the caller holds mu
+func (p *Pool) release() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// recordWait adds a wait that began at start to the statistics; the caller
+// holds mu. A zero start means the caller did not wait.
+func (p *Pool) recordWait(start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	p.waitCount++
+	p.waitTime += time.Since(start)
+}
+
 // Put returns a connection to the pool
 func (p *Pool) Put(conn net.Conn) error {
 	pc, ok := conn.(*poolConn)
@@ -174,10 +291,11 @@ func (p *Pool) Put(conn net.Conn) error {
 
 	delete(p.activeConns, pc)
 	p.active--
+	p.release()
 
 	// Don't cache connections that have failed too many times
 	if pc.failCount > p.config.MaxRetries {
-		conn.Close()
+		p.evict(pc)
 		return nil
 	}
 
@@ -214,6 +332,7 @@ func (p *Pool) Close() error {
 	p.activeConns = nil
 
 	close(p.healthCheck)
+	p.release()
 	p.mu.Unlock()
 	return nil
 }
@@ -235,7 +354,11 @@ func (p *Pool) testConnection(conn net.Conn) error {
 
 // healthChecker periodically checks connection health
 func (p *Pool) healthChecker() {
-	ticker := time.NewTicker(30 * time.Second)
+	interval := p.config.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -251,11 +374,11 @@ func (p *Pool) healthChecker() {
 			var remaining []*poolConn
 			for _, conn := range p.idle {
 				if time.Since(conn.timeUsed) > p.config.IdleTimeout {
-					conn.Conn.Close()
+					p.evict(conn)
 					continue
 				}
 				if err := p.testConnection(conn.Conn); err != nil {
-					conn.Conn.Close()
+					p.evict(conn)
 					continue
 				}
 				remaining = append(remaining, conn)
@@ -269,32 +392,89 @@ func (p *Pool) healthChecker() {
 					if conn.failCount > p.config.MaxRetries {
 						delete(p.activeConns, conn)
 						p.active--
-						conn.Conn.Close()
+						p.evict(conn)
+						p.release()
 					}
 				}
 			}
 			p.mu.Unlock()
 
+			p.fillIdle()
+
 		case <-p.healthCheck:
 			return
 		}
 	}
 }
 
+// Warm starts keeping MinIdle connections open in advance, opening them now.
+// Until it is called the pool only opens connections for Get, so a pool can be
+// created before the endpoint it dials is ready.
+func (p *Pool) Warm() {
+	p.mu.Lock()
+	p.warm = true
+	p.mu.Unlock()
+
+	go p.fillIdle()
+}
+
+// fillIdle opens connections until MinIdle are idle, within MaxIdle and
+// MaxActive, once the pool is warmed
+func (p *Pool) fillIdle() {
+	p.mu.Lock()
+	if !p.warm || p.closed {
+		p.mu.Unlock()
+		return
+	}
+	need := p.config.MinIdle - len(p.idle)
+	if room := p.config.MaxIdle - len(p.idle); need > room {
+		need = room
+	}
+	if room := p.config.MaxActive - p.active - len(p.idle); need > room {
+		need = room
+	}
+	p.mu.Unlock()
+
+	for i := 0; i < need; i++ {
+		conn, err := p.factory(context.Background())
+		if err != nil {
+			p.logger.Warn("Failed to open idle connection", zap.Error(err))
+			return
+		}
+
+		now := time.Now()
+		p.mu.Lock()
+		if p.closed || len(p.idle) >= p.config.MaxIdle {
+			p.mu.Unlock()
+			conn.Close()
+			return
+		}
+		p.created++
+		p.idle = append(p.idle, &poolConn{Conn: conn, pool: p, timeUsed: now, timeAdded: now})
+		p.mu.Unlock()
+	}
+}
+
 // Stats returns pool statistics
 type Stats struct {
-	ActiveCount int
-	IdleCount   int
-	WaitCount   int64
-	WaitTime    time.Duration
+	ActiveCount  int           `json:"active"`
+	IdleCount    int           `json:"idle"`
+	CreatedCount int64         `json:"created"` // Connections opened since the pool was created
+	EvictedCount int64         `json:"evicted"` // Connections closed as stale or unhealthy
+	WaitCount    int64         `json:"wait_count"`
+	WaitTime     time.Duration `json:"wait_time"`
 }
 
 // Stats returns current pool statistics
 func (p *Pool) Stats() Stats {
 	p.mu.Lock()
 	stats := Stats{
-		ActiveCount: p.active,
-		IdleCount:   len(p.idle),
+		ActiveCount:  p.active,
+		IdleCount:    len(p.idle),
+		CreatedCount: p.created,
+		EvictedCount: p.evicted,
+		WaitCount:    p.waitCount,
+		WaitTime:     p.waitTime,
 	}
 	p.mu.Unlock()
 	return stats
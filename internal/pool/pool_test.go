@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
 	"go.uber.org/zap"
 )
 
@@ -124,7 +125,8 @@ func TestPoolHealthCheck(t *testing.T) {
 	}
 
 	cfg := &Config{
-		IdleTimeout: 50 * time.Millisecond,
+		IdleTimeout:         50 * time.Millisecond,
+		HealthCheckInterval: 10 * time.Millisecond,
 	}
 
 	p := NewPool(factory, cfg, logger)
@@ -227,12 +229,29 @@ func TestPoolConnectionFailure(t *testing.T) {
 		t.Fatalf("Failed to get connection: %v", err)
 	}
 
-	// Put it back - should be discarded due to write error
+	// Put keeps it idle; the next Get finds the write error, discards it
+	// and dials a replacement
 	p.Put(conn)
+	if stats := p.Stats(); stats.IdleCount != 1 {
+		t.Fatalf("Expected the returned connection to be idle, got %d idle", stats.IdleCount)
+	}
+
+	replacement, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	defer p.Put(replacement)
 
+	if replacement == conn {
+		t.Error("Expected the failed connection not to be reused")
+	}
+	if !conn.(*poolConn).Conn.(*mockConn).closed {
+		t.Error("Expected the failed connection to be closed")
+	}
 	stats := p.Stats()
-	if stats.IdleCount != 0 {
-		t.Errorf("Expected 0 idle connections after failure, got %d", stats.IdleCount)
+	if stats.IdleCount != 0 || stats.EvictedCount != 1 || stats.CreatedCount != 2 {
+		t.Errorf("Expected 0 idle, 1 evicted and 2 created, got %d idle, %d evicted and %d created",
+			stats.IdleCount, stats.EvictedCount, stats.CreatedCount)
 	}
 }
 
@@ -280,3 +299,180 @@ func TestPoolConcurrency(t *testing.T) {
 		t.Errorf("Expected <= %d idle connections, got %d", cfg.MaxIdle, stats.IdleCount)
 	}
 }
+
+func TestPoolReuse(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	factory := func(ctx context.Context) (net.Conn, error) {
+		return &mockConn{}, nil
+	}
+
+	cfg := &Config{
+		IdleTimeout: time.Minute,
+		MaxIdle:     2,
+		MaxActive:   2,
+	}
+
+	p := NewPool(factory, cfg, logger)
+	defer p.Close()
+
+	conn1, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	p.Put(conn1)
+
+	conn2, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if conn2.(*poolConn).Conn != conn1.(*poolConn).Conn {
+		t.Error("Expected the idle connection to be reused")
+	}
+	p.Put(conn2)
+
+	stats := p.Stats()
+	if stats.CreatedCount != 1 {
+		t.Errorf("Expected 1 created connection, got %d", stats.CreatedCount)
+	}
+	if stats.IdleCount != 1 || stats.ActiveCount != 0 {
+		t.Errorf("Expected 1 idle and 0 active connections, got %d and %d", stats.IdleCount, stats.ActiveCount)
+	}
+}
+
+func TestPoolWaitsWhenSaturated(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	factory := func(ctx context.Context) (net.Conn, error) {
+		return &mockConn{}, nil
+	}
+
+	cfg := &Config{
+		IdleTimeout: time.Minute,
+		MaxIdle:     1,
+		MaxActive:   1,
+		WaitTimeout: 100 * time.Millisecond,
+	}
+
+	p := NewPool(factory, cfg, logger)
+	defer p.Close()
+
+	conn, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+
+	// With no connection returned, Get gives up after the wait timeout
+	start := time.Now()
+	if _, err := p.Get(context.Background()); err != ErrPoolExhausted {
+		t.Errorf("Expected pool exhausted error, got %v", err)
+	}
+	if waited := time.Since(start); waited < cfg.WaitTimeout {
+		t.Errorf("Expected Get to block for %v, returned after %v", cfg.WaitTimeout, waited)
+	}
+
+	// A connection returned while waiting is handed to the waiter
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		p.Put(conn)
+	}()
+	conn, err = p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection after put: %v", err)
+	}
+	p.Put(conn)
+
+	stats := p.Stats()
+	if stats.WaitCount != 2 || stats.WaitTime < cfg.WaitTimeout {
+		t.Errorf("Expected 2 waits of at least %v in total, got %d in %v", cfg.WaitTimeout, stats.WaitCount, stats.WaitTime)
+	}
+	if stats.CreatedCount != 1 {
+		t.Errorf("Expected 1 created connection, got %d", stats.CreatedCount)
+	}
+}
+
+func TestPoolEvictsIdleConnections(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	factory := func(ctx context.Context) (net.Conn, error) {
+		return &mockConn{}, nil
+	}
+
+	cfg := &Config{
+		IdleTimeout:         30 * time.Millisecond,
+		MaxIdle:             2,
+		MaxActive:           2,
+		HealthCheckInterval: 10 * time.Millisecond,
+	}
+
+	p := NewPool(factory, cfg, logger)
+	defer p.Close()
+
+	conn, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	p.Put(conn)
+
+	deadline := time.Now().Add(time.Second)
+	for p.Stats().EvictedCount == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := p.Stats()
+	if stats.EvictedCount != 1 || stats.IdleCount != 0 {
+		t.Fatalf("Expected the idle connection to be evicted, got %d evicted and %d idle", stats.EvictedCount, stats.IdleCount)
+	}
+	if !conn.(*poolConn).Conn.(*mockConn).closed {
+		t.Error("Evicted connection not closed")
+	}
+}
+
+func TestPoolMinIdle(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	factory := func(ctx context.Context) (net.Conn, error) {
+		return &mockConn{}, nil
+	}
+
+	cfg := &Config{
+		IdleTimeout:         time.Minute,
+		MinIdle:             2,
+		MaxIdle:             4,
+		MaxActive:           4,
+		HealthCheckInterval: 10 * time.Millisecond,
+	}
+
+	p := NewPool(factory, cfg, logger)
+	defer p.Close()
+
+	// Nothing is opened in advance until the pool is warmed
+	time.Sleep(5 * cfg.HealthCheckInterval)
+	if created := p.Stats().CreatedCount; created != 0 {
+		t.Fatalf("Expected no connections opened before Warm, got %d", created)
+	}
+
+	p.Warm()
+	deadline := time.Now().Add(time.Second)
+	for p.Stats().IdleCount < cfg.MinIdle && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := p.Stats(); stats.IdleCount != cfg.MinIdle || stats.CreatedCount != int64(cfg.MinIdle) {
+		t.Errorf("Expected %d idle connections opened in advance, got %d idle and %d created", cfg.MinIdle, stats.IdleCount, stats.CreatedCount)
+	}
+}
+
+func TestPoolDoesNotRetryPermanentErrors(t *testing.T) {
+	attempts := 0
+	permanent := resilience.MarkRetryable(errors.New("bad credential"), false)
+	factory := func(ctx context.Context) (net.Conn, error) {
+		attempts++
+		return nil, permanent
+	}
+
+	p := NewPool(factory, &Config{MaxActive: 1, RetryInterval: time.Millisecond, MaxRetries: 3}, zap.NewNop())
+	defer p.Close()
+
+	if _, err := p.Get(context.Background()); !errors.Is(err, permanent) {
+		t.Fatalf("Expected the permanent error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+}
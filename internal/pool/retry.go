@@ -67,59 +67,81 @@ func NewRetryManager(factory Factory, config *RetryConfig, logger *zap.Logger) *
 	}
 }
 
-// GetConnection attempts to get a connection using the three-phase retry strategy
+// GetConnection makes a connection attempt and, when it fails, retries using
+// the three-phase retry strategy. It stops with ctx's error once ctx ends.
 func (r *RetryManager) GetConnection(ctx context.Context) (net.Conn, error) {
 	atomic.AddInt64(&r.metrics.attempts, 1)
 
-	// Phase 1: Immediate retries
-	conn, err := r.immediateRetries(ctx)
+	conn, err := r.retry(ctx)
+	if err != nil {
+		atomic.AddInt64(&r.metrics.failures, 1)
+		return nil, err
+	}
+	atomic.AddInt64(&r.metrics.successes, 1)
+	return conn, nil
+}
+
+// retry runs the first attempt and the retry phases in order
+func (r *RetryManager) retry(ctx context.Context) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := r.factory(ctx)
 	if err == nil {
-		atomic.AddInt64(&r.metrics.successes, 1)
 		return conn, nil
 	}
+	r.logger.Warn("Connection attempt failed", zap.Error(err))
+
+	// Phase 1: Immediate retries
+	if conn, err := r.immediateRetries(ctx); err == nil || ctx.Err() != nil {
+		return conn, err
+	}
 
 	// Phase 2: Gradual retries with exponential backoff
-	conn, err = r.gradualRetries(ctx)
-	if err == nil {
-		atomic.AddInt64(&r.metrics.successes, 1)
-		return conn, nil
+	if conn, err := r.gradualRetries(ctx); err == nil || ctx.Err() != nil {
+		return conn, err
 	}
 
 	// Phase 3: Persistent retries
 	if r.config.PersistentEnabled {
-		conn, err = r.persistentRetries(ctx)
-		if err == nil {
-			atomic.AddInt64(&r.metrics.successes, 1)
-			return conn, nil
-		}
+		return r.persistentRetries(ctx)
 	}
 
-	atomic.AddInt64(&r.metrics.failures, 1)
 	return nil, ErrMaxRetriesExceeded
 }
 
+// sleep waits for d or until ctx ends, returning ctx's error in that case
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // immediateRetries handles the immediate retry phase
 func (r *RetryManager) immediateRetries(ctx context.Context) (net.Conn, error) {
 	for i := 0; i < r.config.ImmediateAttempts; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			conn, err := r.factory(ctx)
-			if err == nil {
-				r.logger.Info("Connection established during immediate retry phase",
-					zap.Int("attempt", i+1),
-				)
-				return conn, nil
-			}
-
-			r.logger.Warn("Connection attempt failed during immediate retry phase",
+		if err := sleep(ctx, r.config.ImmediateInterval); err != nil {
+			return nil, err
+		}
+
+		conn, err := r.factory(ctx)
+		if err == nil {
+			r.logger.Info("Connection established during immediate retry phase",
 				zap.Int("attempt", i+1),
-				zap.Error(err),
 			)
-
-			time.Sleep(r.config.ImmediateInterval)
+			return conn, nil
 		}
+
+		r.logger.Warn("Connection attempt failed during immediate retry phase",
+			zap.Int("attempt", i+1),
+			zap.Error(err),
+		)
 	}
 
 	return nil, ErrMaxRetriesExceeded
@@ -130,29 +152,27 @@ func (r *RetryManager) gradualRetries(ctx context.Context) (net.Conn, error) {
 	interval := r.config.GradualInterval
 
 	for i := 0; i < r.config.GradualAttempts; i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			conn, err := r.factory(ctx)
-			if err == nil {
-				r.logger.Info("Connection established during gradual retry phase",
-					zap.Int("attempt", i+1),
-				)
-				return conn, nil
-			}
-
-			r.logger.Warn("Connection attempt failed during gradual retry phase",
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		conn, err := r.factory(ctx)
+		if err == nil {
+			r.logger.Info("Connection established during gradual retry phase",
 				zap.Int("attempt", i+1),
-				zap.Duration("interval", interval),
-				zap.Error(err),
 			)
+			return conn, nil
+		}
+
+		r.logger.Warn("Connection attempt failed during gradual retry phase",
+			zap.Int("attempt", i+1),
+			zap.Duration("interval", interval),
+			zap.Error(err),
+		)
 
-			time.Sleep(interval)
-			interval = time.Duration(float64(interval) * 2)
-			if interval > r.config.MaxGradualInterval {
-				interval = r.config.MaxGradualInterval
-			}
+		interval = time.Duration(float64(interval) * 2)
+		if interval > r.config.MaxGradualInterval {
+			interval = r.config.MaxGradualInterval
 		}
 	}
 
@@ -161,28 +181,23 @@ func (r *RetryManager) gradualRetries(ctx context.Context) (net.Conn, error) {
 
 // persistentRetries handles the persistent retry phase
 func (r *RetryManager) persistentRetries(ctx context.Context) (net.Conn, error) {
-	attempt := 0
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			attempt++
-			conn, err := r.factory(ctx)
-			if err == nil {
-				r.logger.Info("Connection established during persistent retry phase",
-					zap.Int("attempt", attempt),
-				)
-				return conn, nil
-			}
-
-			r.logger.Warn("Connection attempt failed during persistent retry phase",
+	for attempt := 1; ; attempt++ {
+		if err := sleep(ctx, r.config.PersistentInterval); err != nil {
+			return nil, err
+		}
+
+		conn, err := r.factory(ctx)
+		if err == nil {
+			r.logger.Info("Connection established during persistent retry phase",
 				zap.Int("attempt", attempt),
-				zap.Error(err),
 			)
-
-			time.Sleep(r.config.PersistentInterval)
+			return conn, nil
 		}
+
+		r.logger.Warn("Connection attempt failed during persistent retry phase",
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
 	}
 }
 
@@ -97,6 +97,21 @@ func getDefaultBackoffConfig() *BackoffConfig {
 	}
 }
 
+// Jitter moves d up or down by a random amount of at most factor*d, so that
+// timers started together drift apart. The result is never negative.
+func Jitter(d time.Duration, factor float64, r *rand.Rand) time.Duration {
+	jitter := time.Duration(float64(d) * factor * r.Float64())
+	if r.Int()%2 == 0 {
+		d += jitter
+	} else {
+		d -= jitter
+	}
+	if d < 0 {
+		d = -d
+	}
+	return d
+}
+
 // ShouldRetry determines if a retry should be attempted
 func (b *ExponentialBackoff) ShouldRetry() bool {
 	return atomic.LoadInt32(&b.retryCount) < int32(b.config.MaxRetries)
@@ -111,16 +126,7 @@ func (b *ExponentialBackoff) GetNextDelay() time.Duration {
 
 	// Add jitter to prevent thundering herd
 	if b.config.EnableJitter && b.config.JitterFactor > 0 {
-		jitter := time.Duration(float64(delay) * b.config.JitterFactor * b.rand.Float64())
-		if b.rand.Int()%2 == 0 {
-			delay += jitter
-		} else {
-			delay -= jitter
-		}
-		// Ensure delay remains positive and within bounds
-		if delay < 0 {
-			delay = -delay
-		}
+		delay = Jitter(delay, b.config.JitterFactor, b.rand)
 		if delay > b.config.MaxDelay {
 			delay = b.config.MaxDelay
 		}
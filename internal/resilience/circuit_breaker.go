@@ -44,6 +44,7 @@ type CircuitBreaker struct {
 	requests            uint64 // Total requests
 	successes           uint64 // Total successes
 	failures            uint64 // Total failures
+	transitions         uint64 // State transitions
 	lastStateTransition time.Time
 
 	// Half-open state tracking
@@ -139,6 +140,11 @@ func (cb *CircuitBreaker) CanExecute() bool {
 	return true
 }
 
+// Name returns the configured name of the circuit breaker
+func (cb *CircuitBreaker) Name() string {
+	return cb.config.Name
+}
+
 // GetState returns the current circuit breaker state
 func (cb *CircuitBreaker) GetState() CircuitBreakerState {
 	return CircuitBreakerState(atomic.LoadInt32(&cb.state))
@@ -154,6 +160,7 @@ func (cb *CircuitBreaker) GetStats() CircuitBreakerStats {
 		TotalRequests:       atomic.LoadUint64(&cb.requests),
 		TotalSuccesses:      atomic.LoadUint64(&cb.successes),
 		TotalFailures:       atomic.LoadUint64(&cb.failures),
+		Transitions:         atomic.LoadUint64(&cb.transitions),
 		Buckets:             cb.halfOpenRequests, // Simplified
 		SuccessBuckets:      cb.halfOpenSuccesses,
 		LastStateTransition: cb.lastStateTransition,
@@ -166,6 +173,7 @@ type CircuitBreakerStats struct {
 	TotalRequests       uint64
 	TotalSuccesses      uint64
 	TotalFailures       uint64
+	Transitions         uint64
 	Buckets             int
 	SuccessBuckets      int
 	LastStateTransition time.Time
@@ -242,6 +250,7 @@ func (cb *CircuitBreaker) updateState(err error) {
 func (cb *CircuitBreaker) transitionToState(newState CircuitBreakerState) {
 	oldState := CircuitBreakerState(atomic.LoadInt32(&cb.state))
 	atomic.StoreInt32(&cb.state, int32(newState))
+	atomic.AddUint64(&cb.transitions, 1)
 
 	cb.lastStateTransition = time.Now()
 
@@ -290,6 +299,7 @@ func (cb *CircuitBreaker) Reset() {
 	atomic.StoreUint64(&cb.requests, 0)
 	atomic.StoreUint64(&cb.successes, 0)
 	atomic.StoreUint64(&cb.failures, 0)
+	atomic.StoreUint64(&cb.transitions, 0)
 	cb.halfOpenRequests = 0
 	cb.halfOpenSuccesses = 0
 	cb.lastStateTransition = time.Now()
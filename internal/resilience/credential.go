@@ -1,4 +0,0 @@
-name:
-"o3willard-AI/SSSonector"
-description:
-"SSSonector is a secure SOCKS5 proxy with custom authentication built in Go, providing crypto-aware tunneling, IP filtering, connection pooling, and performance monitoring."
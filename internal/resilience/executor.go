@@ -0,0 +1,195 @@
+package resilience
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// ExecutorConfig configures a ResilientExecutor. Each part is optional.
+type ExecutorConfig struct {
+	Name string
+
+	// Breaker guards every attempt; an open breaker rejects the attempt
+	// without running the operation
+	Breaker *CircuitBreaker
+
+	// Backoff is the retry policy: MaxRetries retries of a retryable error,
+	// waiting between them. Nil runs the operation once.
+	Backoff *BackoffConfig
+
+	// Recovery is tried when the breaker rejects an attempt or the retries
+	// run out; a successful recovery starts the retries afresh
+	Recovery *ErrorRecovery
+
+	// MaxRecoveries is the most recoveries per Execute (default: 1)
+	MaxRecoveries int
+
+	// ErrorClassifier decides whether an error is retried (default:
+	// ClassifyRetryable, retrying unknown errors). Errors it fails are
+	// returned at once, without recovery.
+	ErrorClassifier func(error) RetryAction
+}
+
+// ResilientExecutor runs operations through a circuit breaker, a retry policy
+// and error recovery, in that order
+type ResilientExecutor struct {
+	config *ExecutorConfig
+	logger *zap.Logger
+	stats  ExecutorStats
+}
+
+// ExecutorStats counts what a ResilientExecutor did. Rejections by the breaker
+// are not failures of the operation.
+type ExecutorStats struct {
+	Executions       int64 // Calls to Execute
+	Successes        int64 // Executions that succeeded
+	Attempts         int64 // Times the operation ran
+	Failures         int64 // Times the operation returned an error
+	Rejections       int64 // Attempts the open breaker refused
+	Retries          int64 // Attempts repeated after a retryable error
+	Recoveries       int64 // Successful recoveries
+	FailedRecoveries int64 // Recoveries that failed, ending the execution
+
+	Breaker  CircuitBreakerStats // Zero without a breaker
+	Recovery RecoveryMetrics     // Zero without recovery
+}
+
+// NewResilientExecutor creates an executor from config
+func NewResilientExecutor(config *ExecutorConfig, logger *zap.Logger) *ResilientExecutor {
+	if config == nil {
+		config = &ExecutorConfig{}
+	}
+	if config.MaxRecoveries <= 0 {
+		config.MaxRecoveries = 1
+	}
+	if config.ErrorClassifier == nil {
+		config.ErrorClassifier = defaultErrorClassifier
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ResilientExecutor{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Execute runs fn until it succeeds or the executor gives up, returning the
+// last error. Each attempt first checks the breaker; an error fn returns is
+// classified and retried with backoff while retries remain. A rejection by
+// the breaker, or running out of retries, goes to recovery, after which fn is
+// tried again.
+func (e *ResilientExecutor) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	atomic.AddInt64(&e.stats.Executions, 1)
+
+	backoff := e.newBackoff()
+	recoveries := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ran, err := e.attempt(ctx, fn)
+		if err == nil {
+			atomic.AddInt64(&e.stats.Successes, 1)
+			return nil
+		}
+
+		if !ran {
+			// The open breaker refused the attempt; retrying now fails the same
+			// way, so only recovery can help
+			atomic.AddInt64(&e.stats.Rejections, 1)
+		} else {
+			atomic.AddInt64(&e.stats.Failures, 1)
+			if ctx.Err() != nil {
+				return err
+			}
+
+			switch e.config.ErrorClassifier(err) {
+			case ActionFail, ActionSkip:
+				return err
+			}
+
+			if backoff != nil && backoff.ShouldRetry() {
+				e.logger.Debug("Retrying operation",
+					zap.String("name", e.config.Name),
+					zap.Int("retry", backoff.GetRetryCount()+1),
+					zap.Error(err))
+				if waitErr := backoff.Wait(ctx); waitErr != nil {
+					return err
+				}
+				atomic.AddInt64(&e.stats.Retries, 1)
+				continue
+			}
+		}
+
+		if e.config.Recovery == nil || recoveries >= e.config.MaxRecoveries {
+			return err
+		}
+		recoveries++
+		if recoveryErr := e.config.Recovery.Recover(ctx, err); recoveryErr != nil {
+			atomic.AddInt64(&e.stats.FailedRecoveries, 1)
+			return recoveryErr
+		}
+		atomic.AddInt64(&e.stats.Recoveries, 1)
+		e.logger.Info("Recovered operation, trying again",
+			zap.String("name", e.config.Name),
+			zap.Error(err))
+
+		// The recovered operation gets its retries afresh
+		backoff = e.newBackoff()
+	}
+}
+
+// attempt runs fn once through the breaker, reporting whether fn ran, so that
+// an ErrCircuitOpen from fn itself, e.g. from a nested breaker, is a failure
+// rather than a rejection
+func (e *ResilientExecutor) attempt(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	ran := false
+	run := func(ctx context.Context) error {
+		ran = true
+		atomic.AddInt64(&e.stats.Attempts, 1)
+		return fn(ctx)
+	}
+
+	if e.config.Breaker == nil {
+		return true, run(ctx)
+	}
+	err := e.config.Breaker.Call(ctx, run)
+	return ran, err
+}
+
+// newBackoff returns the retry state of one execution, nil without a retry
+// policy
+func (e *ResilientExecutor) newBackoff() *ExponentialBackoff {
+	if e.config.Backoff == nil {
+		return nil
+	}
+	cfg := *e.config.Backoff
+	return NewExponentialBackoff(&cfg, e.logger)
+}
+
+// Stats returns the executor's counters together with the statistics of its
+// breaker and recovery
+func (e *ResilientExecutor) Stats() ExecutorStats {
+	stats := ExecutorStats{
+		Executions:       atomic.LoadInt64(&e.stats.Executions),
+		Successes:        atomic.LoadInt64(&e.stats.Successes),
+		Attempts:         atomic.LoadInt64(&e.stats.Attempts),
+		Failures:         atomic.LoadInt64(&e.stats.Failures),
+		Rejections:       atomic.LoadInt64(&e.stats.Rejections),
+		Retries:          atomic.LoadInt64(&e.stats.Retries),
+		Recoveries:       atomic.LoadInt64(&e.stats.Recoveries),
+		FailedRecoveries: atomic.LoadInt64(&e.stats.FailedRecoveries),
+	}
+	if e.config.Breaker != nil {
+		stats.Breaker = e.config.Breaker.GetStats()
+	}
+	if e.config.Recovery != nil {
+		stats.Recovery = e.config.Recovery.Snapshot()
+	}
+	return stats
+}
@@ -0,0 +1,134 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitStrategy recovers from an open breaker by waiting out its recovery timeout
+type waitStrategy struct {
+	delay time.Duration
+}
+
+func (s *waitStrategy) Name() string              { return "wait" }
+func (s *waitStrategy) CanRecover(err error) bool { return errors.Is(err, ErrCircuitOpen) }
+func (s *waitStrategy) Recover(ctx context.Context, err error, attempt int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.delay):
+		return nil
+	}
+}
+func (s *waitStrategy) Configure(config map[string]interface{}) error { return nil }
+
+func TestResilientExecutorRecoversOpenBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		Name:             "executor",
+		FailureThreshold: 0.5,
+		RecoveryTimeout:  50 * time.Millisecond,
+		SuccessThreshold: 1,
+		MinRequests:      10,
+	}, nil)
+	recovery, err := NewErrorRecovery(&RecoveryConfig{
+		Strategies: map[string]RecoveryStrategy{"wait": &waitStrategy{delay: 60 * time.Millisecond}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create error recovery: %v", err)
+	}
+	defer recovery.Stop()
+
+	executor := NewResilientExecutor(&ExecutorConfig{
+		Name:     "executor",
+		Breaker:  breaker,
+		Backoff:  &BackoffConfig{Strategy: StrategyFixed, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxRetries: 3},
+		Recovery: recovery,
+	}, nil)
+
+	breaker.ForceOpen()
+	calls := 0
+	err = executor.Execute(context.Background(), func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the execution to succeed after recovery, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the operation to run once, after recovery, got %d", calls)
+	}
+
+	stats := executor.Stats()
+	if stats.Rejections != 1 {
+		t.Errorf("Expected 1 rejection, got %d", stats.Rejections)
+	}
+	if stats.Failures != 0 {
+		t.Errorf("Expected the rejection not to count as a failure, got %d failures", stats.Failures)
+	}
+	if stats.Retries != 0 {
+		t.Errorf("Expected the rejection not to be retried, got %d retries", stats.Retries)
+	}
+	if stats.Recoveries != 1 || stats.Recovery.SuccessfulRecoveries != 1 {
+		t.Errorf("Expected 1 recovery, got %d and %d", stats.Recoveries, stats.Recovery.SuccessfulRecoveries)
+	}
+	if stats.Successes != 1 {
+		t.Errorf("Expected 1 success, got %d", stats.Successes)
+	}
+	if stats.Breaker.State != StateClosed {
+		t.Errorf("Expected the breaker to close after the success, got state %d", stats.Breaker.State)
+	}
+}
+
+func TestResilientExecutorRetries(t *testing.T) {
+	backoff := &BackoffConfig{Strategy: StrategyFixed, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxRetries: 2}
+
+	t.Run("retryable", func(t *testing.T) {
+		executor := NewResilientExecutor(&ExecutorConfig{Backoff: backoff}, nil)
+		calls := 0
+		err := executor.Execute(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return errors.New("temporary")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected the execution to succeed on retry, got %v", err)
+		}
+		if stats := executor.Stats(); stats.Failures != 2 || stats.Retries != 2 || stats.Attempts != 3 {
+			t.Errorf("Expected 2 failures, 2 retries and 3 attempts, got %+v", stats)
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		executor := NewResilientExecutor(&ExecutorConfig{Backoff: backoff}, nil)
+		permanent := MarkRetryable(errors.New("permanent"), false)
+		calls := 0
+		err := executor.Execute(context.Background(), func(ctx context.Context) error {
+			calls++
+			return permanent
+		})
+		if !errors.Is(err, permanent) {
+			t.Errorf("Expected the permanent error, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("Expected no retries, got %d calls", calls)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		executor := NewResilientExecutor(&ExecutorConfig{Backoff: backoff}, nil)
+		temporary := errors.New("temporary")
+		err := executor.Execute(context.Background(), func(ctx context.Context) error {
+			return temporary
+		})
+		if !errors.Is(err, temporary) {
+			t.Errorf("Expected the last error, got %v", err)
+		}
+		if stats := executor.Stats(); stats.Attempts != 3 {
+			t.Errorf("Expected 3 attempts, got %d", stats.Attempts)
+		}
+	})
+}
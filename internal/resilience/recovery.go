@@ -5,11 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"reflect"
+	"net"
 	"sync"
-	"sync/atomic"
+	"syscall"
 	"time"
 
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +21,12 @@ type ErrorRecovery struct {
 	strategies  map[string]RecoveryStrategy
 	observers   []RecoveryObserver
 	metrics     RecoveryMetrics
+	metricsMu   sync.Mutex
+	// timed counts the recoveries a strategy ran for, which RecoveryTime covers
+	timed int64
+
+	// slots bounds the recoveries in flight; nil when unlimited
+	slots chan struct{}
 
 	mu     sync.RWMutex
 	rand   *rand.Rand
@@ -72,6 +79,7 @@ type RecoveryMetrics struct {
 	TotalRecoveries      int64
 	SuccessfulRecoveries int64
 	FailedRecoveries     int64
+	ShedRecoveries       int64 // Not attempted because MaxConcurrent were in flight
 	RecoveryTime         time.Duration
 	AverageRecoveryTime  time.Duration
 	ErrorCategoryMetrics map[ErrorCategory]int64
@@ -85,6 +93,11 @@ type RecoveryConfig struct {
 	Observers   []RecoveryObserver
 	MaxRetries  int
 	Timeout     time.Duration
+
+	// MaxConcurrent is the most recoveries run at once; 0 is unlimited. Excess
+	// recoveries queue for a slot, or with ShedExcess return the original error.
+	MaxConcurrent int
+	ShedExcess    bool
 }
 
 // BuiltinRecoveryStrategies provides standard recovery strategies
@@ -261,22 +274,54 @@ func (s *NetworkRetryStrategy) Configure(config map[string]interface{}) error {
 		JitterFactor: 0.2,
 		EnableJitter: true,
 	}
-	s.backoffStrategy = NewExponentialBackoff(backoffCfg, zap.NewNop())
 
 	if delay, ok := config["retryDelay"].(time.Duration); ok {
 		s.retryDelay = delay
 	}
+	if baseDelay, ok := config["baseDelay"].(time.Duration); ok {
+		backoffCfg.BaseDelay = baseDelay
+	}
+	if maxDelay, ok := config["maxDelay"].(time.Duration); ok {
+		backoffCfg.MaxDelay = maxDelay
+	}
+	if maxRetries, ok := config["maxRetries"].(int); ok {
+		backoffCfg.MaxRetries = maxRetries
+	}
+
+	s.backoffStrategy = NewExponentialBackoff(backoffCfg, zap.NewNop())
 	return nil
 }
 
 // Common error classifiers
 
-// ClassifyCommonError provides common error classification
+// codeCategories maps the codes of coded errors to their recovery category
+var codeCategories = map[apperrors.Code]ErrorCategory{
+	apperrors.ErrConfigRead:      CategoryConfiguration,
+	apperrors.ErrConfigSyntax:    CategoryConfiguration,
+	apperrors.ErrConfigInvalid:   CategoryValidation,
+	apperrors.ErrConfigVersion:   CategoryConfiguration,
+	apperrors.ErrNetworkPort:     CategoryNetwork,
+	apperrors.ErrNetworkDial:     CategoryNetwork,
+	apperrors.ErrNetworkResolve:  CategoryNetwork,
+	apperrors.ErrNetworkLimited:  CategoryRateLimit,
+	apperrors.ErrTunnelInterface: CategoryNonRetryable,
+	apperrors.ErrTunnelHandshake: CategoryRetryable,
+}
+
+// resourceErrnos are the system errors reporting that memory, buffers or
+// descriptors ran out
+var resourceErrnos = []syscall.Errno{syscall.ENOMEM, syscall.ENOBUFS, syscall.EMFILE, syscall.ENFILE}
+
+// ClassifyCommonError provides common error classification. Exhausted system
+// resources are recognized anywhere in the chain, coded errors are classified
+// by their code and other errors by their type, never by message.
 func ClassifyCommonError(err error) ErrorCategory {
 	if err == nil {
 		return CategoryUnknown
 	}
 
+	var coded *apperrors.CodedError
+	var netErr net.Error
 	switch {
 	case errors.Is(err, context.Canceled):
 		return CategoryNonRetryable
@@ -284,39 +329,32 @@ func ClassifyCommonError(err error) ErrorCategory {
 		return CategoryTimeout
 	case errors.Is(err, ErrCircuitOpen):
 		return CategoryCircuitOpen
-	default:
-		// Check error message patterns
-		errStr := err.Error()
-		switch {
-		case matchError(errStr, []string{"connection refused", "no such host", "network is unreachable", "dial tcp"}):
-			return CategoryNetwork
-		case matchError(errStr, []string{"rate limit", "too many requests", "429"}):
-			return CategoryRateLimit
-		case matchError(errStr, []string{"resource exhausted", "out of memory", "insufficient resources"}):
-			return CategoryResourceExhaustion
-		case matchError(errStr, []string{"invalid", "validation", "malformed", "bad request"}):
-			return CategoryValidation
-		case matchError(errStr, []string{"configuration", "config", "settings"}):
-			return CategoryConfiguration
-		default:
-			return CategoryRecoverable
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return CategoryTimeout
+	case isResourceExhausted(err):
+		return CategoryResourceExhaustion
+	case errors.As(err, &coded):
+		if cat, ok := codeCategories[coded.Code]; ok {
+			return cat
 		}
+		return CategoryRecoverable
+	case errors.As(err, &netErr):
+		return CategoryNetwork
+	default:
+		return CategoryRecoverable
 	}
 }
 
-func matchError(errStr string, patterns []string) bool {
-	for _, pattern := range patterns {
-		if containsString(errStr, pattern) {
+// isResourceExhausted reports whether err is caused by one of resourceErrnos
+func isResourceExhausted(err error) bool {
+	for _, errno := range resourceErrnos {
+		if errors.Is(err, errno) {
 			return true
 		}
 	}
 	return false
 }
 
-func containsString(s, substr string) bool {
-	return len(s) >= len(substr) && reflect.ValueOf(s).String() == s[:len(s)-len(substr)+len(substr)]
-}
-
 // ErrorRecovery implementation
 
 // NewErrorRecovery creates a new error recovery system
@@ -332,6 +370,7 @@ func NewErrorRecovery(cfg *RecoveryConfig, logger *zap.Logger) (*ErrorRecovery,
 	}
 
 	er := &ErrorRecovery{
+		config:      cfg,
 		classifiers: make([]ErrorClassifier, 0),
 		strategies:  make(map[string]RecoveryStrategy),
 		observers:   cfg.Observers,
@@ -345,6 +384,10 @@ func NewErrorRecovery(cfg *RecoveryConfig, logger *zap.Logger) (*ErrorRecovery,
 		logger: logger,
 	}
 
+	if cfg.MaxConcurrent > 0 {
+		er.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+
 	// Add default classifiers
 	er.addDefaultClassifiers()
 
@@ -396,18 +439,21 @@ func (er *ErrorRecovery) AddClassifier(classifier ErrorClassifier) error {
 	return nil
 }
 
+// CanRecover reports whether a configured strategy can recover from err
+func (er *ErrorRecovery) CanRecover(err error) bool {
+	return er.findStrategy(err) != nil
+}
+
 // Recover attempts to recover from an error
 func (er *ErrorRecovery) Recover(ctx context.Context, err error) error {
 	if err == nil {
 		return nil
 	}
 
-	atomic.AddInt64(&er.metrics.TotalRecoveries, 1)
 	startTime := time.Now()
 
 	// Classify the error
 	category := er.classifyError(err)
-	atomic.AddInt64(&er.metrics.ErrorCategoryMetrics[category], 1)
 
 	// Find appropriate strategy
 	strategy := er.findStrategy(err)
@@ -415,9 +461,19 @@ func (er *ErrorRecovery) Recover(ctx context.Context, err error) error {
 		er.logger.Warn("No suitable recovery strategy found for error",
 			zap.Error(err),
 			zap.String("category", er.categoryName(category)))
-		atomic.AddInt64(&er.metrics.FailedRecoveries, 1)
+		er.recordRecovery(category, "", 0, false)
+		return err
+	}
+
+	// Bound the recoveries in flight
+	if !er.acquire(ctx) {
+		er.logger.Debug("Recovery limit reached, returning original error",
+			zap.String("strategy", strategy.Name()),
+			zap.Error(err))
+		er.recordShed()
 		return err
 	}
+	defer er.release()
 
 	// Notify observers
 	for _, observer := range er.observers {
@@ -426,20 +482,12 @@ func (er *ErrorRecovery) Recover(ctx context.Context, err error) error {
 
 	// Attempt recovery
 	recoveryErr := strategy.Recover(ctx, err, 1)
-	atomic.AddInt64(&er.metrics.StrategyUsage[strategy.Name()], 1)
 
 	endTime := time.Now()
 	duration := endTime.Sub(startTime)
-	atomic.AddInt64(&er.metrics.RecoveryTime, int64(duration))
-
-	// Calculate running average
-	total := atomic.LoadInt64(&er.metrics.TotalRecoveries)
-	avg := float64(atomic.LoadInt64(&er.metrics.RecoveryTime)) / float64(total)
-	atomic.StoreInt64(&er.metrics.AverageRecoveryTime, int64(avg))
+	er.recordRecovery(category, strategy.Name(), duration, recoveryErr == nil)
 
 	if recoveryErr == nil {
-		atomic.AddInt64(&er.metrics.SuccessfulRecoveries, 1)
-
 		for _, observer := range er.observers {
 			observer.OnRecoverySuccess(err, strategy.Name(), endTime)
 		}
@@ -451,8 +499,6 @@ func (er *ErrorRecovery) Recover(ctx context.Context, err error) error {
 		return nil
 	}
 
-	atomic.AddInt64(&er.metrics.FailedRecoveries, 1)
-
 	for _, observer := range er.observers {
 		observer.OnRecoveryFailed(err, strategy.Name(), recoveryErr)
 	}
@@ -465,9 +511,101 @@ func (er *ErrorRecovery) Recover(ctx context.Context, err error) error {
 	return recoveryErr
 }
 
+// acquire takes a recovery slot, waiting for one unless ShedExcess is set. It
+// reports false when the recovery should be shed.
+func (er *ErrorRecovery) acquire(ctx context.Context) bool {
+	if er.slots == nil {
+		return true
+	}
+
+	if er.config.ShedExcess {
+		select {
+		case er.slots <- struct{}{}:
+			return true
+		default:
+			return false
+		}
+	}
+
+	select {
+	case er.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-er.ctx.Done():
+		return false
+	}
+}
+
+// release frees the slot taken by acquire
+func (er *ErrorRecovery) release() {
+	if er.slots != nil {
+		<-er.slots
+	}
+}
+
+// recordShed counts a recovery that was not attempted
+func (er *ErrorRecovery) recordShed() {
+	er.metricsMu.Lock()
+	defer er.metricsMu.Unlock()
+	er.metrics.ShedRecoveries++
+}
+
+// recordRecovery updates recovery metrics for a single recovery attempt
+func (er *ErrorRecovery) recordRecovery(category ErrorCategory, strategy string, duration time.Duration, success bool) {
+	er.metricsMu.Lock()
+	defer er.metricsMu.Unlock()
+
+	er.metrics.TotalRecoveries++
+	er.metrics.ErrorCategoryMetrics[category]++
+
+	if success {
+		er.metrics.SuccessfulRecoveries++
+	} else {
+		er.metrics.FailedRecoveries++
+	}
+
+	if strategy == "" {
+		return
+	}
+
+	er.metrics.StrategyUsage[strategy]++
+	er.metrics.RecoveryTime += duration
+	er.timed++
+	er.metrics.AverageRecoveryTime = er.metrics.RecoveryTime / time.Duration(er.timed)
+}
+
+// Snapshot returns a consistent copy of the current recovery metrics that is
+// safe to read while recoveries are in progress
+func (er *ErrorRecovery) Snapshot() RecoveryMetrics {
+	er.metricsMu.Lock()
+	defer er.metricsMu.Unlock()
+
+	snapshot := er.metrics
+	snapshot.ErrorCategoryMetrics = make(map[ErrorCategory]int64, len(er.metrics.ErrorCategoryMetrics))
+	for category, count := range er.metrics.ErrorCategoryMetrics {
+		snapshot.ErrorCategoryMetrics[category] = count
+	}
+	snapshot.StrategyUsage = make(map[string]int64, len(er.metrics.StrategyUsage))
+	for strategy, count := range er.metrics.StrategyUsage {
+		snapshot.StrategyUsage[strategy] = count
+	}
+
+	return snapshot
+}
+
 // GetMetrics returns current recovery metrics
 func (er *ErrorRecovery) GetMetrics() RecoveryMetrics {
-	return er.metrics
+	return er.Snapshot()
+}
+
+// CategoryUsage returns error category counts keyed by category name
+func (m RecoveryMetrics) CategoryUsage() map[string]int64 {
+	usage := make(map[string]int64, len(m.ErrorCategoryMetrics))
+	for category, count := range m.ErrorCategoryMetrics {
+		usage[category.String()] += count
+	}
+	return usage
 }
 
 // Stop stops the error recovery system
@@ -522,6 +660,9 @@ func (er *ErrorRecovery) classifyError(err error) ErrorCategory {
 }
 
 func (er *ErrorRecovery) findStrategy(err error) RecoveryStrategy {
+	er.mu.RLock()
+	defer er.mu.RUnlock()
+
 	for _, strategy := range er.strategies {
 		if strategy.CanRecover(err) {
 			return strategy
@@ -531,6 +672,11 @@ func (er *ErrorRecovery) findStrategy(err error) RecoveryStrategy {
 }
 
 func (er *ErrorRecovery) categoryName(category ErrorCategory) string {
+	return category.String()
+}
+
+// String returns the name of the error category
+func (category ErrorCategory) String() string {
 	switch category {
 	case CategoryRetryable:
 		return "retryable"
@@ -562,7 +708,6 @@ func getDefaultRecoveryConfig() *RecoveryConfig {
 		Strategies: make(map[string]RecoveryStrategy),
 		MaxRetries: 3,
 		Timeout:    30 * time.Second,
-		LogLevel:   zap.InfoLevel,
 	}
 }
 
@@ -0,0 +1,105 @@
+package resilience
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// newBuiltinStrategy returns a fresh, unconfigured instance of a built-in recovery strategy
+func newBuiltinStrategy(name string) (RecoveryStrategy, error) {
+	switch name {
+	case "backoff_retry":
+		return &BackoffRetryStrategy{}, nil
+	case "circuit_reset":
+		return &CircuitResetStrategy{}, nil
+	case "resource_retry":
+		return &ResourceRetryStrategy{}, nil
+	case "network_retry":
+		return &NetworkRetryStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown recovery strategy: %s", name)
+	}
+}
+
+// strategyParameters converts declarative strategy settings into the parameter map
+// understood by RecoveryStrategy.Configure. Zero values are omitted so that each
+// strategy keeps its own default.
+func strategyParameters(cfg types.RecoveryStrategyConfig) map[string]interface{} {
+	params := make(map[string]interface{})
+
+	durations := map[string]time.Duration{
+		"baseDelay":  cfg.BaseDelay,
+		"maxDelay":   cfg.MaxDelay,
+		"retryDelay": cfg.RetryDelay,
+		"resetDelay": cfg.ResetDelay,
+	}
+	for key, value := range durations {
+		if value > 0 {
+			params[key] = value
+		}
+	}
+
+	if cfg.MaxRetries > 0 {
+		params["maxRetries"] = cfg.MaxRetries
+	}
+	if cfg.MaxResets > 0 {
+		params["maxResets"] = cfg.MaxResets
+	}
+
+	return params
+}
+
+// NewStrategiesFromConfig constructs and configures the built-in recovery strategies
+// enabled in the configuration
+func NewStrategiesFromConfig(cfg types.RecoveryConfig) (map[string]RecoveryStrategy, error) {
+	names := make([]string, 0, len(cfg.Strategies))
+	for name := range cfg.Strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	strategies := make(map[string]RecoveryStrategy)
+	for _, name := range names {
+		strategyCfg := cfg.Strategies[name]
+		if !strategyCfg.Enabled {
+			continue
+		}
+
+		strategy, err := newBuiltinStrategy(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := strategy.Configure(strategyParameters(strategyCfg)); err != nil {
+			return nil, fmt.Errorf("failed to configure recovery strategy %s: %w", name, err)
+		}
+
+		strategies[name] = strategy
+	}
+
+	return strategies, nil
+}
+
+// NewRecoveryConfigFromConfig builds a RecoveryConfig from the declarative configuration
+func NewRecoveryConfigFromConfig(cfg types.RecoveryConfig) (*RecoveryConfig, error) {
+	strategies, err := NewStrategiesFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCfg := getDefaultRecoveryConfig()
+	recoveryCfg.Strategies = strategies
+	if cfg.MaxRetries > 0 {
+		recoveryCfg.MaxRetries = cfg.MaxRetries
+	}
+	if cfg.Timeout > 0 {
+		recoveryCfg.Timeout = cfg.Timeout
+	}
+	recoveryCfg.MaxConcurrent = cfg.MaxConcurrent
+	recoveryCfg.ShedExcess = cfg.ShedExcess
+
+	return recoveryCfg, nil
+}
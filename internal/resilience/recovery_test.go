@@ -0,0 +1,339 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+)
+
+// errRefused is a network error, as returned when the server refuses to connect
+var errRefused = apperrors.Network(apperrors.ErrNetworkDial, errors.New("connection refused"), "failed to connect to server")
+
+// countingStrategy recovers every error immediately
+type countingStrategy struct {
+	name string
+}
+
+func (s *countingStrategy) Name() string                                              { return s.name }
+func (s *countingStrategy) CanRecover(err error) bool                                 { return true }
+func (s *countingStrategy) Recover(ctx context.Context, err error, attempt int) error { return nil }
+func (s *countingStrategy) Configure(config map[string]interface{}) error             { return nil }
+
+func TestErrorRecoveryConcurrentSnapshot(t *testing.T) {
+	er, err := NewErrorRecovery(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create error recovery: %v", err)
+	}
+	defer er.Stop()
+
+	if err := er.AddStrategy("counting", &countingStrategy{name: "counting"}); err != nil {
+		t.Fatalf("Failed to add strategy: %v", err)
+	}
+
+	const workers = 8
+	const perWorker = 200
+
+	stop := make(chan struct{})
+	snapshotDone := make(chan struct{})
+	go func() {
+		defer close(snapshotDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			snapshot := er.Snapshot()
+			var total int64
+			for _, count := range snapshot.StrategyUsage {
+				total += count
+			}
+			for range snapshot.CategoryUsage() {
+			}
+			if total > snapshot.TotalRecoveries {
+				t.Errorf("Strategy usage %d exceeds total recoveries %d", total, snapshot.TotalRecoveries)
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				if err := er.Recover(context.Background(), errRefused); err != nil {
+					t.Errorf("Unexpected recovery error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-snapshotDone
+
+	metrics := er.GetMetrics()
+	const expected = workers * perWorker
+	if metrics.TotalRecoveries != expected {
+		t.Errorf("Expected %d total recoveries, got %d", expected, metrics.TotalRecoveries)
+	}
+	if metrics.SuccessfulRecoveries != expected {
+		t.Errorf("Expected %d successful recoveries, got %d", expected, metrics.SuccessfulRecoveries)
+	}
+	if metrics.StrategyUsage["counting"] != expected {
+		t.Errorf("Expected %d uses of counting strategy, got %d", expected, metrics.StrategyUsage["counting"])
+	}
+	if usage := metrics.CategoryUsage()["network"]; usage != expected {
+		t.Errorf("Expected %d network category recoveries, got %d", expected, usage)
+	}
+
+	// Snapshots must not alias internal state
+	metrics.StrategyUsage["counting"] = 0
+	if er.Snapshot().StrategyUsage["counting"] != expected {
+		t.Error("Modifying a snapshot changed internal metrics")
+	}
+}
+
+func TestRecoveryStrategiesFromConfig(t *testing.T) {
+	content := `
+type: server
+metadata:
+  schema_version: "2.0.0"
+config:
+  mode: server
+  recovery:
+    max_retries: 4
+    strategies:
+      network_retry:
+        enabled: true
+        retry_delay: 250ms
+        base_delay: 100ms
+        max_delay: 2s
+        max_retries: 7
+      circuit_reset:
+        enabled: false
+`
+	cfg, err := config.LoadConfigString(content, "yaml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	recoveryCfg, err := NewRecoveryConfigFromConfig(cfg.Config.Recovery)
+	if err != nil {
+		t.Fatalf("Failed to build recovery config: %v", err)
+	}
+
+	if recoveryCfg.MaxRetries != 4 {
+		t.Errorf("Expected max retries 4, got %d", recoveryCfg.MaxRetries)
+	}
+	if len(recoveryCfg.Strategies) != 1 {
+		t.Fatalf("Expected 1 enabled strategy, got %d", len(recoveryCfg.Strategies))
+	}
+
+	strategy, ok := recoveryCfg.Strategies["network_retry"].(*NetworkRetryStrategy)
+	if !ok {
+		t.Fatalf("Expected network_retry strategy, got %T", recoveryCfg.Strategies["network_retry"])
+	}
+	if strategy.retryDelay != 250*time.Millisecond {
+		t.Errorf("Expected retry delay 250ms, got %v", strategy.retryDelay)
+	}
+	if strategy.backoffStrategy == nil {
+		t.Fatal("Expected backoff to be configured")
+	}
+	backoffCfg := strategy.backoffStrategy.config
+	if backoffCfg.BaseDelay != 100*time.Millisecond {
+		t.Errorf("Expected base delay 100ms, got %v", backoffCfg.BaseDelay)
+	}
+	if backoffCfg.MaxDelay != 2*time.Second {
+		t.Errorf("Expected max delay 2s, got %v", backoffCfg.MaxDelay)
+	}
+	if backoffCfg.MaxRetries != 7 {
+		t.Errorf("Expected max retries 7, got %d", backoffCfg.MaxRetries)
+	}
+
+	er, err := NewErrorRecovery(recoveryCfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to create error recovery: %v", err)
+	}
+	defer er.Stop()
+
+	if ctx := er.GetRecoveryContext(errRefused, 1); ctx.Strategy != "network_retry" {
+		t.Errorf("Expected network_retry to handle network errors, got %s", ctx.Strategy)
+	}
+}
+
+func TestRecoveryStrategiesFromConfigUnknown(t *testing.T) {
+	cfg := types.RecoveryConfig{
+		Strategies: map[string]types.RecoveryStrategyConfig{
+			"teleport_retry": {Enabled: true},
+		},
+	}
+
+	if _, err := NewStrategiesFromConfig(cfg); err == nil {
+		t.Error("Expected error for unknown recovery strategy")
+	}
+}
+
+// blockingStrategy tracks how many recoveries run at once
+type blockingStrategy struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	delay       time.Duration
+}
+
+func (s *blockingStrategy) Name() string              { return "blocking" }
+func (s *blockingStrategy) CanRecover(err error) bool { return true }
+func (s *blockingStrategy) Recover(ctx context.Context, err error, attempt int) error {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		max := s.maxInFlight.Load()
+		if n <= max || s.maxInFlight.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(s.delay)
+	return nil
+}
+func (s *blockingStrategy) Configure(config map[string]interface{}) error { return nil }
+
+func TestErrorRecoveryAverageTime(t *testing.T) {
+	er, err := NewErrorRecovery(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create error recovery: %v", err)
+	}
+	defer er.Stop()
+
+	// Errors no strategy handles take no recovery time and leave the average alone
+	er.recordRecovery(CategoryNetwork, "network_retry", 10*time.Millisecond, true)
+	er.recordRecovery(CategoryValidation, "", 0, false)
+	er.recordRecovery(CategoryNetwork, "network_retry", 30*time.Millisecond, false)
+
+	if avg := er.GetMetrics().AverageRecoveryTime; avg != 20*time.Millisecond {
+		t.Errorf("Expected an average recovery time of 20ms, got %v", avg)
+	}
+}
+
+func TestErrorRecoveryMaxConcurrent(t *testing.T) {
+	const limit = 3
+	const recoveries = 30
+
+	strategy := &blockingStrategy{delay: 5 * time.Millisecond}
+	er, err := NewErrorRecovery(&RecoveryConfig{
+		Strategies:    map[string]RecoveryStrategy{"blocking": strategy},
+		MaxConcurrent: limit,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create error recovery: %v", err)
+	}
+	defer er.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < recoveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := er.Recover(context.Background(), errRefused); err != nil {
+				t.Errorf("Unexpected recovery error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if max := strategy.maxInFlight.Load(); max > limit {
+		t.Errorf("Expected at most %d recoveries in flight, got %d", limit, max)
+	}
+	metrics := er.GetMetrics()
+	if metrics.SuccessfulRecoveries != recoveries || metrics.ShedRecoveries != 0 {
+		t.Errorf("Expected all %d queued recoveries to run, got %d successful and %d shed",
+			recoveries, metrics.SuccessfulRecoveries, metrics.ShedRecoveries)
+	}
+}
+
+func TestErrorRecoveryShedExcess(t *testing.T) {
+	const limit = 2
+	const recoveries = 20
+
+	strategy := &blockingStrategy{delay: 50 * time.Millisecond}
+	er, err := NewErrorRecovery(&RecoveryConfig{
+		Strategies:    map[string]RecoveryStrategy{"blocking": strategy},
+		MaxConcurrent: limit,
+		ShedExcess:    true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create error recovery: %v", err)
+	}
+	defer er.Stop()
+
+	original := errors.New("connection refused")
+	var shed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < recoveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := er.Recover(context.Background(), original); err != nil {
+				if err != original {
+					t.Errorf("Expected the original error for a shed recovery, got %v", err)
+				}
+				shed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if max := strategy.maxInFlight.Load(); max > limit {
+		t.Errorf("Expected at most %d recoveries in flight, got %d", limit, max)
+	}
+	metrics := er.GetMetrics()
+	if shed.Load() == 0 || metrics.ShedRecoveries != int64(shed.Load()) {
+		t.Errorf("Expected shed recoveries to be counted, got %d returned and %d counted", shed.Load(), metrics.ShedRecoveries)
+	}
+	if metrics.SuccessfulRecoveries+metrics.ShedRecoveries != recoveries {
+		t.Errorf("Expected %d recoveries run or shed, got %d successful and %d shed",
+			recoveries, metrics.SuccessfulRecoveries, metrics.ShedRecoveries)
+	}
+}
+
+func TestClassifyCommonErrorByCode(t *testing.T) {
+	_, loadErr := config.LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"), config.LoadOptions{})
+
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorCategory
+	}{
+		{"config read", loadErr, CategoryConfiguration},
+		{"config invalid", apperrors.Config(apperrors.ErrConfigInvalid, nil, "invalid tunnel configuration"), CategoryValidation},
+		{"wrapped dial", fmt.Errorf("start: %w", apperrors.Network(apperrors.ErrNetworkDial, errors.New("config settings"), "failed to connect")), CategoryNetwork},
+		{"handshake", apperrors.Tunnel(apperrors.ErrTunnelHandshake, errors.New("EOF"), "TLS handshake failed"), CategoryRetryable},
+		{"dial timeout", apperrors.Network(apperrors.ErrNetworkDial, &net.OpError{Op: "dial", Err: context.DeadlineExceeded}, "failed to connect"), CategoryTimeout},
+		{"uncoded net error", &net.OpError{Op: "dial", Err: errors.New("refused")}, CategoryNetwork},
+		{"rate limited", apperrors.Network(apperrors.ErrNetworkLimited, nil, "connection rate limit exceeded"), CategoryRateLimit},
+		{"descriptors exhausted", &net.OpError{Op: "accept", Err: os.NewSyscallError("accept4", syscall.EMFILE)}, CategoryResourceExhaustion},
+		{"buffers exhausted", apperrors.Network(apperrors.ErrNetworkDial, syscall.ENOBUFS, "failed to connect"), CategoryResourceExhaustion},
+		// Messages are never matched
+		{"uncoded message", errors.New("dial tcp: connection refused"), CategoryRecoverable},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyCommonError(tt.err); got != tt.expected {
+			t.Errorf("%s: expected category %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
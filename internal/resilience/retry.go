@@ -2,9 +2,11 @@ package resilience
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -14,11 +16,11 @@ import (
 type RetryStrategy int
 
 const (
-	StrategyFixed RetryStrategy = iota
-	StrategyLinear
-	StrategyExponential
-	StrategyGeometric
-	StrategyCustom
+	RetryFixed RetryStrategy = iota
+	RetryLinear
+	RetryExponential
+	RetryGeometric
+	RetryCustom
 )
 
 // RetryConfig defines retry behavior configuration
@@ -39,16 +41,11 @@ type RetryConfig struct {
 	CircuitBreaker  *CircuitBreaker
 	BackoffStrategy *ExponentialBackoff
 	Name            string
-}
-
-// RetryAction defines what action to take for a specific error
-type RetryAction int
 
-const (
-	ActionRetry RetryAction = iota // Retry the operation
-	ActionFail                     // Fail immediately
-	ActionSkip                     // Skip this operation
-)
+	// UnknownErrorAction is the action of the default ErrorClassifier for
+	// errors that are neither context errors nor Retryable
+	UnknownErrorAction RetryAction
+}
 
 // RetryResult represents the result of a retry operation
 type RetryResult struct {
@@ -68,6 +65,9 @@ type RetryManager struct {
 	logger     *zap.Logger
 	statistics RetryStatistics
 	stopCh     chan struct{}
+
+	mu      sync.RWMutex // Guards config
+	statsMu sync.Mutex   // Guards statistics
 }
 
 // RetryStatistics tracks retry performance metrics
@@ -91,9 +91,7 @@ func NewRetryManager(config *RetryConfig, logger *zap.Logger) *RetryManager {
 		logger = zap.NewNop()
 	}
 
-	if config.ErrorClassifier == nil {
-		config.ErrorClassifier = defaultErrorClassifier
-	}
+	setDefaultErrorClassifier(config)
 
 	return &RetryManager{
 		config: config,
@@ -102,51 +100,56 @@ func NewRetryManager(config *RetryConfig, logger *zap.Logger) *RetryManager {
 	}
 }
 
-// Execute runs a function with retry logic
+// setDefaultErrorClassifier gives config the default ErrorClassifier, using
+// its UnknownErrorAction, when it has none
+func setDefaultErrorClassifier(config *RetryConfig) {
+	if config.ErrorClassifier == nil {
+		unknown := config.UnknownErrorAction
+		config.ErrorClassifier = func(err error) RetryAction {
+			return ClassifyRetryable(err, unknown)
+		}
+	}
+}
+
+// Execute runs a function with retry logic. The configuration is read once,
+// so a concurrent SetConfig applies from the next call.
 func (rm *RetryManager) Execute(ctx context.Context, fn func(ctx context.Context) error) RetryResult {
+	config := rm.GetConfig()
+
 	startTime := time.Now()
 	result := RetryResult{
 		StartTime: startTime,
 	}
 
 	// Update statistics
-	atomic.AddInt64(&rm.statistics.TotalRetries, 1)
+	rm.updateStatistics(func(stats *RetryStatistics) { stats.TotalRetries++ })
 
 	// Check circuit breaker if configured
-	if rm.config.CircuitBreaker != nil {
-		canExecute := rm.config.CircuitBreaker.CanExecute()
+	if config.CircuitBreaker != nil {
+		canExecute := config.CircuitBreaker.CanExecute()
 		if !canExecute {
 			rm.logger.Warn("Circuit breaker blocked retry execution",
-				zap.String("name", rm.config.Name))
+				zap.String("name", config.Name))
 			result.Skipped = true
-			atomic.AddInt64(&rm.statistics.SkippedRetries, 1)
+			rm.updateStatistics(func(stats *RetryStatistics) { stats.SkippedRetries++ })
 			return result
 		}
 	}
 
-	var attemptErr error
-	for attempt := 1; attempt <= rm.config.MaxAttempts; attempt++ {
-		// Create attempt context with timeout if configured
-		attemptCtx := ctx
-		var attemptCancel context.CancelFunc
-		if rm.config.AttemptTimeout > 0 {
-			attemptCtx, attemptCancel = context.WithTimeout(ctx, rm.config.AttemptTimeout)
-			defer attemptCancel()
-		}
-
+	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
 			result.EndTime = time.Now()
 			result.LastError = ctx.Err()
-			atomic.AddInt64(&rm.statistics.FailedRetries, 1)
-			rm.callOnFailure(result.Attempts, ctx.Err())
+			rm.updateStatistics(func(stats *RetryStatistics) { stats.FailedRetries++ })
+			callOnFailure(config, result.Attempts, ctx.Err())
 			return result
 		default:
 		}
 
 		// Execute the function
-		err := fn(attemptCtx)
+		timedOut, err := runAttempt(ctx, config.AttemptTimeout, fn)
 		result.Attempts = attempt
 		result.LastError = err
 
@@ -154,25 +157,29 @@ func (rm *RetryManager) Execute(ctx context.Context, fn func(ctx context.Context
 		if err == nil {
 			result.EndTime = time.Now()
 			result.Success = true
-			atomic.AddInt64(&rm.statistics.SuccessfulRetries, 1)
+			rm.updateStatistics(func(stats *RetryStatistics) { stats.SuccessfulRetries++ })
 			result.TotalDelay = result.EndTime.Sub(startTime)
-			rm.callOnSuccess(attempt)
+			callOnSuccess(config, attempt)
 
 			// Update circuit breaker on success
-			if rm.config.CircuitBreaker != nil {
-				rm.config.CircuitBreaker.Call(attemptCtx, func(ctx context.Context) error { return nil })
+			if config.CircuitBreaker != nil {
+				config.CircuitBreaker.Call(ctx, func(ctx context.Context) error { return nil })
 			}
 
 			rm.logger.Debug("Retry operation succeeded",
-				zap.String("name", rm.config.Name),
+				zap.String("name", config.Name),
 				zap.Int("attempt", attempt),
 				zap.Duration("total_delay", result.TotalDelay))
 
 			return result
 		}
 
-		// Classify the error
-		action := rm.config.ErrorClassifier(err)
+		// Classify the error. An attempt that ran out of its own time is
+		// retried, though its error is a context deadline.
+		action := config.ErrorClassifier(err)
+		if timedOut {
+			action = ActionRetry
+		}
 		result.LastError = err
 
 		switch action {
@@ -180,16 +187,16 @@ func (rm *RetryManager) Execute(ctx context.Context, fn func(ctx context.Context
 			// Fail immediately for this error type
 			result.EndTime = time.Now()
 			result.Failed = true
-			atomic.AddInt64(&rm.statistics.FailedRetries, 1)
+			rm.updateStatistics(func(stats *RetryStatistics) { stats.FailedRetries++ })
 			result.TotalDelay = result.EndTime.Sub(startTime)
-			rm.callOnFailure(attempt, err)
+			callOnFailure(config, attempt, err)
 			return result
 
 		case ActionSkip:
 			// Skip this operation
 			result.EndTime = time.Now()
 			result.Skipped = true
-			atomic.AddInt64(&rm.statistics.SkippedRetries, 1)
+			rm.updateStatistics(func(stats *RetryStatistics) { stats.SkippedRetries++ })
 			result.TotalDelay = result.EndTime.Sub(startTime)
 			return result
 
@@ -202,114 +209,133 @@ func (rm *RetryManager) Execute(ctx context.Context, fn func(ctx context.Context
 		}
 
 		// Check if we should retry (not the last attempt)
-		if attempt < rm.config.MaxAttempts {
-			delay := rm.calculateDelay(attempt)
+		if attempt < config.MaxAttempts {
+			delay := rm.calculateDelay(config, attempt)
 
 			rm.logger.Info("Retrying operation",
-				zap.String("name", rm.config.Name),
+				zap.String("name", config.Name),
 				zap.Int("attempt", attempt),
 				zap.Duration("delay", delay),
 				zap.Error(err))
 
-			rm.callOnRetry(attempt, delay, err)
+			callOnRetry(config, attempt, delay, err)
 
 			// Wait for the calculated delay
 			select {
 			case <-ctx.Done():
 				result.EndTime = time.Now()
 				result.LastError = ctx.Err()
-				atomic.AddInt64(&rm.statistics.FailedRetries, 1)
-				rm.callOnFailure(attempt, ctx.Err())
+				rm.updateStatistics(func(stats *RetryStatistics) { stats.FailedRetries++ })
+				callOnFailure(config, attempt, ctx.Err())
 				return result
 			case <-time.After(delay):
 				// Continue to next attempt
 			}
 
-			atomic.AddInt64(&rm.statistics.TotalDelayTime, int64(delay))
+			rm.updateStatistics(func(stats *RetryStatistics) { stats.TotalDelayTime += delay })
 		}
 	}
 
 	// All attempts exhausted
 	result.EndTime = time.Now()
 	result.Failed = true
-	atomic.AddInt64(&rm.statistics.FailedRetries, 1)
+	rm.updateStatistics(func(stats *RetryStatistics) { stats.FailedRetries++ })
 	result.TotalDelay = result.EndTime.Sub(startTime)
 
 	rm.logger.Error("Retry operation failed after all attempts",
-		zap.String("name", rm.config.Name),
+		zap.String("name", config.Name),
 		zap.Int("attempts", result.Attempts),
 		zap.Duration("total_delay", result.TotalDelay),
 		zap.Error(result.LastError))
 
-	rm.callOnFailure(result.Attempts, result.LastError)
-	atomic.StoreInt64(&rm.statistics.LastRetryTime, result.EndTime.UnixNano())
+	callOnFailure(config, result.Attempts, result.LastError)
+	rm.updateStatistics(func(stats *RetryStatistics) { stats.LastRetryTime = result.EndTime })
 
 	return result
 }
 
+// runAttempt runs fn once, bounded by timeout when it is positive. The
+// attempt's context is cancelled as soon as fn returns, so that anything fn
+// started for it stops before the next attempt. timedOut reports whether the
+// attempt, rather than ctx, ran out of time.
+func runAttempt(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) (timedOut bool, err error) {
+	if timeout <= 0 {
+		return false, fn(ctx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = fn(attemptCtx)
+	timedOut = err != nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	return timedOut, err
+}
+
 // calculateDelay computes the delay for the given attempt
-func (rm *RetryManager) calculateDelay(attempt int) time.Duration {
+func (rm *RetryManager) calculateDelay(config *RetryConfig, attempt int) time.Duration {
 	var delay time.Duration
 
-	switch rm.config.Strategy {
-	case StrategyFixed:
-		delay = rm.config.BaseDelay
+	switch config.Strategy {
+	case RetryFixed:
+		delay = config.BaseDelay
 
-	case StrategyLinear:
-		delay = rm.config.BaseDelay + time.Duration(attempt-1)*rm.config.BaseDelay
+	case RetryLinear:
+		delay = config.BaseDelay + time.Duration(attempt-1)*config.BaseDelay
 
-	case StrategyExponential:
-		if rm.config.BackoffStrategy != nil {
-			delay = rm.config.BackoffStrategy.GetNextDelay()
+	case RetryExponential:
+		if config.BackoffStrategy != nil {
+			delay = config.BackoffStrategy.GetNextDelay()
 		} else {
-			multiplier := rm.config.Multiplier
+			multiplier := config.Multiplier
 			if multiplier == 0 {
 				multiplier = 2.0
 			}
-			timeValue := float64(rm.config.BaseDelay) * pow(multiplier, float64(attempt-1))
-			delay = time.Duration(timeValue)
+			delay = scaledDelay(config.BaseDelay, multiplier, attempt-1, config.MaxDelay)
 		}
 
-	case StrategyGeometric:
-		if rm.config.BackoffStrategy != nil {
-			delay = rm.config.BackoffStrategy.GetNextDelay()
+	case RetryGeometric:
+		if config.BackoffStrategy != nil {
+			delay = config.BackoffStrategy.GetNextDelay()
 		} else {
-			multiplier := rm.config.Multiplier
+			multiplier := config.Multiplier
 			if multiplier == 0 {
 				multiplier = 1.5
 			}
-			timeValue := float64(rm.config.BaseDelay)
-			for i := 1; i < attempt; i++ {
-				timeValue *= multiplier
-			}
-			delay = time.Duration(timeValue)
+			delay = scaledDelay(config.BaseDelay, multiplier, attempt-1, config.MaxDelay)
 		}
 
-	case StrategyCustom:
+	case RetryCustom:
 		// Custom strategy implemented through backoff
-		if rm.config.BackoffStrategy != nil {
-			delay = rm.config.BackoffStrategy.GetNextDelay()
+		if config.BackoffStrategy != nil {
+			delay = config.BackoffStrategy.GetNextDelay()
 		} else {
-			delay = rm.config.BaseDelay
+			delay = config.BaseDelay
 		}
 
 	default:
-		delay = rm.config.BaseDelay
+		delay = config.BaseDelay
 	}
 
 	// Cap at maximum delay
-	if delay > rm.config.MaxDelay && rm.config.MaxDelay > 0 {
-		delay = rm.config.MaxDelay
+	if delay > config.MaxDelay && config.MaxDelay > 0 {
+		delay = config.MaxDelay
 	}
 
 	// Add jitter if enabled
-	if rm.config.EnableJitter && rm.config.JitterFactor > 0 {
-		jitter := rm.config.JitterFactor
+	if config.EnableJitter && config.JitterFactor > 0 {
+		jitter := config.JitterFactor
 		if jitter > 1.0 {
 			jitter = 1.0
 		}
 		jitterAmount := time.Duration(float64(delay) * jitter * rand.Float64())
-		delay += jitterAmount
+		if delay > longestDelay-jitterAmount {
+			delay = longestDelay
+		} else {
+			delay += jitterAmount
+		}
+		if delay > config.MaxDelay && config.MaxDelay > 0 {
+			delay = config.MaxDelay
+		}
 	}
 
 	return delay
@@ -317,7 +343,9 @@ func (rm *RetryManager) calculateDelay(attempt int) time.Duration {
 
 // GetStatistics returns current retry statistics
 func (rm *RetryManager) GetStatistics() RetryStatistics {
+	rm.statsMu.Lock()
 	stats := rm.statistics
+	rm.statsMu.Unlock()
 
 	// Calculate average delay
 	totalRetries := stats.SuccessfulRetries + stats.FailedRetries + stats.SkippedRetries
@@ -328,32 +356,38 @@ func (rm *RetryManager) GetStatistics() RetryStatistics {
 	return stats
 }
 
+// updateStatistics applies update to the statistics
+func (rm *RetryManager) updateStatistics(update func(stats *RetryStatistics)) {
+	rm.statsMu.Lock()
+	defer rm.statsMu.Unlock()
+	update(&rm.statistics)
+}
+
 // ResetStatistics resets all retry statistics
 func (rm *RetryManager) ResetStatistics() {
-	atomic.StoreInt64(&rm.statistics.TotalRetries, 0)
-	atomic.StoreInt64(&rm.statistics.SuccessfulRetries, 0)
-	atomic.StoreInt64(&rm.statistics.FailedRetries, 0)
-	atomic.StoreInt64(&rm.statistics.SkippedRetries, 0)
-	atomic.StoreInt64(&rm.statistics.TotalDelayTime, 0)
-	atomic.StoreInt64(&rm.statistics.LastRetryTime, 0)
-	atomic.StoreInt64(&rm.statistics.AverageDelayTime, 0)
+	rm.statsMu.Lock()
+	defer rm.statsMu.Unlock()
+	rm.statistics = RetryStatistics{}
 }
 
 // GetConfig returns the current configuration
 func (rm *RetryManager) GetConfig() *RetryConfig {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
 	return rm.config
 }
 
-// SetConfig updates the retry configuration
+// SetConfig updates the retry configuration. Executions already running keep
+// the configuration they started with.
 func (rm *RetryManager) SetConfig(config *RetryConfig) error {
 	if config == nil {
 		return fmt.Errorf("retry configuration cannot be nil")
 	}
+	setDefaultErrorClassifier(config)
 
 	rm.mu.Lock()
-	defer rm.mu.Unlock()
-
 	rm.config = config
+	rm.mu.Unlock()
 
 	rm.logger.Info("Retry configuration updated",
 		zap.String("name", config.Name),
@@ -366,66 +400,48 @@ func (rm *RetryManager) SetConfig(config *RetryConfig) error {
 }
 
 // callOnRetry calls the retry callback if configured
-func (rm *RetryManager) callOnRetry(attempt int, delay time.Duration, err error) {
-	if rm.config.OnRetry != nil {
-		rm.config.OnRetry(attempt, delay, err)
+func callOnRetry(config *RetryConfig, attempt int, delay time.Duration, err error) {
+	if config.OnRetry != nil {
+		config.OnRetry(attempt, delay, err)
 	}
 }
 
 // callOnSuccess calls the success callback if configured
-func (rm *RetryManager) callOnSuccess(attempt int) {
-	if rm.config.OnSuccess != nil {
-		rm.config.OnSuccess(attempt)
+func callOnSuccess(config *RetryConfig, attempt int) {
+	if config.OnSuccess != nil {
+		config.OnSuccess(attempt)
 	}
 }
 
 // callOnFailure calls the failure callback if configured
-func (rm *RetryManager) callOnFailure(attempt int, err error) {
-	if rm.config.OnFailure != nil {
-		rm.config.OnFailure(attempt, err)
+func callOnFailure(config *RetryConfig, attempt int, err error) {
+	if config.OnFailure != nil {
+		config.OnFailure(attempt, err)
 	}
 }
 
 // strategyName returns the string name of a retry strategy
 func (rm *RetryManager) strategyName(strategy RetryStrategy) string {
 	switch strategy {
-	case StrategyFixed:
+	case RetryFixed:
 		return "fixed"
-	case StrategyLinear:
+	case RetryLinear:
 		return "linear"
-	case StrategyExponential:
+	case RetryExponential:
 		return "exponential"
-	case StrategyGeometric:
+	case RetryGeometric:
 		return "geometric"
-	case StrategyCustom:
+	case RetryCustom:
 		return "custom"
 	default:
 		return "unknown"
 	}
 }
 
-// defaultErrorClassifier provides a default error classification strategy
-func defaultErrorClassifier(err error) RetryAction {
-	if err == nil {
-		return ActionRetry // Shouldn't happen
-	}
-
-	// Check for specific error types that should not be retried
-	switch err {
-	case context.Canceled:
-		return ActionFail
-	case context.DeadlineExceeded:
-		return ActionFail
-	default:
-		// Default to retry for most errors
-		return ActionRetry
-	}
-}
-
 // getDefaultRetryConfig returns default retry configuration
 func getDefaultRetryConfig() *RetryConfig {
 	return &RetryConfig{
-		Strategy:        StrategyExponential,
+		Strategy:        RetryExponential,
 		MaxAttempts:     3,
 		BaseDelay:       100 * time.Millisecond,
 		MaxDelay:        5 * time.Second,
@@ -439,13 +455,25 @@ func getDefaultRetryConfig() *RetryConfig {
 	}
 }
 
-// pow calculates base^exponent for float64
-func pow(base, exponent float64) float64 {
-	result := 1.0
-	for i := 0; i < int(exponent); i++ {
-		result *= base
+// longestDelay is the longest delay a time.Duration holds
+const longestDelay = time.Duration(math.MaxInt64)
+
+// scaledDelay returns base*multiplier^exponent, capped at limit (or at the
+// largest time.Duration when limit is not positive). The product is capped
+// while still a float64, so a large exponent never wraps around to a
+// negative or short delay.
+func scaledDelay(base time.Duration, multiplier float64, exponent int, limit time.Duration) time.Duration {
+	if limit <= 0 {
+		limit = longestDelay
 	}
-	return result
+	delay := float64(base) * math.Pow(multiplier, float64(exponent))
+	if math.IsNaN(delay) || delay < 0 {
+		return 0
+	}
+	if delay >= float64(limit) {
+		return limit
+	}
+	return time.Duration(delay)
 }
 
 // RetryContext provides contextual information for retry operations
@@ -460,14 +488,15 @@ type RetryContext struct {
 
 // GetContext returns current retry context for use in callbacks
 func (rm *RetryManager) GetContext(attempt int, delay time.Duration, err error) RetryContext {
+	config := rm.GetConfig()
 	isOpen := false
-	if rm.config.CircuitBreaker != nil {
-		isOpen = rm.config.CircuitBreaker.GetState() == StateOpen
+	if config.CircuitBreaker != nil {
+		isOpen = config.CircuitBreaker.GetState() == StateOpen
 	}
 
 	return RetryContext{
 		Attempt:     attempt,
-		MaxAttempts: rm.config.MaxAttempts,
+		MaxAttempts: config.MaxAttempts,
 		Delay:       delay,
 		LastError:   err,
 		StartTime:   time.Now(),
@@ -478,7 +507,7 @@ func (rm *RetryManager) GetContext(attempt int, delay time.Duration, err error)
 // QuickRetry provides a simple retry function for quick use-cases
 func QuickRetry(ctx context.Context, fn func() error, maxAttempts int, baseDelay time.Duration) error {
 	manager := NewRetryManager(&RetryConfig{
-		Strategy:    StrategyExponential,
+		Strategy:    RetryExponential,
 		MaxAttempts: maxAttempts,
 		BaseDelay:   baseDelay,
 		MaxDelay:    30 * time.Second,
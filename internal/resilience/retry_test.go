@@ -0,0 +1,155 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryManagerConcurrentSetConfig(t *testing.T) {
+	manager := NewRetryManager(&RetryConfig{
+		Strategy:    RetryFixed,
+		MaxAttempts: 3,
+		BaseDelay:   time.Microsecond,
+		Name:        "concurrent",
+	}, nil)
+
+	// Replace the config for as long as the executions run
+	stop := make(chan struct{})
+	setterDone := make(chan struct{})
+	go func() {
+		defer close(setterDone)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := manager.SetConfig(&RetryConfig{
+				Strategy:    RetryLinear,
+				MaxAttempts: 2 + i%3,
+				BaseDelay:   time.Microsecond,
+				Name:        "concurrent",
+			}); err != nil {
+				t.Errorf("Failed to set config: %v", err)
+				return
+			}
+		}
+	}()
+
+	const workers = 4
+	errTemporary := errors.New("temporary")
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				calls := 0
+				result := manager.Execute(context.Background(), func(ctx context.Context) error {
+					calls++
+					if calls == 1 {
+						return errTemporary
+					}
+					return nil
+				})
+				if !result.Success {
+					t.Errorf("Expected the retry to succeed, got %v", result.LastError)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(stop)
+	<-setterDone
+
+	stats := manager.GetStatistics()
+	if stats.SuccessfulRetries != workers*100 {
+		t.Errorf("Expected %d successful executions, got %d", workers*100, stats.SuccessfulRetries)
+	}
+	if manager.GetConfig().ErrorClassifier == nil {
+		t.Error("Expected SetConfig to install the default error classifier")
+	}
+}
+
+func TestRetryManagerDelayOverflow(t *testing.T) {
+	for _, strategy := range []RetryStrategy{RetryExponential, RetryGeometric} {
+		for _, jitter := range []bool{false, true} {
+			manager := NewRetryManager(&RetryConfig{
+				Strategy:     strategy,
+				MaxAttempts:  50,
+				BaseDelay:    time.Second,
+				MaxDelay:     time.Minute,
+				Multiplier:   10,
+				EnableJitter: jitter,
+				JitterFactor: 0.5,
+			}, nil)
+
+			config := manager.GetConfig()
+			for attempt := 1; attempt <= 40; attempt++ {
+				delay := manager.calculateDelay(config, attempt)
+				if delay <= 0 || delay > config.MaxDelay {
+					t.Fatalf("Strategy %s: attempt %d: expected a delay within (0, %v], got %v",
+						manager.strategyName(strategy), attempt, config.MaxDelay, delay)
+				}
+			}
+			if delay := manager.calculateDelay(config, 40); !jitter && delay != config.MaxDelay {
+				t.Errorf("Strategy %s: expected attempt 40 to be clamped to %v, got %v",
+					manager.strategyName(strategy), config.MaxDelay, delay)
+			}
+		}
+	}
+
+	// Without a maximum the delay saturates rather than wrapping around
+	if delay := scaledDelay(time.Second, 1e6, 40, 0); delay != longestDelay {
+		t.Errorf("Expected an unbounded delay to saturate at %v, got %v", longestDelay, delay)
+	}
+}
+
+func TestRetryManagerAttemptTimeout(t *testing.T) {
+	manager := NewRetryManager(&RetryConfig{
+		Strategy:       RetryFixed,
+		MaxAttempts:    3,
+		BaseDelay:      time.Millisecond,
+		AttemptTimeout: 20 * time.Millisecond,
+	}, nil)
+
+	var contexts []context.Context
+	result := manager.Execute(context.Background(), func(ctx context.Context) error {
+		contexts = append(contexts, ctx)
+		switch len(contexts) {
+		case 1:
+			// The first attempt outlives its timeout, unless cancelled
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return errors.New("attempt was not cancelled")
+			}
+		case 2:
+			// The second fails well within its timeout
+			return errors.New("temporary")
+		}
+
+		// Both earlier attempts were cancelled before this one started
+		if err := contexts[0].Err(); !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("expected the timed out attempt to be cancelled, got %v", err)
+		}
+		if err := contexts[1].Err(); !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("expected the failed attempt to be cancelled, got %v", err)
+		}
+		return nil
+	})
+
+	if !result.Success {
+		t.Fatalf("Expected the timed out attempt to be retried, got %v", result.LastError)
+	}
+	if result.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", result.Attempts)
+	}
+}
@@ -0,0 +1,72 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+)
+
+// RetryAction defines what action to take for a specific error
+type RetryAction int
+
+const (
+	ActionRetry RetryAction = iota // Retry the operation
+	ActionFail                     // Fail immediately
+	ActionSkip                     // Skip this operation
+)
+
+// Retryable is implemented by errors that know whether the operation that
+// returned them can safely be retried
+type Retryable interface {
+	Retryable() bool
+}
+
+// IsRetryable reports whether the first error in the chain of err that
+// implements Retryable allows a retry; ok is false when none implements it
+func IsRetryable(err error) (retryable, ok bool) {
+	var r Retryable
+	if errors.As(err, &r) {
+		return r.Retryable(), true
+	}
+	return false, false
+}
+
+// retryableError marks the error it wraps as retryable or not
+type retryableError struct {
+	err       error
+	retryable bool
+}
+
+func (e *retryableError) Error() string   { return e.err.Error() }
+func (e *retryableError) Unwrap() error   { return e.err }
+func (e *retryableError) Retryable() bool { return e.retryable }
+
+// MarkRetryable wraps err so that it reports whether the operation that
+// returned it can be retried. A nil err stays nil.
+func MarkRetryable(err error, retryable bool) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err, retryable: retryable}
+}
+
+// ClassifyRetryable decides the retry action for err. Context cancellation and
+// deadlines always fail, errors implementing Retryable decide for themselves
+// and any other error gets the unknown action.
+func ClassifyRetryable(err error, unknown RetryAction) RetryAction {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ActionFail
+	}
+	if retryable, ok := IsRetryable(err); ok {
+		if retryable {
+			return ActionRetry
+		}
+		return ActionFail
+	}
+	return unknown
+}
+
+// defaultErrorClassifier classifies errors with ClassifyRetryable, retrying
+// unknown errors
+func defaultErrorClassifier(err error) RetryAction {
+	return ClassifyRetryable(err, ActionRetry)
+}
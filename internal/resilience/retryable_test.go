@@ -0,0 +1,62 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// permanentError is an error type that reports its own retryability
+type permanentError struct{}
+
+func (permanentError) Error() string   { return "duplicate request" }
+func (permanentError) Retryable() bool { return false }
+
+func TestClassifyRetryable(t *testing.T) {
+	unknown := errors.New("write failed")
+
+	tests := []struct {
+		name     string
+		err      error
+		fallback RetryAction
+		expected RetryAction
+	}{
+		{"retryable", MarkRetryable(unknown, true), ActionFail, ActionRetry},
+		{"not retryable", MarkRetryable(unknown, false), ActionRetry, ActionFail},
+		{"wrapped retryable", fmt.Errorf("dial: %w", MarkRetryable(unknown, true)), ActionFail, ActionRetry},
+		{"own type", fmt.Errorf("send: %w", permanentError{}), ActionRetry, ActionFail},
+		{"outermost mark wins", MarkRetryable(MarkRetryable(unknown, false), true), ActionFail, ActionRetry},
+		{"canceled", context.Canceled, ActionRetry, ActionFail},
+		{"wrapped deadline", fmt.Errorf("read: %w", context.DeadlineExceeded), ActionRetry, ActionFail},
+		// Context errors fail even when marked retryable
+		{"retryable canceled", MarkRetryable(context.Canceled, true), ActionRetry, ActionFail},
+		{"unknown with retry fallback", unknown, ActionRetry, ActionRetry},
+		{"unknown with fail fallback", unknown, ActionFail, ActionFail},
+		{"unknown with skip fallback", unknown, ActionSkip, ActionSkip},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyRetryable(tt.err, tt.fallback); got != tt.expected {
+			t.Errorf("%s: expected action %d, got %d", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestMarkRetryable(t *testing.T) {
+	if MarkRetryable(nil, true) != nil {
+		t.Error("Expected a nil error to stay nil")
+	}
+
+	cause := errors.New("connection reset")
+	err := MarkRetryable(cause, true)
+	if err.Error() != cause.Error() {
+		t.Errorf("Expected message %q, got %q", cause.Error(), err.Error())
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected the marked error to wrap its cause")
+	}
+	if _, ok := IsRetryable(cause); ok {
+		t.Error("Expected an unmarked error to have unknown retryability")
+	}
+}
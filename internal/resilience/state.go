@@ -44,6 +44,14 @@ func (cs *CircuitBreakerStates) AddBreaker(name string, breaker *CircuitBreaker)
 	return nil
 }
 
+// RemoveBreaker removes a circuit breaker from the state manager
+func (cs *CircuitBreakerStates) RemoveBreaker(name string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	delete(cs.breakers, name)
+}
+
 // GetBreaker retrieves a circuit breaker by name
 func (cs *CircuitBreakerStates) GetBreaker(name string) (*CircuitBreaker, bool) {
 	cs.mu.RLock()
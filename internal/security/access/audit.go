@@ -0,0 +1,154 @@
+package access
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditOutcome is the result recorded for an audit event
+type AuditOutcome string
+
+const (
+	AuditAllow   AuditOutcome = "allow"
+	AuditDeny    AuditOutcome = "deny"
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// Audit event types
+const (
+	AuditPermissionCheck = "permission_check"
+	AuditPolicyCheck     = "policy_check"
+	AuditPolicyAdd       = "policy_add"
+	AuditPolicyUpdate    = "policy_update"
+	AuditPolicyDelete    = "policy_delete"
+	AuditModelReload     = "model_reload"
+	AuditSessionCreate   = "session_create"
+	AuditSessionRefresh  = "session_refresh"
+	AuditSessionRevoke   = "session_revoke"
+	AuditSessionExpire   = "session_expire"
+)
+
+// AuditEvent is a single entry in the audit trail
+type AuditEvent struct {
+	Timestamp     time.Time         `json:"timestamp"`
+	Type          string            `json:"type"`
+	Actor         string            `json:"actor"`
+	Outcome       AuditOutcome      `json:"outcome"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Role          string            `json:"role,omitempty"`
+	Resource      string            `json:"resource,omitempty"`
+	Action        string            `json:"action,omitempty"`
+	Session       string            `json:"session,omitempty"`
+	Details       map[string]string `json:"details,omitempty"`
+}
+
+// AuditSink receives audit events
+type AuditSink interface {
+	Record(event AuditEvent)
+}
+
+// Option configures an RBACManager or SessionManager
+type Option func(*managerOptions)
+
+type managerOptions struct {
+	audit AuditSink
+}
+
+// WithAuditSink records access decisions and session events to sink
+func WithAuditSink(sink AuditSink) Option {
+	return func(o *managerOptions) {
+		o.audit = sink
+	}
+}
+
+func applyOptions(opts []Option) managerOptions {
+	var o managerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// recordAudit sends an event to sink, if one is configured
+func recordAudit(sink AuditSink, event AuditEvent) {
+	if sink == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	sink.Record(event)
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context whose audit events carry id
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID stored in ctx, if any
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// sessionRef identifies a session in the audit trail without exposing its token
+func sessionRef(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// FileAuditSink appends audit events to a file, one JSON object per line
+type FileAuditSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	enc    *json.Encoder
+	logger *zap.Logger
+}
+
+// NewFileAuditSink opens path for appending audit events
+func NewFileAuditSink(path string, logger *zap.Logger) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+
+	return &FileAuditSink{
+		file:   file,
+		enc:    json.NewEncoder(file),
+		logger: logger,
+	}, nil
+}
+
+// Record implements AuditSink. Write failures are logged, not returned, so auditing
+// never changes an access decision.
+func (s *FileAuditSink) Record(event AuditEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.enc.Encode(event); err != nil {
+		s.logger.Error("Failed to write audit event",
+			zap.String("type", event.Type),
+			zap.Error(err))
+	}
+}
+
+// Close closes the audit log file
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
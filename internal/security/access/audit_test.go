@@ -0,0 +1,175 @@
+package access
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// memoryAuditSink keeps audit events in memory
+type memoryAuditSink struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (s *memoryAuditSink) Record(event AuditEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+// ofType returns the recorded events of the given type
+func (s *memoryAuditSink) ofType(eventType string) []AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []AuditEvent
+	for _, event := range s.events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestAuditDeniedPolicyCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileAuditSink(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create audit sink: %v", err)
+	}
+
+	m := NewRBACManager(zap.NewNop(), WithAuditSink(sink))
+	if err := m.AddRole(&Role{Name: "viewer", Permissions: []Permission{{Name: "view_status", Resource: "status", Action: "read"}}}); err != nil {
+		t.Fatalf("Failed to add role: %v", err)
+	}
+
+	ctx := WithCorrelationID(context.Background(), "req-42")
+	allowed, err := m.CheckPolicy(ctx, "viewer", "tunnels", "write")
+	if err != nil {
+		t.Fatalf("Failed to check policy: %v", err)
+	}
+	if allowed {
+		t.Fatal("Expected viewer to be denied tunnels:write")
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close audit sink: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Failed to decode audit line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 audit event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Type != AuditPolicyCheck || event.Outcome != AuditDeny {
+		t.Errorf("Expected a denied policy check, got %s/%s", event.Type, event.Outcome)
+	}
+	if event.Role != "viewer" || event.Resource != "tunnels" || event.Action != "write" {
+		t.Errorf("Expected viewer tunnels:write, got %s %s:%s", event.Role, event.Resource, event.Action)
+	}
+	if event.CorrelationID != "req-42" {
+		t.Errorf("Expected correlation ID req-42, got %q", event.CorrelationID)
+	}
+	if event.Timestamp.IsZero() {
+		t.Error("Expected a timestamp")
+	}
+}
+
+func TestAuditPermissionAndPolicyChanges(t *testing.T) {
+	sink := &memoryAuditSink{}
+	m := NewRBACManager(zap.NewNop(), WithAuditSink(sink))
+	if err := m.AddRole(&Role{Name: "operator", Permissions: []Permission{{Name: "manage_tunnels", Resource: "tunnels", Action: "*"}}}); err != nil {
+		t.Fatalf("Failed to add role: %v", err)
+	}
+
+	if ok, _ := m.CheckPermission("operator", "tunnels", "write"); !ok {
+		t.Fatal("Expected operator to be allowed tunnels:write")
+	}
+	if _, err := m.CheckPermission("missing", "tunnels", "write"); err == nil {
+		t.Fatal("Expected an error for a missing role")
+	}
+
+	checks := sink.ofType(AuditPermissionCheck)
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 permission checks, got %d", len(checks))
+	}
+	if checks[0].Outcome != AuditAllow {
+		t.Errorf("Expected allow, got %s", checks[0].Outcome)
+	}
+	if checks[1].Outcome != AuditDeny || checks[1].Details["error"] == "" {
+		t.Errorf("Expected deny with an error detail, got %s %v", checks[1].Outcome, checks[1].Details)
+	}
+
+	policy := &Policy{Name: "ops", Roles: []string{"operator"}, Resources: []string{"logs"}, Actions: []string{"read"}}
+	if err := m.AddPolicy(policy); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+	if err := m.AddPolicy(policy); err == nil {
+		t.Fatal("Expected an error adding a duplicate policy")
+	}
+	if err := m.DeletePolicy("ops"); err != nil {
+		t.Fatalf("Failed to delete policy: %v", err)
+	}
+
+	adds := sink.ofType(AuditPolicyAdd)
+	if len(adds) != 2 || adds[0].Outcome != AuditSuccess || adds[1].Outcome != AuditFailure {
+		t.Errorf("Expected a successful and a failed policy add, got %+v", adds)
+	}
+	if deletes := sink.ofType(AuditPolicyDelete); len(deletes) != 1 || deletes[0].Details["policy"] != "ops" {
+		t.Errorf("Expected a policy delete for ops, got %+v", deletes)
+	}
+}
+
+func TestAuditSessionEvents(t *testing.T) {
+	sink := &memoryAuditSink{}
+	m := NewSessionManager(DefaultSessionConfig(), zap.NewNop(), WithAuditSink(sink))
+
+	ctx := WithCorrelationID(context.Background(), "login-7")
+	session, err := m.CreateSession(ctx, "alice", "admin", "10.0.0.2", "test")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := m.RevokeSession(session.ID); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+
+	creates := sink.ofType(AuditSessionCreate)
+	if len(creates) != 1 {
+		t.Fatalf("Expected 1 session create, got %d", len(creates))
+	}
+	if creates[0].Actor != "alice" || creates[0].CorrelationID != "login-7" {
+		t.Errorf("Expected alice with correlation ID login-7, got %s %q", creates[0].Actor, creates[0].CorrelationID)
+	}
+	revokes := sink.ofType(AuditSessionRevoke)
+	if len(revokes) != 1 || revokes[0].Session != creates[0].Session {
+		t.Errorf("Expected a revoke for session %s, got %+v", creates[0].Session, revokes)
+	}
+
+	// Tokens are never written to the audit trail
+	for _, event := range sink.events {
+		if strings.Contains(event.Session, session.ID) || event.CorrelationID == session.ID {
+			t.Errorf("Expected session token to be hashed in %s event", event.Type)
+		}
+	}
+}
@@ -3,10 +3,14 @@ package access
 import (
 	"fmt"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
 // IPFilterRule represents an IP filtering rule
@@ -20,45 +24,74 @@ type IPFilterRule struct {
 	Source      string // "manual", "automatic", etc.
 }
 
-// IPFilterManager manages IP filtering rules
+// IPFilterManager manages IP filtering rules. Checks read an immutable rule set
+// that is swapped atomically on every change, so an update applies to the next
+// check without blocking checks in progress.
 type IPFilterManager struct {
-	rules    []*IPFilterRule
-	ruleLock sync.RWMutex
+	rules atomic.Pointer[[]*IPFilterRule]
+	// ruleLock serializes rule set updates
+	ruleLock sync.Mutex
 	logger   *zap.Logger
+	// defaultDeny refuses addresses that no rule matches, see SetDefaultAction
+	defaultDeny atomic.Bool
 }
 
 // NewIPFilterManager creates a new IP filter manager
 func NewIPFilterManager(logger *zap.Logger) *IPFilterManager {
-	return &IPFilterManager{
-		rules:  make([]*IPFilterRule, 0),
+	m := &IPFilterManager{
 		logger: logger,
 	}
+	m.rules.Store(&[]*IPFilterRule{})
+	return m
 }
 
-// AddRule adds a new IP filtering rule
-func (m *IPFilterManager) AddRule(cidr string, action string, description string) error {
+// currentRules returns the active rule set. It must not be modified.
+func (m *IPFilterManager) currentRules() []*IPFilterRule {
+	return *m.rules.Load()
+}
+
+// appendRule adds a rule to a copy of the rule set and swaps it in
+func (m *IPFilterManager) appendRule(rule *IPFilterRule) {
+	m.ruleLock.Lock()
+	defer m.ruleLock.Unlock()
+
+	old := m.currentRules()
+	rules := make([]*IPFilterRule, len(old), len(old)+1)
+	copy(rules, old)
+	rules = append(rules, rule)
+	m.rules.Store(&rules)
+}
+
+// parseRule validates a CIDR and action and builds a rule
+func parseRule(cidr, action, description string) (*IPFilterRule, error) {
 	_, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
-		return fmt.Errorf("invalid CIDR notation: %v", err)
+		return nil, fmt.Errorf("invalid CIDR notation: %v", err)
 	}
 
 	if action != "allow" && action != "deny" {
-		return fmt.Errorf("invalid action: %s (must be 'allow' or 'deny')", action)
+		return nil, fmt.Errorf("invalid action: %s (must be 'allow' or 'deny')", action)
 	}
 
-	rule := &IPFilterRule{
+	now := time.Now()
+	return &IPFilterRule{
 		IPNet:       ipNet,
 		Action:      action,
 		Description: description,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
 		Source:      "manual",
-	}
+	}, nil
+}
 
-	m.ruleLock.Lock()
-	defer m.ruleLock.Unlock()
+// AddRule adds a new IP filtering rule
+func (m *IPFilterManager) AddRule(cidr string, action string, description string) error {
+	rule, err := parseRule(cidr, action, description)
+	if err != nil {
+		return err
+	}
 
-	m.rules = append(m.rules, rule)
+	m.appendRule(rule)
 
 	m.logger.Info("Added IP filter rule",
 		zap.String("cidr", cidr),
@@ -70,31 +103,16 @@ func (m *IPFilterManager) AddRule(cidr string, action string, description string
 
 // AddTemporaryRule adds a temporary IP filtering rule with expiration
 func (m *IPFilterManager) AddTemporaryRule(cidr string, action string, description string, duration time.Duration) error {
-	_, ipNet, err := net.ParseCIDR(cidr)
+	rule, err := parseRule(cidr, action, description)
 	if err != nil {
-		return fmt.Errorf("invalid CIDR notation: %v", err)
-	}
-
-	if action != "allow" && action != "deny" {
-		return fmt.Errorf("invalid action: %s (must be 'allow' or 'deny')", action)
+		return err
 	}
 
 	expiresAt := time.Now().Add(duration)
+	rule.ExpiresAt = &expiresAt
+	rule.Source = "temporary"
 
-	rule := &IPFilterRule{
-		IPNet:       ipNet,
-		Action:      action,
-		Description: description,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		ExpiresAt:   &expiresAt,
-		Source:      "temporary",
-	}
-
-	m.ruleLock.Lock()
-	defer m.ruleLock.Unlock()
-
-	m.rules = append(m.rules, rule)
+	m.appendRule(rule)
 
 	m.logger.Info("Added temporary IP filter rule",
 		zap.String("cidr", cidr),
@@ -118,7 +136,7 @@ func (m *IPFilterManager) RemoveRule(cidr string) error {
 	newRules := []*IPFilterRule{}
 	found := false
 
-	for _, rule := range m.rules {
+	for _, rule := range m.currentRules() {
 		if rule.IPNet.String() == ipNet.String() {
 			found = true
 			continue
@@ -130,7 +148,7 @@ func (m *IPFilterManager) RemoveRule(cidr string) error {
 		return fmt.Errorf("rule for CIDR %s not found", cidr)
 	}
 
-	m.rules = newRules
+	m.rules.Store(&newRules)
 
 	m.logger.Info("Removed IP filter rule",
 		zap.String("cidr", cidr))
@@ -144,38 +162,52 @@ func (m *IPFilterManager) CheckIP(ipStr string) (bool, string, error) {
 	if ip == nil {
 		return false, "", fmt.Errorf("invalid IP address: %s", ipStr)
 	}
+	allowed, reason := m.CheckNetIP(ip)
+	return allowed, reason, nil
+}
 
-	m.ruleLock.RLock()
-	defer m.ruleLock.RUnlock()
-
-	// Clean up expired rules
-	m.cleanupExpiredRules()
+// CheckNetIP checks if a parsed IP address is allowed based on filtering rules
+func (m *IPFilterManager) CheckNetIP(ip net.IP) (bool, string) {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
 
 	// Check rules in order, first match wins
-	for _, rule := range m.rules {
+	for _, rule := range m.currentRules() {
 		if rule.IPNet.Contains(ip) {
 			if rule.ExpiresAt != nil && time.Now().After(*rule.ExpiresAt) {
 				continue // Skip expired rules
 			}
-			return rule.Action == "allow", rule.Description, nil
+			return rule.Action == "allow", rule.Description
 		}
 	}
 
-	// No matching rules - default to allow
-	return true, "no matching rules", nil
+	// No matching rules - apply the default action
+	if m.defaultDeny.Load() {
+		return false, "denied by default"
+	}
+	return true, "no matching rules"
+}
+
+// SetDefaultAction sets whether addresses that no rule matches are allowed or
+// denied. The default is allow.
+func (m *IPFilterManager) SetDefaultAction(action string) error {
+	if action != "allow" && action != "deny" {
+		return fmt.Errorf("invalid default action: %s (must be 'allow' or 'deny')", action)
+	}
+	m.defaultDeny.Store(action == "deny")
+	return nil
 }
 
 // ListRules returns all IP filtering rules
 func (m *IPFilterManager) ListRules() ([]*IPFilterRule, error) {
-	m.ruleLock.RLock()
-	defer m.ruleLock.RUnlock()
-
 	// Clean up expired rules
-	m.cleanupExpiredRules()
+	m.ClearExpiredRules()
 
 	// Return a copy of the rules
-	rules := make([]*IPFilterRule, len(m.rules))
-	copy(rules, m.rules)
+	current := m.currentRules()
+	rules := make([]*IPFilterRule, len(current))
+	copy(rules, current)
 
 	return rules, nil
 }
@@ -188,12 +220,12 @@ func (m *IPFilterManager) ClearExpiredRules() {
 	m.cleanupExpiredRules()
 }
 
-// cleanupExpiredRules removes expired rules (internal method)
+// cleanupExpiredRules removes expired rules. The caller must hold ruleLock.
 func (m *IPFilterManager) cleanupExpiredRules() {
 	now := time.Now()
 	newRules := []*IPFilterRule{}
 
-	for _, rule := range m.rules {
+	for _, rule := range m.currentRules() {
 		if rule.ExpiresAt == nil || now.Before(*rule.ExpiresAt) {
 			newRules = append(newRules, rule)
 		} else {
@@ -203,7 +235,7 @@ func (m *IPFilterManager) cleanupExpiredRules() {
 		}
 	}
 
-	m.rules = newRules
+	m.rules.Store(&newRules)
 }
 
 // AddCommonSecurityRules adds common security filtering rules
@@ -282,3 +314,118 @@ func (m *IPFilterManager) CheckAndUpdateRateLimit(ipStr string, maxAttempts int,
 
 	return true, nil
 }
+
+// IPFilterRuleSpec is a rule as written in an ACL file
+type IPFilterRuleSpec struct {
+	CIDR        string `yaml:"cidr"`
+	Action      string `yaml:"action"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// ReplaceRules validates a complete rule set and swaps it in. Unexpired temporary
+// rules, such as rate limit blocks, are kept after the new rules. If any rule is
+// invalid the current rules stay active.
+func (m *IPFilterManager) ReplaceRules(specs []IPFilterRuleSpec) error {
+	rules := make([]*IPFilterRule, 0, len(specs))
+	for i, spec := range specs {
+		rule, err := parseRule(spec.CIDR, spec.Action, spec.Description)
+		if err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	m.ruleLock.Lock()
+	now := time.Now()
+	for _, rule := range m.currentRules() {
+		if rule.ExpiresAt != nil && now.Before(*rule.ExpiresAt) {
+			rules = append(rules, rule)
+		}
+	}
+	m.rules.Store(&rules)
+	m.ruleLock.Unlock()
+
+	m.logger.Info("Replaced IP filter rules",
+		zap.Int("rules", len(rules)))
+
+	return nil
+}
+
+// ReloadRules reads an ACL file and replaces the current rules with it. The file
+// holds a list of rules under "rules", checked in order with the first match
+// winning. An unreadable or invalid file leaves the current rules active.
+func (m *IPFilterManager) ReloadRules(path string) error {
+	specs, err := readRuleFile(path)
+	if err != nil {
+		return err
+	}
+	if err := m.ReplaceRules(specs); err != nil {
+		return fmt.Errorf("invalid ACL file: %v", err)
+	}
+	return nil
+}
+
+// readRuleFile reads the rules of an ACL file
+func readRuleFile(path string) ([]IPFilterRuleSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACL file: %v", err)
+	}
+
+	var file struct {
+		Rules []IPFilterRuleSpec `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse ACL file: %v", err)
+	}
+	return file.Rules, nil
+}
+
+// ACLConfigured reports whether an ACL configuration restricts connections
+func ACLConfigured(cfg types.ACLConfig) bool {
+	return cfg.File != "" || len(cfg.Rules) > 0 || cfg.DefaultAction == "deny"
+}
+
+// NewIPFilterFromConfig creates an IP filter manager enforcing an ACL
+// configuration, see ApplyConfig
+func NewIPFilterFromConfig(cfg types.ACLConfig, logger *zap.Logger) (*IPFilterManager, error) {
+	m := NewIPFilterManager(logger)
+	if err := m.ApplyConfig(cfg); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ApplyConfig replaces the current rules with the inline rules of an ACL
+// configuration followed by those of its file, and sets its default action. If
+// any of them is invalid the current rules and default action stay active.
+func (m *IPFilterManager) ApplyConfig(cfg types.ACLConfig) error {
+	defaultAction := cfg.DefaultAction
+	if defaultAction == "" {
+		defaultAction = "allow"
+	}
+	if defaultAction != "allow" && defaultAction != "deny" {
+		return fmt.Errorf("invalid default action: %s (must be 'allow' or 'deny')", defaultAction)
+	}
+
+	specs := make([]IPFilterRuleSpec, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		description := rule.Description
+		if description == "" {
+			description = rule.Action + " " + rule.Network
+		}
+		specs = append(specs, IPFilterRuleSpec{CIDR: rule.Network, Action: rule.Action, Description: description})
+	}
+	if cfg.File != "" {
+		fileSpecs, err := readRuleFile(cfg.File)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, fileSpecs...)
+	}
+
+	if err := m.ReplaceRules(specs); err != nil {
+		return fmt.Errorf("invalid ACL: %v", err)
+	}
+	return m.SetDefaultAction(defaultAction)
+}
@@ -0,0 +1,135 @@
+package access
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+func TestReloadRules(t *testing.T) {
+	dir := t.TempDir()
+	m := NewIPFilterManager(zap.NewNop())
+	if err := m.AddTemporaryRule("203.0.113.9/32", "deny", "Rate limit exceeded", time.Minute); err != nil {
+		t.Fatalf("Failed to add temporary rule: %v", err)
+	}
+
+	valid := filepath.Join(dir, "acl.yaml")
+	if err := os.WriteFile(valid, []byte(`
+rules:
+  - cidr: 192.0.2.0/24
+    action: deny
+    description: reported scanner
+  - cidr: 0.0.0.0/0
+    action: allow
+`), 0600); err != nil {
+		t.Fatalf("Failed to write ACL file: %v", err)
+	}
+	if err := m.ReloadRules(valid); err != nil {
+		t.Fatalf("Failed to reload rules: %v", err)
+	}
+
+	if allowed, reason, _ := m.CheckIP("192.0.2.10"); allowed || reason != "reported scanner" {
+		t.Errorf("Expected 192.0.2.10 to be denied, got %v (%s)", allowed, reason)
+	}
+	if allowed, _, _ := m.CheckIP("198.51.100.1"); !allowed {
+		t.Error("Expected 198.51.100.1 to be allowed")
+	}
+	if rules, _ := m.ListRules(); len(rules) != 3 {
+		t.Errorf("Expected 2 file rules and the temporary rule, got %d", len(rules))
+	}
+
+	// An invalid file leaves the current rules active
+	broken := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(broken, []byte(`
+rules:
+  - cidr: 10.0.0.0/8
+    action: allow
+  - cidr: 192.0.2.300/24
+    action: deny
+`), 0600); err != nil {
+		t.Fatalf("Failed to write ACL file: %v", err)
+	}
+	err := m.ReloadRules(broken)
+	if err == nil || !strings.Contains(err.Error(), "rule 2") {
+		t.Fatalf("Expected error for rule 2, got %v", err)
+	}
+	if allowed, _, _ := m.CheckIP("192.0.2.10"); allowed {
+		t.Error("Expected previous rules to stay active")
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "acl.yaml")
+	if err := os.WriteFile(file, []byte(`
+rules:
+  - cidr: 198.51.100.0/24
+    action: allow
+`), 0600); err != nil {
+		t.Fatalf("Failed to write ACL file: %v", err)
+	}
+
+	m, err := NewIPFilterFromConfig(types.ACLConfig{
+		File: file,
+		Rules: []types.ACLRule{
+			// Overlapping networks: the narrower deny comes first and wins
+			{Network: "10.1.2.0/24", Action: "deny", Description: "quarantined subnet"},
+			{Network: "10.0.0.0/8", Action: "allow"},
+			{Network: "2001:db8::/32", Action: "allow"},
+		},
+		DefaultAction: "deny",
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to apply ACL: %v", err)
+	}
+
+	tests := []struct {
+		ip      string
+		allowed bool
+		reason  string
+	}{
+		{"10.1.2.3", false, "quarantined subnet"},
+		{"10.1.3.3", true, "allow 10.0.0.0/8"},
+		{"2001:db8::1", true, "allow 2001:db8::/32"},
+		{"198.51.100.7", true, ""},
+		{"203.0.113.1", false, "denied by default"},
+		{"2001:db9::1", false, "denied by default"},
+	}
+	for _, tt := range tests {
+		allowed, reason, err := m.CheckIP(tt.ip)
+		if err != nil {
+			t.Fatalf("Failed to check %s: %v", tt.ip, err)
+		}
+		if allowed != tt.allowed || reason != tt.reason {
+			t.Errorf("Expected %s to be allowed=%v (%q), got %v (%q)", tt.ip, tt.allowed, tt.reason, allowed, reason)
+		}
+	}
+
+	// The default action is allow unless configured
+	if err := m.ApplyConfig(types.ACLConfig{Rules: []types.ACLRule{{Network: "10.1.2.0/24", Action: "deny"}}}); err != nil {
+		t.Fatalf("Failed to apply ACL: %v", err)
+	}
+	if allowed, reason := m.CheckNetIP(net.ParseIP("203.0.113.1")); !allowed || reason != "no matching rules" {
+		t.Errorf("Expected unmatched addresses to be allowed by default, got %v (%s)", allowed, reason)
+	}
+
+	// An invalid rule or default action leaves the current rules active
+	for _, cfg := range []types.ACLConfig{
+		{Rules: []types.ACLRule{{Network: "10.0.0.0/33", Action: "allow"}}},
+		{Rules: []types.ACLRule{{Network: "10.0.0.0/8", Action: "permit"}}},
+		{DefaultAction: "reject"},
+	} {
+		if err := m.ApplyConfig(cfg); err == nil {
+			t.Errorf("Expected ACL %+v to be rejected", cfg)
+		}
+	}
+	if allowed, _, _ := m.CheckIP("10.1.2.3"); allowed {
+		t.Error("Expected the previous rules to stay active after a rejected ACL")
+	}
+}
@@ -0,0 +1,138 @@
+package access
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const validModel = `
+roles:
+  - name: viewer
+    permissions:
+      - name: view_status
+        resource: status
+        action: read
+  - name: operator
+    inherits: [viewer]
+    permissions:
+      - name: manage_tunnels
+        resource: tunnels/*
+        action: "*"
+policies:
+  - name: viewer_metrics
+    roles: [viewer]
+    resources: [metrics]
+    actions: [read]
+`
+
+// writeModel writes a model file and returns its path
+func writeModel(t *testing.T, dir, name, data string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write model: %v", err)
+	}
+	return path
+}
+
+func TestReloadModel(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestRBACManager(t, DefaultRoles()...)
+
+	if err := m.ReloadModel(writeModel(t, dir, "valid.yaml", validModel)); err != nil {
+		t.Fatalf("Failed to reload valid model: %v", err)
+	}
+	if _, err := m.GetRole("admin"); err == nil {
+		t.Error("Expected roles missing from the model to be removed")
+	}
+	if ok, err := m.CheckPermission("operator", "tunnels/conn-1", "write"); err != nil || !ok {
+		t.Errorf("Expected operator to manage tunnels, got %v (%v)", ok, err)
+	}
+	if ok, err := m.CheckPolicy(context.Background(), "operator", "metrics", "read"); err != nil || !ok {
+		t.Errorf("Expected operator to read metrics through viewer policy, got %v (%v)", ok, err)
+	}
+
+	broken := []struct {
+		name  string
+		model string
+		err   string
+	}{
+		{"undefined inherited role", `
+roles:
+  - name: operator
+    inherits: [viewer]
+`, "role viewer inherited by operator not found"},
+		{"cycle", `
+roles:
+  - name: a
+    inherits: [b]
+  - name: b
+    inherits: [a]
+`, "circular role inheritance"},
+		{"undefined policy role", `
+roles:
+  - name: viewer
+policies:
+  - name: admin_all
+    roles: [admin]
+    resources: ["*"]
+    actions: ["*"]
+`, "role admin used by policy admin_all not found"},
+		{"duplicate role", `
+roles:
+  - name: viewer
+  - name: viewer
+`, "defined more than once"},
+		{"malformed", "roles: [", "failed to parse RBAC model"},
+	}
+
+	for _, tt := range broken {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.ReloadModel(writeModel(t, dir, "broken.yaml", tt.model))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+			}
+
+			// The previous model stays active
+			if ok, err := m.CheckPermission("operator", "tunnels/conn-1", "write"); err != nil || !ok {
+				t.Errorf("Expected previous model to stay active, got %v (%v)", ok, err)
+			}
+			if roles, _ := m.ListRoles(); len(roles) != 2 {
+				t.Errorf("Expected 2 roles, got %d", len(roles))
+			}
+			if policies, _ := m.ListPolicies(); len(policies) != 1 {
+				t.Errorf("Expected 1 policy, got %d", len(policies))
+			}
+		})
+	}
+}
+
+func TestSaveModelRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rbac.yaml")
+	m := newTestRBACManager(t, DefaultRoles()...)
+	for _, policy := range DefaultPolicies() {
+		if err := m.AddPolicy(policy); err != nil {
+			t.Fatalf("Failed to add policy: %v", err)
+		}
+	}
+	if err := m.SaveModel(path); err != nil {
+		t.Fatalf("Failed to save model: %v", err)
+	}
+
+	reloaded := NewRBACManager(zap.NewNop())
+	if err := reloaded.ReloadModel(path); err != nil {
+		t.Fatalf("Failed to reload saved model: %v", err)
+	}
+	roles, _ := reloaded.ListRoles()
+	if len(roles) != len(DefaultRoles()) {
+		t.Errorf("Expected %d roles, got %d", len(DefaultRoles()), len(roles))
+	}
+	policies, _ := reloaded.ListPolicies()
+	if len(policies) != len(DefaultPolicies()) {
+		t.Errorf("Expected %d policies, got %d", len(DefaultPolicies()), len(policies))
+	}
+}
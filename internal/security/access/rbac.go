@@ -3,6 +3,7 @@ package access
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,47 +12,54 @@ import (
 
 // Role represents a user role with associated permissions
 type Role struct {
-	Name        string
-	Permissions []Permission
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	Name        string       `yaml:"name"`
+	Permissions []Permission `yaml:"permissions"`
+	// Inherits names roles whose permissions this role also has
+	Inherits  []string  `yaml:"inherits,omitempty"`
+	CreatedAt time.Time `yaml:"-"`
+	UpdatedAt time.Time `yaml:"-"`
 }
 
 // Permission represents an individual permission
 type Permission struct {
-	Name        string
-	Description string
-	Resource    string
-	Action      string
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Resource    string `yaml:"resource"`
+	Action      string `yaml:"action"`
 }
 
 // Policy represents an access control policy
 type Policy struct {
-	Name        string
-	Description string
-	Roles       []string
-	Resources   []string
-	Actions     []string
-	Conditions  map[string]string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description,omitempty"`
+	Roles       []string          `yaml:"roles"`
+	Resources   []string          `yaml:"resources"`
+	Actions     []string          `yaml:"actions"`
+	Conditions  map[string]string `yaml:"conditions,omitempty"`
+	CreatedAt   time.Time         `yaml:"-"`
+	UpdatedAt   time.Time         `yaml:"-"`
 }
 
 // RBACManager manages role-based access control
 type RBACManager struct {
 	roles      map[string]*Role
+	resolved   map[string]*resolvedRole // By role name, rebuilt on every role change
 	policies   map[string]*Policy
 	roleLock   sync.RWMutex
 	policyLock sync.RWMutex
 	logger     *zap.Logger
+	audit      AuditSink
 }
 
 // NewRBACManager creates a new RBAC manager
-func NewRBACManager(logger *zap.Logger) *RBACManager {
+func NewRBACManager(logger *zap.Logger, opts ...Option) *RBACManager {
+	o := applyOptions(opts)
 	return &RBACManager{
 		roles:    make(map[string]*Role),
+		resolved: make(map[string]*resolvedRole),
 		policies: make(map[string]*Policy),
 		logger:   logger,
+		audit:    o.audit,
 	}
 }
 
@@ -67,10 +75,12 @@ func (m *RBACManager) AddRole(role *Role) error {
 	role.CreatedAt = time.Now()
 	role.UpdatedAt = time.Now()
 	m.roles[role.Name] = role
+	m.resolveAll()
 
 	m.logger.Info("Added new role",
 		zap.String("role", role.Name),
-		zap.Int("permissions", len(role.Permissions)))
+		zap.Int("permissions", len(role.Permissions)),
+		zap.Strings("inherits", role.Inherits))
 
 	return nil
 }
@@ -99,6 +109,7 @@ func (m *RBACManager) UpdateRole(role *Role) error {
 
 	role.UpdatedAt = time.Now()
 	m.roles[role.Name] = role
+	m.resolveAll()
 
 	m.logger.Info("Updated role",
 		zap.String("role", role.Name),
@@ -117,6 +128,7 @@ func (m *RBACManager) DeleteRole(name string) error {
 	}
 
 	delete(m.roles, name)
+	m.resolveAll()
 
 	m.logger.Info("Deleted role",
 		zap.String("role", name))
@@ -130,6 +142,7 @@ func (m *RBACManager) AddPolicy(policy *Policy) error {
 	defer m.policyLock.Unlock()
 
 	if _, exists := m.policies[policy.Name]; exists {
+		m.auditPolicyChange(AuditPolicyAdd, policy.Name, AuditFailure)
 		return fmt.Errorf("policy %s already exists", policy.Name)
 	}
 
@@ -141,6 +154,7 @@ func (m *RBACManager) AddPolicy(policy *Policy) error {
 		zap.String("policy", policy.Name),
 		zap.Strings("roles", policy.Roles),
 		zap.Strings("resources", policy.Resources))
+	m.auditPolicyChange(AuditPolicyAdd, policy.Name, AuditSuccess)
 
 	return nil
 }
@@ -164,6 +178,7 @@ func (m *RBACManager) UpdatePolicy(policy *Policy) error {
 	defer m.policyLock.Unlock()
 
 	if _, exists := m.policies[policy.Name]; !exists {
+		m.auditPolicyChange(AuditPolicyUpdate, policy.Name, AuditFailure)
 		return fmt.Errorf("policy %s does not exist", policy.Name)
 	}
 
@@ -173,6 +188,7 @@ func (m *RBACManager) UpdatePolicy(policy *Policy) error {
 	m.logger.Info("Updated policy",
 		zap.String("policy", policy.Name),
 		zap.Strings("roles", policy.Roles))
+	m.auditPolicyChange(AuditPolicyUpdate, policy.Name, AuditSuccess)
 
 	return nil
 }
@@ -183,6 +199,7 @@ func (m *RBACManager) DeletePolicy(name string) error {
 	defer m.policyLock.Unlock()
 
 	if _, exists := m.policies[name]; !exists {
+		m.auditPolicyChange(AuditPolicyDelete, name, AuditFailure)
 		return fmt.Errorf("policy %s does not exist", name)
 	}
 
@@ -190,57 +207,71 @@ func (m *RBACManager) DeletePolicy(name string) error {
 
 	m.logger.Info("Deleted policy",
 		zap.String("policy", name))
+	m.auditPolicyChange(AuditPolicyDelete, name, AuditSuccess)
 
 	return nil
 }
 
-// CheckPermission checks if a role has a specific permission
+// CheckPermission checks if a role has a specific permission, directly or through
+// the roles it inherits. Resources and actions are matched as described for
+// matchPermission.
 func (m *RBACManager) CheckPermission(roleName, resource, action string) (bool, error) {
+	allowed, err := m.checkPermission(roleName, resource, action)
+	m.auditDecision(AuditPermissionCheck, "", roleName, resource, action, allowed, err)
+	return allowed, err
+}
+
+// checkPermission implements CheckPermission without recording an audit event
+func (m *RBACManager) checkPermission(roleName, resource, action string) (bool, error) {
 	m.roleLock.RLock()
 	defer m.roleLock.RUnlock()
 
-	role, exists := m.roles[roleName]
-	if !exists {
-		return false, fmt.Errorf("role %s not found", roleName)
-	}
-
-	for _, permission := range role.Permissions {
-		if permission.Resource == resource && permission.Action == action {
-			return true, nil
-		}
+	role, err := m.resolvedRole(roleName)
+	if err != nil {
+		return false, err
 	}
 
-	return false, nil
+	_, found := matchPermission(role.permissions, resource, action)
+	return found, nil
 }
 
-// CheckPolicy checks if a request complies with access policies
+// CheckPolicy checks if a request complies with access policies. Policies granted to
+// an inherited role also apply.
 func (m *RBACManager) CheckPolicy(ctx context.Context, roleName, resource, action string) (bool, error) {
+	allowed, err := m.checkPolicy(roleName, resource, action)
+	m.auditDecision(AuditPolicyCheck, CorrelationID(ctx), roleName, resource, action, allowed, err)
+	return allowed, err
+}
+
+// checkPolicy implements CheckPolicy without recording an audit event
+func (m *RBACManager) checkPolicy(roleName, resource, action string) (bool, error) {
 	m.policyLock.RLock()
 	defer m.policyLock.RUnlock()
+	m.roleLock.RLock()
+	defer m.roleLock.RUnlock()
 
-	// First check if the role has direct permission
-	hasPermission, err := m.CheckPermission(roleName, resource, action)
+	role, err := m.resolvedRole(roleName)
 	if err != nil {
 		return false, err
 	}
-	if hasPermission {
+
+	// First check if the role has direct permission
+	if _, found := matchPermission(role.permissions, resource, action); found {
 		return true, nil
 	}
 
-	// Check policies that apply to the role
+	// Check policies that apply to the role or a role it inherits
 	for _, policy := range m.policies {
-		for _, role := range policy.Roles {
-			if role == roleName {
-				// Check if resource is allowed
-				for _, allowedResource := range policy.Resources {
-					if allowedResource == resource || allowedResource == "*" {
-						// Check if action is allowed
-						for _, allowedAction := range policy.Actions {
-							if allowedAction == action || allowedAction == "*" {
-								return true, nil
-							}
-						}
-					}
+		if !policyApplies(policy, role.names) {
+			continue
+		}
+		for _, allowedResource := range policy.Resources {
+			if !resourceMatches(allowedResource, resource) {
+				continue
+			}
+			for _, allowedAction := range policy.Actions {
+				if actionMatches(allowedAction, action) {
+					return true, nil
 				}
 			}
 		}
@@ -249,6 +280,195 @@ func (m *RBACManager) CheckPolicy(ctx context.Context, roleName, resource, actio
 	return false, nil
 }
 
+// policyApplies reports whether a policy grants to one of the named roles
+func policyApplies(policy *Policy, roleNames []string) bool {
+	for _, granted := range policy.Roles {
+		for _, name := range roleNames {
+			if granted == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// auditDecision records the outcome of an access check
+func (m *RBACManager) auditDecision(eventType, correlationID, roleName, resource, action string, allowed bool, err error) {
+	if m.audit == nil {
+		return
+	}
+
+	event := AuditEvent{
+		Type:          eventType,
+		Actor:         roleName,
+		Outcome:       AuditDeny,
+		CorrelationID: correlationID,
+		Role:          roleName,
+		Resource:      resource,
+		Action:        action,
+	}
+	if allowed {
+		event.Outcome = AuditAllow
+	}
+	if err != nil {
+		event.Details = map[string]string{"error": err.Error()}
+	}
+	recordAudit(m.audit, event)
+}
+
+// auditPolicyChange records a change to the policy set
+func (m *RBACManager) auditPolicyChange(eventType, policyName string, outcome AuditOutcome) {
+	recordAudit(m.audit, AuditEvent{
+		Type:    eventType,
+		Actor:   "system",
+		Outcome: outcome,
+		Details: map[string]string{"policy": policyName},
+	})
+}
+
+// matchPermission returns the most specific permission granting an action on a
+// resource. A permission resource matches exactly, as a prefix wildcard ("tunnels/*"
+// covers "tunnels/conn-123" but not "tunnels"), or as "*" for any resource. An exact
+// match takes precedence over the longest prefix wildcard, which takes precedence over
+// "*". The action must match exactly or be "*".
+func matchPermission(permissions []Permission, resource, action string) (Permission, bool) {
+	best := -1
+	bestRank := -1
+	for i, permission := range permissions {
+		if !actionMatches(permission.Action, action) || !resourceMatches(permission.Resource, resource) {
+			continue
+		}
+
+		// Rank: "*" is 0, prefix wildcards by prefix length, exact above all prefixes
+		rank := 0
+		switch {
+		case permission.Resource == resource:
+			rank = len(resource) + 1
+		case permission.Resource != "*":
+			rank = len(permission.Resource) - 1
+		}
+		if rank > bestRank {
+			best, bestRank = i, rank
+		}
+	}
+
+	if best < 0 {
+		return Permission{}, false
+	}
+	return permissions[best], true
+}
+
+// resourceMatches reports whether a permission or policy resource covers resource
+func resourceMatches(pattern, resource string) bool {
+	if pattern == "*" || pattern == resource {
+		return true
+	}
+	if n := len(pattern); n >= 2 && pattern[n-2:] == "/*" {
+		prefix := pattern[:n-1] // keeps the trailing slash
+		return len(resource) > len(prefix) && resource[:len(prefix)] == prefix
+	}
+	return false
+}
+
+// actionMatches reports whether a permission or policy action covers action
+func actionMatches(pattern, action string) bool {
+	return pattern == "*" || pattern == action
+}
+
+// resolveRoles returns a role followed by every role it inherits, directly or
+// transitively. The caller must hold roleLock.
+func (m *RBACManager) resolveRoles(roleName string) ([]*Role, error) {
+	var resolved []*Role
+	visited := make(map[string]bool)
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		for _, p := range path {
+			if p == name {
+				return fmt.Errorf("circular role inheritance: %s", strings.Join(append(path, name), " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+
+		role, exists := m.roles[name]
+		if !exists {
+			if len(path) == 0 {
+				return fmt.Errorf("role %s not found", name)
+			}
+			return fmt.Errorf("role %s inherited by %s not found", name, path[len(path)-1])
+		}
+
+		visited[name] = true
+		resolved = append(resolved, role)
+		for _, parent := range role.Inherits {
+			if err := visit(parent, append(path, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := visit(roleName, nil); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// resolvedRole is what access checks need of a role, worked out once per role
+// change so that checks do not allocate: the names of the role and the roles
+// it inherits, and their permissions without duplicates. err is set instead
+// when the inheritance cannot be resolved.
+type resolvedRole struct {
+	names       []string
+	permissions []Permission
+	err         error
+}
+
+// resolveAll rebuilds the resolved roles. The caller must hold roleLock for
+// writing.
+func (m *RBACManager) resolveAll() {
+	resolved := make(map[string]*resolvedRole, len(m.roles))
+	for name := range m.roles {
+		roles, err := m.resolveRoles(name)
+		if err != nil {
+			resolved[name] = &resolvedRole{err: err}
+			continue
+		}
+
+		type key struct{ resource, action string }
+		seen := make(map[key]bool)
+		r := &resolvedRole{names: make([]string, 0, len(roles))}
+		for _, role := range roles {
+			r.names = append(r.names, role.Name)
+			for _, permission := range role.Permissions {
+				k := key{permission.Resource, permission.Action}
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				r.permissions = append(r.permissions, permission)
+			}
+		}
+		resolved[name] = r
+	}
+	m.resolved = resolved
+}
+
+// resolvedRole returns the resolved role of a name. The caller must hold
+// roleLock.
+func (m *RBACManager) resolvedRole(roleName string) (*resolvedRole, error) {
+	role, exists := m.resolved[roleName]
+	if !exists {
+		return nil, fmt.Errorf("role %s not found", roleName)
+	}
+	if role.err != nil {
+		return nil, role.err
+	}
+	return role, nil
+}
+
 // ListRoles returns all defined roles
 func (m *RBACManager) ListRoles() ([]*Role, error) {
 	m.roleLock.RLock()
@@ -275,17 +495,17 @@ func (m *RBACManager) ListPolicies() ([]*Policy, error) {
 	return policies, nil
 }
 
-// GetRolePermissions returns all permissions for a specific role
+// GetRolePermissions returns the effective permissions for a specific role, including
+// those of the roles it inherits
 func (m *RBACManager) GetRolePermissions(roleName string) ([]Permission, error) {
 	m.roleLock.RLock()
 	defer m.roleLock.RUnlock()
 
-	role, exists := m.roles[roleName]
-	if !exists {
-		return nil, fmt.Errorf("role %s not found", roleName)
+	role, err := m.resolvedRole(roleName)
+	if err != nil {
+		return nil, err
 	}
-
-	return role.Permissions, nil
+	return append([]Permission{}, role.permissions...), nil
 }
 
 // AddPermissionToRole adds a permission to an existing role
@@ -308,6 +528,7 @@ func (m *RBACManager) AddPermissionToRole(roleName string, permission Permission
 
 	role.Permissions = append(role.Permissions, permission)
 	role.UpdatedAt = time.Now()
+	m.resolveAll()
 
 	m.logger.Info("Added permission to role",
 		zap.String("role", roleName),
@@ -345,6 +566,7 @@ func (m *RBACManager) RemovePermissionFromRole(roleName, resource, action string
 
 	role.Permissions = newPermissions
 	role.UpdatedAt = time.Now()
+	m.resolveAll()
 
 	m.logger.Info("Removed permission from role",
 		zap.String("role", roleName),
@@ -0,0 +1,220 @@
+package access
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newTestRBACManager creates a manager holding the given roles
+func newTestRBACManager(t *testing.T, roles ...*Role) *RBACManager {
+	m := NewRBACManager(zap.NewNop())
+	for _, role := range roles {
+		if err := m.AddRole(role); err != nil {
+			t.Fatalf("Failed to add role %s: %v", role.Name, err)
+		}
+	}
+	return m
+}
+
+func TestRoleInheritance(t *testing.T) {
+	m := newTestRBACManager(t,
+		&Role{Name: "viewer", Permissions: []Permission{{Name: "view_status", Resource: "status", Action: "read"}}},
+		&Role{Name: "monitor", Inherits: []string{"viewer"}, Permissions: []Permission{
+			{Name: "view_logs", Resource: "logs", Action: "read"},
+			{Name: "view_status", Resource: "status", Action: "read"},
+		}},
+		&Role{Name: "operator", Inherits: []string{"monitor"}, Permissions: []Permission{{Name: "manage_tunnels", Resource: "tunnels", Action: "*"}}},
+	)
+	if err := m.AddPolicy(&Policy{Name: "viewer_metrics", Roles: []string{"viewer"}, Resources: []string{"metrics"}, Actions: []string{"read"}}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	for _, perm := range [][2]string{{"tunnels", "*"}, {"logs", "read"}, {"status", "read"}} {
+		ok, err := m.CheckPermission("operator", perm[0], perm[1])
+		if err != nil || !ok {
+			t.Errorf("Expected operator to have %s:%s, got %v (%v)", perm[0], perm[1], ok, err)
+		}
+	}
+	if ok, _ := m.CheckPermission("monitor", "tunnels", "*"); ok {
+		t.Error("Expected monitor not to inherit from operator")
+	}
+
+	// Policies granted to an inherited role apply too
+	if ok, err := m.CheckPolicy(context.Background(), "operator", "metrics", "read"); err != nil || !ok {
+		t.Errorf("Expected operator to be allowed metrics:read via viewer policy, got %v (%v)", ok, err)
+	}
+
+	// Effective permissions are flattened without duplicates
+	permissions, err := m.GetRolePermissions("operator")
+	if err != nil {
+		t.Fatalf("Failed to get role permissions: %v", err)
+	}
+	if len(permissions) != 3 {
+		t.Errorf("Expected 3 effective permissions, got %d: %v", len(permissions), permissions)
+	}
+}
+
+func TestRoleInheritanceCycle(t *testing.T) {
+	m := newTestRBACManager(t,
+		&Role{Name: "a", Inherits: []string{"b"}},
+		&Role{Name: "b", Inherits: []string{"c"}},
+		&Role{Name: "c", Inherits: []string{"a"}},
+		&Role{Name: "orphan", Inherits: []string{"missing"}},
+	)
+
+	_, err := m.CheckPermission("a", "status", "read")
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("Expected circular inheritance error, got: %v", err)
+	}
+	if _, err := m.CheckPolicy(context.Background(), "b", "status", "read"); err == nil {
+		t.Error("Expected circular inheritance error from CheckPolicy")
+	}
+	if _, err := m.GetRolePermissions("c"); err == nil {
+		t.Error("Expected circular inheritance error from GetRolePermissions")
+	}
+
+	_, err = m.GetRolePermissions("orphan")
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected missing inherited role error, got: %v", err)
+	}
+}
+
+func TestDefaultRolesWithoutInheritance(t *testing.T) {
+	m := newTestRBACManager(t, DefaultRoles()...)
+
+	for _, role := range DefaultRoles() {
+		permissions, err := m.GetRolePermissions(role.Name)
+		if err != nil {
+			t.Fatalf("Failed to get permissions for %s: %v", role.Name, err)
+		}
+		if len(permissions) != len(role.Permissions) {
+			t.Errorf("Expected %d permissions for %s, got %d", len(role.Permissions), role.Name, len(permissions))
+		}
+	}
+
+	tests := []struct {
+		role, resource, action string
+		want                   bool
+	}{
+		{"operator", "tunnels", "*", true},
+		{"operator", "logs", "read", false},
+		{"monitor", "logs", "read", true},
+		{"client", "tunnels", "connect", true},
+		{"client", "config", "read", false},
+	}
+	for _, tt := range tests {
+		got, err := m.CheckPermission(tt.role, tt.resource, tt.action)
+		if err != nil {
+			t.Fatalf("Failed to check permission: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("CheckPermission(%s, %s, %s): expected %v, got %v", tt.role, tt.resource, tt.action, tt.want, got)
+		}
+	}
+}
+
+func TestMatchPermission(t *testing.T) {
+	permissions := []Permission{
+		{Name: "any", Resource: "*", Action: "read"},
+		{Name: "tunnels", Resource: "tunnels/*", Action: "*"},
+		{Name: "tunnel_conn", Resource: "tunnels/conn-123", Action: "restart"},
+		{Name: "tunnel_stats", Resource: "tunnels/conn-123/*", Action: "read"},
+		{Name: "config", Resource: "config", Action: "update"},
+	}
+
+	tests := []struct {
+		name             string
+		resource, action string
+		want             string // matched permission name, empty for no match
+	}{
+		{"exact resource and action", "tunnels/conn-123", "restart", "tunnel_conn"},
+		{"exact resource beats prefix", "config", "update", "config"},
+		{"prefix with action wildcard", "tunnels/conn-456", "delete", "tunnels"},
+		{"nested resource under prefix", "tunnels/conn-456/stats", "delete", "tunnels"},
+		{"longest prefix wins", "tunnels/conn-123/stats", "read", "tunnel_stats"},
+		{"prefix beats resource wildcard", "tunnels/conn-9", "read", "tunnels"},
+		{"resource wildcard", "audit", "read", "any"},
+		{"prefix does not cover its parent", "tunnels", "delete", ""},
+		{"prefix needs the separator", "tunnelsx/conn", "delete", ""},
+		{"action mismatch", "config", "delete", ""},
+		{"exact resource requires exact match", "config/extra", "update", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchPermission(permissions, tt.resource, tt.action)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Expected no match, got %s", got.Name)
+				}
+				return
+			}
+			if !ok || got.Name != tt.want {
+				t.Errorf("Expected %s, got %s (matched=%v)", tt.want, got.Name, ok)
+			}
+		})
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		matchPermission(permissions, "tunnels/conn-123/stats", "read")
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+func TestCheckPermissionWildcards(t *testing.T) {
+	m := newTestRBACManager(t, &Role{Name: "operator", Permissions: []Permission{
+		{Name: "manage_tunnels", Resource: "tunnels/*", Action: "*"},
+		{Name: "view_status", Resource: "status", Action: "read"},
+	}})
+	if err := m.AddPolicy(&Policy{Name: "logs", Roles: []string{"operator"}, Resources: []string{"logs/*"}, Actions: []string{"read"}}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	tests := []struct {
+		resource, action string
+		want             bool
+	}{
+		{"tunnels/conn-123", "restart", true},
+		{"tunnels", "restart", false},
+		{"status", "read", true},
+		{"status", "write", false},
+		{"status/detail", "read", false},
+	}
+	for _, tt := range tests {
+		got, err := m.CheckPermission("operator", tt.resource, tt.action)
+		if err != nil {
+			t.Fatalf("Failed to check permission: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("CheckPermission(%s, %s): expected %v, got %v", tt.resource, tt.action, tt.want, got)
+		}
+	}
+
+	if ok, _ := m.CheckPolicy(context.Background(), "operator", "logs/tunnel", "read"); !ok {
+		t.Error("Expected policy prefix wildcard to allow logs/tunnel")
+	}
+	if ok, _ := m.CheckPolicy(context.Background(), "operator", "logs", "read"); ok {
+		t.Error("Expected policy prefix wildcard not to allow logs")
+	}
+
+	// Checks work on the roles resolved when they last changed
+	allocs := testing.AllocsPerRun(100, func() {
+		m.checkPermission("operator", "tunnels/conn-123", "restart")
+		m.checkPolicy("operator", "logs/tunnel", "read")
+	})
+	if allocs != 0 {
+		t.Errorf("Expected checks not to allocate, got %v", allocs)
+	}
+
+	if err := m.AddPermissionToRole("operator", Permission{Name: "write_status", Resource: "status", Action: "write"}); err != nil {
+		t.Fatalf("Failed to add permission: %v", err)
+	}
+	if ok, _ := m.CheckPermission("operator", "status", "write"); !ok {
+		t.Error("Expected an added permission to apply at once")
+	}
+}
@@ -52,19 +52,22 @@ type SessionManager struct {
 	sessionLock sync.RWMutex
 	userLock    sync.RWMutex
 	logger      *zap.Logger
+	audit       AuditSink
 }
 
 // NewSessionManager creates a new session manager
-func NewSessionManager(config *SessionConfig, logger *zap.Logger) *SessionManager {
+func NewSessionManager(config *SessionConfig, logger *zap.Logger, opts ...Option) *SessionManager {
 	if config == nil {
 		config = DefaultSessionConfig()
 	}
 
+	o := applyOptions(opts)
 	return &SessionManager{
 		config:    config,
 		sessions:  make(map[string]*Session),
 		userIndex: make(map[string]map[string]bool),
 		logger:    logger,
+		audit:     o.audit,
 	}
 }
 
@@ -84,6 +87,7 @@ func (m *SessionManager) CreateSession(ctx context.Context, userID string, role
 	// Generate secure session token
 	token, err := m.generateToken()
 	if err != nil {
+		m.auditSession(AuditSessionCreate, CorrelationID(ctx), userID, "", AuditFailure, err.Error())
 		return nil, fmt.Errorf("failed to generate session token: %v", err)
 	}
 
@@ -128,6 +132,7 @@ func (m *SessionManager) CreateSession(ctx context.Context, userID string, role
 		zap.String("role", role),
 		zap.String("ip_address", ipAddress),
 		zap.Duration("timeout", m.config.SessionTimeout))
+	m.auditSession(AuditSessionCreate, CorrelationID(ctx), userID, token, AuditSuccess, "")
 
 	return session, nil
 }
@@ -143,9 +148,13 @@ func (m *SessionManager) GetSession(token string) (*Session, error) {
 	}
 
 	// Check if session has expired
-	if time.Now().After(session.ExpiresAt) {
+	now := time.Now()
+	if now.After(session.ExpiresAt) {
 		return nil, fmt.Errorf("session expired")
 	}
+	if now.After(m.absoluteExpiry(session)) {
+		return nil, fmt.Errorf("session timed out")
+	}
 
 	return session, nil
 }
@@ -180,6 +189,85 @@ func (m *SessionManager) UpdateActivity(token string) error {
 	return nil
 }
 
+// RefreshSession replaces a session token with a new one. The session keeps its
+// metadata and original creation time, so the absolute timeout still applies. The old
+// token is rejected once the new session is returned.
+func (m *SessionManager) RefreshSession(oldToken string) (*Session, error) {
+	token, err := m.generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %v", err)
+	}
+
+	m.sessionLock.Lock()
+	defer m.sessionLock.Unlock()
+
+	old, exists := m.sessions[oldToken]
+	if !exists {
+		return nil, fmt.Errorf("session not found")
+	}
+	if old.State != SessionStateActive {
+		return nil, fmt.Errorf("session is not active")
+	}
+
+	now := time.Now()
+	if now.After(old.ExpiresAt) {
+		return nil, fmt.Errorf("session expired")
+	}
+	absoluteExpiry := m.absoluteExpiry(old)
+	if now.After(absoluteExpiry) {
+		return nil, fmt.Errorf("session timed out")
+	}
+
+	session := &Session{
+		ID:           token,
+		UserID:       old.UserID,
+		Role:         old.Role,
+		IPAddress:    old.IPAddress,
+		UserAgent:    old.UserAgent,
+		CreatedAt:    old.CreatedAt,
+		LastActivity: now,
+		ExpiresAt:    now.Add(m.config.SessionTimeout),
+		State:        SessionStateActive,
+		Metadata:     make(map[string]interface{}, len(old.Metadata)),
+	}
+	if session.ExpiresAt.After(absoluteExpiry) {
+		session.ExpiresAt = absoluteExpiry
+	}
+	for k, v := range old.Metadata {
+		session.Metadata[k] = v
+	}
+
+	// Swap the tokens in the user index
+	m.userLock.Lock()
+	userSessions := m.userIndex[old.UserID]
+	if userSessions == nil {
+		userSessions = make(map[string]bool)
+		m.userIndex[old.UserID] = userSessions
+	}
+	delete(userSessions, oldToken)
+	userSessions[token] = true
+	m.userLock.Unlock()
+
+	old.State = SessionStateRevoked
+	delete(m.sessions, oldToken)
+	m.sessions[token] = session
+
+	m.logger.Info("Refreshed session",
+		zap.String("old_session_id", oldToken),
+		zap.String("session_id", token),
+		zap.String("user_id", session.UserID),
+		zap.Time("expires_at", session.ExpiresAt))
+	m.auditSession(AuditSessionRefresh, sessionRef(oldToken), session.UserID, token, AuditSuccess, "")
+
+	return session, nil
+}
+
+// absoluteExpiry returns the time after which a session is rejected regardless of
+// activity or refreshes
+func (m *SessionManager) absoluteExpiry(session *Session) time.Time {
+	return session.CreatedAt.Add(m.config.AbsoluteTimeout)
+}
+
 // RevokeSession revokes a session
 func (m *SessionManager) RevokeSession(token string) error {
 	m.sessionLock.Lock()
@@ -205,6 +293,7 @@ func (m *SessionManager) RevokeSession(token string) error {
 	m.logger.Info("Revoked session",
 		zap.String("session_id", token),
 		zap.String("user_id", session.UserID))
+	m.auditSession(AuditSessionRevoke, "", session.UserID, token, AuditSuccess, "")
 
 	return nil
 }
@@ -225,6 +314,7 @@ func (m *SessionManager) RevokeAllUserSessions(userID string) error {
 	for token := range userSessions {
 		if session, exists := m.sessions[token]; exists {
 			session.State = SessionStateRevoked
+			m.auditSession(AuditSessionRevoke, "", userID, token, AuditSuccess, "")
 		}
 		delete(m.sessions, token)
 	}
@@ -334,11 +424,12 @@ func (m *SessionManager) cleanupExpiredSessions() {
 			m.logger.Info("Session expired due to timeout",
 				zap.String("session_id", token),
 				zap.String("user_id", session.UserID))
+			m.auditSession(AuditSessionExpire, "", session.UserID, token, AuditSuccess, "idle timeout")
 			continue
 		}
 
 		// Check absolute timeout
-		if now.Sub(session.CreatedAt) > m.config.AbsoluteTimeout {
+		if now.After(m.absoluteExpiry(session)) {
 			session.State = SessionStateTimedOut
 			expiredTokens = append(expiredTokens, token)
 
@@ -351,6 +442,7 @@ func (m *SessionManager) cleanupExpiredSessions() {
 			m.logger.Info("Session timed out due to absolute timeout",
 				zap.String("session_id", token),
 				zap.String("user_id", session.UserID))
+			m.auditSession(AuditSessionExpire, "", session.UserID, token, AuditSuccess, "absolute timeout")
 		}
 	}
 
@@ -360,6 +452,31 @@ func (m *SessionManager) cleanupExpiredSessions() {
 	}
 }
 
+// auditSession records a session lifecycle event. The session is identified by a
+// hash of its token; the correlation ID defaults to the same value.
+func (m *SessionManager) auditSession(eventType, correlationID, userID, token string, outcome AuditOutcome, reason string) {
+	if m.audit == nil {
+		return
+	}
+
+	event := AuditEvent{
+		Type:          eventType,
+		Actor:         userID,
+		Outcome:       outcome,
+		CorrelationID: correlationID,
+	}
+	if token != "" {
+		event.Session = sessionRef(token)
+		if event.CorrelationID == "" {
+			event.CorrelationID = event.Session
+		}
+	}
+	if reason != "" {
+		event.Details = map[string]string{"reason": reason}
+	}
+	recordAudit(m.audit, event)
+}
+
 // removeOldestSession removes the oldest session for a user
 func (m *SessionManager) removeOldestSession(userID string) {
 	m.sessionLock.RLock()
@@ -0,0 +1,71 @@
+package access
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRefreshSession(t *testing.T) {
+	config := DefaultSessionConfig()
+	config.SessionTimeout = 400 * time.Millisecond
+	config.AbsoluteTimeout = 600 * time.Millisecond
+	m := NewSessionManager(config, zap.NewNop())
+
+	session, err := m.CreateSession(context.Background(), "alice", "admin", "10.0.0.2", "test")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session.Metadata["mfa"] = true
+	oldToken := session.ID
+
+	refreshed, err := m.RefreshSession(oldToken)
+	if err != nil {
+		t.Fatalf("Failed to refresh session: %v", err)
+	}
+	if refreshed.ID == oldToken {
+		t.Fatal("Expected a new session token")
+	}
+	if refreshed.UserID != "alice" || refreshed.Role != "admin" || refreshed.Metadata["mfa"] != true {
+		t.Errorf("Expected session details to be preserved, got %+v", refreshed)
+	}
+	if !refreshed.CreatedAt.Equal(session.CreatedAt) {
+		t.Errorf("Expected creation time %v, got %v", session.CreatedAt, refreshed.CreatedAt)
+	}
+
+	// The old token is rejected and cannot be refreshed again
+	if _, err := m.GetSession(oldToken); err == nil {
+		t.Error("Expected old token to be rejected")
+	}
+	if _, err := m.RefreshSession(oldToken); err == nil {
+		t.Error("Expected refresh with old token to fail")
+	}
+
+	sessions, _ := m.GetUserSessions("alice")
+	if len(sessions) != 1 || sessions[0].ID != refreshed.ID {
+		t.Errorf("Expected user index to hold only the new token, got %d sessions", len(sessions))
+	}
+
+	// Refreshing again never extends past the absolute timeout
+	time.Sleep(300 * time.Millisecond)
+	refreshed, err = m.RefreshSession(refreshed.ID)
+	if err != nil {
+		t.Fatalf("Failed to refresh session: %v", err)
+	}
+	if deadline := session.CreatedAt.Add(config.AbsoluteTimeout); refreshed.ExpiresAt.After(deadline) {
+		t.Errorf("Expected expiry no later than %v, got %v", deadline, refreshed.ExpiresAt)
+	}
+	if valid, err := m.ValidateSession(refreshed.ID); !valid {
+		t.Errorf("Expected refreshed token to be valid: %v", err)
+	}
+
+	time.Sleep(time.Until(session.CreatedAt.Add(config.AbsoluteTimeout)) + 50*time.Millisecond)
+	if _, err := m.GetSession(refreshed.ID); err == nil {
+		t.Error("Expected refreshed token to be rejected after the absolute timeout")
+	}
+	if _, err := m.RefreshSession(refreshed.ID); err == nil {
+		t.Error("Expected refresh to fail after the absolute timeout")
+	}
+}
@@ -0,0 +1,71 @@
+package caps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Capability is a Linux capability number
+type Capability uint
+
+// Capabilities used by the tunnel
+const (
+	NetBindService Capability = 10
+	NetAdmin       Capability = 12
+	NetRaw         Capability = 13
+)
+
+// Required are the capabilities needed to create the TUN interface and open
+// raw sockets
+var Required = []Capability{NetAdmin, NetRaw}
+
+// ErrMissingCapabilities is returned when the process lacks required capabilities
+var ErrMissingCapabilities = errors.New("missing required capabilities")
+
+// String returns the capability name, such as CAP_NET_ADMIN
+func (c Capability) String() string {
+	switch c {
+	case NetBindService:
+		return "CAP_NET_BIND_SERVICE"
+	case NetAdmin:
+		return "CAP_NET_ADMIN"
+	case NetRaw:
+		return "CAP_NET_RAW"
+	default:
+		return fmt.Sprintf("CAP_%d", uint(c))
+	}
+}
+
+// effectiveSet returns the effective capability set of the process as a bit
+// mask; replaced in tests
+var effectiveSet = readEffectiveSet
+
+// Check returns an error wrapping ErrMissingCapabilities that lists the
+// required capabilities missing from the process's effective set, and the
+// setcap command that grants them
+func Check(required ...Capability) error {
+	effective, err := effectiveSet()
+	if err != nil {
+		return fmt.Errorf("failed to read effective capabilities: %w", err)
+	}
+
+	var missing, setcap []string
+	for _, c := range required {
+		if effective&(1<<c) == 0 {
+			missing = append(missing, c.String())
+			setcap = append(setcap, strings.ToLower(c.String()))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		binary = os.Args[0]
+	}
+	return fmt.Errorf("%w: %s; run as root or grant them with: setcap %s+ep %s",
+		ErrMissingCapabilities, strings.Join(missing, ", "), strings.Join(setcap, ","), binary)
+}
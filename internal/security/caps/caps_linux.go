@@ -0,0 +1,15 @@
+//go:build linux
+
+package caps
+
+import "golang.org/x/sys/unix"
+
+// readEffectiveSet reads the effective capability set with capget
+func readEffectiveSet() (uint64, error) {
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&header, &data[0]); err != nil {
+		return 0, err
+	}
+	return uint64(data[0].Effective) | uint64(data[1].Effective)<<32, nil
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package caps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// useEffectiveSet stubs the effective capability set for the test
+func useEffectiveSet(t *testing.T, set uint64, err error) {
+	t.Helper()
+	original := effectiveSet
+	effectiveSet = func() (uint64, error) { return set, err }
+	t.Cleanup(func() { effectiveSet = original })
+}
+
+func TestCheckListsMissingCapabilities(t *testing.T) {
+	useEffectiveSet(t, 1<<NetRaw, nil)
+
+	err := Check(Required...)
+	if !errors.Is(err, ErrMissingCapabilities) {
+		t.Fatalf("Expected ErrMissingCapabilities, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "missing required capabilities: CAP_NET_ADMIN;") {
+		t.Errorf("Expected the error to list only CAP_NET_ADMIN, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "setcap cap_net_admin+ep ") {
+		t.Errorf("Expected the error to give the setcap command, got %v", err)
+	}
+
+	useEffectiveSet(t, 0, nil)
+	err = Check(Required...)
+	if err == nil || !strings.Contains(err.Error(), "CAP_NET_ADMIN, CAP_NET_RAW;") ||
+		!strings.Contains(err.Error(), "setcap cap_net_admin,cap_net_raw+ep ") {
+		t.Errorf("Expected both capabilities to be listed, got %v", err)
+	}
+}
+
+func TestCheckPassesWithCapabilities(t *testing.T) {
+	useEffectiveSet(t, 1<<NetAdmin|1<<NetRaw, nil)
+	if err := Check(Required...); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestCheckReadFailure(t *testing.T) {
+	useEffectiveSet(t, 0, errors.New("capget: operation not permitted"))
+	err := Check(Required...)
+	if err == nil || errors.Is(err, ErrMissingCapabilities) {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+}
+
+func TestReadEffectiveSet(t *testing.T) {
+	if _, err := readEffectiveSet(); err != nil {
+		t.Errorf("Failed to read effective capabilities: %v", err)
+	}
+}
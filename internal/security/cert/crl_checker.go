@@ -0,0 +1,318 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+// maxCRLSize bounds the size of a downloaded CRL
+const maxCRLSize = 16 * 1024 * 1024
+
+// CRLCheckerConfig represents configuration for client certificate revocation checking
+type CRLCheckerConfig struct {
+	Source          string              // CRL URL or file path; empty to use certificate distribution points
+	Issuers         []*x509.Certificate // CAs a CRL must be signed by; without any, no CRL is trusted
+	RefreshInterval time.Duration       // How often loaded CRLs are reloaded
+	FailOpen        bool                // Accept certificates when no current CRL is available
+	HTTPClient      *http.Client
+}
+
+// DefaultCRLCheckerConfig returns the default CRL checking configuration
+func DefaultCRLCheckerConfig() *CRLCheckerConfig {
+	return &CRLCheckerConfig{
+		RefreshInterval: 1 * time.Hour,
+	}
+}
+
+// NewCRLCheckerConfig creates CRL checking configuration from the security
+// configuration, trusting CRLs signed by one of issuers
+func NewCRLCheckerConfig(cfg types.CRLConfig, issuers []*x509.Certificate) *CRLCheckerConfig {
+	config := DefaultCRLCheckerConfig()
+	config.Source = cfg.Source
+	config.Issuers = issuers
+	config.FailOpen = cfg.FailOpen
+	if cfg.RefreshInterval > 0 {
+		config.RefreshInterval = cfg.RefreshInterval
+	}
+	return config
+}
+
+// cachedCRL is a loaded revocation list indexed by revoked serial number
+type cachedCRL struct {
+	list     *x509.RevocationList
+	revoked  map[string]bool
+	loadedAt time.Time
+}
+
+// CRLChecker rejects revoked client certificates during the TLS handshake
+type CRLChecker struct {
+	config *CRLCheckerConfig
+	logger *zap.Logger
+	client *http.Client
+
+	mu     sync.RWMutex
+	crls   map[string]*cachedCRL
+	stopCh chan struct{}
+}
+
+// NewCRLChecker creates a new CRL checker
+func NewCRLChecker(config *CRLCheckerConfig, logger *zap.Logger) *CRLChecker {
+	if config == nil {
+		config = DefaultCRLCheckerConfig()
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &CRLChecker{
+		config: config,
+		logger: logger,
+		client: client,
+		crls:   make(map[string]*cachedCRL),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start loads the configured CRL and begins refreshing loaded CRLs periodically
+func (c *CRLChecker) Start(ctx context.Context) {
+	if c.config.Source != "" {
+		if _, err := c.load(c.config.Source); err != nil {
+			c.logger.Warn("Failed to load initial CRL",
+				zap.String("source", c.config.Source),
+				zap.Error(err),
+			)
+		}
+	}
+
+	go c.refreshLoop(ctx)
+}
+
+// Stop stops the refresh loop
+func (c *CRLChecker) Stop() {
+	close(c.stopCh)
+}
+
+// Refresh reloads every CRL the checker has seen
+func (c *CRLChecker) Refresh() error {
+	c.mu.RLock()
+	sources := make([]string, 0, len(c.crls))
+	for source := range c.crls {
+		sources = append(sources, source)
+	}
+	c.mu.RUnlock()
+
+	var lastErr error
+	for _, source := range sources {
+		if _, err := c.load(source); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// VerifyPeerCertificate checks the presented client certificate against its CRL. It is
+// meant to be used as tls.Config.VerifyPeerCertificate on the server.
+func (c *CRLChecker) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return nil
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse client certificate: %v", err)
+	}
+
+	return c.Check(cert)
+}
+
+// Check returns an error if the certificate is revoked, or if its revocation status
+// cannot be determined and the checker fails closed
+func (c *CRLChecker) Check(cert *x509.Certificate) error {
+	serial := cert.SerialNumber.String()
+
+	sources := c.sources(cert)
+	if len(sources) == 0 {
+		return c.unavailable(serial, fmt.Errorf("no CRL source configured"))
+	}
+
+	for _, source := range sources {
+		crl, err := c.get(source)
+		if err != nil {
+			if err := c.unavailable(serial, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if crl.revoked[serial] {
+			return fmt.Errorf("client certificate %s has been revoked", serial)
+		}
+
+		if !crl.list.NextUpdate.IsZero() && time.Now().After(crl.list.NextUpdate) {
+			err := fmt.Errorf("CRL from %s is stale (next update was %s)", source, crl.list.NextUpdate.Format(time.RFC3339))
+			if err := c.unavailable(serial, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// unavailable handles a certificate whose revocation status cannot be determined
+func (c *CRLChecker) unavailable(serial string, cause error) error {
+	if c.config.FailOpen {
+		c.logger.Warn("Revocation status unavailable, accepting client certificate",
+			zap.String("serial", serial),
+			zap.Error(cause),
+		)
+		return nil
+	}
+	return fmt.Errorf("cannot verify revocation status of client certificate %s: %v", serial, cause)
+}
+
+// sources returns the CRL locations to check for a certificate
+func (c *CRLChecker) sources(cert *x509.Certificate) []string {
+	if c.config.Source != "" {
+		return []string{c.config.Source}
+	}
+	return cert.CRLDistributionPoints
+}
+
+// get returns a cached CRL, loading it if it is missing or due for refresh
+func (c *CRLChecker) get(source string) (*cachedCRL, error) {
+	c.mu.RLock()
+	cached := c.crls[source]
+	c.mu.RUnlock()
+
+	if cached != nil && time.Since(cached.loadedAt) < c.config.RefreshInterval {
+		return cached, nil
+	}
+
+	loaded, err := c.load(source)
+	if err != nil {
+		if cached != nil {
+			// Keep using the previous CRL; staleness is checked by the caller
+			return cached, nil
+		}
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// load fetches, verifies and caches a CRL
+func (c *CRLChecker) load(source string) (*cachedCRL, error) {
+	data, err := c.fetch(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode(data); block != nil && block.Type == "X509 CRL" {
+		data = block.Bytes
+	}
+
+	list, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL from %s: %v", source, err)
+	}
+
+	if err := c.checkSignature(list); err != nil {
+		return nil, fmt.Errorf("invalid CRL signature from %s: %v", source, err)
+	}
+
+	cached := &cachedCRL{
+		list:     list,
+		revoked:  make(map[string]bool, len(list.RevokedCertificateEntries)),
+		loadedAt: time.Now(),
+	}
+	for _, entry := range list.RevokedCertificateEntries {
+		cached.revoked[entry.SerialNumber.String()] = true
+	}
+
+	c.mu.Lock()
+	c.crls[source] = cached
+	c.mu.Unlock()
+
+	c.logger.Debug("Loaded CRL",
+		zap.String("source", source),
+		zap.Int("revoked", len(cached.revoked)),
+		zap.Time("next_update", list.NextUpdate),
+	)
+	return cached, nil
+}
+
+// checkSignature verifies that a CRL was signed by one of the trusted issuers
+func (c *CRLChecker) checkSignature(list *x509.RevocationList) error {
+	if len(c.config.Issuers) == 0 {
+		return fmt.Errorf("no CA configured to verify the CRL against")
+	}
+
+	var err error
+	for _, issuer := range c.config.Issuers {
+		if err = list.CheckSignatureFrom(issuer); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// fetch reads a CRL from a URL or local file
+func (c *CRLChecker) fetch(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CRL file: %v", err)
+		}
+		return data, nil
+	}
+
+	resp, err := c.client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch CRL from %s: %v", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CRL server %s returned status %d", source, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRL from %s: %v", source, err)
+	}
+	return data, nil
+}
+
+// refreshLoop periodically reloads CRLs
+func (c *CRLChecker) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			if err := c.Refresh(); err != nil {
+				c.logger.Warn("CRL refresh failed",
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type crlTestPKI struct {
+	ca         *x509.Certificate
+	caKey      *rsa.PrivateKey
+	valid      *x509.Certificate
+	revoked    *x509.Certificate
+	revokedKey *rsa.PrivateKey
+}
+
+func createCRLTestPKI(t *testing.T) *crlTestPKI {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	assert.NoError(t, err)
+
+	clientCert := func(serial int64) (*x509.Certificate, *rsa.PrivateKey) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "client"},
+			NotBefore:    now.Add(-time.Hour),
+			NotAfter:     now.Add(12 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		assert.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		assert.NoError(t, err)
+		return cert, key
+	}
+
+	valid, _ := clientCert(10)
+	revoked, revokedKey := clientCert(11)
+	return &crlTestPKI{
+		ca:         ca,
+		caKey:      caKey,
+		valid:      valid,
+		revoked:    revoked,
+		revokedKey: revokedKey,
+	}
+}
+
+// writeCRL writes a PEM encoded CRL revoking the PKI's revoked certificate
+func writeCRL(t *testing.T, pki *crlTestPKI, nextUpdate time.Time) string {
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: nextUpdate.Add(-2 * time.Hour),
+		NextUpdate: nextUpdate,
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: pki.revoked.SerialNumber, RevocationTime: time.Now().Add(-time.Minute)},
+		},
+	}, pki.ca, pki.caKey)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ca.crl")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644)
+	assert.NoError(t, err)
+	return path
+}
+
+func newTestCRLChecker(pki *crlTestPKI, source string, failOpen bool) *CRLChecker {
+	config := DefaultCRLCheckerConfig()
+	config.Source = source
+	config.Issuers = []*x509.Certificate{pki.ca}
+	config.FailOpen = failOpen
+	return NewCRLChecker(config, zap.NewNop())
+}
+
+func TestCRLChecker_ValidAndRevoked(t *testing.T) {
+	pki := createCRLTestPKI(t)
+	checker := newTestCRLChecker(pki, writeCRL(t, pki, time.Now().Add(time.Hour)), false)
+
+	assert.NoError(t, checker.VerifyPeerCertificate([][]byte{pki.valid.Raw}, nil))
+
+	err := checker.VerifyPeerCertificate([][]byte{pki.revoked.Raw}, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "has been revoked")
+	}
+
+	// Fail-open does not accept certificates known to be revoked
+	checker = newTestCRLChecker(pki, writeCRL(t, pki, time.Now().Add(time.Hour)), true)
+	assert.Error(t, checker.VerifyPeerCertificate([][]byte{pki.revoked.Raw}, nil))
+}
+
+func TestCRLChecker_StaleOrMissingCRL(t *testing.T) {
+	pki := createCRLTestPKI(t)
+	stale := writeCRL(t, pki, time.Now().Add(-time.Hour))
+	missing := filepath.Join(t.TempDir(), "missing.crl")
+
+	tests := []struct {
+		name     string
+		source   string
+		failOpen bool
+		wantErr  bool
+	}{
+		{"stale fail-closed", stale, false, true},
+		{"stale fail-open", stale, true, false},
+		{"missing fail-closed", missing, false, true},
+		{"missing fail-open", missing, true, false},
+		{"no source fail-closed", "", false, true},
+		{"no source fail-open", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := newTestCRLChecker(pki, tt.source, tt.failOpen)
+			err := checker.VerifyPeerCertificate([][]byte{pki.valid.Raw}, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCRLChecker_RejectsForeignCRL(t *testing.T) {
+	pki := createCRLTestPKI(t)
+	other := createCRLTestPKI(t)
+
+	// A CRL signed by another CA is not trusted
+	checker := newTestCRLChecker(pki, writeCRL(t, other, time.Now().Add(time.Hour)), false)
+	assert.Error(t, checker.VerifyPeerCertificate([][]byte{pki.valid.Raw}, nil))
+
+	// Without a CA to verify against, no CRL is trusted
+	checker = NewCRLChecker(NewCRLCheckerConfig(types.CRLConfig{Source: writeCRL(t, pki, time.Now().Add(time.Hour))}, nil), zap.NewNop())
+	assert.Error(t, checker.VerifyPeerCertificate([][]byte{pki.valid.Raw}, nil))
+}
+
+func TestCRLChecker_Handshake(t *testing.T) {
+	pki := createCRLTestPKI(t)
+	checker := newTestCRLChecker(pki, writeCRL(t, pki, time.Now().Add(time.Hour)), false)
+
+	serverCert := tls.Certificate{
+		Certificate: [][]byte{pki.ca.Raw},
+		PrivateKey:  pki.caKey,
+	}
+	clientCert := tls.Certificate{
+		Certificate: [][]byte{pki.revoked.Raw},
+		PrivateKey:  pki.revokedKey,
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates:          []tls.Certificate{serverCert},
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: checker.VerifyPeerCertificate,
+	})
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer conn.Close()
+		errCh <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{clientCert},
+	})
+	if err == nil {
+		conn.Close()
+	}
+
+	serverErr := <-errCh
+	if assert.Error(t, serverErr) {
+		assert.Contains(t, serverErr.Error(), "has been revoked")
+	}
+}
@@ -0,0 +1,12 @@
+package cert
+
+import "github.com/o3willard-AI/SSSonector/internal/cert/keys"
+
+// KeyType represents the private key algorithm of a certificate
+type KeyType = keys.Type
+
+const (
+	KeyTypeRSA       = keys.TypeRSA
+	KeyTypeECDSAP256 = keys.TypeECDSAP256
+	KeyTypeEd25519   = keys.TypeEd25519
+)
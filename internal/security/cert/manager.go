@@ -2,13 +2,14 @@ package cert
 
 import (
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/cert/keys"
 	"go.uber.org/zap"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 // Manager implements CertificateManager interface
@@ -33,7 +34,7 @@ func (m *Manager) GetCertificateStore() CertificateStore {
 // CreateCA creates a new CA certificate
 func (m *Manager) CreateCA(req *CertificateRequest) (*Certificate, error) {
 	// Generate key pair
-	key, err := rsa.GenerateKey(rand.Reader, req.KeySize)
+	key, err := keys.Generate(req.KeyType, req.KeySize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate key pair: %v", err)
 	}
@@ -48,12 +49,12 @@ func (m *Manager) CreateCA(req *CertificateRequest) (*Certificate, error) {
 		ExtKeyUsage:           req.ExtKeyUsage,
 		BasicConstraintsValid: true,
 		IsCA:                  true,
-		MaxPathLen:            0,
-		MaxPathLenZero:        true,
+		MaxPathLen:            req.MaxPathLen,
+		MaxPathLenZero:        req.MaxPathLen == 0,
 	}
 
 	// Self-sign CA certificate
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CA certificate: %v", err)
 	}
@@ -90,7 +91,7 @@ func (m *Manager) CreateCA(req *CertificateRequest) (*Certificate, error) {
 // CreateIntermediate creates a new intermediate certificate
 func (m *Manager) CreateIntermediate(req *CertificateRequest, parent *Certificate) (*Certificate, error) {
 	// Generate key pair
-	key, err := rsa.GenerateKey(rand.Reader, req.KeySize)
+	key, err := keys.Generate(req.KeyType, req.KeySize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate key pair: %v", err)
 	}
@@ -110,7 +111,7 @@ func (m *Manager) CreateIntermediate(req *CertificateRequest, parent *Certificat
 	}
 
 	// Sign certificate with parent
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent.X509, &key.PublicKey, parent.PrivateKey)
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent.X509, key.Public(), parent.PrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create intermediate certificate: %v", err)
 	}
@@ -148,7 +149,7 @@ func (m *Manager) CreateIntermediate(req *CertificateRequest, parent *Certificat
 // CreateServer creates a new server certificate
 func (m *Manager) CreateServer(req *CertificateRequest, parent *Certificate) (*Certificate, error) {
 	// Generate key pair
-	key, err := rsa.GenerateKey(rand.Reader, req.KeySize)
+	key, err := keys.Generate(req.KeyType, req.KeySize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate key pair: %v", err)
 	}
@@ -159,7 +160,7 @@ func (m *Manager) CreateServer(req *CertificateRequest, parent *Certificate) (*C
 		Subject:               req.Subject,
 		NotBefore:             req.NotBefore,
 		NotAfter:              req.NotAfter,
-		KeyUsage:              req.KeyUsage | x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              req.KeyUsage | keys.LeafUsage(key),
 		ExtKeyUsage:           append(req.ExtKeyUsage, x509.ExtKeyUsageServerAuth),
 		BasicConstraintsValid: true,
 		IsCA:                  false,
@@ -168,7 +169,7 @@ func (m *Manager) CreateServer(req *CertificateRequest, parent *Certificate) (*C
 	}
 
 	// Sign certificate with parent
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent.X509, &key.PublicKey, parent.PrivateKey)
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent.X509, key.Public(), parent.PrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create server certificate: %v", err)
 	}
@@ -207,7 +208,7 @@ func (m *Manager) CreateServer(req *CertificateRequest, parent *Certificate) (*C
 // CreateClient creates a new client certificate
 func (m *Manager) CreateClient(req *CertificateRequest, parent *Certificate) (*Certificate, error) {
 	// Generate key pair
-	key, err := rsa.GenerateKey(rand.Reader, req.KeySize)
+	key, err := keys.Generate(req.KeyType, req.KeySize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate key pair: %v", err)
 	}
@@ -218,7 +219,7 @@ func (m *Manager) CreateClient(req *CertificateRequest, parent *Certificate) (*C
 		Subject:               req.Subject,
 		NotBefore:             req.NotBefore,
 		NotAfter:              req.NotAfter,
-		KeyUsage:              req.KeyUsage | x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              req.KeyUsage | keys.LeafUsage(key),
 		ExtKeyUsage:           append(req.ExtKeyUsage, x509.ExtKeyUsageClientAuth),
 		BasicConstraintsValid: true,
 		IsCA:                  false,
@@ -227,7 +228,7 @@ func (m *Manager) CreateClient(req *CertificateRequest, parent *Certificate) (*C
 	}
 
 	// Sign certificate with parent
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent.X509, &key.PublicKey, parent.PrivateKey)
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent.X509, key.Public(), parent.PrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client certificate: %v", err)
 	}
@@ -312,6 +313,44 @@ func (m *Manager) Validate(cert *Certificate) error {
 	return nil
 }
 
+// ExportPKCS12 bundles a client certificate, its private key and its CA chain into a
+// password-protected PKCS#12 file for distribution to clients
+func (m *Manager) ExportPKCS12(clientSerial string, password string) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("PKCS#12 export requires a password")
+	}
+
+	cert, err := m.store.Load(clientSerial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate %s: %v", clientSerial, err)
+	}
+	if cert.PrivateKey == nil {
+		return nil, fmt.Errorf("certificate %s has no private key", clientSerial)
+	}
+	if cert.X509.IsCA {
+		return nil, fmt.Errorf("certificate %s is a CA certificate", clientSerial)
+	}
+
+	chain, err := m.store.GetChain(cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate chain: %v", err)
+	}
+
+	var caCerts []*x509.Certificate
+	for _, c := range chain {
+		if c.SerialNumber != cert.SerialNumber {
+			caCerts = append(caCerts, c.X509)
+		}
+	}
+
+	data, err := pkcs12.Modern.Encode(cert.PrivateKey, cert.X509, caCerts, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PKCS#12 bundle: %v", err)
+	}
+
+	return data, nil
+}
+
 // Helper function to generate a random serial number
 func generateSerialNumber() *big.Int {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
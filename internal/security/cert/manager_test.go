@@ -0,0 +1,131 @@
+package cert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestManager_KeyTypes(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeRSA, KeyTypeECDSAP256, KeyTypeEd25519} {
+		t.Run(string(keyType), func(t *testing.T) {
+			store := &MockCertificateStore{}
+			store.On("Store", mock.Anything).Return(nil)
+			manager := NewManager(store, zap.NewNop())
+
+			now := time.Now()
+			request := func(cn string) *CertificateRequest {
+				return &CertificateRequest{
+					Subject:   pkix.Name{CommonName: cn},
+					DNSNames:  []string{cn},
+					NotBefore: now.Add(-time.Minute),
+					NotAfter:  now.Add(time.Hour),
+					KeyType:   keyType,
+				}
+			}
+
+			ca, err := manager.CreateCA(request("Test CA"))
+			if !assert.NoError(t, err) {
+				return
+			}
+			// Unless requested, a CA may only issue end-entity certificates
+			assert.True(t, ca.X509.MaxPathLenZero)
+			assert.Equal(t, 0, ca.X509.MaxPathLen)
+			server, err := manager.CreateServer(request("server.example.com"), ca)
+			if !assert.NoError(t, err) {
+				return
+			}
+			client, err := manager.CreateClient(request("client.example.com"), ca)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(ca.X509)
+			_, err = server.X509.Verify(x509.VerifyOptions{
+				Roots:     roots,
+				DNSName:   "server.example.com",
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})
+			assert.NoError(t, err)
+			_, err = client.X509.Verify(x509.VerifyOptions{
+				Roots:     roots,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			assert.NoError(t, err)
+
+			// Keys survive a round trip through the file store
+			dir := t.TempDir()
+			fileStore := NewFileStore(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+			assert.NoError(t, fileStore.Store(server.ToCertPair()))
+			loaded, err := fileStore.LoadCurrent()
+			if assert.NoError(t, err) {
+				assert.Equal(t, server.PrivateKey, loaded.Key)
+			}
+		})
+	}
+}
+
+func TestManager_UnsupportedKeyType(t *testing.T) {
+	manager := NewManager(&MockCertificateStore{}, zap.NewNop())
+	_, err := manager.CreateCA(&CertificateRequest{KeyType: "DSA"})
+	assert.Error(t, err)
+}
+
+func TestManager_ExportPKCS12(t *testing.T) {
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.crt")
+
+	// Create the CA and client certificates in separate file stores
+	caStore := NewFileStore(caPath, filepath.Join(dir, "ca.key"))
+	now := time.Now()
+	ca, err := NewManager(caStore, zap.NewNop()).CreateCA(&CertificateRequest{
+		Subject:   pkix.Name{CommonName: "Test CA"},
+		NotBefore: now.Add(-time.Minute),
+		NotAfter:  now.Add(time.Hour),
+		KeyType:   KeyTypeECDSAP256,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	store := NewFileStoreWithCA(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), caPath)
+	manager := NewManager(store, zap.NewNop())
+	client, err := manager.CreateClient(&CertificateRequest{
+		Subject:   pkix.Name{CommonName: "client.example.com"},
+		NotBefore: now.Add(-time.Minute),
+		NotAfter:  now.Add(time.Hour),
+		KeyType:   KeyTypeECDSAP256,
+	}, ca)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data, err := manager.ExportPKCS12(client.SerialNumber, "s3cret")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	key, leaf, caCerts, err := pkcs12.DecodeChain(data, "s3cret")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, client.PrivateKey, key)
+	assert.Equal(t, client.X509.Raw, leaf.Raw)
+	if assert.Len(t, caCerts, 1) {
+		assert.Equal(t, ca.X509.Raw, caCerts[0].Raw)
+	}
+
+	_, _, _, err = pkcs12.DecodeChain(data, "wrong")
+	assert.Error(t, err)
+
+	_, err = manager.ExportPKCS12(client.SerialNumber, "")
+	assert.Error(t, err)
+}
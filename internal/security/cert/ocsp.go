@@ -0,0 +1,284 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxOCSPResponseSize bounds the size of an OCSP responder reply
+const maxOCSPResponseSize = 64 * 1024
+
+// OCSPConfig represents configuration for OCSP stapling
+type OCSPConfig struct {
+	Issuer        *x509.Certificate // Issuer of the stapled certificate, used to build and verify requests
+	RefreshMargin time.Duration     // Refresh the staple this long before the response's NextUpdate
+	RetryInterval time.Duration     // Retry delay after a failed fetch
+	Timeout       time.Duration     // Timeout for a single responder request
+	HTTPClient    *http.Client
+}
+
+// DefaultOCSPConfig returns the default OCSP stapling configuration
+func DefaultOCSPConfig(issuer *x509.Certificate) *OCSPConfig {
+	return &OCSPConfig{
+		Issuer:        issuer,
+		RefreshMargin: 1 * time.Hour,
+		RetryInterval: 5 * time.Minute,
+		Timeout:       10 * time.Second,
+	}
+}
+
+// ocspStaple is a cached OCSP response for a single certificate
+type ocspStaple struct {
+	serial     string
+	raw        []byte
+	nextUpdate time.Time
+}
+
+// OCSPStapler fetches and caches OCSP responses for the active server certificate
+// so they can be stapled to TLS handshakes. When the responder is unavailable the
+// certificate is served without a staple rather than failing the handshake.
+type OCSPStapler struct {
+	config *OCSPConfig
+	logger *zap.Logger
+	client *http.Client
+
+	mu     sync.Mutex
+	leaf   *x509.Certificate
+	staple atomic.Pointer[ocspStaple]
+	wakeCh chan struct{}
+	stopCh chan struct{}
+}
+
+// NewOCSPStapler creates a new OCSP stapler
+func NewOCSPStapler(config *OCSPConfig, logger *zap.Logger) (*OCSPStapler, error) {
+	if config == nil || config.Issuer == nil {
+		return nil, fmt.Errorf("OCSP stapling requires an issuer certificate")
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	return &OCSPStapler{
+		config: config,
+		logger: logger,
+		client: client,
+		wakeCh: make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// Start begins refreshing the staple before it expires
+func (s *OCSPStapler) Start(ctx context.Context) {
+	go s.refreshLoop(ctx)
+}
+
+// Stop stops the refresh loop
+func (s *OCSPStapler) Stop() {
+	close(s.stopCh)
+}
+
+// Update sets the certificate to staple and fetches a fresh response for it. On
+// failure the previous staple is dropped so the new certificate is served unstapled.
+func (s *OCSPStapler) Update(leaf *x509.Certificate) error {
+	s.mu.Lock()
+	s.leaf = leaf
+	s.mu.Unlock()
+
+	err := s.Refresh()
+
+	// Reschedule the refresh loop for the new certificate
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+
+	return err
+}
+
+// Refresh fetches a new OCSP response for the current certificate
+func (s *OCSPStapler) Refresh() error {
+	s.mu.Lock()
+	leaf := s.leaf
+	s.mu.Unlock()
+
+	if leaf == nil {
+		return fmt.Errorf("no certificate to staple")
+	}
+
+	staple, err := s.fetch(leaf)
+	if err != nil {
+		if current := s.staple.Load(); current != nil && current.serial != leaf.SerialNumber.String() {
+			s.staple.Store(nil)
+		}
+		return err
+	}
+
+	s.staple.Store(staple)
+	s.logger.Debug("Refreshed OCSP staple",
+		zap.String("serial", staple.serial),
+		zap.Time("next_update", staple.nextUpdate),
+	)
+	return nil
+}
+
+// Staple returns the certificate with the cached OCSP response attached. The
+// certificate is returned unchanged if there is no valid staple for it.
+func (s *OCSPStapler) Staple(cert *tls.Certificate) *tls.Certificate {
+	staple := s.staple.Load()
+	if cert == nil || staple == nil || time.Now().After(staple.nextUpdate) {
+		return cert
+	}
+
+	leaf := cert.Leaf
+	if leaf == nil {
+		if len(cert.Certificate) == 0 {
+			return cert
+		}
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return cert
+		}
+		leaf = parsed
+	}
+	if leaf.SerialNumber.String() != staple.serial {
+		return cert
+	}
+
+	stapled := *cert
+	stapled.OCSPStaple = staple.raw
+	return &stapled
+}
+
+// GetCertificate wraps a tls.Config.GetCertificate callback to staple its certificates
+func (s *OCSPStapler) GetCertificate(get func(*tls.ClientHelloInfo) (*tls.Certificate, error)) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := get(hello)
+		if err != nil {
+			return nil, err
+		}
+		return s.Staple(cert), nil
+	}
+}
+
+// refreshLoop refreshes the staple ahead of its NextUpdate, retrying on failure
+func (s *OCSPStapler) refreshLoop(ctx context.Context) {
+	timer := time.NewTimer(s.nextRefresh())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-s.wakeCh:
+		case <-timer.C:
+			if err := s.Refresh(); err != nil {
+				s.logger.Warn("OCSP staple refresh failed, serving without staple",
+					zap.Error(err),
+				)
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(s.nextRefresh())
+	}
+}
+
+// nextRefresh returns the delay until the staple should be refreshed
+func (s *OCSPStapler) nextRefresh() time.Duration {
+	staple := s.staple.Load()
+	if staple == nil {
+		return s.config.RetryInterval
+	}
+
+	remaining := time.Until(staple.nextUpdate)
+	if remaining <= 0 {
+		return s.config.RetryInterval
+	}
+	if remaining > s.config.RefreshMargin*2 {
+		return remaining - s.config.RefreshMargin
+	}
+	return remaining / 2
+}
+
+// fetch requests and verifies an OCSP response for the certificate
+func (s *OCSPStapler) fetch(leaf *x509.Certificate) (*ocspStaple, error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, fmt.Errorf("certificate has no OCSP servers")
+	}
+
+	request, err := ocsp.CreateRequest(leaf, s.config.Issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCSP request: %v", err)
+	}
+
+	var lastErr error
+	for _, server := range leaf.OCSPServer {
+		raw, err := s.post(server, request)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		resp, err := ocsp.ParseResponseForCert(raw, leaf, s.config.Issuer)
+		if err != nil {
+			lastErr = fmt.Errorf("invalid OCSP response from %s: %v", server, err)
+			continue
+		}
+		if resp.Status != ocsp.Good {
+			return nil, fmt.Errorf("OCSP responder %s reports certificate %s as not good (status %d)",
+				server, leaf.SerialNumber, resp.Status)
+		}
+
+		nextUpdate := resp.NextUpdate
+		if nextUpdate.IsZero() {
+			nextUpdate = time.Now().Add(s.config.RetryInterval)
+		}
+
+		return &ocspStaple{
+			serial:     leaf.SerialNumber.String(),
+			raw:        raw,
+			nextUpdate: nextUpdate,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("failed to fetch OCSP response: %v", lastErr)
+}
+
+// post sends an OCSP request to a responder and returns the raw response
+func (s *OCSPStapler) post(server string, request []byte) ([]byte, error) {
+	resp, err := s.client.Post(server, "application/ocsp-request", bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("OCSP request to %s failed: %v", server, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder %s returned status %d", server, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCSP response from %s: %v", server, err)
+	}
+	return body, nil
+}
@@ -0,0 +1,183 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ocsp"
+)
+
+type ocspTestPKI struct {
+	ca      *x509.Certificate
+	caKey   *rsa.PrivateKey
+	leaf    *x509.Certificate
+	leafKey *rsa.PrivateKey
+}
+
+func createOCSPTestPKI(t *testing.T, responderURL string) *ocspTestPKI {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	assert.NoError(t, err)
+
+	leafKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(200),
+		Subject:      pkix.Name{CommonName: "test.example.com"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(12 * time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"test.example.com"},
+		OCSPServer:   []string{responderURL},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	leaf, err := x509.ParseCertificate(leafDER)
+	assert.NoError(t, err)
+
+	return &ocspTestPKI{ca: ca, caKey: caKey, leaf: leaf, leafKey: leafKey}
+}
+
+// newOCSPResponder returns a mock OCSP responder that reports every certificate as good
+func newOCSPResponder(t *testing.T, pki **ocspTestPKI, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		req, err := ocsp.ParseRequest(body)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		resp, err := ocsp.CreateResponse((*pki).ca, (*pki).ca, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   now.Add(-time.Minute),
+			NextUpdate:   now.Add(time.Hour),
+		}, (*pki).caKey)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+}
+
+func dialOCSPStaple(t *testing.T, config *tls.Config) []byte {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().OCSPResponse
+}
+
+func TestOCSPStapler_StaplesResponse(t *testing.T) {
+	var pki *ocspTestPKI
+	requests := 0
+	responder := newOCSPResponder(t, &pki, &requests)
+	defer responder.Close()
+
+	pki = createOCSPTestPKI(t, responder.URL)
+
+	stapler, err := NewOCSPStapler(DefaultOCSPConfig(pki.ca), zap.NewNop())
+	assert.NoError(t, err)
+	assert.NoError(t, stapler.Update(pki.leaf))
+	assert.Equal(t, 1, requests)
+
+	tlsCert := &tls.Certificate{
+		Certificate: [][]byte{pki.leaf.Raw},
+		PrivateKey:  pki.leafKey,
+	}
+	config := &tls.Config{
+		GetCertificate: stapler.GetCertificate(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return tlsCert, nil
+		}),
+	}
+
+	staple := dialOCSPStaple(t, config)
+	if !assert.NotEmpty(t, staple, "Expected OCSP staple in handshake") {
+		return
+	}
+
+	resp, err := ocsp.ParseResponseForCert(staple, pki.leaf, pki.ca)
+	assert.NoError(t, err)
+	assert.Equal(t, ocsp.Good, resp.Status)
+	assert.Equal(t, pki.leaf.SerialNumber, resp.SerialNumber)
+
+	// The cached staple is reused rather than fetched per handshake
+	assert.Equal(t, 1, requests)
+	assert.Nil(t, tlsCert.OCSPStaple, "Source certificate should not be modified")
+}
+
+func TestOCSPStapler_ResponderDown(t *testing.T) {
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	responderURL := responder.URL
+	responder.Close()
+
+	pki := createOCSPTestPKI(t, responderURL)
+
+	stapler, err := NewOCSPStapler(DefaultOCSPConfig(pki.ca), zap.NewNop())
+	assert.NoError(t, err)
+	assert.Error(t, stapler.Update(pki.leaf))
+
+	tlsCert := &tls.Certificate{
+		Certificate: [][]byte{pki.leaf.Raw},
+		PrivateKey:  pki.leafKey,
+	}
+	config := &tls.Config{
+		GetCertificate: stapler.GetCertificate(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return tlsCert, nil
+		}),
+	}
+
+	// Handshakes succeed without a staple
+	assert.Empty(t, dialOCSPStaple(t, config))
+}
+
+func TestNewOCSPStapler_RequiresIssuer(t *testing.T) {
+	_, err := NewOCSPStapler(DefaultOCSPConfig(nil), zap.NewNop())
+	assert.Error(t, err)
+}
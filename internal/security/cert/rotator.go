@@ -2,9 +2,11 @@ package cert
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -31,6 +33,13 @@ type CertificateRotator struct {
 	rotationTimer *time.Timer
 	stopCh        chan struct{}
 	metrics       RotationMetrics
+
+	// rotateMu serializes scheduled and on-demand rotations
+	rotateMu sync.Mutex
+	// tlsCert is the certificate served to new TLS handshakes
+	tlsCert atomic.Pointer[tls.Certificate]
+	// stapler attaches OCSP responses to served certificates when set
+	stapler *OCSPStapler
 }
 
 // NewCertificateRotator creates a new certificate rotator
@@ -47,6 +56,12 @@ func NewCertificateRotator(config *RotationConfig, manager CertificateManager, l
 	}
 }
 
+// SetOCSPStapler enables OCSP stapling for certificates served by GetCertificate.
+// It must be called before Start.
+func (r *CertificateRotator) SetOCSPStapler(stapler *OCSPStapler) {
+	r.stapler = stapler
+}
+
 // Start begins the rotation monitoring process
 func (r *CertificateRotator) Start(ctx context.Context) error {
 	// Load initial certificate
@@ -64,12 +79,20 @@ func (r *CertificateRotator) Start(ctx context.Context) error {
 		return fmt.Errorf("initial certificate validation failed: %v", err)
 	}
 
+	tlsCert, err := r.toTLSCertificate(cert)
+	if err != nil {
+		return fmt.Errorf("failed to load initial certificate: %v", err)
+	}
+
 	r.mu.Lock()
 	r.current = cert
+	r.tlsCert.Store(tlsCert)
 	r.metrics.LastRotation = time.Now()
 	r.metrics.NextRotation = cert.X509.NotAfter.Add(-r.config.RenewalWindow)
 	r.mu.Unlock()
 
+	r.updateStaple(cert)
+
 	// Start rotation monitoring
 	go r.monitor(ctx)
 
@@ -105,6 +128,50 @@ func (r *CertificateRotator) GetPrevious() *Certificate {
 	return r.previous
 }
 
+// GetCertificate returns the certificate for a new TLS handshake. It is meant to be
+// used as tls.Config.GetCertificate so rotated certificates take effect immediately
+// while established connections keep the certificate they negotiated.
+func (r *CertificateRotator) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := r.tlsCert.Load()
+	if cert == nil {
+		return nil, fmt.Errorf("no current certificate")
+	}
+	if r.stapler != nil {
+		return r.stapler.Staple(cert), nil
+	}
+	return cert, nil
+}
+
+// updateStaple fetches an OCSP staple for a newly active certificate. Failures are
+// logged and the certificate is served without a staple.
+func (r *CertificateRotator) updateStaple(cert *Certificate) {
+	if r.stapler == nil {
+		return
+	}
+
+	if err := r.stapler.Update(cert.X509); err != nil {
+		r.logger.Warn("Failed to fetch OCSP staple, serving certificate without staple",
+			zap.String("serial", cert.SerialNumber),
+			zap.Error(err),
+		)
+	}
+}
+
+// Rotate replaces the current certificate immediately, regardless of the renewal window
+func (r *CertificateRotator) Rotate() error {
+	r.mu.Lock()
+	r.metrics.RotationAttempts++
+	r.mu.Unlock()
+
+	if err := r.rotate(); err != nil {
+		r.mu.Lock()
+		r.metrics.RotationErrors++
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
 // GetMetrics returns the current rotation metrics
 func (r *CertificateRotator) GetMetrics() RotationMetrics {
 	r.mu.RLock()
@@ -201,6 +268,23 @@ func (r *CertificateRotator) checkRotation() error {
 	r.metrics.RotationAttempts++
 	r.mu.Unlock()
 
+	return r.rotate()
+}
+
+// rotate issues, validates and stores a replacement for the current certificate, then
+// swaps it in for new TLS handshakes
+func (r *CertificateRotator) rotate() error {
+	r.rotateMu.Lock()
+	defer r.rotateMu.Unlock()
+
+	r.mu.RLock()
+	cert := r.current
+	r.mu.RUnlock()
+
+	if cert == nil {
+		return fmt.Errorf("no current certificate")
+	}
+
 	// Create certificate request for renewal
 	req := &CertificateRequest{
 		Type:        cert.Type,
@@ -212,6 +296,7 @@ func (r *CertificateRotator) checkRotation() error {
 		ExtKeyUsage: cert.X509.ExtKeyUsage,
 		NotBefore:   time.Now(),
 		NotAfter:    time.Now().Add(r.config.RenewalWindow * 2),
+		KeyType:     r.config.KeyType,
 		KeySize:     r.config.KeySize,
 		Metadata:    cert.Metadata,
 	}
@@ -242,6 +327,11 @@ func (r *CertificateRotator) checkRotation() error {
 		return fmt.Errorf("new certificate validation failed: %v", err)
 	}
 
+	tlsCert, err := r.toTLSCertificate(newCert)
+	if err != nil {
+		return fmt.Errorf("failed to load new certificate: %v", err)
+	}
+
 	// Store new certificate
 	if err := r.manager.GetCertificateStore().Store(newCert.ToCertPair()); err != nil {
 		return fmt.Errorf("failed to store new certificate: %v", err)
@@ -251,10 +341,13 @@ func (r *CertificateRotator) checkRotation() error {
 	r.mu.Lock()
 	r.previous = r.current
 	r.current = newCert
+	r.tlsCert.Store(tlsCert)
 	r.metrics.LastRotation = time.Now()
 	r.metrics.NextRotation = newCert.X509.NotAfter.Add(-r.config.RenewalWindow)
 	r.mu.Unlock()
 
+	r.updateStaple(newCert)
+
 	// Notify rotation
 	if r.config.OnRotation != nil {
 		r.config.OnRotation(cert.X509, newCert.X509)
@@ -284,3 +377,30 @@ func (r *CertificateRotator) checkRotation() error {
 
 	return nil
 }
+
+// toTLSCertificate converts a certificate and its private key for use in a TLS
+// handshake. The intermediates from the store are presented after the leaf so clients
+// that only trust the root can build the chain.
+func (r *CertificateRotator) toTLSCertificate(cert *Certificate) (*tls.Certificate, error) {
+	if cert.X509 == nil || cert.PrivateKey == nil {
+		return nil, fmt.Errorf("certificate %s has no key pair", cert.SerialNumber)
+	}
+
+	chain, err := r.manager.GetCertificateStore().GetChain(cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate chain: %v", err)
+	}
+
+	tlsCert := &tls.Certificate{
+		Certificate: [][]byte{cert.X509.Raw},
+		PrivateKey:  cert.PrivateKey,
+		Leaf:        cert.X509,
+	}
+	for _, c := range chain {
+		if c.Type == CertTypeIntermediate {
+			tlsCert.Certificate = append(tlsCert.Certificate, c.X509.Raw)
+		}
+	}
+
+	return tlsCert, nil
+}
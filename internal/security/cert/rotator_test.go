@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"math/big"
@@ -129,11 +130,15 @@ func (m *MockCertificateManager) Validate(cert *Certificate) error {
 }
 
 func createTestCertificate(t *testing.T, notBefore, notAfter time.Time) (*x509.Certificate, *rsa.PrivateKey) {
+	return createTestCertificateWithSerial(t, 1, notBefore, notAfter)
+}
+
+func createTestCertificateWithSerial(t *testing.T, serial int64, notBefore, notAfter time.Time) (*x509.Certificate, *rsa.PrivateKey) {
 	key, err := rsa.GenerateKey(rand.Reader, 2048)
 	assert.NoError(t, err)
 
 	template := &x509.Certificate{
-		SerialNumber: big.NewInt(1),
+		SerialNumber: big.NewInt(serial),
 		Subject: pkix.Name{
 			CommonName: "test.example.com",
 		},
@@ -171,6 +176,7 @@ func TestCertificateRotator_Start(t *testing.T) {
 	// Set up expectations for initial certificate loading and validation
 	store.On("LoadCurrent").Return(certPair, nil)
 	manager.On("GetCertificateStore").Return(store)
+	store.On("GetChain", mock.Anything).Return([]*Certificate{}, nil)
 	manager.On("Validate", mock.MatchedBy(func(c *Certificate) bool {
 		return c.X509.SerialNumber.String() == cert.SerialNumber.String() && c.Type == CertTypeServer
 	})).Return(nil)
@@ -227,6 +233,7 @@ func TestCertificateRotator_CheckRotation(t *testing.T) {
 	// Set up expectations for initial certificate loading and validation
 	store.On("LoadCurrent").Return(oldCertPair, nil)
 	manager.On("GetCertificateStore").Return(store)
+	store.On("GetChain", mock.Anything).Return([]*Certificate{}, nil)
 	manager.On("Validate", mock.MatchedBy(func(c *Certificate) bool {
 		return c.X509.SerialNumber.String() == oldCert.SerialNumber.String() && c.Type == CertTypeServer
 	})).Return(nil)
@@ -317,3 +324,73 @@ func TestCertificateRotator_CheckRotation(t *testing.T) {
 	store.AssertExpectations(t)
 	manager.AssertExpectations(t)
 }
+
+func TestCertificateRotator_RotateHotReload(t *testing.T) {
+	store := &MockCertificateStore{}
+	manager := &MockCertificateManager{}
+	logger := zap.NewNop()
+
+	now := time.Now()
+	oldCert, oldKey := createTestCertificateWithSerial(t, 1, now, now.Add(24*time.Hour))
+	newCert, newKey := createTestCertificateWithSerial(t, 2, now, now.Add(48*time.Hour))
+
+	store.On("LoadCurrent").Return(&CertPair{Cert: oldCert, Key: oldKey}, nil)
+	store.On("ValidateCRL", mock.Anything, mock.Anything).Return(nil)
+	store.On("ValidateOCSP", mock.Anything).Return(nil)
+	store.On("Store", mock.Anything).Return(nil)
+	manager.On("GetCertificateStore").Return(store)
+	store.On("GetChain", mock.Anything).Return([]*Certificate{}, nil)
+	manager.On("Validate", mock.Anything).Return(nil)
+	manager.On("CreateServer", mock.Anything, mock.Anything).Return((&CertPair{Cert: newCert, Key: newKey}).ToCertificate(), nil)
+
+	config := &RotationConfig{
+		RotationInterval: time.Hour,
+		RenewalWindow:    time.Hour,
+		GracePeriod:      time.Hour,
+		KeySize:          2048,
+	}
+
+	rotator := NewCertificateRotator(config, manager, logger)
+	assert.NoError(t, rotator.Start(context.Background()))
+	defer rotator.Stop()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: rotator.GetCertificate})
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go conn.(*tls.Conn).Handshake()
+		}
+	}()
+
+	dial := func() *tls.Conn {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		return conn
+	}
+
+	existing := dial()
+	defer existing.Close()
+	assert.Equal(t, "1", existing.ConnectionState().PeerCertificates[0].SerialNumber.String())
+
+	assert.NoError(t, rotator.Rotate())
+	assert.Equal(t, "2", rotator.GetCurrent().SerialNumber)
+
+	rotated := dial()
+	defer rotated.Close()
+	assert.Equal(t, "2", rotated.ConnectionState().PeerCertificates[0].SerialNumber.String())
+
+	// Connections established before the rotation keep their certificate
+	assert.Equal(t, "1", existing.ConnectionState().PeerCertificates[0].SerialNumber.String())
+
+	metrics := rotator.GetMetrics()
+	assert.Equal(t, int64(1), metrics.RotationAttempts)
+	assert.Equal(t, int64(0), metrics.RotationErrors)
+}
@@ -1,6 +1,7 @@
 package cert
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -10,12 +11,15 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/cert/keys"
 )
 
 // FileStore implements CertificateStore using the filesystem
 type FileStore struct {
 	certPath string
 	keyPath  string
+	caPath   string
 	mu       sync.RWMutex
 	client   *http.Client
 }
@@ -31,6 +35,14 @@ func NewFileStore(certPath, keyPath string) *FileStore {
 	}
 }
 
+// NewFileStoreWithCA creates a new file-based certificate store whose chain includes
+// the CA certificates in caPath
+func NewFileStoreWithCA(certPath, keyPath, caPath string) *FileStore {
+	s := NewFileStore(certPath, keyPath)
+	s.caPath = caPath
+	return s
+}
+
 // LoadCurrent implements Store.LoadCurrent
 func (s *FileStore) LoadCurrent() (*CertPair, error) {
 	s.mu.RLock()
@@ -63,7 +75,7 @@ func (s *FileStore) LoadCurrent() (*CertPair, error) {
 		return nil, fmt.Errorf("failed to decode key PEM")
 	}
 
-	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	key, err := keys.Parse(keyBlock)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse private key: %v", err)
 	}
@@ -84,21 +96,23 @@ func (s *FileStore) Store(cert *CertPair) error {
 		return fmt.Errorf("failed to create certificate directory: %v", err)
 	}
 
-	// Write certificate
-	certPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: cert.Cert.Raw,
-	})
+	// Write certificate followed by its intermediates, so the key pair loads with the
+	// chain a server presents
+	certPEM, err := s.chainPEM(cert.Cert)
+	if err != nil {
+		return err
+	}
 
 	if err := os.WriteFile(s.certPath, certPEM, 0644); err != nil {
 		return fmt.Errorf("failed to write certificate file: %v", err)
 	}
 
 	// Write private key
-	keyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(cert.Key),
-	})
+	keyBlock, err := keys.Encode(cert.Key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(keyBlock)
 
 	if err := os.WriteFile(s.keyPath, keyPEM, 0600); err != nil {
 		return fmt.Errorf("failed to write key file: %v", err)
@@ -213,10 +227,126 @@ func (s *FileStore) UpdateStatus(serialNumber string, status CertificateStatus)
 	return s.Update(cert)
 }
 
-// GetChain implements CertificateStore.GetChain
+// GetChain implements CertificateStore.GetChain. The chain is ordered from the
+// certificate up to its root and only contains the CA certificates that issued it.
 func (s *FileStore) GetChain(cert *Certificate) ([]*Certificate, error) {
-	// For now, just return the certificate itself
-	return []*Certificate{cert}, nil
+	chain := []*Certificate{cert}
+
+	cas, err := s.loadCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, caCert := range issuingChain(cert.X509, cas) {
+		c := (&CertPair{Cert: caCert}).ToCertificate()
+		if !isSelfSigned(caCert) {
+			c.Type = CertTypeIntermediate
+		}
+		chain = append(chain, c)
+	}
+
+	return chain, nil
+}
+
+// WriteChainFile writes a certificate and its intermediates, excluding the root, to a
+// PEM file that can be loaded as the daemon's certificate file
+func (s *FileStore) WriteChainFile(cert *Certificate, path string) error {
+	chainPEM, err := s.chainPEM(cert.X509)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create chain directory: %v", err)
+	}
+	if err := os.WriteFile(path, chainPEM, 0644); err != nil {
+		return fmt.Errorf("failed to write chain file: %v", err)
+	}
+
+	return nil
+}
+
+// chainPEM encodes a certificate followed by the intermediates that issued it
+func (s *FileStore) chainPEM(cert *x509.Certificate) ([]byte, error) {
+	cas, err := s.loadCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	chainPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	for _, caCert := range issuingChain(cert, cas) {
+		if isSelfSigned(caCert) {
+			break
+		}
+		chainPEM = append(chainPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})...)
+	}
+
+	return chainPEM, nil
+}
+
+// CACertificates returns the CA certificates in the store's CA file
+func (s *FileStore) CACertificates() ([]*x509.Certificate, error) {
+	return s.loadCAs()
+}
+
+// loadCAs reads the CA certificates in caPath
+func (s *FileStore) loadCAs() ([]*x509.Certificate, error) {
+	if s.caPath == "" {
+		return nil, nil
+	}
+
+	caPEM, err := os.ReadFile(s.caPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %v", err)
+	}
+
+	var cas []*x509.Certificate
+	for block, rest := pem.Decode(caPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		caCert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA certificate: %v", err)
+		}
+		if caCert.IsCA {
+			cas = append(cas, caCert)
+		}
+	}
+
+	return cas, nil
+}
+
+// issuingChain returns the CA certificates that issued cert, ordered from its issuer
+// up to the root
+func issuingChain(cert *x509.Certificate, cas []*x509.Certificate) []*x509.Certificate {
+	var chain []*x509.Certificate
+	current := cert
+
+	for !isSelfSigned(current) && len(chain) < len(cas) {
+		var issuer *x509.Certificate
+		for _, ca := range cas {
+			if bytes.Equal(ca.Raw, current.Raw) || !bytes.Equal(ca.RawSubject, current.RawIssuer) {
+				continue
+			}
+			if current.CheckSignatureFrom(ca) == nil {
+				issuer = ca
+				break
+			}
+		}
+		if issuer == nil {
+			break
+		}
+		chain = append(chain, issuer)
+		current = issuer
+	}
+
+	return chain
+}
+
+// isSelfSigned reports whether a certificate is a root
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
 }
 
 // ValidateCRL validates a certificate against a CRL
@@ -0,0 +1,130 @@
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// createTestChain creates a root, an intermediate and a server certificate. The CA
+// file lists the root before the intermediate.
+func createTestChain(t *testing.T, dir string) (root, intermediate, server *Certificate, store *FileStore) {
+	now := time.Now()
+	req := func(cn string) *CertificateRequest {
+		return &CertificateRequest{
+			Subject:   pkix.Name{CommonName: cn},
+			DNSNames:  []string{cn},
+			NotBefore: now.Add(-time.Minute),
+			NotAfter:  now.Add(2 * time.Hour),
+			KeyType:   KeyTypeECDSAP256,
+		}
+	}
+
+	rootReq := req("Test Root CA")
+	rootReq.MaxPathLen = 1
+	root, err := NewManager(NewFileStore(filepath.Join(dir, "root.crt"), filepath.Join(dir, "root.key")), zap.NewNop()).
+		CreateCA(rootReq)
+	require.NoError(t, err)
+	intermediate, err = NewManager(NewFileStore(filepath.Join(dir, "intermediate.crt"), filepath.Join(dir, "intermediate.key")), zap.NewNop()).
+		CreateIntermediate(req("Test Intermediate CA"), root)
+	require.NoError(t, err)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.X509.Raw})
+	caPEM = append(caPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: intermediate.X509.Raw})...)
+	caPath := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caPath, caPEM, 0644))
+
+	store = NewFileStoreWithCA(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), caPath)
+	server, err = NewManager(store, zap.NewNop()).CreateServer(req("server.example.com"), intermediate)
+	require.NoError(t, err)
+
+	return root, intermediate, server, store
+}
+
+func TestFileStore_GetChain(t *testing.T) {
+	dir := t.TempDir()
+	root, intermediate, server, store := createTestChain(t, dir)
+
+	chain, err := store.GetChain(server)
+	require.NoError(t, err)
+	require.Len(t, chain, 3)
+	assert.Equal(t, server.SerialNumber, chain[0].SerialNumber)
+	assert.Equal(t, intermediate.SerialNumber, chain[1].SerialNumber)
+	assert.Equal(t, CertTypeIntermediate, chain[1].Type)
+	assert.Equal(t, root.SerialNumber, chain[2].SerialNumber)
+	assert.Equal(t, CertTypeCA, chain[2].Type)
+
+	// The stored certificate file and the chain file hold the leaf and intermediate
+	chainPath := filepath.Join(dir, "chain", "server-chain.pem")
+	require.NoError(t, store.WriteChainFile(server, chainPath))
+	for _, path := range []string{filepath.Join(dir, "server.crt"), chainPath} {
+		keyPair, err := tls.LoadX509KeyPair(path, filepath.Join(dir, "server.key"))
+		require.NoError(t, err)
+		if assert.Len(t, keyPair.Certificate, 2) {
+			assert.Equal(t, server.X509.Raw, keyPair.Certificate[0])
+			assert.Equal(t, intermediate.X509.Raw, keyPair.Certificate[1])
+		}
+	}
+
+	// Loading the bundled certificate file still returns the leaf
+	current, err := store.LoadCurrent()
+	require.NoError(t, err)
+	assert.Equal(t, server.SerialNumber, current.Cert.SerialNumber.String())
+}
+
+func TestCertificateRotator_PresentsChain(t *testing.T) {
+	root, intermediate, _, store := createTestChain(t, t.TempDir())
+
+	config := &RotationConfig{
+		RotationInterval: time.Hour,
+		RenewalWindow:    time.Hour,
+		GracePeriod:      time.Hour,
+		KeyType:          KeyTypeECDSAP256,
+	}
+	rotator := NewCertificateRotator(config, NewManager(store, zap.NewNop()), zap.NewNop())
+	require.NoError(t, rotator.Start(context.Background()))
+	defer rotator.Stop()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: rotator.GetCertificate})
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go conn.(*tls.Conn).Handshake()
+		}
+	}()
+
+	// Clients only trust the root
+	roots := x509.NewCertPool()
+	roots.AddCert(root.X509)
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+		RootCAs:    roots,
+		ServerName: "server.example.com",
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if assert.Len(t, state.PeerCertificates, 2) {
+		assert.Equal(t, intermediate.X509.Raw, state.PeerCertificates[1].Raw)
+	}
+	if assert.NotEmpty(t, state.VerifiedChains) {
+		chain := state.VerifiedChains[0]
+		assert.Equal(t, root.X509.Raw, chain[len(chain)-1].Raw)
+	}
+}
@@ -1,7 +1,7 @@
 package cert
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"net"
@@ -33,7 +33,7 @@ const (
 type Certificate struct {
 	Raw              []byte
 	X509             *x509.Certificate
-	PrivateKey       *rsa.PrivateKey
+	PrivateKey       crypto.Signer
 	Type             CertificateType
 	Status           CertificateStatus
 	SerialNumber     string
@@ -51,7 +51,7 @@ type Certificate struct {
 // CertPair represents a certificate and its private key
 type CertPair struct {
 	Cert *x509.Certificate
-	Key  *rsa.PrivateKey
+	Key  crypto.Signer
 }
 
 // ToCertPair converts a Certificate to a CertPair
@@ -106,7 +106,9 @@ type CertificateRequest struct {
 	ExtKeyUsage []x509.ExtKeyUsage
 	NotBefore   time.Time
 	NotAfter    time.Time
-	KeySize     int
+	KeyType     KeyType // Defaults to RSA
+	KeySize     int     // RSA key size in bits
+	MaxPathLen  int     // CA only: intermediate CAs allowed below it, 0 for none
 	Metadata    map[string]string
 }
 
@@ -115,6 +117,7 @@ type RotationConfig struct {
 	RotationInterval time.Duration
 	RenewalWindow    time.Duration
 	GracePeriod      time.Duration
+	KeyType          KeyType
 	KeySize          int
 	OnRotation       func(old, new *x509.Certificate)
 }
@@ -125,6 +128,7 @@ func DefaultRotationConfig() *RotationConfig {
 		RotationInterval: 1 * time.Hour,
 		RenewalWindow:    30 * 24 * time.Hour, // 30 days
 		GracePeriod:      24 * time.Hour,      // 1 day
+		KeyType:          KeyTypeRSA,
 		KeySize:          2048,
 	}
 }
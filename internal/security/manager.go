@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"syscall"
 
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
 	seccomp "github.com/seccomp/libseccomp-golang"
+	"go.uber.org/zap"
 	"golang.org/x/sys/unix"
 )
 
@@ -13,6 +15,10 @@ type SecurityManager struct {
 	// Seccomp filter
 	seccompFilter *seccomp.ScmpFilter
 
+	// Reports denied system calls in the log violations mode
+	violations *violationReporter
+	logger     *zap.Logger
+
 	// Capabilities to retain
 	keepCaps []string
 
@@ -41,6 +47,9 @@ type SecurityOptions struct {
 	// System call restrictions
 	SeccompMode     string   // Seccomp mode (disabled, strict, filtered)
 	AllowedSyscalls []string // Allowed system calls in filtered mode
+	SeccompProfile  string   // JSON seccomp profile used in place of AllowedSyscalls
+	// How denied system calls are handled (deny, log); strict mode always denies
+	SeccompViolations string
 
 	// Resource restrictions
 	NoCoreDump   bool // Disable core dumps
@@ -63,6 +72,7 @@ type SecurityOptions struct {
 func NewSecurityManager(opts SecurityOptions) (*SecurityManager, error) {
 	mgr := &SecurityManager{
 		options: opts,
+		logger:  zap.NewNop(),
 	}
 
 	// Initialize seccomp if enabled
@@ -75,6 +85,11 @@ func NewSecurityManager(opts SecurityOptions) (*SecurityManager, error) {
 	return mgr, nil
 }
 
+// SetLogger sets the logger that denied system calls are reported to
+func (m *SecurityManager) SetLogger(logger *zap.Logger) {
+	m.logger = logger
+}
+
 // Apply applies security hardening measures
 func (m *SecurityManager) Apply() error {
 	// Set secure process attributes
@@ -104,6 +119,13 @@ func (m *SecurityManager) Apply() error {
 		if err := m.seccompFilter.Load(); err != nil {
 			return fmt.Errorf("failed to load seccomp filter: %w", err)
 		}
+		if m.violations != nil {
+			fd, err := m.seccompFilter.GetNotifFd()
+			if err != nil {
+				return fmt.Errorf("failed to get seccomp notification fd: %w", err)
+			}
+			go m.reportViolations(fd)
+		}
 	}
 
 	return nil
@@ -221,12 +243,35 @@ func (m *SecurityManager) dropCapabilities() error {
 	return nil
 }
 
-// initSeccomp initializes seccomp filtering
+// initSeccomp initializes seccomp filtering from the seccomp profile, or from
+// the allowed syscalls when no profile is set
 func (m *SecurityManager) initSeccomp() error {
 	var err error
 
+	switch m.options.SeccompViolations {
+	case "", SeccompViolationsDeny:
+	case SeccompViolationsLog:
+		if m.options.SeccompMode != "strict" {
+			if m.violations, err = newViolationReporter(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown seccomp violations mode %q", m.options.SeccompViolations)
+	}
+
+	if m.options.SeccompProfile != "" {
+		profile, err := LoadSeccompProfile(m.options.SeccompProfile)
+		if err != nil {
+			return err
+		}
+		m.seccompFilter, err = profile.filter(m.violations)
+		return err
+	}
+
 	// Create filter
-	m.seccompFilter, err = seccomp.NewFilter(seccomp.ActErrno.SetReturnCode(int16(syscall.EPERM)))
+	defaultAction := m.violations.defaultAction(seccomp.ActErrno.SetReturnCode(int16(syscall.EPERM)))
+	m.seccompFilter, err = seccomp.NewFilter(defaultAction)
 	if err != nil {
 		return fmt.Errorf("failed to create seccomp filter: %w", err)
 	}
@@ -319,3 +364,15 @@ func GetDefaultOptions() SecurityOptions {
 		PrivateDevices: true,
 	}
 }
+
+// OptionsFromConfig returns the default options with the seccomp settings of
+// the security configuration applied
+func OptionsFromConfig(cfg types.SecurityConfig) SecurityOptions {
+	opts := GetDefaultOptions()
+	if !cfg.Seccomp.Enabled {
+		opts.SeccompMode = "disabled"
+	}
+	opts.SeccompProfile = cfg.Seccomp.Profile
+	opts.SeccompViolations = cfg.Seccomp.Violations
+	return opts
+}
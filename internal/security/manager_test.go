@@ -4,6 +4,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
 	"github.com/stretchr/testify/require"
 )
 
@@ -131,3 +132,15 @@ func TestSecurityManagerFeatures(t *testing.T) {
 		helper.VerifySeccompFilter(mgr)
 	})
 }
+
+func TestOptionsFromConfig(t *testing.T) {
+	opts := OptionsFromConfig(types.SecurityConfig{
+		Seccomp: types.SeccompConfig{Enabled: true, Profile: "/etc/sssonector/seccomp.json", Violations: "log"},
+	})
+	require.Equal(t, "filtered", opts.SeccompMode)
+	require.Equal(t, "/etc/sssonector/seccomp.json", opts.SeccompProfile)
+	require.Equal(t, SeccompViolationsLog, opts.SeccompViolations)
+
+	opts = OptionsFromConfig(types.SecurityConfig{})
+	require.Equal(t, "disabled", opts.SeccompMode)
+}
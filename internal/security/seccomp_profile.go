@@ -0,0 +1,209 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"syscall"
+
+	seccomp "github.com/seccomp/libseccomp-golang"
+)
+
+// SeccompProfile is a Docker/OCI-style seccomp profile. Rules are applied to
+// the native architecture; rules restricted to capabilities, architectures or
+// kernel versions are not supported.
+type SeccompProfile struct {
+	DefaultAction   string        `json:"defaultAction"`
+	DefaultErrnoRet *uint         `json:"defaultErrnoRet,omitempty"`
+	Syscalls        []SyscallRule `json:"syscalls"`
+}
+
+// SyscallRule applies an action to the named system calls, optionally only
+// when all of its argument conditions match
+type SyscallRule struct {
+	Names    []string     `json:"names"`
+	Name     string       `json:"name,omitempty"` // Single name, from older profiles
+	Action   string       `json:"action"`
+	ErrnoRet *uint        `json:"errnoRet,omitempty"`
+	Args     []SyscallArg `json:"args,omitempty"`
+	Includes ruleFilter   `json:"includes"`
+	Excludes ruleFilter   `json:"excludes"`
+}
+
+// SyscallArg compares one system call argument. SCMP_CMP_MASKED_EQ compares the
+// argument masked with Value to ValueTwo.
+type SyscallArg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo"`
+	Op       string `json:"op"`
+}
+
+// ruleFilter restricts a Docker profile rule to some hosts
+type ruleFilter struct {
+	Caps      []string `json:"caps,omitempty"`
+	Arches    []string `json:"arches,omitempty"`
+	MinKernel string   `json:"minKernel,omitempty"`
+}
+
+func (f ruleFilter) empty() bool {
+	return len(f.Caps) == 0 && len(f.Arches) == 0 && f.MinKernel == ""
+}
+
+var seccompActions = map[string]seccomp.ScmpAction{
+	"SCMP_ACT_ALLOW":        seccomp.ActAllow,
+	"SCMP_ACT_ERRNO":        seccomp.ActErrno,
+	"SCMP_ACT_KILL":         seccomp.ActKillThread,
+	"SCMP_ACT_KILL_THREAD":  seccomp.ActKillThread,
+	"SCMP_ACT_KILL_PROCESS": seccomp.ActKillProcess,
+	"SCMP_ACT_TRAP":         seccomp.ActTrap,
+	"SCMP_ACT_LOG":          seccomp.ActLog,
+}
+
+var seccompOperators = map[string]seccomp.ScmpCompareOp{
+	"SCMP_CMP_NE":        seccomp.CompareNotEqual,
+	"SCMP_CMP_LT":        seccomp.CompareLess,
+	"SCMP_CMP_LE":        seccomp.CompareLessOrEqual,
+	"SCMP_CMP_EQ":        seccomp.CompareEqual,
+	"SCMP_CMP_GE":        seccomp.CompareGreaterEqual,
+	"SCMP_CMP_GT":        seccomp.CompareGreater,
+	"SCMP_CMP_MASKED_EQ": seccomp.CompareMaskedEqual,
+}
+
+// LoadSeccompProfile reads a JSON seccomp profile and checks that its actions,
+// operators and system call names are known
+func LoadSeccompProfile(path string) (*SeccompProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seccomp profile: %w", err)
+	}
+
+	var profile SeccompProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse seccomp profile %s: %w", path, err)
+	}
+	if err := profile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile %s: %w", path, err)
+	}
+	return &profile, nil
+}
+
+// validate checks every rule without building a filter
+func (p *SeccompProfile) validate() error {
+	if _, err := parseSeccompAction(p.DefaultAction, p.DefaultErrnoRet); err != nil {
+		return fmt.Errorf("defaultAction: %w", err)
+	}
+
+	for i, rule := range p.Syscalls {
+		if !rule.Includes.empty() || !rule.Excludes.empty() {
+			return fmt.Errorf("rule %d: includes and excludes are not supported", i)
+		}
+		if _, err := parseSeccompAction(rule.Action, rule.ErrnoRet); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if _, err := rule.conditions(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		names := rule.names()
+		if len(names) == 0 {
+			return fmt.Errorf("rule %d: no system call names", i)
+		}
+		for _, name := range names {
+			if _, err := seccomp.GetSyscallFromName(name); err != nil {
+				return fmt.Errorf("rule %d: unknown system call %q", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Filter builds a seccomp filter applying the profile's rules. Rules whose
+// action is the default action are skipped, as libseccomp rejects them.
+func (p *SeccompProfile) Filter() (*seccomp.ScmpFilter, error) {
+	return p.filter(nil)
+}
+
+// filter builds the seccomp filter, with the SCMP_ACT_ERRNO actions reported
+// to violations when it is set
+func (p *SeccompProfile) filter(violations *violationReporter) (*seccomp.ScmpFilter, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	defaultAction, _ := parseSeccompAction(p.DefaultAction, p.DefaultErrnoRet)
+	defaultAction = violations.defaultAction(defaultAction)
+	filter, err := seccomp.NewFilter(defaultAction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create seccomp filter: %w", err)
+	}
+
+	for _, rule := range p.Syscalls {
+		conditions, _ := rule.conditions()
+
+		for _, name := range rule.names() {
+			syscallID, _ := seccomp.GetSyscallFromName(name)
+			action, _ := parseSeccompAction(rule.Action, rule.ErrnoRet)
+			if action = violations.ruleAction(syscallID, action); action == defaultAction {
+				continue
+			}
+			if len(conditions) == 0 {
+				err = filter.AddRule(syscallID, action)
+			} else {
+				err = filter.AddRuleConditional(syscallID, action, conditions)
+			}
+			if err != nil {
+				filter.Release()
+				return nil, fmt.Errorf("failed to add syscall rule for %s: %w", name, err)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// names returns the system calls of the rule, in either profile format
+func (r SyscallRule) names() []string {
+	if r.Name != "" {
+		return append([]string{r.Name}, r.Names...)
+	}
+	return r.Names
+}
+
+// conditions converts the argument comparisons of the rule
+func (r SyscallRule) conditions() ([]seccomp.ScmpCondition, error) {
+	var conditions []seccomp.ScmpCondition
+	for _, arg := range r.Args {
+		op, ok := seccompOperators[arg.Op]
+		if !ok {
+			return nil, fmt.Errorf("unknown operator %q", arg.Op)
+		}
+
+		values := []uint64{arg.Value}
+		if op == seccomp.CompareMaskedEqual {
+			values = append(values, arg.ValueTwo)
+		}
+		condition, err := seccomp.MakeCondition(arg.Index, op, values...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition on argument %d: %w", arg.Index, err)
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// parseSeccompAction converts a profile action. SCMP_ACT_ERRNO returns errnoRet,
+// or EPERM when it is not set.
+func parseSeccompAction(name string, errnoRet *uint) (seccomp.ScmpAction, error) {
+	action, ok := seccompActions[name]
+	if !ok {
+		return seccomp.ActInvalid, fmt.Errorf("unknown action %q", name)
+	}
+	if action == seccomp.ActErrno {
+		errno := uint(syscall.EPERM)
+		if errnoRet != nil {
+			errno = *errnoRet
+		}
+		action = action.SetReturnCode(int16(errno))
+	}
+	return action, nil
+}
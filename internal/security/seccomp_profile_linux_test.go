@@ -0,0 +1,131 @@
+//go:build linux
+
+package security
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	seccomp "github.com/seccomp/libseccomp-golang"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// seccompChildEnv makes the test binary load the profile it names and exercise
+// it, as a loaded seccomp filter cannot be removed from the process
+const seccompChildEnv = "SSSONECTOR_SECCOMP_PROFILE"
+
+func writeSeccompProfile(t *testing.T, profile string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "seccomp.json")
+	require.NoError(t, os.WriteFile(path, []byte(profile), 0644))
+	return path
+}
+
+func TestSeccompProfileBlocksSyscall(t *testing.T) {
+	if path := os.Getenv(seccompChildEnv); path != "" {
+		mgr, err := NewSecurityManager(SecurityOptions{SeccompMode: "filtered", SeccompProfile: path})
+		require.NoError(t, err)
+		require.NoError(t, mgr.Apply())
+
+		// chdir is denied by the profile; everything else is allowed
+		err = os.Chdir(os.TempDir())
+		require.True(t, errors.Is(err, syscall.EPERM), "Expected chdir to fail with EPERM, got %v", err)
+		_, err = os.Stat(path)
+		require.NoError(t, err)
+		return
+	}
+
+	path := writeSeccompProfile(t, `{
+		"defaultAction": "SCMP_ACT_ALLOW",
+		"syscalls": [
+			{"names": ["chdir", "fchdir"], "action": "SCMP_ACT_ERRNO", "errnoRet": 1}
+		]
+	}`)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSeccompProfileBlocksSyscall$", "-test.v")
+	cmd.Env = append(os.Environ(), seccompChildEnv+"="+path)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "Child process failed:\n%s", out)
+}
+
+func TestLoadSeccompProfileRejectsUnknownNames(t *testing.T) {
+	tests := []struct {
+		profile string
+		message string
+	}{
+		{`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["chdir", "not_a_syscall"], "action": "SCMP_ACT_ERRNO"}]}`, `unknown system call "not_a_syscall"`},
+		{`{"defaultAction": "SCMP_ACT_DENY"}`, `unknown action "SCMP_ACT_DENY"`},
+		{`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["kill"], "action": "SCMP_ACT_ERRNO", "args": [{"index": 1, "value": 9, "op": "SCMP_CMP_IS"}]}]}`, `unknown operator "SCMP_CMP_IS"`},
+		{`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mount"], "action": "SCMP_ACT_ALLOW", "includes": {"caps": ["CAP_SYS_ADMIN"]}}]}`, "includes and excludes are not supported"},
+	}
+
+	for _, tt := range tests {
+		_, err := LoadSeccompProfile(writeSeccompProfile(t, tt.profile))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), tt.message)
+	}
+}
+
+func TestSeccompProfileDefaultsToAllowedSyscalls(t *testing.T) {
+	mgr, err := NewSecurityManager(SecurityOptions{SeccompMode: "filtered", AllowedSyscalls: []string{"read", "write"}})
+	require.NoError(t, err)
+
+	action, err := mgr.seccompFilter.GetDefaultAction()
+	require.NoError(t, err)
+	require.Equal(t, int16(syscall.EPERM), action.GetReturnCode())
+}
+
+func TestSeccompViolationsAreLogged(t *testing.T) {
+	if api, err := seccomp.GetAPI(); err != nil || api < minNotifyAPI {
+		t.Skipf("libseccomp API level %d does not support notifications", api)
+	}
+
+	if path := os.Getenv(seccompChildEnv); path != "" {
+		mgr, err := NewSecurityManager(SecurityOptions{
+			SeccompMode:       "filtered",
+			SeccompProfile:    path,
+			SeccompViolations: SeccompViolationsLog,
+		})
+		require.NoError(t, err)
+		core, logs := observer.New(zapcore.WarnLevel)
+		mgr.SetLogger(zap.New(core))
+		require.NoError(t, mgr.Apply())
+
+		// The call is still denied, after being logged by name
+		err = os.Chdir(os.TempDir())
+		require.True(t, errors.Is(err, syscall.EPERM), "Expected chdir to fail with EPERM, got %v", err)
+
+		entries := logs.FilterMessage("Seccomp denied system call").All()
+		require.Len(t, entries, 1)
+		require.Equal(t, "chdir", entries[0].ContextMap()["syscall"])
+		return
+	}
+
+	path := writeSeccompProfile(t, `{
+		"defaultAction": "SCMP_ACT_ALLOW",
+		"syscalls": [
+			{"names": ["chdir"], "action": "SCMP_ACT_ERRNO"}
+		]
+	}`)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSeccompViolationsAreLogged$", "-test.v")
+	cmd.Env = append(os.Environ(), seccompChildEnv+"="+path)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "Child process failed:\n%s", out)
+}
+
+func TestSeccompStrictModeDenies(t *testing.T) {
+	mgr, err := NewSecurityManager(SecurityOptions{SeccompMode: "strict", SeccompViolations: SeccompViolationsLog})
+	require.NoError(t, err)
+	require.Nil(t, mgr.violations, "Expected strict mode to deny without reporting")
+
+	_, err = NewSecurityManager(SecurityOptions{SeccompMode: "filtered", SeccompViolations: "audit"})
+	require.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	seccomp "github.com/seccomp/libseccomp-golang"
+	"go.uber.org/zap"
+)
+
+// Ways of handling system calls denied by the seccomp filter
+const (
+	SeccompViolationsDeny = "deny" // Fail the call with its errno
+	SeccompViolationsLog  = "log"  // Log the call by name, then fail it with its errno
+)
+
+// minNotifyAPI is the libseccomp API level that supports ActNotify
+const minNotifyAPI = 6
+
+// violationReporter turns the SCMP_ACT_ERRNO actions of a filter into
+// ActNotify and remembers each errno, so that reportViolations can log a
+// denied call before failing it as the filter would have
+type violationReporter struct {
+	defaultErrno int16
+	errnos       map[seccomp.ScmpSyscall]int16
+}
+
+func newViolationReporter() (*violationReporter, error) {
+	if api, err := seccomp.GetAPI(); err != nil || api < minNotifyAPI {
+		return nil, fmt.Errorf("logging seccomp violations requires libseccomp API level %d, got %d", minNotifyAPI, api)
+	}
+	return &violationReporter{
+		defaultErrno: int16(syscall.EPERM),
+		errnos:       make(map[seccomp.ScmpSyscall]int16),
+	}, nil
+}
+
+// defaultAction returns the filter's default action; a nil reporter keeps it
+func (r *violationReporter) defaultAction(action seccomp.ScmpAction) seccomp.ScmpAction {
+	if r == nil || !isErrnoAction(action) {
+		return action
+	}
+	r.defaultErrno = action.GetReturnCode()
+	return seccomp.ActNotify
+}
+
+// ruleAction returns the action of a rule for call; a nil reporter keeps it
+func (r *violationReporter) ruleAction(call seccomp.ScmpSyscall, action seccomp.ScmpAction) seccomp.ScmpAction {
+	if r == nil || !isErrnoAction(action) {
+		return action
+	}
+	r.errnos[call] = action.GetReturnCode()
+	return seccomp.ActNotify
+}
+
+// errno returns the errno the filter denies call with
+func (r *violationReporter) errno(call seccomp.ScmpSyscall) int16 {
+	if errno, ok := r.errnos[call]; ok {
+		return errno
+	}
+	return r.defaultErrno
+}
+
+// isErrnoAction reports whether action is SCMP_ACT_ERRNO with any return code
+func isErrnoAction(action seccomp.ScmpAction) bool {
+	return action&0xFFFF == seccomp.ActErrno
+}
+
+// reportViolations logs each system call the loaded filter denies and fails it
+// with its errno, until the notification fd is closed. The calling thread is
+// blocked until the call is answered, so the calls made here must be allowed.
+func (m *SecurityManager) reportViolations(fd seccomp.ScmpFd) {
+	for {
+		req, err := seccomp.NotifReceive(fd)
+		if errors.Is(err, syscall.ENOENT) {
+			continue // The caller was interrupted before it was answered
+		}
+		if err != nil {
+			m.logger.Error("Stopped reporting seccomp violations", zap.Error(err))
+			return
+		}
+
+		name, err := req.Data.Syscall.GetName()
+		if err != nil {
+			name = fmt.Sprintf("syscall(%d)", int32(req.Data.Syscall))
+		}
+		errno := m.violations.errno(req.Data.Syscall)
+		m.logger.Warn("Seccomp denied system call",
+			zap.String("syscall", name),
+			zap.Uint32("pid", req.Pid),
+			zap.String("errno", syscall.Errno(errno).Error()))
+
+		resp := &seccomp.ScmpNotifResp{ID: req.ID, Error: int32(errno)}
+		if err := seccomp.NotifRespond(fd, resp); err != nil && !errors.Is(err, syscall.ENOENT) {
+			m.logger.Error("Failed to deny system call", zap.String("syscall", name), zap.Error(err))
+		}
+	}
+}
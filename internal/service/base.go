@@ -1,48 +1,122 @@
 package service
 
 import (
+	"context"
+	"crypto/x509"
 	"fmt"
 	"os"
 	"runtime"
 	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/pool"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"github.com/o3willard-AI/SSSonector/internal/security/access"
+	"github.com/o3willard-AI/SSSonector/internal/security/cert"
+	"github.com/o3willard-AI/SSSonector/internal/service/platform"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
 	"github.com/o3willard-AI/SSSonector/internal/tunnel"
 	"go.uber.org/zap"
 )
 
 // BaseService provides a base implementation of the Service interface
 type BaseService struct {
-	cfg     *types.AppConfig
-	options ServiceOptions
-	status  ServiceStatus
-	metrics ServiceMetrics
-	logger  *zap.Logger
-	server  *tunnel.Server
-	client  *tunnel.Client
+	cfg        *types.AppConfig
+	options    ServiceOptions
+	status     ServiceStatus
+	metrics    ServiceMetrics
+	logger     *zap.Logger
+	level      zap.AtomicLevel // Level of logger, changed by SetLogLevel
+	version    VersionInfo     // Build of the running binary, see SetVersion
+	server     *tunnel.Server
+	client     *tunnel.Client
+	rotator    *cert.CertificateRotator
+	stapler    *cert.OCSPStapler // Nil unless the server certificate is stapled
+	crl        *cert.CRLChecker  // Nil unless client certificates are checked against CRLs
+	recovery   *resilience.ErrorRecovery
+	rbac       *access.RBACManager
+	rbacFile   string
+	memory     *memory.MemoryManager
+	stopMem    context.CancelFunc
+	ipFilter   *access.IPFilterManager
+	health     *HealthChecker
+	limits     *throttle.Registry
+	history    func(time.Duration) []monitor.MetricsSnapshot // See SetMetricsHistory
+	monitor    *monitor.Monitor                              // See SetMonitor
+	stopReport context.CancelFunc
+	stateDir   *platform.StateDir // See SetStateDir
 }
 
+// CmdRotateCerts rotates the TLS certificate without restarting the service
+const CmdRotateCerts ServiceCommand = "rotate-certs"
+
+// CmdReloadRBAC re-reads the RBAC role and policy model without restarting the service
+const CmdReloadRBAC ServiceCommand = "rbac-reload"
+
+// CmdReloadACL re-applies the connection ACL rules and file without restarting the service
+const CmdReloadACL ServiceCommand = "acl-reload"
+
+// CmdConnections lists the server's active client connections
+const CmdConnections ServiceCommand = "connections"
+
+// CmdDisconnect closes one client connection, given by ID or remote address in the "id" argument
+const CmdDisconnect ServiceCommand = "disconnect"
+
+// CmdEndpoints lists, adds or removes the client's server endpoints. The
+// "action" argument is list (the default), add or remove; add and remove take
+// an "address" and add an optional "weight".
+const CmdEndpoints ServiceCommand = "endpoints"
+
+// CmdPool reports the client's connection pool statistics
+const CmdPool ServiceCommand = "pool"
+
+// CmdLogLevel sets the log level to the "level" argument (debug, info, warn or
+// error) without restarting, and reports the current level
+const CmdLogLevel ServiceCommand = "log-level"
+
+// CmdRateLimits reports the configured rate limits and current throttling per scope
+const CmdRateLimits ServiceCommand = "rate-limits"
+
+// CmdVersion reports the version, build time and commit of the running binary
+const CmdVersion ServiceCommand = "version"
+
+// CmdHistory reports the recent metrics snapshots, those within the optional
+// "window" argument (a duration such as 10m)
+const CmdHistory ServiceCommand = "history"
+
 // NewBaseService creates a new base service
 func NewBaseService(cfg *types.AppConfig, opts ServiceOptions) (*BaseService, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config is required")
 	}
 
-	logger, err := zap.NewProduction()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	logConfig := zap.NewProductionConfig()
+	logConfig.Level = level
+	logger, err := logConfig.Build()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
 
+	thresholds, err := HealthThresholdsFrom(cfg.Config.Monitor.Health)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health thresholds: %w", err)
+	}
+
 	svc := &BaseService{
 		cfg:     cfg,
 		options: opts,
 		logger:  logger,
+		level:   level,
+		health:  NewHealthChecker(thresholds),
+		limits:  throttle.NewRegistry(cfg.Throttle),
 		status: ServiceStatus{
 			Name:      opts.Name,
 			State:     "stopped",
 			StartTime: time.Time{},
 			Mode:      string(cfg.Config.Mode),
-			Version:   "1.0.0", // TODO: Get from build info
 		},
 		metrics: ServiceMetrics{
 			Platform: runtime.GOOS,
@@ -66,12 +140,56 @@ func (b *BaseService) Start() error {
 	switch b.cfg.Config.Mode {
 	case types.ModeServer:
 		b.server = tunnel.NewServer(b.cfg, nil, b.logger)
+		if b.monitor != nil {
+			b.monitor.SetLatencyTracker(b.server.LatencyTracker())
+		}
+		if aclConfig := b.cfg.Config.Security.ACL; access.ACLConfigured(aclConfig) {
+			ipFilter, err := access.NewIPFilterFromConfig(aclConfig, b.logger)
+			if err != nil {
+				b.status.State = "stopped"
+				return fmt.Errorf("failed to load ACL: %w", err)
+			}
+			b.ipFilter = ipFilter
+			b.server.SetIPFilter(b.ipFilter)
+		}
+		// Rejections are reported with the other rate limits
+		if limiter := b.limits.Connections(); limiter != nil {
+			b.server.SetConnectionLimiter(limiter)
+		}
+		b.server.SetRateLimiter(b.limits)
+		if err := b.useErrorRecovery(); err != nil {
+			b.status.State = "stopped"
+			return err
+		}
+		if err := b.useServerTLS(); err != nil {
+			b.status.State = "stopped"
+			return err
+		}
 		if err := b.server.Start(); err != nil {
 			b.status.State = "stopped"
 			return fmt.Errorf("failed to start server: %w", err)
 		}
+
+		// Refuse or shed connections under memory pressure
+		b.memory = memory.NewMemoryManager(&memory.MemManagerConfig{
+			MaxMemoryBytes:  b.cfg.Config.Tunnel.MaxMemory,
+			GCThreshold:     0.7,
+			MonitorInterval: time.Second,
+			Logger:          b.logger,
+		})
+		b.server.SetMemoryManager(b.memory)
+		ctx, cancel := context.WithCancel(context.Background())
+		b.stopMem = cancel
+		b.memory.Start(ctx)
+		b.health.SetMemory(b.memory)
+		b.health.SetConnections(b.server, tunnel.MaxServerConnections)
 	case types.ModeClient:
 		b.client = tunnel.NewClient(b.cfg, nil, b.logger)
+		b.client.SetRateLimiter(b.limits)
+		if b.stateDir != nil && b.stateDir.Enabled(platform.PersistDNSBackup) {
+			b.client.SetStateDir(b.stateDir.Path)
+		}
+		b.SetCircuitBreakers(b.client.Endpoints().Breakers())
 		if err := b.client.Start(); err != nil {
 			b.status.State = "stopped"
 			return fmt.Errorf("failed to start client: %w", err)
@@ -81,6 +199,11 @@ func (b *BaseService) Start() error {
 		return fmt.Errorf("invalid mode: %s", b.cfg.Config.Mode)
 	}
 
+	if err := b.writePIDFile(); err != nil {
+		b.logger.Warn("Running without a PID file", zap.Error(err))
+	}
+	b.startReporting()
+
 	b.status.State = "running"
 	return nil
 }
@@ -92,10 +215,28 @@ func (b *BaseService) Stop() error {
 	}
 
 	b.status.State = "stopping"
+	b.removePIDFile()
+	b.stopReporting()
 
 	// Stop tunnel based on mode
 	switch b.cfg.Config.Mode {
 	case types.ModeServer:
+		if b.stopMem != nil {
+			b.stopMem()
+			b.stopMem = nil
+		}
+		if b.memory != nil {
+			// Restores the runtime memory limit in place before Start
+			b.memory.Stop()
+		}
+		if b.stapler != nil {
+			b.stapler.Stop()
+			b.stapler = nil
+		}
+		if b.crl != nil {
+			b.crl.Stop()
+			b.crl = nil
+		}
 		if b.server != nil {
 			if err := b.server.Stop(); err != nil {
 				b.status.State = "stopped"
@@ -138,11 +279,267 @@ func (b *BaseService) Reload() error {
 	return nil
 }
 
+// ReloadConfig applies a re-read configuration to the running tunnel without
+// restarting it. A configuration the tunnel rejects leaves the running one in
+// place.
+func (b *BaseService) ReloadConfig(cfg *types.AppConfig) error {
+	if b.status.State != "running" {
+		return NewServiceError(ErrNotRunning, "Service is not running")
+	}
+
+	var err error
+	switch b.cfg.Config.Mode {
+	case types.ModeServer:
+		err = b.server.Reload(cfg)
+	case types.ModeClient:
+		err = b.client.Reload(cfg)
+	default:
+		err = fmt.Errorf("unknown mode: %s", b.cfg.Config.Mode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reload configuration: %w", err)
+	}
+
+	b.cfg = cfg
+	b.limits.Update(cfg.Throttle)
+	b.status.LastReload = time.Now()
+	return nil
+}
+
+// SetCertificateRotator sets the rotator that serves the tunnel's TLS
+// certificate. It must be called before Start.
+func (b *BaseService) SetCertificateRotator(rotator *cert.CertificateRotator) {
+	b.rotator = rotator
+}
+
+// useServerTLS gives the server its TLS manager for the certificate in auth,
+// served from the certificate rotator when one is set so that new handshakes
+// pick up a rotated certificate at once. Without a certificate the server runs
+// over plain TCP.
+func (b *BaseService) useServerTLS() error {
+	tlsConfig := tunnel.TLSConfigFrom(b.cfg.Config, b.logger)
+	if tlsConfig == nil {
+		return nil
+	}
+	if b.rotator != nil {
+		tlsConfig.GetCertificate = b.rotator.GetCertificate
+	}
+	if err := b.useOCSPStapler(tlsConfig); err != nil {
+		return err
+	}
+	if err := b.useCRLChecker(tlsConfig); err != nil {
+		return err
+	}
+
+	manager, err := tunnel.NewTLSManager(tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	return b.server.SetTLSManager(manager)
+}
+
+// CertificateRotated rotates the server's session ticket key along with its
+// certificate, so that a session cannot be resumed across two certificates,
+// and staples the new certificate. It is the rotator's
+// cert.RotationConfig.OnRotation.
+func (b *BaseService) CertificateRotated(old, new *x509.Certificate) {
+	if b.server == nil {
+		return
+	}
+	if err := b.server.RotateSessionTicketKey(); err != nil {
+		b.logger.Error("Failed to rotate the session ticket key", zap.Error(err))
+	}
+	if stapler := b.stapler; stapler != nil {
+		b.updateStaple(stapler, new)
+	}
+}
+
+// RotateCerts rotates the TLS certificate. New handshakes use the rotated certificate
+// immediately; established connections keep the certificate they negotiated.
+func (b *BaseService) RotateCerts() error {
+	if b.status.State != "running" {
+		return NewServiceError(ErrNotRunning, "Service is not running")
+	}
+	if b.rotator == nil {
+		return fmt.Errorf("certificate rotation not configured")
+	}
+
+	if err := b.rotator.Rotate(); err != nil {
+		return fmt.Errorf("failed to rotate certificates: %w", err)
+	}
+
+	b.logger.Info("Certificates rotated",
+		zap.String("serial", b.rotator.GetCurrent().SerialNumber))
+	return nil
+}
+
+// MemoryPressure returns the memory pressure of the server, none in client
+// mode, for memory_pressure alert rules
+func (b *BaseService) MemoryPressure() memory.MemPressure {
+	if b.memory == nil {
+		return memory.MemPressureNone
+	}
+	return b.memory.GetPressureLevel()
+}
+
+// SetCircuitBreakers adds the state of the given circuit breakers to health
+// checks and to the monitor's metrics
+func (b *BaseService) SetCircuitBreakers(states *resilience.CircuitBreakerStates) {
+	b.health.SetBreakers(states)
+	if b.monitor != nil {
+		b.monitor.SetCircuitBreakers(states)
+	}
+}
+
+// SetRBACModel sets the RBAC manager and the model file it is reloaded from
+func (b *BaseService) SetRBACModel(manager *access.RBACManager, path string) {
+	b.rbac = manager
+	b.rbacFile = path
+}
+
+// ReloadRBAC re-reads the RBAC model file and swaps it in. An invalid model is
+// rejected and the current model stays active.
+func (b *BaseService) ReloadRBAC() error {
+	if b.rbac == nil || b.rbacFile == "" {
+		return fmt.Errorf("RBAC model file not configured")
+	}
+
+	if err := b.rbac.ReloadModel(b.rbacFile); err != nil {
+		b.logger.Error("Rejected RBAC model",
+			zap.String("path", b.rbacFile),
+			zap.Error(err))
+		return fmt.Errorf("failed to reload RBAC model: %w", err)
+	}
+
+	b.logger.Info("RBAC model reloaded", zap.String("path", b.rbacFile))
+	return nil
+}
+
+// ReloadACL re-applies the configured ACL rules and re-reads the ACL file. New
+// connections are checked against the new rules immediately; established
+// connections are re-checked when reevaluate_on_reload is set. Invalid rules are
+// rejected and the current rules stay active.
+func (b *BaseService) ReloadACL() error {
+	if b.ipFilter == nil || b.server == nil {
+		return fmt.Errorf("ACL not configured")
+	}
+
+	aclConfig := b.cfg.Config.Security.ACL
+	if err := b.ipFilter.ApplyConfig(aclConfig); err != nil {
+		b.logger.Error("Rejected ACL",
+			zap.String("path", aclConfig.File),
+			zap.Error(err))
+		return fmt.Errorf("failed to reload ACL: %w", err)
+	}
+
+	closed := 0
+	if aclConfig.ReevaluateOnReload {
+		closed = b.server.ReevaluateConnections()
+	}
+
+	b.logger.Info("ACL reloaded",
+		zap.String("path", aclConfig.File),
+		zap.Int("closed_connections", closed))
+	return nil
+}
+
+// ListConnections returns a snapshot of the active client connections. Only a
+// server has client connections.
+func (b *BaseService) ListConnections() ([]tunnel.ConnectionInfo, error) {
+	if b.status.State != "running" {
+		return nil, NewServiceError(ErrNotRunning, "Service is not running")
+	}
+	if b.server == nil {
+		return nil, fmt.Errorf("connections are only tracked in server mode")
+	}
+	return b.server.ListConnections()
+}
+
+// Disconnect closes the client connection with the given ID or remote address
+func (b *BaseService) Disconnect(id string) error {
+	if b.status.State != "running" {
+		return NewServiceError(ErrNotRunning, "Service is not running")
+	}
+	if b.server == nil {
+		return fmt.Errorf("connections are only tracked in server mode")
+	}
+	return b.server.Disconnect(id)
+}
+
+// PoolStats returns the statistics of the pool of connections to the server.
+// Only a client dials the server through a pool.
+func (b *BaseService) PoolStats() (*pool.Stats, error) {
+	if b.status.State != "running" {
+		return nil, NewServiceError(ErrNotRunning, "Service is not running")
+	}
+	if b.client == nil {
+		return nil, fmt.Errorf("connection pool is only used in client mode")
+	}
+	stats := b.client.PoolStats()
+	return &stats, nil
+}
+
+// endpoints returns the endpoint manager of a running client
+func (b *BaseService) endpoints() (*tunnel.EndpointManager, error) {
+	if b.status.State != "running" {
+		return nil, NewServiceError(ErrNotRunning, "Service is not running")
+	}
+	if b.client == nil {
+		return nil, fmt.Errorf("server endpoints are only used in client mode")
+	}
+	return b.client.Endpoints(), nil
+}
+
+// ListEndpoints returns the server endpoints the client spreads connections over
+func (b *BaseService) ListEndpoints() ([]tunnel.EndpointStatus, error) {
+	endpoints, err := b.endpoints()
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.List(), nil
+}
+
+// AddEndpoint adds a server endpoint for new client connections
+func (b *BaseService) AddEndpoint(address string, weight int) error {
+	endpoints, err := b.endpoints()
+	if err != nil {
+		return err
+	}
+	return endpoints.Add(address, weight)
+}
+
+// RemoveEndpoint stops new client connections to a server endpoint
+func (b *BaseService) RemoveEndpoint(address string) error {
+	endpoints, err := b.endpoints()
+	if err != nil {
+		return err
+	}
+	return endpoints.Remove(address)
+}
+
+// RateLimiter returns the registry that applies and tracks the configured rate limits
+func (b *BaseService) RateLimiter() *throttle.Registry {
+	return b.limits
+}
+
+// RateLimitStatus returns the rate limit state of the global and per-client
+// scopes and of the per-source-IP connection limit
+func (b *BaseService) RateLimitStatus() (*throttle.RateLimitStatus, error) {
+	status := b.limits.Status()
+	return &status, nil
+}
+
 // Status returns the current service status
 func (b *BaseService) Status() (*ServiceStatus, error) {
 	if b.status.State == "running" {
 		b.updateMetrics()
 	}
+	if b.server != nil {
+		b.status.MemoryPressure = b.server.MemoryPressure().String()
+		b.status.Drain = b.server.DrainStatus()
+	}
+	b.status.LogLevel = b.LogLevel()
+	b.status.Version = b.version
 	return &b.status, nil
 }
 
@@ -179,6 +576,15 @@ func (b *BaseService) Health() error {
 	return nil
 }
 
+// HealthReport rolls circuit breaker, memory and connection state into one health
+// status with per-subsystem detail
+func (b *BaseService) HealthReport() (*HealthReport, error) {
+	if err := b.Health(); err != nil {
+		return nil, err
+	}
+	return b.health.Check(), nil
+}
+
 // ExecuteCommand executes a service command
 func (b *BaseService) ExecuteCommand(cmd ServiceCommand, args map[string]interface{}) (*ServiceResponse, error) {
 	switch cmd {
@@ -203,10 +609,121 @@ func (b *BaseService) ExecuteCommand(cmd ServiceCommand, args map[string]interfa
 		return &ServiceResponse{Success: true, Data: metrics}, nil
 
 	case CmdHealth:
-		if err := b.Health(); err != nil {
+		report, err := b.HealthReport()
+		if err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{
+			Success: report.Status != HealthUnhealthy,
+			Message: fmt.Sprintf("Service is %s", report.Status),
+			Data:    report,
+		}, nil
+
+	case CmdRotateCerts:
+		if err := b.RotateCerts(); err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Message: "Certificates rotated"}, nil
+
+	case CmdReloadRBAC:
+		if err := b.ReloadRBAC(); err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Message: "RBAC model reloaded"}, nil
+
+	case CmdReloadACL:
+		if err := b.ReloadACL(); err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Message: "ACL reloaded"}, nil
+
+	case CmdConnections:
+		conns, err := b.ListConnections()
+		if err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Data: conns}, nil
+
+	case CmdDisconnect:
+		id, _ := args["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("connection id is required")
+		}
+		if err := b.Disconnect(id); err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Message: fmt.Sprintf("Connection %s disconnected", id)}, nil
+
+	case CmdEndpoints:
+		address, _ := args["address"].(string)
+		switch action, _ := args["action"].(string); action {
+		case "", "list":
+			endpoints, err := b.ListEndpoints()
+			if err != nil {
+				return nil, err
+			}
+			return &ServiceResponse{Success: true, Data: endpoints}, nil
+		case "add":
+			weight := 1
+			if w, ok := args["weight"].(float64); ok {
+				weight = int(w)
+			}
+			if err := b.AddEndpoint(address, weight); err != nil {
+				return nil, err
+			}
+			return &ServiceResponse{Success: true, Message: fmt.Sprintf("Endpoint %s added", address)}, nil
+		case "remove":
+			if err := b.RemoveEndpoint(address); err != nil {
+				return nil, err
+			}
+			return &ServiceResponse{Success: true, Message: fmt.Sprintf("Endpoint %s removed", address)}, nil
+		default:
+			return nil, fmt.Errorf("unknown endpoints action: %s", action)
+		}
+
+	case CmdPool:
+		stats, err := b.PoolStats()
+		if err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Data: stats}, nil
+
+	case CmdLogLevel:
+		if level, _ := args["level"].(string); level != "" {
+			if err := b.SetLogLevel(level); err != nil {
+				return nil, err
+			}
+		}
+		return &ServiceResponse{
+			Success: true,
+			Message: fmt.Sprintf("Log level is %s", b.LogLevel()),
+			Data:    b.LogLevel(),
+		}, nil
+
+	case CmdRateLimits:
+		status, err := b.RateLimitStatus()
+		if err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Data: status}, nil
+
+	case CmdVersion:
+		info, err := b.GetVersion()
+		if err != nil {
+			return nil, err
+		}
+		return &ServiceResponse{Success: true, Data: &info}, nil
+
+	case CmdHistory:
+		window, err := HistoryWindow(args)
+		if err != nil {
+			return nil, err
+		}
+		history, err := b.MetricsHistory(window)
+		if err != nil {
 			return nil, err
 		}
-		return &ServiceResponse{Success: true, Message: "Service is healthy"}, nil
+		return &ServiceResponse{Success: true, Data: history}, nil
 
 	default:
 		return nil, NewServiceError(ErrInvalidCommand, fmt.Sprintf("Unknown command: %s", cmd))
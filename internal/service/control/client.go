@@ -3,23 +3,71 @@ package control
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/pool"
 	"github.com/o3willard-AI/SSSonector/internal/service"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
 	"go.uber.org/zap"
 )
 
+// Control transports
+const (
+	NetworkUnix = "unix"
+	NetworkTCP  = "tcp"
+	NetworkPipe = "npipe" // Windows named pipe
+)
+
+// DefaultPipeName is the Windows named pipe of the control server
+const DefaultPipeName = `\\.\pipe\sssonector`
+
+// ReadTokenFile reads a control token from path, ignoring surrounding whitespace
+func ReadTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read control token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("control token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// ApplyPipeDefault sets *socketPath to DefaultPipeName for the npipe transport
+// unless the socket flag was given on flags
+func ApplyPipeDefault(flags *flag.FlagSet, network string, socketPath *string) {
+	if network != NetworkPipe {
+		return
+	}
+	socketSet := false
+	flags.Visit(func(f *flag.Flag) {
+		socketSet = socketSet || f.Name == "socket"
+	})
+	if !socketSet {
+		*socketPath = DefaultPipeName
+	}
+}
+
 // Client represents a control client
 type Client struct {
 	cfg        *config.AppConfig
 	logger     *zap.Logger
 	conn       net.Conn
+	network    string
 	socketPath string
+	compress   bool
+	token      string
 }
 
 // NewClient creates a new control client
@@ -27,26 +75,55 @@ func NewClient(cfg *config.AppConfig, logger *zap.Logger) (*Client, error) {
 	return &Client{
 		cfg:        cfg,
 		logger:     logger,
-		socketPath: filepath.Join(os.TempDir(), "sssonector.sock"),
+		network:    DefaultNetwork,
+		socketPath: defaultAddress(),
 	}, nil
 }
 
-// SetSocketPath sets the control socket path
+// SetNetwork sets the control transport: NetworkUnix, NetworkTCP or NetworkPipe
+func (c *Client) SetNetwork(network string) {
+	c.network = network
+}
+
+// SetSocketPath sets the control socket path, TCP address or pipe name
 func (c *Client) SetSocketPath(path string) {
 	c.socketPath = path
 }
 
+// SetToken sets the token sent with each command, as required by a control
+// server listening on TCP
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetCompression asks the service to gzip large responses. It saves bandwidth for
+// remote administration and is off by default, as it only costs CPU on a local
+// socket.
+func (c *Client) SetCompression(enabled bool) {
+	c.compress = enabled
+}
+
 // Connect establishes a connection to the control socket
 func (c *Client) Connect() error {
-	var err error
-
 	// Connect with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	return c.ConnectContext(ctx)
+}
+
+// ConnectContext establishes a connection to the control socket, giving up when
+// ctx ends
+func (c *Client) ConnectContext(ctx context.Context) error {
+	var err error
+
 	// Create connection
-	var d net.Dialer
-	c.conn, err = d.DialContext(ctx, "unix", c.socketPath)
+	if c.network == NetworkPipe {
+		c.conn, err = dialPipe(ctx, c.socketPath)
+	} else {
+		var d net.Dialer
+		c.conn, err = d.DialContext(ctx, c.network, c.socketPath)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to control socket: %w", err)
 	}
@@ -64,18 +141,36 @@ func (c *Client) Close() error {
 
 // ExecuteCommand executes a service command
 func (c *Client) ExecuteCommand(cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+	return c.ExecuteCommandContext(context.Background(), cmd, args)
+}
+
+// ExecuteCommandContext executes a service command bounded by ctx. The deadline is
+// sent with the request so the service abandons the command at the same time; if
+// ctx ends first the returned error wraps ctx.Err(). A command the service
+// rejects returns its *Error.
+func (c *Client) ExecuteCommandContext(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
 	if c.conn == nil {
 		return nil, fmt.Errorf("not connected")
 	}
 
 	// Create command request
-	request := struct {
-		Command service.ServiceCommand `json:"command"`
-		Args    map[string]interface{} `json:"args,omitempty"`
-	}{
-		Command: cmd,
-		Args:    args,
+	request := commandRequest{
+		Command:  cmd,
+		Args:     args,
+		Compress: c.compress,
+		Token:    c.token,
 	}
+	if deadline, ok := ctx.Deadline(); ok {
+		request.Deadline = deadline
+		c.conn.SetDeadline(deadline)
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	// Unblock pending I/O as soon as ctx is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
 
 	// Marshal request
 	data, err := json.Marshal(request)
@@ -85,21 +180,34 @@ func (c *Client) ExecuteCommand(cmd service.ServiceCommand, args map[string]inte
 
 	// Send request
 	if _, err := c.conn.Write(append(data, '\n')); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", contextError(ctx, err))
 	}
 
-	// Read response
-	buf := make([]byte, 4096)
-	n, err := c.conn.Read(buf)
+	// Read response; the service closes the connection after sending it
+	raw, err := readResponse(c.conn)
+	if err == nil && len(raw) == 0 {
+		err = io.EOF
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", contextError(ctx, err))
+	}
+	payload, err := decodeResponse(raw)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse response
-	var response service.ServiceResponse
-	if err := json.Unmarshal(buf[:n], &response); err != nil {
+	var wire commandResponse
+	if err := json.Unmarshal(payload, &wire); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if wire.Error != nil {
+		return nil, wire.Error
+	}
+	if wire.ServiceResponse == nil {
+		return nil, NewError(CodeInternal, "empty response to %s", cmd)
+	}
+	response := wire.ServiceResponse
 
 	// Handle status and metrics responses
 	if response.Data != nil {
@@ -125,8 +233,229 @@ func (c *Client) ExecuteCommand(cmd service.ServiceCommand, args map[string]inte
 				return nil, fmt.Errorf("failed to unmarshal metrics data: %w", err)
 			}
 			response.Data = &metrics
+
+		case service.CmdHealth:
+			var report service.HealthReport
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal health data: %w", err)
+			}
+			if err := json.Unmarshal(data, &report); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal health data: %w", err)
+			}
+			response.Data = &report
+
+		case service.CmdConnections:
+			var conns []tunnel.ConnectionInfo
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal connection data: %w", err)
+			}
+			if err := json.Unmarshal(data, &conns); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal connection data: %w", err)
+			}
+			response.Data = conns
+
+		case service.CmdEndpoints:
+			var endpoints []tunnel.EndpointStatus
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal endpoint data: %w", err)
+			}
+			if err := json.Unmarshal(data, &endpoints); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal endpoint data: %w", err)
+			}
+			response.Data = endpoints
+
+		case service.CmdHistory:
+			var history []monitor.MetricsSnapshot
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal history data: %w", err)
+			}
+			if err := json.Unmarshal(data, &history); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal history data: %w", err)
+			}
+			response.Data = history
+
+		case service.CmdPool:
+			var stats pool.Stats
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal pool data: %w", err)
+			}
+			if err := json.Unmarshal(data, &stats); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal pool data: %w", err)
+			}
+			response.Data = &stats
+
+		case service.CmdRateLimits:
+			var status throttle.RateLimitStatus
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal rate limit data: %w", err)
+			}
+			if err := json.Unmarshal(data, &status); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal rate limit data: %w", err)
+			}
+			response.Data = &status
+
+		case service.CmdVersion:
+			var info service.VersionInfo
+			data, err := json.Marshal(response.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal version data: %w", err)
+			}
+			if err := json.Unmarshal(data, &info); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal version data: %w", err)
+			}
+			response.Data = &info
 		}
 	}
 
-	return &response, nil
+	return response, nil
+}
+
+// RotateCerts asks the service to rotate its TLS certificate. New handshakes use the
+// rotated certificate immediately; established connections are unaffected.
+func (c *Client) RotateCerts() (*service.ServiceResponse, error) {
+	return c.ExecuteCommand(service.CmdRotateCerts, nil)
+}
+
+// ReloadRBAC asks the service to re-read its RBAC model. An invalid model is rejected
+// and the current one stays active.
+func (c *Client) ReloadRBAC() (*service.ServiceResponse, error) {
+	return c.ExecuteCommand(service.CmdReloadRBAC, nil)
+}
+
+// ReloadACL asks the service to re-read its connection ACL. An invalid file is
+// rejected and the current rules stay active.
+func (c *Client) ReloadACL() (*service.ServiceResponse, error) {
+	return c.ExecuteCommand(service.CmdReloadACL, nil)
+}
+
+// ListConnections returns the service's active client connections
+func (c *Client) ListConnections() ([]tunnel.ConnectionInfo, error) {
+	resp, err := c.ExecuteCommand(service.CmdConnections, nil)
+	if err != nil {
+		return nil, err
+	}
+	conns, _ := resp.Data.([]tunnel.ConnectionInfo)
+	return conns, nil
+}
+
+// Endpoints returns the server endpoints the client spreads connections over
+func (c *Client) Endpoints() ([]tunnel.EndpointStatus, error) {
+	resp, err := c.ExecuteCommand(service.CmdEndpoints, map[string]interface{}{"action": "list"})
+	if err != nil {
+		return nil, err
+	}
+	endpoints, _ := resp.Data.([]tunnel.EndpointStatus)
+	return endpoints, nil
+}
+
+// AddEndpoint adds a server endpoint with the given weight
+func (c *Client) AddEndpoint(address string, weight int) error {
+	_, err := c.ExecuteCommand(service.CmdEndpoints, map[string]interface{}{
+		"action":  "add",
+		"address": address,
+		"weight":  weight,
+	})
+	return err
+}
+
+// RemoveEndpoint removes a server endpoint
+func (c *Client) RemoveEndpoint(address string) error {
+	_, err := c.ExecuteCommand(service.CmdEndpoints, map[string]interface{}{
+		"action":  "remove",
+		"address": address,
+	})
+	return err
+}
+
+// PoolStats returns the statistics of the client's connection pool
+func (c *Client) PoolStats() (*pool.Stats, error) {
+	resp, err := c.ExecuteCommand(service.CmdPool, nil)
+	if err != nil {
+		return nil, err
+	}
+	stats, _ := resp.Data.(*pool.Stats)
+	return stats, nil
+}
+
+// History returns the service's metrics snapshots taken within window, oldest
+// first; a window of zero returns all of them
+func (c *Client) History(window time.Duration) ([]monitor.MetricsSnapshot, error) {
+	var args map[string]interface{}
+	if window > 0 {
+		args = map[string]interface{}{"window": window.String()}
+	}
+	resp, err := c.ExecuteCommand(service.CmdHistory, args)
+	if err != nil {
+		return nil, err
+	}
+	history, _ := resp.Data.([]monitor.MetricsSnapshot)
+	return history, nil
+}
+
+// RateLimits returns the configured rate limits and current throttling per scope
+func (c *Client) RateLimits() (*throttle.RateLimitStatus, error) {
+	resp, err := c.ExecuteCommand(service.CmdRateLimits, nil)
+	if err != nil {
+		return nil, err
+	}
+	status, _ := resp.Data.(*throttle.RateLimitStatus)
+	return status, nil
+}
+
+// GetVersion returns the version, build time and commit of the running service
+func (c *Client) GetVersion() (service.VersionInfo, error) {
+	resp, err := c.ExecuteCommand(service.CmdVersion, nil)
+	if err != nil {
+		return service.VersionInfo{}, err
+	}
+	info, _ := resp.Data.(*service.VersionInfo)
+	if info == nil {
+		return service.VersionInfo{}, fmt.Errorf("no version in response")
+	}
+	return *info, nil
+}
+
+// SetLogLevel changes the service's log level to debug, info, warn or error
+func (c *Client) SetLogLevel(level string) error {
+	_, err := c.ExecuteCommand(service.CmdLogLevel, map[string]interface{}{"level": level})
+	return err
+}
+
+// LogLevel returns the service's current log level
+func (c *Client) LogLevel() (string, error) {
+	resp, err := c.ExecuteCommand(service.CmdLogLevel, nil)
+	if err != nil {
+		return "", err
+	}
+	level, _ := resp.Data.(string)
+	return level, nil
+}
+
+// Disconnect asks the service to close the client connection with the given ID
+// or remote address. An unknown connection returns an *Error with CodeNotFound.
+func (c *Client) Disconnect(id string) error {
+	_, err := c.ExecuteCommand(service.CmdDisconnect, map[string]interface{}{"id": id})
+	return err
+}
+
+// contextError reports an I/O error caused by ctx ending as the context error.
+// The service closes the connection at the deadline, so an error at or past the
+// deadline is a timeout even if the local timer has not fired yet.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
 }
@@ -0,0 +1,74 @@
+package control
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// compressThreshold is the smallest response compressed on request; smaller
+// responses are sent as plain JSON
+const compressThreshold = 1024
+
+// maxResponseSize bounds a response read by the client, before and after
+// decompression, so that a misbehaving peer cannot exhaust its memory
+const maxResponseSize = 16 << 20 // 16MB
+
+// gzipMagic starts every gzip stream and never starts a JSON document
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// readResponse reads r to EOF, failing once more than maxResponseSize bytes
+// have been read
+func readResponse(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxResponseSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+	}
+	return data, nil
+}
+
+// encodeResponse marshals a response, gzip-compressing it when compress is set
+// and the response is large enough to benefit
+func encodeResponse(resp commandResponse, compress bool) ([]byte, error) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	if !compress || len(data) < compressThreshold {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress response: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress response: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeResponse returns the JSON of a response, decompressing it if needed
+func decodeResponse(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response: %w", err)
+	}
+	defer zr.Close()
+
+	plain, err := readResponse(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response: %w", err)
+	}
+	return plain, nil
+}
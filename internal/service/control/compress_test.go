@@ -0,0 +1,103 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/service"
+	"go.uber.org/zap"
+)
+
+func TestCompressedLargeResponse(t *testing.T) {
+	export := strings.Repeat("connection 10.0.0.2:4000 bytes_sent=1024 bytes_received=2048\n", 4000)
+
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		return &service.ServiceResponse{Success: true, Message: export}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetSocketPath(server.socketPath)
+	client.SetCompression(true)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	resp, err := client.ExecuteCommand(service.CmdStatus, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+	if resp.Message != export {
+		t.Errorf("Expected decompressed message of %d bytes to match, got %d bytes", len(export), len(resp.Message))
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	large := commandResponse{ServiceResponse: &service.ServiceResponse{
+		Success: true,
+		Message: strings.Repeat("a", 10*compressThreshold),
+	}}
+
+	plain, err := encodeResponse(large, false)
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+	compressed, err := encodeResponse(large, true)
+	if err != nil {
+		t.Fatalf("Failed to encode compressed response: %v", err)
+	}
+	if !bytes.HasPrefix(compressed, gzipMagic) || len(compressed) >= len(plain) {
+		t.Errorf("Expected a smaller gzip response, got %d bytes from %d", len(compressed), len(plain))
+	}
+
+	decoded, err := decodeResponse(compressed)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !bytes.Equal(decoded, plain) {
+		t.Error("Decoded response does not match the original")
+	}
+
+	// Small responses stay plain even when compression is requested
+	small := commandResponse{ServiceResponse: &service.ServiceResponse{Success: true, Message: "ok"}}
+	data, err := encodeResponse(small, true)
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+	if bytes.HasPrefix(data, gzipMagic) {
+		t.Error("Expected a small response to be sent uncompressed")
+	}
+}
+
+func TestDecodeResponseRejectsOversizedResponse(t *testing.T) {
+	// A small compressed response that inflates past the limit
+	huge := commandResponse{ServiceResponse: &service.ServiceResponse{
+		Success: true,
+		Message: strings.Repeat("a", maxResponseSize),
+	}}
+	compressed, err := encodeResponse(huge, true)
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+	if _, err := decodeResponse(compressed); err == nil {
+		t.Error("Expected a response inflating past the limit to be rejected")
+	}
+
+	if _, err := readResponse(bytes.NewReader(make([]byte, maxResponseSize+1))); err == nil {
+		t.Error("Expected a response past the limit to be rejected")
+	}
+	if data, err := readResponse(bytes.NewReader(make([]byte, maxResponseSize))); err != nil || len(data) != maxResponseSize {
+		t.Errorf("Expected a response at the limit to be read, got %d bytes (%v)", len(data), err)
+	}
+}
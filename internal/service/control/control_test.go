@@ -0,0 +1,451 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/service"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+	"go.uber.org/zap"
+)
+
+func TestExecuteCommandContextTimeout(t *testing.T) {
+	// The handler is deliberately slower than any client deadline
+	release := make(chan struct{})
+	defer close(release)
+
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		<-release
+		return &service.ServiceResponse{Success: true}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetSocketPath(server.socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := client.ExecuteCommandContext(ctx, service.CmdStatus, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got response %v and error %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected command to give up at its deadline, took %v", elapsed)
+	}
+}
+
+func TestExecuteCommandContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		<-release
+		return &service.ServiceResponse{Success: true}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetSocketPath(server.socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	// Cancellation without a deadline still unblocks the pending read
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err := client.ExecuteCommandContext(ctx, service.CmdStatus, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled, got %v", err)
+	}
+}
+
+func TestPermissionDeniedErrorCode(t *testing.T) {
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		return nil, NewError(CodePermissionDenied, "not allowed to %s", cmd)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetSocketPath(server.socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.ExecuteCommand(service.CmdStatus, nil)
+	var ctlErr *Error
+	if !errors.As(err, &ctlErr) {
+		t.Fatalf("Expected a control error, got %v", err)
+	}
+	if ctlErr.Code != CodePermissionDenied {
+		t.Errorf("Expected code %s, got %s", CodePermissionDenied, ctlErr.Code)
+	}
+	if ctlErr.Message != "not allowed to status" {
+		t.Errorf("Expected message 'not allowed to status', got %q", ctlErr.Message)
+	}
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCode
+	}{
+		{NewError(CodeNotFound, "missing"), CodeNotFound},
+		{fmt.Errorf("failed to read response: %w", context.DeadlineExceeded), CodeTimeout},
+		{fmt.Errorf("failed to read ACL file: %w", os.ErrPermission), CodePermissionDenied},
+		{errors.New("boom"), CodeInternal},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorCodeOf(tt.err); got != tt.want {
+			t.Errorf("ErrorCodeOf(%v): expected %s, got %s", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestListConnections(t *testing.T) {
+	established := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []tunnel.ConnectionInfo{
+		{RemoteAddr: "10.0.0.2:5000", Established: established, BytesIn: 10, BytesOut: 20, LastActivity: established.Add(time.Minute)},
+		{RemoteAddr: "192.0.2.7:5000", Established: established.Add(time.Second), BytesIn: 30, BytesOut: 40, LastActivity: established.Add(time.Hour), TunnelIP: "10.8.0.2"},
+	}
+
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		if cmd != service.CmdConnections {
+			return nil, NewError(CodeNotFound, "Unknown command: %s", cmd)
+		}
+		return &service.ServiceResponse{Success: true, Data: want}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetSocketPath(server.socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	conns, err := client.ListConnections()
+	if err != nil {
+		t.Fatalf("Failed to list connections: %v", err)
+	}
+	if len(conns) != len(want) {
+		t.Fatalf("Expected %d connections, got %d", len(want), len(conns))
+	}
+	for i := range want {
+		if conns[i] != want[i] {
+			t.Errorf("Expected connection %+v, got %+v", want[i], conns[i])
+		}
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	active := map[string]bool{"7": true}
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		id, _ := args["id"].(string)
+		if cmd != service.CmdDisconnect || !active[id] {
+			return nil, NewError(CodeNotFound, "connection not found: %s", id)
+		}
+		delete(active, id)
+		return &service.ServiceResponse{Success: true}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	disconnect := func(id string) error {
+		client, err := NewClient(nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		client.SetSocketPath(server.socketPath)
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer client.Close()
+		return client.Disconnect(id)
+	}
+
+	if err := disconnect("7"); err != nil {
+		t.Fatalf("Failed to disconnect: %v", err)
+	}
+	if code := ErrorCodeOf(disconnect("7")); code != CodeNotFound {
+		t.Errorf("Expected a repeated disconnect to be %s, got %s", CodeNotFound, code)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	registry := throttle.NewRegistry(types.ThrottleConfig{Enabled: true, Rate: 1000, Burst: 100, PerClient: true})
+	registry.Wait("10.0.0.2:5000", true, 100)
+	registry.Wait("10.0.0.2:5000", true, 50)
+
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		status := registry.Status()
+		return &service.ServiceResponse{Success: true, Data: &status}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetSocketPath(server.socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	status, err := client.RateLimits()
+	if err != nil {
+		t.Fatalf("Failed to get rate limits: %v", err)
+	}
+	if status == nil || len(status.Scopes) != 2 {
+		t.Fatalf("Expected global and client scopes, got %+v", status)
+	}
+	client0 := status.Scopes[1]
+	if client0.Scope != "10.0.0.2:5000" || client0.ThrottleCount == 0 || !client0.Throttled {
+		t.Errorf("Expected the client to be throttled, got %+v", client0)
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	injected := service.NewVersionInfo("sssonector", "1.2.3", "2026-10-01T12:00:00Z", "4f2a9c1")
+
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		if cmd != service.CmdVersion {
+			return nil, NewError(CodeNotFound, "Unknown command: %s", cmd)
+		}
+		return &service.ServiceResponse{Success: true, Data: &injected}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetSocketPath(server.socketPath)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	info, err := client.GetVersion()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+	if info.Version != "1.2.3" || info.CommitHash != "4f2a9c1" {
+		t.Errorf("Expected version 1.2.3 at commit 4f2a9c1, got %+v", info)
+	}
+	if want := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC); !info.BuildDate.Equal(want) {
+		t.Errorf("Expected build date %v, got %v", want, info.BuildDate)
+	}
+}
+
+// fakeEndpoints is an endpointManager over a plain list
+type fakeEndpoints struct {
+	endpoints []tunnel.EndpointStatus
+}
+
+func (f *fakeEndpoints) ListEndpoints() ([]tunnel.EndpointStatus, error) {
+	return f.endpoints, nil
+}
+
+func (f *fakeEndpoints) AddEndpoint(address string, weight int) error {
+	f.endpoints = append(f.endpoints, tunnel.EndpointStatus{Address: address, Weight: weight, Healthy: true, State: "closed"})
+	return nil
+}
+
+func (f *fakeEndpoints) RemoveEndpoint(address string) error {
+	for i, e := range f.endpoints {
+		if e.Address == address {
+			f.endpoints = append(f.endpoints[:i], f.endpoints[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", tunnel.ErrEndpointNotFound, address)
+}
+
+func TestEndpoints(t *testing.T) {
+	manager := &fakeEndpoints{}
+
+	server := &ControlServer{socketPath: filepath.Join(t.TempDir(), "control.sock")}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		return handleEndpoints(manager, args)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	// The server serves one command per connection
+	connect := func() *Client {
+		client, err := NewClient(nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		client.SetSocketPath(server.socketPath)
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		return client
+	}
+
+	client := connect()
+	err := client.AddEndpoint("10.0.0.1:8443", 3)
+	client.Close()
+	if err != nil {
+		t.Fatalf("Failed to add endpoint: %v", err)
+	}
+
+	client = connect()
+	endpoints, err := client.Endpoints()
+	client.Close()
+	if err != nil {
+		t.Fatalf("Failed to list endpoints: %v", err)
+	}
+	if len(endpoints) != 1 || endpoints[0].Address != "10.0.0.1:8443" || endpoints[0].Weight != 3 {
+		t.Errorf("Expected the added endpoint with weight 3, got %+v", endpoints)
+	}
+
+	for i, want := range []ErrorCode{"", CodeNotFound} {
+		client = connect()
+		err := client.RemoveEndpoint("10.0.0.1:8443")
+		client.Close()
+		if code := ErrorCodeOf(err); code != want {
+			t.Errorf("Expected removal %d to give %q, got %q", i+1, want, code)
+		}
+	}
+}
+
+func TestTCPControlRequiresToken(t *testing.T) {
+	handler := func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		return &service.ServiceResponse{Success: true, Message: "ok"}, nil
+	}
+
+	// Without a token, or on an address other machines can reach, it refuses to start
+	server := &ControlServer{network: NetworkTCP, socketPath: "127.0.0.1:0", handler: handler}
+	if err := server.Start(); err == nil {
+		server.Stop()
+		t.Fatal("Expected the tcp transport to require a token")
+	}
+	server = &ControlServer{network: NetworkTCP, socketPath: "0.0.0.0:0", handler: handler, token: "secret"}
+	if err := server.Start(); err == nil {
+		server.Stop()
+		t.Fatal("Expected the tcp transport to require a loopback address")
+	}
+
+	server = &ControlServer{network: NetworkTCP, socketPath: "127.0.0.1:0", handler: handler, token: "secret"}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	run := func(token string) (*service.ServiceResponse, error) {
+		client, err := NewClient(nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		client.SetNetwork(NetworkTCP)
+		client.SetSocketPath(server.socket.Addr().String())
+		client.SetToken(token)
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer client.Close()
+		return client.ExecuteCommand(service.CmdStatus, nil)
+	}
+
+	if _, err := run("wrong"); ErrorCodeOf(err) != CodePermissionDenied {
+		t.Errorf("Expected a wrong token to be denied, got %v", err)
+	}
+	if resp, err := run("secret"); err != nil || !resp.Success {
+		t.Errorf("Expected the token to be accepted, got %v, %v", resp, err)
+	}
+}
+
+// stopCounter is a service that counts Stop calls
+type stopCounter struct {
+	service.Service
+	stops int
+}
+
+func (s *stopCounter) Stop() error {
+	s.stops++
+	return nil
+}
+
+func TestHandleCommandEndedContext(t *testing.T) {
+	svc := &stopCounter{}
+	server, err := NewControlServer(svc)
+	if err != nil {
+		t.Fatalf("Failed to create control server: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := server.handleCommand(ctx, service.CmdStop, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled command to fail with context.Canceled, got %v", err)
+	}
+	if svc.stops != 0 {
+		t.Errorf("Expected a canceled command not to stop the service, got %d stops", svc.stops)
+	}
+
+	if _, err := server.handleCommand(context.Background(), service.CmdStop, nil); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+	if svc.stops != 1 {
+		t.Errorf("Expected 1 stop, got %d", svc.stops)
+	}
+}
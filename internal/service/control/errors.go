@@ -0,0 +1,61 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrorCode classifies a failed control command so clients can branch on it
+type ErrorCode string
+
+const (
+	CodeNotFound         ErrorCode = "not_found"
+	CodePermissionDenied ErrorCode = "permission_denied"
+	CodeTimeout          ErrorCode = "timeout"
+	CodeInternal         ErrorCode = "internal"
+)
+
+// Error is a control command failure as sent over the wire
+type Error struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// NewError creates a control error
+func NewError(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// toError converts a handler error to a control error. Errors that are not
+// control errors are classified by cause, defaulting to internal.
+func toError(err error) *Error {
+	var ctlErr *Error
+	switch {
+	case errors.As(err, &ctlErr):
+		return ctlErr
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return &Error{Code: CodeTimeout, Message: err.Error()}
+	case errors.Is(err, os.ErrPermission):
+		return &Error{Code: CodePermissionDenied, Message: err.Error()}
+	case errors.Is(err, os.ErrNotExist):
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	default:
+		return &Error{Code: CodeInternal, Message: err.Error()}
+	}
+}
+
+// ErrorCodeOf returns the code of an error returned by the client. A command
+// that gave up at its context deadline is a timeout.
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	return toError(err).Code
+}
@@ -1,36 +1,153 @@
 package control
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/pool"
 	"github.com/o3willard-AI/SSSonector/internal/service"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
 )
 
+// certRotator is implemented by services that support certificate rotation without restart
+type certRotator interface {
+	RotateCerts() error
+}
+
+// rbacReloader is implemented by services that can reload their RBAC model at runtime
+type rbacReloader interface {
+	ReloadRBAC() error
+}
+
+// healthReporter is implemented by services that report composite subsystem health
+type healthReporter interface {
+	HealthReport() (*service.HealthReport, error)
+}
+
+// aclReloader is implemented by services that can reload their connection ACL at runtime
+type aclReloader interface {
+	ReloadACL() error
+}
+
+// connectionLister is implemented by services that report their active client connections
+type connectionLister interface {
+	ListConnections() ([]tunnel.ConnectionInfo, error)
+}
+
+// connectionDisconnector is implemented by services that can close a client connection on request
+type connectionDisconnector interface {
+	Disconnect(id string) error
+}
+
+// endpointManager is implemented by services that balance connections over
+// server endpoints
+type endpointManager interface {
+	ListEndpoints() ([]tunnel.EndpointStatus, error)
+	AddEndpoint(address string, weight int) error
+	RemoveEndpoint(address string) error
+}
+
+// logLeveler is implemented by services whose log level can be changed at runtime
+type logLeveler interface {
+	SetLogLevel(level string) error
+	LogLevel() string
+}
+
+// poolReporter is implemented by services that dial through a connection pool
+type poolReporter interface {
+	PoolStats() (*pool.Stats, error)
+}
+
+// rateLimitReporter is implemented by services that report rate limiter state
+type rateLimitReporter interface {
+	RateLimitStatus() (*throttle.RateLimitStatus, error)
+}
+
+// versionReporter is implemented by services that report the build they run
+type versionReporter interface {
+	GetVersion() (service.VersionInfo, error)
+}
+
+// historyReporter is implemented by services that keep a metrics history
+type historyReporter interface {
+	MetricsHistory(window time.Duration) ([]monitor.MetricsSnapshot, error)
+}
+
+// commandRequest is the wire format of a control command. Deadline carries the
+// client's context deadline so the server stops waiting when the client does.
+// Compress asks for a gzip-compressed response. Token authenticates the client
+// to a server that requires one.
+type commandRequest struct {
+	Command  service.ServiceCommand `json:"command"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+	Deadline time.Time              `json:"deadline,omitempty"`
+	Compress bool                   `json:"compress,omitempty"`
+	Token    string                 `json:"token,omitempty"`
+}
+
+// commandResponse is the wire format of a command result. Error is set when the
+// command failed.
+type commandResponse struct {
+	*service.ServiceResponse
+	Error *Error `json:"error,omitempty"`
+}
+
+// commandHandler runs a control command with its arguments
+type commandHandler func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error)
+
 // ControlServer represents a control server
 type ControlServer struct {
 	service    service.Service
+	handler    commandHandler
 	socket     net.Listener
+	network    string
 	socketPath string
+	token      string
 }
 
 // NewControlServer creates a new control server
 func NewControlServer(svc service.Service) (*ControlServer, error) {
-	return &ControlServer{
+	c := &ControlServer{
 		service: svc,
-	}, nil
+		network: DefaultNetwork,
+	}
+	c.handler = c.handleCommand
+	return c, nil
+}
+
+// SetNetwork sets the control transport: NetworkUnix, NetworkTCP or NetworkPipe
+func (c *ControlServer) SetNetwork(network string) {
+	c.network = network
 }
 
-// SetSocketPath sets the control socket path
+// SetSocketPath sets the control socket path, TCP address or pipe name
 func (c *ControlServer) SetSocketPath(path string) {
 	c.socketPath = path
 }
 
-// handleCommand handles a control command
-func (c *ControlServer) handleCommand(cmd service.ServiceCommand) (*service.ServiceResponse, error) {
+// SetToken sets the token every command must carry. The TCP transport, which
+// has no file or pipe permissions to rely on, refuses to start without one.
+func (c *ControlServer) SetToken(token string) {
+	c.token = token
+}
+
+// handleCommand handles a control command. A command is not started once ctx
+// has ended, so a client that gave up does not stop or reload the service
+// later; the service methods take no context, so a started command completes.
+func (c *ControlServer) handleCommand(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("command %s: %w", cmd, err)
+	}
+
 	switch cmd {
 	case service.CmdStatus:
 		status, err := c.service.Status()
@@ -53,6 +170,17 @@ func (c *ControlServer) handleCommand(cmd service.ServiceCommand) (*service.Serv
 		}, nil
 
 	case service.CmdHealth:
+		if reporter, ok := c.service.(healthReporter); ok {
+			report, err := reporter.HealthReport()
+			if err != nil {
+				return nil, err
+			}
+			return &service.ServiceResponse{
+				Success: report.Status != service.HealthUnhealthy,
+				Message: fmt.Sprintf("Service is %s", report.Status),
+				Data:    report,
+			}, nil
+		}
 		if err := c.service.Health(); err != nil {
 			return nil, err
 		}
@@ -88,11 +216,210 @@ func (c *ControlServer) handleCommand(cmd service.ServiceCommand) (*service.Serv
 			Message: "Configuration reloaded",
 		}, nil
 
+	case service.CmdRotateCerts:
+		rotator, ok := c.service.(certRotator)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not support certificate rotation")
+		}
+		if err := rotator.RotateCerts(); err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Message: "Certificates rotated",
+		}, nil
+
+	case service.CmdReloadRBAC:
+		reloader, ok := c.service.(rbacReloader)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not support RBAC reload")
+		}
+		if err := reloader.ReloadRBAC(); err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Message: "RBAC model reloaded",
+		}, nil
+
+	case service.CmdReloadACL:
+		reloader, ok := c.service.(aclReloader)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not support ACL reload")
+		}
+		if err := reloader.ReloadACL(); err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Message: "ACL reloaded",
+		}, nil
+
+	case service.CmdConnections:
+		lister, ok := c.service.(connectionLister)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not report connections")
+		}
+		conns, err := lister.ListConnections()
+		if err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Data:    conns,
+		}, nil
+
+	case service.CmdDisconnect:
+		disconnector, ok := c.service.(connectionDisconnector)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not support disconnecting clients")
+		}
+		id, _ := args["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("connection id is required")
+		}
+		if err := disconnector.Disconnect(id); err != nil {
+			if errors.Is(err, tunnel.ErrConnectionNotFound) {
+				return nil, NewError(CodeNotFound, "%v", err)
+			}
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Message: fmt.Sprintf("Connection %s disconnected", id),
+		}, nil
+
+	case service.CmdEndpoints:
+		manager, ok := c.service.(endpointManager)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not manage server endpoints")
+		}
+		return handleEndpoints(manager, args)
+
+	case service.CmdPool:
+		reporter, ok := c.service.(poolReporter)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not use a connection pool")
+		}
+		stats, err := reporter.PoolStats()
+		if err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Data:    stats,
+		}, nil
+
+	case service.CmdLogLevel:
+		leveler, ok := c.service.(logLeveler)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not support changing the log level")
+		}
+		if level, _ := args["level"].(string); level != "" {
+			if err := leveler.SetLogLevel(level); err != nil {
+				return nil, err
+			}
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Message: fmt.Sprintf("Log level is %s", leveler.LogLevel()),
+			Data:    leveler.LogLevel(),
+		}, nil
+
+	case service.CmdRateLimits:
+		reporter, ok := c.service.(rateLimitReporter)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not report rate limits")
+		}
+		status, err := reporter.RateLimitStatus()
+		if err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Data:    status,
+		}, nil
+
+	case service.CmdVersion:
+		reporter, ok := c.service.(versionReporter)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not report its version")
+		}
+		info, err := reporter.GetVersion()
+		if err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Data:    &info,
+		}, nil
+
+	case service.CmdHistory:
+		reporter, ok := c.service.(historyReporter)
+		if !ok {
+			return nil, NewError(CodeNotFound, "Service does not keep a metrics history")
+		}
+		window, err := service.HistoryWindow(args)
+		if err != nil {
+			return nil, err
+		}
+		history, err := reporter.MetricsHistory(window)
+		if err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Data:    history,
+		}, nil
+
 	default:
-		return nil, service.NewServiceError(service.ErrInvalidCommand, fmt.Sprintf("Unknown command: %s", cmd))
+		return nil, NewError(CodeNotFound, "Unknown command: %s", cmd)
 	}
 }
 
+// handleEndpoints lists, adds or removes server endpoints as given by the
+// "action" argument
+func handleEndpoints(manager endpointManager, args map[string]interface{}) (*service.ServiceResponse, error) {
+	address, _ := args["address"].(string)
+
+	var err error
+	var message string
+	switch action, _ := args["action"].(string); action {
+	case "", "list":
+		endpoints, err := manager.ListEndpoints()
+		if err != nil {
+			return nil, err
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Data:    endpoints,
+		}, nil
+	case "add":
+		weight := 1
+		if w, ok := args["weight"].(float64); ok {
+			weight = int(w)
+		}
+		err = manager.AddEndpoint(address, weight)
+		message = fmt.Sprintf("Endpoint %s added", address)
+	case "remove":
+		err = manager.RemoveEndpoint(address)
+		message = fmt.Sprintf("Endpoint %s removed", address)
+	default:
+		return nil, fmt.Errorf("unknown endpoints action: %s", action)
+	}
+
+	if errors.Is(err, tunnel.ErrEndpointNotFound) {
+		return nil, NewError(CodeNotFound, "%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &service.ServiceResponse{
+		Success: true,
+		Message: message,
+	}, nil
+}
+
 // handleConnection handles a client connection
 func (c *ControlServer) handleConnection(conn net.Conn) {
 	defer conn.Close()
@@ -106,25 +433,53 @@ func (c *ControlServer) handleConnection(conn net.Conn) {
 	}
 
 	// Parse command
-	var cmd service.ServiceCommand
-	if err := json.Unmarshal(buf[:n], &cmd); err != nil {
+	var req commandRequest
+	if err := json.Unmarshal(buf[:n], &req); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to parse command: %v\n", err)
 		return
 	}
 
+	// Refuse clients without the token
+	if c.token != "" && subtle.ConstantTimeCompare([]byte(req.Token), []byte(c.token)) != 1 {
+		err := NewError(CodePermissionDenied, "invalid control token")
+		data, _ := encodeResponse(commandResponse{
+			ServiceResponse: &service.ServiceResponse{Success: false, Message: err.Message},
+			Error:           err,
+		}, false)
+		conn.Write(data)
+		return
+	}
+
+	// Bound the command by the client's deadline
+	ctx := context.Background()
+	if !req.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, req.Deadline)
+		defer cancel()
+		conn.SetWriteDeadline(req.Deadline)
+	}
+
 	// Handle command
-	resp, err := c.handleCommand(cmd)
+	resp := commandResponse{}
+	result, err := c.runCommand(ctx, req.Command, req.Args)
 	if err != nil {
-		resp = &service.ServiceResponse{
+		if ctx.Err() != nil {
+			// The client has given up; closing the connection is the answer
+			fmt.Fprintf(os.Stderr, "Command %s aborted: %v\n", req.Command, err)
+			return
+		}
+		resp.Error = toError(err)
+		result = &service.ServiceResponse{
 			Success: false,
 			Message: err.Error(),
 		}
 	}
+	resp.ServiceResponse = result
 
 	// Send response
-	data, err := json.Marshal(resp)
+	data, err := encodeResponse(resp, req.Compress)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to marshal response: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to encode response: %v\n", err)
 		return
 	}
 
@@ -133,26 +488,36 @@ func (c *ControlServer) handleConnection(conn net.Conn) {
 	}
 }
 
-// Start starts the control server
-func (c *ControlServer) Start() error {
-	if c.socketPath == "" {
-		c.socketPath = filepath.Join(os.TempDir(), "sssonector.sock")
+// runCommand runs a command until it completes or ctx ends. A command that
+// outlives ctx is abandoned and finishes in the background.
+func (c *ControlServer) runCommand(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+	type result struct {
+		resp *service.ServiceResponse
+		err  error
 	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := c.handler(ctx, cmd, args)
+		done <- result{resp, err}
+	}()
 
-	// Create socket directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(c.socketPath), 0755); err != nil {
-		return fmt.Errorf("failed to create socket directory: %w", err)
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("command %s: %w", cmd, ctx.Err())
 	}
+}
 
-	// Remove existing socket if it exists
-	if err := os.Remove(c.socketPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove existing socket: %w", err)
+// Start starts the control server
+func (c *ControlServer) Start() error {
+	if c.socketPath == "" {
+		c.socketPath = defaultAddress()
 	}
 
-	// Create socket
-	listener, err := net.Listen("unix", c.socketPath)
+	listener, err := c.listen()
 	if err != nil {
-		return fmt.Errorf("failed to create control socket: %w", err)
+		return err
 	}
 	c.socket = listener
 
@@ -173,6 +538,58 @@ func (c *ControlServer) Start() error {
 	return nil
 }
 
+// listen creates the listener for the configured transport
+func (c *ControlServer) listen() (net.Listener, error) {
+	switch c.network {
+	case NetworkPipe:
+		return newPipeListener(c.socketPath)
+	case NetworkTCP:
+		if err := checkTCPAddress(c.socketPath); err != nil {
+			return nil, err
+		}
+		if c.token == "" {
+			return nil, fmt.Errorf("the tcp control transport requires a token")
+		}
+		listener, err := net.Listen("tcp", c.socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create control listener: %w", err)
+		}
+		return listener, nil
+	}
+
+	// Create socket directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(c.socketPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// Remove existing socket if it exists
+	if err := os.Remove(c.socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove existing socket: %w", err)
+	}
+
+	// Create socket
+	listener, err := net.Listen("unix", c.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control socket: %w", err)
+	}
+	return listener, nil
+}
+
+// checkTCPAddress rejects control addresses other machines could reach
+func checkTCPAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid control address %s: %w", address, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("control address %s is not a loopback address", address)
+	}
+	return nil
+}
+
 // Stop stops the control server
 func (c *ControlServer) Stop() error {
 	if c.socket != nil {
@@ -3,8 +3,14 @@
 package control
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -16,15 +22,36 @@ const (
 	pipeBufSize     = 65536 // 64KB buffer size
 	pipeTimeout     = 5000  // 5 seconds in milliseconds
 	maxInstances    = 255   // Maximum pipe instances
-	defaultPipeName = `\\.\pipe\sssonector`
+	defaultPipeName = DefaultPipeName
+
+	// pipeBusyRetry is the pause between attempts to open a busy pipe
+	pipeBusyRetry = 10 * time.Millisecond
+
+	// pipeSDDL grants full access to Administrators and SYSTEM only, without
+	// inheriting other entries
+	pipeSDDL = "D:P(A;;GA;;;BA)(A;;GA;;;SY)"
 )
 
+// DefaultNetwork is the control transport used unless SetNetwork is called
+const DefaultNetwork = NetworkPipe
+
+// defaultAddress returns the control pipe used unless SetSocketPath is called
+func defaultAddress() string {
+	return defaultPipeName
+}
+
 // pipeListener implements net.Listener for Windows named pipes
 type pipeListener struct {
 	path         string
 	securityAttr *windows.SecurityAttributes
-	handle       windows.Handle
-	closed       bool
+	closeEvent   windows.Handle // Signalled by Close to wake a pending Accept
+
+	// The idle pipe instance is owned by a running Accept, and otherwise
+	// closed by Close
+	mu        sync.Mutex
+	handle    windows.Handle // InvalidHandle while there is no idle instance
+	accepting bool
+	closed    bool
 }
 
 // newPipeListener creates a new Windows named pipe listener
@@ -39,9 +66,15 @@ func newPipeListener(path string) (net.Listener, error) {
 		return nil, fmt.Errorf("failed to create security attributes: %w", err)
 	}
 
+	closeEvent, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+
 	// Create the first pipe instance
 	handle, err := createNamedPipe(path, sa)
 	if err != nil {
+		windows.CloseHandle(closeEvent)
 		return nil, err
 	}
 
@@ -49,29 +82,73 @@ func newPipeListener(path string) (net.Listener, error) {
 		path:         path,
 		securityAttr: sa,
 		handle:       handle,
-		closed:       false,
+		closeEvent:   closeEvent,
 	}, nil
 }
 
 // Accept waits for and returns the next connection
 func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
 	if l.closed {
-		return nil, fmt.Errorf("listener closed")
+		l.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	l.accepting = true
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.accepting = false
+		if l.closed {
+			l.release()
+		}
+	}()
+
+	// A failed Accept may have left no idle instance
+	if l.handle == windows.InvalidHandle {
+		handle, err := createNamedPipe(l.path, l.securityAttr)
+		if err != nil {
+			return nil, err
+		}
+		l.handle = handle
 	}
 
-	// Wait for client connection
-	err := windows.ConnectNamedPipe(l.handle, nil)
+	// Wait for a client connection or Close
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+	defer windows.CloseHandle(event)
+
+	ov := windows.Overlapped{HEvent: event}
+	err = windows.ConnectNamedPipe(l.handle, &ov)
+	if err == windows.ERROR_IO_PENDING {
+		s, werr := windows.WaitForMultipleObjects([]windows.Handle{event, l.closeEvent}, false, windows.INFINITE)
+		if werr != nil || s != windows.WAIT_OBJECT_0 {
+			// Woken by Close, or the wait failed
+			windows.CancelIoEx(l.handle, &ov)
+		}
+		var n uint32
+		err = windows.GetOverlappedResult(l.handle, &ov, &n, true)
+	}
+	if l.isClosed() {
+		return nil, net.ErrClosed
+	}
 	if err != nil && err != windows.ERROR_PIPE_CONNECTED {
 		return nil, fmt.Errorf("connect named pipe failed: %w", err)
 	}
 
-	// Create pipe connection
-	conn := &pipeConn{
-		handle: l.handle,
+	// The connected instance now belongs to the connection
+	handle := l.handle
+	l.handle = windows.InvalidHandle
+	conn, err := newPipeConn(handle, l.path, true)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, err
 	}
 
 	// Create new pipe instance for next client
-	handle, err := createNamedPipe(l.path, l.securityAttr)
+	handle, err = createNamedPipe(l.path, l.securityAttr)
 	if err != nil {
 		conn.Close()
 		return nil, err
@@ -81,13 +158,41 @@ func (l *pipeListener) Accept() (net.Conn, error) {
 	return conn, nil
 }
 
-// Close closes the listener
+// isClosed reports whether Close was called
+func (l *pipeListener) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+// release closes the idle pipe instance and the close event once the
+// listener is closed and no Accept is running; the caller holds l.mu
+func (l *pipeListener) release() {
+	if l.handle != windows.InvalidHandle {
+		windows.CloseHandle(l.handle)
+		l.handle = windows.InvalidHandle
+	}
+	if l.closeEvent != 0 {
+		windows.CloseHandle(l.closeEvent)
+		l.closeEvent = 0
+	}
+}
+
+// Close closes the listener, waking a pending Accept, which then releases the
+// idle pipe instance; without one Close releases it
 func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	if l.closed {
 		return nil
 	}
 	l.closed = true
-	return windows.CloseHandle(l.handle)
+	if l.accepting {
+		return windows.SetEvent(l.closeEvent)
+	}
+	l.release()
+	return nil
 }
 
 // Addr returns the listener's address
@@ -95,126 +200,261 @@ func (l *pipeListener) Addr() net.Addr {
 	return pipeAddr(l.path)
 }
 
-// pipeConn implements net.Conn for Windows named pipes
+// pipeConn implements net.Conn for Windows named pipes. I/O is overlapped, so
+// that deadlines can end a blocked read or write.
 type pipeConn struct {
 	handle windows.Handle
-	closed bool
+	path   string
+	server bool
+	closed atomic.Bool
+
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+	readWake      windows.Handle // Signalled when the read deadline changes
+	writeWake     windows.Handle // Signalled when the write deadline changes
 }
 
-// Read implements io.Reader
-func (c *pipeConn) Read(b []byte) (int, error) {
-	if c.closed {
-		return 0, fmt.Errorf("connection closed")
+// newPipeConn wraps an overlapped pipe handle
+func newPipeConn(handle windows.Handle, path string, server bool) (*pipeConn, error) {
+	readWake, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+	writeWake, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		windows.CloseHandle(readWake)
+		return nil, fmt.Errorf("failed to create event: %w", err)
 	}
+	return &pipeConn{
+		handle:    handle,
+		path:      path,
+		server:    server,
+		readWake:  readWake,
+		writeWake: writeWake,
+	}, nil
+}
 
-	var n uint32
-	err := windows.ReadFile(c.handle, b, &n, nil)
+// dialPipe opens a client connection to a named pipe, retrying while every
+// instance is busy
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	for {
+		handle, err := openPipe(path)
+		if err == nil {
+			conn, err := newPipeConn(handle, path, false)
+			if err != nil {
+				windows.CloseHandle(handle)
+				return nil, err
+			}
+			return conn, nil
+		}
+		if err != windows.ERROR_PIPE_BUSY {
+			return nil, fmt.Errorf("failed to open named pipe: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pipeBusyRetry):
+		}
+	}
+}
+
+// openPipe opens the client end of a named pipe for overlapped I/O
+func openPipe(path string) (windows.Handle, error) {
+	path16, err := windows.UTF16PtrFromString(path)
 	if err != nil {
-		return 0, fmt.Errorf("read failed: %w", err)
+		return windows.InvalidHandle, fmt.Errorf("failed to convert path: %w", err)
+	}
+	return windows.CreateFile(
+		path16,
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		0,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OVERLAPPED,
+		0,
+	)
+}
+
+// Read implements io.Reader. The peer closing the pipe reads as io.EOF.
+func (c *pipeConn) Read(b []byte) (int, error) {
+	if c.closed.Load() {
+		return 0, net.ErrClosed
+	}
+
+	n, err := c.overlapped(func(ov *windows.Overlapped) error {
+		return windows.ReadFile(c.handle, b, nil, ov)
+	}, &c.readDeadline, c.readWake)
+	switch {
+	case err == nil, errors.Is(err, windows.ERROR_MORE_DATA):
+		// The rest of a message is returned by the next read
+		return n, nil
+	case errors.Is(err, windows.ERROR_BROKEN_PIPE), errors.Is(err, windows.ERROR_PIPE_NOT_CONNECTED):
+		return n, io.EOF
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return n, err
+	default:
+		return n, fmt.Errorf("read failed: %w", err)
 	}
-	return int(n), nil
 }
 
 // Write implements io.Writer
 func (c *pipeConn) Write(b []byte) (int, error) {
-	if c.closed {
-		return 0, fmt.Errorf("connection closed")
+	if c.closed.Load() {
+		return 0, net.ErrClosed
 	}
 
-	var n uint32
-	err := windows.WriteFile(c.handle, b, &n, nil)
+	n, err := c.overlapped(func(ov *windows.Overlapped) error {
+		return windows.WriteFile(c.handle, b, nil, ov)
+	}, &c.writeDeadline, c.writeWake)
+	switch {
+	case err == nil:
+		return n, nil
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return n, err
+	default:
+		return n, fmt.Errorf("write failed: %w", err)
+	}
+}
+
+// overlapped starts an I/O operation and waits for it to complete, cancelling
+// it once *deadline passes. wake is signalled when the deadline changes.
+func (c *pipeConn) overlapped(start func(ov *windows.Overlapped) error, deadline *time.Time, wake windows.Handle) (int, error) {
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
 	if err != nil {
-		return 0, fmt.Errorf("write failed: %w", err)
+		return 0, fmt.Errorf("failed to create event: %w", err)
+	}
+	defer windows.CloseHandle(event)
+
+	if c.deadlinePassed(deadline) {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	// Completed or pending operations signal event; a message larger than b
+	// completes with ERROR_MORE_DATA
+	ov := windows.Overlapped{HEvent: event}
+	if err := start(&ov); err != nil && err != windows.ERROR_IO_PENDING && err != windows.ERROR_MORE_DATA {
+		return 0, err
 	}
-	return int(n), nil
+
+	timedOut := false
+	for {
+		timeout := uint32(windows.INFINITE)
+		c.mu.Lock()
+		d := *deadline
+		c.mu.Unlock()
+		if !d.IsZero() {
+			remaining := time.Until(d)
+			if remaining <= 0 {
+				timedOut = true
+				windows.CancelIoEx(c.handle, &ov)
+				break
+			}
+			timeout = uint32((remaining + time.Millisecond - 1) / time.Millisecond)
+		}
+
+		s, err := windows.WaitForMultipleObjects([]windows.Handle{event, wake}, false, timeout)
+		if err != nil {
+			windows.CancelIoEx(c.handle, &ov)
+			break
+		}
+		if s == windows.WAIT_OBJECT_0 {
+			break
+		}
+		// The deadline changed or passed; check it again
+	}
+
+	var n uint32
+	err = windows.GetOverlappedResult(c.handle, &ov, &n, true)
+	if timedOut && errors.Is(err, windows.ERROR_OPERATION_ABORTED) {
+		return int(n), os.ErrDeadlineExceeded
+	}
+	return int(n), err
+}
+
+// deadlinePassed reports whether *deadline is set and has passed
+func (c *pipeConn) deadlinePassed(deadline *time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !deadline.IsZero() && !time.Now().Before(*deadline)
 }
 
-// Close closes the connection
+// Close closes the connection. The server end waits for the client to read
+// what was written first, as closing discards unread data.
 func (c *pipeConn) Close() error {
-	if c.closed {
+	if c.closed.Swap(true) {
 		return nil
 	}
-	c.closed = true
+	if c.server {
+		windows.FlushFileBuffers(c.handle)
+	}
+	windows.CancelIoEx(c.handle, nil)
+	windows.CloseHandle(c.readWake)
+	windows.CloseHandle(c.writeWake)
 	return windows.CloseHandle(c.handle)
 }
 
 // LocalAddr returns the local network address
 func (c *pipeConn) LocalAddr() net.Addr {
-	return pipeAddr("")
+	return pipeAddr(c.path)
 }
 
 // RemoteAddr returns the remote network address
 func (c *pipeConn) RemoteAddr() net.Addr {
-	return pipeAddr("")
+	return pipeAddr(c.path)
 }
 
 // SetDeadline implements net.Conn
 func (c *pipeConn) SetDeadline(t time.Time) error {
-	return fmt.Errorf("deadlines not supported")
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
 }
 
 // SetReadDeadline implements net.Conn
 func (c *pipeConn) SetReadDeadline(t time.Time) error {
-	return fmt.Errorf("read deadlines not supported")
+	return c.setDeadline(&c.readDeadline, c.readWake, t)
 }
 
 // SetWriteDeadline implements net.Conn
 func (c *pipeConn) SetWriteDeadline(t time.Time) error {
-	return fmt.Errorf("write deadlines not supported")
+	return c.setDeadline(&c.writeDeadline, c.writeWake, t)
+}
+
+// setDeadline changes a deadline and wakes an operation waiting on the old one
+func (c *pipeConn) setDeadline(deadline *time.Time, wake windows.Handle, t time.Time) error {
+	if c.closed.Load() {
+		return net.ErrClosed
+	}
+	c.mu.Lock()
+	*deadline = t
+	c.mu.Unlock()
+	return windows.SetEvent(wake)
 }
 
 // pipeAddr implements net.Addr for Windows named pipes
 type pipeAddr string
 
-func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) Network() string { return NetworkPipe }
 func (a pipeAddr) String() string  { return string(a) }
 
-// createPipeSecurityAttributes creates security attributes for the pipe
+// createPipeSecurityAttributes creates security attributes restricting the pipe
+// to administrators
 func createPipeSecurityAttributes() (*windows.SecurityAttributes, error) {
-	// Create a security descriptor with default DACL
-	sd, err := windows.NewSecurityDescriptor()
+	sd, err := windows.SecurityDescriptorFromString(pipeSDDL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create security descriptor: %w", err)
 	}
 
-	// Set default DACL allowing access to authenticated users
-	dacl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{
-		{
-			AccessPermissions: windows.GENERIC_READ | windows.GENERIC_WRITE,
-			AccessMode:        windows.GRANT_ACCESS,
-			Inheritance:       windows.NO_INHERITANCE,
-			Trustee: windows.TRUSTEE{
-				TrusteeForm:  windows.TRUSTEE_IS_SID,
-				TrusteeType:  windows.TRUSTEE_IS_GROUP,
-				TrusteeValue: getTrusteeSID(windows.WinAuthenticatedUserSid),
-			},
-		},
-	}, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create DACL: %w", err)
-	}
-
-	if err := sd.SetDACL(dacl, true, false); err != nil {
-		return nil, fmt.Errorf("failed to set DACL: %w", err)
-	}
-
 	return &windows.SecurityAttributes{
 		Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
 		SecurityDescriptor: sd,
-		InheritHandle:      1,
+		InheritHandle:      0,
 	}, nil
 }
 
-// getTrusteeSID creates a SID for the given well-known SID type
-func getTrusteeSID(sidType windows.WELL_KNOWN_SID_TYPE) windows.TrusteeValue {
-	sid, err := windows.CreateWellKnownSid(sidType)
-	if err != nil {
-		// Fall back to Everyone if we can't create the requested SID
-		sid, _ = windows.CreateWellKnownSid(windows.WinWorldSid)
-	}
-	return windows.TrusteeValue(unsafe.Pointer(sid))
-}
-
 // createNamedPipe creates a new named pipe instance
 func createNamedPipe(path string, sa *windows.SecurityAttributes) (windows.Handle, error) {
 	path16, err := windows.UTF16PtrFromString(path)
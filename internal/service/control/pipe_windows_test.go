@@ -0,0 +1,83 @@
+//go:build windows
+
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/service"
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+func TestStatusOverNamedPipe(t *testing.T) {
+	pipeName := fmt.Sprintf(`\\.\pipe\sssonector-test-%d`, os.Getpid())
+
+	server := &ControlServer{network: NetworkPipe, socketPath: pipeName}
+	server.handler = func(ctx context.Context, cmd service.ServiceCommand, args map[string]interface{}) (*service.ServiceResponse, error) {
+		if cmd != service.CmdStatus {
+			return nil, NewError(CodeNotFound, "Unknown command: %s", cmd)
+		}
+		return &service.ServiceResponse{
+			Success: true,
+			Data:    &service.ServiceStatus{State: "running"},
+		}, nil
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetNetwork(NetworkPipe)
+	client.SetSocketPath(pipeName)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	resp, err := client.ExecuteCommand(service.CmdStatus, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute status: %v", err)
+	}
+	status, ok := resp.Data.(*service.ServiceStatus)
+	if !ok {
+		t.Fatalf("Expected status data, got %T", resp.Data)
+	}
+	if status.State != "running" {
+		t.Errorf("Expected state running, got %s", status.State)
+	}
+}
+
+func TestPipeListenerCloseReleasesIdleInstance(t *testing.T) {
+	pipeName := fmt.Sprintf(`\\.\pipe\sssonector-close-test-%d`, os.Getpid())
+
+	listener, err := newPipeListener(pipeName)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	if err := listener.Close(); err != nil {
+		t.Fatalf("Failed to close listener: %v", err)
+	}
+	if _, err := listener.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected Accept on a closed listener to fail with net.ErrClosed, got %v", err)
+	}
+
+	// Without an idle instance the pipe no longer exists
+	handle, err := openPipe(pipeName)
+	if err == nil {
+		windows.CloseHandle(handle)
+		t.Fatal("Expected the closed listener to leave no pipe instance")
+	}
+	if !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		t.Errorf("Expected the pipe not to be found, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@
 package control
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -11,6 +12,24 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// DefaultNetwork is the control transport used unless SetNetwork is called
+const DefaultNetwork = NetworkUnix
+
+// defaultAddress returns the control socket used unless SetSocketPath is called
+func defaultAddress() string {
+	return filepath.Join(os.TempDir(), "sssonector.sock")
+}
+
+// dialPipe is only supported on Windows
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	return nil, fmt.Errorf("named pipes are only supported on Windows")
+}
+
+// newPipeListener is only supported on Windows
+func newPipeListener(path string) (net.Listener, error) {
+	return nil, fmt.Errorf("named pipes are only supported on Windows")
+}
+
 // newUnixListener creates a new Unix domain socket listener
 func newUnixListener(path string) (net.Listener, error) {
 	// Create socket directory if needed
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/o3willard-AI/SSSonector/internal/security/cert"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+)
+
+// useCRLChecker checks client certificates against their CRL during the
+// handshake when security.crl is enabled. A CRL is trusted only when it is
+// signed by a CA in auth.ca_file.
+func (b *BaseService) useCRLChecker(tlsConfig *tunnel.TLSConfig) error {
+	crl := b.cfg.Config.Security.CRL
+	if !crl.Enabled {
+		return nil
+	}
+
+	auth := b.cfg.Config.Auth
+	issuers, err := cert.NewFileStoreWithCA(auth.CertFile, auth.KeyFile, auth.CAFile).CACertificates()
+	if err != nil {
+		return fmt.Errorf("failed to load CA certificates for CRL checking: %w", err)
+	}
+	if len(issuers) == 0 {
+		return fmt.Errorf("CRL checking requires CA certificates in auth.ca_file to verify CRLs against")
+	}
+
+	checker := cert.NewCRLChecker(cert.NewCRLCheckerConfig(crl, issuers), b.logger)
+	tlsConfig.VerifyPeerCertificate = checker.VerifyPeerCertificate
+
+	b.crl = checker
+	checker.Start(context.Background())
+	return nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+	"go.uber.org/zap"
+)
+
+func TestCRLCheckerVerifiesClientCertificates(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Auth = writeOCSPTestCerts(t, nil)
+	b := &BaseService{cfg: cfg, logger: zap.NewNop()}
+
+	tlsConfig := tunnel.TLSConfigFrom(cfg.Config, zap.NewNop())
+	if err := b.useCRLChecker(tlsConfig); err != nil {
+		t.Fatalf("Failed to skip CRL checking: %v", err)
+	}
+	if b.crl != nil || tlsConfig.VerifyPeerCertificate != nil {
+		t.Fatal("Expected no CRL checking while disabled")
+	}
+
+	cfg.Config.Security.CRL.Enabled = true
+	if err := b.useCRLChecker(tlsConfig); err != nil {
+		t.Fatalf("Failed to set up CRL checking: %v", err)
+	}
+	if b.crl == nil || tlsConfig.VerifyPeerCertificate == nil {
+		t.Fatal("Expected client certificates to be checked against CRLs")
+	}
+	defer b.crl.Stop()
+
+	// The certificate names no CRL distribution point, so its revocation status
+	// is unknown and the handshake fails closed
+	certPEM, err := os.ReadFile(cfg.Config.Auth.CertFile)
+	if err != nil {
+		t.Fatalf("Failed to read certificate: %v", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if err := tlsConfig.VerifyPeerCertificate([][]byte{block.Bytes}, nil); err == nil {
+		t.Error("Expected a certificate without a CRL to be rejected")
+	}
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+)
+
+// HealthState is the rolled-up health of the service or one of its subsystems
+type HealthState string
+
+const (
+	HealthHealthy   HealthState = "healthy"
+	HealthDegraded  HealthState = "degraded"
+	HealthUnhealthy HealthState = "unhealthy"
+)
+
+// healthRank orders states from best to worst
+var healthRank = map[HealthState]int{
+	HealthHealthy:   0,
+	HealthDegraded:  1,
+	HealthUnhealthy: 2,
+}
+
+// Subsystem names used in a HealthReport
+const (
+	HealthSubsystemBreakers    = "circuit_breakers"
+	HealthSubsystemMemory      = "memory"
+	HealthSubsystemConnections = "connections"
+)
+
+// BreakerStateSource reports the combined state of the circuit breakers
+type BreakerStateSource interface {
+	GetGlobalState() resilience.CircuitBreakerGlobalState
+}
+
+// PressureSource reports the current memory pressure level
+type PressureSource interface {
+	GetPressureLevel() memory.MemPressure
+}
+
+// ConnectionCounter reports the number of active connections
+type ConnectionCounter interface {
+	GetConnectionCount() int
+}
+
+// HealthThresholds decides when a subsystem is degraded or unhealthy. A breaker
+// threshold of zero never applies.
+type HealthThresholds struct {
+	DegradedOpenBreakers     int
+	UnhealthyOpenBreakers    int
+	DegradedMemoryPressure   memory.MemPressure
+	UnhealthyMemoryPressure  memory.MemPressure
+	DegradedConnectionRatio  float64
+	UnhealthyConnectionRatio float64
+}
+
+// DefaultHealthThresholds returns the default health thresholds
+func DefaultHealthThresholds() HealthThresholds {
+	return HealthThresholds{
+		DegradedOpenBreakers:     1,
+		DegradedMemoryPressure:   memory.MemPressureHigh,
+		UnhealthyMemoryPressure:  memory.MemPressureCritical,
+		DegradedConnectionRatio:  0.8,
+		UnhealthyConnectionRatio: 1.0,
+	}
+}
+
+// HealthThresholdsFrom builds thresholds from the health configuration, using
+// the defaults for unset values
+func HealthThresholdsFrom(cfg types.HealthConfig) (HealthThresholds, error) {
+	t := DefaultHealthThresholds()
+	if cfg.DegradedOpenBreakers > 0 {
+		t.DegradedOpenBreakers = cfg.DegradedOpenBreakers
+	}
+	if cfg.UnhealthyOpenBreakers > 0 {
+		t.UnhealthyOpenBreakers = cfg.UnhealthyOpenBreakers
+	}
+	if cfg.DegradedMemoryPressure != "" {
+		level, err := memory.ParsePressure(cfg.DegradedMemoryPressure)
+		if err != nil {
+			return t, err
+		}
+		t.DegradedMemoryPressure = level
+	}
+	if cfg.UnhealthyMemoryPressure != "" {
+		level, err := memory.ParsePressure(cfg.UnhealthyMemoryPressure)
+		if err != nil {
+			return t, err
+		}
+		t.UnhealthyMemoryPressure = level
+	}
+	if cfg.DegradedConnectionRatio > 0 {
+		t.DegradedConnectionRatio = cfg.DegradedConnectionRatio
+	}
+	if cfg.UnhealthyConnectionRatio > 0 {
+		t.UnhealthyConnectionRatio = cfg.UnhealthyConnectionRatio
+	}
+	return t, nil
+}
+
+// SubsystemHealth is the health of one subsystem
+type SubsystemHealth struct {
+	Status HealthState `json:"status"`
+	Detail string      `json:"detail"`
+}
+
+// HealthReport is the composite health of the service
+type HealthReport struct {
+	Status     HealthState                `json:"status"`
+	Subsystems map[string]SubsystemHealth `json:"subsystems"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+}
+
+// HealthChecker rolls the state of the service's subsystems into one health
+// status. Subsystems without a source are left out of the report.
+type HealthChecker struct {
+	mu             sync.RWMutex // Guards the sources, which may be set while checks run
+	thresholds     HealthThresholds
+	breakers       BreakerStateSource
+	memory         PressureSource
+	connections    ConnectionCounter
+	maxConnections int
+}
+
+// NewHealthChecker creates a health checker with the given thresholds
+func NewHealthChecker(thresholds HealthThresholds) *HealthChecker {
+	return &HealthChecker{thresholds: thresholds}
+}
+
+// SetBreakers sets the circuit breaker state source
+func (h *HealthChecker) SetBreakers(src BreakerStateSource) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.breakers = src
+}
+
+// SetMemory sets the memory pressure source
+func (h *HealthChecker) SetMemory(src PressureSource) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.memory = src
+}
+
+// SetConnections sets the active connection source and the connection limit
+func (h *HealthChecker) SetConnections(src ConnectionCounter, maxConnections int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connections = src
+	h.maxConnections = maxConnections
+}
+
+// Check evaluates every subsystem; the overall status is the worst of them
+func (h *HealthChecker) Check() *HealthReport {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	report := &HealthReport{
+		Status:     HealthHealthy,
+		Subsystems: make(map[string]SubsystemHealth),
+		CheckedAt:  time.Now(),
+	}
+
+	if h.breakers != nil {
+		report.add(HealthSubsystemBreakers, h.checkBreakers())
+	}
+	if h.memory != nil {
+		report.add(HealthSubsystemMemory, h.checkMemory())
+	}
+	if h.connections != nil {
+		report.add(HealthSubsystemConnections, h.checkConnections())
+	}
+
+	return report
+}
+
+// add records a subsystem result and worsens the overall status if needed
+func (r *HealthReport) add(name string, health SubsystemHealth) {
+	r.Subsystems[name] = health
+	if healthRank[health.Status] > healthRank[r.Status] {
+		r.Status = health.Status
+	}
+}
+
+func (h *HealthChecker) checkBreakers() SubsystemHealth {
+	state := h.breakers.GetGlobalState()
+	health := SubsystemHealth{
+		Status: HealthHealthy,
+		Detail: fmt.Sprintf("%d of %d breakers open, %d half-open", state.OpenCount, state.TotalBreakers, state.HalfOpenCount),
+	}
+
+	switch {
+	case h.thresholds.UnhealthyOpenBreakers > 0 && state.OpenCount >= h.thresholds.UnhealthyOpenBreakers:
+		health.Status = HealthUnhealthy
+	case h.thresholds.DegradedOpenBreakers > 0 && state.OpenCount >= h.thresholds.DegradedOpenBreakers:
+		health.Status = HealthDegraded
+	}
+	return health
+}
+
+func (h *HealthChecker) checkMemory() SubsystemHealth {
+	level := h.memory.GetPressureLevel()
+	health := SubsystemHealth{
+		Status: HealthHealthy,
+		Detail: fmt.Sprintf("memory pressure %s", level),
+	}
+
+	switch {
+	case level >= h.thresholds.UnhealthyMemoryPressure:
+		health.Status = HealthUnhealthy
+	case level >= h.thresholds.DegradedMemoryPressure:
+		health.Status = HealthDegraded
+	}
+	return health
+}
+
+func (h *HealthChecker) checkConnections() SubsystemHealth {
+	active := h.connections.GetConnectionCount()
+	if h.maxConnections <= 0 {
+		return SubsystemHealth{
+			Status: HealthHealthy,
+			Detail: fmt.Sprintf("%d active connections", active),
+		}
+	}
+
+	ratio := float64(active) / float64(h.maxConnections)
+	health := SubsystemHealth{
+		Status: HealthHealthy,
+		Detail: fmt.Sprintf("%d of %d connections in use", active, h.maxConnections),
+	}
+
+	switch {
+	case ratio >= h.thresholds.UnhealthyConnectionRatio:
+		health.Status = HealthUnhealthy
+	case ratio >= h.thresholds.DegradedConnectionRatio:
+		health.Status = HealthDegraded
+	}
+	return health
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+)
+
+type stubBreakers resilience.CircuitBreakerGlobalState
+
+func (s stubBreakers) GetGlobalState() resilience.CircuitBreakerGlobalState {
+	return resilience.CircuitBreakerGlobalState(s)
+}
+
+type stubPressure memory.MemPressure
+
+func (s stubPressure) GetPressureLevel() memory.MemPressure {
+	return memory.MemPressure(s)
+}
+
+type stubConnections int
+
+func (s stubConnections) GetConnectionCount() int {
+	return int(s)
+}
+
+func TestHealthCheckerRollUp(t *testing.T) {
+	tests := []struct {
+		name        string
+		open        int
+		pressure    memory.MemPressure
+		connections int
+		want        HealthState
+		wantFailing string
+	}{
+		{"all healthy", 0, memory.MemPressureLow, 10, HealthHealthy, ""},
+		{"open breaker", 1, memory.MemPressureNone, 10, HealthDegraded, HealthSubsystemBreakers},
+		{"high memory", 0, memory.MemPressureHigh, 10, HealthDegraded, HealthSubsystemMemory},
+		{"critical memory", 1, memory.MemPressureCritical, 10, HealthUnhealthy, HealthSubsystemMemory},
+		{"near connection limit", 0, memory.MemPressureNone, 85, HealthDegraded, HealthSubsystemConnections},
+		{"connection limit", 0, memory.MemPressureNone, 100, HealthUnhealthy, HealthSubsystemConnections},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewHealthChecker(DefaultHealthThresholds())
+			checker.SetBreakers(stubBreakers{TotalBreakers: 3, OpenCount: tt.open})
+			checker.SetMemory(stubPressure(tt.pressure))
+			checker.SetConnections(stubConnections(tt.connections), 100)
+
+			report := checker.Check()
+			if report.Status != tt.want {
+				t.Errorf("Expected status %s, got %s", tt.want, report.Status)
+			}
+			if len(report.Subsystems) != 3 {
+				t.Fatalf("Expected 3 subsystems, got %d", len(report.Subsystems))
+			}
+			if tt.wantFailing != "" && report.Subsystems[tt.wantFailing].Status != tt.want {
+				t.Errorf("Expected %s to be %s, got %+v", tt.wantFailing, tt.want, report.Subsystems[tt.wantFailing])
+			}
+		})
+	}
+}
+
+func TestHealthThresholdsFrom(t *testing.T) {
+	thresholds, err := HealthThresholdsFrom(types.HealthConfig{
+		DegradedOpenBreakers:    2,
+		UnhealthyOpenBreakers:   3,
+		DegradedMemoryPressure:  "medium",
+		UnhealthyMemoryPressure: "high",
+	})
+	if err != nil {
+		t.Fatalf("Failed to build thresholds: %v", err)
+	}
+
+	checker := NewHealthChecker(thresholds)
+	checker.SetBreakers(stubBreakers{TotalBreakers: 3, OpenCount: 1})
+	if status := checker.Check().Status; status != HealthHealthy {
+		t.Errorf("Expected one open breaker to be healthy, got %s", status)
+	}
+
+	checker.SetBreakers(stubBreakers{TotalBreakers: 3, OpenCount: 3})
+	checker.SetMemory(stubPressure(memory.MemPressureMedium))
+	report := checker.Check()
+	if report.Status != HealthUnhealthy {
+		t.Errorf("Expected three open breakers to be unhealthy, got %s", report.Status)
+	}
+	if report.Subsystems[HealthSubsystemMemory].Status != HealthDegraded {
+		t.Errorf("Expected medium memory pressure to be degraded, got %s", report.Subsystems[HealthSubsystemMemory].Status)
+	}
+
+	if _, err := HealthThresholdsFrom(types.HealthConfig{DegradedMemoryPressure: "extreme"}); err == nil {
+		t.Error("Expected an unknown pressure level to be rejected")
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+)
+
+// SetMetricsHistory sets the source of the metrics history reported by the
+// history command, such as Monitor.History
+func (b *BaseService) SetMetricsHistory(history func(window time.Duration) []monitor.MetricsSnapshot) {
+	b.history = history
+}
+
+// MetricsHistory returns the metrics snapshots taken within window, oldest
+// first. A window of zero or less returns every snapshot kept.
+func (b *BaseService) MetricsHistory(window time.Duration) ([]monitor.MetricsSnapshot, error) {
+	if b.history == nil {
+		return nil, fmt.Errorf("metrics history not configured")
+	}
+	history := b.history(window)
+	if history == nil {
+		return nil, fmt.Errorf("metrics history is disabled (set monitor.history_size)")
+	}
+	return history, nil
+}
+
+// HistoryWindow reads the "window" argument of the history command, a
+// duration such as 10m; none selects the whole history
+func HistoryWindow(args map[string]interface{}) (time.Duration, error) {
+	value, _ := args["window"].(string)
+	if value == "" {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window < 0 {
+		return 0, fmt.Errorf("invalid history window: %q", value)
+	}
+	return window, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ParseLogLevel parses a log level that can be set at runtime: debug, info,
+// warn or error
+func ParseLogLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("invalid log level %q: must be debug, info, warn or error", level)
+	}
+}
+
+// SetLogger replaces the service logger. level must be the level the logger
+// was built with, so that SetLogLevel takes effect on it.
+func (b *BaseService) SetLogger(logger *zap.Logger, level zap.AtomicLevel) {
+	b.logger = logger
+	b.level = level
+}
+
+// SetLogLevel changes the level of the service logger without restarting
+func (b *BaseService) SetLogLevel(level string) error {
+	next, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+
+	// Log the change at whichever of the two levels lets it through
+	previous := b.level.Level()
+	if next < previous {
+		b.level.SetLevel(next)
+	}
+	b.logger.Info("Log level changed",
+		zap.Stringer("from", previous),
+		zap.Stringer("to", next))
+	b.level.SetLevel(next)
+	return nil
+}
+
+// LogLevel returns the current level of the service logger
+func (b *BaseService) LogLevel() string {
+	return b.level.String()
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	core, logs := observer.New(level)
+	b := &BaseService{}
+	b.SetLogger(zap.New(core), level)
+
+	b.logger.Debug("before")
+	if logs.FilterMessage("before").Len() != 0 {
+		t.Fatal("Expected debug messages to be dropped at info level")
+	}
+
+	if err := b.SetLogLevel("debug"); err != nil {
+		t.Fatalf("Failed to set log level: %v", err)
+	}
+	if b.LogLevel() != "debug" {
+		t.Errorf("Expected log level debug, got %s", b.LogLevel())
+	}
+	b.logger.Debug("after")
+	if logs.FilterMessage("after").Len() != 1 {
+		t.Error("Expected a debug message after switching to debug")
+	}
+
+	if err := b.SetLogLevel("verbose"); err == nil {
+		t.Error("Expected an invalid log level to be rejected")
+	}
+	if b.LogLevel() != "debug" {
+		t.Errorf("Expected an invalid level to leave debug in place, got %s", b.LogLevel())
+	}
+
+	if err := b.SetLogLevel("WARN"); err != nil {
+		t.Fatalf("Failed to set log level: %v", err)
+	}
+	b.logger.Info("quiet")
+	if logs.FilterMessage("quiet").Len() != 0 {
+		t.Error("Expected info messages to be dropped at warn level")
+	}
+	if logs.FilterMessage("Log level changed").Len() != 2 {
+		t.Errorf("Expected both changes to be logged, got %d", logs.FilterMessage("Log level changed").Len())
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+)
+
+// monitorInterval is how often the running tunnel's statistics are reported to
+// the monitor
+const monitorInterval = time.Second
+
+// SetMonitor makes the service report through m; the history command reads
+// m's history. The caller starts and stops m.
+func (b *BaseService) SetMonitor(m *monitor.Monitor) {
+	b.monitor = m
+	b.SetMetricsHistory(m.History)
+	m.SetConnectionSource(b.talkerStats)
+}
+
+// talkerStats returns the traffic of the server's client connections for the
+// connection summary, none in client mode
+func (b *BaseService) talkerStats() []monitor.TalkerStats {
+	if b.server == nil {
+		return nil
+	}
+	return b.server.TalkerStats()
+}
+
+// startReporting reports the tunnel's statistics to the monitor every
+// monitorInterval until stopReporting
+func (b *BaseService) startReporting() {
+	if b.monitor == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.stopReport = cancel
+
+	go func() {
+		ticker := time.NewTicker(monitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.reportMetrics()
+			}
+		}
+	}()
+}
+
+// stopReporting stops the reports started by startReporting
+func (b *BaseService) stopReporting() {
+	if b.stopReport != nil {
+		b.stopReport()
+		b.stopReport = nil
+	}
+}
+
+// reportMetrics hands the tunnel's current statistics to the monitor
+func (b *BaseService) reportMetrics() {
+	switch b.cfg.Config.Mode {
+	case types.ModeServer:
+		traffic := b.server.TrafficStats()
+		b.monitor.UpdateMetrics(traffic.BytesIn, traffic.BytesOut, traffic.PacketsIn, traffic.PacketsOut,
+			traffic.Errors, b.server.GetConnectionCount())
+		b.monitor.UpdateHandshakeMetrics(b.server.HandshakeStats())
+		b.monitor.UpdateTLSMetrics(b.server.TLSStats())
+	case types.ModeClient:
+		traffic := b.client.TrafficStats()
+		b.monitor.UpdateMetrics(traffic.BytesIn, traffic.BytesOut, traffic.PacketsIn, traffic.PacketsOut,
+			traffic.Errors, b.client.PoolStats().ActiveCount)
+		b.monitor.UpdateHandshakeMetrics(b.client.HandshakeStats())
+		b.monitor.UpdateTLSMetrics(b.client.TLSStats())
+	}
+	b.monitor.UpdateThrottleMetrics(b.throttleStats())
+	if b.recovery != nil {
+		b.monitor.UpdateRecoveryMetrics(recoveryStats(b.recovery.Snapshot()))
+	}
+}
+
+// throttleStats returns the global rate limit and the connections it has rejected
+func (b *BaseService) throttleStats() monitor.ThrottleStats {
+	status := b.limits.Status()
+	global := status.Scopes[0]
+	stats := monitor.ThrottleStats{
+		Enabled: status.Enabled,
+		Rate:    global.Rate,
+		Burst:   int64(global.Burst),
+	}
+	if status.Connections != nil {
+		stats.Drops = int64(status.Connections.Rejected)
+	}
+	return stats
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+)
+
+func TestSetMonitorReportsHistory(t *testing.T) {
+	svc, err := NewBaseService(types.NewAppConfig(types.TypeServer), ServiceOptions{Name: "test"})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	mon, err := monitor.New(&monitor.Config{
+		LogFile: "stderr",
+		History: monitor.HistoryConfig{Interval: 10 * time.Millisecond, Size: 10},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+	svc.SetMonitor(mon)
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+	defer mon.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	history, err := svc.MetricsHistory(0)
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if len(history) == 0 {
+		t.Error("Expected the monitor's snapshots in the history")
+	}
+}
+
+func TestReportMetricsUpdatesHandshakes(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	svc, err := NewBaseService(cfg, ServiceOptions{Name: "test"})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	svc.server = tunnel.NewServer(cfg, nil, svc.logger)
+
+	mon, err := monitor.New(&monitor.Config{LogFile: "stderr"})
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+	svc.SetMonitor(mon)
+
+	// A server without TLS reports no handshakes, replacing the stale figures
+	mon.UpdateHandshakeMetrics(monitor.HandshakeStats{Count: 5})
+	svc.reportMetrics()
+	if count := mon.GetMetrics().Handshake.Count; count != 0 {
+		t.Errorf("Expected the server's handshake count 0, got %d", count)
+	}
+}
+
+func TestReportMetricsUpdatesThrottle(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Throttle = types.ThrottleConfig{Enabled: true, Rate: 2048, Burst: 512}
+	svc, err := NewBaseService(cfg, ServiceOptions{Name: "test"})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	svc.server = tunnel.NewServer(cfg, nil, svc.logger)
+
+	mon, err := monitor.New(&monitor.Config{LogFile: "stderr"})
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+	svc.SetMonitor(mon)
+
+	svc.reportMetrics()
+	want := monitor.ThrottleStats{Enabled: true, Rate: 2048, Burst: 512}
+	if got := mon.GetMetrics().Throttle; got != want {
+		t.Errorf("Expected throttle metrics %+v, got %+v", want, got)
+	}
+}
+
+func TestTalkerStatsFollowsServer(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	svc, err := NewBaseService(cfg, ServiceOptions{Name: "test"})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if talkers := svc.talkerStats(); talkers != nil {
+		t.Errorf("Expected no talkers before the server runs, got %v", talkers)
+	}
+
+	svc.server = tunnel.NewServer(cfg, nil, svc.logger)
+	if talkers := svc.talkerStats(); len(talkers) != 0 {
+		t.Errorf("Expected no talkers without connections, got %v", talkers)
+	}
+}
+
+func TestErrorRecoveryFromConfig(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Recovery.Strategies = map[string]types.RecoveryStrategyConfig{
+		"network_retry": {Enabled: true, RetryDelay: time.Millisecond},
+	}
+	svc, err := NewBaseService(cfg, ServiceOptions{Name: "test"})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	svc.server = tunnel.NewServer(cfg, nil, svc.logger)
+
+	if err := svc.useErrorRecovery(); err != nil {
+		t.Fatalf("Failed to set up error recovery: %v", err)
+	}
+	if !svc.recovery.CanRecover(&net.OpError{Op: "accept", Net: "tcp", Err: syscall.ECONNABORTED}) {
+		t.Error("Expected the configured network_retry strategy to handle accept errors")
+	}
+
+	cfg.Config.Recovery.Strategies = map[string]types.RecoveryStrategyConfig{"unknown": {Enabled: true}}
+	svc.recovery = nil
+	if err := svc.useErrorRecovery(); err == nil {
+		t.Error("Expected an unknown recovery strategy to be rejected")
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/o3willard-AI/SSSonector/internal/security/cert"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+	"go.uber.org/zap"
+)
+
+// useOCSPStapler staples OCSP responses to the server certificate when it names
+// OCSP responders and its issuer is in auth.ca_file. While a responder is down
+// the certificate is served without a staple.
+func (b *BaseService) useOCSPStapler(tlsConfig *tunnel.TLSConfig) error {
+	auth := b.cfg.Config.Auth
+	store := cert.NewFileStoreWithCA(auth.CertFile, auth.KeyFile, auth.CAFile)
+	pair, err := store.LoadCurrent()
+	if err != nil {
+		return fmt.Errorf("failed to load certificate for OCSP stapling: %w", err)
+	}
+	if len(pair.Cert.OCSPServer) == 0 {
+		return nil
+	}
+	chain, err := store.GetChain(pair.ToCertificate())
+	if err != nil {
+		return fmt.Errorf("failed to find the certificate's issuer for OCSP stapling: %w", err)
+	}
+	if len(chain) < 2 {
+		b.logger.Warn("Not stapling OCSP responses, the certificate's issuer is not in the CA file",
+			zap.String("ca_file", auth.CAFile))
+		return nil
+	}
+
+	stapler, err := cert.NewOCSPStapler(cert.DefaultOCSPConfig(chain[1].X509), b.logger)
+	if err != nil {
+		return err
+	}
+
+	get := tlsConfig.GetCertificate
+	if get == nil {
+		keyPair, err := tls.LoadX509KeyPair(auth.CertFile, auth.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate for OCSP stapling: %w", err)
+		}
+		get = func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &keyPair, nil }
+	}
+	tlsConfig.GetCertificate = stapler.GetCertificate(get)
+
+	b.stapler = stapler
+	stapler.Start(context.Background())
+	go b.updateStaple(stapler, pair.Cert)
+	return nil
+}
+
+// updateStaple fetches an OCSP response for a newly served certificate
+func (b *BaseService) updateStaple(stapler *cert.OCSPStapler, leaf *x509.Certificate) {
+	if err := stapler.Update(leaf); err != nil {
+		b.logger.Warn("Failed to fetch OCSP staple, serving certificate without staple",
+			zap.String("serial", leaf.SerialNumber.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
+	"go.uber.org/zap"
+)
+
+// writeOCSPTestCerts writes a CA and a server certificate it issued, naming
+// ocspServers, and returns the auth configuration for them
+func writeOCSPTestCerts(t *testing.T, ocspServers []string) types.AuthConfig {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "server"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		OCSPServer:   ocspServers,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	auth := types.AuthConfig{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+	for path, block := range map[string]*pem.Block{
+		auth.CertFile: {Type: "CERTIFICATE", Bytes: der},
+		auth.KeyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+		auth.CAFile:   {Type: "CERTIFICATE", Bytes: caDER},
+	} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	return auth
+}
+
+func TestOCSPStaplerAttachedToServerCertificate(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	// Nothing listens on the responder, so the certificate is served unstapled
+	cfg.Config.Auth = writeOCSPTestCerts(t, []string{"http://127.0.0.1:1/ocsp"})
+	b := &BaseService{cfg: cfg, logger: zap.NewNop()}
+
+	tlsConfig := tunnel.TLSConfigFrom(cfg.Config, zap.NewNop())
+	if err := b.useOCSPStapler(tlsConfig); err != nil {
+		t.Fatalf("Failed to set up OCSP stapling: %v", err)
+	}
+	if b.stapler == nil || tlsConfig.GetCertificate == nil {
+		t.Fatal("Expected a certificate naming an OCSP responder to be stapled")
+	}
+	defer b.stapler.Stop()
+
+	served, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("Expected the certificate to be served while the responder is down, got %v", err)
+	}
+	if len(served.OCSPStaple) != 0 {
+		t.Error("Expected no staple while the responder is down")
+	}
+}
+
+func TestOCSPStaplerSkippedWithoutResponders(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Auth = writeOCSPTestCerts(t, nil)
+	b := &BaseService{cfg: cfg, logger: zap.NewNop()}
+
+	tlsConfig := tunnel.TLSConfigFrom(cfg.Config, zap.NewNop())
+	if err := b.useOCSPStapler(tlsConfig); err != nil {
+		t.Fatalf("Failed to set up OCSP stapling: %v", err)
+	}
+	if b.stapler != nil || tlsConfig.GetCertificate != nil {
+		t.Error("Expected a certificate without OCSP responders not to be stapled")
+	}
+}
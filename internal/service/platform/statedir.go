@@ -0,0 +1,80 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+)
+
+// Persistence features that write to the state directory
+const (
+	PersistPIDFile   = "pid_file"
+	PersistDNSBackup = "dns_backup"
+)
+
+// persistenceFeatures lists the features disabled when the state directory is not writable
+var persistenceFeatures = []string{
+	PersistPIDFile,
+	PersistDNSBackup,
+}
+
+// createProbe creates the file used to test the state directory; replaced in tests
+var createProbe = os.CreateTemp
+
+// StateDir describes whether the state directory can hold persisted state
+type StateDir struct {
+	Path     string
+	Writable bool
+	Reason   error // Why the directory is not writable
+}
+
+// ProbeStateDir checks at startup that path can be written to. A read-only or
+// otherwise unwritable directory is not an error: persistence features are
+// disabled, and the caller reports Reason and Disabled.
+func ProbeStateDir(path string) *StateDir {
+	s := &StateDir{Path: path}
+	if err := probeWritable(path); err != nil {
+		s.Reason = err
+		return s
+	}
+
+	s.Writable = true
+	return s
+}
+
+// probeWritable creates and removes a file in dir
+func probeWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	f, err := createProbe(dir, ".write-probe-*")
+	if err != nil {
+		return fmt.Errorf("failed to write to state directory: %w", err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// Enabled reports whether a feature may write to the state directory. Only
+// the persistence features are turned off by an unwritable directory. A nil
+// StateDir has not been probed and enables everything.
+func (s *StateDir) Enabled(feature string) bool {
+	if s == nil || s.Writable {
+		return true
+	}
+	for _, disabled := range persistenceFeatures {
+		if feature == disabled {
+			return false
+		}
+	}
+	return true
+}
+
+// Disabled returns the persistence features turned off by an unwritable directory
+func (s *StateDir) Disabled() []string {
+	if s == nil || s.Writable {
+		return nil
+	}
+	return append([]string(nil), persistenceFeatures...)
+}
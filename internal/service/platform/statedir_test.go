@@ -0,0 +1,65 @@
+package platform
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestProbeStateDirReadOnly(t *testing.T) {
+	dir := t.TempDir()
+
+	// Root can write to any mode, so simulate the read-only mount
+	createProbe = func(dir, pattern string) (*os.File, error) {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: syscall.EROFS}
+	}
+	defer func() { createProbe = os.CreateTemp }()
+
+	state := ProbeStateDir(dir)
+
+	if state.Writable {
+		t.Fatal("Expected a read-only state directory to be reported unwritable")
+	}
+	if !errors.Is(state.Reason, syscall.EROFS) {
+		t.Errorf("Expected reason to be EROFS, got %v", state.Reason)
+	}
+	if state.Enabled(PersistPIDFile) {
+		t.Error("Expected persistence features to be disabled")
+	}
+	if !state.Enabled("metrics_export") {
+		t.Error("Expected a feature that does not persist state to stay enabled")
+	}
+	if len(state.Disabled()) != len(persistenceFeatures) {
+		t.Errorf("Expected %d disabled features, got %v", len(persistenceFeatures), state.Disabled())
+	}
+}
+
+func TestProbeStateDirWritable(t *testing.T) {
+	state := ProbeStateDir(t.TempDir())
+	if !state.Writable || !state.Enabled(PersistPIDFile) || state.Disabled() != nil {
+		t.Errorf("Expected a writable state directory to enable persistence, got %+v", state)
+	}
+
+	var unprobed *StateDir
+	if !unprobed.Enabled(PersistPIDFile) {
+		t.Error("Expected an unprobed state directory to enable persistence")
+	}
+}
+
+func TestProbeStateDirPermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("Permission bits do not apply to root")
+	}
+
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatalf("Failed to make directory read-only: %v", err)
+	}
+	defer os.Chmod(dir, 0755)
+
+	state := ProbeStateDir(dir)
+	if state.Writable || !errors.Is(state.Reason, os.ErrPermission) {
+		t.Errorf("Expected a read-only directory to be unwritable, got %+v", state)
+	}
+}
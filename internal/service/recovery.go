@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+)
+
+// useErrorRecovery makes the server wait out temporary accept errors with the
+// recovery strategies enabled under recovery.strategies. The recovery outlives
+// restarts so its metrics add up.
+func (b *BaseService) useErrorRecovery() error {
+	if b.recovery == nil {
+		recoveryCfg, err := resilience.NewRecoveryConfigFromConfig(b.cfg.Config.Recovery)
+		if err != nil {
+			return fmt.Errorf("invalid recovery configuration: %w", err)
+		}
+		recovery, err := resilience.NewErrorRecovery(recoveryCfg, b.logger)
+		if err != nil {
+			return fmt.Errorf("failed to create error recovery: %w", err)
+		}
+		b.recovery = recovery
+	}
+	b.server.SetErrorRecovery(b.recovery)
+	return nil
+}
+
+// recoveryStats converts recovery metrics for the monitor
+func recoveryStats(metrics resilience.RecoveryMetrics) monitor.RecoveryStats {
+	return monitor.RecoveryStats{
+		TotalRecoveries:      metrics.TotalRecoveries,
+		SuccessfulRecoveries: metrics.SuccessfulRecoveries,
+		FailedRecoveries:     metrics.FailedRecoveries,
+		AverageRecoveryTime:  metrics.AverageRecoveryTime,
+		StrategyUsage:        metrics.StrategyUsage,
+		CategoryUsage:        metrics.CategoryUsage(),
+	}
+}
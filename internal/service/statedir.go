@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/o3willard-AI/SSSonector/internal/service/platform"
+	"go.uber.org/zap"
+)
+
+// SetStateDir sets the state directory the startup probe checked. The service
+// writes its PID file there while running, unless the probe disabled it.
+// Without it no PID file is written.
+func (b *BaseService) SetStateDir(stateDir *platform.StateDir) {
+	b.stateDir = stateDir
+}
+
+// pidFile returns the path of the PID file, or "" when none is written
+func (b *BaseService) pidFile() string {
+	if b.stateDir == nil || !b.stateDir.Enabled(platform.PersistPIDFile) {
+		return ""
+	}
+	name := b.options.Name
+	if name == "" {
+		name = "sssonector"
+	}
+	return filepath.Join(b.stateDir.Path, name+".pid")
+}
+
+// writePIDFile records the service's PID in the state directory
+func (b *BaseService) writePIDFile() error {
+	path := b.pidFile()
+	if path == "" {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return nil
+}
+
+// removePIDFile removes the PID file written by writePIDFile
+func (b *BaseService) removePIDFile() {
+	path := b.pidFile()
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		b.logger.Warn("Failed to remove PID file", zap.String("path", path), zap.Error(err))
+	}
+}
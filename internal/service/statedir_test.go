@@ -0,0 +1,46 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/service/platform"
+	"go.uber.org/zap"
+)
+
+func TestPIDFileFollowsStateDir(t *testing.T) {
+	dir := t.TempDir()
+	b := &BaseService{logger: zap.NewNop(), options: ServiceOptions{Name: "sssonector"}}
+
+	// Without a probed state directory nothing is written
+	if err := b.writePIDFile(); err != nil {
+		t.Fatalf("Failed to skip PID file: %v", err)
+	}
+
+	b.SetStateDir(platform.ProbeStateDir(dir))
+	if err := b.writePIDFile(); err != nil {
+		t.Fatalf("Failed to write PID file: %v", err)
+	}
+	path := filepath.Join(dir, "sssonector.pid")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read PID file: %v", err)
+	}
+	if pid, _ := strconv.Atoi(strings.TrimSpace(string(data))); pid != os.Getpid() {
+		t.Errorf("Expected PID %d, got %q", os.Getpid(), data)
+	}
+
+	b.removePIDFile()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the PID file to be removed, got %v", err)
+	}
+
+	// An unwritable state directory disables the PID file
+	b.SetStateDir(&platform.StateDir{Path: filepath.Join(dir, "missing")})
+	if b.pidFile() != "" {
+		t.Error("Expected no PID file for an unwritable state directory")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/tunnel"
 )
 
 // ServiceState represents the state of a service
@@ -44,13 +45,13 @@ const (
 
 // VersionInfo represents version information for a component
 type VersionInfo struct {
-	Component      string    `json:"component"`
-	Version        string    `json:"version"`
-	SchemaVersion  string    `json:"schema_version"`
-	BuildNumber    string    `json:"build_number,omitempty"`
-	CommitHash     string    `json:"commit_hash,omitempty"`
-	BuildDate      time.Time `json:"build_date"`
-	StartedAt      time.Time `json:"started_at"`
+	Component     string    `json:"component"`
+	Version       string    `json:"version"`
+	SchemaVersion string    `json:"schema_version"`
+	BuildNumber   string    `json:"build_number,omitempty"`
+	CommitHash    string    `json:"commit_hash,omitempty"`
+	BuildDate     time.Time `json:"build_date"`
+	StartedAt     time.Time `json:"started_at"`
 }
 
 // ResourceMetrics represents resource usage metrics
@@ -88,6 +89,13 @@ type ComponentStatus struct {
 
 // ServiceStatus represents the overall status of the service
 type ServiceStatus struct {
+	Name       string    `json:"name"`
+	State      string    `json:"state"`
+	Mode       string    `json:"mode"`
+	PID        int       `json:"pid"`
+	StartTime  time.Time `json:"start_time"`
+	LastReload time.Time `json:"last_reload,omitempty"`
+
 	OverallHealth    ComponentHealth   `json:"overall_health"`
 	Version          VersionInfo       `json:"version"`
 	Components       []ComponentStatus `json:"components"`
@@ -96,7 +104,12 @@ type ServiceStatus struct {
 	ResourceMetrics  ResourceMetrics   `json:"resource_metrics"`
 	SecurityMetrics  SecurityMetrics   `json:"security_metrics"`
 	Uptime           time.Duration     `json:"uptime"`
+	MemoryPressure   string            `json:"memory_pressure,omitempty"`
+	LogLevel         string            `json:"log_level,omitempty"`
 	CurrentConfig    *config.AppConfig `json:"current_config,omitempty"`
+
+	// Drain reports the progress of a server shutdown, once one has started
+	Drain *tunnel.DrainStatus `json:"drain,omitempty"`
 }
 
 // LifecycleHook is a function type for lifecycle hooks
@@ -127,7 +140,7 @@ func (e *TypedError) Error() string {
 // Common error types
 var (
 	ErrServiceNotRunning     = &TypedError{Type: "service_error", Message: "service is not running", Code: 1001}
-	ErrServiceAlreadyRunning  = &TypedError{Type: "service_error", Message: "service is already running", Code: 1002}
+	ErrServiceAlreadyRunning = &TypedError{Type: "service_error", Message: "service is already running", Code: 1002}
 	ErrConfigInvalid         = &TypedError{Type: "config_error", Message: "configuration is invalid", Code: 2001}
 	ErrConfigVersionMismatch = &TypedError{Type: "config_error", Message: "configuration version mismatch", Code: 2002}
 	ErrMigrationInProgress   = &TypedError{Type: "migration_error", Message: "migration is in progress", Code: 3001}
@@ -136,44 +149,59 @@ var (
 	ErrHealthCheckFailed     = &TypedError{Type: "health_error", Message: "health check failed", Code: 5001}
 )
 
-// Service interface defines the core service operations
+// ServiceOptions holds the directories and name a service runs with
+type ServiceOptions struct {
+	Name      string
+	ConfigDir string
+	DataDir   string
+	LogDir    string
+}
+
+// ServiceMetrics represents the metrics reported by the metrics command
+type ServiceMetrics struct {
+	Platform      string `json:"platform"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// ServiceCommand is a command sent to a running service
+type ServiceCommand string
+
+// Commands every service handles
+const (
+	CmdStatus  ServiceCommand = "status"
+	CmdStart   ServiceCommand = "start"
+	CmdStop    ServiceCommand = "stop"
+	CmdReload  ServiceCommand = "reload"
+	CmdMetrics ServiceCommand = "metrics"
+	CmdHealth  ServiceCommand = "health"
+)
+
+// ServiceResponse is the result of a service command
+type ServiceResponse struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Codes of the errors returned by NewServiceError
+const (
+	ErrNotRunning     = 1001
+	ErrAlreadyRunning = 1002
+	ErrInvalidCommand = 1003
+)
+
+// NewServiceError creates a service error with the given code
+func NewServiceError(code int, message string) *TypedError {
+	return &TypedError{Type: "service_error", Message: message, Code: code}
+}
+
+// Service interface defines the operations of a service controlled through
+// the control socket
 type Service interface {
-	// Lifecycle methods
-	Start(ctx context.Context) error
-	Stop(ctx context.Context) error
-	Reload(ctx context.Context) error
-
-	// Status methods
-	Status(ctx context.Context) (*ServiceStatus, error)
-	Health(ctx context.Context) ComponentHealth
-
-	// Version methods
-	GetVersion() VersionInfo
-	GetSchemaVersion() string
-
-	// Migration methods
-	StartMigration(ctx context.Context) error
-	AbortMigration(ctx context.Context) error
-	GetMigrationStatus() (MigrationStatus, string)
-
-	// Component management
-	RegisterComponent(name string, component Service) error
-	UnregisterComponent(name string) error
-	GetComponent(name string) (Service, bool)
+	Start() error
+	Stop() error
+	Reload() error
+	Status() (*ServiceStatus, error)
+	Metrics() (*ServiceMetrics, error)
+	Health() error
 }
-</parameter>
-<task_progress>
-- [x] Clone and analyze SSSonector repository
-- [x] Review existing Issues.md and codebase
-- [x] Identify improvement areas
-- [x] Create prioritized atomic task list (96 tasks)
-- [x] Create sprint/iteration breakdown (11 sprints, 22 weeks)
-- [x] Implement Sprint 1 tasks (100% complete - 16/16 tasks)
-- [x] Implement Sprint 2 tasks (100% complete - 10/10 tasks)
-- [ ] Implement Sprint 3 tasks (SERVICE-001 through SERVICE-010)
-- [ ] Implement Sprint 4-5 tasks (PERF-001 through PERF-015)
-- [ ] Implement Sprint 6-7 tasks (REL-001 through REL-020)
-- [ ] Implement Sprint 8-9 tasks (requires Act Mode)
-- [ ] Implement Sprint 10-11 tasks (requires Act Mode)
-</task_progress>
-</write_to_file>
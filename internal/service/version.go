@@ -0,0 +1,30 @@
+package service
+
+import "time"
+
+// NewVersionInfo describes a build from the values injected at link time.
+// buildTime is in RFC 3339 form; an empty or malformed one leaves BuildDate
+// unset.
+func NewVersionInfo(component, version, buildTime, commitHash string) VersionInfo {
+	info := VersionInfo{
+		Component:  component,
+		Version:    version,
+		CommitHash: commitHash,
+		StartedAt:  time.Now(),
+	}
+	if date, err := time.Parse(time.RFC3339, buildTime); err == nil {
+		info.BuildDate = date
+	}
+	return info
+}
+
+// SetVersion records the build of the running binary, reported by GetVersion
+// and Status
+func (b *BaseService) SetVersion(info VersionInfo) {
+	b.version = info
+}
+
+// GetVersion returns the build of the running binary
+func (b *BaseService) GetVersion() (VersionInfo, error) {
+	return b.version, nil
+}
@@ -0,0 +1,218 @@
+package startup
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Phase is a step of process startup
+type Phase int
+
+const (
+	PhaseInitialization Phase = iota
+	PhaseConfiguration
+	PhaseAdapter
+	PhaseConnection
+	PhaseListen
+	PhaseRunning
+)
+
+// String returns the phase name
+func (p Phase) String() string {
+	switch p {
+	case PhaseInitialization:
+		return "initialization"
+	case PhaseConfiguration:
+		return "configuration"
+	case PhaseAdapter:
+		return "adapter"
+	case PhaseConnection:
+		return "connection"
+	case PhaseListen:
+		return "listen"
+	case PhaseRunning:
+		return "running"
+	default:
+		return fmt.Sprintf("phase(%d)", int(p))
+	}
+}
+
+// ErrPhaseTimeout is passed to the abort handler when a phase outlives its deadline
+var ErrPhaseTimeout = errors.New("startup phase timed out")
+
+// ValidatePhaseTransition checks that startup moves forward from one phase to a
+// later one. Phases may be skipped: a server has no connection phase and a
+// client no listen phase.
+func ValidatePhaseTransition(from, to Phase) error {
+	if to < PhaseInitialization || to > PhaseRunning {
+		return fmt.Errorf("unknown startup phase %s", to)
+	}
+	if to <= from {
+		return fmt.Errorf("invalid startup phase transition from %s to %s", from, to)
+	}
+	return nil
+}
+
+// StartupLogger logs the progress of startup through its phases and records it
+// for Report. A phase may be given a deadline, after which a watchdog logs a
+// timeout and calls the abort handler, if set.
+type StartupLogger struct {
+	mu         sync.Mutex
+	logger     *zap.Logger
+	started    time.Time
+	phase      Phase
+	phaseStart time.Time
+	watchdog   *time.Timer
+	generation uint64 // Incremented on each transition, so a stale watchdog does nothing
+	abort      func(phase Phase, err error)
+
+	// Recorded for Report
+	phases     []phaseSpan
+	operations []operation
+	warnings   []ReportEntry
+	errors     []ReportEntry
+}
+
+// NewStartupLogger creates a startup logger in the initialization phase
+func NewStartupLogger(logger *zap.Logger) *StartupLogger {
+	now := time.Now()
+	return &StartupLogger{
+		logger:     logger,
+		started:    now,
+		phase:      PhaseInitialization,
+		phaseStart: now,
+	}
+}
+
+// SetAbortHandler sets the function called when a phase deadline passes.
+// Without one, timeouts are only logged.
+func (l *StartupLogger) SetAbortHandler(abort func(phase Phase, err error)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.abort = abort
+}
+
+// Phase returns the current phase
+func (l *StartupLogger) Phase() Phase {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.phase
+}
+
+// SetPhase advances startup to phase, cancelling the previous phase's deadline
+func (l *StartupLogger) SetPhase(phase Phase) error {
+	return l.setPhase(phase, 0)
+}
+
+// SetPhaseWithDeadline advances startup to phase and starts a watchdog that
+// reports a timeout unless the phase advances within timeout
+func (l *StartupLogger) SetPhaseWithDeadline(phase Phase, timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("startup phase timeout must be positive, got %v", timeout)
+	}
+	return l.setPhase(phase, timeout)
+}
+
+// LogOperation logs and records an operation of component that took duration
+// in the current phase. A non-nil err marks the operation failed.
+func (l *StartupLogger) LogOperation(component, name string, duration time.Duration, err error, fields ...zap.Field) {
+	l.mu.Lock()
+	phase := l.phase
+	l.operations = append(l.operations, operation{phase: phase, component: component, duration: duration, failed: err != nil})
+	if err != nil {
+		l.errors = append(l.errors, newEntry(phase, component, name, err.Error()))
+	}
+	l.mu.Unlock()
+
+	fields = append([]zap.Field{
+		zap.Stringer("phase", phase),
+		zap.String("component", component),
+		zap.String("operation", name),
+		zap.Duration("duration", duration),
+	}, fields...)
+	if err != nil {
+		l.logger.Error("Startup operation failed", append(fields, zap.Error(err))...)
+		return
+	}
+	l.logger.Info("Startup operation completed", fields...)
+}
+
+// Warn logs and records a startup warning of component in the current phase
+func (l *StartupLogger) Warn(component, message string, fields ...zap.Field) {
+	l.mu.Lock()
+	phase := l.phase
+	l.warnings = append(l.warnings, newEntry(phase, component, "", message))
+	l.mu.Unlock()
+
+	l.logger.Warn(message, append([]zap.Field{
+		zap.Stringer("phase", phase),
+		zap.String("component", component),
+	}, fields...)...)
+}
+
+// Stop cancels the watchdog of the current phase
+func (l *StartupLogger) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopWatchdog()
+}
+
+func (l *StartupLogger) setPhase(phase Phase, timeout time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := ValidatePhaseTransition(l.phase, phase); err != nil {
+		return err
+	}
+	l.stopWatchdog()
+
+	now := time.Now()
+	l.phases = append(l.phases, phaseSpan{phase: l.phase, start: l.phaseStart, end: now})
+	l.logger.Info("Startup phase completed",
+		zap.Stringer("phase", l.phase),
+		zap.Duration("duration", now.Sub(l.phaseStart)))
+	l.phase = phase
+	l.phaseStart = now
+
+	fields := []zap.Field{zap.Stringer("phase", phase)}
+	if timeout > 0 {
+		generation := l.generation
+		l.watchdog = time.AfterFunc(timeout, func() { l.expire(generation, timeout) })
+		fields = append(fields, zap.Duration("timeout", timeout))
+	}
+	l.logger.Info("Startup phase started", fields...)
+	return nil
+}
+
+// expire reports a phase that outlived its deadline, unless it has advanced
+func (l *StartupLogger) expire(generation uint64, timeout time.Duration) {
+	l.mu.Lock()
+	if generation != l.generation {
+		l.mu.Unlock()
+		return
+	}
+	phase, abort := l.phase, l.abort
+	err := fmt.Errorf("%w: %s did not complete within %v", ErrPhaseTimeout, phase, timeout)
+	l.errors = append(l.errors, newEntry(phase, "startup", "", err.Error()))
+	l.mu.Unlock()
+
+	l.logger.Error("Startup phase timed out",
+		zap.Stringer("phase", phase),
+		zap.Duration("timeout", timeout))
+	if abort != nil {
+		abort(phase, err)
+	}
+}
+
+// stopWatchdog cancels the current watchdog; the caller holds mu
+func (l *StartupLogger) stopWatchdog() {
+	l.generation++
+	if l.watchdog != nil {
+		l.watchdog.Stop()
+		l.watchdog = nil
+	}
+}
@@ -0,0 +1,93 @@
+package startup
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPhaseDeadlineExceeded(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	l := NewStartupLogger(zap.New(core))
+
+	aborted := make(chan error, 1)
+	l.SetAbortHandler(func(phase Phase, err error) {
+		if phase != PhaseConnection {
+			t.Errorf("Expected the connection phase to abort, got %s", phase)
+		}
+		aborted <- err
+	})
+
+	if err := l.SetPhaseWithDeadline(PhaseConnection, 20*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+
+	select {
+	case err := <-aborted:
+		if !errors.Is(err, ErrPhaseTimeout) {
+			t.Errorf("Expected a phase timeout, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the watchdog to abort startup")
+	}
+
+	entries := logs.FilterMessage("Startup phase timed out").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one timeout log, got %d", len(entries))
+	}
+	if phase := entries[0].ContextMap()["phase"]; phase != "connection" {
+		t.Errorf("Expected the timeout to name the connection phase, got %v", phase)
+	}
+}
+
+func TestPhaseAdvanceCancelsWatchdog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	l := NewStartupLogger(zap.New(core))
+	l.SetAbortHandler(func(phase Phase, err error) {
+		t.Errorf("Expected no abort, got %s: %v", phase, err)
+	})
+
+	if err := l.SetPhaseWithDeadline(PhaseConfiguration, 30*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+	if err := l.SetPhaseWithDeadline(PhaseAdapter, 30*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+	if err := l.SetPhase(PhaseRunning); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if n := logs.FilterMessage("Startup phase timed out").Len(); n != 0 {
+		t.Errorf("Expected no timeouts after advancing in time, got %d", n)
+	}
+	if n := logs.FilterMessage("Startup phase completed").Len(); n != 3 {
+		t.Errorf("Expected 3 completed phases, got %d", n)
+	}
+}
+
+func TestValidatePhaseTransition(t *testing.T) {
+	if err := ValidatePhaseTransition(PhaseInitialization, PhaseConfiguration); err != nil {
+		t.Errorf("Expected a forward transition to be valid, got %v", err)
+	}
+	if err := ValidatePhaseTransition(PhaseConnection, PhaseAdapter); err == nil {
+		t.Error("Expected a backward transition to be rejected")
+	}
+	if err := ValidatePhaseTransition(PhaseRunning, Phase(9)); err == nil {
+		t.Error("Expected an unknown phase to be rejected")
+	}
+
+	l := NewStartupLogger(zap.NewNop())
+	if err := l.SetPhase(PhaseInitialization); err == nil {
+		t.Error("Expected staying in the same phase to be rejected")
+	}
+	if err := l.SetPhaseWithDeadline(PhaseAdapter, 0); err == nil {
+		t.Error("Expected a zero timeout to be rejected")
+	}
+	if l.Phase() != PhaseInitialization {
+		t.Errorf("Expected a rejected transition to keep the phase, got %s", l.Phase())
+	}
+}
@@ -0,0 +1,117 @@
+package startup
+
+import (
+	"time"
+)
+
+// StartupReport summarizes how startup went: time spent per phase and per
+// component, and the warnings and errors raised on the way
+type StartupReport struct {
+	StartedAt  time.Time         `json:"started_at"`
+	Total      time.Duration     `json:"total"`
+	Phase      string            `json:"phase"` // Phase reached
+	Failed     bool              `json:"failed"`
+	Phases     []PhaseTiming     `json:"phases"`
+	Components []ComponentTiming `json:"components"`
+	Warnings   []ReportEntry     `json:"warnings,omitempty"`
+	Errors     []ReportEntry     `json:"errors,omitempty"`
+}
+
+// PhaseTiming is the time spent in one phase
+type PhaseTiming struct {
+	Phase      string        `json:"phase"`
+	Duration   time.Duration `json:"duration"`
+	Operations int           `json:"operations"`
+	Failed     int           `json:"failed"`
+}
+
+// ComponentTiming is the time spent in the operations of one component
+type ComponentTiming struct {
+	Component  string        `json:"component"`
+	Duration   time.Duration `json:"duration"`
+	Operations int           `json:"operations"`
+	Failed     int           `json:"failed"`
+}
+
+// ReportEntry is a startup warning or error
+type ReportEntry struct {
+	Time      time.Time `json:"time"`
+	Phase     string    `json:"phase"`
+	Component string    `json:"component"`
+	Operation string    `json:"operation,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// phaseSpan is a completed phase
+type phaseSpan struct {
+	phase      Phase
+	start, end time.Time
+}
+
+// operation is a recorded startup operation
+type operation struct {
+	phase     Phase
+	component string
+	duration  time.Duration
+	failed    bool
+}
+
+// newEntry creates a warning or error entry stamped with the current time
+func newEntry(phase Phase, component, operation, message string) ReportEntry {
+	return ReportEntry{
+		Time:      time.Now(),
+		Phase:     phase.String(),
+		Component: component,
+		Operation: operation,
+		Message:   message,
+	}
+}
+
+// Report aggregates what was logged so far. The current phase is reported with
+// its duration up to now.
+func (l *StartupLogger) Report() StartupReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	report := StartupReport{
+		StartedAt: l.started,
+		Total:     now.Sub(l.started),
+		Phase:     l.phase.String(),
+		Failed:    len(l.errors) > 0,
+		Warnings:  append([]ReportEntry(nil), l.warnings...),
+		Errors:    append([]ReportEntry(nil), l.errors...),
+	}
+
+	spans := append(append([]phaseSpan(nil), l.phases...), phaseSpan{phase: l.phase, start: l.phaseStart, end: now})
+	phaseIndex := make(map[Phase]int, len(spans))
+	for _, span := range spans {
+		phaseIndex[span.phase] = len(report.Phases)
+		report.Phases = append(report.Phases, PhaseTiming{
+			Phase:    span.phase.String(),
+			Duration: span.end.Sub(span.start),
+		})
+	}
+
+	componentIndex := make(map[string]int)
+	for _, op := range l.operations {
+		phase := &report.Phases[phaseIndex[op.phase]]
+		phase.Operations++
+
+		i, ok := componentIndex[op.component]
+		if !ok {
+			i = len(report.Components)
+			componentIndex[op.component] = i
+			report.Components = append(report.Components, ComponentTiming{Component: op.component})
+		}
+		component := &report.Components[i]
+		component.Operations++
+		component.Duration += op.duration
+
+		if op.failed {
+			phase.Failed++
+			component.Failed++
+		}
+	}
+	return report
+}
@@ -0,0 +1,104 @@
+package startup
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestStartupReport(t *testing.T) {
+	l := NewStartupLogger(zap.NewNop())
+
+	// Simulated server startup with a failing optional step
+	l.LogOperation("integrity", "verify binary", 2*time.Millisecond, nil)
+	time.Sleep(10 * time.Millisecond)
+	if err := l.SetPhase(PhaseConfiguration); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+	l.LogOperation("config", "load", 5*time.Millisecond, nil)
+	l.Warn("state", "State directory is read-only")
+	time.Sleep(20 * time.Millisecond)
+	if err := l.SetPhase(PhaseListen); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+	l.LogOperation("control", "start", 3*time.Millisecond, nil)
+	l.LogOperation("tunnel", "start", 7*time.Millisecond, errors.New("address already in use"))
+	l.LogOperation("tunnel", "start", 4*time.Millisecond, nil)
+
+	report := l.Report()
+
+	if report.Phase != "listen" || !report.Failed {
+		t.Errorf("Expected a failed report in the listen phase, got %s (failed %v)", report.Phase, report.Failed)
+	}
+	if len(report.Phases) != 3 {
+		t.Fatalf("Expected 3 phases, got %+v", report.Phases)
+	}
+	for i, want := range []struct {
+		phase      string
+		min        time.Duration
+		operations int
+		failed     int
+	}{
+		{"initialization", 10 * time.Millisecond, 1, 0},
+		{"configuration", 20 * time.Millisecond, 1, 0},
+		{"listen", 0, 3, 1},
+	} {
+		got := report.Phases[i]
+		if got.Phase != want.phase || got.Duration < want.min || got.Operations != want.operations || got.Failed != want.failed {
+			t.Errorf("Expected %s lasting at least %v with %d operations and %d failed, got %+v",
+				want.phase, want.min, want.operations, want.failed, got)
+		}
+	}
+	var phases time.Duration
+	for _, p := range report.Phases {
+		phases += p.Duration
+	}
+	if report.Total < phases {
+		t.Errorf("Expected the total %v to cover the phases' %v", report.Total, phases)
+	}
+
+	if len(report.Components) != 4 {
+		t.Fatalf("Expected 4 components, got %+v", report.Components)
+	}
+	tunnel := report.Components[3]
+	if tunnel.Component != "tunnel" || tunnel.Duration != 11*time.Millisecond || tunnel.Operations != 2 || tunnel.Failed != 1 {
+		t.Errorf("Expected tunnel with 11ms over 2 operations and 1 failure, got %+v", tunnel)
+	}
+
+	if len(report.Errors) != 1 || report.Errors[0].Component != "tunnel" || report.Errors[0].Message != "address already in use" {
+		t.Errorf("Expected the tunnel start error, got %+v", report.Errors)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Phase != "configuration" {
+		t.Errorf("Expected the state warning in the configuration phase, got %+v", report.Warnings)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to marshal report: %v", err)
+	}
+	var decoded StartupReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal report: %v", err)
+	}
+	if len(decoded.Phases) != 3 || decoded.Errors[0].Operation != "start" {
+		t.Errorf("Expected the report to survive JSON, got %s", data)
+	}
+}
+
+func TestStartupReportRecordsTimeout(t *testing.T) {
+	l := NewStartupLogger(zap.NewNop())
+	aborted := make(chan struct{})
+	l.SetAbortHandler(func(Phase, error) { close(aborted) })
+	if err := l.SetPhaseWithDeadline(PhaseConnection, 10*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+	<-aborted
+
+	report := l.Report()
+	if !report.Failed || len(report.Errors) != 1 || report.Errors[0].Phase != "connection" {
+		t.Errorf("Expected the connection timeout in the errors, got %+v", report.Errors)
+	}
+}
@@ -0,0 +1,157 @@
+package throttle
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// DefaultConnectionIdleTimeout is how long a source IP is tracked after its
+// last connection when no idle timeout is configured
+const DefaultConnectionIdleTimeout = time.Minute
+
+// ConnectionSourceStatus is the state of the connection limit for one source IP
+type ConnectionSourceStatus struct {
+	Source       string    `json:"source"`
+	Tokens       float64   `json:"tokens"`
+	Rejected     uint64    `json:"rejected"`
+	LastRejected time.Time `json:"last_rejected,omitempty"`
+}
+
+// ConnectionLimitStatus is a snapshot of the per-source-IP connection limit
+type ConnectionLimitStatus struct {
+	Rate     float64                  `json:"rate"`
+	Burst    float64                  `json:"burst"`
+	Tracked  int                      `json:"tracked"`  // Source IPs currently tracked
+	Rejected uint64                   `json:"rejected"` // Connections rejected since start
+	Sources  []ConnectionSourceStatus `json:"sources"`  // Tracked sources with rejections
+}
+
+// connectionSource is the token bucket of one source IP
+type connectionSource struct {
+	bucket       *TokenBucket
+	lastSeen     time.Time
+	rejected     uint64
+	lastRejected time.Time
+}
+
+// ConnectionLimiter limits how fast each source IP may open connections, with a
+// token bucket per IP. Sources idle for longer than the idle timeout are
+// dropped to bound memory.
+type ConnectionLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	idle      time.Duration
+	sources   map[string]*connectionSource
+	rejected  uint64
+	lastSweep time.Time
+}
+
+// NewConnectionLimiter creates a connection limiter for cfg. The idle timeout
+// is at least the time an empty bucket takes to refill, so that dropping a
+// source never grants it more connections than keeping it would.
+func NewConnectionLimiter(cfg types.ConnectionThrottleConfig) *ConnectionLimiter {
+	return &ConnectionLimiter{
+		rate:      cfg.Rate,
+		burst:     float64(cfg.Burst),
+		idle:      connectionIdleTimeout(cfg),
+		sources:   make(map[string]*connectionSource),
+		lastSweep: time.Now(),
+	}
+}
+
+// connectionIdleTimeout returns the idle timeout of cfg, at least the time an
+// empty bucket takes to refill
+func connectionIdleTimeout(cfg types.ConnectionThrottleConfig) time.Duration {
+	idle := cfg.IdleTimeout
+	if idle <= 0 {
+		idle = DefaultConnectionIdleTimeout
+	}
+	if refill := time.Duration(float64(cfg.Burst) / cfg.Rate * float64(time.Second)); idle < refill {
+		idle = refill
+	}
+	return idle
+}
+
+// Update applies a reloaded configuration to the limiter, including the
+// buckets of the sources already tracked
+func (l *ConnectionLimiter) Update(cfg types.ConnectionThrottleConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate, l.burst, l.idle = cfg.Rate, float64(cfg.Burst), connectionIdleTimeout(cfg)
+	for _, s := range l.sources {
+		s.bucket.Update(l.rate, l.burst)
+	}
+}
+
+// Allow takes a connection token for source and reports whether the connection
+// is within the limit. Rejections are counted per source and in total.
+func (l *ConnectionLimiter) Allow(source string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= l.idle {
+		l.sweep(now)
+	}
+
+	s, ok := l.sources[source]
+	if !ok {
+		s = &connectionSource{bucket: NewTokenBucket(l.rate, l.burst)}
+		l.sources[source] = s
+	}
+	s.lastSeen = now
+
+	if s.bucket.Allow(1) {
+		return true
+	}
+	s.rejected++
+	s.lastRejected = now
+	l.rejected++
+	return false
+}
+
+// sweep drops the sources idle for longer than the idle timeout; the caller
+// holds the limiter lock
+func (l *ConnectionLimiter) sweep(now time.Time) {
+	for source, s := range l.sources {
+		if now.Sub(s.lastSeen) >= l.idle {
+			delete(l.sources, source)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Status returns the limits, the rejection count and the tracked sources that
+// had connections rejected, by name
+func (l *ConnectionLimiter) Status() ConnectionLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := ConnectionLimitStatus{
+		Rate:     l.rate,
+		Burst:    l.burst,
+		Tracked:  len(l.sources),
+		Rejected: l.rejected,
+		Sources:  []ConnectionSourceStatus{},
+	}
+	for source, s := range l.sources {
+		if s.rejected == 0 {
+			continue
+		}
+		status.Sources = append(status.Sources, ConnectionSourceStatus{
+			Source:       source,
+			Tokens:       s.bucket.Tokens(),
+			Rejected:     s.rejected,
+			LastRejected: s.lastRejected,
+		})
+	}
+	sort.Slice(status.Sources, func(i, j int) bool {
+		return status.Sources[i].Source < status.Sources[j].Source
+	})
+	return status
+}
@@ -0,0 +1,87 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestConnectionLimiterRejectsAfterBurst(t *testing.T) {
+	limiter := NewConnectionLimiter(types.ConnectionThrottleConfig{
+		Enabled: true,
+		Rate:    1, // 1 connection/s
+		Burst:   3,
+	})
+
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("192.0.2.10") {
+			t.Fatalf("Expected connection %d within the burst to be allowed", i+1)
+		}
+	}
+	if limiter.Allow("192.0.2.10") {
+		t.Error("Expected a connection past the burst to be rejected")
+	}
+
+	// Each source has its own bucket
+	if !limiter.Allow("192.0.2.20") {
+		t.Error("Expected another source to be allowed")
+	}
+
+	status := limiter.Status()
+	if status.Tracked != 2 || status.Rejected != 1 {
+		t.Errorf("Expected 2 tracked sources and 1 rejection, got %+v", status)
+	}
+	if len(status.Sources) != 1 || status.Sources[0].Source != "192.0.2.10" || status.Sources[0].Rejected != 1 {
+		t.Errorf("Expected only the rejected source to be listed, got %+v", status.Sources)
+	}
+}
+
+func TestConnectionLimiterDropsIdleSources(t *testing.T) {
+	limiter := NewConnectionLimiter(types.ConnectionThrottleConfig{
+		Enabled:     true,
+		Rate:        1000,
+		Burst:       1,
+		IdleTimeout: 20 * time.Millisecond,
+	})
+
+	limiter.Allow("192.0.2.10")
+	limiter.Allow("192.0.2.20")
+	time.Sleep(30 * time.Millisecond)
+
+	// The next connection sweeps the idle sources before tracking its own
+	limiter.Allow("192.0.2.30")
+	if tracked := limiter.Status().Tracked; tracked != 1 {
+		t.Errorf("Expected idle sources to be dropped, %d still tracked", tracked)
+	}
+}
+
+func TestConnectionLimiterIdleTimeoutCoversRefill(t *testing.T) {
+	limiter := NewConnectionLimiter(types.ConnectionThrottleConfig{
+		Enabled:     true,
+		Rate:        0.01, // Empty bucket refills in 1000s
+		Burst:       10,
+		IdleTimeout: time.Second,
+	})
+	if limiter.idle < 1000*time.Second {
+		t.Errorf("Expected the idle timeout to cover a full refill, got %v", limiter.idle)
+	}
+}
+
+func TestRegistryReportsConnectionLimit(t *testing.T) {
+	registry := NewRegistry(types.ThrottleConfig{})
+	if registry.Connections() != nil || registry.Status().Connections != nil {
+		t.Error("Expected no connection limiter when the connection limit is disabled")
+	}
+
+	registry = NewRegistry(types.ThrottleConfig{
+		Connections: types.ConnectionThrottleConfig{Enabled: true, Rate: 1, Burst: 1},
+	})
+	registry.Connections().Allow("192.0.2.10")
+	registry.Connections().Allow("192.0.2.10")
+
+	status := registry.Status()
+	if status.Connections == nil || status.Connections.Rejected != 1 {
+		t.Errorf("Expected the status to report 1 rejected connection, got %+v", status.Connections)
+	}
+}
@@ -3,9 +3,8 @@ package throttle
 import (
 	"bytes"
 	"testing"
-	"time"
 
-	"github.com/o3willard-AI/SSSonector/internal/config"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
 	"go.uber.org/zap"
 )
 
@@ -13,24 +12,21 @@ func TestDynamicLimiter(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 
 	// Create a base config
-	baseConfig := &config.AppConfig{
-		Throttle: config.ThrottleConfig{
-			Enabled: true,
-			Rate:    1024 * 1024, // 1MB/s
-			Burst:   1024 * 100,  // 100KB burst
-		},
+	baseConfig := &types.ThrottleConfig{
+		Enabled: true,
+		Rate:    1024 * 1024, // 1MB/s
+		Burst:   1024 * 100,  // 100KB burst
 	}
 
-	// Create base limiter
-	reader := bytes.NewBuffer(nil)
-	writer := bytes.NewBuffer(nil)
-	baseLimiter := NewLimiter(baseConfig, reader, writer, logger)
+	newLimiter := func() *DynamicLimiter {
+		return NewDynamicLimiter(baseConfig, bytes.NewBuffer(nil), bytes.NewBuffer(nil), logger)
+	}
 
 	t.Run("Initial Configuration", func(t *testing.T) {
-		dl := NewDynamicLimiter(baseConfig, baseLimiter, logger)
+		dl := newLimiter()
 
-		expectedMinRate := float64(baseConfig.Throttle.Rate) / 2
-		expectedMaxRate := float64(baseConfig.Throttle.Rate) * 2
+		expectedMinRate := baseConfig.Rate * 0.5
+		expectedMaxRate := baseConfig.Rate * 1.5
 
 		if dl.minRate != expectedMinRate {
 			t.Errorf("Expected min rate %f, got %f", expectedMinRate, dl.minRate)
@@ -38,126 +34,83 @@ func TestDynamicLimiter(t *testing.T) {
 		if dl.maxRate != expectedMaxRate {
 			t.Errorf("Expected max rate %f, got %f", expectedMaxRate, dl.maxRate)
 		}
+		if rate, burst := dl.GetCurrentConfig(); rate != baseConfig.Rate || burst != int64(baseConfig.Burst) {
+			t.Errorf("Expected rate %f and burst %d, got %f and %d", baseConfig.Rate, baseConfig.Burst, rate, burst)
+		}
 	})
 
 	t.Run("Rate Increase", func(t *testing.T) {
-		dl := NewDynamicLimiter(baseConfig, baseLimiter, logger)
-		initialRate := float64(baseConfig.Throttle.Rate)
-
-		// Increase by 50%
-		dl.IncreaseRate(50)
+		dl := newLimiter()
 
-		inMetrics, _ := dl.GetMetrics()
-		newRate := inMetrics.Rate
+		// Utilization well below the target raises the rate by one step
+		dl.adjustRateLimits()
 
-		expectedRate := initialRate * 1.5
-		if expectedRate > dl.maxRate {
-			expectedRate = dl.maxRate
-		}
-
-		if abs(newRate-expectedRate) > 0.1 {
-			t.Errorf("Expected rate around %f, got %f", expectedRate, newRate)
+		rate, _ := dl.GetCurrentConfig()
+		expectedRate := baseConfig.Rate * (1 + dl.adjustmentStep)
+		if abs(rate-expectedRate) > 0.1 {
+			t.Errorf("Expected rate around %f, got %f", expectedRate, rate)
 		}
 	})
 
 	t.Run("Rate Decrease", func(t *testing.T) {
-		dl := NewDynamicLimiter(baseConfig, baseLimiter, logger)
-		initialRate := float64(baseConfig.Throttle.Rate)
-
-		// Decrease by 50%
-		dl.DecreaseRate(50)
+		dl := newLimiter()
 
-		inMetrics, _ := dl.GetMetrics()
-		newRate := inMetrics.Rate
+		// Utilization well above the target lowers the rate by one step
+		dl.metrics.ActualUtilization = 1
+		dl.applyAdjustment(dl.calculateAdjustment())
 
-		expectedRate := initialRate * 0.5
-		if expectedRate < dl.minRate {
-			expectedRate = dl.minRate
-		}
-
-		if abs(newRate-expectedRate) > 0.1 {
-			t.Errorf("Expected rate around %f, got %f", expectedRate, newRate)
+		rate, _ := dl.GetCurrentConfig()
+		expectedRate := baseConfig.Rate * (1 - dl.adjustmentStep)
+		if abs(rate-expectedRate) > 0.1 {
+			t.Errorf("Expected rate around %f, got %f", expectedRate, rate)
 		}
 	})
 
 	t.Run("Rate Bounds", func(t *testing.T) {
-		dl := NewDynamicLimiter(baseConfig, baseLimiter, logger)
+		dl := newLimiter()
 
 		// Try to increase beyond max rate
-		dl.IncreaseRate(1000) // Large increase
-
-		inMetrics, _ := dl.GetMetrics()
-		if inMetrics.Rate > dl.maxRate {
-			t.Errorf("Rate %f exceeded maximum %f", inMetrics.Rate, dl.maxRate)
+		for i := 0; i < 20; i++ {
+			dl.adjustRateLimits()
+		}
+		if rate, _ := dl.GetCurrentConfig(); rate > dl.maxRate+0.1 {
+			t.Errorf("Rate %f exceeded maximum %f", rate, dl.maxRate)
 		}
 
 		// Reset and try to decrease below min rate
-		dl = NewDynamicLimiter(baseConfig, baseLimiter, logger)
-		dl.DecreaseRate(90) // Large decrease
-
-		inMetrics, _ = dl.GetMetrics()
-		if inMetrics.Rate < dl.minRate {
-			t.Errorf("Rate %f went below minimum %f", inMetrics.Rate, dl.minRate)
+		dl = newLimiter()
+		for i := 0; i < 20; i++ {
+			dl.metrics.ActualUtilization = 1
+			dl.applyAdjustment(dl.calculateAdjustment())
+		}
+		if rate, _ := dl.GetCurrentConfig(); rate < dl.minRate-0.1 {
+			t.Errorf("Rate %f went below minimum %f", rate, dl.minRate)
 		}
 	})
 
 	t.Run("Adjustment Count", func(t *testing.T) {
-		dl := NewDynamicLimiter(baseConfig, baseLimiter, logger)
+		dl := newLimiter()
 
-		if count := dl.GetAdjustCount(); count != 0 {
+		if count := dl.GetDynamicMetrics().AdjustmentCount; count != 0 {
 			t.Errorf("Expected initial adjust count 0, got %d", count)
 		}
 
-		dl.IncreaseRate(10)
-		if count := dl.GetAdjustCount(); count != 1 {
+		dl.adjustRateLimits()
+		if count := dl.GetDynamicMetrics().AdjustmentCount; count != 1 {
 			t.Errorf("Expected adjust count 1, got %d", count)
 		}
-
-		dl.DecreaseRate(10)
-		if count := dl.GetAdjustCount(); count != 2 {
-			t.Errorf("Expected adjust count 2, got %d", count)
-		}
-
-		dl.ResetAdjustCount()
-		if count := dl.GetAdjustCount(); count != 0 {
-			t.Errorf("Expected reset adjust count 0, got %d", count)
-		}
 	})
 
-	t.Run("Rate Adjustment Cooldown", func(t *testing.T) {
-		dl := NewDynamicLimiter(baseConfig, baseLimiter, logger)
-		initialRate := float64(baseConfig.Throttle.Rate)
-
-		// First adjustment should work
-		dl.IncreaseRate(50)
-		inMetrics, _ := dl.GetMetrics()
-		firstAdjustRate := inMetrics.Rate
-
-		// Wait a bit but not enough for cooldown
-		time.Sleep(100 * time.Millisecond)
+	t.Run("Target Utilization", func(t *testing.T) {
+		dl := newLimiter()
 
-		// Immediate second adjustment should be ignored
-		dl.IncreaseRate(50)
-		inMetrics, _ = dl.GetMetrics()
-		secondAdjustRate := inMetrics.Rate
-
-		if firstAdjustRate == initialRate {
-			t.Error("First adjustment had no effect")
-		}
-		if secondAdjustRate != firstAdjustRate {
-			t.Error("Second adjustment should have been ignored due to cooldown")
+		if err := dl.SetTargetUtilization(0.5); err != nil {
+			t.Errorf("Expected target utilization 0.5 to be accepted, got %v", err)
 		}
-
-		// Wait for cooldown
-		time.Sleep(time.Second)
-
-		// Third adjustment should work
-		dl.IncreaseRate(50)
-		inMetrics, _ = dl.GetMetrics()
-		thirdAdjustRate := inMetrics.Rate
-
-		if thirdAdjustRate == secondAdjustRate {
-			t.Error("Third adjustment should have worked after cooldown")
+		for _, utilization := range []float64{0, 1.5} {
+			if err := dl.SetTargetUtilization(utilization); err == nil {
+				t.Errorf("Expected target utilization %f to be rejected", utilization)
+			}
 		}
 	})
 }
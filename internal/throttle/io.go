@@ -8,17 +8,19 @@ import (
 
 // ThrottledReader implements a rate-limited io.Reader
 type ThrottledReader struct {
-	reader io.Reader
-	pool   *BufferPool
-	logger *zap.Logger
+	reader  io.Reader
+	limiter *Limiter
+	pool    *BufferPool
+	logger  *zap.Logger
 }
 
 // NewThrottledReader creates a new throttled reader
 func NewThrottledReader(reader io.Reader, limiter *Limiter, logger *zap.Logger) *ThrottledReader {
 	return &ThrottledReader{
-		reader: reader,
-		pool:   limiter.bufferPool,
-		logger: logger,
+		reader:  reader,
+		limiter: limiter,
+		pool:    limiter.bufferPool,
+		logger:  logger,
 	}
 }
 
@@ -34,6 +36,11 @@ func (r *ThrottledReader) Read(p []byte) (n int, err error) {
 		return 0, err
 	}
 
+	// Wait for tokens for the bytes read
+	if err := r.limiter.Wait(true, n); err != nil {
+		return 0, err
+	}
+
 	// Copy to output buffer
 	copy(p, buf[:n])
 	return n, nil
@@ -41,22 +48,29 @@ func (r *ThrottledReader) Read(p []byte) (n int, err error) {
 
 // ThrottledWriter implements a rate-limited io.Writer
 type ThrottledWriter struct {
-	writer io.Writer
-	pool   *BufferPool
-	logger *zap.Logger
+	writer  io.Writer
+	limiter *Limiter
+	pool    *BufferPool
+	logger  *zap.Logger
 }
 
 // NewThrottledWriter creates a new throttled writer
 func NewThrottledWriter(writer io.Writer, limiter *Limiter, logger *zap.Logger) *ThrottledWriter {
 	return &ThrottledWriter{
-		writer: writer,
-		pool:   limiter.bufferPool,
-		logger: logger,
+		writer:  writer,
+		limiter: limiter,
+		pool:    limiter.bufferPool,
+		logger:  logger,
 	}
 }
 
 // Write implements io.Writer
 func (w *ThrottledWriter) Write(p []byte) (n int, err error) {
+	// Wait for tokens for the bytes to write
+	if err := w.limiter.Wait(false, len(p)); err != nil {
+		return 0, err
+	}
+
 	// Get buffer from pool
 	buf := w.pool.Get(len(p))
 	defer w.pool.Put(buf)
@@ -28,21 +28,27 @@ type Limiter struct {
 type LimiterMetrics struct {
 	Rate      float64
 	Burst     float64
-	LimitHits uint64
+	LimitHits uint64 // Waits the rate limit delayed or timed out
 }
 
 // NewLimiter creates a new rate limiter
 func NewLimiter(cfg *types.AppConfig, reader io.Reader, writer io.Writer, logger *zap.Logger) *Limiter {
+	// A missing configuration leaves the limiter disabled
+	var throttleCfg types.ThrottleConfig
+	if cfg != nil {
+		throttleCfg = cfg.Throttle
+	}
+
 	l := &Limiter{
-		enabled: cfg.Throttle.Enabled,
+		enabled: throttleCfg.Enabled,
 		reader:  reader,
 		writer:  writer,
 		logger:  logger,
 	}
 
 	// Initialize token buckets with TCP overhead adjustment
-	rate := float64(cfg.Throttle.Rate) * tcpOverheadFactor
-	burst := float64(cfg.Throttle.Burst) * tcpOverheadFactor
+	rate := float64(throttleCfg.Rate) * tcpOverheadFactor
+	burst := float64(throttleCfg.Burst) * tcpOverheadFactor
 
 	l.inBucket = NewTokenBucket(rate, burst)
 	l.outBucket = NewTokenBucket(rate, burst)
@@ -116,17 +122,21 @@ func (l *Limiter) Wait(isRead bool, size int) error {
 	}
 
 	timeout := time.After(defaultTimeout)
-	done := make(chan struct{})
+	done := make(chan time.Duration, 1)
 
 	go func() {
-		bucket.Wait(float64(size))
-		close(done)
+		done <- bucket.Wait(float64(size))
 	}()
 
 	select {
-	case <-done:
+	case waited := <-done:
+		if waited > 0 {
+			l.recordLimitHit(isRead)
+		}
 		return nil
 	case <-timeout:
+		l.recordLimitHit(isRead)
+
 		err := fmt.Errorf("timeout waiting for %d tokens after %v", size, defaultTimeout)
 		l.logger.Warn("Rate limit wait timeout",
 			zap.Bool("read", isRead),
@@ -137,6 +147,17 @@ func (l *Limiter) Wait(isRead bool, size int) error {
 	}
 }
 
+// recordLimitHit counts a wait the rate limit delayed or timed out
+func (l *Limiter) recordLimitHit(isRead bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if isRead {
+		l.inMetrics.LimitHits++
+	} else {
+		l.outMetrics.LimitHits++
+	}
+}
+
 // Update updates the limiter configuration
 func (l *Limiter) Update(cfg *types.AppConfig) {
 	l.mu.Lock()
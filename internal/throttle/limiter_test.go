@@ -30,8 +30,8 @@ func TestLimiterWithTCPOverhead(t *testing.T) {
 		t.Errorf("Expected effective rate %f, got %f", expectedRate, inMetrics.Rate)
 	}
 
-	// Test burst is 100ms worth of data
-	expectedBurst := expectedRate * 0.1 // 100ms
+	// Test burst includes the same overhead
+	expectedBurst := float64(cfg.Throttle.Burst) * tcpOverheadFactor
 	if inMetrics.Burst != expectedBurst {
 		t.Errorf("Expected burst %f, got %f", expectedBurst, inMetrics.Burst)
 	}
@@ -43,7 +43,7 @@ func TestLimiterRateEnforcement(t *testing.T) {
 		Throttle: config.ThrottleConfig{
 			Enabled: true,
 			Rate:    100,  // 100 bytes/s
-			Burst:   1000, // Large enough burst to not interfere
+			Burst:   1000, // Spent up front below
 		},
 	}
 
@@ -53,8 +53,18 @@ func TestLimiterRateEnforcement(t *testing.T) {
 	writer := &bytes.Buffer{}
 	limiter := NewLimiter(cfg, reader, writer, logger)
 
-	// Test read rate limiting
+	// The full burst is available at once
+	burst := int(float64(cfg.Throttle.Burst) * tcpOverheadFactor)
 	start := time.Now()
+	if err := limiter.Wait(true, burst); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected the burst not to wait, waited %v", elapsed)
+	}
+
+	// Test read rate limiting once the burst is spent
+	start = time.Now()
 	err := limiter.Wait(true, 200) // Wait for 200 bytes
 	elapsed := time.Since(start)
 
@@ -62,8 +72,9 @@ func TestLimiterRateEnforcement(t *testing.T) {
 		t.Fatalf("Wait failed: %v", err)
 	}
 
-	// Should take at least 1.8 seconds to read 200 bytes at 100 bytes/s
-	minExpectedDuration := 1800 * time.Millisecond
+	// Should take about 1.8 seconds to read 200 bytes at 110 bytes/s
+	// (100 bytes/s plus overhead), less a little refilled since the burst
+	minExpectedDuration := 1700 * time.Millisecond
 	if elapsed < minExpectedDuration {
 		t.Errorf("Rate limit not enforced: waited %v, expected at least %v", elapsed, minExpectedDuration)
 	}
@@ -158,7 +169,7 @@ func TestLimiterUpdate(t *testing.T) {
 		t.Errorf("Expected updated rate %f, got %f", expectedRate, inMetrics.Rate)
 	}
 
-	expectedBurst := expectedRate * 0.1 // 100ms
+	expectedBurst := float64(newCfg.Throttle.Burst) * tcpOverheadFactor
 	if inMetrics.Burst != expectedBurst {
 		t.Errorf("Expected updated burst %f, got %f", expectedBurst, inMetrics.Burst)
 	}
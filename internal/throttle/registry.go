@@ -0,0 +1,276 @@
+package throttle
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+const (
+	// GlobalScope is the scope of the limit shared by all clients
+	GlobalScope = "global"
+
+	// maxThrottleEvents is the number of recent throttle events kept
+	maxThrottleEvents = 32
+
+	// throttledWindow is how long after its last throttle event a scope is
+	// reported as currently throttled
+	throttledWindow = 10 * time.Second
+)
+
+// Directions of the traffic in a scope, each limited to the rate on its own
+const (
+	DirectionIn  = "in"  // Read from the client
+	DirectionOut = "out" // Read from the tunnel, towards the client
+)
+
+// ThrottleEvent records one request delayed by a rate limit
+type ThrottleEvent struct {
+	Time      time.Time     `json:"time"`
+	Scope     string        `json:"scope"`
+	Direction string        `json:"direction"`
+	Size      int           `json:"size"`
+	Waited    time.Duration `json:"waited"`
+}
+
+// ScopeStatus is the state of the limiter for one scope
+type ScopeStatus struct {
+	Scope         string    `json:"scope"`
+	Rate          float64   `json:"rate"`
+	Burst         float64   `json:"burst"`
+	InTokens      float64   `json:"in_tokens"`
+	OutTokens     float64   `json:"out_tokens"`
+	ThrottleCount uint64    `json:"throttle_count"`
+	LastThrottled time.Time `json:"last_throttled,omitempty"`
+	Throttled     bool      `json:"throttled"` // Throttled within the last throttledWindow
+}
+
+// RateLimitStatus is a snapshot of all rate limit scopes
+type RateLimitStatus struct {
+	Enabled   bool            `json:"enabled"`
+	PerClient bool            `json:"per_client"`
+	Scopes    []ScopeStatus   `json:"scopes"`
+	Events    []ThrottleEvent `json:"events"` // Most recent last
+
+	// Connections is the per-source-IP connection limit, when enabled
+	Connections *ConnectionLimitStatus `json:"connections,omitempty"`
+}
+
+// scope is a token bucket per direction with their throttle statistics
+type scope struct {
+	inBucket      *TokenBucket
+	outBucket     *TokenBucket
+	throttleCount uint64
+	lastThrottled time.Time
+	refs          int // Acquire calls not yet released, for client scopes
+}
+
+// newScope creates a scope limiting each direction to rate and burst
+func newScope(rate, burst float64) *scope {
+	return &scope{
+		inBucket:  NewTokenBucket(rate, burst),
+		outBucket: NewTokenBucket(rate, burst),
+	}
+}
+
+// bucket returns the bucket of a direction
+func (s *scope) bucket(in bool) *TokenBucket {
+	if in {
+		return s.inBucket
+	}
+	return s.outBucket
+}
+
+// Registry applies the global rate limit and, with PerClient, a limit per
+// client, and tracks throttling in each scope. Each direction of the traffic
+// is limited on its own, so that traffic one way does not slow the other.
+type Registry struct {
+	mu      sync.Mutex
+	config  types.ThrottleConfig
+	global  *scope
+	clients map[string]*scope
+	events  []ThrottleEvent
+
+	// connections limits new connections per source IP, if enabled
+	connections *ConnectionLimiter
+}
+
+// NewRegistry creates a rate limiter registry for cfg
+func NewRegistry(cfg types.ThrottleConfig) *Registry {
+	r := &Registry{
+		config:  cfg,
+		global:  newScope(cfg.Rate, float64(cfg.Burst)),
+		clients: make(map[string]*scope),
+	}
+	if cfg.Connections.Enabled {
+		r.connections = NewConnectionLimiter(cfg.Connections)
+	}
+	return r
+}
+
+// Update applies a reloaded configuration. The buckets of every scope take the
+// new rate and burst at once, and the connection limit its new settings.
+// Enabling or disabling the connection limit takes a restart.
+func (r *Registry) Update(cfg types.ThrottleConfig) {
+	r.mu.Lock()
+	r.config = cfg
+	scopes := []*scope{r.global}
+	for _, s := range r.clients {
+		scopes = append(scopes, s)
+	}
+	r.mu.Unlock()
+
+	// Buckets are updated without the registry lock, which take acquires
+	// after waiting on a bucket
+	for _, s := range scopes {
+		s.inBucket.Update(cfg.Rate, float64(cfg.Burst))
+		s.outBucket.Update(cfg.Rate, float64(cfg.Burst))
+	}
+	if r.connections != nil && cfg.Connections.Enabled {
+		r.connections.Update(cfg.Connections)
+	}
+}
+
+// Connections returns the per-source-IP connection limiter, or nil when the
+// connection limit is disabled
+func (r *Registry) Connections() *ConnectionLimiter {
+	return r.connections
+}
+
+// Wait takes size tokens for traffic in direction in (read from the client)
+// or out for client from the client's scope, with PerClient, and then from the
+// global scope, and returns the total time spent waiting. The client's own
+// limit is applied first so that a single busy client is reported as throttled
+// rather than the global scope.
+func (r *Registry) Wait(client string, in bool, size int) time.Duration {
+	r.mu.Lock()
+	enabled, perClient := r.config.Enabled, r.config.PerClient
+	r.mu.Unlock()
+	if !enabled {
+		return 0
+	}
+
+	var waited time.Duration
+	if perClient && client != "" {
+		waited += r.take(client, r.clientScope(client), in, size)
+	}
+	return waited + r.take(GlobalScope, r.global, in, size)
+}
+
+// Acquire holds the scope of client for a connection sending traffic as the
+// client, so that the connections of one client share its scope. Each call
+// must be paired with a Release.
+func (r *Registry) Acquire(client string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scopeLocked(client).refs++
+}
+
+// Release drops a hold taken by Acquire, and the scope of client once its
+// last connection has released it
+func (r *Registry) Release(client string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.clients[client]
+	if !ok {
+		return
+	}
+	if s.refs--; s.refs <= 0 {
+		delete(r.clients, client)
+	}
+}
+
+// clientScope returns the scope of client, creating it on first use
+func (r *Registry) clientScope(client string) *scope {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.scopeLocked(client)
+}
+
+// scopeLocked returns the scope of client, creating it on first use; the
+// caller holds mu
+func (r *Registry) scopeLocked(client string) *scope {
+	s, ok := r.clients[client]
+	if !ok {
+		s = newScope(r.config.Rate, float64(r.config.Burst))
+		r.clients[client] = s
+	}
+	return s
+}
+
+// take waits on the bucket of s for the direction and records a throttle
+// event if it had to wait
+func (r *Registry) take(name string, s *scope, in bool, size int) time.Duration {
+	waited := s.bucket(in).Wait(float64(size))
+	if waited == 0 {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	s.throttleCount++
+	s.lastThrottled = now
+	direction := DirectionOut
+	if in {
+		direction = DirectionIn
+	}
+	r.events = append(r.events, ThrottleEvent{Time: now, Scope: name, Direction: direction, Size: size, Waited: waited})
+	if len(r.events) > maxThrottleEvents {
+		r.events = r.events[len(r.events)-maxThrottleEvents:]
+	}
+	return waited
+}
+
+// Status returns the state of every scope, global first and then clients by name
+func (r *Registry) Status() RateLimitStatus {
+	r.mu.Lock()
+	status := RateLimitStatus{
+		Enabled:   r.config.Enabled,
+		PerClient: r.config.PerClient,
+		Scopes:    []ScopeStatus{r.global.stats(GlobalScope)},
+		Events:    append([]ThrottleEvent(nil), r.events...),
+	}
+	scopes := []*scope{r.global}
+
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		status.Scopes = append(status.Scopes, r.clients[name].stats(name))
+		scopes = append(scopes, r.clients[name])
+	}
+	r.mu.Unlock()
+
+	// Bucket state is read without the registry lock, which take acquires
+	// after waiting on a bucket
+	now := time.Now()
+	for i, s := range scopes {
+		sc := &status.Scopes[i]
+		sc.Rate, sc.Burst = s.inBucket.Limits()
+		sc.InTokens = s.inBucket.Tokens()
+		sc.OutTokens = s.outBucket.Tokens()
+		sc.Throttled = !sc.LastThrottled.IsZero() && now.Sub(sc.LastThrottled) < throttledWindow
+	}
+
+	if r.connections != nil {
+		connections := r.connections.Status()
+		status.Connections = &connections
+	}
+	return status
+}
+
+// stats returns the throttle statistics of the scope; the caller holds the registry lock
+func (s *scope) stats(name string) ScopeStatus {
+	return ScopeStatus{
+		Scope:         name,
+		ThrottleCount: s.throttleCount,
+		LastThrottled: s.lastThrottled,
+	}
+}
@@ -0,0 +1,176 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+func TestRegistryReportsThrottling(t *testing.T) {
+	registry := NewRegistry(types.ThrottleConfig{
+		Enabled:   true,
+		Rate:      1000, // 1000 bytes/s
+		Burst:     100,
+		PerClient: true,
+	})
+
+	// The burst is free; the next 50 bytes wait about 50ms for tokens
+	if waited := registry.Wait("10.0.0.2:5000", true, 100); waited != 0 {
+		t.Errorf("Expected the burst to pass without waiting, waited %v", waited)
+	}
+	if waited := registry.Wait("10.0.0.2:5000", true, 50); waited == 0 {
+		t.Error("Expected a request past the burst to wait")
+	}
+
+	status := registry.Status()
+	if !status.Enabled || !status.PerClient {
+		t.Errorf("Expected an enabled per-client status, got %+v", status)
+	}
+	if len(status.Scopes) != 2 {
+		t.Fatalf("Expected global and client scopes, got %+v", status.Scopes)
+	}
+
+	global, client := status.Scopes[0], status.Scopes[1]
+	if global.Scope != GlobalScope || client.Scope != "10.0.0.2:5000" {
+		t.Errorf("Expected the global scope and then the client, got %s and %s", global.Scope, client.Scope)
+	}
+	if client.ThrottleCount == 0 || !client.Throttled {
+		t.Errorf("Expected the client scope to be throttled, got %+v", client)
+	}
+	if global.Throttled {
+		t.Errorf("Expected the client limit to absorb the wait, got a throttled global scope %+v", global)
+	}
+	if client.Rate != 1000 || client.Burst != 100 {
+		t.Errorf("Expected rate 1000 and burst 100, got %v and %v", client.Rate, client.Burst)
+	}
+	if len(status.Events) == 0 || status.Events[0].Scope != client.Scope {
+		t.Errorf("Expected a throttle event for the client, got %+v", status.Events)
+	}
+
+	registry.Acquire("10.0.0.2:5000")
+	registry.Release("10.0.0.2:5000")
+	if scopes := registry.Status().Scopes; len(scopes) != 1 {
+		t.Errorf("Expected only the global scope after releasing the client, got %+v", scopes)
+	}
+}
+
+func TestRegistryScopeHeldUntilLastRelease(t *testing.T) {
+	registry := NewRegistry(types.ThrottleConfig{Enabled: true, Rate: 1000, Burst: 100, PerClient: true})
+
+	// Two connections of one client share its scope
+	registry.Acquire("10.0.0.2")
+	registry.Acquire("10.0.0.2")
+	registry.Wait("10.0.0.2", true, 100)
+
+	// The first connection ending keeps the scope and its spent burst
+	registry.Release("10.0.0.2")
+	status := registry.Status()
+	if len(status.Scopes) != 2 {
+		t.Fatalf("Expected the client scope to survive its first release, got %+v", status.Scopes)
+	}
+	if tokens := status.Scopes[1].InTokens; tokens > 50 {
+		t.Errorf("Expected the remaining connection to keep the spent burst, got %v tokens", tokens)
+	}
+
+	registry.Release("10.0.0.2")
+	if scopes := registry.Status().Scopes; len(scopes) != 1 {
+		t.Errorf("Expected the client scope to be dropped after its last release, got %+v", scopes)
+	}
+
+	// A release without an acquire is ignored
+	registry.Release("10.0.0.3")
+}
+
+func TestRegistryDisabled(t *testing.T) {
+	registry := NewRegistry(types.ThrottleConfig{Rate: 1, Burst: 1})
+	if waited := registry.Wait("10.0.0.2:5000", true, 1000); waited != 0 {
+		t.Errorf("Expected a disabled registry not to wait, waited %v", waited)
+	}
+	if status := registry.Status(); status.Enabled || status.Scopes[0].ThrottleCount != 0 {
+		t.Errorf("Expected no throttling while disabled, got %+v", status)
+	}
+}
+
+func TestRegistryStatusWhileWaiting(t *testing.T) {
+	registry := NewRegistry(types.ThrottleConfig{Enabled: true, Rate: 100, Burst: 1})
+	registry.Wait("", true, 1)
+
+	// The next byte waits about 10s for its token
+	go registry.Wait("", true, 1000)
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan RateLimitStatus)
+	go func() { done <- registry.Status() }()
+	select {
+	case status := <-done:
+		if tokens := status.Scopes[0].InTokens; tokens != 0 {
+			t.Errorf("Expected no tokens while a waiter pays them off, got %v", tokens)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Status not to block while a waiter sleeps")
+	}
+}
+
+func TestRegistryLimitsDirectionsSeparately(t *testing.T) {
+	registry := NewRegistry(types.ThrottleConfig{Enabled: true, Rate: 1000, Burst: 100})
+
+	// Each direction has its own burst
+	if waited := registry.Wait("", true, 100); waited != 0 {
+		t.Errorf("Expected the inbound burst to pass without waiting, waited %v", waited)
+	}
+	if waited := registry.Wait("", false, 100); waited != 0 {
+		t.Errorf("Expected inbound traffic not to slow outbound traffic, waited %v", waited)
+	}
+
+	status := registry.Status()
+	if len(status.Events) != 0 {
+		t.Errorf("Expected no throttle events, got %+v", status.Events)
+	}
+	if in, out := status.Scopes[0].InTokens, status.Scopes[0].OutTokens; in > 10 || out > 10 {
+		t.Errorf("Expected both directions to have used their burst, got %v in and %v out", in, out)
+	}
+}
+
+func TestRegistryUpdate(t *testing.T) {
+	registry := NewRegistry(types.ThrottleConfig{
+		Enabled:     true,
+		Rate:        1000,
+		Burst:       100,
+		PerClient:   true,
+		Connections: types.ConnectionThrottleConfig{Enabled: true, Rate: 1, Burst: 1},
+	})
+	registry.Wait("10.0.0.2:5000", true, 1)
+
+	registry.Update(types.ThrottleConfig{
+		Enabled:     true,
+		Rate:        5000,
+		Burst:       500,
+		PerClient:   true,
+		Connections: types.ConnectionThrottleConfig{Enabled: true, Rate: 10, Burst: 20},
+	})
+
+	// Existing scopes take the new limits
+	status := registry.Status()
+	for _, scope := range status.Scopes {
+		if scope.Rate != 5000 || scope.Burst != 500 {
+			t.Errorf("Expected scope %s to take rate 5000 and burst 500, got %v and %v", scope.Scope, scope.Rate, scope.Burst)
+		}
+	}
+	if status.Connections.Rate != 10 || status.Connections.Burst != 20 {
+		t.Errorf("Expected the connection limit to take rate 10 and burst 20, got %+v", status.Connections)
+	}
+
+	// Disabling the limit stops throttling at once
+	registry.Update(types.ThrottleConfig{Rate: 1, Burst: 1})
+	if waited := registry.Wait("10.0.0.2:5000", true, 1000); waited != 0 {
+		t.Errorf("Expected a disabled registry not to wait, waited %v", waited)
+	}
+}
+
+func TestTokenBucketWithoutRate(t *testing.T) {
+	bucket := NewTokenBucket(0, 10)
+	if waited := bucket.Wait(100); waited != 0 {
+		t.Errorf("Expected a bucket without a rate not to wait, waited %v", waited)
+	}
+}
@@ -36,22 +36,64 @@ func (b *TokenBucket) Update(rate, burst float64) {
 	}
 }
 
-// Wait waits until enough tokens are available
-func (b *TokenBucket) Wait(size float64) {
+// Wait waits until enough tokens are available and returns how long it had to
+// wait; zero means the request was not throttled. The tokens are reserved up
+// front, leaving the bucket in debt, so that the sleep does not hold the lock.
+// A bucket without a positive rate never refills, so it does not limit.
+func (b *TokenBucket) Wait(size float64) time.Duration {
+	b.mu.Lock()
+	if b.rate <= 0 {
+		b.mu.Unlock()
+		return 0
+	}
+	b.refill(time.Now())
+	b.tokens -= size
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return wait
+}
+
+// Allow takes size tokens if they are available and reports whether it did,
+// without waiting
+func (b *TokenBucket) Allow(size float64) bool {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	now := time.Now()
+	b.refill(time.Now())
+	if b.tokens < size {
+		return false
+	}
+	b.tokens -= size
+	return true
+}
+
+// Tokens returns the tokens currently available, zero while waiters are
+// paying off reserved tokens
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	return max(b.tokens, 0)
+}
+
+// Limits returns the rate and burst size
+func (b *TokenBucket) Limits() (rate, burst float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rate, b.burst
+}
+
+// refill adds the tokens accrued since the last update
+func (b *TokenBucket) refill(now time.Time) {
 	elapsed := now.Sub(b.lastUpdate).Seconds()
 	b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
 	b.lastUpdate = now
-
-	if b.tokens < size {
-		sleepDuration := time.Duration((size - b.tokens) / b.rate * float64(time.Second))
-		time.Sleep(sleepDuration)
-		b.tokens = 0
-		b.lastUpdate = time.Now()
-	} else {
-		b.tokens -= size
-	}
 }
@@ -0,0 +1,68 @@
+package tunnel
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/o3willard-AI/SSSonector/internal/security/access"
+	"go.uber.org/zap"
+)
+
+// SetIPFilter checks the address of each new connection against filter. Rule
+// changes apply to the next accepted connection.
+func (s *Server) SetIPFilter(filter *access.IPFilterManager) {
+	s.ipFilter.Store(filter)
+}
+
+// useConfiguredACL enforces the ACL of the server's configuration unless a
+// filter was set with SetIPFilter
+func (s *Server) useConfiguredACL() error {
+	aclConfig := s.config.Config.Security.ACL
+	if s.ipFilter.Load() != nil || !access.ACLConfigured(aclConfig) {
+		return nil
+	}
+	filter, err := access.NewIPFilterFromConfig(aclConfig, s.logger)
+	if err != nil {
+		return fmt.Errorf("failed to load ACL: %w", err)
+	}
+	s.SetIPFilter(filter)
+	return nil
+}
+
+// allowedByACL reports whether the IP filter permits a remote address. Addresses
+// that cannot be parsed are refused when a filter is set.
+func (s *Server) allowedByACL(addr net.Addr) (bool, string) {
+	filter := s.ipFilter.Load()
+	if filter == nil {
+		return true, ""
+	}
+
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return filter.CheckNetIP(tcpAddr.IP)
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false, err.Error()
+	}
+	allowed, reason, err := filter.CheckIP(host)
+	if err != nil {
+		return false, err.Error()
+	}
+	return allowed, reason
+}
+
+// ReevaluateConnections closes established connections whose address the IP
+// filter no longer permits. It returns the number of connections closed.
+func (s *Server) ReevaluateConnections() int {
+	closed := 0
+	for _, conn := range s.conns.all() {
+		if allowed, reason := s.allowedByACL(conn.RemoteAddr()); !allowed {
+			s.logger.Warn("Closing connection denied by updated ACL",
+				zap.String("remote_addr", conn.RemoteAddr().String()),
+				zap.String("reason", reason))
+			conn.Close()
+			closed++
+		}
+	}
+	return closed
+}
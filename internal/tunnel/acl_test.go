@@ -0,0 +1,162 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/security/access"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestServerRefusesConnectionDeniedAtRuntime(t *testing.T) {
+	// The server pool dials network.name; a backend that hangs up at once lets
+	// accepted connections finish quickly
+	backend, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Network.Name = backend.Addr().String()
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	useStartupAdapter(t, &startupAdapter{mockAdapter: newMockAdapter()})
+	server := NewServer(cfg, nil, zap.New(core))
+
+	filter := access.NewIPFilterManager(zap.NewNop())
+	server.SetIPFilter(filter)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+	addr := server.listeners[0].Addr().String()
+
+	rejections := func() int {
+		return logs.FilterMessage("Rejected connection denied by ACL").Len()
+	}
+	dial := func() {
+		conn, err := net.Dial("tcp4", addr)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		conn.Close()
+	}
+
+	dial()
+	if err := filter.AddRule("127.0.0.1/32", "deny", "blocked at runtime"); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	dial()
+
+	// Connections are accepted in order, so the first has been admitted by the
+	// time the second is refused
+	if !waitFor(t, func() bool { return rejections() > 0 }) {
+		t.Fatal("Expected connection to be refused after adding a deny rule")
+	}
+	if n := rejections(); n != 1 {
+		t.Fatalf("Expected only the second connection to be refused, got %d rejections", n)
+	}
+	if reason := logs.FilterMessage("Rejected connection denied by ACL").All()[0].ContextMap()["reason"]; reason != "blocked at runtime" {
+		t.Errorf("Expected reason from the deny rule, got %v", reason)
+	}
+}
+
+// addrConn reports a fixed remote address
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr { return c.remote }
+
+func TestServerReevaluateConnections(t *testing.T) {
+	server := &Server{logger: zap.NewNop()}
+	filter := access.NewIPFilterManager(zap.NewNop())
+	server.SetIPFilter(filter)
+
+	var conns []*activityConn
+	for _, ip := range []string{"10.0.0.2", "192.0.2.7"} {
+		local, peer := net.Pipe()
+		defer peer.Close()
+		conn := newActivityConn(&addrConn{Conn: local, remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+		server.conns.add(conn)
+		conns = append(conns, conn)
+	}
+
+	if n := server.ReevaluateConnections(); n != 0 {
+		t.Fatalf("Expected no connections closed, got %d", n)
+	}
+
+	if err := filter.ReplaceRules([]access.IPFilterRuleSpec{{CIDR: "192.0.2.0/24", Action: "deny"}}); err != nil {
+		t.Fatalf("Failed to replace rules: %v", err)
+	}
+	if n := server.ReevaluateConnections(); n != 1 {
+		t.Fatalf("Expected 1 connection closed, got %d", n)
+	}
+	if _, err := conns[1].Write([]byte{0}); err == nil {
+		t.Error("Expected denied connection to be closed")
+	}
+}
+
+func TestServerEnforcesConfiguredACL(t *testing.T) {
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+	cfg.Config.Security.ACL = types.ACLConfig{
+		Rules:         []types.ACLRule{{Network: "127.0.0.0/8", Action: "deny", Description: "loopback"}},
+		DefaultAction: "allow",
+	}
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	useStartupAdapter(t, &startupAdapter{mockAdapter: newMockAdapter()})
+	server := NewServer(cfg, nil, zap.New(core))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp4", server.listeners[0].Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// The connection is closed without a handshake or a pool connection
+	if !waitFor(t, func() bool { return logs.FilterMessage("Rejected connection denied by ACL").Len() > 0 }) {
+		t.Fatal("Expected the connection to be denied by the configured ACL")
+	}
+	if reason := logs.FilterMessage("Rejected connection denied by ACL").All()[0].ContextMap()["reason"]; reason != "loopback" {
+		t.Errorf("Expected reason from the configured rule, got %v", reason)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected the denied connection to be closed")
+	}
+}
+
+func TestServerRejectsInvalidConfiguredACL(t *testing.T) {
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+	cfg.Config.Security.ACL.Rules = []types.ACLRule{{Network: "127.0.0.0/33", Action: "deny"}}
+
+	iface := &startupAdapter{mockAdapter: newMockAdapter()}
+	useStartupAdapter(t, iface)
+	if err := NewServer(cfg, nil, zap.NewNop()).Start(); err == nil {
+		t.Fatal("Expected an invalid ACL to fail startup")
+	}
+	if iface.cleanups != 0 {
+		t.Errorf("Expected the ACL to fail before the interface is created")
+	}
+}
@@ -0,0 +1,217 @@
+package tunnel
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+)
+
+// Authentication methods for security.auth_method
+const (
+	AuthMethodCertificate = "certificate" // Client certificates only, the default
+	AuthMethodPSK         = "psk"         // A pre-shared key
+	AuthMethodToken       = "token"       // One of a set of bearer tokens
+)
+
+const (
+	// authFailureDelay is how long the server waits before rejecting a
+	// credential, the same whatever was wrong with it, to slow down guessing
+	authFailureDelay = time.Second
+
+	// maxCredentialSize bounds the credential a client may send
+	maxCredentialSize = 4096
+)
+
+// Replies to a credential
+const (
+	authRejected byte = 0
+	authAccepted byte = 1
+)
+
+// Authenticator checks the credential a client presents after the TLS
+// handshake. Implementations must take the same time to reject any wrong
+// credential, so that the rejection does not tell how close it was.
+type Authenticator interface {
+	Authenticate(credential []byte) bool
+}
+
+// PSKAuthenticator accepts clients presenting the pre-shared key
+type PSKAuthenticator struct {
+	digest [sha256.Size]byte
+}
+
+// NewPSKAuthenticator creates an authenticator for key
+func NewPSKAuthenticator(key string) *PSKAuthenticator {
+	return &PSKAuthenticator{digest: sha256.Sum256([]byte(key))}
+}
+
+// Authenticate compares digests in constant time, so that neither the content
+// nor the length of the key leaks
+func (a *PSKAuthenticator) Authenticate(credential []byte) bool {
+	digest := sha256.Sum256(credential)
+	return subtle.ConstantTimeCompare(digest[:], a.digest[:]) == 1
+}
+
+// TokenAuthenticator accepts clients presenting any of a set of tokens
+type TokenAuthenticator struct {
+	digests [][sha256.Size]byte
+}
+
+// NewTokenAuthenticator creates an authenticator for tokens
+func NewTokenAuthenticator(tokens []string) *TokenAuthenticator {
+	a := &TokenAuthenticator{digests: make([][sha256.Size]byte, len(tokens))}
+	for i, token := range tokens {
+		a.digests[i] = sha256.Sum256([]byte(token))
+	}
+	return a
+}
+
+// Authenticate compares the credential with every token, without stopping at
+// a match, so the time taken does not tell which token matched
+func (a *TokenAuthenticator) Authenticate(credential []byte) bool {
+	digest := sha256.Sum256(credential)
+	matched := 0
+	for _, token := range a.digests {
+		matched |= subtle.ConstantTimeCompare(digest[:], token[:])
+	}
+	return matched == 1
+}
+
+// authMethod returns the configured authentication method, from
+// security.auth_method or its alias auth.auth_method
+func authMethod(cfg *types.Config) string {
+	if cfg.Security.AuthMethod != "" {
+		return cfg.Security.AuthMethod
+	}
+	return cfg.Auth.AuthMethod
+}
+
+// NewAuthenticator returns the authenticator for the configured method, nil
+// when clients are authenticated by their certificate alone
+func NewAuthenticator(cfg *types.Config) (Authenticator, error) {
+	switch method := authMethod(cfg); method {
+	case "", AuthMethodCertificate:
+		return nil, nil
+	case AuthMethodPSK:
+		if cfg.Security.PSK == "" {
+			return nil, fmt.Errorf("auth method %s requires security.psk", method)
+		}
+		return NewPSKAuthenticator(cfg.Security.PSK), nil
+	case AuthMethodToken:
+		if len(cfg.Security.Tokens) == 0 {
+			return nil, fmt.Errorf("auth method %s requires security.tokens", method)
+		}
+		return NewTokenAuthenticator(cfg.Security.Tokens), nil
+	default:
+		return nil, fmt.Errorf("unknown auth method: %s", method)
+	}
+}
+
+// clientCredential returns the credential the client presents for the
+// configured method, nil when it presents none
+func clientCredential(cfg *types.Config) ([]byte, error) {
+	switch method := authMethod(cfg); method {
+	case "", AuthMethodCertificate:
+		return nil, nil
+	case AuthMethodPSK:
+		if cfg.Security.PSK == "" {
+			return nil, fmt.Errorf("auth method %s requires security.psk", method)
+		}
+		return []byte(cfg.Security.PSK), nil
+	case AuthMethodToken:
+		if cfg.Security.Token == "" {
+			return nil, fmt.Errorf("auth method %s requires security.token", method)
+		}
+		return []byte(cfg.Security.Token), nil
+	default:
+		return nil, fmt.Errorf("unknown auth method: %s", method)
+	}
+}
+
+// SetAuthenticator makes the server check a credential from each client after
+// the TLS handshake. It must be called before Start, which otherwise uses the
+// authenticator for the configured method.
+func (s *Server) SetAuthenticator(authenticator Authenticator) {
+	s.authenticator = authenticator
+}
+
+// useConfiguredAuthenticator sets the authenticator for the configured method
+// unless one was set. Credentials are refused without TLS, which would send
+// them in the clear.
+func (s *Server) useConfiguredAuthenticator() error {
+	if s.authenticator == nil {
+		authenticator, err := NewAuthenticator(s.config.Config)
+		if err != nil {
+			return fmt.Errorf("failed to configure authentication: %w", err)
+		}
+		s.authenticator = authenticator
+	}
+	if s.authenticator != nil && s.tlsManager == nil {
+		return fmt.Errorf("%w: auth method %s requires TLS, set auth.cert_file and auth.key_file",
+			ErrInvalidConfiguration, authMethod(s.config.Config))
+	}
+	return nil
+}
+
+// authenticate reads the client's credential, a big-endian 2 byte length and
+// the credential, and replies whether it was accepted. Every rejection, for a
+// wrong credential or a malformed one, is delayed by authFailureDelay and
+// returns ErrAuthenticationFailed.
+func (s *Server) authenticate(conn net.Conn) error {
+	if err := conn.SetDeadline(time.Now().Add(s.handshakeTimeout())); err != nil {
+		return err
+	}
+	defer conn.SetDeadline(time.Time{})
+
+	accepted := false
+	var header [2]byte
+	if _, err := io.ReadFull(conn, header[:]); err == nil {
+		size := binary.BigEndian.Uint16(header[:])
+		if size <= maxCredentialSize {
+			credential := make([]byte, size)
+			if _, err := io.ReadFull(conn, credential); err == nil {
+				accepted = s.authenticator.Authenticate(credential)
+			}
+		}
+	}
+
+	if !accepted {
+		time.Sleep(authFailureDelay)
+		conn.Write([]byte{authRejected})
+		return ErrAuthenticationFailed
+	}
+	_, err := conn.Write([]byte{authAccepted})
+	return err
+}
+
+// clientAuthenticate presents credential to the server and waits for it to be
+// accepted. A rejection is not retryable, as the same credential fails again.
+func clientAuthenticate(conn net.Conn, credential []byte, timeout time.Duration) error {
+	if len(credential) > maxCredentialSize {
+		return fmt.Errorf("credential of %d bytes exceeds the %d byte limit", len(credential), maxCredentialSize)
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	defer conn.SetDeadline(time.Time{})
+
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(credential)))
+	if _, err := conn.Write(append(frame, credential...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != authAccepted {
+		return resilience.MarkRetryable(ErrAuthenticationFailed, false)
+	}
+	return nil
+}
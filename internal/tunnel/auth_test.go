@@ -0,0 +1,174 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"go.uber.org/zap"
+)
+
+func TestAuthenticators(t *testing.T) {
+	psk := NewPSKAuthenticator("correct horse")
+	tokens := NewTokenAuthenticator([]string{"token-a", "token-b"})
+
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		credential    string
+		accepted      bool
+	}{
+		{"psk match", psk, "correct horse", true},
+		{"psk mismatch", psk, "correct horsf", false},
+		{"psk prefix", psk, "correct", false},
+		{"psk empty", psk, "", false},
+		{"first token", tokens, "token-a", true},
+		{"second token", tokens, "token-b", true},
+		{"unknown token", tokens, "token-c", false},
+		{"empty token", tokens, "", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.authenticator.Authenticate([]byte(tt.credential)); got != tt.accepted {
+			t.Errorf("%s: expected accepted %v, got %v", tt.name, tt.accepted, got)
+		}
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name     string
+		security types.SecurityConfig
+		auth     types.AuthConfig
+		want     Authenticator
+		wantErr  bool
+	}{
+		{"default", types.SecurityConfig{}, types.AuthConfig{}, nil, false},
+		{"certificate", types.SecurityConfig{AuthMethod: "certificate"}, types.AuthConfig{}, nil, false},
+		{"psk", types.SecurityConfig{AuthMethod: "psk", PSK: "secret"}, types.AuthConfig{}, &PSKAuthenticator{}, false},
+		{"psk alias", types.SecurityConfig{PSK: "secret"}, types.AuthConfig{AuthMethod: "psk"}, &PSKAuthenticator{}, false},
+		{"psk without key", types.SecurityConfig{AuthMethod: "psk"}, types.AuthConfig{}, nil, true},
+		{"token", types.SecurityConfig{AuthMethod: "token", Tokens: []string{"t"}}, types.AuthConfig{}, &TokenAuthenticator{}, false},
+		{"token without tokens", types.SecurityConfig{AuthMethod: "token"}, types.AuthConfig{}, nil, true},
+		{"unknown", types.SecurityConfig{AuthMethod: "kerberos"}, types.AuthConfig{}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := NewAuthenticator(&types.Config{Security: tt.security, Auth: tt.auth})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		switch tt.want.(type) {
+		case nil:
+			if got != nil {
+				t.Errorf("%s: expected no authenticator, got %T", tt.name, got)
+			}
+		case *PSKAuthenticator:
+			if _, ok := got.(*PSKAuthenticator); !ok {
+				t.Errorf("%s: expected a PSK authenticator, got %T", tt.name, got)
+			}
+		case *TokenAuthenticator:
+			if _, ok := got.(*TokenAuthenticator); !ok {
+				t.Errorf("%s: expected a token authenticator, got %T", tt.name, got)
+			}
+		}
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		credential    string
+		accepted      bool
+	}{
+		{"psk accepted", NewPSKAuthenticator("secret"), "secret", true},
+		{"psk rejected", NewPSKAuthenticator("secret"), "guess", false},
+		{"token accepted", NewTokenAuthenticator([]string{"alpha", "beta"}), "beta", true},
+		{"token rejected", NewTokenAuthenticator([]string{"alpha", "beta"}), "gamma", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := &Server{config: types.NewAppConfig(types.TypeServer), logger: zap.NewNop()}
+			server.SetAuthenticator(tt.authenticator)
+
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+
+			start := time.Now()
+			serverErr := make(chan error, 1)
+			go func() { serverErr <- server.authenticate(serverConn) }()
+			clientErr := clientAuthenticate(clientConn, []byte(tt.credential), 5*time.Second)
+
+			if tt.accepted {
+				if clientErr != nil {
+					t.Errorf("Expected the client to be accepted, got %v", clientErr)
+				}
+				if err := <-serverErr; err != nil {
+					t.Errorf("Expected the server to accept, got %v", err)
+				}
+				return
+			}
+
+			// Every rejection is the same error, after the same delay
+			if !errors.Is(clientErr, ErrAuthenticationFailed) {
+				t.Errorf("Expected the client to be rejected, got %v", clientErr)
+			}
+			if retryable, _ := resilience.IsRetryable(clientErr); retryable {
+				t.Error("Expected a rejected credential not to be retryable")
+			}
+			if err := <-serverErr; !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("Expected the server to reject, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < authFailureDelay {
+				t.Errorf("Expected the rejection delayed by %v, took %v", authFailureDelay, elapsed)
+			}
+		})
+	}
+}
+
+func TestServerAuthenticationRejectsMalformedCredential(t *testing.T) {
+	server := &Server{config: types.NewAppConfig(types.TypeServer), logger: zap.NewNop()}
+	server.SetAuthenticator(NewPSKAuthenticator("secret"))
+
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+
+	// A length over the limit, then nothing
+	go func() {
+		clientConn.Write([]byte{0xff, 0xff})
+		clientConn.Read(make([]byte, 1))
+		clientConn.Close()
+	}()
+	if err := server.authenticate(serverConn); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Expected a malformed credential to fail authentication, got %v", err)
+	}
+}
+
+func TestCredentialAuthRequiresTLS(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Security.AuthMethod = AuthMethodPSK
+	cfg.Config.Security.PSK = "secret"
+
+	server := &Server{config: cfg, logger: zap.NewNop()}
+	if err := server.useConfiguredAuthenticator(); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected the server to refuse a PSK without TLS, got %v", err)
+	}
+
+	client := NewClient(cfg, nil, zap.NewNop())
+	defer client.pool.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.pool.Get(ctx); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected the client to refuse to send a PSK without TLS, got %v", err)
+	}
+}
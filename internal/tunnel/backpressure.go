@@ -0,0 +1,218 @@
+package tunnel
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"go.uber.org/zap"
+)
+
+// shedDivisor sets the share of connections closed at critical memory pressure:
+// one in shedDivisor, rounded up, idlest first
+const shedDivisor = 4
+
+// activityConn records the time of the last successful read or write and the
+// bytes transferred in each direction. Close may be called more than once.
+type activityConn struct {
+	net.Conn
+	id          string       // Assigned by connTracker.add
+	identity    PeerIdentity // Verified client certificate, empty without TLS
+	tlsVersion  string       // Negotiated TLS version, empty without TLS
+	cipherSuite string       // Negotiated cipher suite, empty without TLS
+	closeOnce   sync.Once
+	closeErr    error
+	established time.Time
+	lastActive  atomic.Int64 // UnixNano
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	tunnelIP    atomic.Pointer[string] // Source of the first tunneled packet, see recordTunnelIP
+}
+
+func newActivityConn(conn net.Conn) *activityConn {
+	c := &activityConn{Conn: conn, established: time.Now()}
+	c.touch()
+	return c
+}
+
+func (c *activityConn) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+func (c *activityConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.bytesIn.Add(int64(n))
+		c.touch()
+	}
+	return n, err
+}
+
+func (c *activityConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.bytesOut.Add(int64(n))
+		c.touch()
+	}
+	return n, err
+}
+
+// Close closes the connection once; later calls return the first result
+func (c *activityConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.Conn.Close()
+	})
+	return c.closeErr
+}
+
+// connTracker holds the server's active client connections
+type connTracker struct {
+	mu     sync.Mutex
+	conns  map[*activityConn]struct{}
+	nextID uint64
+}
+
+// add tracks conn and assigns its ID
+func (t *connTracker) add(conn *activityConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns == nil {
+		t.conns = make(map[*activityConn]struct{})
+	}
+	t.nextID++
+	conn.id = strconv.FormatUint(t.nextID, 10)
+	t.conns[conn] = struct{}{}
+}
+
+// take removes and returns the connection with the given ID or remote address,
+// or nil if none is tracked. Only one caller can take a connection.
+func (t *connTracker) take(id string) *activityConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for conn := range t.conns {
+		if conn.id == id || (conn.RemoteAddr() != nil && conn.RemoteAddr().String() == id) {
+			delete(t.conns, conn)
+			return conn
+		}
+	}
+	return nil
+}
+
+func (t *connTracker) remove(conn *activityConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, conn)
+}
+
+func (t *connTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// all returns the active connections
+func (t *connTracker) all() []*activityConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conns := make([]*activityConn, 0, len(t.conns))
+	for conn := range t.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// snapshot describes the active connections, oldest first. The tracker lock is
+// held throughout so the result reflects a single point in time.
+func (t *connTracker) snapshot() []ConnectionInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	infos := make([]ConnectionInfo, 0, len(t.conns))
+	for conn := range t.conns {
+		infos = append(infos, conn.info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Established.Equal(infos[j].Established) {
+			return infos[i].Established.Before(infos[j].Established)
+		}
+		return infos[i].RemoteAddr < infos[j].RemoteAddr
+	})
+	return infos
+}
+
+// idlest returns up to n connections, least recently active first
+func (t *connTracker) idlest(n int) []*activityConn {
+	conns := t.all()
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].lastActive.Load() < conns[j].lastActive.Load()
+	})
+	if len(conns) > n {
+		conns = conns[:n]
+	}
+	return conns
+}
+
+// SetMemoryManager makes the server react to memory pressure reported by mgr. At
+// MemPressureHigh and above new connections are refused until pressure falls; at
+// MemPressureCritical the idlest connections are also closed.
+func (s *Server) SetMemoryManager(mgr *memory.MemoryManager) {
+	s.memory = mgr
+	mgr.OnPressureChange(s.handleMemoryPressure)
+}
+
+// MemoryPressure returns the memory pressure level last reported to the server
+func (s *Server) MemoryPressure() memory.MemPressure {
+	if s.memory == nil {
+		return memory.MemPressureNone
+	}
+	return s.memory.LastPressureLevel()
+}
+
+// GetConnectionCount returns the number of active client connections
+func (s *Server) GetConnectionCount() int {
+	return s.conns.count()
+}
+
+// AcceptingConnections reports whether new connections are currently accepted
+func (s *Server) AcceptingConnections() bool {
+	return !s.acceptPaused.Load()
+}
+
+// handleMemoryPressure applies backpressure for a new memory pressure level
+func (s *Server) handleMemoryPressure(level memory.MemPressure) {
+	paused := level >= memory.MemPressureHigh
+	if s.acceptPaused.Swap(paused) != paused {
+		if paused {
+			s.logger.Warn("Refusing new connections under memory pressure",
+				zap.Stringer("pressure", level))
+		} else {
+			s.logger.Info("Accepting new connections again",
+				zap.Stringer("pressure", level))
+		}
+	}
+
+	if level >= memory.MemPressureCritical {
+		s.shedIdleConnections()
+	}
+}
+
+// shedIdleConnections closes the idlest share of active connections
+func (s *Server) shedIdleConnections() {
+	active := s.conns.count()
+	if active == 0 {
+		return
+	}
+
+	n := (active + shedDivisor - 1) / shedDivisor
+	for _, conn := range s.conns.idlest(n) {
+		s.logger.Warn("Closing idle connection under critical memory pressure",
+			zap.String("remote_addr", conn.RemoteAddr().String()),
+			zap.Duration("idle", time.Since(time.Unix(0, conn.lastActive.Load()))))
+		conn.Close()
+	}
+}
@@ -0,0 +1,123 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"go.uber.org/zap"
+)
+
+// stubMemStats reports a configurable allocated memory
+type stubMemStats struct {
+	allocPercent atomic.Uint64
+}
+
+func (s *stubMemStats) read(stats *runtime.MemStats) {
+	*stats = runtime.MemStats{Alloc: s.allocPercent.Load()}
+}
+
+// waitFor polls cond until it holds or the deadline passes
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func TestServerMemoryPressureClosesAcceptGate(t *testing.T) {
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Network.Name = "tun-test0"
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+
+	useStartupAdapter(t, &startupAdapter{mockAdapter: newMockAdapter()})
+	server := NewServer(cfg, nil, zap.NewNop())
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	// Leave the runtime limit alone; a 100 byte limit would keep the real GC busy
+	t.Setenv("GOMEMLIMIT", "off")
+
+	stats := &stubMemStats{}
+	stats.allocPercent.Store(10)
+	mgr := memory.NewMemoryManager(&memory.MemManagerConfig{
+		MaxMemoryBytes:  100,
+		GCThreshold:     1, // Keep the stubbed stats from retuning the real GC
+		MonitorInterval: 10 * time.Millisecond,
+		StatSource:      stats.read,
+	})
+	server.SetMemoryManager(mgr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Failed to start memory manager: %v", err)
+	}
+	defer mgr.Stop()
+
+	if !server.AcceptingConnections() {
+		t.Fatal("Expected server to accept connections without memory pressure")
+	}
+
+	// 70% of the max memory is high pressure
+	stats.allocPercent.Store(70)
+	if !waitFor(t, func() bool { return !server.AcceptingConnections() }) {
+		t.Fatal("Expected accept gate to close at high memory pressure")
+	}
+	if level := server.MemoryPressure(); level != memory.MemPressureHigh {
+		t.Errorf("Expected pressure high, got %s", level)
+	}
+
+	stats.allocPercent.Store(10)
+	if !waitFor(t, server.AcceptingConnections) {
+		t.Fatal("Expected accept gate to reopen when memory pressure falls")
+	}
+}
+
+func TestServerCriticalPressureShedsIdlestConnections(t *testing.T) {
+	server := &Server{logger: zap.NewNop()}
+
+	var conns []*activityConn
+	var peers []net.Conn
+	for i := 0; i < 5; i++ {
+		local, peer := net.Pipe()
+		conn := newActivityConn(local)
+		conn.lastActive.Store(int64(i + 1)) // conns[0] is the idlest
+		server.conns.add(conn)
+		conns = append(conns, conn)
+		peers = append(peers, peer)
+	}
+	defer func() {
+		for _, peer := range peers {
+			peer.Close()
+		}
+	}()
+
+	server.handleMemoryPressure(memory.MemPressureCritical)
+	if server.AcceptingConnections() {
+		t.Error("Expected accept gate to close at critical memory pressure")
+	}
+
+	// One in four, rounded up: the two idlest connections are closed
+	for i, conn := range conns {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Millisecond))
+		_, err := conn.Write([]byte{0})
+		if closed, want := errors.Is(err, io.ErrClosedPipe), i < 2; closed != want {
+			t.Errorf("Connection %d: expected closed=%v, got %v", i, want, err)
+		}
+	}
+}
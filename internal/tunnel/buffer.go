@@ -1,26 +1,26 @@
 package tunnel
 
 import (
-	"github.com/o3willard-AI/SSSonector/internal/buffer"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
 )
 
-var (
-	// defaultBufferConfig is the default configuration for tunnel buffers
-	defaultBufferConfig = buffer.Config{
-		MinSize: 1500, // Standard MTU size
-		MaxSize: 9000, // Jumbo frame size
-	}
-
-	// globalBufferPool is a shared buffer pool for all tunnels
-	globalBufferPool = buffer.NewPool(defaultBufferConfig)
-)
+// sharedBufferPool serves transfers that are not tied to a memory manager
+var sharedBufferPool = memory.NewSizedPool()
 
-// getBuffer gets a buffer from the pool sized according to the MTU
-func getBuffer(mtu int) []byte {
-	return globalBufferPool.GetWithMTU(mtu)
-}
+// packetBufferSize returns the read buffer size for a tunnel configuration. An explicit
+// max packet size wins; otherwise the buffer holds a full MTU-sized packet plus overhead.
+func packetBufferSize(cfg *types.AppConfig) int {
+	if cfg == nil || cfg.Config == nil {
+		return types.DefaultMTU + types.PacketOverhead
+	}
 
-// putBuffer returns a buffer to the pool
-func putBuffer(buf []byte) {
-	globalBufferPool.Put(buf)
+	network := cfg.Config.Network
+	if network.MaxPacketSize > 0 {
+		return network.MaxPacketSize
+	}
+	if network.MTU > 0 {
+		return network.MTU + types.PacketOverhead
+	}
+	return types.DefaultMTU + types.PacketOverhead
 }
@@ -0,0 +1,141 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/cert/generator"
+	"go.uber.org/zap"
+)
+
+// dnsServerCert issues a server certificate carrying only a DNS SAN, signed by
+// the CA in dir
+func dnsServerCert(t *testing.T, dir, dnsName string) tls.Certificate {
+	t.Helper()
+
+	caPair, err := tls.LoadX509KeyPair(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key"))
+	if err != nil {
+		t.Fatalf("Failed to load CA: %v", err)
+	}
+	ca, err := x509.ParseCertificate(caPair.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse CA: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caPair.PrivateKey)
+	if err != nil {
+		t.Fatalf("Failed to create server certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientServerNameOverride(t *testing.T) {
+	// Keep the certificates out of /tmp, where the client skips verification
+	dir, err := os.MkdirTemp(".", ".server-name-test-")
+	if err != nil {
+		t.Fatalf("Failed to create cert dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := generator.GenerateTemporaryCertificates(dir); err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+
+	caPEM, err := os.ReadFile(filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatalf("Failed to read CA: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(caPEM)
+
+	listener, err := tls.Listen("tcp4", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{dnsServerCert(t, dir, "tunnel.example.com")},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	manager, err := NewTLSManager(&TLSConfig{
+		CertFile:      filepath.Join(dir, "client.crt"),
+		KeyFile:       filepath.Join(dir, "client.key"),
+		CAFile:        filepath.Join(dir, "ca.crt"),
+		SecurityLevel: SecurityModern,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client TLS manager: %v", err)
+	}
+	address := listener.Addr().String()
+	dial := func(serverName string) error {
+		conn, err := net.Dial("tcp4", address)
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		defer conn.Close()
+		_, err = clientHandshake(context.Background(), conn, manager, address, serverName)
+		return err
+	}
+
+	if err := dial(""); err == nil {
+		t.Error("Expected verification by IP to fail against a DNS-only certificate")
+	}
+	if err := dial("tunnel.example.com"); err != nil {
+		t.Errorf("Expected verification against the endpoint's server name to succeed, got %v", err)
+	}
+}
+
+func TestEndpointServerNames(t *testing.T) {
+	m := NewEndpointManager(testBreakerConfig(), zap.NewNop())
+	if err := m.AddWithServerName("192.0.2.1:8443", 1, "tunnel.example.com"); err != nil {
+		t.Fatalf("Failed to add endpoint: %v", err)
+	}
+
+	var dialed string
+	m.Dial(context.Background(), func(ctx context.Context, address, serverName string) (net.Conn, error) {
+		dialed = serverName
+		return nil, errors.New("unreachable")
+	})
+	if dialed != "tunnel.example.com" {
+		t.Errorf("Expected the endpoint's server name to be verified, got %q", dialed)
+	}
+	if statuses := m.List(); statuses[0].ServerName != "tunnel.example.com" {
+		t.Errorf("Expected the server name to be listed, got %+v", statuses[0])
+	}
+}
@@ -0,0 +1,171 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// frameHeaderSize is the big-endian length prefix of each packet in a
+// coalesced stream
+const frameHeaderSize = 2
+
+// maxFrameSize is the largest packet a frame header can describe
+const maxFrameSize = 1<<(8*frameHeaderSize) - 1
+
+// errBadFrame means a coalesced stream cannot be split into packets any more,
+// usually because the peer does not coalesce
+var errBadFrame = errors.New("invalid packet frame")
+
+// coalesceSettings are the thresholds of a coalescingWriter
+type coalesceSettings struct {
+	window  time.Duration // Longest a packet waits; 0 disables coalescing
+	bytes   int           // Framed bytes that trigger a write
+	packets int           // Packets that trigger a write
+}
+
+// coalesceSettingsFrom reads the coalescing thresholds of cfg, with defaults
+// for the byte and packet thresholds
+func coalesceSettingsFrom(cfg *types.AppConfig) coalesceSettings {
+	if cfg == nil || cfg.Config == nil || cfg.Config.Tunnel.CoalesceWindow <= 0 {
+		return coalesceSettings{}
+	}
+
+	settings := coalesceSettings{
+		window:  cfg.Config.Tunnel.CoalesceWindow,
+		bytes:   cfg.Config.Tunnel.CoalesceBytes,
+		packets: cfg.Config.Tunnel.CoalescePackets,
+	}
+	if settings.bytes <= 0 {
+		settings.bytes = types.DefaultCoalesceBytes
+	}
+	if settings.packets <= 0 {
+		settings.packets = types.DefaultCoalescePackets
+	}
+	return settings
+}
+
+// coalescingWriter frames each packet written to it with its length and
+// batches the frames, writing them to w in one call once the byte or packet
+// threshold is reached or the oldest has waited for the window. Packets are
+// written whole and in order.
+type coalescingWriter struct {
+	w        io.Writer
+	settings coalesceSettings
+
+	mu      sync.Mutex
+	buf     []byte
+	packets int
+	timer   *time.Timer
+	err     error // First failed write, returned by every later call
+}
+
+func newCoalescingWriter(w io.Writer, settings coalesceSettings) *coalescingWriter {
+	return &coalescingWriter{
+		w:        w,
+		settings: settings,
+		buf:      make([]byte, 0, settings.bytes),
+	}
+}
+
+// Write queues packet as one frame. An error from an earlier timed flush is
+// returned here, as the packets it held are lost.
+func (c *coalescingWriter) Write(packet []byte) (int, error) {
+	if len(packet) > maxFrameSize {
+		return 0, fmt.Errorf("%w: packet of %d bytes exceeds the %d byte limit", errBadFrame, len(packet), maxFrameSize)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	c.buf = binary.BigEndian.AppendUint16(c.buf, uint16(len(packet)))
+	c.buf = append(c.buf, packet...)
+	c.packets++
+
+	if len(c.buf) >= c.settings.bytes || c.packets >= c.settings.packets {
+		if err := c.flushLocked(); err != nil {
+			return 0, err
+		}
+	} else if c.timer == nil {
+		c.timer = time.AfterFunc(c.settings.window, func() { c.Flush() })
+	}
+	return len(packet), nil
+}
+
+// Flush writes the queued frames now
+func (c *coalescingWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return c.flushLocked()
+}
+
+// flushLocked writes the queued frames in one call; the caller holds c.mu
+func (c *coalescingWriter) flushLocked() error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.buf) == 0 {
+		return nil
+	}
+
+	_, err := c.w.Write(c.buf)
+	c.buf = c.buf[:0]
+	c.packets = 0
+	if err != nil {
+		c.err = err
+	}
+	return err
+}
+
+// frameReader reads the packets of a framed stream, one per Read, skipping
+// heartbeats
+type frameReader struct {
+	r      io.Reader
+	header [frameHeaderSize]byte
+	seen   func() // Called for every frame, when set
+}
+
+// Read reads the next packet into b, which must be large enough to hold it.
+// Nothing is returned of a packet that is cut short: the stream ending between
+// packets returns io.EOF, and within one io.ErrUnexpectedEOF.
+func (f *frameReader) Read(b []byte) (int, error) {
+	var size int
+	for {
+		if _, err := io.ReadFull(f.r, f.header[:]); err != nil {
+			return 0, err
+		}
+		if size = int(binary.BigEndian.Uint16(f.header[:])); size > 0 {
+			break
+		}
+		// An empty frame is a heartbeat
+		if f.seen != nil {
+			f.seen()
+		}
+	}
+
+	if size > len(b) {
+		return 0, fmt.Errorf("%w: packet of %d bytes exceeds the %d byte buffer", errBadFrame, size, len(b))
+	}
+	if _, err := io.ReadFull(f.r, b[:size]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	if f.seen != nil {
+		f.seen()
+	}
+	return size, nil
+}
@@ -0,0 +1,190 @@
+package tunnel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+// countingWriter counts the writes made to it
+type countingWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return w.buf.Write(b)
+}
+
+// testPackets returns packets of varied sizes, each filled with its index
+func testPackets(count int) [][]byte {
+	packets := make([][]byte, count)
+	for i := range packets {
+		packets[i] = bytes.Repeat([]byte{byte(i)}, 1+i*37%1400)
+	}
+	return packets
+}
+
+func TestCoalescingPreservesPacketBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings coalesceSettings
+	}{
+		{"packet threshold", coalesceSettings{window: time.Second, bytes: 1 << 20, packets: 7}},
+		{"byte threshold", coalesceSettings{window: time.Second, bytes: 4096, packets: 1000}},
+		{"window", coalesceSettings{window: time.Millisecond, bytes: 1 << 20, packets: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &countingWriter{}
+			writer := newCoalescingWriter(out, tt.settings)
+			packets := testPackets(50)
+			for _, packet := range packets {
+				if _, err := writer.Write(packet); err != nil {
+					t.Fatalf("Failed to write packet: %v", err)
+				}
+			}
+			time.Sleep(10 * time.Millisecond) // Let the window expire
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("Failed to flush: %v", err)
+			}
+
+			out.mu.Lock()
+			writes := out.writes
+			reader := &frameReader{r: bytes.NewReader(out.buf.Bytes())}
+			out.mu.Unlock()
+			if writes == 0 || writes >= len(packets) {
+				t.Errorf("Expected the packets to be coalesced into fewer writes, got %d", writes)
+			}
+
+			buf := make([]byte, types.MaxPacketSizeLimit)
+			for i, packet := range packets {
+				n, err := reader.Read(buf)
+				if err != nil {
+					t.Fatalf("Failed to read packet %d: %v", i, err)
+				}
+				if !bytes.Equal(buf[:n], packet) {
+					t.Fatalf("Packet %d: expected %d bytes of %d, got %d bytes", i, len(packet), i, n)
+				}
+			}
+			if _, err := reader.Read(buf); err != io.EOF {
+				t.Errorf("Expected EOF after the last packet, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFrameReaderRejectsBadFrames(t *testing.T) {
+	// A packet cut short returns nothing of it
+	reader := &frameReader{r: bytes.NewReader([]byte{0, 5, 'a', 'b'})}
+	if n, err := reader.Read(make([]byte, 16)); n != 0 || err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected an unexpected EOF and no data, got %d bytes and %v", n, err)
+	}
+
+	// A frame larger than the buffer means the stream is not framed
+	reader = &frameReader{r: bytes.NewReader([]byte{0xff, 0xff})}
+	if _, err := reader.Read(make([]byte, 16)); !errors.Is(err, errBadFrame) || isTransientConnError(err) {
+		t.Errorf("Expected a fatal bad frame error, got %v", err)
+	}
+}
+
+func TestTransferCoalescesPacketsToSource(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Tunnel.CoalesceWindow = time.Millisecond
+	cfg.Config.Tunnel.CoalescePackets = 4
+
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	transfer := NewTransfer(src, dst, cfg, zap.NewNop())
+	go transfer.Start()
+	defer transfer.Stop()
+
+	// Packets from dst reach the peer framed, in order
+	packets := testPackets(10)
+	go func() {
+		for _, packet := range packets {
+			if _, err := dstPeer.Write(packet); err != nil {
+				return
+			}
+		}
+	}()
+	srcPeer.SetDeadline(time.Now().Add(2 * time.Second))
+	peer := &frameReader{r: srcPeer}
+	buf := make([]byte, types.MaxPacketSizeLimit)
+	for i, packet := range packets {
+		n, err := peer.Read(buf)
+		if err != nil {
+			t.Fatalf("Failed to read packet %d: %v", i, err)
+		}
+		if !bytes.Equal(buf[:n], packet) {
+			t.Fatalf("Packet %d: expected %d bytes, got %d", i, len(packet), n)
+		}
+	}
+
+	// Framed packets from the peer reach dst one at a time
+	go func() {
+		writer := newCoalescingWriter(srcPeer, coalesceSettings{window: time.Second, bytes: 1 << 20, packets: 2})
+		writer.Write([]byte("first"))
+		writer.Write([]byte("second"))
+	}()
+	dstPeer.SetDeadline(time.Now().Add(2 * time.Second))
+	for _, expected := range []string{"first", "second"} {
+		n, err := dstPeer.Read(buf)
+		if err != nil {
+			t.Fatalf("Failed to read packet: %v", err)
+		}
+		if string(buf[:n]) != expected {
+			t.Errorf("Expected packet %q, got %q", expected, buf[:n])
+		}
+	}
+}
+
+func BenchmarkCoalescingSmallPackets(b *testing.B) {
+	packet := make([]byte, 64) // e.g. a VoIP frame
+	benchmarks := []struct {
+		name     string
+		settings coalesceSettings
+	}{
+		{"direct", coalesceSettings{}},
+		{"coalesced", coalesceSettings{window: 500 * time.Microsecond, bytes: types.DefaultCoalesceBytes, packets: types.DefaultCoalescePackets}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			out := &countingWriter{}
+			var w io.Writer = out
+			var writer *coalescingWriter
+			if bm.settings.window > 0 {
+				writer = newCoalescingWriter(out, bm.settings)
+				w = writer
+			}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w.Write(packet)
+				out.mu.Lock()
+				out.buf.Reset()
+				out.mu.Unlock()
+			}
+			if writer != nil {
+				writer.Flush()
+			}
+			b.ReportMetric(float64(out.writes)/float64(b.N), "writes/packet")
+		})
+	}
+}
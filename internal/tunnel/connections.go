@@ -0,0 +1,146 @@
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"go.uber.org/zap"
+)
+
+// Fixed IP header lengths, up to and including the addresses
+const (
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+)
+
+// ConnectionInfo describes one active client connection
+type ConnectionInfo struct {
+	ID           string    `json:"id"`
+	RemoteAddr   string    `json:"remote_addr"`
+	Established  time.Time `json:"established"`
+	BytesIn      int64     `json:"bytes_in"`
+	BytesOut     int64     `json:"bytes_out"`
+	LastActivity time.Time `json:"last_activity"`
+	// TunnelIP is the tunnel address the peer sends from, taken from the
+	// first packet it tunnels; empty until then
+	TunnelIP string `json:"tunnel_ip,omitempty"`
+	// ClientCN and ClientSerial identify the peer's verified TLS client
+	// certificate
+	ClientCN     string `json:"client_cn,omitempty"`
+	ClientSerial string `json:"client_serial,omitempty"`
+	// TLSVersion and CipherSuite are the TLS parameters the connection
+	// negotiated, e.g. "TLS 1.3"
+	TLSVersion  string `json:"tls_version,omitempty"`
+	CipherSuite string `json:"cipher_suite,omitempty"`
+}
+
+// info describes the connection
+func (c *activityConn) info() ConnectionInfo {
+	info := ConnectionInfo{
+		ID:           c.id,
+		Established:  c.established,
+		BytesIn:      c.bytesIn.Load(),
+		BytesOut:     c.bytesOut.Load(),
+		LastActivity: time.Unix(0, c.lastActive.Load()),
+		ClientCN:     c.identity.CommonName,
+		ClientSerial: c.identity.Serial,
+		TLSVersion:   c.tlsVersion,
+		CipherSuite:  c.cipherSuite,
+	}
+	if addr := c.RemoteAddr(); addr != nil {
+		info.RemoteAddr = addr.String()
+	}
+	if ip := c.tunnelIP.Load(); ip != nil {
+		info.TunnelIP = *ip
+	}
+	return info
+}
+
+// recordTunnelIP records ip as the peer's tunnel address, unless one was
+// already recorded
+func (c *activityConn) recordTunnelIP(ip net.IP) {
+	addr := ip.String()
+	c.tunnelIP.CompareAndSwap(nil, &addr)
+}
+
+// packetSource returns the source address of an IPv4 or IPv6 packet, or nil
+// when packet does not start with a complete IP header
+func packetSource(packet []byte) net.IP {
+	if len(packet) == 0 {
+		return nil
+	}
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) >= ipv4HeaderLen {
+			return net.IP(append([]byte(nil), packet[12:16]...))
+		}
+	case 6:
+		if len(packet) >= ipv6HeaderLen {
+			return net.IP(append([]byte(nil), packet[8:24]...))
+		}
+	}
+	return nil
+}
+
+// tunnelIPReader passes packets through, recording the source address of
+// the first IP packet read
+type tunnelIPReader struct {
+	r      io.Reader
+	record func(ip net.IP)
+	done   bool
+}
+
+func (t *tunnelIPReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if !t.done && n > 0 {
+		if ip := packetSource(p[:n]); ip != nil {
+			t.record(ip)
+			t.done = true
+		}
+	}
+	return n, err
+}
+
+// ListConnections returns a consistent snapshot of the active client
+// connections, oldest first. A stopped server returns ErrServerStopped.
+func (s *Server) ListConnections() ([]ConnectionInfo, error) {
+	if s.ctx != nil && s.ctx.Err() != nil {
+		return nil, ErrServerStopped
+	}
+	return s.conns.snapshot(), nil
+}
+
+// TalkerStats returns the traffic of each active client connection, labelled
+// with its client certificate identity, for the monitor's connection summary
+func (s *Server) TalkerStats() []monitor.TalkerStats {
+	conns := s.conns.snapshot()
+	talkers := make([]monitor.TalkerStats, len(conns))
+	for i, c := range conns {
+		talkers[i] = monitor.TalkerStats{
+			RemoteAddr:    c.RemoteAddr,
+			ClientCN:      c.ClientCN,
+			BytesSent:     c.BytesOut,
+			BytesReceived: c.BytesIn,
+		}
+	}
+	return talkers
+}
+
+// Disconnect closes the client connection with the given ID or remote address and
+// removes it from the active set. Its transfer ends when the closed connection
+// fails its next read or write. An unknown ID returns ErrConnectionNotFound.
+func (s *Server) Disconnect(id string) error {
+	conn := s.conns.take(id)
+	if conn == nil {
+		return fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+	}
+
+	s.logger.Info("Disconnecting client",
+		zap.String("id", conn.id),
+		zap.String("remote_addr", conn.info().RemoteAddr))
+	conn.Close()
+	return nil
+}
@@ -0,0 +1,175 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestServerListConnections(t *testing.T) {
+	server := &Server{logger: zap.NewNop()}
+
+	addrs := []string{"10.0.0.2", "192.0.2.7"}
+	var conns []*activityConn
+	for _, ip := range addrs {
+		local, remote := net.Pipe()
+		defer local.Close()
+		defer remote.Close()
+		go io.Copy(io.Discard, remote)
+
+		conn := newActivityConn(&addrConn{Conn: local, remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+		server.conns.add(conn)
+		conns = append(conns, conn)
+	}
+
+	if _, err := conns[1].Write([]byte("hello")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	// The first packet from a client records its tunnel IP
+	packet := make([]byte, ipv4HeaderLen)
+	packet[0] = 0x45
+	copy(packet[12:16], net.IPv4(10, 8, 0, 2).To4())
+	reader := &tunnelIPReader{r: bytes.NewReader(packet), record: conns[0].recordTunnelIP}
+	if _, err := reader.Read(make([]byte, 64)); err != nil {
+		t.Fatalf("Failed to read packet: %v", err)
+	}
+
+	infos, err := server.ListConnections()
+	if err != nil {
+		t.Fatalf("Failed to list connections: %v", err)
+	}
+	if len(infos) != len(addrs) {
+		t.Fatalf("Expected %d connections, got %d", len(addrs), len(infos))
+	}
+	for i, info := range infos {
+		if want := net.JoinHostPort(addrs[i], "5000"); info.RemoteAddr != want {
+			t.Errorf("Expected connection %d from %s, got %s", i, want, info.RemoteAddr)
+		}
+		if info.Established.IsZero() || info.LastActivity.Before(info.Established) {
+			t.Errorf("Expected activity after establishment, got %+v", info)
+		}
+	}
+	if infos[1].BytesOut != 5 || infos[1].BytesIn != 0 {
+		t.Errorf("Expected 5 bytes out and none in, got %d out and %d in", infos[1].BytesOut, infos[1].BytesIn)
+	}
+	if infos[0].TunnelIP != "10.8.0.2" || infos[1].TunnelIP != "" {
+		t.Errorf("Expected only the first connection to have tunnel IP 10.8.0.2, got %q and %q", infos[0].TunnelIP, infos[1].TunnelIP)
+	}
+
+	server.conns.remove(conns[0])
+	if infos, _ := server.ListConnections(); len(infos) != 1 {
+		t.Errorf("Expected 1 connection after removal, got %d", len(infos))
+	}
+
+	// A stopped server reports it rather than an empty list
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server.ctx = ctx
+	if _, err := server.ListConnections(); !errors.Is(err, ErrServerStopped) {
+		t.Errorf("Expected ErrServerStopped from a stopped server, got %v", err)
+	}
+}
+
+func TestPacketSource(t *testing.T) {
+	v4 := make([]byte, ipv4HeaderLen)
+	v4[0] = 0x45
+	copy(v4[12:16], net.IPv4(10, 8, 0, 2).To4())
+	v6 := make([]byte, ipv6HeaderLen)
+	v6[0] = 0x60
+	copy(v6[8:24], net.ParseIP("fd00::2"))
+
+	tests := []struct {
+		name   string
+		packet []byte
+		want   string
+	}{
+		{"ipv4", v4, "10.8.0.2"},
+		{"ipv6", v6, "fd00::2"},
+		{"truncated ipv4", v4[:12], ""},
+		{"not ip", []byte{0x00, 0x01}, ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if ip := packetSource(tt.packet); ip != nil {
+			got = ip.String()
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestServerDisconnect(t *testing.T) {
+	server := &Server{logger: zap.NewNop()}
+
+	// Mirror handleConnection: the transfer runs until the connection fails,
+	// then closes it and drops it from the active set
+	track := func(ip string) (*activityConn, chan struct{}) {
+		local, remote := net.Pipe()
+		t.Cleanup(func() { remote.Close() })
+		conn := newActivityConn(&addrConn{Conn: local, remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+		server.conns.add(conn)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer server.conns.remove(conn)
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}()
+		return conn, done
+	}
+
+	byID, byIDDone := track("10.0.0.2")
+	_, byAddrDone := track("192.0.2.7")
+
+	// Concurrent disconnects of one connection succeed exactly once
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := server.Disconnect(byID.id)
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case !errors.Is(err, ErrConnectionNotFound):
+				t.Errorf("Expected not found for a repeated disconnect, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := succeeded.Load(); n != 1 {
+		t.Errorf("Expected exactly one disconnect to succeed, got %d", n)
+	}
+
+	if err := server.Disconnect("192.0.2.7:5000"); err != nil {
+		t.Fatalf("Failed to disconnect by address: %v", err)
+	}
+
+	for _, done := range []chan struct{}{byIDDone, byAddrDone} {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the transfer to end after disconnect")
+		}
+	}
+	if infos, _ := server.ListConnections(); len(infos) != 0 {
+		t.Errorf("Expected no connections after disconnect, got %+v", infos)
+	}
+
+	if err := server.Disconnect("42"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Errorf("Expected not found for an unknown ID, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package tunnel
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
+)
+
+// rejectLogInterval is the least time between rejected connection warnings
+const rejectLogInterval = 10 * time.Second
+
+// sourcePrefixLen is the IPv6 prefix length connections are limited by, since
+// a single host is commonly given a whole /64
+const sourcePrefixLen = 64
+
+// rejectionLog rate limits the warning logged for a rejected connection, so
+// that a flood of connections does not flood the log as well
+type rejectionLog struct {
+	mu         sync.Mutex
+	last       time.Time
+	suppressed uint64
+}
+
+// due reports whether a rejection at now is logged and, if so, how many
+// rejections were not logged since the last warning
+func (r *rejectionLog) due(now time.Time) (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.last.IsZero() && now.Sub(r.last) < rejectLogInterval {
+		r.suppressed++
+		return 0, false
+	}
+	suppressed := r.suppressed
+	r.last, r.suppressed = now, 0
+	return suppressed, true
+}
+
+// SetConnectionLimiter limits how fast each source IP may open connections.
+// It must be called before Start.
+func (s *Server) SetConnectionLimiter(limiter *throttle.ConnectionLimiter) {
+	s.connLimiter = limiter
+}
+
+// SetRateLimiter applies the rate limits of registry to the forwarded traffic,
+// per client host with per_client. It must be called before Start.
+func (s *Server) SetRateLimiter(registry *throttle.Registry) {
+	s.limits = registry
+}
+
+// SetRateLimiter applies the rate limits of registry to the tunnel traffic. It
+// must be called before Start.
+func (c *Client) SetRateLimiter(registry *throttle.Registry) {
+	c.limits = registry
+}
+
+// useConfiguredConnectionLimit enforces the connection limit of the server's
+// configuration unless a limiter was set with SetConnectionLimiter
+func (s *Server) useConfiguredConnectionLimit() {
+	cfg := s.config.Throttle.Connections
+	if s.connLimiter != nil || !cfg.Enabled {
+		return
+	}
+	s.connLimiter = throttle.NewConnectionLimiter(cfg)
+}
+
+// allowedByConnectionLimit takes a connection token for the source of addr
+func (s *Server) allowedByConnectionLimit(addr net.Addr) bool {
+	if s.connLimiter == nil {
+		return true
+	}
+	return s.connLimiter.Allow(sourceKey(addr))
+}
+
+// rateLimitClient returns the client whose per-client rate limit traffic from
+// addr counts against: its host, so that every connection and stream of a
+// client shares one limit
+func rateLimitClient(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// sourceKey returns the source connections from addr are limited as: its IPv4
+// address, its IPv6 /64 prefix, or the whole address when it has no IP
+func sourceKey(addr net.Addr) string {
+	var ip net.IP
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		ip = tcpAddr.IP
+	} else {
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return addr.String()
+		}
+		if ip = net.ParseIP(host); ip == nil {
+			return host
+		}
+	}
+
+	if ip.To4() != nil {
+		return ip.String()
+	}
+	prefix := net.IPNet{IP: ip.Mask(net.CIDRMask(sourcePrefixLen, 128)), Mask: net.CIDRMask(sourcePrefixLen, 128)}
+	return prefix.String()
+}
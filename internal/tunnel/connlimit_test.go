@@ -0,0 +1,126 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestServerRejectsConnectionsOverRateLimit(t *testing.T) {
+	// Allowed connections are forwarded to a backend that hangs up at once
+	backend, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Network.Name = backend.Addr().String()
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+	cfg.Throttle.Connections = types.ConnectionThrottleConfig{
+		Enabled: true,
+		Rate:    0.01, // No refill while the test runs
+		Burst:   3,
+	}
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	useStartupAdapter(t, &startupAdapter{mockAdapter: newMockAdapter()})
+	server := NewServer(cfg, nil, zap.New(core))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	for i := 0; i < 6; i++ {
+		conn, err := net.DialTimeout("tcp4", server.listeners[0].Addr().String(), time.Second)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+	}
+
+	rejected := func() uint64 {
+		return server.connLimiter.Status().Rejected
+	}
+	if !waitFor(t, func() bool { return rejected() == 3 }) {
+		t.Fatalf("Expected the 3 connections past the burst to be rejected, got %d", rejected())
+	}
+
+	// Only the first rejection within the log interval is logged
+	if warnings := logs.FilterMessage("Rejected connection over the connection rate limit").Len(); warnings != 1 {
+		t.Errorf("Expected 1 rejected connection warning, got %d", warnings)
+	}
+
+	status := server.connLimiter.Status()
+	if len(status.Sources) != 1 || status.Sources[0].Source != "127.0.0.1" {
+		t.Errorf("Expected the rejections to be counted for 127.0.0.1, got %+v", status.Sources)
+	}
+}
+
+func TestRejectionLogSuppressesWarnings(t *testing.T) {
+	var log rejectionLog
+	now := time.Now()
+
+	if _, ok := log.due(now); !ok {
+		t.Fatal("Expected the first rejection to be logged")
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := log.due(now.Add(time.Second)); ok {
+			t.Fatal("Expected rejections within the interval to be suppressed")
+		}
+	}
+
+	suppressed, ok := log.due(now.Add(rejectLogInterval))
+	if !ok || suppressed != 5 {
+		t.Errorf("Expected a warning reporting 5 suppressed rejections, got %d (%v)", suppressed, ok)
+	}
+}
+
+func TestSourceKey(t *testing.T) {
+	tests := []struct {
+		addr net.Addr
+		want string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 4000}, "192.0.2.10"},
+		{&net.TCPAddr{IP: net.ParseIP("::ffff:192.0.2.10"), Port: 4000}, "192.0.2.10"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8:1:2:aaaa::1"), Port: 4000}, "2001:db8:1:2::/64"},
+		{&net.UDPAddr{IP: net.ParseIP("2001:db8:1:2:bbbb::1"), Port: 4000}, "2001:db8:1:2::/64"},
+		{&net.UnixAddr{Name: "/run/sssonector.sock", Net: "unix"}, "/run/sssonector.sock"},
+	}
+	for _, tt := range tests {
+		if got := sourceKey(tt.addr); got != tt.want {
+			t.Errorf("sourceKey(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitClient(t *testing.T) {
+	tests := []struct {
+		addr net.Addr
+		want string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 4000}, "192.0.2.10"},
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 4001}, "192.0.2.10"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4000}, "2001:db8::1"},
+		{&net.UnixAddr{Name: "/run/sssonector.sock", Net: "unix"}, "/run/sssonector.sock"},
+	}
+	for _, tt := range tests {
+		if got := rateLimitClient(tt.addr); got != tt.want {
+			t.Errorf("rateLimitClient(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
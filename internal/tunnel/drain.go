@@ -0,0 +1,121 @@
+package tunnel
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultDrainTimeout bounds how long Stop waits for client connections to
+// finish before closing them
+const DefaultDrainTimeout = 30 * time.Second
+
+// drainTimeout returns how long Stop waits for client connections to finish,
+// from tunnel.drain_timeout
+func (s *Server) drainTimeout() time.Duration {
+	if cfg := s.currentConfig(); cfg != nil && cfg.Config != nil && cfg.Config.Tunnel.DrainTimeout != nil {
+		return *cfg.Config.Tunnel.DrainTimeout
+	}
+	return DefaultDrainTimeout
+}
+
+// drainPollInterval is how often Drain checks for remaining connections
+const drainPollInterval = 50 * time.Millisecond
+
+// DrainStatus reports the progress of a server shutdown
+type DrainStatus struct {
+	StartedAt time.Time     `json:"started_at"`
+	Deadline  time.Time     `json:"deadline"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Initial   int           `json:"initial_connections"` // Active when the drain started
+	Active    int           `json:"active_connections"`
+	Drained   int           `json:"drained_connections"` // Finished since the drain started
+	Forced    int           `json:"forced_connections"`  // Closed at the deadline
+	Done      bool          `json:"done"`
+}
+
+// drainState records a drain in progress or completed
+type drainState struct {
+	started  time.Time
+	deadline time.Time
+	initial  int
+	finished time.Time // Zero while draining
+	forced   int
+}
+
+// Drain stops accepting connections and waits up to timeout for the active
+// client connections to finish, then closes those left. DrainStatus reports
+// the progress meanwhile. It returns the number of connections it closed.
+func (s *Server) Drain(timeout time.Duration) int {
+	// Stop accepting new connections
+	s.cancel()
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+
+	now := time.Now()
+	state := &drainState{
+		started:  now,
+		deadline: now.Add(timeout),
+		initial:  s.conns.count(),
+	}
+	s.drain.Store(state)
+	s.logger.Info("Draining client connections",
+		zap.Int("active_connections", state.initial),
+		zap.Duration("timeout", timeout))
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for s.conns.count() > 0 && time.Now().Before(state.deadline) {
+		<-ticker.C
+	}
+
+	// Close the connections that outlived the deadline
+	left := s.conns.all()
+	for _, conn := range left {
+		conn.Close()
+	}
+	if len(left) > 0 {
+		s.logger.Warn("Closed connections left at the drain deadline",
+			zap.Int("connections", len(left)))
+	}
+
+	s.drain.Store(&drainState{
+		started:  state.started,
+		deadline: state.deadline,
+		initial:  state.initial,
+		finished: time.Now(),
+		forced:   len(left),
+	})
+	s.logger.Info("Drained client connections",
+		zap.Duration("elapsed", time.Since(state.started)),
+		zap.Int("forced_connections", len(left)))
+	return len(left)
+}
+
+// DrainStatus returns the progress of the shutdown, or nil if the server is
+// not shutting down
+func (s *Server) DrainStatus() *DrainStatus {
+	state := s.drain.Load()
+	if state == nil {
+		return nil
+	}
+
+	status := &DrainStatus{
+		StartedAt: state.started,
+		Deadline:  state.deadline,
+		Initial:   state.initial,
+		Active:    s.conns.count(),
+		Forced:    state.forced,
+		Done:      !state.finished.IsZero(),
+	}
+	if status.Done {
+		status.Elapsed = state.finished.Sub(state.started)
+	} else {
+		status.Elapsed = time.Since(state.started)
+	}
+	if drained := state.initial - status.Active - state.forced; drained > 0 {
+		status.Drained = drained
+	}
+	return status
+}
@@ -0,0 +1,109 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+// drainTestServer returns a server tracking n open client connections
+func drainTestServer(t *testing.T, n int) (*Server, []*activityConn) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{logger: zap.NewNop(), ctx: ctx, cancel: cancel}
+
+	conns := make([]*activityConn, n)
+	for i := range conns {
+		local, remote := net.Pipe()
+		t.Cleanup(func() { remote.Close() })
+		conns[i] = newActivityConn(local)
+		s.conns.add(conns[i])
+	}
+	return s, conns
+}
+
+func TestDrainReportsProgress(t *testing.T) {
+	s, conns := drainTestServer(t, 3)
+
+	if status := s.DrainStatus(); status != nil {
+		t.Fatalf("Expected no drain status before shutdown, got %+v", status)
+	}
+
+	done := make(chan int)
+	go func() { done <- s.Drain(5 * time.Second) }()
+
+	// Wait for the drain to start
+	deadline := time.Now().Add(2 * time.Second)
+	for s.DrainStatus() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Connections finish one at a time
+	for i, conn := range conns {
+		status := s.DrainStatus()
+		if status == nil || status.Done {
+			t.Fatalf("Expected a drain in progress, got %+v", status)
+		}
+		if status.Initial != 3 || status.Active != 3-i || status.Drained != i {
+			t.Errorf("Expected %d active and %d drained of 3, got %+v", 3-i, i, status)
+		}
+		if !status.Deadline.Equal(status.StartedAt.Add(5 * time.Second)) {
+			t.Errorf("Expected the deadline 5s after the start, got %+v", status)
+		}
+		s.conns.remove(conn)
+	}
+
+	select {
+	case forced := <-done:
+		if forced != 0 {
+			t.Errorf("Expected no connections to be closed, got %d", forced)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the drain to finish once no connections remain")
+	}
+
+	status := s.DrainStatus()
+	if !status.Done || status.Active != 0 || status.Drained != 3 || status.Forced != 0 {
+		t.Errorf("Expected a completed drain of 3 connections, got %+v", status)
+	}
+	if status.Elapsed <= 0 || status.Elapsed > 5*time.Second {
+		t.Errorf("Expected the elapsed drain time to be recorded, got %s", status.Elapsed)
+	}
+}
+
+func TestDrainClosesConnectionsAtDeadline(t *testing.T) {
+	s, conns := drainTestServer(t, 2)
+	s.conns.remove(conns[0])
+
+	if forced := s.Drain(100 * time.Millisecond); forced != 1 {
+		t.Fatalf("Expected one connection to be closed at the deadline, got %d", forced)
+	}
+	if _, err := conns[1].Write([]byte("x")); err == nil {
+		t.Error("Expected the remaining connection to be closed")
+	}
+
+	status := s.DrainStatus()
+	if !status.Done || status.Forced != 1 {
+		t.Errorf("Expected a completed drain with one forced close, got %+v", status)
+	}
+	if status.Elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the drain to wait for the deadline, got %s", status.Elapsed)
+	}
+}
+
+func TestDrainTimeoutFromConfig(t *testing.T) {
+	s := &Server{config: &types.AppConfig{Config: &types.Config{}}}
+	if timeout := s.drainTimeout(); timeout != DefaultDrainTimeout {
+		t.Errorf("Expected the default drain timeout when unset, got %s", timeout)
+	}
+
+	immediate := time.Duration(0)
+	s.config.Config.Tunnel.DrainTimeout = &immediate
+	if timeout := s.drainTimeout(); timeout != 0 {
+		t.Errorf("Expected a drain timeout of 0 to close connections at once, got %s", timeout)
+	}
+}
@@ -0,0 +1,140 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+// Defaults for endpoint health checks when none are configured
+const (
+	DefaultEndpointCheckInterval      = 10 * time.Second
+	DefaultEndpointCheckTimeout       = 2 * time.Second
+	DefaultEndpointHealthyThreshold   = 2
+	DefaultEndpointUnhealthyThreshold = 3
+)
+
+// EndpointHealthConfig controls active health checking of server endpoints
+type EndpointHealthConfig struct {
+	Interval           time.Duration // Time between checks of each endpoint
+	Timeout            time.Duration // Bound on a single probe
+	HealthyThreshold   int           // Consecutive successful probes that mark a down endpoint up
+	UnhealthyThreshold int           // Consecutive failed probes that mark an endpoint down
+}
+
+// EndpointHealthConfigFrom converts the configured endpoint health checks
+func EndpointHealthConfigFrom(cfg types.EndpointHealthConfig) EndpointHealthConfig {
+	return EndpointHealthConfig{
+		Interval:           cfg.Interval,
+		Timeout:            cfg.Timeout,
+		HealthyThreshold:   cfg.HealthyThreshold,
+		UnhealthyThreshold: cfg.UnhealthyThreshold,
+	}
+}
+
+// EndpointProbe checks that the endpoint at address accepts connections
+type EndpointProbe func(ctx context.Context, address string) error
+
+// DialProbe returns a probe that opens a connection with dial and closes it
+func DialProbe(dial func(ctx context.Context, address string) (net.Conn, error)) EndpointProbe {
+	return func(ctx context.Context, address string) error {
+		conn, err := dial(ctx, address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// EndpointHealthChecker periodically probes the endpoints of an endpoint
+// manager, so that an endpoint that went away is skipped before a client
+// connection has to fail against it
+type EndpointHealthChecker struct {
+	manager   *EndpointManager
+	config    EndpointHealthConfig
+	probe     EndpointProbe
+	logger    *zap.Logger
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// NewEndpointHealthChecker creates a health checker for the endpoints of manager
+func NewEndpointHealthChecker(manager *EndpointManager, cfg EndpointHealthConfig, probe EndpointProbe, logger *zap.Logger) *EndpointHealthChecker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultEndpointCheckInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultEndpointCheckTimeout
+	}
+	if cfg.HealthyThreshold <= 0 {
+		cfg.HealthyThreshold = DefaultEndpointHealthyThreshold
+	}
+	if cfg.UnhealthyThreshold <= 0 {
+		cfg.UnhealthyThreshold = DefaultEndpointUnhealthyThreshold
+	}
+	return &EndpointHealthChecker{
+		manager: manager,
+		config:  cfg,
+		probe:   probe,
+		logger:  logger,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Start begins checking the endpoints at the configured interval. Calls after
+// the first have no effect.
+func (h *EndpointHealthChecker) Start() {
+	h.startOnce.Do(func() {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+
+			ticker := time.NewTicker(h.config.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-h.stopCh:
+					return
+				case <-ticker.C:
+					h.Check(context.Background())
+				}
+			}
+		}()
+	})
+}
+
+// Stop stops checking and waits for a running check to finish
+func (h *EndpointHealthChecker) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+	h.wg.Wait()
+}
+
+// Check probes every endpoint once, concurrently, and records the results
+func (h *EndpointHealthChecker) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, address := range h.manager.addresses() {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+			err := h.probe(probeCtx, address)
+			cancel()
+
+			if err != nil {
+				h.logger.Debug("Endpoint health check failed", zap.String("address", address), zap.Error(err))
+			}
+			h.manager.recordProbe(address, err, h.config.HealthyThreshold, h.config.UnhealthyThreshold)
+		}(address)
+	}
+	wg.Wait()
+}
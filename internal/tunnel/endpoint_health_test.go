@@ -0,0 +1,103 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// stubProbe fails probes of an address while it is marked unreachable
+type stubProbe struct {
+	mu          sync.Mutex
+	unreachable map[string]bool
+}
+
+func (p *stubProbe) set(address string, unreachable bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unreachable[address] = unreachable
+}
+
+func (p *stubProbe) probe(ctx context.Context, address string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unreachable[address] {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestEndpointHealthCheckTransitions(t *testing.T) {
+	m := NewEndpointManager(testBreakerConfig(), zap.NewNop())
+	m.Add("10.0.0.1:8443", 1)
+	m.Add("10.0.0.2:8443", 1)
+
+	stub := &stubProbe{unreachable: map[string]bool{"10.0.0.2:8443": true}}
+	checker := NewEndpointHealthChecker(m, EndpointHealthConfig{
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 3,
+	}, stub.probe, zap.NewNop())
+
+	// Failures below the threshold leave the endpoint up
+	for i := 0; i < 2; i++ {
+		checker.Check(context.Background())
+	}
+	if status := m.List()[1]; status.Down || !status.Healthy {
+		t.Fatalf("Expected the endpoint to stay up after 2 failed checks, got %+v", status)
+	}
+
+	checker.Check(context.Background())
+	status := m.List()[1]
+	if !status.Down || status.Healthy || status.Transitions != 1 || status.LastProbe == "" {
+		t.Fatalf("Expected the endpoint to be down after 3 failed checks, got %+v", status)
+	}
+	for i := 0; i < 5; i++ {
+		if address, _ := m.Next(); address != "10.0.0.1:8443" {
+			t.Errorf("Expected the down endpoint to be skipped, got %s", address)
+		}
+	}
+
+	// The endpoint recovers, but one success is not enough to mark it up
+	stub.set("10.0.0.2:8443", false)
+	checker.Check(context.Background())
+	if status := m.List()[1]; !status.Down {
+		t.Fatalf("Expected the endpoint to stay down after 1 successful check, got %+v", status)
+	}
+
+	checker.Check(context.Background())
+	status = m.List()[1]
+	if status.Down || !status.Healthy || status.Transitions != 2 || status.LastProbe != "" {
+		t.Fatalf("Expected the endpoint to be up after 2 successful checks, got %+v", status)
+	}
+	if first := m.List()[0]; first.Transitions != 0 {
+		t.Errorf("Expected no transitions of the reachable endpoint, got %d", first.Transitions)
+	}
+}
+
+func TestEndpointHealthCheckerRunsPeriodically(t *testing.T) {
+	m := NewEndpointManager(testBreakerConfig(), zap.NewNop())
+	m.Add("10.0.0.1:8443", 1)
+
+	stub := &stubProbe{unreachable: map[string]bool{"10.0.0.1:8443": true}}
+	checker := NewEndpointHealthChecker(m, EndpointHealthConfig{
+		Interval:           5 * time.Millisecond,
+		UnhealthyThreshold: 2,
+	}, stub.probe, zap.NewNop())
+	checker.Start()
+	defer checker.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !m.List()[0].Down {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected periodic checks to mark the endpoint down")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := m.Next(); !errors.Is(err, ErrNoHealthyEndpoints) {
+		t.Errorf("Expected no healthy endpoints, got %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"go.uber.org/zap"
+)
+
+// EndpointStatus describes one server endpoint of the client
+type EndpointStatus struct {
+	Address    string `json:"address"`
+	ServerName string `json:"server_name,omitempty"` // Verified in place of the address's host
+	Weight     int    `json:"weight"`
+	Healthy    bool   `json:"healthy"`
+	State      string `json:"state"` // Circuit breaker state: closed, half-open or open
+	Selected   uint64 `json:"selected"`
+	Failures   uint64 `json:"failures"`
+	// Down is set when active health checks found the endpoint unreachable
+	Down        bool   `json:"down"`
+	Transitions uint64 `json:"transitions"` // Times health checks marked it up or down
+	LastProbe   string `json:"last_probe_error,omitempty"`
+}
+
+// endpoint is a server address with its weighted round-robin state and breaker
+type endpoint struct {
+	address    string
+	serverName string // Hostname the certificate is verified against; empty uses the host
+	weight     int
+	current    int // Smooth weighted round-robin credit
+	selected   uint64
+	breaker    *resilience.CircuitBreaker
+
+	// Active health check state
+	down        bool
+	successes   int // Consecutive successful probes
+	failures    int // Consecutive failed probes
+	transitions uint64
+	probeErr    error
+}
+
+// EndpointManager spreads new client connections over a set of server
+// endpoints by smooth weighted round-robin, skipping endpoints whose circuit
+// breaker is open or that health checks marked down
+type EndpointManager struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+	breaker   resilience.CircuitBreakerConfig  // Template for each endpoint's breaker
+	breakers  *resilience.CircuitBreakerStates // The endpoints' breakers, by name
+	logger    *zap.Logger
+}
+
+// NewEndpointManager creates an endpoint manager. breaker configures the circuit
+// breaker of each endpoint; nil uses the breaker defaults.
+func NewEndpointManager(breaker *resilience.CircuitBreakerConfig, logger *zap.Logger) *EndpointManager {
+	if breaker == nil {
+		breaker = &resilience.CircuitBreakerConfig{
+			FailureThreshold: 0.5,
+			RecoveryTimeout:  30 * time.Second,
+			SuccessThreshold: 1,
+			MinRequests:      3,
+		}
+	}
+	return &EndpointManager{
+		breaker:  *breaker,
+		breakers: resilience.NewCircuitBreakerStates(logger),
+		logger:   logger,
+	}
+}
+
+// Add registers a server endpoint in host:port form with a positive weight
+func (m *EndpointManager) Add(address string, weight int) error {
+	return m.AddWithServerName(address, weight, "")
+}
+
+// AddWithServerName registers a server endpoint whose certificate is verified
+// against serverName rather than the address's host
+func (m *EndpointManager) AddWithServerName(address string, weight int, serverName string) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return fmt.Errorf("%w: invalid endpoint address %q", ErrInvalidConfiguration, address)
+	}
+	if weight <= 0 {
+		return fmt.Errorf("%w: endpoint weight must be positive, got %d", ErrInvalidConfiguration, weight)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.find(address) >= 0 {
+		return fmt.Errorf("%w: %s", ErrEndpointExists, address)
+	}
+
+	cfg := m.breaker
+	cfg.Name = "endpoint " + address
+	breaker := resilience.NewCircuitBreaker(&cfg, m.logger)
+	if err := m.breakers.AddBreaker(cfg.Name, breaker); err != nil {
+		return err
+	}
+	m.endpoints = append(m.endpoints, &endpoint{
+		address:    address,
+		serverName: serverName,
+		weight:     weight,
+		breaker:    breaker,
+	})
+	m.logger.Info("Added server endpoint", zap.String("address", address), zap.Int("weight", weight))
+	return nil
+}
+
+// Breakers returns the circuit breakers of the endpoints, for health checks and
+// metrics. Endpoints added or removed later are reflected.
+func (m *EndpointManager) Breakers() *resilience.CircuitBreakerStates {
+	return m.breakers
+}
+
+// Remove unregisters a server endpoint. Established connections are not closed.
+func (m *EndpointManager) Remove(address string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.find(address)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrEndpointNotFound, address)
+	}
+	m.breakers.RemoveBreaker(m.endpoints[i].breaker.Name())
+	m.endpoints = append(m.endpoints[:i], m.endpoints[i+1:]...)
+	m.logger.Info("Removed server endpoint", zap.String("address", address))
+	return nil
+}
+
+// List returns the endpoints in the order they were added
+func (m *EndpointManager) List() []EndpointStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]EndpointStatus, 0, len(m.endpoints))
+	for _, e := range m.endpoints {
+		stats := e.breaker.GetStats()
+		status := EndpointStatus{
+			Address:     e.address,
+			ServerName:  e.serverName,
+			Weight:      e.weight,
+			Healthy:     m.healthy(e),
+			State:       breakerState(stats.State),
+			Selected:    e.selected,
+			Failures:    stats.TotalFailures,
+			Down:        e.down,
+			Transitions: e.transitions,
+		}
+		if e.probeErr != nil {
+			status.LastProbe = e.probeErr.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Len returns the number of registered endpoints
+func (m *EndpointManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.endpoints)
+}
+
+// Next selects the endpoint for a new connection
+func (m *EndpointManager) Next() (string, error) {
+	e, err := m.next()
+	if err != nil {
+		return "", err
+	}
+	return e.address, nil
+}
+
+// Dial connects to the next endpoint through its circuit breaker, so that failed
+// dials mark the endpoint unhealthy. dial is passed the endpoint's server name,
+// empty unless set.
+func (m *EndpointManager) Dial(ctx context.Context, dial func(ctx context.Context, address, serverName string) (net.Conn, error)) (net.Conn, error) {
+	e, err := m.next()
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	err = e.breaker.Call(ctx, func(ctx context.Context) error {
+		var dialErr error
+		conn, dialErr = dial(ctx, e.address, e.serverName)
+		return dialErr
+	})
+	if err != nil {
+		return nil, apperrors.Network(apperrors.ErrNetworkDial, err, "failed to connect to endpoint %s", e.address)
+	}
+	return conn, nil
+}
+
+// next picks a healthy endpoint by smooth weighted round-robin: every healthy
+// endpoint gains its weight in credit, the one with the most credit is chosen
+// and pays back the total weight
+func (m *EndpointManager) next() (*endpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var best *endpoint
+	total := 0
+	for _, e := range m.endpoints {
+		if !m.healthy(e) {
+			continue
+		}
+		e.current += e.weight
+		total += e.weight
+		if best == nil || e.current > best.current {
+			best = e
+		}
+	}
+	if best == nil {
+		return nil, ErrNoHealthyEndpoints
+	}
+
+	best.current -= total
+	best.selected++
+	return best, nil
+}
+
+// recordProbe applies the result of an active health check to address. The
+// endpoint is marked down after downAfter consecutive failures and up again
+// after upAfter consecutive successes. Probes of removed endpoints are ignored.
+func (m *EndpointManager) recordProbe(address string, err error, upAfter, downAfter int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.find(address)
+	if i < 0 {
+		return
+	}
+	e := m.endpoints[i]
+	e.probeErr = err
+
+	if err != nil {
+		e.successes = 0
+		e.failures++
+		if !e.down && e.failures >= downAfter {
+			e.down = true
+			e.transitions++
+			m.logger.Warn("Server endpoint is down",
+				zap.String("address", address),
+				zap.Int("failed_checks", e.failures),
+				zap.Error(err))
+		}
+		return
+	}
+
+	e.failures = 0
+	e.successes++
+	if e.down && e.successes >= upAfter {
+		e.down = false
+		e.transitions++
+		m.logger.Info("Server endpoint is up",
+			zap.String("address", address),
+			zap.Int("successful_checks", e.successes))
+	}
+}
+
+// addresses returns the registered endpoint addresses
+func (m *EndpointManager) addresses() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	addresses := make([]string, len(m.endpoints))
+	for i, e := range m.endpoints {
+		addresses[i] = e.address
+	}
+	return addresses
+}
+
+// healthy reports whether the endpoint may take connections: health checks
+// have not marked it down, and its breaker is not open or has been open long
+// enough to let a probe through
+func (m *EndpointManager) healthy(e *endpoint) bool {
+	if e.down {
+		return false
+	}
+	stats := e.breaker.GetStats()
+	if stats.State != resilience.StateOpen {
+		return true
+	}
+	return time.Since(stats.LastStateTransition) >= m.breaker.RecoveryTimeout
+}
+
+// find returns the index of address, or -1; the caller holds mu
+func (m *EndpointManager) find(address string) int {
+	for i, e := range m.endpoints {
+		if e.address == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// breakerState names a circuit breaker state
+func breakerState(state resilience.CircuitBreakerState) string {
+	switch state {
+	case resilience.StateClosed:
+		return "closed"
+	case resilience.StateHalfOpen:
+		return "half-open"
+	case resilience.StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,141 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"go.uber.org/zap"
+)
+
+func testBreakerConfig() *resilience.CircuitBreakerConfig {
+	return &resilience.CircuitBreakerConfig{
+		FailureThreshold: 0.5,
+		RecoveryTimeout:  time.Hour,
+		SuccessThreshold: 1,
+		MinRequests:      3,
+	}
+}
+
+func TestEndpointManagerWeightedSelection(t *testing.T) {
+	m := NewEndpointManager(testBreakerConfig(), zap.NewNop())
+	for _, e := range []struct {
+		address string
+		weight  int
+	}{
+		{"10.0.0.1:8443", 1},
+		{"10.0.0.2:8443", 3},
+		{"10.0.0.3:8443", 5},
+	} {
+		if err := m.Add(e.address, e.weight); err != nil {
+			t.Fatalf("Failed to add endpoint: %v", err)
+		}
+	}
+	m.endpoints[2].breaker.ForceOpen()
+
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		address, err := m.Next()
+		if err != nil {
+			t.Fatalf("Failed to select endpoint: %v", err)
+		}
+		counts[address]++
+	}
+
+	if counts["10.0.0.3:8443"] != 0 {
+		t.Errorf("Expected the unhealthy endpoint to be skipped, selected %d times", counts["10.0.0.3:8443"])
+	}
+	if counts["10.0.0.1:8443"] != 100 || counts["10.0.0.2:8443"] != 300 {
+		t.Errorf("Expected a 1:3 split of 400 connections, got %d and %d", counts["10.0.0.1:8443"], counts["10.0.0.2:8443"])
+	}
+
+	statuses := m.List()
+	if len(statuses) != 3 || statuses[2].Healthy || statuses[2].State != "open" {
+		t.Errorf("Expected the third endpoint to be listed as open, got %+v", statuses)
+	}
+	if statuses[1].Selected != 300 {
+		t.Errorf("Expected 300 selections of the second endpoint, got %d", statuses[1].Selected)
+	}
+}
+
+func TestEndpointManagerDialFailuresOpenBreaker(t *testing.T) {
+	m := NewEndpointManager(testBreakerConfig(), zap.NewNop())
+	m.Add("10.0.0.1:8443", 1)
+	m.Add("10.0.0.2:8443", 1)
+
+	refused := errors.New("connection refused")
+	dial := func(ctx context.Context, address, serverName string) (net.Conn, error) {
+		if address == "10.0.0.2:8443" {
+			return nil, refused
+		}
+		local, remote := net.Pipe()
+		remote.Close()
+		return local, nil
+	}
+
+	for i := 0; i < 20; i++ {
+		conn, err := m.Dial(context.Background(), dial)
+		if err == nil {
+			conn.Close()
+		}
+	}
+
+	statuses := m.List()
+	if statuses[1].Healthy {
+		t.Errorf("Expected repeated dial failures to mark the endpoint unhealthy, got %+v", statuses[1])
+	}
+	if !statuses[0].Healthy {
+		t.Errorf("Expected the reachable endpoint to stay healthy, got %+v", statuses[0])
+	}
+	for i := 0; i < 5; i++ {
+		if address, _ := m.Next(); address != "10.0.0.1:8443" {
+			t.Errorf("Expected only the healthy endpoint to be selected, got %s", address)
+		}
+	}
+}
+
+func TestEndpointManagerAddRemove(t *testing.T) {
+	m := NewEndpointManager(testBreakerConfig(), zap.NewNop())
+
+	if _, err := m.Next(); !errors.Is(err, ErrNoHealthyEndpoints) {
+		t.Errorf("Expected no healthy endpoints, got %v", err)
+	}
+	if err := m.Add("10.0.0.1", 1); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected an address without a port to be rejected, got %v", err)
+	}
+	if err := m.Add("10.0.0.1:8443", 0); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected a zero weight to be rejected, got %v", err)
+	}
+	if err := m.Add("10.0.0.1:8443", 1); err != nil {
+		t.Fatalf("Failed to add endpoint: %v", err)
+	}
+	if err := m.Add("10.0.0.1:8443", 2); !errors.Is(err, ErrEndpointExists) {
+		t.Errorf("Expected a duplicate endpoint to be rejected, got %v", err)
+	}
+	if err := m.Remove("10.0.0.1:8443"); err != nil {
+		t.Fatalf("Failed to remove endpoint: %v", err)
+	}
+	if err := m.Remove("10.0.0.1:8443"); !errors.Is(err, ErrEndpointNotFound) {
+		t.Errorf("Expected not found for a removed endpoint, got %v", err)
+	}
+}
+
+func TestEndpointManagerRegistersBreakers(t *testing.T) {
+	m := NewEndpointManager(testBreakerConfig(), zap.NewNop())
+	for _, address := range []string{"10.0.0.1:8443", "10.0.0.2:8443"} {
+		if err := m.Add(address, 1); err != nil {
+			t.Fatalf("Failed to add endpoint: %v", err)
+		}
+	}
+	if err := m.Remove("10.0.0.1:8443"); err != nil {
+		t.Fatalf("Failed to remove endpoint: %v", err)
+	}
+
+	breakers := m.Breakers().GetAllBreakers()
+	if len(breakers) != 1 || breakers[0].Name() != "endpoint 10.0.0.2:8443" {
+		t.Errorf("Expected only the remaining endpoint's breaker, got %d breakers", len(breakers))
+	}
+}
@@ -1,20 +1,60 @@
 package tunnel
 
-import "errors"
+import (
+	"errors"
+
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+)
 
 var (
 	// ErrCertManagerNotInitialized is returned when attempting to use TLS functions without initializing the certificate manager
 	ErrCertManagerNotInitialized = errors.New("certificate manager not initialized")
 
 	// ErrInvalidMode is returned when an invalid tunnel mode is specified
-	ErrInvalidMode = errors.New("invalid tunnel mode (must be 'server' or 'client')")
+	ErrInvalidMode = apperrors.Config(apperrors.ErrConfigInvalid, nil, "invalid tunnel mode (must be 'server' or 'client')")
 
 	// ErrConnectionClosed is returned when attempting to use a closed connection
 	ErrConnectionClosed = errors.New("connection closed")
 
 	// ErrInterfaceNotInitialized is returned when attempting to use a tunnel without initializing the network interface
-	ErrInterfaceNotInitialized = errors.New("network interface not initialized")
+	ErrInterfaceNotInitialized = apperrors.Tunnel(apperrors.ErrTunnelInterface, nil, "network interface not initialized")
 
 	// ErrInvalidConfiguration is returned when the tunnel configuration is invalid
-	ErrInvalidConfiguration = errors.New("invalid tunnel configuration")
+	ErrInvalidConfiguration = apperrors.Config(apperrors.ErrConfigInvalid, nil, "invalid tunnel configuration")
+
+	// ErrConnectionNotFound is returned when no active connection matches a disconnect request
+	ErrConnectionNotFound = errors.New("connection not found")
+
+	// ErrServerStopped is returned when listing the connections of a server that has stopped
+	ErrServerStopped = errors.New("server stopped")
+
+	// ErrNetworkPort is returned when the server cannot bind its listen address
+	ErrNetworkPort = apperrors.Network(apperrors.ErrNetworkPort, nil, "listen port unavailable")
+
+	// ErrEndpointExists is returned when adding a server endpoint that is already registered
+	ErrEndpointExists = errors.New("endpoint already exists")
+
+	// ErrEndpointNotFound is returned when removing a server endpoint that is not registered
+	ErrEndpointNotFound = errors.New("endpoint not found")
+
+	// ErrNoHealthyEndpoints is returned when every server endpoint is down or has its circuit breaker open
+	ErrNoHealthyEndpoints = apperrors.Network(apperrors.ErrNetworkDial, nil, "no healthy endpoints")
+
+	// ErrReloadRequiresRestart is returned when a reloaded configuration changes a setting that only applies on restart
+	ErrReloadRequiresRestart = errors.New("setting requires a restart")
+
+	// ErrInvalidProxyHeader is returned when an accepted connection does not start with a valid PROXY protocol header
+	ErrInvalidProxyHeader = errors.New("invalid PROXY protocol header")
+
+	// ErrAuthenticationFailed is returned when a client's credential is rejected, whatever was wrong with it
+	ErrAuthenticationFailed = errors.New("authentication failed")
+
+	// ErrMuxRefused is returned when the server does not accept multiplexing on a new connection
+	ErrMuxRefused = errors.New("server refused multiplexing")
+
+	// ErrMuxSessionClosed is returned when using a multiplexed connection, or one of its streams, after it closed
+	ErrMuxSessionClosed = errors.New("multiplexed connection closed")
+
+	// ErrPeerTimeout is returned when a transfer closes because the peer sent nothing, not even a heartbeat, for too long
+	ErrPeerTimeout = errors.New("peer missed heartbeats")
 )
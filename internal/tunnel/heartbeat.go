@@ -0,0 +1,123 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+// heartbeatSettings are the keepalive interval and tolerance of a transfer.
+// Heartbeats are empty frames; no tunneled packet is empty, so the framed
+// stream tells them apart.
+type heartbeatSettings struct {
+	interval time.Duration // Between heartbeats; 0 disables them
+	misses   int           // Intervals without a frame before the peer is dead
+}
+
+// heartbeatSettingsFrom reads the keepalive of cfg when heartbeats are enabled.
+// A keepalive that does not parse disables heartbeats, as the validator rejects it.
+func heartbeatSettingsFrom(cfg *types.AppConfig) heartbeatSettings {
+	if cfg == nil || cfg.Config == nil || !cfg.Config.Tunnel.Heartbeat || cfg.Config.Tunnel.Keepalive == "" {
+		return heartbeatSettings{}
+	}
+	interval, err := time.ParseDuration(cfg.Config.Tunnel.Keepalive)
+	if err != nil || interval <= 0 {
+		return heartbeatSettings{}
+	}
+
+	settings := heartbeatSettings{interval: interval, misses: cfg.Config.Tunnel.KeepaliveMisses}
+	if settings.misses <= 0 {
+		settings.misses = types.DefaultKeepaliveMisses
+	}
+	return settings
+}
+
+// frameWriter frames each packet written to it with its length and writes it
+// at once, for a framed stream without coalescing
+type frameWriter struct {
+	w  io.Writer
+	mu sync.Mutex // Keeps heartbeats from interleaving with packets
+}
+
+func (f *frameWriter) Write(packet []byte) (int, error) {
+	if len(packet) > maxFrameSize {
+		return 0, fmt.Errorf("%w: packet of %d bytes exceeds the %d byte limit", errBadFrame, len(packet), maxFrameSize)
+	}
+
+	frame := make([]byte, 0, frameHeaderSize+len(packet))
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(packet)))
+	frame = append(frame, packet...)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.w.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(packet), nil
+}
+
+// heartbeat sends empty frames to the peer every interval and watches for
+// frames from it, declaring the peer dead after misses intervals of silence
+type heartbeat struct {
+	settings heartbeatSettings
+	logger   *zap.Logger
+	lastSeen atomic.Int64 // UnixNano of the last frame from the peer
+}
+
+func newHeartbeat(settings heartbeatSettings, logger *zap.Logger) *heartbeat {
+	h := &heartbeat{settings: settings, logger: logger}
+	h.seen()
+	return h
+}
+
+// seen records a frame, packet or heartbeat, from the peer
+func (h *heartbeat) seen() {
+	h.lastSeen.Store(time.Now().UnixNano())
+}
+
+// send writes a heartbeat to w every interval until stop is closed or a write
+// fails; the copy loops report a broken connection
+func (h *heartbeat) send(w io.Writer, stop <-chan struct{}) {
+	ticker := time.NewTicker(h.settings.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := w.Write(nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// watch returns ErrPeerTimeout once the peer has been silent for misses
+// intervals, or nil when stop is closed. It runs apart from send, so a write
+// blocked on a dead peer does not hold up the check.
+func (h *heartbeat) watch(stop <-chan struct{}) error {
+	timeout := time.Duration(h.settings.misses) * h.settings.interval
+	ticker := time.NewTicker(h.settings.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			silence := time.Since(time.Unix(0, h.lastSeen.Load()))
+			if silence > timeout {
+				h.logger.Warn("Peer missed heartbeats, closing connection",
+					zap.Duration("silence", silence),
+					zap.Int("misses", h.settings.misses),
+				)
+				return ErrPeerTimeout
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package tunnel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+func heartbeatConfig(keepalive string) *types.AppConfig {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Tunnel.Keepalive = keepalive
+	cfg.Config.Tunnel.KeepaliveMisses = 3
+	cfg.Config.Tunnel.Heartbeat = true
+	return cfg
+}
+
+func TestTransferSendsHeartbeats(t *testing.T) {
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	transfer := NewTransfer(src, dst, heartbeatConfig("20ms"), zap.NewNop())
+	result := make(chan error, 1)
+	go func() { result <- transfer.Start() }()
+	defer transfer.Stop()
+
+	// The peer answers with heartbeats of its own, keeping the connection up
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := srcPeer.Write([]byte{0, 0}); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Heartbeats are empty frames, about one per interval
+	srcPeer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	start := time.Now()
+	header := make([]byte, frameHeaderSize)
+	for i := 0; i < 10; i++ {
+		if _, err := io.ReadFull(srcPeer, header); err != nil {
+			t.Fatalf("Failed to read heartbeat %d: %v", i, err)
+		}
+		if !bytes.Equal(header, []byte{0, 0}) {
+			t.Fatalf("Expected an empty frame, got header %v", header)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected 10 heartbeats to take about 200ms, took %v", elapsed)
+	}
+
+	// Ten intervals on, the peer's heartbeats kept the transfer going
+	select {
+	case err := <-result:
+		t.Fatalf("Expected the transfer to keep running, it ended with %v", err)
+	default:
+	}
+
+	// A packet from the peer still reaches dst, and the heartbeats do not
+	go func() {
+		(&frameWriter{w: srcPeer}).Write([]byte("packet"))
+	}()
+	dstPeer.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 64)
+	n, err := dstPeer.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read packet: %v", err)
+	}
+	if string(buf[:n]) != "packet" {
+		t.Errorf("Expected the packet alone, got %q", buf[:n])
+	}
+}
+
+func TestTransferClosesSilentPeer(t *testing.T) {
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	// The peer reads the heartbeats but never sends any
+	go io.Copy(io.Discard, srcPeer)
+
+	transfer := NewTransfer(src, dst, heartbeatConfig("10ms"), zap.NewNop())
+	result := make(chan error, 1)
+	go func() { result <- transfer.Start() }()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrPeerTimeout) {
+			t.Errorf("Expected ErrPeerTimeout, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the transfer to end after 3 missed heartbeats")
+	}
+
+	// The connections were closed
+	if _, err := dstPeer.Write([]byte("late")); err == nil {
+		t.Error("Expected dst to be closed")
+	}
+}
+
+func TestHeartbeatSettings(t *testing.T) {
+	tests := []struct {
+		keepalive string
+		misses    int
+		expected  heartbeatSettings
+	}{
+		{"", 0, heartbeatSettings{}},
+		{"invalid", 0, heartbeatSettings{}},
+		{"30s", 0, heartbeatSettings{interval: 30 * time.Second, misses: types.DefaultKeepaliveMisses}},
+		{"5s", 5, heartbeatSettings{interval: 5 * time.Second, misses: 5}},
+	}
+
+	for _, tt := range tests {
+		cfg := heartbeatConfig(tt.keepalive)
+		cfg.Config.Tunnel.KeepaliveMisses = tt.misses
+		if settings := heartbeatSettingsFrom(cfg); settings != tt.expected {
+			t.Errorf("Keepalive %q: expected %+v, got %+v", tt.keepalive, tt.expected, settings)
+		}
+	}
+
+	// A keepalive alone leaves the stream unframed
+	cfg := heartbeatConfig("30s")
+	cfg.Config.Tunnel.Heartbeat = false
+	if settings := heartbeatSettingsFrom(cfg); settings != (heartbeatSettings{}) {
+		t.Errorf("Expected no heartbeats without tunnel.heartbeat, got %+v", settings)
+	}
+}
@@ -0,0 +1,209 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"go.uber.org/zap"
+)
+
+// DefaultServerHandshakeTimeout bounds the TLS handshake of an accepted connection
+const DefaultServerHandshakeTimeout = 10 * time.Second
+
+// PeerIdentity is the identity in a client's verified TLS certificate
+type PeerIdentity struct {
+	CommonName string
+	Serial     string // Decimal, as used by the certificate store
+}
+
+// verifiedPeerIdentity returns the identity of the verified client certificate
+// of a TLS connection. Connections that presented none return false.
+func verifiedPeerIdentity(state tls.ConnectionState) (PeerIdentity, bool) {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return PeerIdentity{}, false
+	}
+	leaf := state.VerifiedChains[0][0]
+	return PeerIdentity{
+		CommonName: leaf.Subject.CommonName,
+		Serial:     leaf.SerialNumber.String(),
+	}, true
+}
+
+// fields returns the identity as log fields, none if it is empty
+func (p PeerIdentity) fields() []zap.Field {
+	if p.CommonName == "" && p.Serial == "" {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("client_cn", p.CommonName),
+		zap.String("client_serial", p.Serial),
+	}
+}
+
+// TLSConfigFrom returns the TLS settings for the certificate, key and CA in
+// auth, nil when no certificate and key are configured and the tunnel runs
+// over plain TCP
+func TLSConfigFrom(cfg *types.Config, logger *zap.Logger) *TLSConfig {
+	if cfg.Auth.CertFile == "" || cfg.Auth.KeyFile == "" {
+		return nil
+	}
+	return &TLSConfig{
+		CertFile:      cfg.Auth.CertFile,
+		KeyFile:       cfg.Auth.KeyFile,
+		CAFile:        cfg.Auth.CAFile,
+		SecurityLevel: SecurityModern,
+		Logger:        logger,
+
+		DisableSessionResumption: cfg.Security.TLS.DisableSessionResumption,
+	}
+}
+
+// newConfiguredTLSManager creates the TLS manager for the certificate in auth,
+// nil when none is configured
+func newConfiguredTLSManager(cfg *types.Config, logger *zap.Logger) (*TLSManager, error) {
+	tlsConfig := TLSConfigFrom(cfg, logger)
+	if tlsConfig == nil {
+		return nil, nil
+	}
+	manager, err := NewTLSManager(tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	return manager, nil
+}
+
+// useConfiguredTLS sets the TLS manager for the certificate in auth unless one
+// was set. Without a certificate connections are served over plain TCP.
+func (s *Server) useConfiguredTLS() error {
+	if s.tlsManager != nil {
+		return nil
+	}
+	manager, err := newConfiguredTLSManager(s.config.Config, s.logger)
+	if err != nil {
+		return err
+	}
+	if manager == nil {
+		s.logger.Warn("No certificate configured, serving tunnel connections without TLS")
+		return nil
+	}
+	return s.SetTLSManager(manager)
+}
+
+// RotateSessionTicketKey rotates the key the server encrypts session tickets
+// with, see TLSManager.RotateSessionTicketKey. It does nothing without TLS.
+func (s *Server) RotateSessionTicketKey() error {
+	if s.tlsManager == nil {
+		return nil
+	}
+	return s.tlsManager.RotateSessionTicketKey()
+}
+
+// HandshakeStats returns the durations of the TLS handshakes of accepted
+// connections, zero without TLS
+func (s *Server) HandshakeStats() monitor.HandshakeStats {
+	if s.tlsManager == nil {
+		return monitor.HandshakeStats{}
+	}
+	return s.tlsManager.HandshakeStats()
+}
+
+// HandshakeStats returns the durations of the TLS handshakes of connections to
+// the server, zero without TLS
+func (c *Client) HandshakeStats() monitor.HandshakeStats {
+	if c.tls == nil {
+		return monitor.HandshakeStats{}
+	}
+	return c.tls.HandshakeStats()
+}
+
+// TLSStats returns the accepted connections counted by negotiated TLS version
+// and cipher suite, zero without TLS
+func (s *Server) TLSStats() monitor.TLSStats {
+	if s.tlsManager == nil {
+		return monitor.TLSStats{}
+	}
+	return s.tlsManager.TLSStats()
+}
+
+// TLSStats returns the connections to the server counted by negotiated TLS
+// version and cipher suite, zero without TLS
+func (c *Client) TLSStats() monitor.TLSStats {
+	if c.tls == nil {
+		return monitor.TLSStats{}
+	}
+	return c.tls.TLSStats()
+}
+
+// SetTLSManager makes the server run a TLS handshake on each accepted
+// connection. The identity of a verified client certificate is attached to the
+// connection's logs and statistics. It must be called before Start.
+func (s *Server) SetTLSManager(manager *TLSManager) error {
+	config, err := manager.GetServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get server TLS config: %w", err)
+	}
+	s.tlsManager = manager
+	s.tlsConfig = config
+	return nil
+}
+
+// clientHandshake runs the TLS handshake on a connection to a server address in
+// host:port form. The server certificate is verified against serverName, or
+// else the manager's server name, or else the host.
+func clientHandshake(ctx context.Context, conn net.Conn, manager *TLSManager, address, serverName string) (net.Conn, error) {
+	config, err := manager.GetClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client TLS config: %w", err)
+	}
+	if serverName != "" {
+		config.ServerName = serverName
+	}
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(address)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultServerHandshakeTimeout)
+	defer cancel()
+	tlsConn := tls.Client(conn, config)
+	if err := manager.Handshake(ctx, tlsConn); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// handshakeTimeout returns the configured bound on the TLS handshake of an
+// accepted connection
+func (s *Server) handshakeTimeout() time.Duration {
+	if cfg := s.currentConfig(); cfg != nil && cfg.Config != nil && cfg.Config.Tunnel.HandshakeTimeout > 0 {
+		return cfg.Config.Tunnel.HandshakeTimeout
+	}
+	return DefaultServerHandshakeTimeout
+}
+
+// serverHandshake runs the TLS handshake on an accepted connection. A client
+// that does not complete it within the handshake timeout fails it, so that
+// connections that never send a ClientHello do not hold their goroutine and
+// descriptor. It runs before the connection is tracked as a client.
+func (s *Server) serverHandshake(conn net.Conn) (*tls.Conn, PeerIdentity, error) {
+	timeout := s.handshakeTimeout()
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, PeerIdentity{}, err
+	}
+	tlsConn := tls.Server(conn, s.tlsConfig)
+	if err := s.tlsManager.Handshake(ctx, tlsConn); err != nil {
+		return nil, PeerIdentity{}, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, PeerIdentity{}, err
+	}
+	identity, _ := verifiedPeerIdentity(tlsConn.ConnectionState())
+	return tlsConn, identity, nil
+}
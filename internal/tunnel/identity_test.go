@@ -0,0 +1,256 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/cert/generator"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/pool"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestServerLogsClientCertIdentity(t *testing.T) {
+	// Keep the certificates out of /tmp, where the server skips client verification
+	certDir, err := os.MkdirTemp(".", ".identity-test-")
+	if err != nil {
+		t.Fatalf("Failed to create cert dir: %v", err)
+	}
+	defer os.RemoveAll(certDir)
+	if err := generator.GenerateTemporaryCertificates(certDir); err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+
+	serverManager, err := NewTLSManager(&TLSConfig{
+		CertFile:      filepath.Join(certDir, "server.crt"),
+		KeyFile:       filepath.Join(certDir, "server.key"),
+		CAFile:        filepath.Join(certDir, "ca.crt"),
+		SecurityLevel: SecurityModern,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server TLS manager: %v", err)
+	}
+	clientManager, err := NewTLSManager(&TLSConfig{
+		CertFile:      filepath.Join(certDir, "client.crt"),
+		KeyFile:       filepath.Join(certDir, "client.key"),
+		CAFile:        filepath.Join(certDir, "ca.crt"),
+		ServerName:    "127.0.0.1",
+		SecurityLevel: SecurityModern,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client TLS manager: %v", err)
+	}
+
+	core, logs := observer.New(zap.InfoLevel)
+	backend := pool.NewPool(func(ctx context.Context) (net.Conn, error) {
+		return nil, errors.New("no backend")
+	}, &pool.Config{MaxActive: 1, MaxRetries: 1, RetryInterval: time.Millisecond}, zap.NewNop())
+	defer backend.Close()
+
+	server := &Server{logger: zap.New(core), pool: backend, ctx: context.Background()}
+	if err := server.SetTLSManager(serverManager); err != nil {
+		t.Fatalf("Failed to set TLS manager: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.handleConnection(conn)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if _, err := clientManager.WrapConn(conn, false); err != nil {
+		t.Fatalf("Failed to complete the client handshake: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the server to handle the connection")
+	}
+
+	for _, msg := range []string{"Client connected", "Failed to get connection from pool"} {
+		entries := logs.FilterMessage(msg).All()
+		if len(entries) != 1 {
+			t.Fatalf("Expected one %q log, got %d", msg, len(entries))
+		}
+		fields := entries[0].ContextMap()
+		if fields["client_cn"] != "SSSonector client" {
+			t.Errorf("Expected %q to carry the client CN, got %v", msg, fields)
+		}
+		if serial, _ := fields["client_serial"].(string); serial == "" {
+			t.Errorf("Expected %q to carry the client serial, got %v", msg, fields)
+		}
+	}
+}
+
+func TestServerHandshakeTimeout(t *testing.T) {
+	certDir := t.TempDir()
+	if err := generator.GenerateTemporaryCertificates(certDir); err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+	serverManager, err := NewTLSManager(&TLSConfig{
+		CertFile:      filepath.Join(certDir, "server.crt"),
+		KeyFile:       filepath.Join(certDir, "server.key"),
+		CAFile:        filepath.Join(certDir, "ca.crt"),
+		SecurityLevel: SecurityModern,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server TLS manager: %v", err)
+	}
+
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Tunnel.HandshakeTimeout = 100 * time.Millisecond
+	server := &Server{config: cfg, logger: zap.NewNop(), ctx: context.Background()}
+	if err := server.SetTLSManager(serverManager); err != nil {
+		t.Fatalf("Failed to set TLS manager: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.handleConnection(conn)
+	}()
+
+	// Connect and never send a ClientHello
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Expected the server to close the connection, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected the connection closed after the 100ms timeout, took %v", elapsed)
+	}
+
+	if stats := serverManager.HandshakeStats(); stats.Timeouts != 1 {
+		t.Errorf("Expected 1 handshake timeout, got %+v", stats)
+	}
+	if count := server.conns.count(); count != 0 {
+		t.Errorf("Expected the connection never to be tracked, got %d", count)
+	}
+}
+
+func TestConfiguredTLS(t *testing.T) {
+	// Keep the certificates out of /tmp, where the server skips client verification
+	certDir, err := os.MkdirTemp(".", ".identity-test-")
+	if err != nil {
+		t.Fatalf("Failed to create cert dir: %v", err)
+	}
+	defer os.RemoveAll(certDir)
+	if err := generator.GenerateTemporaryCertificates(certDir); err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+
+	serverCfg := types.NewAppConfig(types.TypeServer)
+	serverCfg.Config.Auth.CertFile = filepath.Join(certDir, "server.crt")
+	serverCfg.Config.Auth.KeyFile = filepath.Join(certDir, "server.key")
+	serverCfg.Config.Auth.CAFile = filepath.Join(certDir, "ca.crt")
+
+	core, logs := observer.New(zap.InfoLevel)
+	server := NewServer(serverCfg, nil, zap.New(core))
+	defer server.pool.Close()
+	if err := server.useConfiguredTLS(); err != nil {
+		t.Fatalf("Failed to configure TLS: %v", err)
+	}
+	if server.tlsManager == nil {
+		t.Fatal("Expected the server to run TLS with a configured certificate")
+	}
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.handleConnection(conn)
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	clientCfg := types.NewAppConfig(types.TypeClient)
+	clientCfg.Config.Auth.CertFile = filepath.Join(certDir, "client.crt")
+	clientCfg.Config.Auth.KeyFile = filepath.Join(certDir, "client.key")
+	clientCfg.Config.Auth.CAFile = filepath.Join(certDir, "ca.crt")
+	clientCfg.Config.Tunnel.ServerAddress = host
+	clientCfg.Config.Tunnel.ServerPort, _ = strconv.Atoi(port)
+
+	client := NewClient(clientCfg, nil, zap.NewNop())
+	defer client.pool.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := client.pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Failed to connect over TLS: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage("Client connected").Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	entries := logs.FilterMessage("Client connected").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected the server to log the client, got %d entries", len(entries))
+	}
+	if cn := entries[0].ContextMap()["client_cn"]; cn != "SSSonector client" {
+		t.Errorf("Expected the client certificate identity, got %v", cn)
+	}
+}
+
+func TestTLSConfigFromSessionResumption(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Auth.CertFile = "server.crt"
+	cfg.Config.Auth.KeyFile = "server.key"
+	if TLSConfigFrom(cfg.Config, zap.NewNop()).DisableSessionResumption {
+		t.Error("Expected session resumption to be enabled by default")
+	}
+
+	cfg.Config.Security.TLS.DisableSessionResumption = true
+	if !TLSConfigFrom(cfg.Config, zap.NewNop()).DisableSessionResumption {
+		t.Error("Expected tls.disable_session_resumption to disable session resumption")
+	}
+
+	// Without TLS there is no ticket key to rotate
+	server := &Server{}
+	if err := server.RotateSessionTicketKey(); err != nil {
+		t.Errorf("Expected rotating without TLS to do nothing, got %v", err)
+	}
+}
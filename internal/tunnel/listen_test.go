@@ -0,0 +1,307 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// freeAddress returns a loopback address with a port that is free to bind
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestServerListensOnEveryAddress(t *testing.T) {
+	// The server pool dials network.name; a backend that echoes one message and
+	// hangs up shows that each listener forwards to it
+	backend, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 4)
+				if _, err := io.ReadFull(conn, buf); err == nil {
+					conn.Write(buf)
+				}
+			}()
+		}
+	}()
+
+	addrs := []string{freeAddress(t), freeAddress(t)}
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Network.Name = backend.Addr().String()
+	cfg.Config.Tunnel.ListenAddresses = addrs
+
+	useStartupAdapter(t, &startupAdapter{mockAdapter: newMockAdapter()})
+	server := NewServer(cfg, nil, zap.NewNop())
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	if len(server.listeners) != 2 {
+		t.Fatalf("Expected 2 listeners, got %d", len(server.listeners))
+	}
+	for _, addr := range addrs {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			t.Fatalf("Failed to connect to %s: %v", addr, err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			t.Fatalf("Failed to write to %s: %v", addr, err)
+		}
+		reply := make([]byte, 4)
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			t.Fatalf("Failed to read from %s: %v", addr, err)
+		}
+		if string(reply) != "ping" {
+			t.Errorf("Expected the backend to echo through %s, got %q", addr, reply)
+		}
+		conn.Close()
+	}
+}
+
+func TestServerListenFailureNamesAddress(t *testing.T) {
+	taken, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer taken.Close()
+
+	free := freeAddress(t)
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Tunnel.ListenAddresses = []string{free, taken.Addr().String()}
+
+	_, err = listen(cfg)
+	if !errors.Is(err, ErrNetworkPort) {
+		t.Fatalf("Expected ErrNetworkPort, got %v", err)
+	}
+	if !strings.Contains(err.Error(), taken.Addr().String()) {
+		t.Errorf("Expected the error to name %s, got %v", taken.Addr(), err)
+	}
+
+	// The address bound before the failure is released
+	ln, err := net.Listen("tcp4", free)
+	if err != nil {
+		t.Fatalf("Expected %s to be released, got %v", free, err)
+	}
+	ln.Close()
+}
+
+// scriptedListener returns the scripted results from Accept, recording when
+// each call was made, then blocks until closed
+type scriptedListener struct {
+	net.Listener
+	results []error // nil accepts a connection
+	calls   []time.Time
+	closed  chan struct{}
+}
+
+func (l *scriptedListener) Accept() (net.Conn, error) {
+	l.calls = append(l.calls, time.Now())
+	if len(l.results) == 0 {
+		<-l.closed
+		return nil, net.ErrClosed
+	}
+	err := l.results[0]
+	l.results = l.results[1:]
+	if err != nil {
+		return nil, err
+	}
+	client, server := net.Pipe()
+	client.Close()
+	return server, nil
+}
+
+func (l *scriptedListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *scriptedListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8443}
+}
+
+func TestServerAcceptBacksOffOnTemporaryErrors(t *testing.T) {
+	emfile := &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	ln := &scriptedListener{
+		results: []error{emfile, emfile, emfile, emfile, nil, emfile, net.ErrClosed},
+		closed:  make(chan struct{}),
+	}
+
+	server := NewServer(types.NewAppConfig(types.TypeServer), nil, zap.NewNop())
+	server.acceptPaused.Store(true) // Close the accepted connection at once
+	server.wg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		server.accept(ln)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the accept loop to exit once the listener closed")
+	}
+
+	if len(ln.calls) != 7 {
+		t.Fatalf("Expected 7 accept calls, got %d", len(ln.calls))
+	}
+	// The delay doubles with each consecutive error rather than spinning
+	for i, least := range []time.Duration{5, 10, 20, 40} {
+		if gap := ln.calls[i+1].Sub(ln.calls[i]); gap < least*time.Millisecond {
+			t.Errorf("Expected retry %d after at least %dms, got %v", i+1, least, gap)
+		}
+	}
+	// A successful accept resets the backoff
+	if gap := ln.calls[6].Sub(ln.calls[5]); gap < 5*time.Millisecond || gap >= 80*time.Millisecond {
+		t.Errorf("Expected the backoff to restart at 5ms after a successful accept, got %v", gap)
+	}
+	if server.ctx.Err() != nil {
+		t.Error("Expected a closed listener to leave the server running")
+	}
+}
+
+// acceptStrategy recovers from any error at once, counting its calls
+type acceptStrategy struct {
+	calls atomic.Int32
+}
+
+func (s *acceptStrategy) Name() string              { return "accept" }
+func (s *acceptStrategy) CanRecover(err error) bool { return true }
+func (s *acceptStrategy) Recover(ctx context.Context, err error, attempt int) error {
+	s.calls.Add(1)
+	return nil
+}
+func (s *acceptStrategy) Configure(config map[string]interface{}) error { return nil }
+
+func TestServerAcceptRecoversTemporaryErrors(t *testing.T) {
+	emfile := &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	ln := &scriptedListener{
+		results: []error{emfile, emfile, net.ErrClosed},
+		closed:  make(chan struct{}),
+	}
+
+	strategy := &acceptStrategy{}
+	recovery, err := resilience.NewErrorRecovery(&resilience.RecoveryConfig{
+		Strategies: map[string]resilience.RecoveryStrategy{"accept": strategy},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create error recovery: %v", err)
+	}
+	defer recovery.Stop()
+
+	server := NewServer(types.NewAppConfig(types.TypeServer), nil, zap.NewNop())
+	server.SetErrorRecovery(recovery)
+	server.wg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		server.accept(ln)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the accept loop to exit once the listener closed")
+	}
+
+	// The strategy waits in place of the backoff, and its recoveries are counted
+	if calls := strategy.calls.Load(); calls != 2 {
+		t.Errorf("Expected the strategy to handle 2 accept errors, got %d", calls)
+	}
+	if metrics := recovery.GetMetrics(); metrics.SuccessfulRecoveries != 2 {
+		t.Errorf("Expected 2 successful recoveries, got %d", metrics.SuccessfulRecoveries)
+	}
+}
+
+func TestServerStopsOnFatalAcceptError(t *testing.T) {
+	ln := &scriptedListener{
+		results: []error{errors.New("listener broken")},
+		closed:  make(chan struct{}),
+	}
+
+	core, logs := observer.New(zap.ErrorLevel)
+	server := NewServer(types.NewAppConfig(types.TypeServer), nil, zap.New(core))
+	server.listeners = []net.Listener{ln}
+	server.wg.Add(1)
+	go server.accept(ln)
+
+	select {
+	case <-server.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a fatal accept error to stop the server")
+	}
+	server.wg.Wait()
+	if logs.FilterMessage("Stopping tunnel server after a fatal accept error").Len() != 1 {
+		t.Errorf("Expected the reason to be logged, got %v", logs.All())
+	}
+}
+
+func TestListenNetworkPerAddress(t *testing.T) {
+	tests := []struct {
+		addr    string
+		network string
+	}{
+		{"0.0.0.0:8443", "tcp4"},
+		{"127.0.0.1:8443", "tcp4"},
+		{"[::]:8443", "tcp6"},
+		{"[::1]:8443", "tcp6"},
+		{"localhost:8443", "tcp"},
+		{":8443", "tcp"},
+	}
+	for _, tt := range tests {
+		if network := listenNetwork(tt.addr); network != tt.network {
+			t.Errorf("Expected %s to be bound on %s, got %s", tt.addr, tt.network, network)
+		}
+	}
+}
+
+func TestServerListensDualStack(t *testing.T) {
+	probe, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	port := probe.Addr().(*net.TCPAddr).Port
+	probe.Close()
+
+	// The IPv6 wildcard must not take the IPv4 port as well
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Tunnel.ListenAddresses = []string{
+		net.JoinHostPort("0.0.0.0", strconv.Itoa(port)),
+		net.JoinHostPort("::", strconv.Itoa(port)),
+	}
+	listeners, err := listen(cfg)
+	if err != nil {
+		t.Fatalf("Expected the IPv4 and IPv6 wildcards to bind the same port, got %v", err)
+	}
+	for _, ln := range listeners {
+		ln.Close()
+	}
+}
@@ -0,0 +1,620 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// A multiplexed connection carries several logical streams, each forwarded
+// like a connection of its own. Every frame starts with a 9 byte header: the
+// frame type, the big-endian stream ID and a big-endian length. Data frames
+// carry length bytes of one packet; window frames grant the peer length more
+// bytes of the stream's receive window.
+const muxHeaderSize = 9
+
+const (
+	// MuxHandshakeTimeout bounds the exchange that turns multiplexing on for a
+	// new connection
+	MuxHandshakeTimeout = 5 * time.Second
+
+	// MuxPrefaceWait is how long a server with multiplexing enabled waits for a
+	// new connection's first bytes to see whether it offers multiplexing. A
+	// client that offers it sends the preface as soon as it connects, so only
+	// clients that wait for the server to speak first are delayed this long.
+	MuxPrefaceWait = time.Second
+
+	// MuxMaxStreams is the most streams a session carries at once; the peer's
+	// opens beyond it are refused
+	MuxMaxStreams = 256
+
+	// MuxStreamWindow is how many bytes a stream may have in flight before its
+	// reader catches up; a stream whose reader stalls stops there rather than
+	// holding up the others
+	MuxStreamWindow = 256 * 1024
+
+	// muxMaxPayload is the most a data frame carries. Larger writes are split so
+	// that the streams interleave; it must not exceed half the window.
+	muxMaxPayload = 32 * 1024
+
+	// muxAcceptBacklog is how many opened streams wait for Accept before more
+	// are refused
+	muxAcceptBacklog = 64
+)
+
+// muxPreface is sent by a client offering multiplexing and echoed by a server
+// accepting it
+var muxPreface = []byte("SSSMUX/1")
+
+type muxFrameType uint8
+
+const (
+	muxFrameOpen muxFrameType = iota + 1
+	muxFrameData
+	muxFrameWindow
+	muxFrameClose
+)
+
+// clientMuxHandshake offers multiplexing on a new connection to the server and
+// waits for the server to accept it
+func clientMuxHandshake(conn net.Conn, timeout time.Duration) error {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write(muxPreface); err != nil {
+		return fmt.Errorf("%w: %w", ErrMuxRefused, err)
+	}
+	reply := make([]byte, len(muxPreface))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("%w: %w", ErrMuxRefused, err)
+	}
+	if !bytes.Equal(reply, muxPreface) {
+		return fmt.Errorf("%w: unexpected reply", ErrMuxRefused)
+	}
+	return nil
+}
+
+// bufferedConn is a connection whose first bytes were read ahead
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader // Holds the bytes read ahead
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// acceptMuxHandshake accepts multiplexing when the client's first bytes on
+// conn offer it, reporting whether it did. A client that sends anything else,
+// or nothing within timeout, does not multiplex and gets back what it sent
+// from the returned connection.
+func acceptMuxHandshake(conn net.Conn, timeout time.Duration) (net.Conn, bool, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, false, err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	buffered := &bufferedConn{Conn: conn, reader: reader}
+	preface, err := reader.Peek(len(muxPreface))
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return buffered, false, nil
+		}
+		return nil, false, err
+	}
+	if !bytes.Equal(preface, muxPreface) {
+		return buffered, false, nil
+	}
+
+	reader.Discard(len(muxPreface))
+	if _, err := conn.Write(muxPreface); err != nil {
+		return nil, false, err
+	}
+	return buffered, true, nil
+}
+
+// MuxSession carries streams over one connection. Either end may open
+// streams; the other end takes them from Accept.
+type MuxSession struct {
+	conn net.Conn
+
+	writeMu sync.Mutex // Serializes frames written to conn
+	header  [muxHeaderSize]byte
+
+	mu      sync.Mutex
+	streams map[uint32]*MuxStream
+	nextID  uint32 // Odd for streams the client opens, even for the server
+	err     error  // Why the session closed, once it has
+
+	accept    chan *MuxStream
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMuxSession starts a session over conn once both ends have agreed to
+// multiplex; client tells the two ends apart
+func NewMuxSession(conn net.Conn, client bool) *MuxSession {
+	s := &MuxSession{
+		conn:    conn,
+		streams: make(map[uint32]*MuxStream),
+		nextID:  2,
+		accept:  make(chan *MuxStream, muxAcceptBacklog),
+		done:    make(chan struct{}),
+	}
+	if client {
+		s.nextID = 1
+	}
+	go s.readLoop()
+	return s
+}
+
+// Open opens a new stream to the peer
+func (s *MuxSession) Open() (*MuxStream, error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	stream := newMuxStream(s, s.nextID)
+	s.nextID += 2
+	s.streams[stream.id] = stream
+	s.mu.Unlock()
+
+	if err := s.writeFrame(muxFrameOpen, stream.id, 0, nil); err != nil {
+		s.remove(stream.id)
+		return nil, err
+	}
+	return stream, nil
+}
+
+// Accept waits for the peer to open a stream
+func (s *MuxSession) Accept() (*MuxStream, error) {
+	select {
+	case stream := <-s.accept:
+		return stream, nil
+	case <-s.done:
+		return nil, s.closeErr()
+	}
+}
+
+// NumStreams returns the number of open streams
+func (s *MuxSession) NumStreams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+// IsClosed reports whether the session has closed
+func (s *MuxSession) IsClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the session, its streams and its connection
+func (s *MuxSession) Close() error {
+	return s.close(ErrMuxSessionClosed)
+}
+
+func (s *MuxSession) close(cause error) error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.err = cause
+		streams := s.streams
+		s.streams = make(map[uint32]*MuxStream)
+		s.mu.Unlock()
+
+		close(s.done)
+		err = s.conn.Close()
+		for _, stream := range streams {
+			stream.reset(cause)
+		}
+	})
+	return err
+}
+
+func (s *MuxSession) closeErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *MuxSession) remove(id uint32) {
+	s.mu.Lock()
+	delete(s.streams, id)
+	s.mu.Unlock()
+}
+
+// writeFrame writes one frame; a failed write closes the session, as the
+// peer can no longer find the frames that follow
+func (s *MuxSession) writeFrame(frameType muxFrameType, id, length uint32, payload []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.header[0] = byte(frameType)
+	binary.BigEndian.PutUint32(s.header[1:5], id)
+	binary.BigEndian.PutUint32(s.header[5:9], length)
+	buffers := net.Buffers{s.header[:], payload}
+	if _, err := buffers.WriteTo(s.conn); err != nil {
+		s.close(fmt.Errorf("%w: %w", ErrMuxSessionClosed, err))
+		return s.closeErr()
+	}
+	return nil
+}
+
+// readLoop dispatches the frames read from the connection until it fails
+func (s *MuxSession) readLoop() {
+	var header [muxHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(s.conn, header[:]); err != nil {
+			s.close(fmt.Errorf("%w: %w", ErrMuxSessionClosed, err))
+			return
+		}
+		frameType := muxFrameType(header[0])
+		id := binary.BigEndian.Uint32(header[1:5])
+		length := binary.BigEndian.Uint32(header[5:9])
+
+		if err := s.handleFrame(frameType, id, length); err != nil {
+			s.close(fmt.Errorf("%w: %w", ErrMuxSessionClosed, err))
+			return
+		}
+	}
+}
+
+func (s *MuxSession) handleFrame(frameType muxFrameType, id, length uint32) error {
+	s.mu.Lock()
+	stream := s.streams[id]
+	s.mu.Unlock()
+
+	switch frameType {
+	case muxFrameOpen:
+		if stream != nil {
+			return fmt.Errorf("stream %d opened twice", id)
+		}
+		s.mu.Lock()
+		full := len(s.streams) >= MuxMaxStreams
+		if !full {
+			stream = newMuxStream(s, id)
+			s.streams[id] = stream
+		}
+		s.mu.Unlock()
+		if full {
+			return s.writeFrame(muxFrameClose, id, 0, nil)
+		}
+		select {
+		case s.accept <- stream:
+		default:
+			stream.Close() // Nobody is accepting
+		}
+
+	case muxFrameData:
+		if length > muxMaxPayload {
+			return fmt.Errorf("data frame of %d bytes exceeds the %d byte limit", length, muxMaxPayload)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(s.conn, payload); err != nil {
+			return err
+		}
+		if stream != nil {
+			return stream.receive(payload)
+		}
+		// Data racing a local close is dropped
+
+	case muxFrameWindow:
+		if stream != nil {
+			stream.grant(length)
+		}
+
+	case muxFrameClose:
+		if stream != nil {
+			stream.remoteClose()
+		}
+
+	default:
+		return fmt.Errorf("unknown frame type %d", frameType)
+	}
+	return nil
+}
+
+// MuxStream is one logical connection of a MuxSession. Each Write arrives as
+// one Read at the other end, when the reader's buffer can hold it.
+type MuxStream struct {
+	id      uint32
+	session *MuxSession
+
+	mu            sync.Mutex
+	changed       chan struct{} // Closed and replaced on every state change
+	queue         [][]byte      // Received packets not read yet
+	queued        uint32        // Bytes in queue
+	consumed      uint32        // Bytes read but not yet granted back to the peer
+	sendWindow    uint32        // Bytes the peer is ready to receive
+	readDeadline  time.Time
+	writeDeadline time.Time
+	remoteClosed  bool
+	closed        bool
+	err           error // Why the session closed under the stream
+}
+
+func newMuxStream(session *MuxSession, id uint32) *MuxStream {
+	return &MuxStream{
+		id:         id,
+		session:    session,
+		changed:    make(chan struct{}),
+		sendWindow: MuxStreamWindow,
+	}
+}
+
+// ID returns the stream ID, unique within its session
+func (m *MuxStream) ID() uint32 {
+	return m.id
+}
+
+// notifyLocked wakes the goroutines waiting on the stream; the caller holds m.mu
+func (m *MuxStream) notifyLocked() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// waitMuxChange waits for the stream to change or the deadline to pass
+func waitMuxChange(changed <-chan struct{}, deadline time.Time) error {
+	if deadline.IsZero() {
+		<-changed
+		return nil
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-changed:
+		return nil
+	case <-timer.C:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+func (m *MuxStream) Read(b []byte) (int, error) {
+	m.mu.Lock()
+	for len(m.queue) == 0 {
+		switch {
+		case m.closed:
+			m.mu.Unlock()
+			return 0, net.ErrClosed
+		case m.err != nil:
+			m.mu.Unlock()
+			return 0, m.err
+		case m.remoteClosed:
+			m.mu.Unlock()
+			return 0, io.EOF
+		case !m.readDeadline.IsZero() && !time.Now().Before(m.readDeadline):
+			m.mu.Unlock()
+			return 0, os.ErrDeadlineExceeded
+		}
+		changed, deadline := m.changed, m.readDeadline
+		m.mu.Unlock()
+		if err := waitMuxChange(changed, deadline); err != nil {
+			return 0, err
+		}
+		m.mu.Lock()
+	}
+
+	n := copy(b, m.queue[0])
+	if n < len(m.queue[0]) {
+		m.queue[0] = m.queue[0][n:]
+	} else {
+		m.queue[0] = nil
+		m.queue = m.queue[1:]
+	}
+	m.queued -= uint32(n)
+	m.consumed += uint32(n)
+
+	// Grant the window back in batches rather than per packet
+	var credit uint32
+	if m.consumed >= MuxStreamWindow/2 {
+		credit, m.consumed = m.consumed, 0
+	}
+	m.mu.Unlock()
+
+	if credit > 0 {
+		m.session.writeFrame(muxFrameWindow, m.id, credit, nil)
+	}
+	return n, nil
+}
+
+func (m *MuxStream) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		chunk := b[written:]
+		if len(chunk) > muxMaxPayload {
+			chunk = chunk[:muxMaxPayload]
+		}
+		if err := m.reserve(uint32(len(chunk))); err != nil {
+			return written, err
+		}
+		if err := m.session.writeFrame(muxFrameData, m.id, uint32(len(chunk)), chunk); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+	}
+	return written, nil
+}
+
+// reserve waits until the peer's window has room for size bytes and takes them
+func (m *MuxStream) reserve(size uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for m.sendWindow < size {
+		switch {
+		case m.closed:
+			return net.ErrClosed
+		case m.err != nil:
+			return m.err
+		case m.remoteClosed:
+			return io.ErrClosedPipe
+		case !m.writeDeadline.IsZero() && !time.Now().Before(m.writeDeadline):
+			return os.ErrDeadlineExceeded
+		}
+		changed, deadline := m.changed, m.writeDeadline
+		m.mu.Unlock()
+		err := waitMuxChange(changed, deadline)
+		m.mu.Lock()
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case m.closed:
+		return net.ErrClosed
+	case m.err != nil:
+		return m.err
+	case m.remoteClosed:
+		return io.ErrClosedPipe
+	}
+	m.sendWindow -= size
+	return nil
+}
+
+// receive queues a packet from the peer, which must fit in the window it was
+// granted
+func (m *MuxStream) receive(payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queued+m.consumed+uint32(len(payload)) > MuxStreamWindow {
+		return fmt.Errorf("stream %d overran its receive window", m.id)
+	}
+	if m.closed {
+		return nil
+	}
+	m.queue = append(m.queue, payload)
+	m.queued += uint32(len(payload))
+	m.notifyLocked()
+	return nil
+}
+
+// grant adds to the bytes the peer is ready to receive
+func (m *MuxStream) grant(credit uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendWindow += credit
+	m.notifyLocked()
+}
+
+// remoteClose ends the stream's reads once the queued packets are read
+func (m *MuxStream) remoteClose() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remoteClosed = true
+	m.notifyLocked()
+}
+
+// reset fails the stream's reads and writes with err
+func (m *MuxStream) reset(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+	m.notifyLocked()
+}
+
+// Close closes the stream and tells the peer; the session stays open
+func (m *MuxStream) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.queue = nil
+	failed := m.err != nil
+	m.notifyLocked()
+	m.mu.Unlock()
+
+	m.session.remove(m.id)
+	if failed {
+		return nil
+	}
+	return m.session.writeFrame(muxFrameClose, m.id, 0, nil)
+}
+
+func (m *MuxStream) LocalAddr() net.Addr {
+	return m.session.conn.LocalAddr()
+}
+
+func (m *MuxStream) RemoteAddr() net.Addr {
+	return m.session.conn.RemoteAddr()
+}
+
+func (m *MuxStream) SetDeadline(t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.readDeadline, m.writeDeadline = t, t
+	m.notifyLocked()
+	return nil
+}
+
+func (m *MuxStream) SetReadDeadline(t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.readDeadline = t
+	m.notifyLocked()
+	return nil
+}
+
+func (m *MuxStream) SetWriteDeadline(t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writeDeadline = t
+	m.notifyLocked()
+	return nil
+}
+
+// muxDialer opens streams over one shared connection to the server, dialing
+// and handshaking a new one when the last has closed
+type muxDialer struct {
+	dial func(ctx context.Context) (net.Conn, error)
+
+	mu      sync.Mutex
+	session *MuxSession
+}
+
+// Open opens a stream, sharing the current connection when it is still open
+func (d *muxDialer) Open(ctx context.Context) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.session == nil || d.session.IsClosed() {
+		conn, err := d.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := clientMuxHandshake(conn, MuxHandshakeTimeout); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		d.session = NewMuxSession(conn, true)
+	}
+	return d.session.Open()
+}
+
+// Close closes the shared connection and its streams
+func (d *muxDialer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.session == nil {
+		return nil
+	}
+	return d.session.Close()
+}
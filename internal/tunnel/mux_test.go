@@ -0,0 +1,327 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+)
+
+// muxPair returns the client and server ends of a multiplexed connection
+func muxPair(t *testing.T) (*MuxSession, *MuxSession) {
+	clientConn, serverConn := net.Pipe()
+	client := NewMuxSession(clientConn, true)
+	server := NewMuxSession(serverConn, false)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestMuxStreamsCarryDistinctPackets(t *testing.T) {
+	client, server := muxPair(t)
+
+	// Each stream carries its own packet set, filled with a stream marker
+	sets := map[uint32][][]byte{}
+	for _, marker := range []byte{0xaa, 0xbb} {
+		stream, err := client.Open()
+		if err != nil {
+			t.Fatalf("Failed to open stream: %v", err)
+		}
+		defer stream.Close()
+
+		packets := testPackets(40)
+		for _, packet := range packets {
+			packet[0] = marker
+		}
+		sets[stream.ID()] = packets
+		go func() {
+			for _, packet := range packets {
+				if _, err := stream.Write(packet); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	if len(sets) != 2 {
+		t.Fatalf("Expected two distinct stream IDs, got %d", len(sets))
+	}
+
+	done := make(chan error, len(sets))
+	for range sets {
+		stream, err := server.Accept()
+		if err != nil {
+			t.Fatalf("Failed to accept stream: %v", err)
+		}
+		packets, ok := sets[stream.ID()]
+		if !ok {
+			t.Fatalf("Accepted unknown stream %d", stream.ID())
+		}
+		go func() {
+			stream.SetReadDeadline(time.Now().Add(2 * time.Second))
+			buf := make([]byte, types.MaxPacketSizeLimit)
+			for i, packet := range packets {
+				n, err := stream.Read(buf)
+				if err != nil {
+					done <- err
+					return
+				}
+				if !bytes.Equal(buf[:n], packet) {
+					done <- fmt.Errorf("stream %d: packet %d differs", stream.ID(), i)
+					return
+				}
+			}
+			done <- nil
+		}()
+	}
+	for range sets {
+		if err := <-done; err != nil {
+			t.Fatalf("Failed to receive packets: %v", err)
+		}
+	}
+	if client.NumStreams() != 2 {
+		t.Errorf("Expected 2 open streams, got %d", client.NumStreams())
+	}
+}
+
+func TestMuxStalledStreamDoesNotBlockOthers(t *testing.T) {
+	client, server := muxPair(t)
+
+	stalled, err := client.Open()
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	active, err := client.Open()
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	if _, err := server.Accept(); err != nil {
+		t.Fatalf("Failed to accept stream: %v", err)
+	}
+	activePeer, err := server.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept stream: %v", err)
+	}
+
+	// Nobody reads the stalled stream, so its writes stop at the window
+	stalled.SetWriteDeadline(time.Now().Add(200 * time.Millisecond))
+	n, err := stalled.Write(make([]byte, 2*MuxStreamWindow))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Expected the stalled stream to block, got %v", err)
+	}
+	if n != MuxStreamWindow {
+		t.Errorf("Expected %d bytes written before blocking, got %d", MuxStreamWindow, n)
+	}
+
+	// The other stream still flows
+	go active.Write([]byte("still moving"))
+	activePeer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, err = activePeer.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read from the active stream: %v", err)
+	}
+	if string(buf[:n]) != "still moving" {
+		t.Errorf("Expected %q, got %q", "still moving", buf[:n])
+	}
+}
+
+func TestMuxStreamClose(t *testing.T) {
+	client, server := muxPair(t)
+
+	stream, err := client.Open()
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	peer, err := server.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept stream: %v", err)
+	}
+
+	if _, err := stream.Write([]byte("last")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Failed to close stream: %v", err)
+	}
+
+	// Packets sent before the close are still read, then EOF
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 16)
+	if n, err := peer.Read(buf); err != nil || string(buf[:n]) != "last" {
+		t.Errorf("Expected %q, got %q and %v", "last", buf[:n], err)
+	}
+	if _, err := peer.Read(buf); err != io.EOF {
+		t.Errorf("Expected EOF after the peer closed, got %v", err)
+	}
+
+	// Closing the session fails the streams left open
+	other, err := client.Open()
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	client.Close()
+	if _, err := other.Read(buf); !errors.Is(err, ErrMuxSessionClosed) {
+		t.Errorf("Expected a closed session error, got %v", err)
+	}
+	if _, err := client.Open(); !errors.Is(err, ErrMuxSessionClosed) {
+		t.Errorf("Expected a closed session error, got %v", err)
+	}
+}
+
+func TestMuxHandshake(t *testing.T) {
+	t.Run("offered", func(t *testing.T) {
+		clientConn, serverConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		errc := make(chan error, 1)
+		go func() { errc <- clientMuxHandshake(clientConn, time.Second) }()
+		_, muxed, err := acceptMuxHandshake(serverConn, time.Second)
+		if err != nil {
+			t.Fatalf("Failed to accept handshake: %v", err)
+		}
+		if !muxed {
+			t.Error("Expected the server to accept multiplexing")
+		}
+		if err := <-errc; err != nil {
+			t.Errorf("Expected the client handshake to succeed, got %v", err)
+		}
+	})
+
+	t.Run("plain client", func(t *testing.T) {
+		clientConn, serverConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		go clientConn.Write([]byte("plain packet"))
+		conn, muxed, err := acceptMuxHandshake(serverConn, time.Second)
+		if err != nil {
+			t.Fatalf("Failed to accept handshake: %v", err)
+		}
+		if muxed {
+			t.Error("Expected a plain client not to multiplex")
+		}
+
+		// The bytes read ahead are not lost
+		buf := make([]byte, 64)
+		n, err := io.ReadAtLeast(conn, buf, len("plain packet"))
+		if err != nil || string(buf[:n]) != "plain packet" {
+			t.Errorf("Expected %q, got %q and %v", "plain packet", buf[:n], err)
+		}
+	})
+
+	t.Run("quiet client", func(t *testing.T) {
+		clientConn, serverConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		_, muxed, err := acceptMuxHandshake(serverConn, 50*time.Millisecond)
+		if err != nil || muxed {
+			t.Errorf("Expected a quiet client not to multiplex, got %v and %v", muxed, err)
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		clientConn, serverConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		// A server without multiplexing never answers the preface
+		go io.Copy(io.Discard, serverConn)
+		if err := clientMuxHandshake(clientConn, 50*time.Millisecond); !errors.Is(err, ErrMuxRefused) {
+			t.Errorf("Expected the handshake to be refused, got %v", err)
+		}
+	})
+}
+
+func TestMuxDialerSharesConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan *MuxSession, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			muxConn, muxed, err := acceptMuxHandshake(conn, time.Second)
+			if err != nil || !muxed {
+				conn.Close()
+				continue
+			}
+			accepted <- NewMuxSession(muxConn, false)
+		}
+	}()
+
+	dials := 0
+	dialer := &muxDialer{dial: func(ctx context.Context) (net.Conn, error) {
+		dials++
+		return net.Dial("tcp", ln.Addr().String())
+	}}
+	defer dialer.Close()
+
+	for i := 0; i < 3; i++ {
+		stream, err := dialer.Open(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to open stream: %v", err)
+		}
+		defer stream.Close()
+	}
+	if dials != 1 {
+		t.Errorf("Expected the streams to share 1 connection, got %d", dials)
+	}
+
+	// A closed connection is replaced by the next open
+	session := <-accepted
+	session.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for !dialer.session.IsClosed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := dialer.Open(context.Background()); err != nil {
+		t.Fatalf("Failed to open stream after reconnecting: %v", err)
+	}
+	if dials != 2 {
+		t.Errorf("Expected a new connection after the old one closed, got %d dials", dials)
+	}
+	(<-accepted).Close()
+}
+
+func TestMuxRefusesStreamsOverLimit(t *testing.T) {
+	client, server := muxPair(t)
+
+	for i := 0; i < MuxMaxStreams; i++ {
+		if _, err := client.Open(); err != nil {
+			t.Fatalf("Failed to open stream %d: %v", i, err)
+		}
+		if _, err := server.Accept(); err != nil {
+			t.Fatalf("Failed to accept stream %d: %v", i, err)
+		}
+	}
+
+	// The stream over the limit is closed by the server at once
+	over, err := client.Open()
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	over.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := over.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the stream over the limit to be refused, got %v", err)
+	}
+	if n := server.NumStreams(); n != MuxMaxStreams {
+		t.Errorf("Expected the server to carry %d streams, got %d", MuxMaxStreams, n)
+	}
+}
@@ -0,0 +1,166 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxyHeaderTimeout bounds reading the PROXY protocol header of an accepted
+// connection
+const ProxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature starts every PROXY protocol version 2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyV1MaxLength = 107 // Longest version 1 header, including CRLF
+	proxyV2HeaderLen = 16  // Signature, version and command, family, length
+)
+
+// proxyConn is a connection accepted from a load balancer. RemoteAddr is the
+// client address from its PROXY protocol header.
+type proxyConn struct {
+	net.Conn
+	reader *bufio.Reader // Holds any bytes read past the header
+	remote net.Addr
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// proxyTrusted reports whether addr, the peer of an accepted connection, is in
+// one of the trusted networks allowed to send a PROXY protocol header. Other
+// peers could claim any client address.
+func proxyTrusted(addr net.Addr, trusted []string) bool {
+	addrPort, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := addrPort.Addr().Unmap()
+	for _, network := range trusted {
+		if prefix, err := netip.ParsePrefix(network); err == nil && prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// readProxyHeader reads the PROXY protocol version 1 or 2 header that a load
+// balancer sends ahead of the client's data, and returns the connection with
+// the client address as its RemoteAddr. Headers for health checks (v1 UNKNOWN,
+// v2 LOCAL) and for non-TCP clients keep the load balancer's address. A
+// missing or malformed header returns an error wrapping ErrInvalidProxyHeader.
+func readProxyHeader(conn net.Conn, timeout time.Duration) (net.Conn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	signature, err := reader.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProxyHeader, err)
+	}
+
+	var remote net.Addr
+	switch {
+	case bytes.Equal(signature, proxyV2Signature):
+		remote, err = readProxyV2(reader)
+	case bytes.HasPrefix(signature, []byte("PROXY ")):
+		remote, err = readProxyV1(reader)
+	default:
+		err = fmt.Errorf("%w: no PROXY protocol signature", ErrInvalidProxyHeader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if remote == nil {
+		remote = conn.RemoteAddr()
+	}
+	return &proxyConn{Conn: conn, reader: reader, remote: remote}, nil
+}
+
+// readProxyV1 parses a text header such as
+// "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"
+func readProxyV1(reader *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyV1MaxLength {
+			return nil, fmt.Errorf("%w: v1 header longer than %d bytes", ErrInvalidProxyHeader, proxyV1MaxLength)
+		}
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidProxyHeader, err)
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("%w: malformed v1 header %q", ErrInvalidProxyHeader, line)
+	}
+
+	ip := net.ParseIP(fields[2])
+	if ip == nil || (ip.To4() != nil) != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("%w: invalid v1 source address %q", ErrInvalidProxyHeader, fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid v1 source port %q", ErrInvalidProxyHeader, fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2 parses a binary header
+func readProxyV2(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, proxyV2HeaderLen)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProxyHeader, err)
+	}
+	if version := header[12] >> 4; version != 2 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidProxyHeader, version)
+	}
+	command, family := header[12]&0x0f, header[13]
+	if command > 1 {
+		return nil, fmt.Errorf("%w: unsupported command %d", ErrInvalidProxyHeader, command)
+	}
+
+	addresses := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(reader, addresses); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProxyHeader, err)
+	}
+	if command == 0 { // LOCAL: the load balancer's own connection
+		return nil, nil
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(addresses) < 12 {
+			return nil, fmt.Errorf("%w: v2 IPv4 addresses truncated", ErrInvalidProxyHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(addresses[0:4]), Port: int(binary.BigEndian.Uint16(addresses[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(addresses) < 36 {
+			return nil, fmt.Errorf("%w: v2 IPv6 addresses truncated", ErrInvalidProxyHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(addresses[0:16]), Port: int(binary.BigEndian.Uint16(addresses[32:34]))}, nil
+	default: // Unspecified, UDP or Unix socket clients
+		return nil, nil
+	}
+}
@@ -0,0 +1,159 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// proxyV2Header builds a version 2 PROXY header for a TCP client
+func proxyV2Header(src, dst net.IP, srcPort, dstPort uint16) []byte {
+	family := byte(0x11)
+	addresses := append(append([]byte{}, src.To4()...), dst.To4()...)
+	if src.To4() == nil {
+		family = 0x21
+		addresses = append(append([]byte{}, src.To16()...), dst.To16()...)
+	}
+	addresses = binary.BigEndian.AppendUint16(addresses, srcPort)
+	addresses = binary.BigEndian.AppendUint16(addresses, dstPort)
+
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x21, family) // Version 2, PROXY command
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+// sendWithProxyHeader writes header followed by payload from the far end of a
+// pipe and returns the near end
+func sendWithProxyHeader(t *testing.T, header []byte, payload string) net.Conn {
+	t.Helper()
+	local, remote := net.Pipe()
+	t.Cleanup(func() { local.Close() })
+	go func() {
+		defer remote.Close()
+		remote.Write(append(append([]byte{}, header...), payload...))
+	}()
+	return local
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"v1 IPv4", []byte("PROXY TCP4 192.0.2.10 198.51.100.1 56324 8443\r\n"), "192.0.2.10:56324"},
+		{"v1 IPv6", []byte("PROXY TCP6 2001:db8::10 2001:db8::1 56324 8443\r\n"), "[2001:db8::10]:56324"},
+		{"v2 IPv4", proxyV2Header(net.ParseIP("192.0.2.20"), net.ParseIP("198.51.100.1"), 40000, 8443), "192.0.2.20:40000"},
+		{"v2 IPv6", proxyV2Header(net.ParseIP("2001:db8::20"), net.ParseIP("2001:db8::1"), 40000, 8443), "[2001:db8::20]:40000"},
+	}
+
+	for _, tt := range tests {
+		conn, err := readProxyHeader(sendWithProxyHeader(t, tt.header, "hello"), time.Second)
+		if err != nil {
+			t.Fatalf("%s: Failed to read header: %v", tt.name, err)
+		}
+		if got := conn.RemoteAddr().String(); got != tt.want {
+			t.Errorf("%s: Expected client address %s, got %s", tt.name, tt.want, got)
+		}
+
+		// Data after the header reaches the connection's reader intact
+		payload, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatalf("%s: Failed to read payload: %v", tt.name, err)
+		}
+		if string(payload) != "hello" {
+			t.Errorf("%s: Expected payload %q, got %q", tt.name, "hello", payload)
+		}
+	}
+}
+
+func TestReadProxyHeaderKeepsLoadBalancerAddress(t *testing.T) {
+	local := proxyV2Header(net.ParseIP("192.0.2.20"), net.ParseIP("198.51.100.1"), 40000, 8443)
+	local[12] = 0x20 // LOCAL command, sent by load balancer health checks
+
+	for _, header := range [][]byte{[]byte("PROXY UNKNOWN\r\n"), local} {
+		pipe := sendWithProxyHeader(t, header, "")
+		conn, err := readProxyHeader(pipe, time.Second)
+		if err != nil {
+			t.Fatalf("Failed to read header %q: %v", header, err)
+		}
+		if conn.RemoteAddr() != pipe.RemoteAddr() {
+			t.Errorf("Expected header %q to keep the connection's address, got %v", header, conn.RemoteAddr())
+		}
+	}
+}
+
+func TestReadProxyHeaderMalformed(t *testing.T) {
+	truncated := proxyV2Header(net.ParseIP("192.0.2.20"), net.ParseIP("198.51.100.1"), 40000, 8443)
+	binary.BigEndian.PutUint16(truncated[14:16], 4)
+
+	for _, header := range [][]byte{
+		[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		[]byte("PROXY TCP4 192.0.2.10 198.51.100.1 56324\r\n"),
+		[]byte("PROXY TCP4 2001:db8::10 198.51.100.1 56324 8443\r\n"),
+		[]byte("PROXY TCP4 192.0.2.10 198.51.100.1 70000 8443\r\n"),
+		append([]byte("PROXY TCP4 "), make([]byte, 200)...),
+		truncated,
+	} {
+		_, err := readProxyHeader(sendWithProxyHeader(t, header, ""), time.Second)
+		if !errors.Is(err, ErrInvalidProxyHeader) {
+			t.Errorf("Expected header %q to be rejected with ErrInvalidProxyHeader, got %v", header, err)
+		}
+	}
+}
+
+func TestProxyTrusted(t *testing.T) {
+	trusted := []string{"10.0.0.0/24", "2001:db8::/32"}
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"10.0.0.7:4000", true},
+		{"10.0.1.7:4000", false},
+		{"[::ffff:10.0.0.7]:4000", true},
+		{"[2001:db8::1]:4000", true},
+		{"[2001:db9::1]:4000", false},
+	}
+	for _, tt := range tests {
+		addr, err := net.ResolveTCPAddr("tcp", tt.addr)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", tt.addr, err)
+		}
+		if got := proxyTrusted(addr, trusted); got != tt.want {
+			t.Errorf("%s: expected trusted %v, got %v", tt.addr, tt.want, got)
+		}
+	}
+}
+
+func TestAdmitRefusesProxyHeaderFromUntrustedPeer(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Tunnel.ProxyProtocol = true
+	cfg.Config.Tunnel.TrustedProxies = []string{"10.0.0.0/24"}
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	server := &Server{config: cfg, logger: zap.New(core)}
+
+	// A client connecting directly and claiming to be 192.0.2.10
+	header := proxyV2Header(net.ParseIP("192.0.2.10"), net.ParseIP("198.51.100.1"), 4000, 8443)
+	conn := &addrConn{
+		Conn:   sendWithProxyHeader(t, header, "payload"),
+		remote: &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 5000},
+	}
+	server.admit(conn)
+
+	if logs.FilterMessage("Rejected connection from a peer not in trusted_proxies").Len() != 1 {
+		t.Errorf("Expected the untrusted peer to be rejected, got %v", logs.All())
+	}
+	if server.conns.count() != 0 {
+		t.Error("Expected the untrusted connection never to be tracked")
+	}
+}
@@ -0,0 +1,150 @@
+package tunnel
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+// restartOnly lists the settings a running tunnel cannot change, with how to
+// read each one from a configuration
+var restartOnly = []struct {
+	name  string
+	value func(cfg *types.Config) interface{}
+}{
+	{"mode", func(cfg *types.Config) interface{} { return cfg.Mode }},
+	{"network.name", func(cfg *types.Config) interface{} { return cfg.Network.Name }},
+	{"network.address", func(cfg *types.Config) interface{} { return cfg.Network.Address }},
+	{"network.mtu", func(cfg *types.Config) interface{} { return cfg.Network.MTU }},
+	{"network.routes", func(cfg *types.Config) interface{} { return strings.Join(cfg.Network.Routes, ",") }},
+	{"network.apply_dns", func(cfg *types.Config) interface{} { return cfg.Network.ApplyDNS }},
+	{"tunnel.listen_address", func(cfg *types.Config) interface{} { return cfg.Tunnel.ListenAddress }},
+	{"tunnel.listen_port", func(cfg *types.Config) interface{} { return cfg.Tunnel.ListenPort }},
+	{"tunnel.listen_addresses", func(cfg *types.Config) interface{} { return strings.Join(cfg.Tunnel.ListenAddresses, ",") }},
+	{"tunnel.server_address", func(cfg *types.Config) interface{} { return cfg.Tunnel.ServerAddress }},
+	{"tunnel.server_port", func(cfg *types.Config) interface{} { return cfg.Tunnel.ServerPort }},
+	{"tunnel.multiplex", func(cfg *types.Config) interface{} { return cfg.Tunnel.Multiplex }},
+	{"tunnel.keepalive", func(cfg *types.Config) interface{} { return cfg.Tunnel.Keepalive }},
+	{"tunnel.heartbeat", func(cfg *types.Config) interface{} { return cfg.Tunnel.Heartbeat }},
+	{"tunnel.coalesce_window", func(cfg *types.Config) interface{} { return cfg.Tunnel.CoalesceWindow }},
+	{"tunnel.max_memory", func(cfg *types.Config) interface{} { return cfg.Tunnel.MaxMemory }},
+	{"auth.cert_file", func(cfg *types.Config) interface{} { return cfg.Auth.CertFile }},
+	{"auth.key_file", func(cfg *types.Config) interface{} { return cfg.Auth.KeyFile }},
+	{"auth.ca_file", func(cfg *types.Config) interface{} { return cfg.Auth.CAFile }},
+}
+
+// checkReloadable returns an error wrapping ErrReloadRequiresRestart naming the
+// first setting that differs between the running and the reloaded configuration
+// and cannot change without a restart
+func checkReloadable(running, reloaded *types.AppConfig) error {
+	if reloaded == nil || reloaded.Config == nil {
+		return fmt.Errorf("%w: configuration is empty", ErrInvalidConfiguration)
+	}
+	if running == nil || running.Config == nil {
+		return nil
+	}
+	for _, setting := range restartOnly {
+		if was, now := setting.value(running.Config), setting.value(reloaded.Config); was != now {
+			return fmt.Errorf("%w: %s changed from %v to %v", ErrReloadRequiresRestart, setting.name, was, now)
+		}
+	}
+	// The connection limiter is handed to the server when it starts
+	if was, now := running.Throttle.Connections.Enabled, reloaded.Throttle.Connections.Enabled; was != now {
+		return fmt.Errorf("%w: throttle.connections.enabled changed from %v to %v", ErrReloadRequiresRestart, was, now)
+	}
+	return nil
+}
+
+// currentConfig returns the configuration new connections use: the last one
+// applied by Reload, or the one the server was created with
+func (s *Server) currentConfig() *types.AppConfig {
+	if cfg := s.reloaded.Load(); cfg != nil {
+		return cfg
+	}
+	return s.config
+}
+
+// Reload applies a new configuration without restarting. Transfer settings such
+// as rate limits and transient error handling apply to connections accepted
+// afterwards. A configuration that changes a restart-only setting is rejected
+// and the running configuration stays in use.
+func (s *Server) Reload(cfg *types.AppConfig) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if err := checkReloadable(s.currentConfig(), cfg); err != nil {
+		return err
+	}
+	s.reloaded.Store(cfg)
+	s.logger.Info("Applied reloaded configuration")
+	return nil
+}
+
+// currentConfig returns the last configuration applied by Reload, or the one
+// the client was created with
+func (c *Client) currentConfig() *types.AppConfig {
+	if cfg := c.reloaded.Load(); cfg != nil {
+		return cfg
+	}
+	return c.config
+}
+
+// Reload applies a new configuration without restarting. The server endpoints
+// are replaced by the configured ones; established connections are kept. A
+// configuration that changes a restart-only setting or lists an invalid
+// endpoint is rejected and the running configuration stays in use.
+func (c *Client) Reload(cfg *types.AppConfig) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	if err := checkReloadable(c.currentConfig(), cfg); err != nil {
+		return err
+	}
+
+	// Validate every endpoint before changing any
+	want := make(map[string]int, len(cfg.Config.Tunnel.Endpoints))
+	names := make(map[string]string, len(cfg.Config.Tunnel.Endpoints))
+	for _, e := range cfg.Config.Tunnel.Endpoints {
+		if _, _, err := net.SplitHostPort(e.Address); err != nil {
+			return fmt.Errorf("%w: invalid endpoint address %q", ErrInvalidConfiguration, e.Address)
+		}
+		weight := e.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 0 {
+			return fmt.Errorf("%w: endpoint weight must be positive, got %d", ErrInvalidConfiguration, weight)
+		}
+		want[e.Address] = weight
+		names[e.Address] = e.ServerName
+	}
+
+	// Drop endpoints no longer configured or whose weight or server name
+	// changed, then add the rest
+	have := make(map[string]bool)
+	for _, e := range c.endpoints.List() {
+		if weight, ok := want[e.Address]; ok && weight == e.Weight && names[e.Address] == e.ServerName {
+			have[e.Address] = true
+			continue
+		}
+		if err := c.endpoints.Remove(e.Address); err != nil {
+			c.logger.Warn("Failed to remove server endpoint", zap.String("address", e.Address), zap.Error(err))
+		}
+	}
+	for _, e := range cfg.Config.Tunnel.Endpoints {
+		if have[e.Address] {
+			continue
+		}
+		if err := c.endpoints.AddWithServerName(e.Address, want[e.Address], names[e.Address]); err != nil {
+			c.logger.Warn("Ignoring server endpoint", zap.String("address", e.Address), zap.Error(err))
+		}
+		have[e.Address] = true
+	}
+
+	c.reloaded.Store(cfg)
+	c.logger.Info("Applied reloaded configuration", zap.Int("endpoints", c.endpoints.Len()))
+	return nil
+}
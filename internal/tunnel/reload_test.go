@@ -0,0 +1,109 @@
+package tunnel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"go.uber.org/zap"
+)
+
+func reloadTestConfig(mode types.Type) *types.AppConfig {
+	cfg := types.NewAppConfig(mode)
+	cfg.Config.Network.Name = "tun0"
+	cfg.Config.Tunnel.ListenPort = 8443
+	cfg.Config.Tunnel.MaxTransientErrors = 3
+	return cfg
+}
+
+func TestServerReload(t *testing.T) {
+	running := reloadTestConfig(types.TypeServer)
+	s := &Server{config: running, logger: zap.NewNop()}
+
+	reloaded := reloadTestConfig(types.TypeServer)
+	reloaded.Config.Tunnel.MaxTransientErrors = 10
+	if err := s.Reload(reloaded); err != nil {
+		t.Fatalf("Failed to reload configuration: %v", err)
+	}
+	if s.currentConfig() != reloaded {
+		t.Fatalf("Expected new connections to use the reloaded configuration")
+	}
+
+	// A restart-only change is rejected and the applied configuration kept
+	moved := reloadTestConfig(types.TypeServer)
+	moved.Config.Tunnel.ListenPort = 9443
+	if err := s.Reload(moved); !errors.Is(err, ErrReloadRequiresRestart) {
+		t.Errorf("Expected ErrReloadRequiresRestart, got %v", err)
+	}
+	if s.currentConfig() != reloaded {
+		t.Errorf("Expected the rejected configuration to leave the running one untouched")
+	}
+}
+
+func TestReloadRejectsRestartOnlySettings(t *testing.T) {
+	changes := map[string]func(cfg *types.Config){
+		"network.routes":         func(cfg *types.Config) { cfg.Network.Routes = []string{"10.20.0.0/16"} },
+		"network.apply_dns":      func(cfg *types.Config) { cfg.Network.ApplyDNS = true },
+		"tunnel.keepalive":       func(cfg *types.Config) { cfg.Tunnel.Keepalive = "10s" },
+		"tunnel.heartbeat":       func(cfg *types.Config) { cfg.Tunnel.Heartbeat = true },
+		"tunnel.coalesce_window": func(cfg *types.Config) { cfg.Tunnel.CoalesceWindow = time.Millisecond },
+	}
+
+	for name, change := range changes {
+		s := &Server{config: reloadTestConfig(types.TypeServer), logger: zap.NewNop()}
+		reloaded := reloadTestConfig(types.TypeServer)
+		change(reloaded.Config)
+		if err := s.Reload(reloaded); !errors.Is(err, ErrReloadRequiresRestart) {
+			t.Errorf("%s: expected ErrReloadRequiresRestart, got %v", name, err)
+		}
+	}
+
+	s := &Server{config: reloadTestConfig(types.TypeServer), logger: zap.NewNop()}
+	reloaded := reloadTestConfig(types.TypeServer)
+	reloaded.Throttle.Connections = types.ConnectionThrottleConfig{Enabled: true, Rate: 1, Burst: 1}
+	if err := s.Reload(reloaded); !errors.Is(err, ErrReloadRequiresRestart) {
+		t.Errorf("throttle.connections.enabled: expected ErrReloadRequiresRestart, got %v", err)
+	}
+}
+
+func TestClientReloadEndpoints(t *testing.T) {
+	running := reloadTestConfig(types.TypeClient)
+	c := &Client{config: running, logger: zap.NewNop(), endpoints: NewEndpointManager(testBreakerConfig(), zap.NewNop())}
+	for _, address := range []string{"10.0.0.1:8443", "10.0.0.2:8443"} {
+		if err := c.endpoints.Add(address, 1); err != nil {
+			t.Fatalf("Failed to add endpoint: %v", err)
+		}
+	}
+
+	reloaded := reloadTestConfig(types.TypeClient)
+	reloaded.Config.Tunnel.Endpoints = []types.EndpointConfig{
+		{Address: "10.0.0.2:8443", Weight: 2},
+		{Address: "10.0.0.3:8443"},
+	}
+	if err := c.Reload(reloaded); err != nil {
+		t.Fatalf("Failed to reload configuration: %v", err)
+	}
+
+	statuses := c.endpoints.List()
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 endpoints, got %+v", statuses)
+	}
+	weights := map[string]int{}
+	for _, status := range statuses {
+		weights[status.Address] = status.Weight
+	}
+	if weights["10.0.0.2:8443"] != 2 || weights["10.0.0.3:8443"] != 1 {
+		t.Errorf("Expected the configured endpoints and weights, got %+v", statuses)
+	}
+
+	// An invalid endpoint rejects the whole reload
+	invalid := reloadTestConfig(types.TypeClient)
+	invalid.Config.Tunnel.Endpoints = []types.EndpointConfig{{Address: "10.0.0.4"}}
+	if err := c.Reload(invalid); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Expected ErrInvalidConfiguration, got %v", err)
+	}
+	if c.endpoints.Len() != 2 || c.currentConfig() != reloaded {
+		t.Errorf("Expected the rejected configuration to leave the running one untouched")
+	}
+}
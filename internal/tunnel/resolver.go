@@ -0,0 +1,106 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+	"go.uber.org/zap"
+)
+
+// defaultDNSCacheTTL is used when DNS caching is enabled without a TTL
+const defaultDNSCacheTTL = 30 * time.Second
+
+// HostResolver resolves host names to IP addresses; *net.Resolver implements it
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// resolvedHost is a cached resolution result
+type resolvedHost struct {
+	addrs      []net.IPAddr
+	resolvedAt time.Time
+}
+
+// CachingResolver caches host resolutions for a short TTL and falls back to the
+// last good result when resolution fails
+type CachingResolver struct {
+	resolver HostResolver
+	ttl      time.Duration
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	entries map[string]*resolvedHost
+}
+
+// NewCachingResolver creates a new caching resolver
+func NewCachingResolver(resolver HostResolver, ttl time.Duration, logger *zap.Logger) *CachingResolver {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if ttl <= 0 {
+		ttl = defaultDNSCacheTTL
+	}
+
+	return &CachingResolver{
+		resolver: resolver,
+		ttl:      ttl,
+		logger:   logger,
+		entries:  make(map[string]*resolvedHost),
+	}
+}
+
+// Resolve returns the addresses for a host, using the cached result while it is fresh
+func (r *CachingResolver) Resolve(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+
+	r.mu.Lock()
+	cached := r.entries[host]
+	r.mu.Unlock()
+
+	if cached != nil && time.Since(cached.resolvedAt) < r.ttl {
+		return cached.addrs, nil
+	}
+
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no addresses found for %s", host)
+	}
+	if err != nil {
+		if cached != nil {
+			r.logger.Warn("Failed to resolve server address, using last good result",
+				zap.String("host", host),
+				zap.Duration("age", time.Since(cached.resolvedAt)),
+				zap.Error(err),
+			)
+			return cached.addrs, nil
+		}
+		return nil, apperrors.Network(apperrors.ErrNetworkResolve, err, "failed to resolve %s", host)
+	}
+
+	r.mu.Lock()
+	r.entries[host] = &resolvedHost{addrs: addrs, resolvedAt: time.Now()}
+	r.mu.Unlock()
+
+	return addrs, nil
+}
+
+// ResolveIPv4 returns the first IPv4 address for a host
+func (r *CachingResolver) ResolveIPv4(ctx context.Context, host string) (net.IP, error) {
+	addrs, err := r.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+	}
+	return nil, apperrors.Network(apperrors.ErrNetworkResolve, nil, "no IPv4 address found for %s", host)
+}
@@ -0,0 +1,63 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// flakyResolver succeeds once and fails on every later lookup
+type flakyResolver struct {
+	lookups int
+}
+
+func (r *flakyResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.lookups++
+	if r.lookups > 1 {
+		return nil, errors.New("temporary DNS failure")
+	}
+	return []net.IPAddr{{IP: net.ParseIP("192.0.2.10")}}, nil
+}
+
+func TestCachingResolverFallback(t *testing.T) {
+	mock := &flakyResolver{}
+	resolver := NewCachingResolver(mock, time.Hour, zap.NewNop())
+
+	ip, err := resolver.ResolveIPv4(context.Background(), "server.example.com")
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	if ip.String() != "192.0.2.10" {
+		t.Errorf("Expected 192.0.2.10, got %s", ip)
+	}
+
+	// Within the TTL the cached result is reused without a lookup
+	if _, err := resolver.ResolveIPv4(context.Background(), "server.example.com"); err != nil {
+		t.Fatalf("Failed to resolve from cache: %v", err)
+	}
+	if mock.lookups != 1 {
+		t.Errorf("Expected 1 lookup, got %d", mock.lookups)
+	}
+
+	// After the TTL a failed lookup falls back to the last good result
+	resolver.ttl = 0
+	ip, err = resolver.ResolveIPv4(context.Background(), "server.example.com")
+	if err != nil {
+		t.Fatalf("Expected fallback to cached result, got error: %v", err)
+	}
+	if ip.String() != "192.0.2.10" {
+		t.Errorf("Expected cached 192.0.2.10, got %s", ip)
+	}
+	if mock.lookups != 2 {
+		t.Errorf("Expected 2 lookups, got %d", mock.lookups)
+	}
+
+	// Without a previous result the failure is returned
+	if _, err := resolver.ResolveIPv4(context.Background(), "other.example.com"); err == nil {
+		t.Error("Expected error resolving uncached host")
+	}
+}
@@ -0,0 +1,42 @@
+package tunnel
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+)
+
+// rttProbe times request/response exchanges across a transfer. A packet forwarded
+// from src to dst opens a request; the first packet forwarded back answers it and
+// yields one RTT sample. Packets sent while a request is open do not restart it.
+type rttProbe struct {
+	tracker *monitor.LatencyTracker
+	pending atomic.Int64 // UnixNano of the open request, or zero
+}
+
+// request marks a packet forwarded towards dst
+func (p *rttProbe) request() {
+	p.pending.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+// response marks a packet forwarded back towards src
+func (p *rttProbe) response() {
+	if sent := p.pending.Swap(0); sent != 0 {
+		p.tracker.AddRTTSample(time.Duration(time.Now().UnixNano() - sent))
+	}
+}
+
+// probeWriter calls mark as each packet is handed on, before the peer can see it
+type probeWriter struct {
+	io.Writer
+	mark func()
+}
+
+func (w *probeWriter) Write(b []byte) (int, error) {
+	if len(b) > 0 {
+		w.mark()
+	}
+	return w.Writer.Write(b)
+}
@@ -0,0 +1,158 @@
+package tunnel
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/o3willard-AI/SSSonector/internal/adapter"
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+	"go.uber.org/zap"
+)
+
+// startupAdapter records whether the interface was removed
+type startupAdapter struct {
+	*mockAdapter
+	configureErr error
+	onConfigure  func() // Runs when the interface is configured, if set
+	cleanups     int
+}
+
+func (a *startupAdapter) Configure(cfg *adapter.Config) error {
+	if a.onConfigure != nil {
+		a.onConfigure()
+	}
+	return a.configureErr
+}
+func (a *startupAdapter) Cleanup() error {
+	a.cleanups++
+	return nil
+}
+
+// useStartupAdapter makes the tunnel create a startupAdapter for the test
+func useStartupAdapter(t *testing.T, a *startupAdapter) {
+	orig := newAdapter
+	newAdapter = func(name string, opts *adapter.Options) (adapter.Interface, error) {
+		return a, nil
+	}
+	t.Cleanup(func() { newAdapter = orig })
+}
+
+func TestServerStartFailureRemovesInterface(t *testing.T) {
+	// Occupy the listen port so the server fails after creating the interface
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Network.Name = "tun-test0"
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+
+	// A port that is free when probed and taken once the interface exists,
+	// as when another process binds it in between
+	free, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	freePort := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+	takePort := func() {
+		taken, err := net.Listen("tcp4", free.Addr().String())
+		if err != nil {
+			t.Fatalf("Failed to take the listen port: %v", err)
+		}
+		t.Cleanup(func() { taken.Close() })
+	}
+
+	tests := []struct {
+		name         string
+		port         int
+		configureErr error
+		onConfigure  func()
+		wantCleanups int
+		wantCode     apperrors.Code
+	}{
+		{"configure fails", 0, errors.New("configure failed"), nil, 1, apperrors.ErrTunnelInterface},
+		// The port probe fails before the interface is created
+		{"probe fails", ln.Addr().(*net.TCPAddr).Port, nil, nil, 0, apperrors.ErrNetworkPort},
+		{"listen fails", freePort, nil, takePort, 1, apperrors.ErrNetworkPort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Config.Tunnel.ListenPort = tt.port
+			iface := &startupAdapter{mockAdapter: newMockAdapter(), configureErr: tt.configureErr, onConfigure: tt.onConfigure}
+			useStartupAdapter(t, iface)
+
+			server := NewServer(cfg, nil, zap.NewNop())
+			defer server.Stop()
+
+			err := server.Start()
+			if err == nil {
+				t.Fatal("Expected server start to fail")
+			}
+			if code := apperrors.CodeOf(err); code != tt.wantCode {
+				t.Errorf("Expected error code %s, got %q", tt.wantCode, code)
+			}
+			if iface.cleanups != tt.wantCleanups {
+				t.Errorf("Expected %d interface removals, got %d", tt.wantCleanups, iface.cleanups)
+			}
+		})
+	}
+}
+
+func TestServerPortConflictFailsBeforeInterface(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Network.Name = "tun-test0"
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+	cfg.Config.Tunnel.ListenPort = ln.Addr().(*net.TCPAddr).Port
+
+	created := false
+	orig := newAdapter
+	newAdapter = func(name string, opts *adapter.Options) (adapter.Interface, error) {
+		created = true
+		return newMockAdapter(), nil
+	}
+	defer func() { newAdapter = orig }()
+
+	if err := ProbeListenPort(cfg); !errors.Is(err, ErrNetworkPort) {
+		t.Errorf("Expected probe to fail with ErrNetworkPort, got %v", err)
+	}
+
+	server := NewServer(cfg, nil, zap.NewNop())
+	defer server.Stop()
+	if err := server.Start(); !errors.Is(err, ErrNetworkPort) {
+		t.Fatalf("Expected start to fail with ErrNetworkPort, got %v", err)
+	}
+	if created {
+		t.Error("Expected the port conflict to fail before the interface is created")
+	}
+}
+
+func TestServerStartSuccessKeepsInterface(t *testing.T) {
+	cfg := &types.AppConfig{Config: &types.Config{}}
+	cfg.Config.Network.Name = "tun-test0"
+	cfg.Config.Tunnel.ListenAddress = "127.0.0.1"
+
+	iface := &startupAdapter{mockAdapter: newMockAdapter()}
+	useStartupAdapter(t, iface)
+
+	server := NewServer(cfg, nil, zap.NewNop())
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	if iface.cleanups != 0 {
+		t.Errorf("Expected interface to be kept, got %d cleanups", iface.cleanups)
+	}
+}
@@ -1,12 +1,36 @@
 package tunnel
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/cert"
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"go.uber.org/zap"
+)
+
+// defaultSlowHandshakeThreshold is the handshake duration above which a warning is logged
+const defaultSlowHandshakeThreshold = time.Second
+
+const (
+	// sessionTicketKeysKept is how many session ticket keys the server keeps:
+	// the newest encrypts tickets, the older still resume sessions issued
+	// before the last rotation
+	sessionTicketKeysKept = 2
+
+	// clientSessionCacheSize is how many servers' sessions a client keeps for
+	// resumption
+	clientSessionCacheSize = 64
 )
 
 // TLSConfig holds TLS configuration
@@ -16,12 +40,37 @@ type TLSConfig struct {
 	CAFile        string
 	SecurityLevel SecurityLevel // Added security level configuration
 	ServerName    string        // Added server name for client verification
+	// GetCertificate overrides the server certificate source, e.g. a certificate rotator,
+	// so rotated certificates are served to new handshakes without a restart
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	// VerifyPeerCertificate adds server-side checks of client certificates, e.g. CRL revocation
+	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+	// SlowHandshakeThreshold is the handshake duration above which a warning is logged
+	SlowHandshakeThreshold time.Duration
+	// DisableSessionResumption makes every connection run a full handshake, for
+	// environments that forbid session tickets
+	DisableSessionResumption bool
+	Logger                   *zap.Logger
 }
 
 // TLSManager handles TLS operations
 type TLSManager struct {
 	certManager *cert.Manager
 	config      *TLSConfig
+	handshakes  *monitor.HandshakeRecorder
+	logger      *zap.Logger
+
+	// negotiated counts completed handshakes by TLS version and cipher suite
+	negotiatedMu sync.Mutex
+	negotiated   monitor.TLSStats
+
+	// Session resumption, nil when disabled: tickets carries the session ticket
+	// keys shared by every server config handed out, and sessions caches the
+	// client's sessions
+	tickets    *tls.Config
+	sessions   tls.ClientSessionCache
+	ticketMu   sync.Mutex // Serializes RotateSessionTicketKey
+	ticketKeys [][32]byte
 }
 
 // NewTLSManager creates a new TLS manager
@@ -43,10 +92,56 @@ func NewTLSManager(config *TLSConfig) (*TLSManager, error) {
 		return nil, fmt.Errorf("failed to create certificate manager: %w", err)
 	}
 
-	return &TLSManager{
+	threshold := config.SlowHandshakeThreshold
+	if threshold <= 0 {
+		threshold = defaultSlowHandshakeThreshold
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	t := &TLSManager{
 		certManager: manager,
 		config:      config,
-	}, nil
+		handshakes:  monitor.NewHandshakeRecorder(threshold),
+		logger:      logger,
+	}
+	if !config.DisableSessionResumption {
+		t.tickets = &tls.Config{}
+		t.sessions = tls.NewLRUClientSessionCache(clientSessionCacheSize)
+		if err := t.RotateSessionTicketKey(); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// RotateSessionTicketKey starts encrypting session tickets with a new key. The
+// previous key still resumes sessions until the next rotation, when it is
+// dropped. Call it alongside certificate rotation, e.g. from
+// cert.RotationConfig.OnRotation, so that sessions cannot outlive two
+// certificates. It does nothing when session resumption is disabled.
+func (t *TLSManager) RotateSessionTicketKey() error {
+	if t.tickets == nil {
+		return nil
+	}
+
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return fmt.Errorf("failed to generate session ticket key: %w", err)
+	}
+
+	t.ticketMu.Lock()
+	defer t.ticketMu.Unlock()
+	keys := append([][32]byte{key}, t.ticketKeys...)
+	if len(keys) > sessionTicketKeysKept {
+		keys = keys[:sessionTicketKeysKept]
+	}
+	t.ticketKeys = keys
+	t.tickets.SetSessionTicketKeys(keys)
+	return nil
 }
 
 // GetClientConfig returns TLS configuration for client mode
@@ -68,6 +163,10 @@ func (t *TLSManager) GetClientConfig() (*tls.Config, error) {
 		config.InsecureSkipVerify = true
 	}
 
+	// Resume sessions with servers seen before
+	config.SessionTicketsDisabled = t.sessions == nil
+	config.ClientSessionCache = t.sessions
+
 	return config, nil
 }
 
@@ -95,15 +194,110 @@ func (t *TLSManager) GetServerConfig() (*tls.Config, error) {
 	// Apply security level configuration
 	config := GetTLSSecurityLevel(t.config.SecurityLevel, baseConfig)
 
+	if t.config.GetCertificate != nil {
+		config.GetCertificate = t.config.GetCertificate
+	}
+	if t.config.VerifyPeerCertificate != nil {
+		config.VerifyPeerCertificate = t.config.VerifyPeerCertificate
+	}
+
 	// Additional server-specific settings
 	config.PreferServerCipherSuites = true     // Server chooses cipher suite
 	config.DynamicRecordSizingDisabled = false // Enable dynamic record sizing for better performance
-	config.SessionTicketsDisabled = true       // Disable session tickets for perfect forward secrecy
+
+	// Issue session tickets under the manager's rotating keys so reconnecting
+	// clients skip the full handshake
+	config.SessionTicketsDisabled = t.tickets == nil
+	if t.tickets != nil {
+		config.WrapSession = t.tickets.EncryptTicket
+		config.UnwrapSession = t.tickets.DecryptTicket
+		if verify := t.config.VerifyPeerCertificate; verify != nil {
+			config.VerifyConnection = verifyResumedPeer(config.VerifyConnection, verify)
+		}
+	}
 
 	return config, nil
 }
 
-// WrapConn wraps a net.Conn with TLS
+// verifyResumedPeer runs verify on the client certificate of resumed sessions,
+// which skip VerifyPeerCertificate, so that e.g. a certificate revoked since
+// the session began cannot resume it. next is the VerifyConnection it wraps.
+func verifyResumedPeer(next func(tls.ConnectionState) error, verify func([][]byte, [][]*x509.Certificate) error) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if cs.DidResume {
+			rawCerts := make([][]byte, len(cs.PeerCertificates))
+			for i, cert := range cs.PeerCertificates {
+				rawCerts[i] = cert.Raw
+			}
+			if err := verify(rawCerts, cs.VerifiedChains); err != nil {
+				return err
+			}
+		}
+		if next != nil {
+			return next(cs)
+		}
+		return nil
+	}
+}
+
+// HandshakeStats returns TLS handshake duration statistics
+func (t *TLSManager) HandshakeStats() monitor.HandshakeStats {
+	return t.handshakes.Stats()
+}
+
+// Handshake runs the TLS handshake, recording its duration and logging slow handshakes
+func (t *TLSManager) Handshake(ctx context.Context, conn *tls.Conn) error {
+	start := time.Now()
+	err := conn.HandshakeContext(ctx)
+	duration := time.Since(start)
+
+	if t.handshakes.Record(duration, err) {
+		t.logger.Warn("Slow TLS handshake",
+			zap.Duration("duration", duration),
+			zap.Duration("threshold", t.handshakes.SlowThreshold()),
+			zap.String("remote_addr", conn.RemoteAddr().String()),
+		)
+	}
+	if err != nil {
+		err = resilience.MarkRetryable(err, !isCertificateError(err))
+		return apperrors.Tunnel(apperrors.ErrTunnelHandshake, err, "TLS handshake failed after %v", duration)
+	}
+
+	state := conn.ConnectionState()
+	version, cipherSuite := tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite)
+	t.negotiatedMu.Lock()
+	t.negotiated.Record(version, cipherSuite)
+	t.negotiatedMu.Unlock()
+
+	t.logger.Debug("TLS handshake completed",
+		zap.Duration("duration", duration),
+		zap.String("remote_addr", conn.RemoteAddr().String()),
+		zap.String("tls_version", version),
+		zap.String("cipher_suite", cipherSuite),
+	)
+	return nil
+}
+
+// TLSStats returns completed handshakes counted by negotiated TLS version and
+// cipher suite
+func (t *TLSManager) TLSStats() monitor.TLSStats {
+	t.negotiatedMu.Lock()
+	defer t.negotiatedMu.Unlock()
+	return t.negotiated.Clone()
+}
+
+// isCertificateError reports whether a handshake failed verifying a
+// certificate, which fails the same way until the certificates change
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &hostnameErr)
+}
+
+// WrapConn wraps a net.Conn with TLS and completes the handshake
 func (t *TLSManager) WrapConn(conn net.Conn, isServer bool) (net.Conn, error) {
 	var tlsConfig *tls.Config
 	var err error
@@ -118,8 +312,15 @@ func (t *TLSManager) WrapConn(conn net.Conn, isServer bool) (net.Conn, error) {
 		return nil, fmt.Errorf("failed to get TLS config: %w", err)
 	}
 
+	var tlsConn *tls.Conn
 	if isServer {
-		return tls.Server(conn, tlsConfig), nil
+		tlsConn = tls.Server(conn, tlsConfig)
+	} else {
+		tlsConn = tls.Client(conn, tlsConfig)
+	}
+
+	if err := t.Handshake(context.Background(), tlsConn); err != nil {
+		return nil, err
 	}
-	return tls.Client(conn, tlsConfig), nil
+	return tlsConn, nil
 }
@@ -2,14 +2,23 @@ package tunnel
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/cert/generator"
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestTLSSecurityLevels(t *testing.T) {
@@ -206,6 +215,321 @@ func TestTLSHandshake(t *testing.T) {
 	}
 }
 
+// slowConn delays its first write to simulate a slow handshake peer
+type slowConn struct {
+	net.Conn
+	delay   time.Duration
+	delayed bool
+}
+
+func (c *slowConn) Write(b []byte) (int, error) {
+	if !c.delayed {
+		c.delayed = true
+		time.Sleep(c.delay)
+	}
+	return c.Conn.Write(b)
+}
+
+func TestTLSHandshakeMetrics(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tls-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := generator.GenerateTemporaryCertificates(tempDir); err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	serverManager, err := NewTLSManager(&TLSConfig{
+		CertFile:               filepath.Join(tempDir, "server.crt"),
+		KeyFile:                filepath.Join(tempDir, "server.key"),
+		CAFile:                 filepath.Join(tempDir, "ca.crt"),
+		SecurityLevel:          SecurityModern,
+		SlowHandshakeThreshold: 20 * time.Millisecond,
+		Logger:                 zap.New(core),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server TLS manager: %v", err)
+	}
+
+	clientManager, err := NewTLSManager(&TLSConfig{
+		CertFile:      filepath.Join(tempDir, "client.crt"),
+		KeyFile:       filepath.Join(tempDir, "client.key"),
+		CAFile:        filepath.Join(tempDir, "ca.crt"),
+		SecurityLevel: SecurityModern,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client TLS manager: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer listener.Close()
+
+	serverDone := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverDone <- err
+			return
+		}
+		defer conn.Close()
+
+		_, err = serverManager.WrapConn(conn, true)
+		serverDone <- err
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Delay the ClientHello so the server-side handshake exceeds its threshold
+	if _, err := clientManager.WrapConn(&slowConn{Conn: conn, delay: 50 * time.Millisecond}, false); err != nil {
+		t.Fatalf("Client handshake failed: %v", err)
+	}
+
+	select {
+	case err := <-serverDone:
+		if err != nil {
+			t.Fatalf("Server handshake failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server timeout")
+	}
+
+	stats := serverManager.HandshakeStats()
+	if stats.Count != 1 {
+		t.Errorf("Expected 1 recorded handshake, got %d", stats.Count)
+	}
+	if stats.Average < 50*time.Millisecond || stats.P95 < 50*time.Millisecond {
+		t.Errorf("Expected recorded duration of at least 50ms, got avg %v p95 %v", stats.Average, stats.P95)
+	}
+	if stats.SlowCount != 1 {
+		t.Errorf("Expected 1 slow handshake, got %d", stats.SlowCount)
+	}
+
+	if logs.FilterMessage("Slow TLS handshake").Len() != 1 {
+		t.Errorf("Expected a slow handshake warning, got %d warnings", logs.Len())
+	}
+
+	if clientStats := clientManager.HandshakeStats(); clientStats.Count != 1 || clientStats.SlowCount != 0 {
+		t.Errorf("Expected 1 fast client handshake, got %+v", clientStats)
+	}
+
+	negotiated := serverManager.TLSStats()
+	if len(negotiated.Versions) != 1 || len(negotiated.CipherSuites) != 1 {
+		t.Fatalf("Expected one negotiated version and cipher suite, got %+v", negotiated)
+	}
+	for version, count := range negotiated.Versions {
+		if count != 1 || !strings.HasPrefix(version, "TLS 1.") {
+			t.Errorf("Expected 1 connection on a TLS version, got %d on %q", count, version)
+		}
+	}
+}
+
+func TestTLSHandshakeRetryability(t *testing.T) {
+	serverDir, clientDir := t.TempDir(), t.TempDir()
+	for _, dir := range []string{serverDir, clientDir} {
+		if err := generator.GenerateTemporaryCertificates(dir); err != nil {
+			t.Fatalf("Failed to generate certificates: %v", err)
+		}
+	}
+
+	serverManager, err := NewTLSManager(&TLSConfig{
+		CertFile:      filepath.Join(serverDir, "server.crt"),
+		KeyFile:       filepath.Join(serverDir, "server.key"),
+		CAFile:        filepath.Join(serverDir, "ca.crt"),
+		SecurityLevel: SecurityModern,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server TLS manager: %v", err)
+	}
+	// The client trusts a different CA than the one that signed the server, and
+	// names the server so that it is verified
+	clientManager, err := NewTLSManager(&TLSConfig{
+		CertFile:      filepath.Join(clientDir, "client.crt"),
+		KeyFile:       filepath.Join(clientDir, "client.key"),
+		CAFile:        filepath.Join(clientDir, "ca.crt"),
+		ServerName:    "localhost",
+		SecurityLevel: SecurityModern,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client TLS manager: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		serve     func(conn net.Conn)
+		retryable bool
+	}{
+		{"untrusted certificate", func(conn net.Conn) { serverManager.WrapConn(conn, true) }, false},
+		{"connection closed", func(conn net.Conn) {}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatalf("Failed to create listener: %v", err)
+			}
+			defer listener.Close()
+
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				tt.serve(conn)
+			}()
+
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer conn.Close()
+
+			_, err = clientManager.WrapConn(conn, false)
+			if !errors.Is(err, apperrors.ErrTunnelHandshake) {
+				t.Fatalf("Expected a handshake error, got %v", err)
+			}
+			if retryable, ok := resilience.IsRetryable(err); !ok || retryable != tt.retryable {
+				t.Errorf("Expected retryable %v, got %v (marked %v)", tt.retryable, retryable, ok)
+			}
+		})
+	}
+}
+
+func TestTLSSessionResumption(t *testing.T) {
+	certDir := t.TempDir()
+	if err := generator.GenerateTemporaryCertificates(certDir); err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+	managers := func(disabled bool) (*TLSManager, *TLSManager) {
+		server, err := NewTLSManager(&TLSConfig{
+			CertFile:                 filepath.Join(certDir, "server.crt"),
+			KeyFile:                  filepath.Join(certDir, "server.key"),
+			CAFile:                   filepath.Join(certDir, "ca.crt"),
+			SecurityLevel:            SecurityModern,
+			DisableSessionResumption: disabled,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create server TLS manager: %v", err)
+		}
+		client, err := NewTLSManager(&TLSConfig{
+			CertFile:                 filepath.Join(certDir, "client.crt"),
+			KeyFile:                  filepath.Join(certDir, "client.key"),
+			CAFile:                   filepath.Join(certDir, "ca.crt"),
+			SecurityLevel:            SecurityModern,
+			DisableSessionResumption: disabled,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create client TLS manager: %v", err)
+		}
+		return server, client
+	}
+
+	// serve answers handshakes on one address, as sessions are cached per
+	// server address
+	serve := func(server *TLSManager) string {
+		listener, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Failed to create listener: %v", err)
+		}
+		t.Cleanup(func() { listener.Close() })
+
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer conn.Close()
+					tlsConn, err := server.WrapConn(conn, true)
+					if err != nil {
+						return
+					}
+					tlsConn.Write([]byte{1})
+					io.Copy(io.Discard, tlsConn)
+				}()
+			}
+		}()
+		return listener.Addr().String()
+	}
+
+	// connect runs a handshake and reports whether it resumed a session
+	connect := func(address string, client *TLSManager) bool {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		tlsConn, err := client.WrapConn(conn, false)
+		if err != nil {
+			t.Fatalf("Client handshake failed: %v", err)
+		}
+
+		// TLS 1.3 tickets arrive after the handshake, with the first data read
+		if _, err := io.ReadFull(tlsConn, make([]byte, 1)); err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		return tlsConn.(*tls.Conn).ConnectionState().DidResume
+	}
+
+	server, client := managers(false)
+	address := serve(server)
+	if connect(address, client) {
+		t.Error("Expected the first handshake to be a full one")
+	}
+	if !connect(address, client) {
+		t.Error("Expected the reconnect to resume the session")
+	}
+
+	// Sessions survive one ticket key rotation but not two
+	if err := server.RotateSessionTicketKey(); err != nil {
+		t.Fatalf("Failed to rotate session ticket key: %v", err)
+	}
+	if !connect(address, client) {
+		t.Error("Expected a session from before the rotation to resume")
+	}
+	server.RotateSessionTicketKey()
+	server.RotateSessionTicketKey()
+	if connect(address, client) {
+		t.Error("Expected a session under a dropped key to run a full handshake")
+	}
+
+	server, client = managers(true)
+	address = serve(server)
+	connect(address, client)
+	if connect(address, client) {
+		t.Error("Expected no resumption when it is disabled")
+	}
+}
+
+func TestVerifyResumedPeer(t *testing.T) {
+	revoked := errors.New("certificate revoked")
+	verified := 0
+	verify := verifyResumedPeer(nil, func([][]byte, [][]*x509.Certificate) error {
+		verified++
+		return revoked
+	})
+
+	// Full handshakes already ran VerifyPeerCertificate
+	if err := verify(tls.ConnectionState{}); err != nil || verified != 0 {
+		t.Errorf("Expected a full handshake to pass unchecked, got %v after %d checks", err, verified)
+	}
+	if err := verify(tls.ConnectionState{DidResume: true}); !errors.Is(err, revoked) {
+		t.Errorf("Expected the resumed session to be rejected, got %v", err)
+	}
+}
+
 func containsAllCiphers(have, want []uint16) bool {
 	if len(have) < len(want) {
 		return false
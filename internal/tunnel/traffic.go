@@ -0,0 +1,64 @@
+package tunnel
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// TrafficStats holds the traffic forwarded through the tunnel since it started.
+// In is read from the tunnel peer, out is written to it.
+type TrafficStats struct {
+	BytesIn    int64
+	BytesOut   int64
+	PacketsIn  int64
+	PacketsOut int64
+	Errors     int64 // Forwarding directions that ended with an error
+}
+
+// trafficCounters accumulates the traffic of concurrent transfers
+type trafficCounters struct {
+	bytesIn    atomic.Int64
+	bytesOut   atomic.Int64
+	packetsIn  atomic.Int64
+	packetsOut atomic.Int64
+	errors     atomic.Int64
+}
+
+// stats returns the traffic counted so far
+func (c *trafficCounters) stats() TrafficStats {
+	return TrafficStats{
+		BytesIn:    c.bytesIn.Load(),
+		BytesOut:   c.bytesOut.Load(),
+		PacketsIn:  c.packetsIn.Load(),
+		PacketsOut: c.packetsOut.Load(),
+		Errors:     c.errors.Load(),
+	}
+}
+
+// TrafficStats returns the traffic forwarded for all clients since the server
+// was created
+func (s *Server) TrafficStats() TrafficStats {
+	return s.traffic.stats()
+}
+
+// TrafficStats returns the traffic forwarded through the tunnel since the
+// client was created
+func (c *Client) TrafficStats() TrafficStats {
+	return c.traffic.stats()
+}
+
+// countingReader counts the bytes and reads, each a packet, passing through it
+type countingReader struct {
+	r       io.Reader
+	bytes   *atomic.Int64
+	packets *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.bytes.Add(int64(n))
+		c.packets.Add(1)
+	}
+	return n, err
+}
@@ -1,11 +1,17 @@
 package tunnel
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
 	"github.com/o3willard-AI/SSSonector/internal/throttle"
 	"go.uber.org/zap"
 )
@@ -17,42 +23,208 @@ type Transfer struct {
 	srcToDst *throttle.Limiter
 	dstToSrc *throttle.Limiter
 	logger   *zap.Logger
+	// packetSize is the read buffer size, large enough for a full packet
+	packetSize int
+	// maxErrors is the number of consecutive transient errors tolerated per direction
+	maxErrors  int
+	retryDelay time.Duration
+	// buffers supplies the read buffers
+	buffers *memory.SizedPool
+	// rtt measures request/response round trips when set
+	rtt *rttProbe
+	// coalesce batches the packets written to src, framed, when enabled; the
+	// packets read from src are then framed too
+	coalesce coalesceSettings
+	// heartbeat sends heartbeats to src and closes a src that falls silent,
+	// when enabled; the packets to and from src are then framed
+	heartbeat heartbeatSettings
+	// limits applies the shared rate limits to the packets read from either
+	// side, in place of srcToDst and dstToSrc, when set
+	limits *throttle.Registry
+	client string
+	// traffic counts the forwarded traffic when set
+	traffic *trafficCounters
+	// tunnelIP records the source address of the first packet from src
+	// when set
+	tunnelIP func(ip net.IP)
+	// closeOnce makes closing the connections safe from Stop and the copy loops
+	closeOnce sync.Once
 }
 
+// Transient error tolerance defaults
+const (
+	defaultMaxTransientErrors  = 3
+	defaultTransientErrorDelay = 10 * time.Millisecond
+)
+
 // NewTransfer creates a new transfer
 func NewTransfer(src, dst net.Conn, cfg *types.AppConfig, logger *zap.Logger) *Transfer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	// Create rate limiters for each direction
 	srcToDst := throttle.NewLimiter(cfg, src, dst, logger)
 	dstToSrc := throttle.NewLimiter(cfg, dst, src, logger)
 
+	maxErrors, retryDelay := defaultMaxTransientErrors, defaultTransientErrorDelay
+	if cfg != nil && cfg.Config != nil {
+		if cfg.Config.Tunnel.MaxTransientErrors > 0 {
+			maxErrors = cfg.Config.Tunnel.MaxTransientErrors
+		}
+		if cfg.Config.Tunnel.TransientErrorDelay > 0 {
+			retryDelay = cfg.Config.Tunnel.TransientErrorDelay
+		}
+	}
+
 	return &Transfer{
-		src:      src,
-		dst:      dst,
-		srcToDst: srcToDst,
-		dstToSrc: dstToSrc,
-		logger:   logger,
+		src:        src,
+		dst:        dst,
+		srcToDst:   srcToDst,
+		dstToSrc:   dstToSrc,
+		logger:     logger,
+		packetSize: packetBufferSize(cfg),
+		maxErrors:  maxErrors,
+		retryDelay: retryDelay,
+		buffers:    sharedBufferPool,
+		coalesce:   coalesceSettingsFrom(cfg),
+		heartbeat:  heartbeatSettingsFrom(cfg),
+	}
+}
+
+// SetBufferPool makes the transfer draw its read buffers from pool
+func (t *Transfer) SetBufferPool(pool *memory.SizedPool) {
+	t.buffers = pool
+}
+
+// SetLatencyTracker makes the transfer report the round trip between forwarding
+// a packet to dst and forwarding the reply back to src
+func (t *Transfer) SetLatencyTracker(tracker *monitor.LatencyTracker) {
+	if tracker == nil {
+		t.rtt = nil
+		return
 	}
+	t.rtt = &rttProbe{tracker: tracker}
+}
+
+// SetRateLimiter makes the transfer wait on registry for every packet it
+// forwards, counting it against client, instead of limiting each direction
+// on its own
+func (t *Transfer) SetRateLimiter(registry *throttle.Registry, client string) {
+	t.limits = registry
+	t.client = client
+}
+
+// setTrafficCounters makes the transfer count the traffic it forwards in
+// counters, reading from src as in and from dst as out
+func (t *Transfer) setTrafficCounters(counters *trafficCounters) {
+	t.traffic = counters
+}
+
+// setTunnelIPRecorder makes the transfer pass the source address of the
+// first IP packet read from src to record
+func (t *Transfer) setTunnelIPRecorder(record func(ip net.IP)) {
+	t.tunnelIP = record
 }
 
 // Start starts the transfer
 func (t *Transfer) Start() error {
+	return t.StartContext(context.Background())
+}
+
+// StartContext starts the transfer and returns once both directions are done
+// or ctx is, closing the connections either way. When ctx ends the transfer,
+// its error is returned.
+func (t *Transfer) StartContext(ctx context.Context) error {
 	// Start bidirectional transfer
 	errChan := make(chan error, 2)
 
+	var toDst, toSrc io.Writer = t.dst, t.src
+	var readSrc, readDst io.Reader = t.srcToDst, t.dstToSrc
+	if t.limits != nil {
+		readSrc = &registryReader{r: t.src, limits: t.limits, client: t.client, in: true}
+		readDst = &registryReader{r: t.dst, limits: t.limits, client: t.client}
+	}
+	if t.traffic != nil {
+		readSrc = &countingReader{r: readSrc, bytes: &t.traffic.bytesIn, packets: &t.traffic.packetsIn}
+		readDst = &countingReader{r: readDst, bytes: &t.traffic.bytesOut, packets: &t.traffic.packetsOut}
+	}
+	fromSrc := readSrc
+	var coalescer *coalescingWriter
+	var hb *heartbeat
+	if t.coalesce.window > 0 {
+		coalescer = newCoalescingWriter(t.src, t.coalesce)
+		toSrc = coalescer
+	} else if t.heartbeat.interval > 0 {
+		toSrc = &frameWriter{w: t.src}
+	}
+	if t.heartbeat.interval > 0 {
+		hb = newHeartbeat(t.heartbeat, t.logger)
+		fromSrc = &frameReader{r: readSrc, seen: hb.seen}
+	} else if coalescer != nil {
+		fromSrc = &frameReader{r: readSrc}
+	}
+	if t.tunnelIP != nil {
+		fromSrc = &tunnelIPReader{r: fromSrc, record: t.tunnelIP}
+	}
+	framed := toSrc
+	if t.rtt != nil {
+		toDst = &probeWriter{Writer: toDst, mark: t.rtt.request}
+		toSrc = &probeWriter{Writer: toSrc, mark: t.rtt.response}
+	}
+
 	// Forward src -> dst
 	go func() {
 		// Read from src and write to dst through limiter
-		_, err := io.Copy(t.dst, t.srcToDst)
+		_, err := t.copyPackets(toDst, fromSrc)
+		t.countError(err)
 		errChan <- err
 	}()
 
 	// Forward dst -> src
 	go func() {
 		// Read from dst and write to src through limiter
-		_, err := io.Copy(t.src, t.dstToSrc)
+		_, err := t.copyPackets(toSrc, readDst)
+		if coalescer != nil {
+			if flushErr := coalescer.Flush(); err == nil {
+				err = flushErr
+			}
+		}
+		t.countError(err)
 		errChan <- err
 	}()
 
+	// Send heartbeats, bypassing the RTT probe, and close the connections if
+	// the peer falls silent
+	heartbeatErr := make(chan error, 1)
+	stopHeartbeat := make(chan struct{})
+	var heartbeats sync.WaitGroup
+	if hb != nil {
+		heartbeats.Add(2)
+		go func() {
+			defer heartbeats.Done()
+			hb.send(framed, stopHeartbeat)
+		}()
+		go func() {
+			defer heartbeats.Done()
+			if err := hb.watch(stopHeartbeat); err != nil {
+				heartbeatErr <- err
+				t.close()
+			}
+		}()
+	}
+
+	// Closing the connections when ctx is done ends both copies
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.close()
+		case <-done:
+		}
+	}()
+
 	// Wait for first error or completion
 	var err error
 	for i := 0; i < 2; i++ {
@@ -61,26 +233,157 @@ func (t *Transfer) Start() error {
 		}
 	}
 
-	// Close connections
-	t.src.Close()
-	t.dst.Close()
+	t.close()
 
+	close(stopHeartbeat)
+	heartbeats.Wait()
+	select {
+	case err = <-heartbeatErr:
+		return err
+	default:
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
 	return err
 }
 
-// Stop stops the transfer
-func (t *Transfer) Stop() error {
-	// Close connections
-	if err := t.src.Close(); err != nil {
-		t.logger.Error("Failed to close source connection", zap.Error(err))
+// countError counts a forwarding direction that ended with err, if any
+func (t *Transfer) countError(err error) {
+	if err != nil && t.traffic != nil {
+		t.traffic.errors.Add(1)
+	}
+}
+
+// copyPackets forwards data from src to dst one read at a time, using a buffer of the
+// configured packet size so a full packet is never split across writes. Transient
+// errors are retried up to the configured tolerance before the copy fails.
+func (t *Transfer) copyPackets(dst io.Writer, src io.Reader) (int64, error) {
+	buffers := t.buffers
+	if buffers == nil {
+		buffers = sharedBufferPool
+	}
+	buf := buffers.Get(t.packetSize)
+	defer buffers.Put(buf)
+
+	var written int64
+	readErrors := 0
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			w, werr := t.writePacket(dst, buf[:n])
+			written += w
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			if !t.tolerate(&readErrors, err, "read") {
+				return written, err
+			}
+			continue
+		}
+		readErrors = 0
 	}
-	if err := t.dst.Close(); err != nil {
-		t.logger.Error("Failed to close destination connection", zap.Error(err))
+}
+
+// writePacket writes a full packet, retrying the unwritten remainder after transient errors
+func (t *Transfer) writePacket(dst io.Writer, packet []byte) (int64, error) {
+	var written int64
+	writeErrors := 0
+	for len(packet) > 0 {
+		w, err := dst.Write(packet)
+		written += int64(w)
+		packet = packet[w:]
+		if err == nil {
+			if len(packet) > 0 {
+				return written, io.ErrShortWrite
+			}
+			break
+		}
+		if !t.tolerate(&writeErrors, err, "write") {
+			return written, err
+		}
 	}
+	return written, nil
+}
 
+// tolerate reports whether an error should be retried, counting consecutive
+// transient errors and pausing before the retry
+func (t *Transfer) tolerate(count *int, err error, op string) bool {
+	if !isTransientConnError(err) {
+		return false
+	}
+
+	*count++
+	if *count > t.maxErrors {
+		t.logger.Error("Too many consecutive transient errors, closing connection",
+			zap.String("op", op),
+			zap.Int("errors", *count),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	t.logger.Warn("Transient connection error, retrying",
+		zap.String("op", op),
+		zap.Int("attempt", *count),
+		zap.Error(err),
+	)
+	time.Sleep(t.retryDelay)
+	return true
+}
+
+// isTransientConnError reports whether an error is known to clear up on its
+// own: an interrupted call or a momentary shortage of kernel buffers. Anything
+// else, including an expired deadline and errors not known here, means the
+// connection cannot be trusted and is closed.
+func isTransientConnError(err error) bool {
+	return errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.ENOBUFS) ||
+		errors.Is(err, syscall.ENOMEM)
+}
+
+// Stop stops the transfer; stopping it again is a no-op
+func (t *Transfer) Stop() error {
+	t.close()
 	return nil
 }
 
+// close closes both connections the first time it is called. Errors are only
+// logged at debug level, as a peer that went away fails the close too.
+func (t *Transfer) close() {
+	t.closeOnce.Do(func() {
+		if err := t.src.Close(); err != nil {
+			t.logger.Debug("Failed to close source connection", zap.Error(err))
+		}
+		if err := t.dst.Close(); err != nil {
+			t.logger.Debug("Failed to close destination connection", zap.Error(err))
+		}
+	})
+}
+
+// registryReader waits on a rate limit registry for the bytes of every read
+type registryReader struct {
+	r      io.Reader
+	limits *throttle.Registry
+	client string
+	in     bool // Reads from src, limited as inbound traffic
+}
+
+func (r *registryReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.limits.Wait(r.client, r.in, n)
+	}
+	return n, err
+}
+
 // SetDeadline sets the read/write deadlines
 func (t *Transfer) SetDeadline(deadline time.Time) {
 	t.src.SetDeadline(deadline)
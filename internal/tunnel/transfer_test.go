@@ -0,0 +1,292 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	"github.com/o3willard-AI/SSSonector/internal/monitor"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
+	"go.uber.org/zap"
+)
+
+func TestPacketBufferSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		mtu           int
+		maxPacketSize int
+		want          int
+	}{
+		{"default", 0, 0, types.DefaultMTU + types.PacketOverhead},
+		{"standard MTU", 1500, 0, 1500 + types.PacketOverhead},
+		{"jumbo MTU", 9000, 0, 9000 + types.PacketOverhead},
+		{"explicit max packet size", 1500, 4096, 4096},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := types.NewAppConfig(types.TypeServer)
+			cfg.Config.Network.MTU = tt.mtu
+			cfg.Config.Network.MaxPacketSize = tt.maxPacketSize
+
+			if got := packetBufferSize(cfg); got != tt.want {
+				t.Errorf("Expected buffer size %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTransferJumboPacket(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	cfg.Config.Network.MTU = types.MaxJumboMTU
+	cfg.Config.Network.MaxPacketSize = types.MaxPacketSizeLimit
+
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	transfer := NewTransfer(src, dst, cfg, zap.NewNop())
+	done := make(chan error, 1)
+	go func() {
+		done <- transfer.Start()
+	}()
+
+	packet := bytes.Repeat([]byte{0xab}, types.MaxJumboMTU)
+	go srcPeer.Write(packet)
+
+	dstPeer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 2*types.MaxPacketSizeLimit)
+	n, err := dstPeer.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read forwarded packet: %v", err)
+	}
+
+	if n != len(packet) {
+		t.Fatalf("Expected packet of %d bytes in a single read, got %d", len(packet), n)
+	}
+	if !bytes.Equal(buf[:n], packet) {
+		t.Error("Forwarded packet does not match sent packet")
+	}
+
+	srcPeer.Close()
+	dstPeer.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Error("Transfer did not stop after connections closed")
+	}
+}
+
+// flakyWriter fails the first write with a transient error
+type flakyWriter struct {
+	bytes.Buffer
+	failures int
+	err      error
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.failures > 0 {
+		w.failures--
+		return 0, w.err
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestTransferTransientWriteError(t *testing.T) {
+	transfer := &Transfer{
+		logger:     zap.NewNop(),
+		packetSize: 2048,
+		maxErrors:  defaultMaxTransientErrors,
+		retryDelay: time.Millisecond,
+	}
+
+	// One transient error followed by success keeps the connection alive
+	dst := &flakyWriter{failures: 1, err: syscall.ENOBUFS}
+	packets := bytes.NewReader([]byte("first packet"))
+	written, err := transfer.copyPackets(dst, packets)
+	if err != nil {
+		t.Fatalf("Expected transient error to be tolerated, got %v", err)
+	}
+	if written != int64(len("first packet")) || dst.String() != "first packet" {
+		t.Errorf("Expected packet to be forwarded after retry, got %q", dst.String())
+	}
+
+	// Fatal errors are not retried, nor are expired deadlines and unknown errors
+	for _, fatal := range []error{io.ErrClosedPipe, os.ErrDeadlineExceeded, errors.New("unknown")} {
+		dst = &flakyWriter{failures: 1, err: fatal}
+		if _, err := transfer.copyPackets(dst, bytes.NewReader([]byte("packet"))); !errors.Is(err, fatal) {
+			t.Errorf("Expected %v to close the connection, got %v", fatal, err)
+		}
+		if dst.Len() != 0 {
+			t.Errorf("Expected no retry after %v", fatal)
+		}
+	}
+
+	// Persistent transient errors eventually close the connection
+	dst = &flakyWriter{failures: defaultMaxTransientErrors + 1, err: syscall.ENOBUFS}
+	if _, err := transfer.copyPackets(dst, bytes.NewReader([]byte("packet"))); !errors.Is(err, syscall.ENOBUFS) {
+		t.Errorf("Expected error after exceeding tolerance, got %v", err)
+	}
+}
+
+func TestTransferRecordsRoundTrips(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	// The backend answers every request after a short delay
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, err := dstPeer.Read(buf)
+			if err != nil {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+			if _, err := dstPeer.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	tracker := monitor.NewLatencyTracker()
+	transfer := NewTransfer(src, dst, cfg, zap.NewNop())
+	transfer.SetLatencyTracker(tracker)
+	go transfer.Start()
+	defer transfer.Stop()
+
+	srcPeer.SetDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1500)
+	for i := 0; i < 3; i++ {
+		if _, err := srcPeer.Write([]byte("request")); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		if _, err := srcPeer.Read(buf); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+	}
+
+	stats := tracker.Stats()
+	if stats.Samples != 3 {
+		t.Errorf("Expected 3 RTT samples, got %d", stats.Samples)
+	}
+	if stats.RTT < 5*time.Millisecond {
+		t.Errorf("Expected RTT of at least the backend delay, got %v", stats.RTT)
+	}
+}
+
+func TestTransferStartContextCancel(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	transfer := NewTransfer(src, dst, cfg, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- transfer.StartContext(ctx)
+	}()
+
+	// Forward a packet so the transfer is cancelled mid-stream
+	go srcPeer.Write([]byte("ping"))
+	dstPeer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := dstPeer.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("Failed to read forwarded packet: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartContext did not return after the context was cancelled")
+	}
+
+	// The connections are closed, and stopping again is safe
+	if _, err := srcPeer.Write([]byte("late")); err == nil {
+		t.Error("Expected the source connection to be closed")
+	}
+	if err := transfer.Stop(); err != nil {
+		t.Errorf("Expected a second stop to succeed, got %v", err)
+	}
+}
+
+func TestTransferRateLimiter(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	registry := throttle.NewRegistry(types.ThrottleConfig{Enabled: true, Rate: 1000, Burst: 100, PerClient: true})
+	transfer := NewTransfer(src, dst, cfg, zap.NewNop())
+	transfer.SetRateLimiter(registry, "10.0.0.2:5000")
+	go transfer.Start()
+	defer transfer.Stop()
+
+	// The second packet goes past the burst and waits for tokens
+	dstPeer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1500)
+	for i := 0; i < 2; i++ {
+		go srcPeer.Write(bytes.Repeat([]byte{0xab}, 100))
+		if _, err := dstPeer.Read(buf); err != nil {
+			t.Fatalf("Failed to read forwarded packet: %v", err)
+		}
+	}
+
+	status := registry.Status()
+	if len(status.Scopes) != 2 || status.Scopes[1].Scope != "10.0.0.2:5000" || status.Scopes[1].ThrottleCount == 0 {
+		t.Errorf("Expected the client scope to be throttled, got %+v", status.Scopes)
+	}
+}
+
+func TestTransferCountsTraffic(t *testing.T) {
+	cfg := types.NewAppConfig(types.TypeServer)
+	srcPeer, src := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+
+	var counters trafficCounters
+	transfer := NewTransfer(src, dst, cfg, zap.NewNop())
+	transfer.setTrafficCounters(&counters)
+	done := make(chan error, 1)
+	go func() {
+		done <- transfer.Start()
+	}()
+
+	srcPeer.SetDeadline(time.Now().Add(2 * time.Second))
+	dstPeer.SetDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 16)
+	go srcPeer.Write([]byte("request"))
+	if _, err := dstPeer.Read(buf); err != nil {
+		t.Fatalf("Failed to read forwarded request: %v", err)
+	}
+	go dstPeer.Write([]byte("reply"))
+	if _, err := srcPeer.Read(buf); err != nil {
+		t.Fatalf("Failed to read forwarded reply: %v", err)
+	}
+
+	transfer.Stop()
+	<-done
+
+	stats := counters.stats()
+	if stats.BytesIn != 7 || stats.PacketsIn != 1 || stats.BytesOut != 5 || stats.PacketsOut != 1 {
+		t.Errorf("Expected 7 bytes in and 5 bytes out in one packet each, got %+v", stats)
+	}
+}
@@ -2,17 +2,27 @@ package tunnel
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"path/filepath"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/o3willard-AI/SSSonector/internal/adapter"
 	"github.com/o3willard-AI/SSSonector/internal/config/interfaces"
 	"github.com/o3willard-AI/SSSonector/internal/config/types"
+	apperrors "github.com/o3willard-AI/SSSonector/internal/errors"
+	"github.com/o3willard-AI/SSSonector/internal/memory"
 	"github.com/o3willard-AI/SSSonector/internal/monitor"
 	"github.com/o3willard-AI/SSSonector/internal/pool"
+	"github.com/o3willard-AI/SSSonector/internal/resilience"
+	"github.com/o3willard-AI/SSSonector/internal/security/access"
+	"github.com/o3willard-AI/SSSonector/internal/throttle"
 	"go.uber.org/zap"
 )
 
@@ -42,18 +52,167 @@ func UpdateCertificatePaths(cfg *types.AppConfig, baseDir string) error {
 	return nil
 }
 
+// newAdapter creates the tunnel network interface; replaced in tests
+var newAdapter = adapter.New
+
+// teardownAdapter removes a network interface created by a startup that failed.
+// Cleanup is idempotent, so a later Stop may run it again.
+func teardownAdapter(iface adapter.Interface, cause error, logger *zap.Logger) {
+	logger.Warn("Startup failed, removing network interface",
+		zap.String("resource", "interface"),
+		zap.String("interface", iface.GetName()),
+		zap.Error(cause),
+	)
+
+	if err := iface.Cleanup(); err != nil {
+		logger.Error("Failed to remove network interface",
+			zap.String("resource", "interface"),
+			zap.String("interface", iface.GetName()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Info("Removed network interface",
+		zap.String("resource", "interface"),
+		zap.String("interface", iface.GetName()),
+	)
+}
+
+// applyRoutes installs the configured routes through iface. A route whose
+// destination is already routed elsewhere is logged and left out rather than
+// failing startup; any other error removes the routes already added.
+func applyRoutes(iface adapter.Interface, routes []string, logger *zap.Logger) (*adapter.RouteManager, error) {
+	manager := adapter.NewRouteManager(iface.GetName())
+	err := manager.Apply(routes)
+
+	var conflict *adapter.RouteConflictError
+	if errors.As(err, &conflict) {
+		for _, c := range conflict.Conflicts {
+			logger.Warn("Route already exists, not routing it through the tunnel",
+				zap.String("route", c.Route),
+				zap.String("via", c.Via),
+				zap.String("interface", iface.GetName()),
+			)
+		}
+		err = nil
+	}
+	if err != nil {
+		removeRoutes(manager, logger)
+		return nil, apperrors.Tunnel(apperrors.ErrTunnelInterface, err, "failed to apply routes")
+	}
+
+	if added := manager.Routes(); len(added) > 0 {
+		logger.Info("Applied routes",
+			zap.Strings("routes", added),
+			zap.String("interface", iface.GetName()),
+		)
+	}
+	return manager, nil
+}
+
+// applyDNS points the system resolver at the configured DNS servers while the
+// client is connected, if apply_dns is set
+func applyDNS(iface adapter.Interface, network types.NetworkConfig, stateDir string, logger *zap.Logger) (*adapter.DNSManager, error) {
+	if !network.ApplyDNS || len(network.DNSServers) == 0 {
+		return nil, nil
+	}
+
+	manager := adapter.NewDNSManager(iface.GetName(), stateDir)
+	if err := manager.Apply(network.DNSServers); err != nil {
+		return nil, apperrors.Tunnel(apperrors.ErrTunnelInterface, err, "failed to apply DNS servers")
+	}
+	logger.Info("Applied DNS servers",
+		zap.Strings("servers", network.DNSServers),
+		zap.String("interface", iface.GetName()),
+	)
+	return manager, nil
+}
+
+// restoreDNS restores the resolver configuration manager replaced, if any
+func restoreDNS(manager *adapter.DNSManager, logger *zap.Logger) {
+	if manager == nil {
+		return
+	}
+	if err := manager.Restore(); err != nil {
+		logger.Error("Failed to restore DNS servers", zap.Error(err))
+		return
+	}
+	logger.Info("Restored DNS servers")
+}
+
+// removeRoutes removes the routes manager added, if any
+func removeRoutes(manager *adapter.RouteManager, logger *zap.Logger) {
+	if manager == nil {
+		return
+	}
+	if err := manager.Remove(); err != nil {
+		logger.Error("Failed to remove routes", zap.Error(err))
+	}
+}
+
 // Server represents a tunnel server
 type Server struct {
-	config  *types.AppConfig
-	manager interfaces.ConfigManager
-	logger  *zap.Logger
-	pool    *pool.Pool
-	ln      net.Listener
-	wg      sync.WaitGroup
-	ctx     context.Context
-	cancel  context.CancelFunc
+	config    *types.AppConfig
+	manager   interfaces.ConfigManager
+	logger    *zap.Logger
+	pool      *pool.Pool
+	listeners []net.Listener // One per listen address
+	wg        sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	// Memory backpressure, see SetMemoryManager
+	memory       *memory.MemoryManager
+	acceptPaused atomic.Bool
+	conns        connTracker
+
+	// forwarding counts the connections and multiplexed streams being forwarded,
+	// held under MaxServerConnections
+	forwarding atomic.Int64
+
+	// traffic counts the traffic forwarded for all clients
+	traffic trafficCounters
+
+	// ipFilter refuses connections from denied addresses, see SetIPFilter
+	ipFilter atomic.Pointer[access.IPFilterManager]
+
+	// connLimiter refuses connections from IPs connecting too fast, see
+	// SetConnectionLimiter
+	connLimiter *throttle.ConnectionLimiter
+	rejectLog   rejectionLog // Rate limits the rejected connection warning
+
+	// limits rate limits the forwarded traffic, see SetRateLimiter
+	limits *throttle.Registry
+
+	// recovery waits out temporary accept errors, see SetErrorRecovery
+	recovery *resilience.ErrorRecovery
+
+	// latency collects round trips of forwarded request/response exchanges
+	latency *monitor.LatencyTracker
+
+	// TLS handshake of accepted connections, see SetTLSManager
+	tlsManager *TLSManager
+	tlsConfig  *tls.Config
+
+	// authenticator checks client credentials after the TLS handshake, nil
+	// when certificates alone authenticate clients; see SetAuthenticator
+	authenticator Authenticator
+
+	// drain tracks the shutdown progress, see Drain
+	drain atomic.Pointer[drainState]
+
+	// reloaded is the configuration applied by Reload, if any
+	reloaded atomic.Pointer[types.AppConfig]
+	reloadMu sync.Mutex // Serializes Reload
+
+	// routes are the configured routes Start installed, removed by Stop
+	routes atomic.Pointer[adapter.RouteManager]
 }
 
+// MaxServerConnections is the most connections the server forwards at once
+const MaxServerConnections = 1000
+
 // NewServer creates a new tunnel server
 func NewServer(cfg *types.AppConfig, manager interfaces.ConfigManager, logger *zap.Logger) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -62,7 +221,7 @@ func NewServer(cfg *types.AppConfig, manager interfaces.ConfigManager, logger *z
 	poolConfig := &pool.Config{
 		IdleTimeout:   time.Minute * 5,
 		MaxIdle:       100,
-		MaxActive:     1000,
+		MaxActive:     MaxServerConnections,
 		RetryInterval: time.Second * 5,
 		MaxRetries:    3,
 	}
@@ -84,17 +243,120 @@ func NewServer(cfg *types.AppConfig, manager interfaces.ConfigManager, logger *z
 		pool:    pool.NewPool(factory, poolConfig, logger),
 		ctx:     ctx,
 		cancel:  cancel,
+		latency: monitor.NewLatencyTracker(),
+	}
+}
+
+// LatencyTracker returns the tracker fed with the round trips of forwarded
+// traffic, for reporting through monitor.Monitor.SetLatencyTracker
+func (s *Server) LatencyTracker() *monitor.LatencyTracker {
+	return s.latency
+}
+
+// listenAddr is an address the server listens on and the network to bind it on
+type listenAddr struct {
+	network string
+	address string
+}
+
+// listenAddresses returns the addresses the server listens on. listen_addresses
+// may mix IPv4 and IPv6; the single listen_address and listen_port are bound on
+// IPv4 only.
+func listenAddresses(cfg *types.AppConfig) []listenAddr {
+	addrs := cfg.Config.Tunnel.ListenAddresses
+	if len(addrs) == 0 {
+		return []listenAddr{{"tcp4", fmt.Sprintf("%s:%d", cfg.Config.Tunnel.ListenAddress, cfg.Config.Tunnel.ListenPort)}}
+	}
+
+	listenAddrs := make([]listenAddr, len(addrs))
+	for i, addr := range addrs {
+		listenAddrs[i] = listenAddr{listenNetwork(addr), addr}
+	}
+	return listenAddrs
+}
+
+// listenNetwork picks tcp4 or tcp6 for an address with an IP host, so that a
+// wildcard such as [::]:8443 is bound IPv6-only and does not take the port of
+// 0.0.0.0:8443 as well. Hostnames and an empty host keep tcp.
+func listenNetwork(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "tcp"
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
 	}
 }
 
+// ProbeListenPort checks that every server listen address can be bound by
+// binding and releasing it, so a port conflict fails before slower startup
+// work. Errors wrap ErrNetworkPort and name the address that failed.
+func ProbeListenPort(cfg *types.AppConfig) error {
+	for _, addr := range listenAddresses(cfg) {
+		ln, err := net.Listen(addr.network, addr.address)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrNetworkPort, addr.address, err)
+		}
+		if err := ln.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listen binds every server listen address. When one fails, those already
+// bound are closed and the error names the address that failed.
+func listen(cfg *types.AppConfig) ([]net.Listener, error) {
+	addrs := listenAddresses(cfg)
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		ln, err := net.Listen(addr.network, addr.address)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("failed to start listener: %w: %s: %w", ErrNetworkPort, addr.address, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
 // Start starts the tunnel server
-func (s *Server) Start() error {
-	// Create adapter first
+func (s *Server) Start() (err error) {
+	// Fail fast on a port conflict or invalid ACL, before the interface is created
+	if err := ProbeListenPort(s.config); err != nil {
+		return err
+	}
+	if err := s.useConfiguredACL(); err != nil {
+		return err
+	}
+	if err := s.useConfiguredTLS(); err != nil {
+		return err
+	}
+	if err := s.useConfiguredAuthenticator(); err != nil {
+		return err
+	}
+	s.useConfiguredConnectionLimit()
+
+	// Create adapter
 	adapterOpts := adapter.DefaultOptions()
-	iface, err := adapter.New(s.config.Config.Network.Name, adapterOpts)
+	iface, err := newAdapter(s.config.Config.Network.Name, adapterOpts)
 	if err != nil {
-		return fmt.Errorf("failed to create adapter: %w", err)
+		return apperrors.Tunnel(apperrors.ErrTunnelInterface, err, "failed to create adapter")
 	}
+	defer func() {
+		if err != nil {
+			removeRoutes(s.routes.Swap(nil), s.logger)
+			teardownAdapter(iface, err, s.logger)
+		}
+	}()
 
 	// Configure adapter
 	if err := iface.Configure(&adapter.Config{
@@ -102,59 +364,176 @@ func (s *Server) Start() error {
 		Address: s.config.Config.Network.Address,
 		MTU:     s.config.Config.Network.MTU,
 	}); err != nil {
-		return fmt.Errorf("failed to configure adapter: %w", err)
+		return apperrors.Tunnel(apperrors.ErrTunnelInterface, err, "failed to configure adapter")
 	}
 
-	// Start listener
-	listenAddr := fmt.Sprintf("%s:%d", s.config.Config.Tunnel.ListenAddress, s.config.Config.Tunnel.ListenPort)
-	s.logger.Info("Starting tunnel server",
-		zap.String("address", listenAddr),
-	)
+	// Route the configured networks through the interface
+	routes, err := applyRoutes(iface, s.config.Config.Network.Routes, s.logger)
+	if err != nil {
+		return err
+	}
+	s.routes.Store(routes)
 
-	ln, err := net.Listen("tcp4", listenAddr) // Force IPv4
+	// Start listeners
+	listeners, err := listen(s.config)
 	if err != nil {
-		return fmt.Errorf("failed to start listener: %w", err)
+		return err
 	}
-	s.ln = ln
+	s.listeners = listeners
 
-	// Accept connections
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		for {
-			select {
-			case <-s.ctx.Done():
+	// Accept connections from every listener into the same handler
+	for _, ln := range listeners {
+		s.logger.Info("Starting tunnel server",
+			zap.String("address", ln.Addr().String()),
+		)
+		s.wg.Add(1)
+		go s.accept(ln)
+	}
+
+	return nil
+}
+
+// acceptBackoff is how the accept loop backs off while Accept keeps failing
+// temporarily, e.g. out of file descriptors, instead of spinning
+var acceptBackoff = resilience.BackoffConfig{
+	Strategy:   resilience.StrategyExponential,
+	BaseDelay:  5 * time.Millisecond,
+	MaxDelay:   time.Second,
+	Multiplier: 2.0,
+	Name:       "accept",
+}
+
+// SetErrorRecovery makes the server wait out temporary accept errors with the
+// strategies of recovery that can handle them, instead of the accept backoff.
+// It must be called before Start.
+func (s *Server) SetErrorRecovery(recovery *resilience.ErrorRecovery) {
+	s.recovery = recovery
+}
+
+// waitToAccept waits before retrying an accept that failed with a temporary
+// error. A recovery strategy stands in for the backoff; whatever it returns,
+// the accept is retried until the server stops.
+func (s *Server) waitToAccept(backoff *resilience.ExponentialBackoff, err error) error {
+	if s.recovery == nil || !s.recovery.CanRecover(err) {
+		return backoff.Wait(s.ctx)
+	}
+	s.recovery.Recover(s.ctx, err)
+	return s.ctx.Err()
+}
+
+// accept hands the connections accepted by ln to handleConnection until the
+// server stops. Temporary accept errors are retried with backoff; any other
+// stops the server.
+func (s *Server) accept(ln net.Listener) {
+	defer s.wg.Done()
+	config := acceptBackoff
+	backoff := resilience.NewExponentialBackoff(&config, s.logger)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
 				return
-			default:
-				conn, err := ln.Accept()
-				if err != nil {
-					if s.ctx.Err() == nil {
-						s.logger.Error("Failed to accept connection", zap.Error(err))
-					}
-					continue
+			}
+			if isTemporaryAcceptError(err) {
+				s.logger.Warn("Failed to accept connection, backing off",
+					zap.String("address", ln.Addr().String()),
+					zap.Int("attempt", backoff.GetRetryCount()+1),
+					zap.Error(err))
+				if s.waitToAccept(backoff, err) != nil {
+					return
 				}
-
-				s.wg.Add(1)
-				go func() {
-					defer s.wg.Done()
-					s.handleConnection(conn)
-				}()
+				continue
 			}
+
+			s.logger.Error("Stopping tunnel server after a fatal accept error",
+				zap.String("address", ln.Addr().String()),
+				zap.Error(err))
+			go s.Stop() // Stop waits for this goroutine
+			return
+		}
+		if backoff.GetRetryCount() > 0 {
+			backoff.Reset()
 		}
-	}()
 
-	return nil
+		if s.acceptPaused.Load() {
+			s.logger.Warn("Rejected connection under memory pressure",
+				zap.String("remote_addr", conn.RemoteAddr().String()))
+			conn.Close()
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.admit(conn)
+		}()
+	}
+}
+
+// isTemporaryAcceptError reports whether Accept failed for a reason that
+// passes, such as running out of file descriptors, rather than because the
+// listener is unusable
+func isTemporaryAcceptError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM, syscall.ECONNABORTED} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// admit recovers the client address of an accepted connection when the PROXY
+// protocol is enabled, checks it against the ACL and the connection rate limit
+// and handles the connection. Only peers in trusted_proxies may send a PROXY
+// header; connections from any other peer are closed.
+func (s *Server) admit(conn net.Conn) {
+	if tunnelCfg := s.currentConfig().Config.Tunnel; tunnelCfg.ProxyProtocol {
+		if !proxyTrusted(conn.RemoteAddr(), tunnelCfg.TrustedProxies) {
+			s.logger.Warn("Rejected connection from a peer not in trusted_proxies",
+				zap.String("remote_addr", conn.RemoteAddr().String()))
+			conn.Close()
+			return
+		}
+		proxied, err := readProxyHeader(conn, ProxyHeaderTimeout)
+		if err != nil {
+			s.logger.Warn("Rejected connection with an invalid PROXY protocol header",
+				zap.String("lb_addr", conn.RemoteAddr().String()),
+				zap.Error(err))
+			conn.Close()
+			return
+		}
+		conn = proxied
+	}
+
+	if allowed, reason := s.allowedByACL(conn.RemoteAddr()); !allowed {
+		s.logger.Warn("Rejected connection denied by ACL",
+			zap.String("remote_addr", conn.RemoteAddr().String()),
+			zap.String("reason", reason))
+		conn.Close()
+		return
+	}
+
+	if !s.allowedByConnectionLimit(conn.RemoteAddr()) {
+		if suppressed, ok := s.rejectLog.due(time.Now()); ok {
+			s.logger.Warn("Rejected connection over the connection rate limit",
+				zap.String("remote_addr", conn.RemoteAddr().String()),
+				zap.Uint64("suppressed", suppressed))
+		}
+		conn.Close()
+		return
+	}
+
+	s.handleConnection(conn)
 }
 
-// Stop stops the tunnel server
+// Stop stops the tunnel server, draining client connections for up to the
+// configured drain timeout
 func (s *Server) Stop() error {
 	s.logger.Info("Stopping tunnel server")
 
-	// Stop accepting new connections
-	s.cancel()
-	if s.ln != nil {
-		s.ln.Close()
-	}
+	// Stop accepting new connections and let active ones finish
+	s.Drain(s.drainTimeout())
 
 	// Close connection pool
 	s.pool.Close()
@@ -162,36 +541,175 @@ func (s *Server) Stop() error {
 	// Wait for all connections to finish
 	s.wg.Wait()
 
+	removeRoutes(s.routes.Swap(nil), s.logger)
+
 	return nil
 }
 
 // handleConnection handles a client connection
-func (s *Server) handleConnection(clientConn net.Conn) {
+func (s *Server) handleConnection(rawConn net.Conn) {
+	var identity PeerIdentity
+	var tlsState tls.ConnectionState
+	if s.tlsManager != nil {
+		tlsConn, id, err := s.serverHandshake(rawConn)
+		if err != nil {
+			s.logger.Warn("Rejected connection that failed the TLS handshake",
+				zap.String("remote_addr", rawConn.RemoteAddr().String()),
+				zap.Error(err))
+			rawConn.Close()
+			return
+		}
+		rawConn, identity = tlsConn, id
+		tlsState = tlsConn.ConnectionState()
+	}
+
+	if s.authenticator != nil {
+		if err := s.authenticate(rawConn); err != nil {
+			s.logger.Warn("Rejected connection that failed authentication",
+				zap.String("remote_addr", rawConn.RemoteAddr().String()),
+				zap.Error(err))
+			rawConn.Close()
+			return
+		}
+	}
+
+	muxed := false
+	if cfg := s.currentConfig(); cfg != nil && cfg.Config != nil && cfg.Config.Tunnel.Multiplex {
+		conn, ok, err := acceptMuxHandshake(rawConn, MuxPrefaceWait)
+		if err != nil {
+			s.logger.Warn("Rejected connection that failed the multiplexing handshake",
+				zap.String("remote_addr", rawConn.RemoteAddr().String()),
+				zap.Error(err))
+			rawConn.Close()
+			return
+		}
+		rawConn, muxed = conn, ok
+	}
+
+	clientConn := newActivityConn(rawConn)
+	clientConn.identity = identity
+	if tlsState.HandshakeComplete {
+		clientConn.tlsVersion = tls.VersionName(tlsState.Version)
+		clientConn.cipherSuite = tls.CipherSuiteName(tlsState.CipherSuite)
+	}
+	s.conns.add(clientConn)
+	defer s.conns.remove(clientConn)
 	defer clientConn.Close()
 
+	// Everything logged for this connection is attributable to its client
+	logger := s.logger.With(zap.String("conn_id", clientConn.id)).With(identity.fields()...)
+	if s.tlsManager != nil {
+		logger.Info("Client connected", zap.String("remote_addr", rawConn.RemoteAddr().String()))
+	}
+
+	if muxed {
+		s.serveMux(clientConn, logger)
+		return
+	}
+	s.forward(clientConn, clientConn, logger)
+}
+
+// serveMux forwards each stream the client opens over a multiplexed
+// connection like a connection of its own. Each stream counts against the
+// connection rate limit and MaxServerConnections as a connection would, and a
+// session carries at most MuxMaxStreams.
+func (s *Server) serveMux(clientConn *activityConn, logger *zap.Logger) {
+	session := NewMuxSession(clientConn, false)
+	defer session.Close()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		stream, err := session.Accept()
+		if err != nil {
+			logger.Debug("Multiplexed connection closed", zap.Error(err))
+			return
+		}
+		if !s.allowedByConnectionLimit(clientConn.RemoteAddr()) {
+			logger.Debug("Refused stream over the connection rate limit", zap.Uint32("stream_id", stream.ID()))
+			stream.Close()
+			continue
+		}
+		if s.forwarding.Load() >= MaxServerConnections {
+			logger.Debug("Refused stream over the connection limit", zap.Uint32("stream_id", stream.ID()))
+			stream.Close()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer stream.Close()
+			s.forward(stream, clientConn, logger.With(zap.Uint32("stream_id", stream.ID())))
+		}()
+	}
+}
+
+// forward transfers packets between a client connection and a connection
+// from the pool until either closes, recording the tunnel IP of the client
+// connection peer
+func (s *Server) forward(clientConn net.Conn, peer *activityConn, logger *zap.Logger) {
+	s.forwarding.Add(1)
+	defer s.forwarding.Add(-1)
+
 	// Get connection from pool
 	conn, err := s.pool.Get(s.ctx)
 	if err != nil {
-		s.logger.Error("Failed to get connection from pool", zap.Error(err))
+		logger.Error("Failed to get connection from pool", zap.Error(err))
 		return
 	}
 	defer s.pool.Put(conn)
 
 	// Create transfer
-	transfer := NewTransfer(clientConn, conn, s.config, s.logger)
+	transfer := NewTransfer(clientConn, conn, s.currentConfig(), logger)
+	if s.memory != nil {
+		transfer.SetBufferPool(s.memory.BufferPool())
+	}
+	transfer.SetLatencyTracker(s.latency)
+	transfer.setTrafficCounters(&s.traffic)
+	transfer.setTunnelIPRecorder(peer.recordTunnelIP)
+	if s.limits != nil {
+		client := rateLimitClient(clientConn.RemoteAddr())
+		s.limits.Acquire(client)
+		defer s.limits.Release(client)
+		transfer.SetRateLimiter(s.limits, client)
+	}
 	if err := transfer.Start(); err != nil {
-		s.logger.Error("Transfer failed", zap.Error(err))
+		logger.Error("Transfer failed", zap.Error(err))
 	}
 }
 
 // Client represents a tunnel client
 type Client struct {
-	config  *types.AppConfig
-	manager interfaces.ConfigManager
-	logger  *zap.Logger
-	pool    *pool.Pool
-	ctx     context.Context
-	cancel  context.CancelFunc
+	config    *types.AppConfig
+	manager   interfaces.ConfigManager
+	logger    *zap.Logger
+	pool      *pool.Pool
+	endpoints *EndpointManager
+	health    *EndpointHealthChecker // Nil unless endpoint health checks are enabled
+	mux       *muxDialer             // Nil unless multiplexing
+	tls       *TLSManager            // Nil without a certificate
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	// reloaded is the configuration applied by Reload, if any
+	reloaded atomic.Pointer[types.AppConfig]
+	reloadMu sync.Mutex // Serializes Reload
+
+	// routes are the configured routes Start installed, removed by Stop
+	routes atomic.Pointer[adapter.RouteManager]
+
+	// dns is the resolver configuration applied while connected, restored on
+	// disconnect or by Stop
+	dns atomic.Pointer[adapter.DNSManager]
+
+	// stateDir holds the resolver configuration backup, see SetStateDir
+	stateDir string
+
+	// limits rate limits the tunnel traffic, see SetRateLimiter
+	limits *throttle.Registry
+
+	// traffic counts the traffic forwarded through the tunnel
+	traffic trafficCounters
 }
 
 // NewClient creates a new tunnel client
@@ -199,43 +717,199 @@ func NewClient(cfg *types.AppConfig, manager interfaces.ConfigManager, logger *z
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create connection pool
-	poolConfig := &pool.Config{
-		IdleTimeout:   time.Minute * 5,
-		MaxIdle:       100,
-		MaxActive:     1000,
-		RetryInterval: time.Second * 5,
-		MaxRetries:    3,
+	poolConfig := clientPoolConfig(cfg.Config.Tunnel.Pool)
+
+	// Optionally cache server address resolution across reconnects
+	var resolver *CachingResolver
+	if cfg.Config.Tunnel.DNSCache.Enabled {
+		resolver = NewCachingResolver(net.DefaultResolver, cfg.Config.Tunnel.DNSCache.TTL, logger)
 	}
 
-	// Connection factory for the pool
-	factory := func(ctx context.Context) (net.Conn, error) {
-		// Create new connection to server
-		serverAddr := fmt.Sprintf("%s:%d", cfg.Config.Tunnel.ServerAddress, cfg.Config.Tunnel.ServerPort)
-		conn, err := net.Dial("tcp4", serverAddr) // Force IPv4
+	// Spread connections over the configured server endpoints, if any
+	endpoints := NewEndpointManager(nil, logger)
+	for _, e := range cfg.Config.Tunnel.Endpoints {
+		weight := e.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if err := endpoints.AddWithServerName(e.Address, weight, e.ServerName); err != nil {
+			logger.Warn("Ignoring server endpoint", zap.String("address", e.Address), zap.Error(err))
+		}
+	}
+
+	// dial connects to a server address in host:port form, giving up when ctx
+	// is done so that probe and connect timeouts apply to the dial itself
+	var dialer net.Dialer
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, resilience.MarkRetryable(err, false) // A bad address stays bad
+		}
+		if resolver != nil {
+			ip, err := resolver.ResolveIPv4(ctx, host)
+			if err != nil {
+				return nil, resilience.MarkRetryable(err, true)
+			}
+			host = ip.String()
+		}
+		conn, err := dialer.DialContext(ctx, "tcp4", net.JoinHostPort(host, port)) // Force IPv4
 		if err != nil {
-			return nil, fmt.Errorf("failed to connect to server: %w", err)
+			return nil, resilience.MarkRetryable(err, true)
 		}
 		return conn, nil
 	}
 
+	// Probe the endpoints in the background so that down ones are skipped
+	var health *EndpointHealthChecker
+	if cfg.Config.Tunnel.EndpointHealth.Enabled {
+		health = NewEndpointHealthChecker(endpoints, EndpointHealthConfigFrom(cfg.Config.Tunnel.EndpointHealth), DialProbe(dial), logger)
+	}
+
+	// dialTunnel connects to a server address and runs the TLS handshake when
+	// a certificate is configured, verifying serverName or else the host
+	tlsManager, tlsErr := newConfiguredTLSManager(cfg.Config, logger)
+	dialTunnel := func(ctx context.Context, address, serverName string) (net.Conn, error) {
+		conn, err := dial(ctx, address)
+		if err != nil || tlsManager == nil {
+			return conn, err
+		}
+		tlsConn, err := clientHandshake(ctx, conn, tlsManager, address, serverName)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+
+	// connect opens a connection to the server. A credential is only sent
+	// over TLS.
+	credential, credentialErr := clientCredential(cfg.Config)
+	if credential != nil && tlsErr == nil && tlsManager == nil {
+		credentialErr = fmt.Errorf("%w: auth method %s requires TLS, set auth.cert_file and auth.key_file",
+			ErrInvalidConfiguration, authMethod(cfg.Config))
+	}
+	connect := func(ctx context.Context) (net.Conn, error) {
+		if tlsErr != nil {
+			return nil, resilience.MarkRetryable(tlsErr, false)
+		}
+		if credentialErr != nil {
+			return nil, resilience.MarkRetryable(credentialErr, false)
+		}
+
+		var conn net.Conn
+		var err error
+		if endpoints.Len() > 0 {
+			conn, err = endpoints.Dial(ctx, dialTunnel)
+		} else {
+			address := net.JoinHostPort(cfg.Config.Tunnel.ServerAddress, strconv.Itoa(cfg.Config.Tunnel.ServerPort))
+			conn, err = dialTunnel(ctx, address, cfg.Config.Tunnel.ServerName)
+		}
+		if err != nil {
+			return nil, apperrors.Network(apperrors.ErrNetworkDial, err, "failed to connect to server")
+		}
+
+		// Present the credential for the configured auth method, if any
+		if credential != nil {
+			if err := clientAuthenticate(conn, credential, DefaultServerHandshakeTimeout); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		return conn, nil
+	}
+
+	// Connection factory for the pool, which opens streams over one
+	// connection when multiplexing
+	factory := connect
+	var mux *muxDialer
+	if cfg.Config.Tunnel.Multiplex {
+		mux = &muxDialer{dial: connect}
+		factory = mux.Open
+	}
+
 	return &Client{
-		config:  cfg,
-		manager: manager,
-		logger:  logger,
-		pool:    pool.NewPool(factory, poolConfig, logger),
-		ctx:     ctx,
-		cancel:  cancel,
+		config:    cfg,
+		manager:   manager,
+		logger:    logger,
+		pool:      pool.NewPool(factory, poolConfig, logger),
+		endpoints: endpoints,
+		health:    health,
+		mux:       mux,
+		tls:       tlsManager,
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
+// clientPoolConfig applies the configured pool sizes over the client defaults
+func clientPoolConfig(cfg types.PoolConfig) *pool.Config {
+	poolConfig := &pool.Config{
+		IdleTimeout:   time.Minute * 5,
+		MaxIdle:       100,
+		MaxActive:     1000,
+		RetryInterval: time.Second * 5,
+		MaxRetries:    3,
+		MinIdle:       cfg.MinIdle,
+		WaitTimeout:   cfg.WaitTimeout,
+	}
+	if cfg.IdleTimeout > 0 {
+		poolConfig.IdleTimeout = cfg.IdleTimeout
+	}
+	if cfg.MaxIdle > 0 {
+		poolConfig.MaxIdle = cfg.MaxIdle
+	}
+	if cfg.MaxActive > 0 {
+		poolConfig.MaxActive = cfg.MaxActive
+	}
+	return poolConfig
+}
+
+// Endpoints returns the server endpoints new connections are spread over. With
+// no endpoints the client connects to server_address.
+func (c *Client) Endpoints() *EndpointManager {
+	return c.endpoints
+}
+
+// PoolStats returns the statistics of the client's connection pool
+func (c *Client) PoolStats() pool.Stats {
+	return c.pool.Stats()
+}
+
+// SetStateDir sets the directory in which the resolver configuration replaced
+// by apply_dns is saved while connected, so that a client restarted after a
+// crash can restore it. Must be called before Start.
+func (c *Client) SetStateDir(path string) {
+	c.stateDir = path
+}
+
 // Start starts the tunnel client
-func (c *Client) Start() error {
+func (c *Client) Start() (err error) {
+	if c.health != nil {
+		c.health.Start()
+	}
+
+	// Restore the resolver configuration left behind by a client that
+	// exited while connected
+	if c.stateDir != "" {
+		if restored, err := adapter.RestoreDNSBackup(c.stateDir); err != nil {
+			c.logger.Error("Failed to restore saved DNS servers", zap.Error(err))
+		} else if restored {
+			c.logger.Info("Restored DNS servers saved by a previous run")
+		}
+	}
+
 	// Create adapter with default options
 	adapterOpts := adapter.DefaultOptions()
-	iface, err := adapter.New(c.config.Config.Network.Name, adapterOpts)
+	iface, err := newAdapter(c.config.Config.Network.Name, adapterOpts)
 	if err != nil {
-		return fmt.Errorf("failed to create adapter: %w", err)
+		return apperrors.Tunnel(apperrors.ErrTunnelInterface, err, "failed to create adapter")
 	}
+	defer func() {
+		if err != nil {
+			removeRoutes(c.routes.Swap(nil), c.logger)
+			teardownAdapter(iface, err, c.logger)
+		}
+	}()
 
 	// Configure adapter
 	if err := iface.Configure(&adapter.Config{
@@ -243,8 +917,15 @@ func (c *Client) Start() error {
 		Address: c.config.Config.Network.Address,
 		MTU:     c.config.Config.Network.MTU,
 	}); err != nil {
-		return fmt.Errorf("failed to configure adapter: %w", err)
+		return apperrors.Tunnel(apperrors.ErrTunnelInterface, err, "failed to configure adapter")
+	}
+
+	// Route the configured networks through the interface
+	routes, err := applyRoutes(iface, c.config.Config.Network.Routes, c.logger)
+	if err != nil {
+		return err
 	}
+	c.routes.Store(routes)
 
 	// Get connection from pool
 	conn, err := c.pool.Get(c.ctx)
@@ -253,10 +934,25 @@ func (c *Client) Start() error {
 	}
 	defer c.pool.Put(conn)
 
-	// Create tunnel
-	tunnel, err := New(conn, iface, c.config, nil)
+	// Open min_idle connections in advance now that the server is reachable
+	c.pool.Warm()
+
+	// Point the system resolver at the tunnel's DNS servers while connected,
+	// so that names still resolve while the server is unreachable
+	dns, err := applyDNS(iface, c.config.Config.Network, c.stateDir, c.logger)
 	if err != nil {
-		return fmt.Errorf("failed to create tunnel: %w", err)
+		return err
+	}
+	c.dns.Store(dns)
+	defer func() { restoreDNS(c.dns.Swap(nil), c.logger) }()
+
+	// Create tunnel
+	tunnel := &tunnelImpl{
+		conn:    conn,
+		adapter: iface,
+		config:  c.config,
+		limits:  c.limits,
+		traffic: &c.traffic,
 	}
 
 	return tunnel.Start()
@@ -269,8 +965,18 @@ func (c *Client) Stop() error {
 	// Cancel context
 	c.cancel()
 
+	if c.health != nil {
+		c.health.Stop()
+	}
+
 	// Close connection pool
 	c.pool.Close()
+	if c.mux != nil {
+		c.mux.Close()
+	}
+
+	restoreDNS(c.dns.Swap(nil), c.logger)
+	removeRoutes(c.routes.Swap(nil), c.logger)
 
 	return nil
 }
@@ -281,6 +987,8 @@ type tunnelImpl struct {
 	adapter adapter.Interface
 	config  *types.AppConfig
 	monitor *monitor.Monitor
+	limits  *throttle.Registry // Nil unless set on the client
+	traffic *trafficCounters   // Nil unless set on the client
 }
 
 // New creates a new tunnel
@@ -300,6 +1008,12 @@ func (t *tunnelImpl) Start() error {
 
 	// Create transfer to handle data between connection and adapter
 	transfer := NewTransfer(t.conn, adapterConn, t.config, nil)
+	if t.limits != nil {
+		transfer.SetRateLimiter(t.limits, "")
+	}
+	if t.traffic != nil {
+		transfer.setTrafficCounters(t.traffic)
+	}
 	return transfer.Start()
 }
 